@@ -35,6 +35,38 @@ func (src *VCDMachine) ConvertTo(dstRaw conversion.Hub) error {
 	dst.Spec.EnableNvidiaGPU = restored.Spec.EnableNvidiaGPU
 	dst.Spec.ExtraOvdcNetworks = restored.Spec.ExtraOvdcNetworks
 	dst.Spec.VmNamingTemplate = restored.Spec.VmNamingTemplate
+	dst.Spec.VmGroup = restored.Spec.VmGroup
+	dst.Spec.BootDiskBusType = restored.Spec.BootDiskBusType
+	dst.Spec.BootDiskProvisioningType = restored.Spec.BootDiskProvisioningType
+	dst.Spec.BootDiskIops = restored.Spec.BootDiskIops
+	dst.Spec.Networks = restored.Spec.Networks
+	dst.Spec.KubeletConfig = restored.Spec.KubeletConfig
+	dst.Spec.DataDisks = restored.Spec.DataDisks
+	dst.Spec.AntiAffinity = restored.Spec.AntiAffinity
+	dst.Spec.MetadataPropagation = restored.Spec.MetadataPropagation
+	dst.Spec.VAppName = restored.Spec.VAppName
+	dst.Spec.VAppOwnership = restored.Spec.VAppOwnership
+	dst.Spec.Ovdc = restored.Spec.Ovdc
+	dst.Spec.OvdcNetwork = restored.Spec.OvdcNetwork
+	dst.Spec.CatalogOrg = restored.Spec.CatalogOrg
+	dst.Spec.BootstrapFormat = restored.Spec.BootstrapFormat
+	dst.Spec.GPU = restored.Spec.GPU
+	dst.Spec.PrePullImages = restored.Spec.PrePullImages
+	dst.Spec.PreKubeadmCommandsScriptRef = restored.Spec.PreKubeadmCommandsScriptRef
+	dst.Spec.PostKubeadmCommandsScriptRef = restored.Spec.PostKubeadmCommandsScriptRef
+	dst.Spec.SSHAuthorizedKeys = restored.Spec.SSHAuthorizedKeys
+	dst.Spec.EmergencyUser = restored.Spec.EmergencyUser
+	dst.Spec.CaptureConsoleScreenshot = restored.Spec.CaptureConsoleScreenshot
+	dst.Spec.ProvisioningTimeout = restored.Spec.ProvisioningTimeout
+	dst.Spec.BootstrapTimeout = restored.Spec.BootstrapTimeout
+	dst.Spec.BootstrapRetries = restored.Spec.BootstrapRetries
+	dst.Spec.GracefulShutdownTimeout = restored.Spec.GracefulShutdownTimeout
+	dst.Spec.SnapshotBeforeUpgrade = restored.Spec.SnapshotBeforeUpgrade
+	dst.Spec.SnapshotRetention = restored.Spec.SnapshotRetention
+	dst.Spec.NumCPUs = restored.Spec.NumCPUs
+	dst.Spec.CoresPerSocket = restored.Spec.CoresPerSocket
+	dst.Spec.MemoryMiB = restored.Spec.MemoryMiB
+	dst.Spec.VerticalScalingPolicy = restored.Spec.VerticalScalingPolicy
 
 	dst.Status.Template = restored.Status.Template
 	dst.Status.ProviderID = restored.Status.ProviderID
@@ -42,6 +74,17 @@ func (src *VCDMachine) ConvertTo(dstRaw conversion.Hub) error {
 	dst.Status.PlacementPolicy = restored.Status.PlacementPolicy
 	dst.Status.NvidiaGPUEnabled = restored.Status.NvidiaGPUEnabled
 	dst.Status.DiskSize = restored.Status.DiskSize
+	dst.Status.TemplateHash = restored.Status.TemplateHash
+	dst.Status.ResolvedReferences = restored.Status.ResolvedReferences
+	dst.Status.ShutdownStartTime = restored.Status.ShutdownStartTime
+	dst.Status.Hardware = restored.Status.Hardware
+	dst.Status.VAppName = restored.Status.VAppName
+	dst.Status.BootstrapDiagnostics = restored.Status.BootstrapDiagnostics
+	dst.Status.BootstrapStartTime = restored.Status.BootstrapStartTime
+	dst.Status.BootstrapRetries = restored.Status.BootstrapRetries
+	dst.Status.FailureReason = restored.Status.FailureReason
+	dst.Status.FailureMessage = restored.Status.FailureMessage
+	dst.Status.VMName = restored.Status.VMName
 	return nil
 }
 
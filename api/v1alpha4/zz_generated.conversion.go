@@ -230,6 +230,7 @@ func autoConvert_v1beta3_UserCredentialsContext_To_v1alpha4_UserCredentialsConte
 	out.Password = in.Password
 	out.RefreshToken = in.RefreshToken
 	// WARNING: in.SecretRef requires manual conversion: does not exist in peer-type
+	// WARNING: in.AuthType requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -330,14 +331,37 @@ func autoConvert_v1beta3_VCDClusterSpec_To_v1alpha4_VCDClusterSpec(in *v1beta3.V
 	out.Org = in.Org
 	out.Ovdc = in.Ovdc
 	out.OvdcNetwork = in.OvdcNetwork
+	// WARNING: in.ManagedNetwork requires manual conversion: does not exist in peer-type
 	if err := Convert_v1beta3_UserCredentialsContext_To_v1alpha4_UserCredentialsContext(&in.UserCredentialsContext, &out.UserCredentialsContext, s); err != nil {
 		return err
 	}
+	// WARNING: in.IdentityRef requires manual conversion: does not exist in peer-type
 	// WARNING: in.RDEId requires manual conversion: does not exist in peer-type
+	// WARNING: in.RDEManagementDisabled requires manual conversion: does not exist in peer-type
 	// WARNING: in.ParentUID requires manual conversion: does not exist in peer-type
 	// WARNING: in.UseAsManagementCluster requires manual conversion: does not exist in peer-type
 	// WARNING: in.ProxyConfigSpec requires manual conversion: does not exist in peer-type
 	// WARNING: in.LoadBalancerConfigSpec requires manual conversion: does not exist in peer-type
+	// WARNING: in.DefaultMachinePolicies requires manual conversion: does not exist in peer-type
+	// WARNING: in.IPAllocation requires manual conversion: does not exist in peer-type
+	// WARNING: in.EgressAllowlist requires manual conversion: does not exist in peer-type
+	// WARNING: in.EgressConfig requires manual conversion: does not exist in peer-type
+	// WARNING: in.FirewallRules requires manual conversion: does not exist in peer-type
+	// WARNING: in.VerifyControlPlaneEndpoint requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneEndpointMode requires manual conversion: does not exist in peer-type
+	// WARNING: in.KubeVip requires manual conversion: does not exist in peer-type
+	// WARNING: in.PinSiteCertificate requires manual conversion: does not exist in peer-type
+	// WARNING: in.SiteCertificateFingerprints requires manual conversion: does not exist in peer-type
+	// WARNING: in.VCDTrustBundleSecretRef requires manual conversion: does not exist in peer-type
+	// WARNING: in.MetadataPropagation requires manual conversion: does not exist in peer-type
+	// WARNING: in.TemplateCache requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneSizingPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppName requires manual conversion: does not exist in peer-type
+	// WARNING: in.IPPoolExtension requires manual conversion: does not exist in peer-type
+	// WARNING: in.TemplateSources requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppStrategy requires manual conversion: does not exist in peer-type
+	// WARNING: in.VmNamingTemplate requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppNamingTemplate requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -368,6 +392,23 @@ func autoConvert_v1beta3_VCDClusterStatus_To_v1alpha4_VCDClusterStatus(in *v1bet
 	// WARNING: in.UseAsManagementCluster requires manual conversion: does not exist in peer-type
 	// WARNING: in.ProxyConfig requires manual conversion: does not exist in peer-type
 	// WARNING: in.LoadBalancerConfig requires manual conversion: does not exist in peer-type
+	// WARNING: in.EgressIPs requires manual conversion: does not exist in peer-type
+	// WARNING: in.EgressAllowlist requires manual conversion: does not exist in peer-type
+	// WARNING: in.EgressSNATIP requires manual conversion: does not exist in peer-type
+	// WARNING: in.FirewallRules requires manual conversion: does not exist in peer-type
+	// WARNING: in.ResolvedReferences requires manual conversion: does not exist in peer-type
+	// WARNING: in.LastSubsystemRuns requires manual conversion: does not exist in peer-type
+	// WARNING: in.KubernetesVersion requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneReplicas requires manual conversion: does not exist in peer-type
+	// WARNING: in.ReadyControlPlaneReplicas requires manual conversion: does not exist in peer-type
+	// WARNING: in.WorkerReplicas requires manual conversion: does not exist in peer-type
+	// WARNING: in.ReadyWorkerReplicas requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneVIP requires manual conversion: does not exist in peer-type
+	// WARNING: in.PhaseTransitions requires manual conversion: does not exist in peer-type
+	// WARNING: in.TemplateImports requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneMachineEndpoint requires manual conversion: does not exist in peer-type
+	// WARNING: in.ReservedControlPlaneVIP requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppName requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -457,15 +498,47 @@ func autoConvert_v1alpha4_VCDMachineSpec_To_v1beta3_VCDMachineSpec(in *VCDMachin
 func autoConvert_v1beta3_VCDMachineSpec_To_v1alpha4_VCDMachineSpec(in *v1beta3.VCDMachineSpec, out *VCDMachineSpec, s conversion.Scope) error {
 	out.ProviderID = (*string)(unsafe.Pointer(in.ProviderID))
 	out.Catalog = in.Catalog
+	// WARNING: in.CatalogOrg requires manual conversion: does not exist in peer-type
 	out.Template = in.Template
 	// WARNING: in.SizingPolicy requires manual conversion: does not exist in peer-type
 	// WARNING: in.PlacementPolicy requires manual conversion: does not exist in peer-type
 	// WARNING: in.StorageProfile requires manual conversion: does not exist in peer-type
+	// WARNING: in.NumCPUs requires manual conversion: does not exist in peer-type
+	// WARNING: in.CoresPerSocket requires manual conversion: does not exist in peer-type
+	// WARNING: in.MemoryMiB requires manual conversion: does not exist in peer-type
+	// WARNING: in.VerticalScalingPolicy requires manual conversion: does not exist in peer-type
 	// WARNING: in.DiskSize requires manual conversion: does not exist in peer-type
+	// WARNING: in.DataDisks requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootDiskBusType requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootDiskProvisioningType requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootDiskIops requires manual conversion: does not exist in peer-type
 	out.Bootstrapped = in.Bootstrapped
 	// WARNING: in.EnableNvidiaGPU requires manual conversion: does not exist in peer-type
+	// WARNING: in.GPU requires manual conversion: does not exist in peer-type
 	// WARNING: in.ExtraOvdcNetworks requires manual conversion: does not exist in peer-type
+	// WARNING: in.Networks requires manual conversion: does not exist in peer-type
 	// WARNING: in.VmNamingTemplate requires manual conversion: does not exist in peer-type
+	// WARNING: in.KubeletConfig requires manual conversion: does not exist in peer-type
+	// WARNING: in.VmGroup requires manual conversion: does not exist in peer-type
+	// WARNING: in.AntiAffinity requires manual conversion: does not exist in peer-type
+	// WARNING: in.MetadataPropagation requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppName requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppOwnership requires manual conversion: does not exist in peer-type
+	// WARNING: in.Ovdc requires manual conversion: does not exist in peer-type
+	// WARNING: in.OvdcNetwork requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapFormat requires manual conversion: does not exist in peer-type
+	// WARNING: in.PrePullImages requires manual conversion: does not exist in peer-type
+	// WARNING: in.PreKubeadmCommandsScriptRef requires manual conversion: does not exist in peer-type
+	// WARNING: in.PostKubeadmCommandsScriptRef requires manual conversion: does not exist in peer-type
+	// WARNING: in.SSHAuthorizedKeys requires manual conversion: does not exist in peer-type
+	// WARNING: in.EmergencyUser requires manual conversion: does not exist in peer-type
+	// WARNING: in.CaptureConsoleScreenshot requires manual conversion: does not exist in peer-type
+	// WARNING: in.ProvisioningTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapRetries requires manual conversion: does not exist in peer-type
+	// WARNING: in.GracefulShutdownTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.SnapshotBeforeUpgrade requires manual conversion: does not exist in peer-type
+	// WARNING: in.SnapshotRetention requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -490,7 +563,18 @@ func autoConvert_v1beta3_VCDMachineStatus_To_v1alpha4_VCDMachineStatus(in *v1bet
 	// WARNING: in.PlacementPolicy requires manual conversion: does not exist in peer-type
 	// WARNING: in.NvidiaGPUEnabled requires manual conversion: does not exist in peer-type
 	// WARNING: in.DiskSize requires manual conversion: does not exist in peer-type
+	// WARNING: in.TemplateHash requires manual conversion: does not exist in peer-type
 	out.Conditions = *(*apiv1alpha4.Conditions)(unsafe.Pointer(&in.Conditions))
+	// WARNING: in.ResolvedReferences requires manual conversion: does not exist in peer-type
+	// WARNING: in.ShutdownStartTime requires manual conversion: does not exist in peer-type
+	// WARNING: in.Hardware requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppName requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapDiagnostics requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapStartTime requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapRetries requires manual conversion: does not exist in peer-type
+	// WARNING: in.FailureReason requires manual conversion: does not exist in peer-type
+	// WARNING: in.FailureMessage requires manual conversion: does not exist in peer-type
+	// WARNING: in.VMName requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -24,3 +24,15 @@ func Convert_v1beta1_VCDClusterSpec_To_v1beta3_VCDClusterSpec(in *VCDClusterSpec
 func Convert_v1beta3_VCDClusterStatus_To_v1beta1_VCDClusterStatus(in *v1beta3.VCDClusterStatus, out *VCDClusterStatus, s conversion.Scope) error {
 	return autoConvert_v1beta3_VCDClusterStatus_To_v1beta1_VCDClusterStatus(in, out, s)
 }
+
+func Convert_v1beta3_VCDMachineStatus_To_v1beta1_VCDMachineStatus(in *v1beta3.VCDMachineStatus, out *VCDMachineStatus, s conversion.Scope) error {
+	return autoConvert_v1beta3_VCDMachineStatus_To_v1beta1_VCDMachineStatus(in, out, s)
+}
+
+func Convert_v1beta3_LoadBalancerConfig_To_v1beta1_LoadBalancerConfig(in *v1beta3.LoadBalancerConfig, out *LoadBalancerConfig, s conversion.Scope) error {
+	return autoConvert_v1beta3_LoadBalancerConfig_To_v1beta1_LoadBalancerConfig(in, out, s)
+}
+
+func Convert_v1beta3_UserCredentialsContext_To_v1beta1_UserCredentialsContext(in *v1beta3.UserCredentialsContext, out *UserCredentialsContext, s conversion.Scope) error {
+	return autoConvert_v1beta3_UserCredentialsContext_To_v1beta1_UserCredentialsContext(in, out, s)
+}
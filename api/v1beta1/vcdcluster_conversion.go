@@ -17,7 +17,54 @@ func (src *VCDCluster) ConvertTo(dstRaw conversion.Hub) error {
 	if ok, err := utilconversion.UnmarshalData(src, restored); err != nil || !ok {
 		return err
 	}
+	dst.Spec.DefaultMachinePolicies = restored.Spec.DefaultMachinePolicies
+	dst.Spec.EgressAllowlist = restored.Spec.EgressAllowlist
+	dst.Spec.IPAllocation = restored.Spec.IPAllocation
+	dst.Spec.VerifyControlPlaneEndpoint = restored.Spec.VerifyControlPlaneEndpoint
+	dst.Spec.ControlPlaneEndpointMode = restored.Spec.ControlPlaneEndpointMode
+	dst.Spec.PinSiteCertificate = restored.Spec.PinSiteCertificate
+	dst.Spec.SiteCertificateFingerprints = restored.Spec.SiteCertificateFingerprints
+	dst.Spec.MetadataPropagation = restored.Spec.MetadataPropagation
+	dst.Spec.TemplateCache = restored.Spec.TemplateCache
+	dst.Spec.ControlPlaneSizingPolicy = restored.Spec.ControlPlaneSizingPolicy
+	dst.Spec.VAppName = restored.Spec.VAppName
+	dst.Spec.IPPoolExtension = restored.Spec.IPPoolExtension
+	dst.Spec.TemplateSources = restored.Spec.TemplateSources
+	dst.Spec.KubeVip = restored.Spec.KubeVip
+	dst.Spec.EgressConfig = restored.Spec.EgressConfig
+	dst.Spec.FirewallRules = restored.Spec.FirewallRules
+	dst.Spec.ManagedNetwork = restored.Spec.ManagedNetwork
+	dst.Spec.VAppStrategy = restored.Spec.VAppStrategy
+	dst.Spec.VmNamingTemplate = restored.Spec.VmNamingTemplate
+	dst.Spec.VAppNamingTemplate = restored.Spec.VAppNamingTemplate
+	dst.Spec.RDEManagementDisabled = restored.Spec.RDEManagementDisabled
+	dst.Spec.VCDTrustBundleSecretRef = restored.Spec.VCDTrustBundleSecretRef
+	dst.Spec.UserCredentialsContext.AuthType = restored.Spec.UserCredentialsContext.AuthType
+	dst.Spec.IdentityRef = restored.Spec.IdentityRef
+	dst.Spec.LoadBalancerConfigSpec.HealthMonitor = restored.Spec.LoadBalancerConfigSpec.HealthMonitor
+	dst.Spec.LoadBalancerConfigSpec.PersistenceProfile = restored.Spec.LoadBalancerConfigSpec.PersistenceProfile
+	dst.Spec.LoadBalancerConfigSpec.ServiceEngineGroup = restored.Spec.LoadBalancerConfigSpec.ServiceEngineGroup
 	dst.Status.VcdResourceMap = restored.Status.VcdResourceMap
+	dst.Status.EgressIPs = restored.Status.EgressIPs
+	dst.Status.EgressAllowlist = restored.Status.EgressAllowlist
+	dst.Status.ResolvedReferences = restored.Status.ResolvedReferences
+	dst.Status.LastSubsystemRuns = restored.Status.LastSubsystemRuns
+	dst.Status.KubernetesVersion = restored.Status.KubernetesVersion
+	dst.Status.ControlPlaneReplicas = restored.Status.ControlPlaneReplicas
+	dst.Status.ReadyControlPlaneReplicas = restored.Status.ReadyControlPlaneReplicas
+	dst.Status.WorkerReplicas = restored.Status.WorkerReplicas
+	dst.Status.ReadyWorkerReplicas = restored.Status.ReadyWorkerReplicas
+	dst.Status.ControlPlaneVIP = restored.Status.ControlPlaneVIP
+	dst.Status.PhaseTransitions = restored.Status.PhaseTransitions
+	dst.Status.TemplateImports = restored.Status.TemplateImports
+	dst.Status.ControlPlaneMachineEndpoint = restored.Status.ControlPlaneMachineEndpoint
+	dst.Status.ReservedControlPlaneVIP = restored.Status.ReservedControlPlaneVIP
+	dst.Status.EgressSNATIP = restored.Status.EgressSNATIP
+	dst.Status.FirewallRules = restored.Status.FirewallRules
+	dst.Status.VAppName = restored.Status.VAppName
+	dst.Status.LoadBalancerConfig.HealthMonitor = restored.Status.LoadBalancerConfig.HealthMonitor
+	dst.Status.LoadBalancerConfig.PersistenceProfile = restored.Status.LoadBalancerConfig.PersistenceProfile
+	dst.Status.LoadBalancerConfig.ServiceEngineGroup = restored.Status.LoadBalancerConfig.ServiceEngineGroup
 	return nil
 }
 
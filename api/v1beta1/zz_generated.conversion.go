@@ -53,11 +53,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta3.LoadBalancerConfig)(nil), (*LoadBalancerConfig)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta3_LoadBalancerConfig_To_v1beta1_LoadBalancerConfig(a.(*v1beta3.LoadBalancerConfig), b.(*LoadBalancerConfig), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*Ports)(nil), (*v1beta3.Ports)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta1_Ports_To_v1beta3_Ports(a.(*Ports), b.(*v1beta3.Ports), scope)
 	}); err != nil {
@@ -83,11 +78,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta3.UserCredentialsContext)(nil), (*UserCredentialsContext)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta3_UserCredentialsContext_To_v1beta1_UserCredentialsContext(a.(*v1beta3.UserCredentialsContext), b.(*UserCredentialsContext), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*VCDCluster)(nil), (*v1beta3.VCDCluster)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta1_VCDCluster_To_v1beta3_VCDCluster(a.(*VCDCluster), b.(*v1beta3.VCDCluster), scope)
 	}); err != nil {
@@ -143,11 +133,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta3.VCDMachineStatus)(nil), (*VCDMachineStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta3_VCDMachineStatus_To_v1beta1_VCDMachineStatus(a.(*v1beta3.VCDMachineStatus), b.(*VCDMachineStatus), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*VCDMachineTemplate)(nil), (*v1beta3.VCDMachineTemplate)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta1_VCDMachineTemplate_To_v1beta3_VCDMachineTemplate(a.(*VCDMachineTemplate), b.(*v1beta3.VCDMachineTemplate), scope)
 	}); err != nil {
@@ -198,6 +183,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v1beta3.LoadBalancerConfig)(nil), (*LoadBalancerConfig)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta3_LoadBalancerConfig_To_v1beta1_LoadBalancerConfig(a.(*v1beta3.LoadBalancerConfig), b.(*LoadBalancerConfig), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta3.UserCredentialsContext)(nil), (*UserCredentialsContext)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta3_UserCredentialsContext_To_v1beta1_UserCredentialsContext(a.(*v1beta3.UserCredentialsContext), b.(*UserCredentialsContext), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddConversionFunc((*v1beta3.VCDClusterSpec)(nil), (*VCDClusterSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta3_VCDClusterSpec_To_v1beta1_VCDClusterSpec(a.(*v1beta3.VCDClusterSpec), b.(*VCDClusterSpec), scope)
 	}); err != nil {
@@ -213,6 +208,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v1beta3.VCDMachineStatus)(nil), (*VCDMachineStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta3_VCDMachineStatus_To_v1beta1_VCDMachineStatus(a.(*v1beta3.VCDMachineStatus), b.(*VCDMachineStatus), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddConversionFunc((*v1beta3.VCDMachineTemplateResource)(nil), (*VCDMachineTemplateResource)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta3_VCDMachineTemplateResource_To_v1beta1_VCDMachineTemplateResource(a.(*v1beta3.VCDMachineTemplateResource), b.(*VCDMachineTemplateResource), scope)
 	}); err != nil {
@@ -257,14 +257,12 @@ func Convert_v1beta1_LoadBalancerConfig_To_v1beta3_LoadBalancerConfig(in *LoadBa
 func autoConvert_v1beta3_LoadBalancerConfig_To_v1beta1_LoadBalancerConfig(in *v1beta3.LoadBalancerConfig, out *LoadBalancerConfig, s conversion.Scope) error {
 	out.UseOneArm = in.UseOneArm
 	out.VipSubnet = in.VipSubnet
+	// WARNING: in.HealthMonitor requires manual conversion: does not exist in peer-type
+	// WARNING: in.PersistenceProfile requires manual conversion: does not exist in peer-type
+	// WARNING: in.ServiceEngineGroup requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta3_LoadBalancerConfig_To_v1beta1_LoadBalancerConfig is an autogenerated conversion function.
-func Convert_v1beta3_LoadBalancerConfig_To_v1beta1_LoadBalancerConfig(in *v1beta3.LoadBalancerConfig, out *LoadBalancerConfig, s conversion.Scope) error {
-	return autoConvert_v1beta3_LoadBalancerConfig_To_v1beta1_LoadBalancerConfig(in, out, s)
-}
-
 func autoConvert_v1beta1_Ports_To_v1beta3_Ports(in *Ports, out *v1beta3.Ports, s conversion.Scope) error {
 	out.HTTP = in.HTTP
 	out.HTTPS = in.HTTPS
@@ -331,14 +329,10 @@ func autoConvert_v1beta3_UserCredentialsContext_To_v1beta1_UserCredentialsContex
 	out.Password = in.Password
 	out.RefreshToken = in.RefreshToken
 	out.SecretRef = (*v1.SecretReference)(unsafe.Pointer(in.SecretRef))
+	// WARNING: in.AuthType requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta3_UserCredentialsContext_To_v1beta1_UserCredentialsContext is an autogenerated conversion function.
-func Convert_v1beta3_UserCredentialsContext_To_v1beta1_UserCredentialsContext(in *v1beta3.UserCredentialsContext, out *UserCredentialsContext, s conversion.Scope) error {
-	return autoConvert_v1beta3_UserCredentialsContext_To_v1beta1_UserCredentialsContext(in, out, s)
-}
-
 func autoConvert_v1beta1_VCDCluster_To_v1beta3_VCDCluster(in *VCDCluster, out *v1beta3.VCDCluster, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v1beta1_VCDClusterSpec_To_v1beta3_VCDClusterSpec(&in.Spec, &out.Spec, s); err != nil {
@@ -444,10 +438,13 @@ func autoConvert_v1beta3_VCDClusterSpec_To_v1beta1_VCDClusterSpec(in *v1beta3.VC
 	out.Org = in.Org
 	out.Ovdc = in.Ovdc
 	out.OvdcNetwork = in.OvdcNetwork
+	// WARNING: in.ManagedNetwork requires manual conversion: does not exist in peer-type
 	if err := Convert_v1beta3_UserCredentialsContext_To_v1beta1_UserCredentialsContext(&in.UserCredentialsContext, &out.UserCredentialsContext, s); err != nil {
 		return err
 	}
+	// WARNING: in.IdentityRef requires manual conversion: does not exist in peer-type
 	out.RDEId = in.RDEId
+	// WARNING: in.RDEManagementDisabled requires manual conversion: does not exist in peer-type
 	out.ParentUID = in.ParentUID
 	out.UseAsManagementCluster = in.UseAsManagementCluster
 	if err := Convert_v1beta3_ProxyConfig_To_v1beta1_ProxyConfig(&in.ProxyConfigSpec, &out.ProxyConfigSpec, s); err != nil {
@@ -456,6 +453,26 @@ func autoConvert_v1beta3_VCDClusterSpec_To_v1beta1_VCDClusterSpec(in *v1beta3.VC
 	if err := Convert_v1beta3_LoadBalancerConfig_To_v1beta1_LoadBalancerConfig(&in.LoadBalancerConfigSpec, &out.LoadBalancerConfigSpec, s); err != nil {
 		return err
 	}
+	// WARNING: in.DefaultMachinePolicies requires manual conversion: does not exist in peer-type
+	// WARNING: in.IPAllocation requires manual conversion: does not exist in peer-type
+	// WARNING: in.EgressAllowlist requires manual conversion: does not exist in peer-type
+	// WARNING: in.EgressConfig requires manual conversion: does not exist in peer-type
+	// WARNING: in.FirewallRules requires manual conversion: does not exist in peer-type
+	// WARNING: in.VerifyControlPlaneEndpoint requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneEndpointMode requires manual conversion: does not exist in peer-type
+	// WARNING: in.KubeVip requires manual conversion: does not exist in peer-type
+	// WARNING: in.PinSiteCertificate requires manual conversion: does not exist in peer-type
+	// WARNING: in.SiteCertificateFingerprints requires manual conversion: does not exist in peer-type
+	// WARNING: in.VCDTrustBundleSecretRef requires manual conversion: does not exist in peer-type
+	// WARNING: in.MetadataPropagation requires manual conversion: does not exist in peer-type
+	// WARNING: in.TemplateCache requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneSizingPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppName requires manual conversion: does not exist in peer-type
+	// WARNING: in.IPPoolExtension requires manual conversion: does not exist in peer-type
+	// WARNING: in.TemplateSources requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppStrategy requires manual conversion: does not exist in peer-type
+	// WARNING: in.VmNamingTemplate requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppNamingTemplate requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -504,6 +521,23 @@ func autoConvert_v1beta3_VCDClusterStatus_To_v1beta1_VCDClusterStatus(in *v1beta
 	if err := Convert_v1beta3_LoadBalancerConfig_To_v1beta1_LoadBalancerConfig(&in.LoadBalancerConfig, &out.LoadBalancerConfig, s); err != nil {
 		return err
 	}
+	// WARNING: in.EgressIPs requires manual conversion: does not exist in peer-type
+	// WARNING: in.EgressAllowlist requires manual conversion: does not exist in peer-type
+	// WARNING: in.EgressSNATIP requires manual conversion: does not exist in peer-type
+	// WARNING: in.FirewallRules requires manual conversion: does not exist in peer-type
+	// WARNING: in.ResolvedReferences requires manual conversion: does not exist in peer-type
+	// WARNING: in.LastSubsystemRuns requires manual conversion: does not exist in peer-type
+	// WARNING: in.KubernetesVersion requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneReplicas requires manual conversion: does not exist in peer-type
+	// WARNING: in.ReadyControlPlaneReplicas requires manual conversion: does not exist in peer-type
+	// WARNING: in.WorkerReplicas requires manual conversion: does not exist in peer-type
+	// WARNING: in.ReadyWorkerReplicas requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneVIP requires manual conversion: does not exist in peer-type
+	// WARNING: in.PhaseTransitions requires manual conversion: does not exist in peer-type
+	// WARNING: in.TemplateImports requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneMachineEndpoint requires manual conversion: does not exist in peer-type
+	// WARNING: in.ReservedControlPlaneVIP requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppName requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -602,15 +636,47 @@ func Convert_v1beta1_VCDMachineSpec_To_v1beta3_VCDMachineSpec(in *VCDMachineSpec
 func autoConvert_v1beta3_VCDMachineSpec_To_v1beta1_VCDMachineSpec(in *v1beta3.VCDMachineSpec, out *VCDMachineSpec, s conversion.Scope) error {
 	out.ProviderID = (*string)(unsafe.Pointer(in.ProviderID))
 	out.Catalog = in.Catalog
+	// WARNING: in.CatalogOrg requires manual conversion: does not exist in peer-type
 	out.Template = in.Template
 	out.SizingPolicy = in.SizingPolicy
 	out.PlacementPolicy = in.PlacementPolicy
 	out.StorageProfile = in.StorageProfile
+	// WARNING: in.NumCPUs requires manual conversion: does not exist in peer-type
+	// WARNING: in.CoresPerSocket requires manual conversion: does not exist in peer-type
+	// WARNING: in.MemoryMiB requires manual conversion: does not exist in peer-type
+	// WARNING: in.VerticalScalingPolicy requires manual conversion: does not exist in peer-type
 	out.DiskSize = in.DiskSize
+	// WARNING: in.DataDisks requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootDiskBusType requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootDiskProvisioningType requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootDiskIops requires manual conversion: does not exist in peer-type
 	out.Bootstrapped = in.Bootstrapped
 	out.EnableNvidiaGPU = in.EnableNvidiaGPU
+	// WARNING: in.GPU requires manual conversion: does not exist in peer-type
 	// WARNING: in.ExtraOvdcNetworks requires manual conversion: does not exist in peer-type
+	// WARNING: in.Networks requires manual conversion: does not exist in peer-type
 	// WARNING: in.VmNamingTemplate requires manual conversion: does not exist in peer-type
+	// WARNING: in.KubeletConfig requires manual conversion: does not exist in peer-type
+	// WARNING: in.VmGroup requires manual conversion: does not exist in peer-type
+	// WARNING: in.AntiAffinity requires manual conversion: does not exist in peer-type
+	// WARNING: in.MetadataPropagation requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppName requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppOwnership requires manual conversion: does not exist in peer-type
+	// WARNING: in.Ovdc requires manual conversion: does not exist in peer-type
+	// WARNING: in.OvdcNetwork requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapFormat requires manual conversion: does not exist in peer-type
+	// WARNING: in.PrePullImages requires manual conversion: does not exist in peer-type
+	// WARNING: in.PreKubeadmCommandsScriptRef requires manual conversion: does not exist in peer-type
+	// WARNING: in.PostKubeadmCommandsScriptRef requires manual conversion: does not exist in peer-type
+	// WARNING: in.SSHAuthorizedKeys requires manual conversion: does not exist in peer-type
+	// WARNING: in.EmergencyUser requires manual conversion: does not exist in peer-type
+	// WARNING: in.CaptureConsoleScreenshot requires manual conversion: does not exist in peer-type
+	// WARNING: in.ProvisioningTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapRetries requires manual conversion: does not exist in peer-type
+	// WARNING: in.GracefulShutdownTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.SnapshotBeforeUpgrade requires manual conversion: does not exist in peer-type
+	// WARNING: in.SnapshotRetention requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -641,15 +707,21 @@ func autoConvert_v1beta3_VCDMachineStatus_To_v1beta1_VCDMachineStatus(in *v1beta
 	out.PlacementPolicy = in.PlacementPolicy
 	out.NvidiaGPUEnabled = in.NvidiaGPUEnabled
 	out.DiskSize = in.DiskSize
+	// WARNING: in.TemplateHash requires manual conversion: does not exist in peer-type
 	out.Conditions = *(*apiv1beta1.Conditions)(unsafe.Pointer(&in.Conditions))
+	// WARNING: in.ResolvedReferences requires manual conversion: does not exist in peer-type
+	// WARNING: in.ShutdownStartTime requires manual conversion: does not exist in peer-type
+	// WARNING: in.Hardware requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppName requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapDiagnostics requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapStartTime requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapRetries requires manual conversion: does not exist in peer-type
+	// WARNING: in.FailureReason requires manual conversion: does not exist in peer-type
+	// WARNING: in.FailureMessage requires manual conversion: does not exist in peer-type
+	// WARNING: in.VMName requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta3_VCDMachineStatus_To_v1beta1_VCDMachineStatus is an autogenerated conversion function.
-func Convert_v1beta3_VCDMachineStatus_To_v1beta1_VCDMachineStatus(in *v1beta3.VCDMachineStatus, out *VCDMachineStatus, s conversion.Scope) error {
-	return autoConvert_v1beta3_VCDMachineStatus_To_v1beta1_VCDMachineStatus(in, out, s)
-}
-
 func autoConvert_v1beta1_VCDMachineTemplate_To_v1beta3_VCDMachineTemplate(in *VCDMachineTemplate, out *v1beta3.VCDMachineTemplate, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v1beta1_VCDMachineTemplateSpec_To_v1beta3_VCDMachineTemplateSpec(&in.Spec, &out.Spec, s); err != nil {
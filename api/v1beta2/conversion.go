@@ -35,3 +35,15 @@ func Convert_v1beta3_UserCredentialsContext_To_v1beta2_UserCredentialsContext(in
 func Convert_v1beta3_VCDMachineTemplateResource_To_v1beta2_VCDMachineTemplateResource(in *v1beta3.VCDMachineTemplateResource, out *VCDMachineTemplateResource, s conversion.Scope) error {
 	return autoConvert_v1beta3_VCDMachineTemplateResource_To_v1beta2_VCDMachineTemplateResource(in, out, s)
 }
+
+func Convert_v1beta3_LoadBalancerConfig_To_v1beta2_LoadBalancerConfig(in *v1beta3.LoadBalancerConfig, out *LoadBalancerConfig, s conversion.Scope) error {
+	return autoConvert_v1beta3_LoadBalancerConfig_To_v1beta2_LoadBalancerConfig(in, out, s)
+}
+
+func Convert_v1beta3_VCDResourceMap_To_v1beta2_VCDResourceMap(in *v1beta3.VCDResourceMap, out *VCDResourceMap, s conversion.Scope) error {
+	return autoConvert_v1beta3_VCDResourceMap_To_v1beta2_VCDResourceMap(in, out, s)
+}
+
+func Convert_v1beta3_VCDClusterTemplateResource_To_v1beta2_VCDClusterTemplateResource(in *v1beta3.VCDClusterTemplateResource, out *VCDClusterTemplateResource, s conversion.Scope) error {
+	return autoConvert_v1beta3_VCDClusterTemplateResource_To_v1beta2_VCDClusterTemplateResource(in, out, s)
+}
@@ -17,7 +17,28 @@ func (src *VCDCluster) ConvertTo(dstRaw conversion.Hub) error {
 	if ok, err := utilconversion.UnmarshalData(src, restored); err != nil || !ok {
 		return err
 	}
+	restoreVCDClusterSpec(&dst.Spec, &restored.Spec)
 	dst.Status.VcdResourceMap = restored.Status.VcdResourceMap
+	dst.Status.EgressIPs = restored.Status.EgressIPs
+	dst.Status.EgressAllowlist = restored.Status.EgressAllowlist
+	dst.Status.ResolvedReferences = restored.Status.ResolvedReferences
+	dst.Status.LastSubsystemRuns = restored.Status.LastSubsystemRuns
+	dst.Status.KubernetesVersion = restored.Status.KubernetesVersion
+	dst.Status.ControlPlaneReplicas = restored.Status.ControlPlaneReplicas
+	dst.Status.ReadyControlPlaneReplicas = restored.Status.ReadyControlPlaneReplicas
+	dst.Status.WorkerReplicas = restored.Status.WorkerReplicas
+	dst.Status.ReadyWorkerReplicas = restored.Status.ReadyWorkerReplicas
+	dst.Status.ControlPlaneVIP = restored.Status.ControlPlaneVIP
+	dst.Status.PhaseTransitions = restored.Status.PhaseTransitions
+	dst.Status.TemplateImports = restored.Status.TemplateImports
+	dst.Status.ControlPlaneMachineEndpoint = restored.Status.ControlPlaneMachineEndpoint
+	dst.Status.ReservedControlPlaneVIP = restored.Status.ReservedControlPlaneVIP
+	dst.Status.EgressSNATIP = restored.Status.EgressSNATIP
+	dst.Status.FirewallRules = restored.Status.FirewallRules
+	dst.Status.VAppName = restored.Status.VAppName
+	dst.Status.LoadBalancerConfig.HealthMonitor = restored.Status.LoadBalancerConfig.HealthMonitor
+	dst.Status.LoadBalancerConfig.PersistenceProfile = restored.Status.LoadBalancerConfig.PersistenceProfile
+	dst.Status.LoadBalancerConfig.ServiceEngineGroup = restored.Status.LoadBalancerConfig.ServiceEngineGroup
 	return nil
 }
 
@@ -41,3 +62,35 @@ func (dst *VCDClusterList) ConvertFrom(srcRaw conversion.Hub) error {
 	src := srcRaw.(*v1beta3.VCDClusterList)
 	return Convert_v1beta3_VCDClusterList_To_v1beta2_VCDClusterList(src, dst, nil)
 }
+
+// restoreVCDClusterSpec restores the fields of the VCDClusterSpec which do not exist in v1beta2 from the hub version
+// saved in the annotations of the object.
+func restoreVCDClusterSpec(dst *v1beta3.VCDClusterSpec, restored *v1beta3.VCDClusterSpec) {
+	dst.DefaultMachinePolicies = restored.DefaultMachinePolicies
+	dst.EgressAllowlist = restored.EgressAllowlist
+	dst.IPAllocation = restored.IPAllocation
+	dst.VerifyControlPlaneEndpoint = restored.VerifyControlPlaneEndpoint
+	dst.ControlPlaneEndpointMode = restored.ControlPlaneEndpointMode
+	dst.PinSiteCertificate = restored.PinSiteCertificate
+	dst.SiteCertificateFingerprints = restored.SiteCertificateFingerprints
+	dst.MetadataPropagation = restored.MetadataPropagation
+	dst.TemplateCache = restored.TemplateCache
+	dst.ControlPlaneSizingPolicy = restored.ControlPlaneSizingPolicy
+	dst.VAppName = restored.VAppName
+	dst.IPPoolExtension = restored.IPPoolExtension
+	dst.TemplateSources = restored.TemplateSources
+	dst.KubeVip = restored.KubeVip
+	dst.EgressConfig = restored.EgressConfig
+	dst.FirewallRules = restored.FirewallRules
+	dst.ManagedNetwork = restored.ManagedNetwork
+	dst.VAppStrategy = restored.VAppStrategy
+	dst.VmNamingTemplate = restored.VmNamingTemplate
+	dst.VAppNamingTemplate = restored.VAppNamingTemplate
+	dst.RDEManagementDisabled = restored.RDEManagementDisabled
+	dst.VCDTrustBundleSecretRef = restored.VCDTrustBundleSecretRef
+	dst.UserCredentialsContext.AuthType = restored.UserCredentialsContext.AuthType
+	dst.IdentityRef = restored.IdentityRef
+	dst.LoadBalancerConfigSpec.HealthMonitor = restored.LoadBalancerConfigSpec.HealthMonitor
+	dst.LoadBalancerConfigSpec.PersistenceProfile = restored.LoadBalancerConfigSpec.PersistenceProfile
+	dst.LoadBalancerConfigSpec.ServiceEngineGroup = restored.LoadBalancerConfigSpec.ServiceEngineGroup
+}
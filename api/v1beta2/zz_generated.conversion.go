@@ -53,11 +53,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta3.LoadBalancerConfig)(nil), (*LoadBalancerConfig)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta3_LoadBalancerConfig_To_v1beta2_LoadBalancerConfig(a.(*v1beta3.LoadBalancerConfig), b.(*LoadBalancerConfig), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*Ports)(nil), (*v1beta3.Ports)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta2_Ports_To_v1beta3_Ports(a.(*Ports), b.(*v1beta3.Ports), scope)
 	}); err != nil {
@@ -108,6 +103,41 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*VCDClusterTemplate)(nil), (*v1beta3.VCDClusterTemplate)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta2_VCDClusterTemplate_To_v1beta3_VCDClusterTemplate(a.(*VCDClusterTemplate), b.(*v1beta3.VCDClusterTemplate), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v1beta3.VCDClusterTemplate)(nil), (*VCDClusterTemplate)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta3_VCDClusterTemplate_To_v1beta2_VCDClusterTemplate(a.(*v1beta3.VCDClusterTemplate), b.(*VCDClusterTemplate), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*VCDClusterTemplateList)(nil), (*v1beta3.VCDClusterTemplateList)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta2_VCDClusterTemplateList_To_v1beta3_VCDClusterTemplateList(a.(*VCDClusterTemplateList), b.(*v1beta3.VCDClusterTemplateList), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v1beta3.VCDClusterTemplateList)(nil), (*VCDClusterTemplateList)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta3_VCDClusterTemplateList_To_v1beta2_VCDClusterTemplateList(a.(*v1beta3.VCDClusterTemplateList), b.(*VCDClusterTemplateList), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*VCDClusterTemplateResource)(nil), (*v1beta3.VCDClusterTemplateResource)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta2_VCDClusterTemplateResource_To_v1beta3_VCDClusterTemplateResource(a.(*VCDClusterTemplateResource), b.(*v1beta3.VCDClusterTemplateResource), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*VCDClusterTemplateSpec)(nil), (*v1beta3.VCDClusterTemplateSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta2_VCDClusterTemplateSpec_To_v1beta3_VCDClusterTemplateSpec(a.(*VCDClusterTemplateSpec), b.(*v1beta3.VCDClusterTemplateSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*v1beta3.VCDClusterTemplateSpec)(nil), (*VCDClusterTemplateSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta3_VCDClusterTemplateSpec_To_v1beta2_VCDClusterTemplateSpec(a.(*v1beta3.VCDClusterTemplateSpec), b.(*VCDClusterTemplateSpec), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*VCDMachine)(nil), (*v1beta3.VCDMachine)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta2_VCDMachine_To_v1beta3_VCDMachine(a.(*VCDMachine), b.(*v1beta3.VCDMachine), scope)
 	}); err != nil {
@@ -193,11 +223,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta3.VCDResourceMap)(nil), (*VCDResourceMap)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta3_VCDResourceMap_To_v1beta2_VCDResourceMap(a.(*v1beta3.VCDResourceMap), b.(*VCDResourceMap), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddConversionFunc((*VCDClusterSpec)(nil), (*v1beta3.VCDClusterSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta2_VCDClusterSpec_To_v1beta3_VCDClusterSpec(a.(*VCDClusterSpec), b.(*v1beta3.VCDClusterSpec), scope)
 	}); err != nil {
@@ -208,6 +233,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v1beta3.LoadBalancerConfig)(nil), (*LoadBalancerConfig)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta3_LoadBalancerConfig_To_v1beta2_LoadBalancerConfig(a.(*v1beta3.LoadBalancerConfig), b.(*LoadBalancerConfig), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddConversionFunc((*v1beta3.UserCredentialsContext)(nil), (*UserCredentialsContext)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta3_UserCredentialsContext_To_v1beta2_UserCredentialsContext(a.(*v1beta3.UserCredentialsContext), b.(*UserCredentialsContext), scope)
 	}); err != nil {
@@ -223,6 +253,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v1beta3.VCDClusterTemplateResource)(nil), (*VCDClusterTemplateResource)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta3_VCDClusterTemplateResource_To_v1beta2_VCDClusterTemplateResource(a.(*v1beta3.VCDClusterTemplateResource), b.(*VCDClusterTemplateResource), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddConversionFunc((*v1beta3.VCDMachineSpec)(nil), (*VCDMachineSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta3_VCDMachineSpec_To_v1beta2_VCDMachineSpec(a.(*v1beta3.VCDMachineSpec), b.(*VCDMachineSpec), scope)
 	}); err != nil {
@@ -238,6 +273,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v1beta3.VCDResourceMap)(nil), (*VCDResourceMap)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta3_VCDResourceMap_To_v1beta2_VCDResourceMap(a.(*v1beta3.VCDResourceMap), b.(*VCDResourceMap), scope)
+	}); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -277,14 +317,12 @@ func Convert_v1beta2_LoadBalancerConfig_To_v1beta3_LoadBalancerConfig(in *LoadBa
 func autoConvert_v1beta3_LoadBalancerConfig_To_v1beta2_LoadBalancerConfig(in *v1beta3.LoadBalancerConfig, out *LoadBalancerConfig, s conversion.Scope) error {
 	out.UseOneArm = in.UseOneArm
 	out.VipSubnet = in.VipSubnet
+	// WARNING: in.HealthMonitor requires manual conversion: does not exist in peer-type
+	// WARNING: in.PersistenceProfile requires manual conversion: does not exist in peer-type
+	// WARNING: in.ServiceEngineGroup requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta3_LoadBalancerConfig_To_v1beta2_LoadBalancerConfig is an autogenerated conversion function.
-func Convert_v1beta3_LoadBalancerConfig_To_v1beta2_LoadBalancerConfig(in *v1beta3.LoadBalancerConfig, out *LoadBalancerConfig, s conversion.Scope) error {
-	return autoConvert_v1beta3_LoadBalancerConfig_To_v1beta2_LoadBalancerConfig(in, out, s)
-}
-
 func autoConvert_v1beta2_Ports_To_v1beta3_Ports(in *Ports, out *v1beta3.Ports, s conversion.Scope) error {
 	out.HTTP = in.HTTP
 	out.HTTPS = in.HTTPS
@@ -351,6 +389,7 @@ func autoConvert_v1beta3_UserCredentialsContext_To_v1beta2_UserCredentialsContex
 	out.Password = in.Password
 	out.RefreshToken = in.RefreshToken
 	out.SecretRef = (*v1.SecretReference)(unsafe.Pointer(in.SecretRef))
+	// WARNING: in.AuthType requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -459,10 +498,13 @@ func autoConvert_v1beta3_VCDClusterSpec_To_v1beta2_VCDClusterSpec(in *v1beta3.VC
 	out.Org = in.Org
 	out.Ovdc = in.Ovdc
 	out.OvdcNetwork = in.OvdcNetwork
+	// WARNING: in.ManagedNetwork requires manual conversion: does not exist in peer-type
 	if err := Convert_v1beta3_UserCredentialsContext_To_v1beta2_UserCredentialsContext(&in.UserCredentialsContext, &out.UserCredentialsContext, s); err != nil {
 		return err
 	}
+	// WARNING: in.IdentityRef requires manual conversion: does not exist in peer-type
 	out.RDEId = in.RDEId
+	// WARNING: in.RDEManagementDisabled requires manual conversion: does not exist in peer-type
 	out.ParentUID = in.ParentUID
 	out.UseAsManagementCluster = in.UseAsManagementCluster
 	if err := Convert_v1beta3_ProxyConfig_To_v1beta2_ProxyConfig(&in.ProxyConfigSpec, &out.ProxyConfigSpec, s); err != nil {
@@ -471,6 +513,26 @@ func autoConvert_v1beta3_VCDClusterSpec_To_v1beta2_VCDClusterSpec(in *v1beta3.VC
 	if err := Convert_v1beta3_LoadBalancerConfig_To_v1beta2_LoadBalancerConfig(&in.LoadBalancerConfigSpec, &out.LoadBalancerConfigSpec, s); err != nil {
 		return err
 	}
+	// WARNING: in.DefaultMachinePolicies requires manual conversion: does not exist in peer-type
+	// WARNING: in.IPAllocation requires manual conversion: does not exist in peer-type
+	// WARNING: in.EgressAllowlist requires manual conversion: does not exist in peer-type
+	// WARNING: in.EgressConfig requires manual conversion: does not exist in peer-type
+	// WARNING: in.FirewallRules requires manual conversion: does not exist in peer-type
+	// WARNING: in.VerifyControlPlaneEndpoint requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneEndpointMode requires manual conversion: does not exist in peer-type
+	// WARNING: in.KubeVip requires manual conversion: does not exist in peer-type
+	// WARNING: in.PinSiteCertificate requires manual conversion: does not exist in peer-type
+	// WARNING: in.SiteCertificateFingerprints requires manual conversion: does not exist in peer-type
+	// WARNING: in.VCDTrustBundleSecretRef requires manual conversion: does not exist in peer-type
+	// WARNING: in.MetadataPropagation requires manual conversion: does not exist in peer-type
+	// WARNING: in.TemplateCache requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneSizingPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppName requires manual conversion: does not exist in peer-type
+	// WARNING: in.IPPoolExtension requires manual conversion: does not exist in peer-type
+	// WARNING: in.TemplateSources requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppStrategy requires manual conversion: does not exist in peer-type
+	// WARNING: in.VmNamingTemplate requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppNamingTemplate requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -524,9 +586,138 @@ func autoConvert_v1beta3_VCDClusterStatus_To_v1beta2_VCDClusterStatus(in *v1beta
 	if err := Convert_v1beta3_LoadBalancerConfig_To_v1beta2_LoadBalancerConfig(&in.LoadBalancerConfig, &out.LoadBalancerConfig, s); err != nil {
 		return err
 	}
+	// WARNING: in.EgressIPs requires manual conversion: does not exist in peer-type
+	// WARNING: in.EgressAllowlist requires manual conversion: does not exist in peer-type
+	// WARNING: in.EgressSNATIP requires manual conversion: does not exist in peer-type
+	// WARNING: in.FirewallRules requires manual conversion: does not exist in peer-type
+	// WARNING: in.ResolvedReferences requires manual conversion: does not exist in peer-type
+	// WARNING: in.LastSubsystemRuns requires manual conversion: does not exist in peer-type
+	// WARNING: in.KubernetesVersion requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneReplicas requires manual conversion: does not exist in peer-type
+	// WARNING: in.ReadyControlPlaneReplicas requires manual conversion: does not exist in peer-type
+	// WARNING: in.WorkerReplicas requires manual conversion: does not exist in peer-type
+	// WARNING: in.ReadyWorkerReplicas requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneVIP requires manual conversion: does not exist in peer-type
+	// WARNING: in.PhaseTransitions requires manual conversion: does not exist in peer-type
+	// WARNING: in.TemplateImports requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneMachineEndpoint requires manual conversion: does not exist in peer-type
+	// WARNING: in.ReservedControlPlaneVIP requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppName requires manual conversion: does not exist in peer-type
+	return nil
+}
+
+func autoConvert_v1beta2_VCDClusterTemplate_To_v1beta3_VCDClusterTemplate(in *VCDClusterTemplate, out *v1beta3.VCDClusterTemplate, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	if err := Convert_v1beta2_VCDClusterTemplateSpec_To_v1beta3_VCDClusterTemplateSpec(&in.Spec, &out.Spec, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_v1beta2_VCDClusterTemplate_To_v1beta3_VCDClusterTemplate is an autogenerated conversion function.
+func Convert_v1beta2_VCDClusterTemplate_To_v1beta3_VCDClusterTemplate(in *VCDClusterTemplate, out *v1beta3.VCDClusterTemplate, s conversion.Scope) error {
+	return autoConvert_v1beta2_VCDClusterTemplate_To_v1beta3_VCDClusterTemplate(in, out, s)
+}
+
+func autoConvert_v1beta3_VCDClusterTemplate_To_v1beta2_VCDClusterTemplate(in *v1beta3.VCDClusterTemplate, out *VCDClusterTemplate, s conversion.Scope) error {
+	out.ObjectMeta = in.ObjectMeta
+	if err := Convert_v1beta3_VCDClusterTemplateSpec_To_v1beta2_VCDClusterTemplateSpec(&in.Spec, &out.Spec, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_v1beta3_VCDClusterTemplate_To_v1beta2_VCDClusterTemplate is an autogenerated conversion function.
+func Convert_v1beta3_VCDClusterTemplate_To_v1beta2_VCDClusterTemplate(in *v1beta3.VCDClusterTemplate, out *VCDClusterTemplate, s conversion.Scope) error {
+	return autoConvert_v1beta3_VCDClusterTemplate_To_v1beta2_VCDClusterTemplate(in, out, s)
+}
+
+func autoConvert_v1beta2_VCDClusterTemplateList_To_v1beta3_VCDClusterTemplateList(in *VCDClusterTemplateList, out *v1beta3.VCDClusterTemplateList, s conversion.Scope) error {
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]v1beta3.VCDClusterTemplate, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta2_VCDClusterTemplate_To_v1beta3_VCDClusterTemplate(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
+	}
+	return nil
+}
+
+// Convert_v1beta2_VCDClusterTemplateList_To_v1beta3_VCDClusterTemplateList is an autogenerated conversion function.
+func Convert_v1beta2_VCDClusterTemplateList_To_v1beta3_VCDClusterTemplateList(in *VCDClusterTemplateList, out *v1beta3.VCDClusterTemplateList, s conversion.Scope) error {
+	return autoConvert_v1beta2_VCDClusterTemplateList_To_v1beta3_VCDClusterTemplateList(in, out, s)
+}
+
+func autoConvert_v1beta3_VCDClusterTemplateList_To_v1beta2_VCDClusterTemplateList(in *v1beta3.VCDClusterTemplateList, out *VCDClusterTemplateList, s conversion.Scope) error {
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VCDClusterTemplate, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta3_VCDClusterTemplate_To_v1beta2_VCDClusterTemplate(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
+	}
+	return nil
+}
+
+// Convert_v1beta3_VCDClusterTemplateList_To_v1beta2_VCDClusterTemplateList is an autogenerated conversion function.
+func Convert_v1beta3_VCDClusterTemplateList_To_v1beta2_VCDClusterTemplateList(in *v1beta3.VCDClusterTemplateList, out *VCDClusterTemplateList, s conversion.Scope) error {
+	return autoConvert_v1beta3_VCDClusterTemplateList_To_v1beta2_VCDClusterTemplateList(in, out, s)
+}
+
+func autoConvert_v1beta2_VCDClusterTemplateResource_To_v1beta3_VCDClusterTemplateResource(in *VCDClusterTemplateResource, out *v1beta3.VCDClusterTemplateResource, s conversion.Scope) error {
+	if err := Convert_v1beta2_VCDClusterSpec_To_v1beta3_VCDClusterSpec(&in.Spec, &out.Spec, s); err != nil {
+		return err
+	}
 	return nil
 }
 
+// Convert_v1beta2_VCDClusterTemplateResource_To_v1beta3_VCDClusterTemplateResource is an autogenerated conversion function.
+func Convert_v1beta2_VCDClusterTemplateResource_To_v1beta3_VCDClusterTemplateResource(in *VCDClusterTemplateResource, out *v1beta3.VCDClusterTemplateResource, s conversion.Scope) error {
+	return autoConvert_v1beta2_VCDClusterTemplateResource_To_v1beta3_VCDClusterTemplateResource(in, out, s)
+}
+
+func autoConvert_v1beta3_VCDClusterTemplateResource_To_v1beta2_VCDClusterTemplateResource(in *v1beta3.VCDClusterTemplateResource, out *VCDClusterTemplateResource, s conversion.Scope) error {
+	// WARNING: in.ObjectMeta requires manual conversion: does not exist in peer-type
+	if err := Convert_v1beta3_VCDClusterSpec_To_v1beta2_VCDClusterSpec(&in.Spec, &out.Spec, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+func autoConvert_v1beta2_VCDClusterTemplateSpec_To_v1beta3_VCDClusterTemplateSpec(in *VCDClusterTemplateSpec, out *v1beta3.VCDClusterTemplateSpec, s conversion.Scope) error {
+	if err := Convert_v1beta2_VCDClusterTemplateResource_To_v1beta3_VCDClusterTemplateResource(&in.Template, &out.Template, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_v1beta2_VCDClusterTemplateSpec_To_v1beta3_VCDClusterTemplateSpec is an autogenerated conversion function.
+func Convert_v1beta2_VCDClusterTemplateSpec_To_v1beta3_VCDClusterTemplateSpec(in *VCDClusterTemplateSpec, out *v1beta3.VCDClusterTemplateSpec, s conversion.Scope) error {
+	return autoConvert_v1beta2_VCDClusterTemplateSpec_To_v1beta3_VCDClusterTemplateSpec(in, out, s)
+}
+
+func autoConvert_v1beta3_VCDClusterTemplateSpec_To_v1beta2_VCDClusterTemplateSpec(in *v1beta3.VCDClusterTemplateSpec, out *VCDClusterTemplateSpec, s conversion.Scope) error {
+	if err := Convert_v1beta3_VCDClusterTemplateResource_To_v1beta2_VCDClusterTemplateResource(&in.Template, &out.Template, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_v1beta3_VCDClusterTemplateSpec_To_v1beta2_VCDClusterTemplateSpec is an autogenerated conversion function.
+func Convert_v1beta3_VCDClusterTemplateSpec_To_v1beta2_VCDClusterTemplateSpec(in *v1beta3.VCDClusterTemplateSpec, out *VCDClusterTemplateSpec, s conversion.Scope) error {
+	return autoConvert_v1beta3_VCDClusterTemplateSpec_To_v1beta2_VCDClusterTemplateSpec(in, out, s)
+}
+
 func autoConvert_v1beta2_VCDMachine_To_v1beta3_VCDMachine(in *VCDMachine, out *v1beta3.VCDMachine, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v1beta2_VCDMachineSpec_To_v1beta3_VCDMachineSpec(&in.Spec, &out.Spec, s); err != nil {
@@ -619,15 +810,47 @@ func autoConvert_v1beta2_VCDMachineSpec_To_v1beta3_VCDMachineSpec(in *VCDMachine
 func autoConvert_v1beta3_VCDMachineSpec_To_v1beta2_VCDMachineSpec(in *v1beta3.VCDMachineSpec, out *VCDMachineSpec, s conversion.Scope) error {
 	out.ProviderID = (*string)(unsafe.Pointer(in.ProviderID))
 	out.Catalog = in.Catalog
+	// WARNING: in.CatalogOrg requires manual conversion: does not exist in peer-type
 	out.Template = in.Template
 	out.SizingPolicy = in.SizingPolicy
 	out.PlacementPolicy = in.PlacementPolicy
 	out.StorageProfile = in.StorageProfile
+	// WARNING: in.NumCPUs requires manual conversion: does not exist in peer-type
+	// WARNING: in.CoresPerSocket requires manual conversion: does not exist in peer-type
+	// WARNING: in.MemoryMiB requires manual conversion: does not exist in peer-type
+	// WARNING: in.VerticalScalingPolicy requires manual conversion: does not exist in peer-type
 	out.DiskSize = in.DiskSize
+	// WARNING: in.DataDisks requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootDiskBusType requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootDiskProvisioningType requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootDiskIops requires manual conversion: does not exist in peer-type
 	out.Bootstrapped = in.Bootstrapped
 	out.EnableNvidiaGPU = in.EnableNvidiaGPU
+	// WARNING: in.GPU requires manual conversion: does not exist in peer-type
 	out.ExtraOvdcNetworks = *(*[]string)(unsafe.Pointer(&in.ExtraOvdcNetworks))
+	// WARNING: in.Networks requires manual conversion: does not exist in peer-type
 	out.VmNamingTemplate = in.VmNamingTemplate
+	// WARNING: in.KubeletConfig requires manual conversion: does not exist in peer-type
+	// WARNING: in.VmGroup requires manual conversion: does not exist in peer-type
+	// WARNING: in.AntiAffinity requires manual conversion: does not exist in peer-type
+	// WARNING: in.MetadataPropagation requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppName requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppOwnership requires manual conversion: does not exist in peer-type
+	// WARNING: in.Ovdc requires manual conversion: does not exist in peer-type
+	// WARNING: in.OvdcNetwork requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapFormat requires manual conversion: does not exist in peer-type
+	// WARNING: in.PrePullImages requires manual conversion: does not exist in peer-type
+	// WARNING: in.PreKubeadmCommandsScriptRef requires manual conversion: does not exist in peer-type
+	// WARNING: in.PostKubeadmCommandsScriptRef requires manual conversion: does not exist in peer-type
+	// WARNING: in.SSHAuthorizedKeys requires manual conversion: does not exist in peer-type
+	// WARNING: in.EmergencyUser requires manual conversion: does not exist in peer-type
+	// WARNING: in.CaptureConsoleScreenshot requires manual conversion: does not exist in peer-type
+	// WARNING: in.ProvisioningTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapRetries requires manual conversion: does not exist in peer-type
+	// WARNING: in.GracefulShutdownTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.SnapshotBeforeUpgrade requires manual conversion: does not exist in peer-type
+	// WARNING: in.SnapshotRetention requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -658,7 +881,18 @@ func autoConvert_v1beta3_VCDMachineStatus_To_v1beta2_VCDMachineStatus(in *v1beta
 	out.PlacementPolicy = in.PlacementPolicy
 	out.NvidiaGPUEnabled = in.NvidiaGPUEnabled
 	out.DiskSize = in.DiskSize
+	// WARNING: in.TemplateHash requires manual conversion: does not exist in peer-type
 	out.Conditions = *(*v1beta1.Conditions)(unsafe.Pointer(&in.Conditions))
+	// WARNING: in.ResolvedReferences requires manual conversion: does not exist in peer-type
+	// WARNING: in.ShutdownStartTime requires manual conversion: does not exist in peer-type
+	// WARNING: in.Hardware requires manual conversion: does not exist in peer-type
+	// WARNING: in.VAppName requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapDiagnostics requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapStartTime requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapRetries requires manual conversion: does not exist in peer-type
+	// WARNING: in.FailureReason requires manual conversion: does not exist in peer-type
+	// WARNING: in.FailureMessage requires manual conversion: does not exist in peer-type
+	// WARNING: in.VMName requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -835,10 +1069,8 @@ func Convert_v1beta2_VCDResourceMap_To_v1beta3_VCDResourceMap(in *VCDResourceMap
 
 func autoConvert_v1beta3_VCDResourceMap_To_v1beta2_VCDResourceMap(in *v1beta3.VCDResourceMap, out *VCDResourceMap, s conversion.Scope) error {
 	out.Ovdcs = *(*VCDResources)(unsafe.Pointer(&in.Ovdcs))
+	// WARNING: in.AppPortProfiles requires manual conversion: does not exist in peer-type
+	// WARNING: in.OvdcNetworks requires manual conversion: does not exist in peer-type
+	// WARNING: in.VApps requires manual conversion: does not exist in peer-type
 	return nil
 }
-
-// Convert_v1beta3_VCDResourceMap_To_v1beta2_VCDResourceMap is an autogenerated conversion function.
-func Convert_v1beta3_VCDResourceMap_To_v1beta2_VCDResourceMap(in *v1beta3.VCDResourceMap, out *VCDResourceMap, s conversion.Scope) error {
-	return autoConvert_v1beta3_VCDResourceMap_To_v1beta2_VCDResourceMap(in, out, s)
-}
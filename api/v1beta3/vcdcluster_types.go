@@ -26,6 +26,11 @@ const (
 	// ClusterFinalizer allows DockerClusterReconciler to clean up resources associated with DockerCluster before
 	// removing it from the apiserver.
 	ClusterFinalizer = "vcdcluster.infrastructure.cluster.x-k8s.io"
+
+	// DeletionProtectedAnnotation protects a cluster from deletion when set on its Cluster or VCDCluster: the webhook
+	// rejects the deletion of the VCDCluster unless it is paused, and the controllers do not delete the VMs, load
+	// balancer, vApp or RDE of the cluster, until the annotation is removed or set to "false".
+	DeletionProtectedAnnotation = "capvcd.vmware.com/deletion-protected"
 )
 
 // APIEndpoint represents a reachable Kubernetes API endpoint.
@@ -41,6 +46,13 @@ type UserCredentialsContext struct {
 	Password     string              `json:"password,omitempty"`
 	RefreshToken string              `json:"refreshToken,omitempty"`
 	SecretRef    *v1.SecretReference `json:"secretRef,omitempty"`
+	// AuthType is the way the user logs into VCD: local for the local and LDAP users of the org with a username and
+	// password or an API token, saml-adfs for SAML users whose username and password are exchanged for an assertion by
+	// the ADFS server of the org, and saml-assertion for SAML users whose bearer or holder-of-key assertion is carried
+	// by the Secret. Defaults to local.
+	// +optional
+	// +kubebuilder:validation:Enum=local;saml-adfs;saml-assertion
+	AuthType string `json:"authType,omitempty"`
 }
 
 // VCDResources stores the latest ID and name of VCD resources for specific resource types.
@@ -49,6 +61,15 @@ type VCDResources []VCDResource
 // VCDResourceMap provides a structured way to store and retrieve information about VCD resources
 type VCDResourceMap struct {
 	Ovdcs VCDResources `json:"ovdcs,omitempty"`
+	// AppPortProfiles are the application port profiles created for the DNAT rules of the cluster, which are deleted
+	// when the cluster is deleted.
+	AppPortProfiles VCDResources `json:"appPortProfiles,omitempty"`
+	// OvdcNetworks are the OVDC networks created for VCDClusterSpec.ManagedNetwork, which are deleted when the cluster
+	// is deleted.
+	OvdcNetworks VCDResources `json:"ovdcNetworks,omitempty"`
+	// VApps are the vApps created for VCDClusterSpec.VAppStrategy, which are deleted once they have no VMs or when the
+	// cluster is deleted.
+	VApps VCDResources `json:"vApps,omitempty"`
 }
 
 // VCDResource restores the data structure for some VCD Resources
@@ -72,11 +93,155 @@ type Ports struct {
 	TCP   int32 `json:"tcp,omitempty"`
 }
 
+// HealthMonitor defines the health monitor of the load balancer pool of the control plane endpoint. VCD only exposes the
+// system-defined health monitors of NSX-T Advanced Load Balancer, whose interval and timeout are set by the provider on
+// the Avi controller.
+type HealthMonitor struct {
+	// Type is the type of the health monitor checking the control plane nodes: TCP checks that the API server port
+	// accepts connections, HTTPS sends a request to the API server, which must answer anonymous requests to its root
+	// path with a 2xx or 3xx status, and PING checks that the nodes answer ICMP echo requests.
+	// +kubebuilder:validation:Enum=TCP;HTTPS;PING
+	// +kubebuilder:default=TCP
+	// +optional
+	Type string `json:"type,omitempty"`
+}
+
+// PersistenceProfile defines the persistence profile of the load balancer pool of the control plane endpoint, which
+// sends the connections of a client to the same control plane node.
+type PersistenceProfile struct {
+	// Type is the type of the persistence profile: ClientIP identifies the clients by their IP address.
+	// +kubebuilder:validation:Enum=ClientIP
+	// +kubebuilder:default=ClientIP
+	// +optional
+	Type string `json:"type,omitempty"`
+}
+
 // LoadBalancerConfig defines load-balancer configuration for the Cluster both for the control plane nodes and for the CPI
 type LoadBalancerConfig struct {
 	// UseOneArm defines the intent to une OneArm when upgrading CAPVCD from 0.5.x to 1.0.0
 	UseOneArm bool   `json:"useOneArm,omitempty"`
 	VipSubnet string `json:"vipSubnet,omitempty"`
+
+	// HealthMonitor is the health monitor of the load balancer pool of the control plane endpoint. A TCP health
+	// monitor is used when not set.
+	// +optional
+	HealthMonitor *HealthMonitor `json:"healthMonitor,omitempty"`
+
+	// PersistenceProfile is the persistence profile of the load balancer pool of the control plane endpoint. The
+	// connections are not persisted when not set.
+	// +optional
+	PersistenceProfile *PersistenceProfile `json:"persistenceProfile,omitempty"`
+
+	// ServiceEngineGroup is the name of the load balancer service engine group, assigned to the edge gateway, on which
+	// the virtual service of the control plane endpoint is created. The first service engine group of the edge
+	// gateway with free capacity is used when not set. An existing virtual service is not moved when it is changed.
+	// +optional
+	ServiceEngineGroup string `json:"serviceEngineGroup,omitempty"`
+}
+
+// MachinePolicies defines the compute and storage policies of the VMs of the Cluster
+type MachinePolicies struct {
+	// SizingPolicy is the name of the sizing policy to be used by VMs which do not set one
+	// +optional
+	SizingPolicy string `json:"sizingPolicy,omitempty"`
+	// PlacementPolicy is the name of the placement policy to be used by VMs which do not set one
+	// +optional
+	PlacementPolicy string `json:"placementPolicy,omitempty"`
+	// StorageProfile is the name of the storage profile to be used by VMs which do not set one
+	// +optional
+	StorageProfile string `json:"storageProfile,omitempty"`
+}
+
+// IPAllocationConfig defines the IP allocation mode of the NIC attached to the OVDC network of the Cluster, per role
+// of the machines
+type IPAllocationConfig struct {
+	// ControlPlane is the IP allocation mode of the control plane machines. The mode of the template is kept when empty.
+	// +kubebuilder:validation:Enum=POOL;DHCP
+	// +optional
+	ControlPlane string `json:"controlPlane,omitempty"`
+	// Workers is the IP allocation mode of the worker machines. The mode of the template is kept when empty.
+	// +kubebuilder:validation:Enum=POOL;DHCP
+	// +optional
+	Workers string `json:"workers,omitempty"`
+	// DHCPPool is the range of the DHCP pool enabled on the OVDC network of the Cluster when a role uses DHCP and the
+	// DHCP service of the network is not enabled. The pool must not overlap the static IP pool of the network. It is
+	// only enabled on networks connected to an edge gateway, and with the rights to edit the network.
+	// +optional
+	DHCPPool *IPRange `json:"dhcpPool,omitempty"`
+}
+
+// EgressAllowlistConfig lists the destinations the nodes of the Cluster must reach besides the VCD endpoint and the
+// proxies, which are always part of the egress allowlist of the Cluster
+type EgressAllowlistConfig struct {
+	// RegistryMirrors are the container registry mirrors pulled from by the nodes, as a host, host:port or URL
+	// +optional
+	RegistryMirrors []string `json:"registryMirrors,omitempty"`
+	// NTPServers are the NTP servers the nodes synchronize their clocks with
+	// +optional
+	NTPServers []string `json:"ntpServers,omitempty"`
+	// OSRepositories are the package repositories of the operating system of the nodes, as a host, host:port or URL
+	// +optional
+	OSRepositories []string `json:"osRepositories,omitempty"`
+	// ProgramEdgeGateway allows the traffic to the destinations of the allowlist on the gateway firewall of the edge
+	// gateway of the OVDC network of the Cluster. The host names of the destinations are resolved by CAPVCD. The
+	// firewall rule is removed when the Cluster is deleted.
+	// +optional
+	ProgramEdgeGateway bool `json:"programEdgeGateway,omitempty"`
+}
+
+// EgressConfig configures the SNAT rule dedicated to the Cluster on the edge gateway of its OVDC network
+type EgressConfig struct {
+	// ExternalIP is the external IP of the edge gateway the traffic of the nodes is translated to. An unused external
+	// IP of the edge gateway is allocated when empty, in LoadBalancerConfigSpec.VipSubnet if set.
+	// +optional
+	ExternalIP string `json:"externalIP,omitempty"`
+	// Priority is the priority of the SNAT rule. A lower value takes precedence over the other SNAT rules of the edge
+	// gateway applying to the network, e.g. the SNAT rule of the org. Defaults to 0, the highest priority.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Priority *int `json:"priority,omitempty"`
+}
+
+// FirewallRule is a rule of the gateway firewall applying to the traffic to a group of addresses of the Cluster
+type FirewallRule struct {
+	// Name is the name of the rule, unique among the rules of the Cluster
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+	// Action is the action applied to the traffic matching the rule
+	// +kubebuilder:validation:Enum=Allow;Drop;Reject
+	// +kubebuilder:default=Allow
+	// +optional
+	Action string `json:"action,omitempty"`
+	// Sources are the IP addresses, IP ranges or CIDRs the traffic comes from. The rule applies to any source when
+	// empty.
+	// +optional
+	Sources []string `json:"sources,omitempty"`
+	// Destination is the group of addresses of the Cluster the traffic goes to: the host of the control plane
+	// endpoint, or the internal IPs of the control plane machines, of the worker machines or of all the machines
+	// +kubebuilder:validation:Enum=controlPlaneEndpoint;controlPlaneNodes;workerNodes;nodes
+	Destination string `json:"destination"`
+	// Protocol is the protocol of the Ports
+	// +kubebuilder:validation:Enum=TCP;UDP
+	// +kubebuilder:default=TCP
+	// +optional
+	Protocol string `json:"protocol,omitempty"`
+	// Ports are the destination ports or port ranges of the traffic, e.g. 6443 or 30000-32767. The rule applies to
+	// any protocol and port when empty.
+	// +optional
+	Ports []string `json:"ports,omitempty"`
+}
+
+// EgressDestination is a destination the nodes of the Cluster must reach
+type EgressDestination struct {
+	// Host is the host name or IP address of the destination
+	Host string `json:"host"`
+	// Port is the port of the destination
+	Port int32 `json:"port"`
+	// Protocol is the transport protocol used to reach the destination, TCP or UDP
+	Protocol string `json:"protocol"`
+	// Purpose is the reason the nodes reach the destination, e.g. vcd, proxy, registry-mirror, ntp or os-repository
+	Purpose string `json:"purpose"`
 }
 
 // VCDClusterSpec defines the desired state of VCDCluster
@@ -88,16 +253,36 @@ type VCDClusterSpec struct {
 	ControlPlaneEndpoint APIEndpoint `json:"controlPlaneEndpoint"`
 	// +kubebuilder:validation:Required
 	Site string `json:"site"`
+	// Org is the name or URN of the org of the cluster. An org referenced by URN is resolved with credentials naming
+	// the org of the user, i.e. org/user.
 	// +kubebuilder:validation:Required
 	Org string `json:"org"`
+	// Ovdc is the name or URN of the OVDC of the cluster.
 	// +kubebuilder:validation:Required
 	Ovdc string `json:"ovdc"`
+	// OvdcNetwork is the name or URN of the OVDC network of the cluster.
 	// +kubebuilder:validation:Required
 	OvdcNetwork string `json:"ovdcNetwork"`
-	// +kubebuilder:validation:Required
+	// ManagedNetwork makes CAPVCD create the OVDC network named OvdcNetwork with this configuration when it does not
+	// exist, instead of requiring a pre-created network. The network created is tracked in
+	// VCDClusterStatus.VcdResourceMap and deleted with the cluster; a pre-existing network is used as is and never
+	// deleted. Changes made once the network exists are not applied to it.
+	// +optional
+	ManagedNetwork *ManagedNetworkConfig `json:"managedNetwork,omitempty"`
+	// UserCredentialsContext are the credentials of the cluster. Required unless IdentityRef is set.
+	// +optional
 	UserCredentialsContext UserCredentialsContext `json:"userContext"`
+	// IdentityRef references the VCDClusterIdentity whose credentials the cluster uses instead of the userContext.
+	// The namespace of the VCDCluster must be allowed by the VCDClusterIdentity.
+	// +optional
+	IdentityRef *VCDClusterIdentityReference `json:"identityRef,omitempty"`
 	// + optional
 	RDEId string `json:"rdeId,omitempty"`
+	// RDEManagementDisabled creates the cluster without an RDE, as in sites without the capvcdCluster entity type or
+	// for users without the rights on it: the cluster gets a self-generated infra ID and CAPVCD never creates,
+	// updates or deletes an RDE for it. It cannot be enabled once the cluster has an RDE.
+	// +optional
+	RDEManagementDisabled bool `json:"rdeManagementDisabled,omitempty"`
 	// +optional
 	ParentUID string `json:"parentUid,omitempty"`
 	// +optional
@@ -107,6 +292,240 @@ type VCDClusterSpec struct {
 	ProxyConfigSpec ProxyConfig `json:"proxyConfigSpec,omitempty"`
 	// +optional
 	LoadBalancerConfigSpec LoadBalancerConfig `json:"loadBalancerConfigSpec,omitempty"`
+	// DefaultMachinePolicies are the policies inherited by all the VCDMachines of the Cluster which omit them.
+	// Policies set in a VCDMachine or VCDMachineTemplate take precedence over these.
+	// +optional
+	DefaultMachinePolicies MachinePolicies `json:"defaultMachinePolicies,omitempty"`
+	// IPAllocation configures the IP allocation mode of the control plane and worker machines on the OVDC network of
+	// the Cluster, e.g. to give predictable addresses to the control plane machines only. It applies to the machines
+	// created after it is set, and not to machines setting VCDMachineSpec.Networks.
+	// +optional
+	IPAllocation IPAllocationConfig `json:"ipAllocation,omitempty"`
+	// EgressAllowlist configures the destinations of the egress allowlist of the Cluster, reported in
+	// VCDClusterStatus.EgressAllowlist, and whether it is programmed on the edge gateway.
+	// +optional
+	EgressAllowlist EgressAllowlistConfig `json:"egressAllowlist,omitempty"`
+	// EgressConfig makes CAPVCD maintain a SNAT rule on the edge gateway translating the traffic leaving the OVDC
+	// network of the Cluster to an external IP dedicated to the Cluster, instead of the IPs of the SNAT rules shared by
+	// the org, so that the egress of the Cluster can be allowlisted on its own. The rule is removed when the field is
+	// unset or the Cluster is deleted.
+	// +optional
+	EgressConfig *EgressConfig `json:"egressConfig,omitempty"`
+	// FirewallRules are rules of the gateway firewall of the edge gateway of the OVDC network of the Cluster, e.g. to
+	// make the control plane endpoint reachable from given CIDRs only. The rules are placed in order before the other
+	// user defined rules of the edge gateway, and are removed when they are removed from the list or the Cluster is
+	// deleted.
+	// +optional
+	FirewallRules []FirewallRule `json:"firewallRules,omitempty"`
+	// VerifyControlPlaneEndpoint makes the machines check, from the guest and bypassing the proxies, that the API
+	// server answers on the control plane endpoint: after kubeadm init on the first control plane machine and before
+	// kubeadm join on the other machines. A machine failing the check gets the ControlPlaneEndpointReachable condition
+	// set to false, which points at a misconfigured load balancer rather than at a bootstrap timeout.
+	// +optional
+	VerifyControlPlaneEndpoint bool `json:"verifyControlPlaneEndpoint,omitempty"`
+	// ControlPlaneEndpointMode defines how the control plane endpoint of the Cluster is provided. In the Managed mode,
+	// the default, CAPVCD creates an NSX-T ALB virtual service and pool for the control plane machines. In the
+	// Passthrough mode, ControlPlaneEndpoint must be set to the endpoint of a load balancer managed outside of CAPVCD,
+	// e.g. F5 or HAProxy, and no virtual service, pool, DNAT rule or IP is created or allocated on the edge gateway; the
+	// external load balancer must route to the control plane machines. In the DNAT mode, for edge gateways without an
+	// ALB service engine group, CAPVCD creates a DNAT rule forwarding an external IP of the edge gateway to one of the
+	// control plane machines, and moves it to another control plane machine when that machine is deleted. In the Routed
+	// mode, for OVDC networks routed without an NSX-T edge gateway, no edge gateway is looked up: ControlPlaneEndpoint
+	// is either a static IP set by the user, announced from the control plane machines by kube-vip, or else an address
+	// of the static IP pool of the network the first control plane machine is created with. In the KubeVip mode, no
+	// edge load balancer is provisioned either: ControlPlaneEndpoint is a virtual IP announced from the control plane
+	// machines by kube-vip, which is reserved by CAPVCD from the static IP pool of the network unless set by the user.
+	// +kubebuilder:validation:Enum=Managed;Passthrough;DNAT;Routed;KubeVip
+	// +optional
+	ControlPlaneEndpointMode string `json:"controlPlaneEndpointMode,omitempty"`
+	// KubeVip configures kube-vip, which announces the virtual IP of the control plane endpoint from the control plane
+	// machines when the endpoint is not provided by a load balancer.
+	// +optional
+	KubeVip KubeVipConfig `json:"kubeVip,omitempty"`
+	// PinSiteCertificate enables trust on first use of the certificate chain of the VCD site: the fingerprints of the
+	// certificates presented by the site are recorded in SiteCertificateFingerprints on first contact, and the
+	// controllers refuse to send the credentials of the cluster to a site presenting a chain without any of them.
+	// +optional
+	PinSiteCertificate bool `json:"pinSiteCertificate,omitempty"`
+	// SiteCertificateFingerprints are the SHA-256 fingerprints of the trusted certificates of the VCD site, in the
+	// format of `openssl x509 -noout -fingerprint -sha256`. They are set on first contact when PinSiteCertificate is
+	// enabled, and can be set in advance to pin known certificates; clear them to trust a renewed certificate.
+	// +optional
+	SiteCertificateFingerprints []string `json:"siteCertificateFingerprints,omitempty"`
+	// VCDTrustBundleSecretRef references a Secret with the PEM certificates of the CAs trusted to issue the certificate
+	// of the VCD site, in its ca.crt key. The Secret is looked up in the namespace of the VCDCluster when its namespace
+	// is omitted. The controllers verify the certificate of the site against the bundle instead of skipping the
+	// verification, and the bundle is added to the trusted CAs of the machines, e.g. for private registries.
+	// +optional
+	VCDTrustBundleSecretRef *v1.SecretReference `json:"vcdTrustBundleSecretRef,omitempty"`
+	// MetadataPropagation copies the selected labels and annotations of the Cluster to the metadata of the vApp of the
+	// cluster and of the VMs of its machines, and keeps the metadata in sync with them.
+	// +optional
+	MetadataPropagation *MetadataPropagationSpec `json:"metadataPropagation,omitempty"`
+	// TemplateCache copies the templates of the machines of the cluster into a catalog of the org of the cluster on
+	// first use, and creates the VMs from the cached copies, which speeds up the cloning of templates stored in
+	// another OVDC or shared from another org.
+	// +optional
+	TemplateCache *TemplateCacheSpec `json:"templateCache,omitempty"`
+	// ControlPlaneSizingPolicy is the sizing policy the control plane machines are moved to. When it differs from the
+	// sizing policy of the VCDMachineTemplate of the KubeadmControlPlane, the template is cloned with the new sizing
+	// policy and the KubeadmControlPlane is rolled out to the clone one machine at a time, once the etcd cluster and
+	// the control plane components are healthy. The progress is reported by the ControlPlaneSized condition.
+	// +optional
+	ControlPlaneSizingPolicy string `json:"controlPlaneSizingPolicy,omitempty"`
+	// VAppName is the name of the vApp of the cluster, which defaults to the name of the VCDCluster. It is set to adopt
+	// a pre-existing vApp, e.g. of a cluster built by hand: the vApp is used as is if it exists, and is then managed by
+	// CAPVCD like the vApps it creates, including its deletion with the cluster. Immutable field.
+	// +optional
+	VAppName string `json:"vAppName,omitempty"`
+	// IPPoolExtension is a range of addresses of the subnet of the OVDC network of the cluster which is added to the
+	// static IP pool of the network once the pool runs out of addresses, if the user of the cluster has the rights to
+	// edit the network. No VMs are created for the cluster while the pool is exhausted, with or without an extension.
+	// +optional
+	IPPoolExtension *IPRange `json:"ipPoolExtension,omitempty"`
+	// TemplateSources are OVAs imported into catalogs of the org of the cluster when the templates they provide are
+	// missing, so that the templates of the machines need not be uploaded by hand before the cluster is created. The
+	// progress of the imports is reported in the TemplateImports of the status.
+	// +optional
+	TemplateSources []TemplateSource `json:"templateSources,omitempty"`
+	// VAppStrategy spreads the worker machines of the cluster across several vApps created by CAPVCD, as a vApp
+	// degrades beyond a number of VMs. All the machines are created in the vApp of the cluster when omitted. A change
+	// only applies to the machines created afterwards.
+	// +optional
+	VAppStrategy *VAppStrategy `json:"vAppStrategy,omitempty"`
+	// VmNamingTemplate is the Go template of the names of the VMs of the machines of the cluster whose VCDMachine has
+	// no VmNamingTemplate. The template is given the cluster name as .clusterName, the name of the MachineDeployment
+	// of the machine, if any, as .machineDeployment, and the .machine, .vcdMachine and .vcdCluster objects. Functions
+	// of the Sprig library are supported. See https://github.com/Masterminds/sprig. The generated names are lowercased,
+	// and those longer than 63 characters are truncated and suffixed with a hash of the full name to remain unique.
+	// The name of a VM is recorded in the status of its VCDMachine when the VM is created, so a change only applies to
+	// the machines created afterwards. machine.Name is used as VM name when this field is empty.
+	// +optional
+	VmNamingTemplate string `json:"vmNamingTemplate,omitempty"`
+	// VAppNamingTemplate is the Go template of the name of the vApp of the cluster, which is given the cluster name as
+	// .clusterName and the .vcdCluster object, with the same functions and length rules as VmNamingTemplate. It is
+	// ignored when VAppName is set. The name is recorded in the status of the VCDCluster once, so a change does not
+	// rename the vApp of an existing cluster. A template which fails to generate a name is rejected. The name of the
+	// VCDCluster is used when this field is empty.
+	// +optional
+	VAppNamingTemplate string `json:"vAppNamingTemplate,omitempty"`
+}
+
+// VAppStrategy defines how the machines of a cluster are spread across vApps.
+type VAppStrategy struct {
+	// Type is Single to create all the machines in the vApp of the cluster, PerMachineDeployment to create the
+	// machines of each MachineDeployment in a vApp named <vApp of the cluster>-<MachineDeployment>, or Sharded to fill
+	// vApps named <vApp of the cluster>-shard-<n> with Sharded.Size machines each. The control plane machines and the
+	// machines set to a vApp by their VCDMachine are not affected.
+	// +kubebuilder:validation:Enum=Single;PerMachineDeployment;Sharded
+	Type string `json:"type"`
+
+	// Sharded configures the Sharded type.
+	// +optional
+	Sharded *VAppSharding `json:"sharded,omitempty"`
+}
+
+// VAppSharding configures the vApps of the Sharded strategy.
+type VAppSharding struct {
+	// Size is the number of machines created in each vApp.
+	// +kubebuilder:validation:Minimum=1
+	Size int32 `json:"size"`
+}
+
+// KubeVipConfig configures the kube-vip static pods of the control plane machines.
+type KubeVipConfig struct {
+	// Image is the kube-vip image run on the control plane machines. A kube-vip release tested with CAPVCD is used when
+	// omitted.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Interface is the network interface of the control plane machines the virtual IP is announced on. kube-vip uses
+	// the interface of the default route when omitted.
+	// +optional
+	Interface string `json:"interface,omitempty"`
+}
+
+// TemplateSource is an OVA providing a template of a catalog.
+type TemplateSource struct {
+	// Catalog is the name of the catalog of the org of the cluster the template is imported into. The catalog must
+	// exist.
+	// +kubebuilder:validation:MinLength=1
+	Catalog string `json:"catalog"`
+
+	// Template is the name of the template, as referenced by the VCDMachineTemplates.
+	// +kubebuilder:validation:MinLength=1
+	Template string `json:"template"`
+
+	// URL is the HTTP or HTTPS URL the OVA is downloaded from, e.g. a pre-signed URL of an S3 object.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// Checksum is the SHA-256 digest of the OVA, in the form sha256:<hex>. The OVA is not imported if its digest
+	// differs.
+	// +kubebuilder:validation:Pattern=`^sha256:[a-fA-F0-9]{64}$`
+	Checksum string `json:"checksum"`
+}
+
+// ManagedNetworkConfig is the configuration of the OVDC network CAPVCD creates for a cluster.
+type ManagedNetworkConfig struct {
+	// EdgeGateway is the name of the edge gateway of the OVDC the network is routed through. The network is isolated
+	// when empty.
+	// +optional
+	EdgeGateway string `json:"edgeGateway,omitempty"`
+
+	// GatewayCIDR is the gateway address of the network with the prefix length of its subnet, e.g. 192.168.10.1/24.
+	// +kubebuilder:validation:MinLength=1
+	GatewayCIDR string `json:"gatewayCIDR"`
+
+	// DNSServers are the DNS servers of the network.
+	// +kubebuilder:validation:MaxItems=2
+	// +optional
+	DNSServers []string `json:"dnsServers,omitempty"`
+
+	// DNSSuffix is the DNS suffix of the network.
+	// +optional
+	DNSSuffix string `json:"dnsSuffix,omitempty"`
+
+	// StaticIPPool are the ranges of the static IP pool of the network, in its subnet.
+	// +optional
+	StaticIPPool []IPRange `json:"staticIPPool,omitempty"`
+}
+
+// IPRange is a range of IP addresses.
+type IPRange struct {
+	// StartAddress is the first address of the range.
+	// +kubebuilder:validation:MinLength=1
+	StartAddress string `json:"startAddress"`
+
+	// EndAddress is the last address of the range.
+	// +kubebuilder:validation:MinLength=1
+	EndAddress string `json:"endAddress"`
+}
+
+// TemplateCacheSpec defines the catalog the templates of the machines are cached in.
+type TemplateCacheSpec struct {
+	// Catalog is the name of the catalog of the org of the cluster the templates are copied to. It is created if it
+	// does not exist, and can be shared by several clusters of the org.
+	// +kubebuilder:validation:MinLength=1
+	Catalog string `json:"catalog"`
+
+	// StorageProfile is the storage profile of the OVDC of the cluster the catalog is created on. The catalog is
+	// created on the default storage of the org when omitted. It is ignored if the catalog exists.
+	// +optional
+	StorageProfile string `json:"storageProfile,omitempty"`
+}
+
+// MetadataPropagationSpec selects the labels and annotations of CAPI objects which are copied to the metadata of VCD
+// objects.
+type MetadataPropagationSpec struct {
+	// Labels are the keys of the labels copied to metadata entries of the same key.
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+
+	// Annotations are the keys of the annotations copied to metadata entries of the same key. An annotation takes
+	// precedence over a label of the same key.
+	// +optional
+	Annotations []string `json:"annotations,omitempty"`
 }
 
 // VCDClusterStatus defines the observed state of VCDCluster
@@ -159,11 +578,159 @@ type VCDClusterStatus struct {
 
 	// +optional
 	LoadBalancerConfig LoadBalancerConfig `json:"loadBalancerConfig,omitempty"`
+
+	// EgressIPs are the external addresses of the SNAT rules translating the traffic leaving the OVDC network of the
+	// cluster. Each entry is a single IP or a network CIDR.
+	// +optional
+	EgressIPs []string `json:"egressIPs,omitempty"`
+
+	// EgressAllowlist are the destinations the nodes of the cluster must reach to be bootstrapped and run, to be
+	// allowed by the firewalls between the cluster and these destinations.
+	// +optional
+	EgressAllowlist []EgressDestination `json:"egressAllowlist,omitempty"`
+
+	// EgressSNATIP is the external IP of the SNAT rule maintained for VCDClusterSpec.EgressConfig.
+	// +optional
+	EgressSNATIP string `json:"egressSNATIP,omitempty"`
+
+	// FirewallRules are the names of the VCDClusterSpec.FirewallRules in place on the edge gateway.
+	// +optional
+	FirewallRules []string `json:"firewallRules,omitempty"`
+
+	// ResolvedReferences are the name and URN of the org, OVDC and OVDC network referenced by the spec, by name or URN.
+	// +optional
+	ResolvedReferences VCDResources `json:"resolvedReferences,omitempty"`
+
+	// LastSubsystemRuns are the last times the subsystems of the controllers acting on VCD ran for the cluster. None of
+	// them runs while the cluster is paused.
+	// +optional
+	LastSubsystemRuns SubsystemRunTimes `json:"lastSubsystemRuns,omitempty"`
+
+	// KubernetesVersion is the Kubernetes version of the control plane of the cluster.
+	// +optional
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+
+	// ControlPlaneReplicas is the number of control plane machines of the cluster.
+	// +optional
+	ControlPlaneReplicas int32 `json:"controlPlaneReplicas,omitempty"`
+
+	// ReadyControlPlaneReplicas is the number of control plane machines of the cluster whose node is ready.
+	// +optional
+	ReadyControlPlaneReplicas int32 `json:"readyControlPlaneReplicas,omitempty"`
+
+	// WorkerReplicas is the number of worker machines of the machine deployments of the cluster.
+	// +optional
+	WorkerReplicas int32 `json:"workerReplicas,omitempty"`
+
+	// ReadyWorkerReplicas is the number of worker machines of the machine deployments of the cluster whose node is
+	// ready.
+	// +optional
+	ReadyWorkerReplicas int32 `json:"readyWorkerReplicas,omitempty"`
+
+	// ControlPlaneVIP is the host of the control plane endpoint of the cluster, usually the virtual IP of its load
+	// balancer.
+	// +optional
+	ControlPlaneVIP string `json:"controlPlaneVIP,omitempty"`
+
+	// PhaseTransitions are the times at which the cluster went through the key transitions of its lifecycle.
+	// +optional
+	PhaseTransitions ClusterPhaseTransitions `json:"phaseTransitions,omitempty"`
+
+	// TemplateImports are the imports of the TemplateSources of the cluster.
+	// +optional
+	TemplateImports []TemplateImportStatus `json:"templateImports,omitempty"`
+
+	// ControlPlaneMachineEndpoint is set in the Routed mode of the control plane endpoint when the endpoint is not a
+	// virtual IP but the address of the static IP pool of the OVDC network picked for the first control plane machine.
+	// +optional
+	ControlPlaneMachineEndpoint bool `json:"controlPlaneMachineEndpoint,omitempty"`
+
+	// ReservedControlPlaneVIP is the virtual IP of the control plane endpoint reserved in the KubeVip mode by removing
+	// it from the static IP pool of the OVDC network. The address is returned to the pool when the cluster is deleted.
+	// +optional
+	ReservedControlPlaneVIP string `json:"reservedControlPlaneVIP,omitempty"`
+
+	// VAppName is the name of the vApp of the cluster generated from VCDClusterSpec.VAppNamingTemplate.
+	// +optional
+	VAppName string `json:"vAppName,omitempty"`
+}
+
+// TemplateImportStatus is the progress of the import of a TemplateSource.
+type TemplateImportStatus struct {
+	// Catalog is the catalog the template is imported into.
+	Catalog string `json:"catalog"`
+
+	// Template is the name of the template.
+	Template string `json:"template"`
+
+	// Phase is the phase of the import: Downloading, Uploading, Importing, Ready or Failed.
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Progress is the percentage of the OVA downloaded or uploaded in the current phase.
+	// +optional
+	Progress string `json:"progress,omitempty"`
+
+	// Message describes why the import failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastTransitionTime is the last time the phase of the import changed.
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// ClusterPhaseTransitions are the times of the key transitions of the lifecycle of a cluster. Each of them is recorded
+// once, the first time the transition is observed.
+type ClusterPhaseTransitions struct {
+	// InfrastructureReady is the time the VCD infrastructure of the cluster first became ready.
+	// +optional
+	InfrastructureReady *metav1.Time `json:"infrastructureReady,omitempty"`
+
+	// ControlPlaneInitialized is the time the control plane of the cluster was initialized.
+	// +optional
+	ControlPlaneInitialized *metav1.Time `json:"controlPlaneInitialized,omitempty"`
+
+	// FirstWorkerReady is the time the node of a worker machine of the cluster first became ready.
+	// +optional
+	FirstWorkerReady *metav1.Time `json:"firstWorkerReady,omitempty"`
+
+	// DeletionStarted is the time the deletion of the cluster was requested.
+	// +optional
+	DeletionStarted *metav1.Time `json:"deletionStarted,omitempty"`
+}
+
+// SubsystemRunTimes are the last times the subsystems of the controllers acting on VCD ran for a cluster.
+type SubsystemRunTimes struct {
+	// Infrastructure is the last time the VCD infrastructure of the cluster was reconciled.
+	// +optional
+	Infrastructure *metav1.Time `json:"infrastructure,omitempty"`
+
+	// RDE is the last time the status of the cluster was reported in its RDE.
+	// +optional
+	RDE *metav1.Time `json:"rde,omitempty"`
+
+	// RDEDesiredStateSync is the last time the desired state of the cluster was read from its RDE.
+	// +optional
+	RDEDesiredStateSync *metav1.Time `json:"rdeDesiredStateSync,omitempty"`
+
+	// Audit is the last time the infrastructure of the cluster was audited.
+	// +optional
+	Audit *metav1.Time `json:"audit,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=`.metadata.labels['cluster\.x-k8s\.io/cluster-name']`,description="Cluster to which this VCDCluster belongs"
+// +kubebuilder:printcolumn:name="Ready",type="boolean",JSONPath=".status.ready",description="Cluster infrastructure is ready"
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.kubernetesVersion",description="Kubernetes version of the control plane"
+// +kubebuilder:printcolumn:name="CP Ready",type="integer",JSONPath=".status.readyControlPlaneReplicas",description="Number of ready control plane machines"
+// +kubebuilder:printcolumn:name="CP",type="integer",JSONPath=".status.controlPlaneReplicas",description="Number of control plane machines"
+// +kubebuilder:printcolumn:name="Workers Ready",type="integer",JSONPath=".status.readyWorkerReplicas",description="Number of ready worker machines"
+// +kubebuilder:printcolumn:name="Workers",type="integer",JSONPath=".status.workerReplicas",description="Number of worker machines"
+// +kubebuilder:printcolumn:name="VIP",type="string",JSONPath=".status.controlPlaneVIP",description="Host of the control plane endpoint"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of VCDCluster"
 // VCDCluster is the Schema for the vcdclusters API
 type VCDCluster struct {
 	metav1.TypeMeta   `json:",inline"`
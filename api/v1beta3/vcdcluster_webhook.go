@@ -17,18 +17,36 @@ limitations under the License.
 package v1beta3
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
+// NoRDEInfraIDPrefix is the prefix of the infra IDs of the clusters without an RDE.
+const NoRDEInfraIDPrefix = "NO_RDE_"
+
 // log is for logging in this package.
 var vcdclusterlog = logf.Log.WithName("vcdcluster-resource")
 
 func (r *VCDCluster) SetupWebhookWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
+		WithValidator(&vcdClusterValidator{reader: mgr.GetAPIReader()}).
 		Complete()
 }
 
@@ -44,15 +62,22 @@ func (r *VCDCluster) Default() {
 	// TODO(user): fill in your defaulting logic.
 }
 
-// TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
-// +kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta3-vcdcluster,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=vcdclusters,verbs=create;update,versions=v1beta3,name=validation.vcdcluster.infrastructure.cluster.x-k8s.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta3-vcdcluster,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=vcdclusters,verbs=create;update;delete,versions=v1beta3,name=validation.vcdcluster.infrastructure.cluster.x-k8s.io,admissionReviewVersions=v1
 var _ webhook.Validator = &VCDCluster{}
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (r *VCDCluster) ValidateCreate() error {
 	vcdclusterlog.Info("validate create", "name", r.Name)
 
-	// TODO(user): fill in your validation logic upon object creation.
+	if r.Spec.RDEManagementDisabled && r.Spec.RDEId != "" && !strings.HasPrefix(r.Spec.RDEId, NoRDEInfraIDPrefix) {
+		return fmt.Errorf("VCDCluster [%s] cannot disable the RDE management and use RDE [%s]", r.Name, r.Spec.RDEId)
+	}
+	if err := validateVAppStrategy(r.Spec.VAppStrategy); err != nil {
+		return fmt.Errorf("VCDCluster [%s] has an invalid vAppStrategy: [%v]", r.Name, err)
+	}
+	if err := validateVAppNamingTemplate(r); err != nil {
+		return fmt.Errorf("VCDCluster [%s] has an invalid vAppNamingTemplate: [%v]", r.Name, err)
+	}
 	return nil
 }
 
@@ -60,14 +85,160 @@ func (r *VCDCluster) ValidateCreate() error {
 func (r *VCDCluster) ValidateUpdate(old runtime.Object) error {
 	vcdclusterlog.Info("validate update", "name", r.Name)
 
-	// TODO(user): fill in your validation logic upon object update.
+	oldVCDCluster, ok := old.(*VCDCluster)
+	if !ok {
+		return fmt.Errorf("expected a VCDCluster but got [%T]", old)
+	}
+	// the RDE of a cluster is only created along with its infra ID, so a cluster having one keeps it
+	infraID := oldVCDCluster.Status.InfraId
+	if r.Spec.RDEManagementDisabled && !oldVCDCluster.Spec.RDEManagementDisabled && infraID != "" &&
+		!strings.HasPrefix(infraID, NoRDEInfraIDPrefix) {
+		return fmt.Errorf("VCDCluster [%s] cannot disable the RDE management as it already has RDE [%s]", r.Name,
+			infraID)
+	}
+	if err := validateVAppStrategy(r.Spec.VAppStrategy); err != nil {
+		return fmt.Errorf("VCDCluster [%s] has an invalid vAppStrategy: [%v]", r.Name, err)
+	}
+	// the name of the vApp is recorded once, so that a template changed afterwards is not used anymore
+	if r.Spec.VAppNamingTemplate != oldVCDCluster.Spec.VAppNamingTemplate && r.Status.VAppName == "" {
+		if err := validateVAppNamingTemplate(r); err != nil {
+			return fmt.Errorf("VCDCluster [%s] has an invalid vAppNamingTemplate: [%v]", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateVAppNamingTemplate checks that the VAppNamingTemplate of the VCDCluster generates a name for its vApp, as the
+// controllers do when the VAppName is not set.
+func validateVAppNamingTemplate(vcdCluster *VCDCluster) error {
+	if vcdCluster.Spec.VAppName != "" || vcdCluster.Spec.VAppNamingTemplate == "" {
+		return nil
+	}
+	nameTemplate, err := template.New("vAppNamingTemplate").
+		Funcs(sprig.TxtFuncMap()).
+		Parse(vcdCluster.Spec.VAppNamingTemplate)
+	if err != nil {
+		return fmt.Errorf("unable to parse the template: [%v]", err)
+	}
+	buf := new(bytes.Buffer)
+	if err = nameTemplate.Execute(buf, map[string]interface{}{
+		"clusterName": vcdCluster.Name,
+		"vcdCluster":  vcdCluster,
+	}); err != nil {
+		return fmt.Errorf("unable to generate a name with the template: [%v]", err)
+	}
+	if strings.Trim(strings.TrimSpace(buf.String()), "-") == "" {
+		return fmt.Errorf("the template generates an empty name")
+	}
+	return nil
+}
+
+// validateVAppStrategy checks that the size of the vApps is set with the Sharded strategy only.
+func validateVAppStrategy(vAppStrategy *VAppStrategy) error {
+	if vAppStrategy == nil {
+		return nil
+	}
+	if vAppStrategy.Type == "Sharded" && vAppStrategy.Sharded == nil {
+		return fmt.Errorf("sharded.size must be set with the Sharded type")
+	}
+	if vAppStrategy.Type != "Sharded" && vAppStrategy.Sharded != nil {
+		return fmt.Errorf("sharded can only be set with the Sharded type, not [%s]", vAppStrategy.Type)
+	}
 	return nil
 }
 
+// HasDeletionProtectedAnnotation checks if the object is protected from deletion by the DeletionProtectedAnnotation.
+func HasDeletionProtectedAnnotation(obj metav1.Object) bool {
+	value, ok := obj.GetAnnotations()[DeletionProtectedAnnotation]
+	return ok && value != "false"
+}
+
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
 func (r *VCDCluster) ValidateDelete() error {
 	vcdclusterlog.Info("validate delete", "name", r.Name)
 
-	// TODO(user): fill in your validation logic upon object deletion.
+	// a paused VCDCluster is deleted from the source management cluster by clusterctl move once moved
+	if _, paused := r.Annotations[clusterv1.PausedAnnotation]; paused {
+		return nil
+	}
+	if HasDeletionProtectedAnnotation(r) {
+		return fmt.Errorf("VCDCluster [%s] is protected from deletion; remove its annotation [%s] to delete it",
+			r.Name, DeletionProtectedAnnotation)
+	}
+	return nil
+}
+
+// vcdClusterValidator validates the VCDClusters like VCDCluster, and checks that their namespace is allowed to use the
+// VCDClusterIdentity they reference, which requires reading the VCDClusterIdentity and the namespace.
+type vcdClusterValidator struct {
+	reader client.Reader
+}
+
+var _ admission.CustomValidator = &vcdClusterValidator{}
+
+func (v *vcdClusterValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	vcdCluster, ok := obj.(*VCDCluster)
+	if !ok {
+		return fmt.Errorf("expected a VCDCluster but got [%T]", obj)
+	}
+	if err := vcdCluster.ValidateCreate(); err != nil {
+		return err
+	}
+	return v.validateIdentityRef(ctx, vcdCluster)
+}
+
+func (v *vcdClusterValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	vcdCluster, ok := newObj.(*VCDCluster)
+	if !ok {
+		return fmt.Errorf("expected a VCDCluster but got [%T]", newObj)
+	}
+	if err := vcdCluster.ValidateUpdate(oldObj); err != nil {
+		return err
+	}
+	// the identity is only checked when it changes so that a cluster whose namespace is no longer allowed can still
+	// be updated, e.g. to be deleted
+	oldVCDCluster, ok := oldObj.(*VCDCluster)
+	if ok && reflect.DeepEqual(oldVCDCluster.Spec.IdentityRef, vcdCluster.Spec.IdentityRef) {
+		return nil
+	}
+	return v.validateIdentityRef(ctx, vcdCluster)
+}
+
+func (v *vcdClusterValidator) ValidateDelete(_ context.Context, obj runtime.Object) error {
+	vcdCluster, ok := obj.(*VCDCluster)
+	if !ok {
+		return fmt.Errorf("expected a VCDCluster but got [%T]", obj)
+	}
+	return vcdCluster.ValidateDelete()
+}
+
+// validateIdentityRef checks that the VCDCluster does not mix its own credentials with the ones of a
+// VCDClusterIdentity, and that the VCDClusterIdentity allows the namespace of the VCDCluster.
+func (v *vcdClusterValidator) validateIdentityRef(ctx context.Context, vcdCluster *VCDCluster) error {
+	identityRef := vcdCluster.Spec.IdentityRef
+	if identityRef == nil {
+		return nil
+	}
+	if !reflect.DeepEqual(vcdCluster.Spec.UserCredentialsContext, UserCredentialsContext{}) {
+		return fmt.Errorf("VCDCluster [%s] cannot set both userContext and identityRef", vcdCluster.Name)
+	}
+	identity := &VCDClusterIdentity{}
+	if err := v.reader.Get(ctx, types.NamespacedName{Name: identityRef.Name}, identity); err != nil {
+		return fmt.Errorf("unable to get VCDClusterIdentity [%s] of VCDCluster [%s]: [%v]", identityRef.Name,
+			vcdCluster.Name, err)
+	}
+	namespace := &v1.Namespace{}
+	if err := v.reader.Get(ctx, types.NamespacedName{Name: vcdCluster.Namespace}, namespace); err != nil {
+		return fmt.Errorf("unable to get namespace [%s] of VCDCluster [%s]: [%v]", vcdCluster.Namespace,
+			vcdCluster.Name, err)
+	}
+	allowed, err := identity.AllowsNamespace(namespace)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("VCDClusterIdentity [%s] does not allow namespace [%s] of VCDCluster [%s]",
+			identityRef.Name, vcdCluster.Namespace, vcdCluster.Name)
+	}
 	return nil
 }
@@ -0,0 +1,109 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta3
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// VCDClusterIdentityReference references the VCDClusterIdentity whose credentials a VCDCluster uses.
+type VCDClusterIdentityReference struct {
+	// Name of the VCDClusterIdentity.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// AllowedNamespaces are the namespaces whose VCDClusters may use a VCDClusterIdentity.
+type AllowedNamespaces struct {
+	// NamespaceList are the names of the allowed namespaces.
+	// +optional
+	NamespaceList []string `json:"list,omitempty"`
+
+	// Selector selects the allowed namespaces by label. An empty selector selects all the namespaces.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// VCDClusterIdentitySpec defines the desired state of VCDClusterIdentity
+type VCDClusterIdentitySpec struct {
+	// SecretRef is the Secret carrying the credentials of the identity, under the keys of the Secret referenced by
+	// the userContext of a VCDCluster. As the VCDClusterIdentity is cluster-scoped, the namespace of the Secret is
+	// required; it should only be readable by the platform team and CAPVCD.
+	// +kubebuilder:validation:Required
+	SecretRef v1.SecretReference `json:"secretRef"`
+
+	// AuthType is the way the user of the identity logs into VCD, as in the userContext of a VCDCluster. Defaults to
+	// local.
+	// +optional
+	// +kubebuilder:validation:Enum=local;saml-adfs;saml-assertion
+	AuthType string `json:"authType,omitempty"`
+
+	// AllowedNamespaces are the namespaces whose VCDClusters may use the identity: the namespaces of the list and the
+	// namespaces matching the selector. No namespace may use the identity if omitted.
+	// +optional
+	AllowedNamespaces *AllowedNamespaces `json:"allowedNamespaces,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=vcdclusteridentities,scope=Cluster,categories=cluster-api
+// +kubebuilder:storageversion
+// VCDClusterIdentity is the Schema for the vcdclusteridentities API. It lets a platform team own the VCD credentials
+// used by the VCDClusters of the tenant namespaces.
+type VCDClusterIdentity struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec VCDClusterIdentitySpec `json:"spec,omitempty"`
+}
+
+// AllowsNamespace checks if the VCDClusters of the namespace may use the identity.
+func (i *VCDClusterIdentity) AllowsNamespace(namespace *v1.Namespace) (bool, error) {
+	allowedNamespaces := i.Spec.AllowedNamespaces
+	if allowedNamespaces == nil {
+		return false, nil
+	}
+	for _, name := range allowedNamespaces.NamespaceList {
+		if name == namespace.Name {
+			return true, nil
+		}
+	}
+	if allowedNamespaces.Selector == nil {
+		return false, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(allowedNamespaces.Selector)
+	if err != nil {
+		return false, fmt.Errorf("invalid namespace selector of VCDClusterIdentity [%s]: [%v]", i.Name, err)
+	}
+	return selector.Matches(labels.Set(namespace.Labels)), nil
+}
+
+//+kubebuilder:object:root=true
+
+// VCDClusterIdentityList contains a list of VCDClusterIdentity
+type VCDClusterIdentityList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VCDClusterIdentity `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VCDClusterIdentity{}, &VCDClusterIdentityList{})
+}
@@ -0,0 +1,5 @@
+package v1beta3
+
+func (*VCDClusterTemplate) Hub() {}
+
+func (*VCDClusterTemplateList) Hub() {}
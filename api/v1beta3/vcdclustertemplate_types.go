@@ -0,0 +1,62 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// VCDClusterTemplateSpec defines the desired state of VCDClusterTemplate
+type VCDClusterTemplateSpec struct {
+	Template VCDClusterTemplateResource `json:"template"`
+}
+
+// VCDClusterTemplateResource describes the VCDClusters created from a VCDClusterTemplate by the topology controller of
+// a ClusterClass.
+type VCDClusterTemplateResource struct {
+	// Standard object's metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
+	// +optional
+	ObjectMeta clusterv1.ObjectMeta `json:"metadata,omitempty"`
+	// Spec is the specification of the desired behavior of the cluster.
+	Spec VCDClusterSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:storageversion
+
+// VCDClusterTemplate is the Schema for the vcdclustertemplates API
+type VCDClusterTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec VCDClusterTemplateSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// VCDClusterTemplateList contains a list of VCDClusterTemplate
+type VCDClusterTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VCDClusterTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VCDClusterTemplate{}, &VCDClusterTemplateList{})
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta3
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/cluster-api/util/topology"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var vcdclustertemplatelog = logf.Log.WithName("vcdclustertemplate-resource")
+
+func (r *VCDClusterTemplate) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&vcdClusterTemplateValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta3-vcdclustertemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=vcdclustertemplates,verbs=create;update,versions=v1beta3,name=validation.vcdclustertemplate.infrastructure.cluster.x-k8s.io,admissionReviewVersions=v1
+var _ webhook.Validator = &VCDClusterTemplate{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type. The template is shared by
+// all the clusters of a ClusterClass, so it cannot reference the RDE or the vApp of a single cluster.
+func (r *VCDClusterTemplate) ValidateCreate() error {
+	vcdclustertemplatelog.Info("validate create", "name", r.Name)
+
+	spec := r.Spec.Template.Spec
+	if spec.RDEId != "" {
+		return fmt.Errorf("VCDClusterTemplate [%s] cannot set rdeId, which identifies the RDE of a single cluster",
+			r.Name)
+	}
+	if spec.VAppName != "" {
+		return fmt.Errorf("VCDClusterTemplate [%s] cannot set vAppName, which identifies the vApp of a single cluster",
+			r.Name)
+	}
+	if err := validateVAppStrategy(spec.VAppStrategy); err != nil {
+		return fmt.Errorf("VCDClusterTemplate [%s] has an invalid vAppStrategy: [%v]", r.Name, err)
+	}
+	return nil
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type. The template is
+// immutable: the ClusterClass references a new VCDClusterTemplate to change the VCDClusters of its clusters.
+func (r *VCDClusterTemplate) ValidateUpdate(old runtime.Object) error {
+	vcdclustertemplatelog.Info("validate update", "name", r.Name)
+
+	oldTemplate, ok := old.(*VCDClusterTemplate)
+	if !ok {
+		return fmt.Errorf("expected a VCDClusterTemplate but got [%T]", old)
+	}
+	if !reflect.DeepEqual(oldTemplate.Spec.Template.Spec, r.Spec.Template.Spec) {
+		return fmt.Errorf("spec.template.spec of VCDClusterTemplate [%s] is immutable; create a new "+
+			"VCDClusterTemplate and reference it from the ClusterClass", r.Name)
+	}
+	return nil
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *VCDClusterTemplate) ValidateDelete() error {
+	vcdclustertemplatelog.Info("validate delete", "name", r.Name)
+	return nil
+}
+
+// vcdClusterTemplateValidator validates the VCDClusterTemplates like VCDClusterTemplate, and lets the topology
+// controller of a ClusterClass dry-run changes of the templates.
+type vcdClusterTemplateValidator struct{}
+
+var _ admission.CustomValidator = &vcdClusterTemplateValidator{}
+
+func (v *vcdClusterTemplateValidator) ValidateCreate(_ context.Context, obj runtime.Object) error {
+	vcdClusterTemplate, ok := obj.(*VCDClusterTemplate)
+	if !ok {
+		return fmt.Errorf("expected a VCDClusterTemplate but got [%T]", obj)
+	}
+	return vcdClusterTemplate.ValidateCreate()
+}
+
+func (v *vcdClusterTemplateValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	vcdClusterTemplate, ok := newObj.(*VCDClusterTemplate)
+	if !ok {
+		return fmt.Errorf("expected a VCDClusterTemplate but got [%T]", newObj)
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("expected an admission request in the context: [%v]", err)
+	}
+	if topology.ShouldSkipImmutabilityChecks(req, vcdClusterTemplate) {
+		return nil
+	}
+	return vcdClusterTemplate.ValidateUpdate(oldObj)
+}
+
+func (v *vcdClusterTemplateValidator) ValidateDelete(_ context.Context, obj runtime.Object) error {
+	vcdClusterTemplate, ok := obj.(*VCDClusterTemplate)
+	if !ok {
+		return fmt.Errorf("expected a VCDClusterTemplate but got [%T]", obj)
+	}
+	return vcdClusterTemplate.ValidateDelete()
+}
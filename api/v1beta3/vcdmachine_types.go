@@ -17,9 +17,11 @@ limitations under the License.
 package v1beta3
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
 )
 
 const (
@@ -29,40 +31,218 @@ const (
 	VCDProviderID    = "vmware-cloud-director"
 )
 
+// Vertical scaling policies of the VCDMachines
+const (
+	// VerticalScalingPolicyInPlace changes the CPUs and memory of the running VMs.
+	VerticalScalingPolicyInPlace = "InPlace"
+	// VerticalScalingPolicyReplace replaces the machines to change the CPUs and memory of their VMs.
+	VerticalScalingPolicyReplace = "Replace"
+)
+
+// NetworkSpec defines a NIC of a VM attached to an OVDC network.
+type NetworkSpec struct {
+	// Name is the name of the OVDC network the NIC is attached to
+	Name string `json:"name"`
+
+	// IPAllocationMode is the mode in which the IP address of the NIC is allocated: POOL allocates it from the static
+	// IP pool of the network, DHCP leaves it to the DHCP service of the network and MANUAL uses IPAddress.
+	// +kubebuilder:validation:Enum=POOL;DHCP;MANUAL
+	// +kubebuilder:default=POOL
+	// +optional
+	IPAllocationMode string `json:"ipAllocationMode,omitempty"`
+
+	// IPAddress is the IP address of the NIC when IPAllocationMode is MANUAL.
+	// +optional
+	IPAddress string `json:"ipAddress,omitempty"`
+
+	// IPAddressPool is a reference to the pool of an IPAM provider the IP address of the NIC is claimed from,
+	// following the Cluster API IPAM contract. The NIC is configured with the allocated address in MANUAL IP allocation
+	// mode, and IPAllocationMode and IPAddress are ignored.
+	// +optional
+	IPAddressPool *corev1.TypedLocalObjectReference `json:"ipAddressPool,omitempty"`
+}
+
+// KubeletConfig defines the kubelet settings of a machine.
+type KubeletConfig struct {
+	// ExtraArgs are extra arguments passed to the kubelet, without the leading dashes. They take precedence over the
+	// kubelet arguments of the bootstrap configuration.
+	// +optional
+	ExtraArgs map[string]string `json:"extraArgs,omitempty"`
+
+	// SystemReserved is the amount of resources reserved for the system daemons of the machine, e.g. cpu and memory.
+	// It takes precedence over a system-reserved entry of ExtraArgs.
+	// +optional
+	SystemReserved corev1.ResourceList `json:"systemReserved,omitempty"`
+}
+
+// DiskSpec defines an independent data disk attached to a machine
+type DiskSpec struct {
+	// Name identifies the disk within the machine. The independent disk is named after the VM and this name.
+	Name string `json:"name"`
+
+	// Size is the size of the disk
+	Size resource.Quantity `json:"size"`
+
+	// StorageProfile is the storage profile of the disk. The storage profile of the machine is used when empty.
+	// +optional
+	StorageProfile string `json:"storageProfile,omitempty"`
+
+	// BusType is the type of the controller the disk is attached to
+	// +kubebuilder:validation:Enum=paravirtual;sata;nvme
+	// +kubebuilder:default=paravirtual
+	// +optional
+	BusType string `json:"busType,omitempty"`
+
+	// BusNumber is the number of the controller the disk is attached to. VCD picks one when not set.
+	// +optional
+	BusNumber *int32 `json:"busNumber,omitempty"`
+
+	// UnitNumber is the unit of the disk on its controller. VCD picks one when not set.
+	// +optional
+	UnitNumber *int32 `json:"unitNumber,omitempty"`
+
+	// ProvisioningType is the provisioning of the disk. VCD provisions the independent disks of the data disks as set
+	// by the OVDC, so the provisioning reported by VCD is only verified once the disk is attached. EagerZeroed disks are
+	// thick disks zeroed by the vSphere storage policy of the storage profile.
+	// +kubebuilder:validation:Enum=Thin;Thick;EagerZeroed
+	// +optional
+	ProvisioningType string `json:"provisioningType,omitempty"`
+
+	// Iops is the IOPS limit of the disk. The storage profile of the disk must allow setting the IOPS of its disks.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Iops *int64 `json:"iops,omitempty"`
+}
+
+// AntiAffinitySpec defines the VM anti-affinity rule of the machines of a KubeadmControlPlane or MachineDeployment.
+type AntiAffinitySpec struct {
+	// Type is the type of the anti-affinity rule: a Hard rule is mandatory and VCD does not power on a VM which would
+	// violate it, while a Soft rule is applied on a best-effort basis.
+	// +kubebuilder:validation:Enum=Soft;Hard
+	// +kubebuilder:default=Soft
+	// +optional
+	Type string `json:"type,omitempty"`
+}
+
+// ScriptRef references a script stored in a Secret in the namespace of the VCDMachine.
+type ScriptRef struct {
+	// Name is the name of the Secret.
+	Name string `json:"name"`
+
+	// Key is the key of the script in the Secret.
+	// +kubebuilder:default=script
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// EmergencyUser is a break-glass user created on the node to access it when its bootstrap fails.
+type EmergencyUser struct {
+	// Name is the name of the user.
+	// +kubebuilder:validation:Pattern=`^[a-z_][a-z0-9_-]{0,31}$`
+	// +kubebuilder:default=capvcd
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// PasswordHashSecretName is the name of a Secret in the namespace of the VCDMachine whose passwordHash key holds
+	// the crypt(3) hash of the password of the user, e.g. generated with `mkpasswd -m sha-512`, to log in from the
+	// console of the VM when the node cannot be reached over SSH. The hash is readable in the guest properties of the
+	// VM. The user can only log in with the SSHAuthorizedKeys of the machine if not set.
+	// +optional
+	PasswordHashSecretName string `json:"passwordHashSecretName,omitempty"`
+}
+
 // VCDMachineSpec defines the desired state of VCDMachine
 type VCDMachineSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
 	// ProviderID will be the container name in ProviderID format (vmware-cloud-director://<vm id>)
+	// A VCDMachine created with the provider ID of an existing VM of its vApp adopts the VM instead of creating one.
 	// +optional
 	ProviderID *string `json:"providerID,omitempty"`
 
-	// Catalog hosting templates
+	// Catalog hosting templates, by name or URN
 	// +optional
 	Catalog string `json:"catalog,omitempty"`
 
-	// TemplatePath is the path of the template OVA that is to be used
+	// CatalogOrg is the name of the org owning Catalog, when it is a catalog of another org published or shared to the
+	// org of the cluster, e.g. System for a catalog published by the provider. The catalog is looked up in the org of
+	// the cluster when it is not set.
+	// +optional
+	CatalogOrg string `json:"catalogOrg,omitempty"`
+
+	// TemplatePath is the path of the template OVA that is to be used, by name or catalog item or vApp template URN
 	// +optional
 	Template string `json:"template,omitempty"`
 
-	// SizingPolicy is the sizing policy to be used on this machine.
+	// SizingPolicy is the sizing policy to be used on this machine, by name or URN.
 	// If no sizing policy is specified, default sizing policy will be used to create the nodes
 	// +optional
 	SizingPolicy string `json:"sizingPolicy,omitempty"`
 
-	// PlacementPolicy is the placement policy to be used on this machine.
+	// PlacementPolicy is the placement policy to be used on this machine, by name or URN.
 	// +optional
 	PlacementPolicy string `json:"placementPolicy,omitempty"`
 
-	// StorageProfile is the storage profile to be used on this machine
+	// StorageProfile is the storage profile to be used on this machine, by name or URN
 	// +optional
 	StorageProfile string `json:"storageProfile,omitempty"`
 
-	// DiskSize is the size, in bytes, of the disk for this machine
+	// NumCPUs is the number of virtual CPUs of the VM of this machine, for orgs which publish no sizing policy. The
+	// CPUs and memory of the template are kept when they are omitted. Cannot be set with a SizingPolicy.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	NumCPUs *int32 `json:"numCpus,omitempty"`
+
+	// CoresPerSocket is the number of cores per socket of the virtual CPUs of the VM of this machine. It must divide
+	// NumCPUs. Cannot be set with a SizingPolicy.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	CoresPerSocket *int32 `json:"coresPerSocket,omitempty"`
+
+	// MemoryMiB is the memory, in MiB, of the VM of this machine. Cannot be set with a SizingPolicy.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MemoryMiB *int64 `json:"memoryMiB,omitempty"`
+
+	// VerticalScalingPolicy is how a change of NumCPUs, CoresPerSocket or MemoryMiB of the VCDMachineTemplate this
+	// machine was cloned from is rolled out. With Replace, the default, the VCDMachineTemplate is immutable and the
+	// machines are replaced by the ones of a new template. With InPlace, the CPUs and memory of the template can be
+	// changed and are hot-added to the running VM when it supports it.
+	// +kubebuilder:validation:Enum=InPlace;Replace
+	// +optional
+	VerticalScalingPolicy string `json:"verticalScalingPolicy,omitempty"`
+
+	// DiskSize is the size, in bytes, of the disk for this machine. Increasing it, here or in the VCDMachineTemplate
+	// this machine was cloned from, grows the boot disk of the provisioned VM and then its root file system.
 	// +optional
 	DiskSize resource.Quantity `json:"diskSize,omitempty"`
 
+	// DataDisks are the independent disks created and attached to the VM of this machine after it is created, e.g. to
+	// separate the data of etcd or of the container runtime. The disks are deleted with the machine.
+	// +optional
+	DataDisks []DiskSpec `json:"dataDisks,omitempty"`
+
+	// BootDiskBusType is the type of the controller the boot disk of this machine is attached to. The controller of
+	// the template is kept when this field is empty. The boot disk is the disk at unit 0 of bus 0 of the template. The
+	// controller is only changed before the VM is powered on for the first time.
+	// +kubebuilder:validation:Enum=paravirtual;sata;nvme
+	// +optional
+	BootDiskBusType string `json:"bootDiskBusType,omitempty"`
+
+	// BootDiskProvisioningType is the provisioning of the boot disk of this machine, e.g. Thick for the boot disk of a
+	// control plane machine storing the data of etcd. The provisioning of the template is kept when this field is
+	// empty. The provisioning is changed before the VM is powered on for the first time.
+	// +kubebuilder:validation:Enum=Thin;Thick;EagerZeroed
+	// +optional
+	BootDiskProvisioningType string `json:"bootDiskProvisioningType,omitempty"`
+
+	// BootDiskIops is the IOPS limit of the boot disk of this machine. The storage profile of the machine must allow
+	// setting the IOPS of its disks. The limit is set before the VM is powered on for the first time.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	BootDiskIops *int64 `json:"bootDiskIops,omitempty"`
+
 	// Bootstrapped is true when the kubeadm bootstrapping has been run
 	// against this machine
 	// +optional
@@ -73,16 +253,184 @@ type VCDMachineSpec struct {
 	// +optional
 	EnableNvidiaGPU bool `json:"enableNvidiaGPU,omitempty"`
 
+	// GPU selects the vGPU profile of the VM. The VM is created with the vGPU policy of the OVDC providing the profile
+	// and count, which also places the VM on hosts with the GPUs, so PlacementPolicy and VmGroup must not be set.
+	// EnableNvidiaGPU is implied.
+	// +optional
+	GPU *GPUSpec `json:"gpu,omitempty"`
+
 	// ExtraOvdcNetworks is the list of extra Ovdc Networks that are mounted to machines.
 	// VCDClusterSpec.OvdcNetwork is always attached regardless of this field.
 	// +optional
 	ExtraOvdcNetworks []string `json:"extraOvdcNetworks,omitempty"`
 
+	// Networks is the list of OVDC networks the VM of this machine is attached to, one NIC per network. The first
+	// network is the primary network of the VM. VCDClusterSpec.OvdcNetwork and ExtraOvdcNetworks are ignored when
+	// this field is set. All the NICs are configured before the VM is powered on for the first time.
+	// +optional
+	Networks []NetworkSpec `json:"networks,omitempty"`
+
 	// VmNamingTemplate is go template to generate VM names based on Machine and VCDMachine CRs.
 	// Functions of Sprig library are supported. See https://github.com/Masterminds/sprig
 	// Immutable field. machine.Name is used as VM name when this field is empty.
 	// +optional
 	VmNamingTemplate string `json:"vmNamingTemplate,omitempty"`
+
+	// KubeletConfig is the kubelet configuration of this machine. It is merged into the bootstrap data of the machine
+	// so that the kubelet settings tied to the sizing of a pool of machines live in its machine template.
+	// +optional
+	KubeletConfig *KubeletConfig `json:"kubeletConfig,omitempty"`
+
+	// VmGroup is the name of the VM group or logical VM group the VM of this machine should be placed in. The VM is
+	// created with the VM placement policy of the OVDC which references the group, so that the host affinity rules of
+	// the group apply to it. An explicitly set PlacementPolicy must reference the group.
+	// +optional
+	VmGroup string `json:"vmGroup,omitempty"`
+
+	// AntiAffinity places the VMs of the machines of the same KubeadmControlPlane or MachineDeployment in a VCD VM
+	// anti-affinity rule so that they run on different hosts. The VM of this machine is added to the rule once it is
+	// created and removed from it when the machine is deleted.
+	// +optional
+	AntiAffinity *AntiAffinitySpec `json:"antiAffinity,omitempty"`
+
+	// MetadataPropagation copies the selected labels and annotations of the Machine to the metadata of its VM, in
+	// addition to those of the Cluster selected in the VCDCluster, and keeps the metadata in sync with them.
+	// +optional
+	MetadataPropagation *MetadataPropagationSpec `json:"metadataPropagation,omitempty"`
+
+	// VAppName is the name of the vApp of the OVDC of the machine the VM of this machine is created in, instead of the
+	// vApp of the cluster. Immutable field.
+	// +optional
+	VAppName string `json:"vAppName,omitempty"`
+
+	// VAppOwnership defines whether the vApp named VAppName is managed by CAPVCD. A Managed vApp, the default, is
+	// created if it does not exist, tagged with the infra ID of the cluster, and deleted once the last machine in it is
+	// deleted. An Unmanaged vApp must exist and be connected to the OVDC network of the cluster; CAPVCD only creates and
+	// deletes the VMs of its machines in it and never deletes it.
+	// +kubebuilder:validation:Enum=Managed;Unmanaged
+	// +optional
+	VAppOwnership string `json:"vAppOwnership,omitempty"`
+
+	// Ovdc is the name or URN of the OVDC of the org of the cluster the VM of this machine is created in, instead of
+	// VCDClusterSpec.Ovdc, e.g. to run the workers of a MachineDeployment in an OVDC with GPUs. Unless VAppName is set,
+	// the VM is created in a vApp of the cluster in that OVDC, which is deleted once the last machine in it is deleted.
+	// Immutable field.
+	// +optional
+	Ovdc string `json:"ovdc,omitempty"`
+
+	// OvdcNetwork is the name of the OVDC network the VM of this machine is attached to instead of
+	// VCDClusterSpec.OvdcNetwork, e.g. a network of Ovdc. The network must be routed to the network of the cluster. It
+	// is ignored when Networks is set. Immutable field.
+	// +optional
+	OvdcNetwork string `json:"ovdcNetwork,omitempty"`
+
+	// BootstrapFormat is the format of the bootstrap data of the machine, which must match the format of its bootstrap
+	// config. With cloud-config, the default, the bootstrap data is merged into the cloud-init script of CAPVCD and passed
+	// in guestinfo.userdata. With ignition, e.g. for Flatcar Container Linux templates, the bootstrap data is passed as
+	// is in guestinfo.ignition.config.data; the proxy configuration of the cluster and the bootstrap phase reporting of
+	// the cloud-init script of CAPVCD are not applied.
+	// +kubebuilder:validation:Enum=cloud-config;ignition
+	// +optional
+	BootstrapFormat string `json:"bootstrapFormat,omitempty"`
+
+	// PrePullImages are the container images pulled on the node before it joins the cluster, so that the workloads
+	// scheduled on it as soon as it is ready do not all pull them from the registry at once. The images are pulled
+	// with the proxy configuration and trust bundle of the cluster; an image which cannot be pulled does not fail the
+	// bootstrap of the node. Not applied with the ignition BootstrapFormat.
+	// +optional
+	PrePullImages []ImageReference `json:"prePullImages,omitempty"`
+
+	// PreKubeadmCommandsScriptRef references a bash script run on the node right before the kubeadm commands of its
+	// bootstrap data, e.g. to tune NVMe devices or install the NVIDIA drivers, so that the setup tied to the hardware
+	// of a pool of machines lives in its machine template. The script is read when the VM is created; a failing script
+	// fails the bootstrap of the node. Not applied with the ignition BootstrapFormat.
+	// +optional
+	PreKubeadmCommandsScriptRef *ScriptRef `json:"preKubeadmCommandsScriptRef,omitempty"`
+
+	// PostKubeadmCommandsScriptRef references a bash script run on the node once the kubeadm commands of its bootstrap
+	// data succeed. The script is read when the VM is created; a failing script fails the bootstrap of the node. Not
+	// applied with the ignition BootstrapFormat.
+	// +optional
+	PostKubeadmCommandsScriptRef *ScriptRef `json:"postKubeadmCommandsScriptRef,omitempty"`
+
+	// SSHAuthorizedKeys are the SSH public keys authorized to log in as root on the node, and as the EmergencyUser if
+	// set, so that operators can access nodes whose bootstrap failed without relying on keys baked in the template.
+	// Not applied with the ignition BootstrapFormat.
+	// +optional
+	SSHAuthorizedKeys []string `json:"sshAuthorizedKeys,omitempty"`
+
+	// EmergencyUser is a user with passwordless sudo created on the node before its bootstrap starts. Not applied with
+	// the ignition BootstrapFormat.
+	// +optional
+	EmergencyUser *EmergencyUser `json:"emergencyUser,omitempty"`
+
+	// CaptureConsoleScreenshot captures the console of the VM in the Secret of BootstrapDiagnostics when the bootstrap
+	// of the machine fails, e.g. to see a kernel panic or a prompt blocking the boot of a VM whose guestinfo reports
+	// nothing.
+	// +optional
+	CaptureConsoleScreenshot bool `json:"captureConsoleScreenshot,omitempty"`
+
+	// ProvisioningTimeout is how long the VM of the machine may take to be created, attached to its networks and given
+	// an address, from the creation of the VCDMachine. Once it elapses, the machine is failed so that it is replaced,
+	// e.g. by a MachineHealthCheck. The machine waits for its VM as long as needed if not set.
+	// +optional
+	ProvisioningTimeout *metav1.Duration `json:"provisioningTimeout,omitempty"`
+
+	// BootstrapTimeout is how long the bootstrap of the VM of the machine may take from its power on. Once it elapses,
+	// the bootstrap is retried in place up to BootstrapRetries times, then the machine is failed so that it is
+	// replaced, e.g. by a MachineHealthCheck. The machine waits for its bootstrap as long as needed if not set. Not
+	// applied with the ignition BootstrapFormat, whose bootstrap is not reported by the VM.
+	// +optional
+	BootstrapTimeout *metav1.Duration `json:"bootstrapTimeout,omitempty"`
+
+	// BootstrapRetries is the number of times the VM is power cycled to run its guest customization and bootstrap again
+	// once BootstrapTimeout elapses, before the machine is failed. A retry helps with the failures before the kubeadm
+	// commands, e.g. a guest hung at boot or a control plane endpoint not reachable yet.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	BootstrapRetries int32 `json:"bootstrapRetries,omitempty"`
+
+	// GracefulShutdownTimeout is how long the guest OS of the VM is given to shut down when the machine is deleted,
+	// before the VM is powered off and deleted. The guest OS shutdown requires VMware Tools in the VM. Defaults to 5m;
+	// 0s powers the VM off right away.
+	// +optional
+	GracefulShutdownTimeout *metav1.Duration `json:"gracefulShutdownTimeout,omitempty"`
+
+	// SnapshotBeforeUpgrade keeps the VM of the machine, powered off with a snapshot, when the machine is replaced by
+	// an upgrade of the Kubernetes version of its control plane or machine deployment, so that the operator can revert
+	// to it. The VM is deleted once SnapshotRetention elapsed, or with the cluster. VMs with data disks, which VCD
+	// cannot snapshot, and VMs outside the vApp of the cluster are deleted as usual.
+	// +optional
+	SnapshotBeforeUpgrade bool `json:"snapshotBeforeUpgrade,omitempty"`
+
+	// SnapshotRetention is how long the VM of a machine replaced by an upgrade is kept when SnapshotBeforeUpgrade is
+	// set. Defaults to 24h.
+	// +optional
+	SnapshotRetention *metav1.Duration `json:"snapshotRetention,omitempty"`
+}
+
+// ImageReference is the reference of a container image, e.g. registry.example.com/library/nginx:1.25 or
+// registry.example.com/library/nginx@sha256:<digest>.
+// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9][a-zA-Z0-9._/:@-]*$`
+type ImageReference string
+
+// GPUSpec defines the vGPU profile of a VM.
+type GPUSpec struct {
+	// Profile is the name of the vGPU profile of the VM, e.g. a time-sliced profile such as grid_a100-8c or a MIG
+	// profile such as grid_a100-3-20c.
+	// +kubebuilder:validation:MinLength=1
+	Profile string `json:"profile"`
+
+	// Count is the number of vGPUs of the profile attached to the VM.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	// +optional
+	Count int32 `json:"count,omitempty"`
+
+	// Policy is the name of the vGPU policy of the VM, which is required only when several vGPU policies of the OVDC
+	// provide the profile and count.
+	// +optional
+	Policy string `json:"policy,omitempty"`
 }
 
 // VCDMachineStatus defines the observed state of VCDMachine
@@ -122,9 +470,109 @@ type VCDMachineStatus struct {
 	// +optional
 	DiskSize resource.Quantity `json:"diskSize,omitempty"`
 
+	// TemplateHash is the hash of the VCDMachineTemplate spec the VM of this machine was created from. The same hash
+	// is recorded in the metadata of the VM.
+	// +optional
+	TemplateHash string `json:"templateHash,omitempty"`
+
 	// Conditions defines current service state of the DockerMachine.
 	// +optional
 	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+
+	// ResolvedReferences are the name and URN of the catalog, template, policies and storage profile the VM of this
+	// machine was created with, referenced by the spec or the default machine policies of the cluster by name or URN.
+	// +optional
+	ResolvedReferences VCDResources `json:"resolvedReferences,omitempty"`
+
+	// ShutdownStartTime is the time the shutdown of the guest OS of the VM was requested, once the machine is deleted.
+	// +optional
+	ShutdownStartTime *metav1.Time `json:"shutdownStartTime,omitempty"`
+
+	// Hardware is the CPUs and memory of the VM of a machine scaled in place, as last set by the controller.
+	// +optional
+	Hardware *VMHardware `json:"hardware,omitempty"`
+
+	// VAppName is the vApp the VM of this machine was placed in by VCDClusterSpec.VAppStrategy, or the vApp of the
+	// cluster in the OVDC of VCDMachineSpec.Ovdc.
+	// +optional
+	VAppName string `json:"vAppName,omitempty"`
+
+	// BootstrapDiagnostics are the diagnostics collected from the VM of this machine when its bootstrap last failed.
+	// They are cleared once the machine is bootstrapped.
+	// +optional
+	BootstrapDiagnostics *BootstrapDiagnostics `json:"bootstrapDiagnostics,omitempty"`
+
+	// BootstrapStartTime is when the VM of this machine was powered on with its bootstrap data, from which
+	// VCDMachineSpec.BootstrapTimeout elapses.
+	// +optional
+	BootstrapStartTime *metav1.Time `json:"bootstrapStartTime,omitempty"`
+
+	// BootstrapRetries is the number of times the bootstrap of the VM of this machine was retried in place.
+	// +optional
+	BootstrapRetries int32 `json:"bootstrapRetries,omitempty"`
+
+	// FailureReason will be set in case of a terminal problem of the machine, e.g. its VM not being provisioned or
+	// bootstrapped in time, and will contain a succinct value suitable for machine interpretation.
+	// +optional
+	FailureReason *capierrors.MachineStatusError `json:"failureReason,omitempty"`
+
+	// FailureMessage will be set in case of a terminal problem of the machine and will contain a more verbose string
+	// suitable for logging and human consumption.
+	// +optional
+	FailureMessage *string `json:"failureMessage,omitempty"`
+
+	// VMName is the name of the VM of this machine, generated from the VmNamingTemplate of the VCDMachine or of the
+	// VCDCluster when the VM is created. The VM also records the name of its Machine in its CapvcdMachineName
+	// metadata, by which it is found if it no longer has this name.
+	// +optional
+	VMName string `json:"vmName,omitempty"`
+}
+
+// BootstrapDiagnostics are the diagnostics collected from the guestinfo and console of a VM whose bootstrap failed.
+type BootstrapDiagnostics struct {
+	// CollectionTime is when the diagnostics were collected.
+	CollectionTime metav1.Time `json:"collectionTime"`
+
+	// FailedPhase is the guestinfo key of the bootstrap phase which failed or did not complete in time.
+	// +optional
+	FailedPhase string `json:"failedPhase,omitempty"`
+
+	// PhaseStatuses are the statuses of the bootstrap phases of the VM reported in its guestinfo, by guestinfo key. A
+	// phase which did not start has no status.
+	// +optional
+	PhaseStatuses map[string]string `json:"phaseStatuses,omitempty"`
+
+	// ScriptExitCode is the exit code of the failed command of the bootstrap script of the VM, if any.
+	// +optional
+	ScriptExitCode *int32 `json:"scriptExitCode,omitempty"`
+
+	// FailureReason is the failed command of the bootstrap script of the VM and the error of kubeadm, if any.
+	// +optional
+	FailureReason string `json:"failureReason,omitempty"`
+
+	// CloudInitOutput is the end of the cloud-init output log of the VM, reported once its bootstrap script failed.
+	// +optional
+	CloudInitOutput string `json:"cloudInitOutput,omitempty"`
+
+	// ConsoleScreenshotSecretName is the name of the Secret holding the PNG screenshot of the console of the VM in its
+	// screenshot.png key, if VCDMachineSpec.CaptureConsoleScreenshot is set.
+	// +optional
+	ConsoleScreenshotSecretName string `json:"consoleScreenshotSecretName,omitempty"`
+}
+
+// VMHardware is the CPUs and memory of a VM.
+type VMHardware struct {
+	// NumCPUs is the number of virtual CPUs of the VM.
+	// +optional
+	NumCPUs int32 `json:"numCpus,omitempty"`
+
+	// CoresPerSocket is the number of cores per socket of the virtual CPUs of the VM.
+	// +optional
+	CoresPerSocket int32 `json:"coresPerSocket,omitempty"`
+
+	// MemoryMiB is the memory, in MiB, of the VM.
+	// +optional
+	MemoryMiB int64 `json:"memoryMiB,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -157,6 +605,18 @@ func (c *VCDMachine) GetConditions() clusterv1.Conditions {
 func (c *VCDMachine) SetConditions(conditions clusterv1.Conditions) {
 	c.Status.Conditions = conditions
 }
+
+// IsScaledInPlace checks if the CPUs and memory set in the VCDMachineSpec are changed on the running VM rather than by
+// replacing the machine.
+func (s *VCDMachineSpec) IsScaledInPlace() bool {
+	return s.VerticalScalingPolicy == VerticalScalingPolicyInPlace && s.SizingPolicy == "" && s.HasExplicitHardware()
+}
+
+// HasExplicitHardware checks if the VCDMachineSpec sets the CPUs or memory of the VM instead of a sizing policy.
+func (s *VCDMachineSpec) HasExplicitHardware() bool {
+	return s.NumCPUs != nil || s.CoresPerSocket != nil || s.MemoryMiB != nil
+}
+
 func init() {
 	SchemeBuilder.Register(&VCDMachine{}, &VCDMachineList{})
 }
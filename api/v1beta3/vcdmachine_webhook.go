@@ -17,6 +17,8 @@ limitations under the License.
 package v1beta3
 
 import (
+	"fmt"
+
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -52,16 +54,14 @@ var _ webhook.Validator = &VCDMachine{}
 func (r *VCDMachine) ValidateCreate() error {
 	vcdmachinelog.Info("validate create", "name", r.Name)
 
-	// TODO(user): fill in your validation logic upon object creation.
-	return nil
+	return validateMachineHardware(&r.Spec, "VCDMachine", r.Name)
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (r *VCDMachine) ValidateUpdate(old runtime.Object) error {
 	vcdmachinelog.Info("validate update", "name", r.Name)
 
-	// TODO(user): fill in your validation logic upon object update.
-	return nil
+	return validateMachineHardware(&r.Spec, "VCDMachine", r.Name)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -71,3 +71,20 @@ func (r *VCDMachine) ValidateDelete() error {
 	// TODO(user): fill in your validation logic upon object deletion.
 	return nil
 }
+
+// validateMachineHardware checks that the VCDMachineSpec of the object of the kind does not set both a sizing policy
+// and the CPUs or memory of the VM, and that its cores per socket divide its CPUs.
+func validateMachineHardware(spec *VCDMachineSpec, kind string, name string) error {
+	if !spec.HasExplicitHardware() {
+		return nil
+	}
+	if spec.SizingPolicy != "" {
+		return fmt.Errorf("%s [%s] cannot set both sizingPolicy [%s] and numCpus, coresPerSocket or memoryMiB",
+			kind, name, spec.SizingPolicy)
+	}
+	if spec.NumCPUs != nil && spec.CoresPerSocket != nil && *spec.NumCPUs%*spec.CoresPerSocket != 0 {
+		return fmt.Errorf("coresPerSocket [%d] of %s [%s] does not divide numCpus [%d]", *spec.CoresPerSocket,
+			kind, name, *spec.NumCPUs)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+const (
+	// MachinePoolFinalizer allows the VCDMachinePool controller to delete the VMs of the pool before the VCDMachinePool
+	// is removed from the apiserver.
+	MachinePoolFinalizer = "vcdmachinepool.infrastructure.cluster.x-k8s.io"
+)
+
+// VCDMachinePoolTemplate defines the VMs of a VCDMachinePool. The catalog, template, policies and storage profile are
+// referenced by name.
+type VCDMachinePoolTemplate struct {
+	// Catalog is the name of the catalog hosting the template.
+	// +kubebuilder:validation:MinLength=1
+	Catalog string `json:"catalog"`
+
+	// Template is the name of the vApp template the VMs are created from.
+	// +kubebuilder:validation:MinLength=1
+	Template string `json:"template"`
+
+	// SizingPolicy is the sizing policy of the VMs. It is inherited from the default machine policies of the
+	// VCDCluster when omitted.
+	// +optional
+	SizingPolicy string `json:"sizingPolicy,omitempty"`
+
+	// PlacementPolicy is the placement policy of the VMs. It is inherited from the default machine policies of the
+	// VCDCluster when omitted.
+	// +optional
+	PlacementPolicy string `json:"placementPolicy,omitempty"`
+
+	// StorageProfile is the storage profile of the VMs. It is inherited from the default machine policies of the
+	// VCDCluster when omitted.
+	// +optional
+	StorageProfile string `json:"storageProfile,omitempty"`
+
+	// VmGroup is the name of the VM group the VMs are placed in, through the placement policy referencing it.
+	// +optional
+	VmGroup string `json:"vmGroup,omitempty"`
+}
+
+// VCDMachinePoolSpec defines the desired state of VCDMachinePool
+type VCDMachinePoolSpec struct {
+	// ProviderIDList are the provider IDs of the VMs of the pool, in the format vmware-cloud-director://<vm id>. It is
+	// set by the controller, as required by the Cluster API MachinePool contract.
+	// +optional
+	ProviderIDList []string `json:"providerIDList,omitempty"`
+
+	// Template defines the VMs of the pool. The VMs created from a previous template are replaced one at a time once it
+	// changes, as are the VMs created from a previous bootstrap data secret of the MachinePool.
+	Template VCDMachinePoolTemplate `json:"template"`
+}
+
+// VCDMachinePoolInstance is a VM of a VCDMachinePool.
+type VCDMachinePoolInstance struct {
+	// Name is the name of the VM.
+	Name string `json:"name"`
+
+	// ProviderID is the provider ID of the VM, in the format vmware-cloud-director://<vm id>.
+	// +optional
+	ProviderID string `json:"providerID,omitempty"`
+
+	// TemplateHash is the hash of the template and bootstrap data secret the VM was created from.
+	// +optional
+	TemplateHash string `json:"templateHash,omitempty"`
+
+	// Ready denotes that the node of the VM joined the cluster, as reported by the bootstrap script of the VM.
+	// +optional
+	Ready bool `json:"ready"`
+}
+
+// VCDMachinePoolStatus defines the observed state of VCDMachinePool
+type VCDMachinePoolStatus struct {
+	// Ready denotes that the pool has the number of VMs of its MachinePool and that all of them are ready.
+	// +optional
+	Ready bool `json:"ready"`
+
+	// Replicas is the number of ready VMs of the pool.
+	// +optional
+	Replicas int32 `json:"replicas"`
+
+	// Instances are the VMs of the pool.
+	// +optional
+	Instances []VCDMachinePoolInstance `json:"instances,omitempty"`
+
+	// Conditions defines current service state of the VCDMachinePool.
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Ready",type="boolean",JSONPath=".status.ready"
+// +kubebuilder:printcolumn:name="Replicas",type="integer",JSONPath=".status.replicas"
+
+// VCDMachinePool is the Schema for the vcdmachinepools API. It is the infrastructure of a Cluster API MachinePool: a
+// homogeneous set of worker VMs in the vApp of the cluster, without a Machine per VM.
+type VCDMachinePool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VCDMachinePoolSpec   `json:"spec,omitempty"`
+	Status VCDMachinePoolStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// VCDMachinePoolList contains a list of VCDMachinePool
+type VCDMachinePoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VCDMachinePool `json:"items"`
+}
+
+// GetConditions returns the set of conditions for this object.
+func (c *VCDMachinePool) GetConditions() clusterv1.Conditions {
+	return c.Status.Conditions
+}
+
+// SetConditions sets the conditions on this object.
+func (c *VCDMachinePool) SetConditions(conditions clusterv1.Conditions) {
+	c.Status.Conditions = conditions
+}
+
+func init() {
+	SchemeBuilder.Register(&VCDMachinePool{}, &VCDMachinePoolList{})
+}
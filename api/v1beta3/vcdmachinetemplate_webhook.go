@@ -17,8 +17,16 @@ limitations under the License.
 package v1beta3
 
 import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/cluster-api/util/topology"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 // log is for logging in this package.
@@ -27,7 +35,89 @@ var vcdmachinetemplatelog = logf.Log.WithName("vcdmachinetemplate-resource")
 func (r *VCDMachineTemplate) SetupWebhookWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
+		WithValidator(&vcdMachineTemplateValidator{}).
 		Complete()
 }
 
-// TODO(user): EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// +kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta3-vcdmachinetemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=vcdmachinetemplates,verbs=create;update,versions=v1beta3,name=validation.vcdmachinetemplate.infrastructure.cluster.x-k8s.io,admissionReviewVersions=v1
+var _ webhook.Validator = &VCDMachineTemplate{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *VCDMachineTemplate) ValidateCreate() error {
+	vcdmachinetemplatelog.Info("validate create", "name", r.Name)
+	return validateMachineHardware(&r.Spec.Template.Spec, "VCDMachineTemplate", r.Name)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type. The template of the
+// machines is immutable, as for all the infrastructure machine templates of Cluster API: a change of the machines is
+// rolled out by cloning the template and referencing the clone, which the topology controller of a ClusterClass does.
+// The disk size may only grow, as the disks of the machines cloned from the template are grown in place. The CPUs and
+// memory of a template with the InPlace vertical scaling policy may change, as they are changed on the running VMs.
+func (r *VCDMachineTemplate) ValidateUpdate(old runtime.Object) error {
+	vcdmachinetemplatelog.Info("validate update", "name", r.Name)
+
+	oldTemplate, ok := old.(*VCDMachineTemplate)
+	if !ok {
+		return fmt.Errorf("expected a VCDMachineTemplate but got [%T]", old)
+	}
+	oldSpec := oldTemplate.Spec.Template.Spec.DeepCopy()
+	newSpec := r.Spec.Template.Spec.DeepCopy()
+	if newSpec.DiskSize.Cmp(oldSpec.DiskSize) < 0 {
+		return fmt.Errorf("diskSize of VCDMachineTemplate [%s] cannot be decreased from [%s] to [%s]", r.Name,
+			oldSpec.DiskSize.String(), newSpec.DiskSize.String())
+	}
+	newSpec.DiskSize = oldSpec.DiskSize
+	if oldSpec.IsScaledInPlace() && newSpec.IsScaledInPlace() {
+		newSpec.NumCPUs, newSpec.CoresPerSocket, newSpec.MemoryMiB =
+			oldSpec.NumCPUs, oldSpec.CoresPerSocket, oldSpec.MemoryMiB
+	}
+	if !reflect.DeepEqual(oldSpec, newSpec) {
+		return fmt.Errorf("spec.template.spec of VCDMachineTemplate [%s] is immutable except for growing its "+
+			"diskSize and, with the InPlace verticalScalingPolicy, changing its numCpus, coresPerSocket and "+
+			"memoryMiB; create a new VCDMachineTemplate and reference it to roll out the machines", r.Name)
+	}
+	return validateMachineHardware(&r.Spec.Template.Spec, "VCDMachineTemplate", r.Name)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *VCDMachineTemplate) ValidateDelete() error {
+	vcdmachinetemplatelog.Info("validate delete", "name", r.Name)
+	return nil
+}
+
+// vcdMachineTemplateValidator validates the VCDMachineTemplates like VCDMachineTemplate, and lets the topology
+// controller of a ClusterClass dry-run changes of the templates it manages to decide if they must be rotated.
+type vcdMachineTemplateValidator struct{}
+
+var _ admission.CustomValidator = &vcdMachineTemplateValidator{}
+
+func (v *vcdMachineTemplateValidator) ValidateCreate(_ context.Context, obj runtime.Object) error {
+	vcdMachineTemplate, ok := obj.(*VCDMachineTemplate)
+	if !ok {
+		return fmt.Errorf("expected a VCDMachineTemplate but got [%T]", obj)
+	}
+	return vcdMachineTemplate.ValidateCreate()
+}
+
+func (v *vcdMachineTemplateValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	vcdMachineTemplate, ok := newObj.(*VCDMachineTemplate)
+	if !ok {
+		return fmt.Errorf("expected a VCDMachineTemplate but got [%T]", newObj)
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("expected an admission request in the context: [%v]", err)
+	}
+	if topology.ShouldSkipImmutabilityChecks(req, vcdMachineTemplate) {
+		return nil
+	}
+	return vcdMachineTemplate.ValidateUpdate(oldObj)
+}
+
+func (v *vcdMachineTemplateValidator) ValidateDelete(_ context.Context, obj runtime.Object) error {
+	vcdMachineTemplate, ok := obj.(*VCDMachineTemplate)
+	if !ok {
+		return fmt.Errorf("expected a VCDMachineTemplate but got [%T]", obj)
+	}
+	return vcdMachineTemplate.ValidateDelete()
+}
@@ -22,8 +22,10 @@ package v1beta3
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/errors"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -41,73 +43,1035 @@ func (in *APIEndpoint) DeepCopy() *APIEndpoint {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AllowedNamespaces) DeepCopyInto(out *AllowedNamespaces) {
+	*out = *in
+	if in.NamespaceList != nil {
+		in, out := &in.NamespaceList, &out.NamespaceList
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AllowedNamespaces.
+func (in *AllowedNamespaces) DeepCopy() *AllowedNamespaces {
+	if in == nil {
+		return nil
+	}
+	out := new(AllowedNamespaces)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AntiAffinitySpec) DeepCopyInto(out *AntiAffinitySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AntiAffinitySpec.
+func (in *AntiAffinitySpec) DeepCopy() *AntiAffinitySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AntiAffinitySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapDiagnostics) DeepCopyInto(out *BootstrapDiagnostics) {
+	*out = *in
+	in.CollectionTime.DeepCopyInto(&out.CollectionTime)
+	if in.PhaseStatuses != nil {
+		in, out := &in.PhaseStatuses, &out.PhaseStatuses
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ScriptExitCode != nil {
+		in, out := &in.ScriptExitCode, &out.ScriptExitCode
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapDiagnostics.
+func (in *BootstrapDiagnostics) DeepCopy() *BootstrapDiagnostics {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapDiagnostics)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterPhaseTransitions) DeepCopyInto(out *ClusterPhaseTransitions) {
+	*out = *in
+	if in.InfrastructureReady != nil {
+		in, out := &in.InfrastructureReady, &out.InfrastructureReady
+		*out = (*in).DeepCopy()
+	}
+	if in.ControlPlaneInitialized != nil {
+		in, out := &in.ControlPlaneInitialized, &out.ControlPlaneInitialized
+		*out = (*in).DeepCopy()
+	}
+	if in.FirstWorkerReady != nil {
+		in, out := &in.FirstWorkerReady, &out.FirstWorkerReady
+		*out = (*in).DeepCopy()
+	}
+	if in.DeletionStarted != nil {
+		in, out := &in.DeletionStarted, &out.DeletionStarted
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterPhaseTransitions.
+func (in *ClusterPhaseTransitions) DeepCopy() *ClusterPhaseTransitions {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterPhaseTransitions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskSpec) DeepCopyInto(out *DiskSpec) {
+	*out = *in
+	out.Size = in.Size.DeepCopy()
+	if in.BusNumber != nil {
+		in, out := &in.BusNumber, &out.BusNumber
+		*out = new(int32)
+		**out = **in
+	}
+	if in.UnitNumber != nil {
+		in, out := &in.UnitNumber, &out.UnitNumber
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Iops != nil {
+		in, out := &in.Iops, &out.Iops
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskSpec.
+func (in *DiskSpec) DeepCopy() *DiskSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressAllowlistConfig) DeepCopyInto(out *EgressAllowlistConfig) {
+	*out = *in
+	if in.RegistryMirrors != nil {
+		in, out := &in.RegistryMirrors, &out.RegistryMirrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NTPServers != nil {
+		in, out := &in.NTPServers, &out.NTPServers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.OSRepositories != nil {
+		in, out := &in.OSRepositories, &out.OSRepositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressAllowlistConfig.
+func (in *EgressAllowlistConfig) DeepCopy() *EgressAllowlistConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressAllowlistConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressConfig) DeepCopyInto(out *EgressConfig) {
+	*out = *in
+	if in.Priority != nil {
+		in, out := &in.Priority, &out.Priority
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressConfig.
+func (in *EgressConfig) DeepCopy() *EgressConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressDestination) DeepCopyInto(out *EgressDestination) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressDestination.
+func (in *EgressDestination) DeepCopy() *EgressDestination {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressDestination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EmergencyUser) DeepCopyInto(out *EmergencyUser) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EmergencyUser.
+func (in *EmergencyUser) DeepCopy() *EmergencyUser {
+	if in == nil {
+		return nil
+	}
+	out := new(EmergencyUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirewallRule) DeepCopyInto(out *FirewallRule) {
+	*out = *in
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirewallRule.
+func (in *FirewallRule) DeepCopy() *FirewallRule {
+	if in == nil {
+		return nil
+	}
+	out := new(FirewallRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GPUSpec) DeepCopyInto(out *GPUSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GPUSpec.
+func (in *GPUSpec) DeepCopy() *GPUSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GPUSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthMonitor) DeepCopyInto(out *HealthMonitor) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthMonitor.
+func (in *HealthMonitor) DeepCopy() *HealthMonitor {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthMonitor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPAllocationConfig) DeepCopyInto(out *IPAllocationConfig) {
+	*out = *in
+	if in.DHCPPool != nil {
+		in, out := &in.DHCPPool, &out.DHCPPool
+		*out = new(IPRange)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPAllocationConfig.
+func (in *IPAllocationConfig) DeepCopy() *IPAllocationConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(IPAllocationConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPRange) DeepCopyInto(out *IPRange) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPRange.
+func (in *IPRange) DeepCopy() *IPRange {
+	if in == nil {
+		return nil
+	}
+	out := new(IPRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeVipConfig) DeepCopyInto(out *KubeVipConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeVipConfig.
+func (in *KubeVipConfig) DeepCopy() *KubeVipConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeVipConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeletConfig) DeepCopyInto(out *KubeletConfig) {
+	*out = *in
+	if in.ExtraArgs != nil {
+		in, out := &in.ExtraArgs, &out.ExtraArgs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SystemReserved != nil {
+		in, out := &in.SystemReserved, &out.SystemReserved
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeletConfig.
+func (in *KubeletConfig) DeepCopy() *KubeletConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeletConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LoadBalancerConfig) DeepCopyInto(out *LoadBalancerConfig) {
 	*out = *in
+	if in.HealthMonitor != nil {
+		in, out := &in.HealthMonitor, &out.HealthMonitor
+		*out = new(HealthMonitor)
+		**out = **in
+	}
+	if in.PersistenceProfile != nil {
+		in, out := &in.PersistenceProfile, &out.PersistenceProfile
+		*out = new(PersistenceProfile)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerConfig.
+func (in *LoadBalancerConfig) DeepCopy() *LoadBalancerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadBalancerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MachinePolicies) DeepCopyInto(out *MachinePolicies) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MachinePolicies.
+func (in *MachinePolicies) DeepCopy() *MachinePolicies {
+	if in == nil {
+		return nil
+	}
+	out := new(MachinePolicies)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedNetworkConfig) DeepCopyInto(out *ManagedNetworkConfig) {
+	*out = *in
+	if in.DNSServers != nil {
+		in, out := &in.DNSServers, &out.DNSServers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StaticIPPool != nil {
+		in, out := &in.StaticIPPool, &out.StaticIPPool
+		*out = make([]IPRange, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedNetworkConfig.
+func (in *ManagedNetworkConfig) DeepCopy() *ManagedNetworkConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedNetworkConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetadataPropagationSpec) DeepCopyInto(out *MetadataPropagationSpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetadataPropagationSpec.
+func (in *MetadataPropagationSpec) DeepCopy() *MetadataPropagationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MetadataPropagationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkSpec) DeepCopyInto(out *NetworkSpec) {
+	*out = *in
+	if in.IPAddressPool != nil {
+		in, out := &in.IPAddressPool, &out.IPAddressPool
+		*out = new(v1.TypedLocalObjectReference)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkSpec.
+func (in *NetworkSpec) DeepCopy() *NetworkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PersistenceProfile) DeepCopyInto(out *PersistenceProfile) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PersistenceProfile.
+func (in *PersistenceProfile) DeepCopy() *PersistenceProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(PersistenceProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Ports) DeepCopyInto(out *Ports) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Ports.
+func (in *Ports) DeepCopy() *Ports {
+	if in == nil {
+		return nil
+	}
+	out := new(Ports)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxyConfig) DeepCopyInto(out *ProxyConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxyConfig.
+func (in *ProxyConfig) DeepCopy() *ProxyConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxyConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScriptRef) DeepCopyInto(out *ScriptRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScriptRef.
+func (in *ScriptRef) DeepCopy() *ScriptRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ScriptRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SubsystemRunTimes) DeepCopyInto(out *SubsystemRunTimes) {
+	*out = *in
+	if in.Infrastructure != nil {
+		in, out := &in.Infrastructure, &out.Infrastructure
+		*out = (*in).DeepCopy()
+	}
+	if in.RDE != nil {
+		in, out := &in.RDE, &out.RDE
+		*out = (*in).DeepCopy()
+	}
+	if in.RDEDesiredStateSync != nil {
+		in, out := &in.RDEDesiredStateSync, &out.RDEDesiredStateSync
+		*out = (*in).DeepCopy()
+	}
+	if in.Audit != nil {
+		in, out := &in.Audit, &out.Audit
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubsystemRunTimes.
+func (in *SubsystemRunTimes) DeepCopy() *SubsystemRunTimes {
+	if in == nil {
+		return nil
+	}
+	out := new(SubsystemRunTimes)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateCacheSpec) DeepCopyInto(out *TemplateCacheSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateCacheSpec.
+func (in *TemplateCacheSpec) DeepCopy() *TemplateCacheSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateCacheSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateImportStatus) DeepCopyInto(out *TemplateImportStatus) {
+	*out = *in
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateImportStatus.
+func (in *TemplateImportStatus) DeepCopy() *TemplateImportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateImportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateSource) DeepCopyInto(out *TemplateSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateSource.
+func (in *TemplateSource) DeepCopy() *TemplateSource {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserCredentialsContext) DeepCopyInto(out *UserCredentialsContext) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserCredentialsContext.
+func (in *UserCredentialsContext) DeepCopy() *UserCredentialsContext {
+	if in == nil {
+		return nil
+	}
+	out := new(UserCredentialsContext)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VAppSharding) DeepCopyInto(out *VAppSharding) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VAppSharding.
+func (in *VAppSharding) DeepCopy() *VAppSharding {
+	if in == nil {
+		return nil
+	}
+	out := new(VAppSharding)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VAppStrategy) DeepCopyInto(out *VAppStrategy) {
+	*out = *in
+	if in.Sharded != nil {
+		in, out := &in.Sharded, &out.Sharded
+		*out = new(VAppSharding)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VAppStrategy.
+func (in *VAppStrategy) DeepCopy() *VAppStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(VAppStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCDCluster) DeepCopyInto(out *VCDCluster) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDCluster.
+func (in *VCDCluster) DeepCopy() *VCDCluster {
+	if in == nil {
+		return nil
+	}
+	out := new(VCDCluster)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VCDCluster) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCDClusterIdentity) DeepCopyInto(out *VCDClusterIdentity) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDClusterIdentity.
+func (in *VCDClusterIdentity) DeepCopy() *VCDClusterIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(VCDClusterIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VCDClusterIdentity) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCDClusterIdentityList) DeepCopyInto(out *VCDClusterIdentityList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VCDClusterIdentity, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDClusterIdentityList.
+func (in *VCDClusterIdentityList) DeepCopy() *VCDClusterIdentityList {
+	if in == nil {
+		return nil
+	}
+	out := new(VCDClusterIdentityList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VCDClusterIdentityList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCDClusterIdentityReference) DeepCopyInto(out *VCDClusterIdentityReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDClusterIdentityReference.
+func (in *VCDClusterIdentityReference) DeepCopy() *VCDClusterIdentityReference {
+	if in == nil {
+		return nil
+	}
+	out := new(VCDClusterIdentityReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCDClusterIdentitySpec) DeepCopyInto(out *VCDClusterIdentitySpec) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = new(AllowedNamespaces)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDClusterIdentitySpec.
+func (in *VCDClusterIdentitySpec) DeepCopy() *VCDClusterIdentitySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VCDClusterIdentitySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCDClusterList) DeepCopyInto(out *VCDClusterList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VCDCluster, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDClusterList.
+func (in *VCDClusterList) DeepCopy() *VCDClusterList {
+	if in == nil {
+		return nil
+	}
+	out := new(VCDClusterList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VCDClusterList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCDClusterSpec) DeepCopyInto(out *VCDClusterSpec) {
+	*out = *in
+	out.ControlPlaneEndpoint = in.ControlPlaneEndpoint
+	if in.ManagedNetwork != nil {
+		in, out := &in.ManagedNetwork, &out.ManagedNetwork
+		*out = new(ManagedNetworkConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	in.UserCredentialsContext.DeepCopyInto(&out.UserCredentialsContext)
+	if in.IdentityRef != nil {
+		in, out := &in.IdentityRef, &out.IdentityRef
+		*out = new(VCDClusterIdentityReference)
+		**out = **in
+	}
+	out.ProxyConfigSpec = in.ProxyConfigSpec
+	in.LoadBalancerConfigSpec.DeepCopyInto(&out.LoadBalancerConfigSpec)
+	out.DefaultMachinePolicies = in.DefaultMachinePolicies
+	in.IPAllocation.DeepCopyInto(&out.IPAllocation)
+	in.EgressAllowlist.DeepCopyInto(&out.EgressAllowlist)
+	if in.EgressConfig != nil {
+		in, out := &in.EgressConfig, &out.EgressConfig
+		*out = new(EgressConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FirewallRules != nil {
+		in, out := &in.FirewallRules, &out.FirewallRules
+		*out = make([]FirewallRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.KubeVip = in.KubeVip
+	if in.SiteCertificateFingerprints != nil {
+		in, out := &in.SiteCertificateFingerprints, &out.SiteCertificateFingerprints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VCDTrustBundleSecretRef != nil {
+		in, out := &in.VCDTrustBundleSecretRef, &out.VCDTrustBundleSecretRef
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+	if in.MetadataPropagation != nil {
+		in, out := &in.MetadataPropagation, &out.MetadataPropagation
+		*out = new(MetadataPropagationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TemplateCache != nil {
+		in, out := &in.TemplateCache, &out.TemplateCache
+		*out = new(TemplateCacheSpec)
+		**out = **in
+	}
+	if in.IPPoolExtension != nil {
+		in, out := &in.IPPoolExtension, &out.IPPoolExtension
+		*out = new(IPRange)
+		**out = **in
+	}
+	if in.TemplateSources != nil {
+		in, out := &in.TemplateSources, &out.TemplateSources
+		*out = make([]TemplateSource, len(*in))
+		copy(*out, *in)
+	}
+	if in.VAppStrategy != nil {
+		in, out := &in.VAppStrategy, &out.VAppStrategy
+		*out = new(VAppStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDClusterSpec.
+func (in *VCDClusterSpec) DeepCopy() *VCDClusterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VCDClusterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCDClusterStatus) DeepCopyInto(out *VCDClusterStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(v1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.VcdResourceMap.DeepCopyInto(&out.VcdResourceMap)
+	out.ProxyConfig = in.ProxyConfig
+	in.LoadBalancerConfig.DeepCopyInto(&out.LoadBalancerConfig)
+	if in.EgressIPs != nil {
+		in, out := &in.EgressIPs, &out.EgressIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.EgressAllowlist != nil {
+		in, out := &in.EgressAllowlist, &out.EgressAllowlist
+		*out = make([]EgressDestination, len(*in))
+		copy(*out, *in)
+	}
+	if in.FirewallRules != nil {
+		in, out := &in.FirewallRules, &out.FirewallRules
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ResolvedReferences != nil {
+		in, out := &in.ResolvedReferences, &out.ResolvedReferences
+		*out = make(VCDResources, len(*in))
+		copy(*out, *in)
+	}
+	in.LastSubsystemRuns.DeepCopyInto(&out.LastSubsystemRuns)
+	in.PhaseTransitions.DeepCopyInto(&out.PhaseTransitions)
+	if in.TemplateImports != nil {
+		in, out := &in.TemplateImports, &out.TemplateImports
+		*out = make([]TemplateImportStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerConfig.
-func (in *LoadBalancerConfig) DeepCopy() *LoadBalancerConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDClusterStatus.
+func (in *VCDClusterStatus) DeepCopy() *VCDClusterStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(LoadBalancerConfig)
+	out := new(VCDClusterStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Ports) DeepCopyInto(out *Ports) {
+func (in *VCDClusterTemplate) DeepCopyInto(out *VCDClusterTemplate) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Ports.
-func (in *Ports) DeepCopy() *Ports {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDClusterTemplate.
+func (in *VCDClusterTemplate) DeepCopy() *VCDClusterTemplate {
 	if in == nil {
 		return nil
 	}
-	out := new(Ports)
+	out := new(VCDClusterTemplate)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VCDClusterTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProxyConfig) DeepCopyInto(out *ProxyConfig) {
+func (in *VCDClusterTemplateList) DeepCopyInto(out *VCDClusterTemplateList) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VCDClusterTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxyConfig.
-func (in *ProxyConfig) DeepCopy() *ProxyConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDClusterTemplateList.
+func (in *VCDClusterTemplateList) DeepCopy() *VCDClusterTemplateList {
 	if in == nil {
 		return nil
 	}
-	out := new(ProxyConfig)
+	out := new(VCDClusterTemplateList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VCDClusterTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *UserCredentialsContext) DeepCopyInto(out *UserCredentialsContext) {
+func (in *VCDClusterTemplateResource) DeepCopyInto(out *VCDClusterTemplateResource) {
 	*out = *in
-	if in.SecretRef != nil {
-		in, out := &in.SecretRef, &out.SecretRef
-		*out = new(v1.SecretReference)
-		**out = **in
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDClusterTemplateResource.
+func (in *VCDClusterTemplateResource) DeepCopy() *VCDClusterTemplateResource {
+	if in == nil {
+		return nil
 	}
+	out := new(VCDClusterTemplateResource)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserCredentialsContext.
-func (in *UserCredentialsContext) DeepCopy() *UserCredentialsContext {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCDClusterTemplateSpec) DeepCopyInto(out *VCDClusterTemplateSpec) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDClusterTemplateSpec.
+func (in *VCDClusterTemplateSpec) DeepCopy() *VCDClusterTemplateSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(UserCredentialsContext)
+	out := new(VCDClusterTemplateSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VCDCluster) DeepCopyInto(out *VCDCluster) {
+func (in *VCDMachine) DeepCopyInto(out *VCDMachine) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -115,18 +1079,18 @@ func (in *VCDCluster) DeepCopyInto(out *VCDCluster) {
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDCluster.
-func (in *VCDCluster) DeepCopy() *VCDCluster {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDMachine.
+func (in *VCDMachine) DeepCopy() *VCDMachine {
 	if in == nil {
 		return nil
 	}
-	out := new(VCDCluster)
+	out := new(VCDMachine)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VCDCluster) DeepCopyObject() runtime.Object {
+func (in *VCDMachine) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -134,31 +1098,31 @@ func (in *VCDCluster) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VCDClusterList) DeepCopyInto(out *VCDClusterList) {
+func (in *VCDMachineList) DeepCopyInto(out *VCDMachineList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]VCDCluster, len(*in))
+		*out = make([]VCDMachine, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDClusterList.
-func (in *VCDClusterList) DeepCopy() *VCDClusterList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDMachineList.
+func (in *VCDMachineList) DeepCopy() *VCDMachineList {
 	if in == nil {
 		return nil
 	}
-	out := new(VCDClusterList)
+	out := new(VCDMachineList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VCDClusterList) DeepCopyObject() runtime.Object {
+func (in *VCDMachineList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -166,70 +1130,73 @@ func (in *VCDClusterList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VCDClusterSpec) DeepCopyInto(out *VCDClusterSpec) {
+func (in *VCDMachinePool) DeepCopyInto(out *VCDMachinePool) {
 	*out = *in
-	out.ControlPlaneEndpoint = in.ControlPlaneEndpoint
-	in.UserCredentialsContext.DeepCopyInto(&out.UserCredentialsContext)
-	out.ProxyConfigSpec = in.ProxyConfigSpec
-	out.LoadBalancerConfigSpec = in.LoadBalancerConfigSpec
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDClusterSpec.
-func (in *VCDClusterSpec) DeepCopy() *VCDClusterSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDMachinePool.
+func (in *VCDMachinePool) DeepCopy() *VCDMachinePool {
 	if in == nil {
 		return nil
 	}
-	out := new(VCDClusterSpec)
+	out := new(VCDMachinePool)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VCDMachinePool) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VCDClusterStatus) DeepCopyInto(out *VCDClusterStatus) {
+func (in *VCDMachinePoolInstance) DeepCopyInto(out *VCDMachinePoolInstance) {
 	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make(v1beta1.Conditions, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	in.VcdResourceMap.DeepCopyInto(&out.VcdResourceMap)
-	out.ProxyConfig = in.ProxyConfig
-	out.LoadBalancerConfig = in.LoadBalancerConfig
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDClusterStatus.
-func (in *VCDClusterStatus) DeepCopy() *VCDClusterStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDMachinePoolInstance.
+func (in *VCDMachinePoolInstance) DeepCopy() *VCDMachinePoolInstance {
 	if in == nil {
 		return nil
 	}
-	out := new(VCDClusterStatus)
+	out := new(VCDMachinePoolInstance)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VCDMachine) DeepCopyInto(out *VCDMachine) {
+func (in *VCDMachinePoolList) DeepCopyInto(out *VCDMachinePoolList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VCDMachinePool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDMachine.
-func (in *VCDMachine) DeepCopy() *VCDMachine {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDMachinePoolList.
+func (in *VCDMachinePoolList) DeepCopy() *VCDMachinePoolList {
 	if in == nil {
 		return nil
 	}
-	out := new(VCDMachine)
+	out := new(VCDMachinePoolList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VCDMachine) DeepCopyObject() runtime.Object {
+func (in *VCDMachinePoolList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -237,35 +1204,66 @@ func (in *VCDMachine) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VCDMachineList) DeepCopyInto(out *VCDMachineList) {
+func (in *VCDMachinePoolSpec) DeepCopyInto(out *VCDMachinePoolSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]VCDMachine, len(*in))
+	if in.ProviderIDList != nil {
+		in, out := &in.ProviderIDList, &out.ProviderIDList
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Template = in.Template
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDMachinePoolSpec.
+func (in *VCDMachinePoolSpec) DeepCopy() *VCDMachinePoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VCDMachinePoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCDMachinePoolStatus) DeepCopyInto(out *VCDMachinePoolStatus) {
+	*out = *in
+	if in.Instances != nil {
+		in, out := &in.Instances, &out.Instances
+		*out = make([]VCDMachinePoolInstance, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(v1beta1.Conditions, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDMachineList.
-func (in *VCDMachineList) DeepCopy() *VCDMachineList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDMachinePoolStatus.
+func (in *VCDMachinePoolStatus) DeepCopy() *VCDMachinePoolStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(VCDMachineList)
+	out := new(VCDMachinePoolStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VCDMachineList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCDMachinePoolTemplate) DeepCopyInto(out *VCDMachinePoolTemplate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDMachinePoolTemplate.
+func (in *VCDMachinePoolTemplate) DeepCopy() *VCDMachinePoolTemplate {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(VCDMachinePoolTemplate)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -276,12 +1274,111 @@ func (in *VCDMachineSpec) DeepCopyInto(out *VCDMachineSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.NumCPUs != nil {
+		in, out := &in.NumCPUs, &out.NumCPUs
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CoresPerSocket != nil {
+		in, out := &in.CoresPerSocket, &out.CoresPerSocket
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MemoryMiB != nil {
+		in, out := &in.MemoryMiB, &out.MemoryMiB
+		*out = new(int64)
+		**out = **in
+	}
 	out.DiskSize = in.DiskSize.DeepCopy()
+	if in.DataDisks != nil {
+		in, out := &in.DataDisks, &out.DataDisks
+		*out = make([]DiskSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BootDiskIops != nil {
+		in, out := &in.BootDiskIops, &out.BootDiskIops
+		*out = new(int64)
+		**out = **in
+	}
+	if in.GPU != nil {
+		in, out := &in.GPU, &out.GPU
+		*out = new(GPUSpec)
+		**out = **in
+	}
 	if in.ExtraOvdcNetworks != nil {
 		in, out := &in.ExtraOvdcNetworks, &out.ExtraOvdcNetworks
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Networks != nil {
+		in, out := &in.Networks, &out.Networks
+		*out = make([]NetworkSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.KubeletConfig != nil {
+		in, out := &in.KubeletConfig, &out.KubeletConfig
+		*out = new(KubeletConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AntiAffinity != nil {
+		in, out := &in.AntiAffinity, &out.AntiAffinity
+		*out = new(AntiAffinitySpec)
+		**out = **in
+	}
+	if in.MetadataPropagation != nil {
+		in, out := &in.MetadataPropagation, &out.MetadataPropagation
+		*out = new(MetadataPropagationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PrePullImages != nil {
+		in, out := &in.PrePullImages, &out.PrePullImages
+		*out = make([]ImageReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.PreKubeadmCommandsScriptRef != nil {
+		in, out := &in.PreKubeadmCommandsScriptRef, &out.PreKubeadmCommandsScriptRef
+		*out = new(ScriptRef)
+		**out = **in
+	}
+	if in.PostKubeadmCommandsScriptRef != nil {
+		in, out := &in.PostKubeadmCommandsScriptRef, &out.PostKubeadmCommandsScriptRef
+		*out = new(ScriptRef)
+		**out = **in
+	}
+	if in.SSHAuthorizedKeys != nil {
+		in, out := &in.SSHAuthorizedKeys, &out.SSHAuthorizedKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.EmergencyUser != nil {
+		in, out := &in.EmergencyUser, &out.EmergencyUser
+		*out = new(EmergencyUser)
+		**out = **in
+	}
+	if in.ProvisioningTimeout != nil {
+		in, out := &in.ProvisioningTimeout, &out.ProvisioningTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.BootstrapTimeout != nil {
+		in, out := &in.BootstrapTimeout, &out.BootstrapTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.GracefulShutdownTimeout != nil {
+		in, out := &in.GracefulShutdownTimeout, &out.GracefulShutdownTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.SnapshotRetention != nil {
+		in, out := &in.SnapshotRetention, &out.SnapshotRetention
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDMachineSpec.
@@ -315,6 +1412,39 @@ func (in *VCDMachineStatus) DeepCopyInto(out *VCDMachineStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ResolvedReferences != nil {
+		in, out := &in.ResolvedReferences, &out.ResolvedReferences
+		*out = make(VCDResources, len(*in))
+		copy(*out, *in)
+	}
+	if in.ShutdownStartTime != nil {
+		in, out := &in.ShutdownStartTime, &out.ShutdownStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Hardware != nil {
+		in, out := &in.Hardware, &out.Hardware
+		*out = new(VMHardware)
+		**out = **in
+	}
+	if in.BootstrapDiagnostics != nil {
+		in, out := &in.BootstrapDiagnostics, &out.BootstrapDiagnostics
+		*out = new(BootstrapDiagnostics)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BootstrapStartTime != nil {
+		in, out := &in.BootstrapStartTime, &out.BootstrapStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.FailureReason != nil {
+		in, out := &in.FailureReason, &out.FailureReason
+		*out = new(errors.MachineStatusError)
+		**out = **in
+	}
+	if in.FailureMessage != nil {
+		in, out := &in.FailureMessage, &out.FailureMessage
+		*out = new(string)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDMachineStatus.
@@ -457,6 +1587,21 @@ func (in *VCDResourceMap) DeepCopyInto(out *VCDResourceMap) {
 		*out = make(VCDResources, len(*in))
 		copy(*out, *in)
 	}
+	if in.AppPortProfiles != nil {
+		in, out := &in.AppPortProfiles, &out.AppPortProfiles
+		*out = make(VCDResources, len(*in))
+		copy(*out, *in)
+	}
+	if in.OvdcNetworks != nil {
+		in, out := &in.OvdcNetworks, &out.OvdcNetworks
+		*out = make(VCDResources, len(*in))
+		copy(*out, *in)
+	}
+	if in.VApps != nil {
+		in, out := &in.VApps, &out.VApps
+		*out = make(VCDResources, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCDResourceMap.
@@ -487,3 +1632,18 @@ func (in VCDResources) DeepCopy() VCDResources {
 	in.DeepCopyInto(out)
 	return *out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMHardware) DeepCopyInto(out *VMHardware) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMHardware.
+func (in *VMHardware) DeepCopy() *VMHardware {
+	if in == nil {
+		return nil
+	}
+	out := new(VMHardware)
+	in.DeepCopyInto(out)
+	return out
+}
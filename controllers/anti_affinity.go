@@ -0,0 +1,194 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Types of the VM anti-affinity rule of the machines of a KubeadmControlPlane or MachineDeployment
+const (
+	AntiAffinityTypeSoft = "Soft"
+	AntiAffinityTypeHard = "Hard"
+)
+
+// getAntiAffinityGroupLabel returns the label and its value identifying the machines of the KubeadmControlPlane or
+// MachineDeployment of the machine. An empty label is returned if the machine belongs to neither.
+func getAntiAffinityGroupLabel(machine *clusterv1.Machine) (string, string) {
+	if name, ok := machine.Labels[clusterv1.MachineControlPlaneNameLabel]; ok && name != "" {
+		return clusterv1.MachineControlPlaneNameLabel, name
+	}
+	if name, ok := machine.Labels[clusterv1.MachineDeploymentNameLabel]; ok && name != "" {
+		return clusterv1.MachineDeploymentNameLabel, name
+	}
+	return "", ""
+}
+
+// getAntiAffinityRuleName returns the name of the VM anti-affinity rule of the machines of the KubeadmControlPlane or
+// MachineDeployment of the machine.
+func getAntiAffinityRuleName(vcdCluster *infrav1beta3.VCDCluster, groupLabel string, groupName string) string {
+	kind := "md"
+	if groupLabel == clusterv1.MachineControlPlaneNameLabel {
+		kind = "cp"
+	}
+	return fmt.Sprintf("%s-%s-%s-anti-affinity",
+		capisdk.GetVirtualServiceNamePrefix(vcdCluster.Name, vcdCluster.Status.InfraId), kind, groupName)
+}
+
+// getAntiAffinityRuleMembers returns the references of the VMs of the machines of the KubeadmControlPlane or
+// MachineDeployment which have an anti-affinity rule and are not being deleted, sorted by HREF. Machines whose VM has
+// not been created yet are skipped.
+func (r *VCDMachineReconciler) getAntiAffinityRuleMembers(ctx context.Context, machine *clusterv1.Machine,
+	groupLabel string, groupName string, vApp *govcd.VApp, vcdCluster *infrav1beta3.VCDCluster) ([]*types.Reference,
+	error) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	machineList := &clusterv1.MachineList{}
+	if err := r.Client.List(ctx, machineList, client.InNamespace(machine.Namespace), client.MatchingLabels{
+		clusterv1.ClusterNameLabel: machine.Spec.ClusterName,
+		groupLabel:                 groupName,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list the machines with label [%s=%s]: [%v]", groupLabel, groupName, err)
+	}
+
+	vmRefs := make(map[string]*types.Reference)
+	if vApp.VApp.Children != nil {
+		for _, vm := range vApp.VApp.Children.VM {
+			vmRefs[vm.Name] = &types.Reference{HREF: vm.HREF, ID: vm.ID, Name: vm.Name}
+		}
+	}
+
+	members := make([]*types.Reference, 0)
+	for idx := range machineList.Items {
+		member := &machineList.Items[idx]
+		if !member.DeletionTimestamp.IsZero() {
+			continue
+		}
+		vcdMachine := &infrav1beta3.VCDMachine{}
+		err := r.Client.Get(ctx, client.ObjectKey{
+			Namespace: member.Namespace,
+			Name:      member.Spec.InfrastructureRef.Name,
+		}, vcdMachine)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get VCDMachine [%s] of machine [%s]: [%v]",
+				member.Spec.InfrastructureRef.Name, member.Name, err)
+		}
+		if vcdMachine.Spec.AntiAffinity == nil {
+			continue
+		}
+		vmName, err := getVMName(member, vcdMachine, vcdCluster, log)
+		if err != nil {
+			return nil, err
+		}
+		if vmRef, ok := vmRefs[vmName]; ok {
+			members = append(members, vmRef)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].HREF < members[j].HREF
+	})
+	return members, nil
+}
+
+// reconcileAntiAffinityRule maintains the VM anti-affinity rule of the KubeadmControlPlane or MachineDeployment of the
+// machine so that it contains the VMs of its machines which are not being deleted. VCD requires at least two VMs in a
+// rule: the rule is created once two VMs exist and deleted when fewer remain.
+func (r *VCDMachineReconciler) reconcileAntiAffinityRule(ctx context.Context, vdc *govcd.Vdc, vApp *govcd.VApp,
+	machine *clusterv1.Machine, vcdMachine *infrav1beta3.VCDMachine, vcdCluster *infrav1beta3.VCDCluster) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	if vcdMachine.Spec.AntiAffinity == nil {
+		return nil
+	}
+	groupLabel, groupName := getAntiAffinityGroupLabel(machine)
+	if groupLabel == "" {
+		log.Info("Skipping the anti-affinity rule of a machine which belongs to no control plane or machine deployment")
+		return nil
+	}
+	if vApp == nil || vApp.VApp == nil {
+		return fmt.Errorf("no vApp found to reconcile the anti-affinity rule of machine [%s]", machine.Name)
+	}
+
+	ruleName := getAntiAffinityRuleName(vcdCluster, groupLabel, groupName)
+	members, err := r.getAntiAffinityRuleMembers(ctx, machine, groupLabel, groupName, vApp, vcdCluster)
+	if err != nil {
+		return fmt.Errorf("failed to get the VMs of anti-affinity rule [%s]: [%v]", ruleName, err)
+	}
+	isMandatory := vcdMachine.Spec.AntiAffinity.Type == AntiAffinityTypeHard
+
+	rules, err := vdc.GetVmAffinityRulesByName(ruleName, types.PolarityAntiAffinity)
+	if err != nil {
+		return fmt.Errorf("failed to get anti-affinity rule [%s]: [%v]", ruleName, err)
+	}
+	if len(rules) > 1 {
+		return fmt.Errorf("found [%d] anti-affinity rules named [%s]", len(rules), ruleName)
+	}
+
+	if len(rules) == 0 {
+		if len(members) < 2 {
+			return nil
+		}
+		isEnabled := true
+		_, err = vdc.CreateVmAffinityRule(&types.VmAffinityRule{
+			Name:         ruleName,
+			IsEnabled:    &isEnabled,
+			IsMandatory:  &isMandatory,
+			Polarity:     types.PolarityAntiAffinity,
+			VmReferences: []*types.VMs{{VMReference: members}},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create anti-affinity rule [%s]: [%v]", ruleName, err)
+		}
+		log.Info("Created anti-affinity rule", "rule", ruleName, "vms", len(members))
+		return nil
+	}
+
+	rule := rules[0]
+	if len(members) < 2 {
+		if err = rule.Delete(); err != nil {
+			return fmt.Errorf("failed to delete anti-affinity rule [%s]: [%v]", ruleName, err)
+		}
+		log.Info("Deleted anti-affinity rule with fewer than two VMs", "rule", ruleName)
+		return nil
+	}
+
+	currentHREFs := make(map[string]bool)
+	for _, vmRefs := range rule.VmAffinityRule.VmReferences {
+		for _, vmRef := range vmRefs.VMReference {
+			if vmRef != nil {
+				currentHREFs[vmRef.HREF] = true
+			}
+		}
+	}
+	upToDate := len(currentHREFs) == len(members) && rule.VmAffinityRule.IsMandatory != nil &&
+		*rule.VmAffinityRule.IsMandatory == isMandatory
+	for _, member := range members {
+		upToDate = upToDate && currentHREFs[member.HREF]
+	}
+	if upToDate {
+		return nil
+	}
+
+	rule.VmAffinityRule.IsMandatory = &isMandatory
+	rule.VmAffinityRule.VmReferences = []*types.VMs{{VMReference: members}}
+	if err = rule.Update(); err != nil {
+		return fmt.Errorf("failed to update anti-affinity rule [%s]: [%v]", ruleName, err)
+	}
+	log.Info("Updated anti-affinity rule", "rule", ruleName, "vms", len(members))
+	return nil
+}
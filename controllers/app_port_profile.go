@@ -0,0 +1,113 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	vcdsdkutil "github.com/vmware/cloud-provider-for-cloud-director/pkg/util"
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// trackAppPortProfiles records the application port profiles created for the load balancer of the cluster in the
+// resource map of the VCDCluster, so that they are deleted with the cluster even if they are no longer referenced by a
+// DNAT rule.
+func trackAppPortProfiles(vcdCluster *infrav1beta3.VCDCluster, resourcesAllocated *vcdsdkutil.AllocatedResourcesMap) error {
+	for _, appPortProfileRef := range resourcesAllocated.Get(vcdsdk.VcdResourceAppPortProfile) {
+		if appPortProfileRef.Id == "" {
+			continue
+		}
+		if err := updateVdcResourceToVcdCluster(vcdCluster, ResourceTypeAppPortProfile, appPortProfileRef.Id,
+			appPortProfileRef.Name); err != nil {
+			return fmt.Errorf("failed to record app port profile [%s] of cluster [%s]: [%v]",
+				appPortProfileRef.Name, vcdCluster.Name, err)
+		}
+	}
+	return nil
+}
+
+// getAppPortProfileNamePrefix returns the prefix of the names of the application port profiles of the DNAT rules of
+// the virtual services of the cluster.
+func getAppPortProfileNamePrefix(virtualServiceNamePrefix string) string {
+	return vcdsdk.GetAppPortProfileName(vcdsdk.GetDNATRuleName(virtualServiceNamePrefix + "-"))
+}
+
+// deleteAppPortProfiles deletes the application port profiles of the cluster once its load balancer is deleted. The
+// profiles recorded in the resource map are deleted by ID; as a fallback for profiles left behind by earlier failed
+// deletions or created before they were recorded, the profiles named after the virtual services of the cluster are
+// deleted too. The DNAT rules referencing them, which the load balancer deletion skips when one-arm was disabled after
+// their creation, are deleted first.
+func deleteAppPortProfiles(ctx context.Context, vcdClient *vcdsdk.Client, gateway *vcdsdk.GatewayManager,
+	vcdCluster *infrav1beta3.VCDCluster, virtualServiceNamePrefix string) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	org, err := vcdClient.VCDClient.GetOrgByName(vcdClient.ClusterOrgName)
+	if err != nil {
+		return fmt.Errorf("error getting org by name for org [%s]: [%v]", vcdClient.ClusterOrgName, err)
+	}
+
+	trackedAppPortProfiles := append(infrav1beta3.VCDResources{}, vcdCluster.Status.VcdResourceMap.AppPortProfiles...)
+	for _, trackedAppPortProfile := range trackedAppPortProfiles {
+		appPortProfile, err := org.GetNsxtAppPortProfileById(trackedAppPortProfile.ID)
+		if err != nil && !govcd.ContainsNotFound(err) {
+			return fmt.Errorf("unable to get app port profile [%s(%s)]: [%v]", trackedAppPortProfile.Name,
+				trackedAppPortProfile.ID, err)
+		}
+		if err == nil && appPortProfile != nil {
+			if err = deleteAppPortProfile(ctx, gateway, appPortProfile); err != nil {
+				return err
+			}
+		}
+		if err = removeVcdResourceFromVcdCluster(vcdCluster, ResourceTypeAppPortProfile,
+			trackedAppPortProfile.ID); err != nil {
+			log.Error(err, "failed to remove app port profile from the resource map of the cluster",
+				"appPortProfile", trackedAppPortProfile.Name)
+		}
+	}
+
+	appPortProfileNamePrefix := getAppPortProfileNamePrefix(virtualServiceNamePrefix)
+	appPortProfiles, err := org.GetAllNsxtAppPortProfiles(nil, types.ApplicationPortProfileScopeTenant)
+	if err != nil {
+		return fmt.Errorf("unable to list app port profiles of org [%s]: [%v]", vcdClient.ClusterOrgName, err)
+	}
+	for _, appPortProfile := range appPortProfiles {
+		if appPortProfile.NsxtAppPortProfile == nil ||
+			!strings.HasPrefix(appPortProfile.NsxtAppPortProfile.Name, appPortProfileNamePrefix) {
+			continue
+		}
+		log.Info("Deleting leftover app port profile of the cluster", "appPortProfile",
+			appPortProfile.NsxtAppPortProfile.Name)
+		if err = deleteAppPortProfile(ctx, gateway, appPortProfile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteAppPortProfile deletes the application port profile after the DNAT rule it was created for.
+func deleteAppPortProfile(ctx context.Context, gateway *vcdsdk.GatewayManager,
+	appPortProfile *govcd.NsxtAppPortProfile) error {
+
+	appPortProfileName := appPortProfile.NsxtAppPortProfile.Name
+	dnatRuleName := strings.TrimPrefix(appPortProfileName, vcdsdk.GetAppPortProfileName(""))
+	if dnatRuleName != appPortProfileName {
+		if err := gateway.DeleteDNATRule(ctx, dnatRuleName, false); err != nil {
+			return fmt.Errorf("unable to delete dnat rule [%s] of app port profile [%s]: [%v]", dnatRuleName,
+				appPortProfileName, err)
+		}
+	}
+	if err := appPortProfile.Delete(); err != nil {
+		return fmt.Errorf("unable to delete app port profile [%s]: [%v]", appPortProfileName, err)
+	}
+	return nil
+}
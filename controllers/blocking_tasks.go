@@ -0,0 +1,220 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// BlockedTaskStatus is the status of a VCD task held by a blocking task extension of the provider until the
+	// provider resumes or aborts it, e.g. through an approval workflow.
+	BlockedTaskStatus = "preRunning"
+	// VMCreationBlockedCheckDelay is the time after which a VM creation just started is checked for a task awaiting
+	// the approval of the provider.
+	VMCreationBlockedCheckDelay = 30 * time.Second
+	// VMCreationRequeueInterval is the interval at which a machine whose VM is being created checks the creation again.
+	VMCreationRequeueInterval = 15 * time.Second
+	// VMCreationTimeout is the time after which the creation of a VM running in the background is given up on. A VM
+	// created by VCD afterwards, e.g. once the provider approved it, is found by the next reconciliation.
+	VMCreationTimeout = time.Hour
+	// ProviderApprovalRequeueInterval is the interval at which a machine whose VM creation awaits the approval of the
+	// provider checks it again.
+	ProviderApprovalRequeueInterval = time.Minute
+)
+
+// vmCreation is the creation of a VM, which runs in the background so that a creation held by a blocking task does not
+// hold the reconciliation of the machine until the provider approves it. The creation outlives the reconciliation
+// which started it, so that it uses its own context, and is cancelled once its machine is deleted or its cluster is
+// no longer owned by the replica.
+type vmCreation struct {
+	// machine and cluster are the namespace/name of the VCDMachine and of the Cluster the VM is created for.
+	machine   string
+	cluster   string
+	startTime time.Time
+	cancel    context.CancelFunc
+	done      chan struct{}
+	err       error
+}
+
+var (
+	vmCreations     = make(map[string]*vmCreation)
+	vmCreationsLock sync.Mutex
+)
+
+// getVMCreationKey returns the key of the creation of the VM in the vApp.
+func getVMCreationKey(vApp *govcd.VApp, vmName string) string {
+	return fmt.Sprintf("%s/%s", vApp.VApp.HREF, vmName)
+}
+
+// getVMCreation returns the creation of the VM of the key started by an earlier reconciliation, or nil if there is
+// none.
+func getVMCreation(key string) *vmCreation {
+	vmCreationsLock.Lock()
+	defer vmCreationsLock.Unlock()
+	return vmCreations[key]
+}
+
+// startVMCreation starts the creation of the VM of the key for the VCDMachine of the cluster with create, whose
+// context is derived from ctx and given up on after VMCreationTimeout.
+func startVMCreation(ctx context.Context, key string, vcdMachine *infrav1beta3.VCDMachine, clusterName string,
+	create func(ctx context.Context) error) *vmCreation {
+
+	creationCtx, cancel := context.WithTimeout(ctx, VMCreationTimeout)
+	creation := &vmCreation{
+		machine:   fmt.Sprintf("%s/%s", vcdMachine.Namespace, vcdMachine.Name),
+		cluster:   fmt.Sprintf("%s/%s", vcdMachine.Namespace, clusterName),
+		startTime: time.Now(),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	vmCreationsLock.Lock()
+	vmCreations[key] = creation
+	vmCreationsLock.Unlock()
+
+	go func() {
+		defer close(creation.done)
+		defer cancel()
+		creation.err = create(creationCtx)
+	}()
+	return creation
+}
+
+// forgetVMCreation drops the creation of the VM of the key once its result was handled.
+func forgetVMCreation(key string) {
+	vmCreationsLock.Lock()
+	defer vmCreationsLock.Unlock()
+	delete(vmCreations, key)
+}
+
+// cancelVMCreations cancels and drops the creations matching, whose results are no longer awaited.
+func cancelVMCreations(matches func(creation *vmCreation) bool) {
+	vmCreationsLock.Lock()
+	defer vmCreationsLock.Unlock()
+	for key, creation := range vmCreations {
+		if matches(creation) {
+			creation.cancel()
+			delete(vmCreations, key)
+		}
+	}
+}
+
+// cancelMachineVMCreations cancels the creation of the VM of the VCDMachine, e.g. once it is deleted.
+func cancelMachineVMCreations(vcdMachine *infrav1beta3.VCDMachine) {
+	machine := fmt.Sprintf("%s/%s", vcdMachine.Namespace, vcdMachine.Name)
+	cancelVMCreations(func(creation *vmCreation) bool {
+		return creation.machine == machine
+	})
+}
+
+// cancelClusterVMCreations cancels the creations of the VMs of the cluster, e.g. once it is owned by another replica.
+func cancelClusterVMCreations(namespace string, clusterName string) {
+	cluster := fmt.Sprintf("%s/%s", namespace, clusterName)
+	cancelVMCreations(func(creation *vmCreation) bool {
+		return creation.cluster == cluster
+	})
+}
+
+// isDone checks if the creation completed, without waiting for it.
+func (c *vmCreation) isDone() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// getBlockedVAppTask returns the task of the vApp held by a blocking task of the provider, or nil if there is none.
+func getBlockedVAppTask(vApp *govcd.VApp) (*types.Task, error) {
+	if err := vApp.Refresh(); err != nil {
+		return nil, fmt.Errorf("unable to refresh vApp [%s]: [%v]", vApp.VApp.Name, err)
+	}
+	if vApp.VApp.Tasks == nil {
+		return nil, nil
+	}
+	for _, task := range vApp.VApp.Tasks.Task {
+		if task != nil && task.Status == BlockedTaskStatus {
+			return task, nil
+		}
+	}
+	return nil, nil
+}
+
+// markAwaitingProviderApproval sets the ProviderApproved condition to false for the blocked task and reports if it was
+// newly set.
+func markAwaitingProviderApproval(obj conditions.Setter, vAppName string, task *types.Task) bool {
+	newlyBlocked := !conditions.IsFalse(obj, ProviderApprovedCondition)
+	conditions.MarkFalse(obj, ProviderApprovedCondition, TaskBlockedByProviderReason, clusterv1.ConditionSeverityInfo,
+		"task [%s] of vApp [%s] awaits the approval of the provider: [%s]", task.OperationName, vAppName,
+		task.Operation)
+	return newlyBlocked
+}
+
+// clearAwaitingProviderApproval sets the ProviderApproved condition back to true and reports if it was false.
+func clearAwaitingProviderApproval(obj conditions.Setter) bool {
+	if !conditions.IsFalse(obj, ProviderApprovedCondition) {
+		return false
+	}
+	conditions.MarkTrue(obj, ProviderApprovedCondition)
+	return true
+}
+
+// checkProviderApproval checks if a task of the vApp of the machine is held by a blocking task of the provider, in
+// which case the machine is reported as awaiting the approval of the provider.
+func (r *VCDMachineReconciler) checkProviderApproval(ctx context.Context, vcdMachine *infrav1beta3.VCDMachine,
+	vApp *govcd.VApp) (bool, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	blockedTask, err := getBlockedVAppTask(vApp)
+	if err != nil {
+		return false, err
+	}
+	if blockedTask == nil {
+		return false, nil
+	}
+	if markAwaitingProviderApproval(vcdMachine, vApp.VApp.Name, blockedTask) {
+		log.Info("A task of the vApp of the machine awaits the approval of the provider", "vApp", vApp.VApp.Name,
+			"task", blockedTask.HREF, "operation", blockedTask.OperationName)
+		r.recordEvent(vcdMachine, corev1.EventTypeNormal, TaskBlockedByProviderReason,
+			fmt.Sprintf("Task [%s] of vApp [%s] awaits the approval of the provider", blockedTask.OperationName,
+				vApp.VApp.Name))
+	}
+	conditions.MarkFalse(vcdMachine, ContainerProvisionedCondition, TaskBlockedByProviderReason,
+		clusterv1.ConditionSeverityInfo, "task [%s] of vApp [%s] awaits the approval of the provider",
+		blockedTask.OperationName, vApp.VApp.Name)
+	return true, nil
+}
+
+// reportProviderApproval reports the outcome of a VM creation which awaited the approval of the provider, createErr
+// being the error of the creation.
+func (r *VCDMachineReconciler) reportProviderApproval(vcdMachine *infrav1beta3.VCDMachine, vmName string,
+	createErr error) {
+
+	if !clearAwaitingProviderApproval(vcdMachine) {
+		return
+	}
+	if createErr != nil {
+		r.recordEvent(vcdMachine, corev1.EventTypeWarning, TaskRejectedByProviderReason,
+			fmt.Sprintf("Creation of VM [%s] failed after awaiting the approval of the provider: [%v]", vmName,
+				createErr))
+		return
+	}
+	r.recordEvent(vcdMachine, corev1.EventTypeNormal, TaskApprovedByProviderReason,
+		fmt.Sprintf("Creation of VM [%s] was approved by the provider", vmName))
+}
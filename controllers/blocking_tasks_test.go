@@ -0,0 +1,86 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// startTestVMCreation starts the creation of a VM of the machine of the cluster, which runs until it is cancelled.
+func startTestVMCreation(t *testing.T, machineName string, clusterName string) (string, <-chan error) {
+	key := fmt.Sprintf("https://vcd/api/vApp/vapp-1/%s", machineName)
+	vcdMachine := &infrav1beta3.VCDMachine{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: machineName}}
+	result := make(chan error, 1)
+	startVMCreation(context.Background(), key, vcdMachine, clusterName, func(ctx context.Context) error {
+		<-ctx.Done()
+		result <- ctx.Err()
+		return ctx.Err()
+	})
+	t.Cleanup(func() {
+		cancelVMCreations(func(creation *vmCreation) bool { return true })
+	})
+	return key, result
+}
+
+func TestCancelVMCreations(t *testing.T) {
+	testCases := []struct {
+		name         string
+		cancel       func()
+		wantCanceled []bool
+	}{
+		{
+			name: "machine deleted",
+			cancel: func() {
+				cancelMachineVMCreations(&infrav1beta3.VCDMachine{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "machine-1"}})
+			},
+			wantCanceled: []bool{true, false, false},
+		},
+		{
+			name:         "cluster owned by another replica",
+			cancel:       func() { cancelClusterVMCreations("ns", "cluster-1") },
+			wantCanceled: []bool{true, true, false},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keys := make([]string, 0, 3)
+			results := make([]<-chan error, 0, 3)
+			for _, machine := range []struct{ name, cluster string }{
+				{name: "machine-1", cluster: "cluster-1"},
+				{name: "machine-2", cluster: "cluster-1"},
+				{name: "machine-3", cluster: "cluster-2"},
+			} {
+				key, result := startTestVMCreation(t, machine.name, machine.cluster)
+				keys = append(keys, key)
+				results = append(results, result)
+			}
+
+			tc.cancel()
+			for i, wantCanceled := range tc.wantCanceled {
+				if (getVMCreation(keys[i]) == nil) != wantCanceled {
+					t.Errorf("creation [%s] was dropped: [%t], want [%t]", keys[i], !wantCanceled, wantCanceled)
+				}
+				select {
+				case err := <-results[i]:
+					if !wantCanceled {
+						t.Errorf("creation [%s] was cancelled: [%v]", keys[i], err)
+					}
+				case <-time.After(100 * time.Millisecond):
+					if wantCanceled {
+						t.Errorf("creation [%s] was not cancelled", keys[i])
+					}
+				}
+			}
+		})
+	}
+}
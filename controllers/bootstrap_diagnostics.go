@@ -0,0 +1,229 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=create;update;patch
+
+const (
+	// PostCustomizationCloudInitOutput is the guestinfo key of the cloud-init output log of a VM whose bootstrap
+	// script failed.
+	PostCustomizationCloudInitOutput = "guestinfo.post_customization_cloud_init_output"
+	// ConsoleScreenshotKey is the key of the console screenshot in the Secret of the diagnostics of a VCDMachine.
+	ConsoleScreenshotKey = "screenshot.png"
+	// maxDiagnosticsOutputLength is the length the guest outputs kept in the status of a VCDMachine are truncated to.
+	maxDiagnosticsOutputLength = 2048
+)
+
+// getBootstrapPhases returns the guestinfo keys of the phases of the bootstrap of the machine, in the order they are
+// run by the cloud-init script of CAPVCD.
+func getBootstrapPhases(vcdCluster *infrav1beta3.VCDCluster, vcdMachine *infrav1beta3.VCDMachine,
+	isInitialControlPlane bool) []string {
+
+	// the control plane endpoint is checked once the API server runs on the initial control plane, and before
+	// joining on the other machines
+	phases := append([]string{}, postCustPhases...)
+	if isInitialControlPlane {
+		phases = append(phases, getKubeadmPhases(vcdMachine, KubeadmInit)...)
+		if vcdCluster.Spec.VerifyControlPlaneEndpoint {
+			phases = append(phases, ControlPlaneEndpointCheck)
+		}
+	} else {
+		if vcdCluster.Spec.VerifyControlPlaneEndpoint {
+			phases = append(phases, ControlPlaneEndpointCheck)
+		}
+		phases = append(phases, getKubeadmPhases(vcdMachine, KubeadmNodeJoin)...)
+	}
+
+	if vcdCluster.Spec.ProxyConfigSpec.HTTPSProxy == "" &&
+		vcdCluster.Spec.ProxyConfigSpec.HTTPProxy == "" {
+		phases = removeFromSlice(ProxyConfiguration, phases)
+	}
+	return phases
+}
+
+// getConsoleScreenshotSecretName returns the name of the Secret of the console screenshot of the VM of the machine.
+func getConsoleScreenshotSecretName(vcdMachine *infrav1beta3.VCDMachine) string {
+	return fmt.Sprintf("%s-console-screenshot", vcdMachine.Name)
+}
+
+// truncateDiagnosticsOutput keeps the end of a guest output, where the error usually is.
+func truncateDiagnosticsOutput(output string) string {
+	if len(output) <= maxDiagnosticsOutputLength {
+		return output
+	}
+	return output[len(output)-maxDiagnosticsOutputLength:]
+}
+
+// getConsoleScreenshot returns the PNG thumbnail of the console of the VM.
+func getConsoleScreenshot(vcdClient *vcdsdk.Client, vm *govcd.VM) ([]byte, error) {
+	screenURL, err := url.Parse(vm.VM.HREF + "/screen")
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the console screen URL of VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	client := &vcdClient.VCDClient.Client
+	req := client.NewRequest(nil, http.MethodGet, *screenURL, nil)
+	req.Header.Set("Accept", "image/png")
+	resp, err := client.Http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the console screen of VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to get the console screen of VM [%s]: [%s]", vm.VM.Name, resp.Status)
+	}
+	screenshot, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the console screen of VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	return screenshot, nil
+}
+
+// reconcileConsoleScreenshot stores the console screenshot of the VM of the machine in a Secret owned by the
+// VCDMachine, and returns the name of the Secret.
+func (r *VCDMachineReconciler) reconcileConsoleScreenshot(ctx context.Context, vcdClient *vcdsdk.Client,
+	vm *govcd.VM, vcdMachine *infrav1beta3.VCDMachine) (string, error) {
+
+	screenshot, err := getConsoleScreenshot(vcdClient, vm)
+	if err != nil {
+		return "", err
+	}
+	// the console may show credentials or the data of the workloads, so it is kept in a Secret
+	screenshotSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getConsoleScreenshotSecretName(vcdMachine),
+			Namespace: vcdMachine.Namespace,
+		},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, screenshotSecret, func() error {
+		if err := controllerutil.SetControllerReference(vcdMachine, screenshotSecret, r.Client.Scheme()); err != nil {
+			return err
+		}
+		screenshotSecret.Data = map[string][]byte{
+			ConsoleScreenshotKey: screenshot,
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store the console screenshot of VM [%s] in Secret [%s/%s]: [%v]",
+			vm.VM.Name, screenshotSecret.Namespace, screenshotSecret.Name, err)
+	}
+	return screenshotSecret.Name, nil
+}
+
+// getBootstrapDiagnostics collects the statuses of the bootstrap phases and the bootstrap script failure reported by
+// the VM in its guestinfo. The failed phase is the first phase which did not succeed if it is not known.
+func getBootstrapDiagnostics(vdcManager *vcdsdk.VdcManager, vm *govcd.VM, phases []string,
+	failedPhase string) (*infrav1beta3.BootstrapDiagnostics, error) {
+
+	diagnostics := &infrav1beta3.BootstrapDiagnostics{
+		CollectionTime: metav1.Now(),
+		FailedPhase:    failedPhase,
+		PhaseStatuses:  make(map[string]string),
+	}
+	for _, phase := range phases {
+		phaseStatus, err := vdcManager.GetExtraConfigValue(vm, phase)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get extra config value for key [%s] for vm [%s]: [%v]", phase,
+				vm.VM.Name, err)
+		}
+		if phaseStatus != "" {
+			diagnostics.PhaseStatuses[phase] = phaseStatus
+		}
+		// a failure found before waiting for the phases is attributed to the first phase which did not succeed
+		if diagnostics.FailedPhase == "" && phaseStatus != "successful" {
+			diagnostics.FailedPhase = phase
+		}
+	}
+
+	scriptExecutionStatus, err := vdcManager.GetExtraConfigValue(vm, PostCustomizationScriptExecutionStatus)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get extra config value for key [%s] for vm [%s]: [%v]",
+			PostCustomizationScriptExecutionStatus, vm.VM.Name, err)
+	}
+	if scriptExecutionStatus == "" {
+		return diagnostics, nil
+	}
+	if exitCode, err := strconv.ParseInt(scriptExecutionStatus, 10, 32); err == nil {
+		scriptExitCode := int32(exitCode)
+		diagnostics.ScriptExitCode = &scriptExitCode
+	}
+	for key, output := range map[string]*string{
+		PostCustomizationScriptFailureReason: &diagnostics.FailureReason,
+		PostCustomizationCloudInitOutput:     &diagnostics.CloudInitOutput,
+	} {
+		value, err := vdcManager.GetExtraConfigValue(vm, key)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get extra config value for key [%s] for vm [%s]: [%v]", key,
+				vm.VM.Name, err)
+		}
+		*output = truncateDiagnosticsOutput(value)
+	}
+	return diagnostics, nil
+}
+
+// getBootstrapDiagnosticsMessage summarizes the diagnostics of a failed bootstrap for the Event of the VCDMachine.
+func getBootstrapDiagnosticsMessage(vmName string, diagnostics *infrav1beta3.BootstrapDiagnostics) string {
+	phaseStatuses := make([]string, 0, len(diagnostics.PhaseStatuses))
+	for phase, phaseStatus := range diagnostics.PhaseStatuses {
+		phaseStatuses = append(phaseStatuses, fmt.Sprintf("%s=%s", phase, phaseStatus))
+	}
+	sort.Strings(phaseStatuses)
+	message := fmt.Sprintf("bootstrap of VM [%s] failed in phase [%s]; phase statuses [%s]", vmName,
+		diagnostics.FailedPhase, strings.Join(phaseStatuses, ", "))
+	if diagnostics.ScriptExitCode != nil {
+		message += fmt.Sprintf("; script exited with [%d]: [%s]", *diagnostics.ScriptExitCode,
+			diagnostics.FailureReason)
+	}
+	if diagnostics.ConsoleScreenshotSecretName != "" {
+		message += fmt.Sprintf("; console screenshot in Secret [%s]", diagnostics.ConsoleScreenshotSecretName)
+	}
+	return message
+}
+
+// reconcileBootstrapDiagnostics records the diagnostics of the failed bootstrap of the VM of the machine in the
+// status of the VCDMachine and in an Event, so that stuck machines can be debugged without access to the console of
+// the VM in VCD. The diagnostics are collected on a best-effort basis: the bootstrap error is reported regardless.
+func (r *VCDMachineReconciler) reconcileBootstrapDiagnostics(ctx context.Context, vcdClient *vcdsdk.Client,
+	vdcManager *vcdsdk.VdcManager, vm *govcd.VM, vcdMachine *infrav1beta3.VCDMachine, phases []string,
+	failedPhase string) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	diagnostics, err := getBootstrapDiagnostics(vdcManager, vm, phases, failedPhase)
+	if err != nil {
+		log.Error(err, "failed to collect the bootstrap diagnostics of the VM", "vmName", vm.VM.Name)
+		return
+	}
+	if vcdMachine.Spec.CaptureConsoleScreenshot {
+		screenshotSecretName, err := r.reconcileConsoleScreenshot(ctx, vcdClient, vm, vcdMachine)
+		if err != nil {
+			log.Error(err, "failed to capture the console of the VM", "vmName", vm.VM.Name)
+		}
+		diagnostics.ConsoleScreenshotSecretName = screenshotSecretName
+	}
+	vcdMachine.Status.BootstrapDiagnostics = diagnostics
+	r.recordEvent(vcdMachine, corev1.EventTypeWarning, BootstrapFailedReason,
+		getBootstrapDiagnosticsMessage(vm.VM.Name, diagnostics))
+}
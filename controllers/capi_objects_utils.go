@@ -2,25 +2,44 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
 	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
-	rdeType "github.com/vmware/cluster-api-provider-cloud-director/pkg/vcdtypes/rde_type_1_1_0"
+	rdeType "github.com/vmware/cluster-api-provider-cloud-director/pkg/vcdtypes/rde_type_1_3_0"
 	"github.com/vmware/go-vcloud-director/v2/govcd"
 	"gopkg.in/yaml.v2"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
 	kcpv1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
 	addonsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strconv"
 	"strings"
 )
 
 const tkgVersionLabel = "TKGVERSION"
 
+const (
+	// AutoscalerMinSizeAnnotation and AutoscalerMaxSizeAnnotation are set on MachineDeployments to define the range
+	// within which the cluster-autoscaler scales the MachineDeployment.
+	AutoscalerMinSizeAnnotation = "cluster.x-k8s.io/cluster-api-autoscaler-node-group-min-size"
+	AutoscalerMaxSizeAnnotation = "cluster.x-k8s.io/cluster-api-autoscaler-node-group-max-size"
+)
+
+// retainedAnnotations are the annotations preserved in the CAPI yaml of the RDE so that they round-trip when the
+// CAPI yaml is projected back onto the cluster.
+var retainedAnnotations = []string{
+	AutoscalerMinSizeAnnotation,
+	AutoscalerMaxSizeAnnotation,
+}
+
 func getTKGVersion(cluster *clusterv1.Cluster) string {
 	annotationsMap := cluster.GetAnnotations()
 	if tkgVersion, exists := annotationsMap[tkgVersionLabel]; exists {
@@ -51,12 +70,27 @@ func filterTypeMetaAndObjectMetaFromK8sObjectMap(objMap map[string]interface{})
 		if !ok {
 			return fmt.Errorf("failed to convert objectmeta [%v] to map[interface{}]interface{}", objMap["objectmeta"])
 		}
-		// remove all keys from objectMetaMap except for name and namespace.
+		// remove all keys from objectMetaMap except for name, namespace and the retained annotations.
 		for k := range objectMetaMap {
-			if k.(string) != "name" && k.(string) != "namespace" {
+			if k.(string) != "name" && k.(string) != "namespace" && k.(string) != "annotations" {
 				delete(objectMetaMap, k)
 			}
 		}
+		if annotationsMap, ok := objectMetaMap["annotations"].(map[interface{}]interface{}); ok {
+			retainedAnnotationsMap := make(map[interface{}]interface{})
+			for _, annotation := range retainedAnnotations {
+				if value, exists := annotationsMap[annotation]; exists {
+					retainedAnnotationsMap[annotation] = value
+				}
+			}
+			if len(retainedAnnotationsMap) > 0 {
+				objectMetaMap["annotations"] = retainedAnnotationsMap
+			} else {
+				delete(objectMetaMap, "annotations")
+			}
+		} else {
+			delete(objectMetaMap, "annotations")
+		}
 		// preserve name and namespace of the object as part of "metadata"
 		objMap["metadata"] = objectMetaMap
 		delete(objMap, "objectmeta")
@@ -202,30 +236,23 @@ func getVcdResourceFromVcdCluster(vcdCluster *infrav1beta3.VCDCluster, vcdResour
 // Update the existing vcdResource into vcdcluster.status.VcdResourceMap.
 // It should be the uniform function for all the types - org, ovdc, catalog, etc
 func updateVdcResourceToVcdCluster(vcdCluster *infrav1beta3.VCDCluster, vcdResourceType string, resourceID string, resourceName string) error {
-	switch vcdResourceType {
-	case ResourceTypeOvdc:
-		resourceList := vcdCluster.Status.VcdResourceMap.Ovdcs
-		if resourceList == nil {
-			resourceList = []infrav1beta3.VCDResource{}
-		}
-		for i, resource := range resourceList {
-			if resource.ID == resourceID {
-				if resource.Name != resourceName {
-					resourceList[i].Name = resourceName
-					vcdCluster.Status.VcdResourceMap.Ovdcs = resourceList
-					return nil
-				}
-				return nil // Resource already exists with the same ID and name, no need for further action
+	resourceList, err := getVcdResourceListOfVcdCluster(vcdCluster, vcdResourceType)
+	if err != nil {
+		return err
+	}
+	for i, resource := range *resourceList {
+		if resource.ID == resourceID {
+			if resource.Name != resourceName {
+				(*resourceList)[i].Name = resourceName
 			}
+			return nil // Resource already exists with the same ID and name, no need for further action
 		}
-		// Resource not found, add it to the list
-		vcdCluster.Status.VcdResourceMap.Ovdcs = append(resourceList, infrav1beta3.VCDResource{
-			ID:   resourceID,
-			Name: resourceName,
-		})
-	default:
-		return fmt.Errorf("unsupported VCD resource type: %s", vcdResourceType)
 	}
+	// Resource not found, add it to the list
+	*resourceList = append(*resourceList, infrav1beta3.VCDResource{
+		ID:   resourceID,
+		Name: resourceName,
+	})
 	return nil
 }
 
@@ -233,20 +260,34 @@ func updateVdcResourceToVcdCluster(vcdCluster *infrav1beta3.VCDCluster, vcdResou
 // Remove vcdResource from vcdcluster.status.VcdResourceMap.
 // It should be the uniform function for all the types - org, ovdc, catalog, etc
 func removeVcdResourceFromVcdCluster(vcdCluster *infrav1beta3.VCDCluster, vcdResourceType string, resourceID string) error {
+	resourceList, err := getVcdResourceListOfVcdCluster(vcdCluster, vcdResourceType)
+	if err != nil {
+		return err
+	}
+	for i, resource := range *resourceList {
+		if resource.ID == resourceID {
+			*resourceList = append((*resourceList)[:i], (*resourceList)[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("resource with ID %s not found in VCD cluster", resourceID)
+}
+
+// getVcdResourceListOfVcdCluster returns the list of vcdcluster.status.VcdResourceMap holding the resources of the type.
+func getVcdResourceListOfVcdCluster(vcdCluster *infrav1beta3.VCDCluster,
+	vcdResourceType string) (*infrav1beta3.VCDResources, error) {
 	switch vcdResourceType {
 	case ResourceTypeOvdc:
-		resourceList := vcdCluster.Status.VcdResourceMap.Ovdcs
-		for i, resource := range resourceList {
-			if resource.ID == resourceID {
-				resourceList = append(resourceList[:i], resourceList[i+1:]...)
-				vcdCluster.Status.VcdResourceMap.Ovdcs = resourceList
-				return nil
-			}
-		}
+		return &vcdCluster.Status.VcdResourceMap.Ovdcs, nil
+	case ResourceTypeAppPortProfile:
+		return &vcdCluster.Status.VcdResourceMap.AppPortProfiles, nil
+	case ResourceTypeOvdcNetwork:
+		return &vcdCluster.Status.VcdResourceMap.OvdcNetworks, nil
+	case ResourceTypeVApp:
+		return &vcdCluster.Status.VcdResourceMap.VApps, nil
 	default:
-		return fmt.Errorf("unsupported VCD resource type: %s", vcdResourceType)
+		return nil, fmt.Errorf("unsupported VCD resource type: %s", vcdResourceType)
 	}
-	return fmt.Errorf("resource with ID %s not found in VCD cluster", resourceID)
 }
 
 // checkIfOvdcNameChange is used to check if ovdc name is changed during the CAPVCD provisioning process.
@@ -255,8 +296,8 @@ func removeVcdResourceFromVcdCluster(vcdCluster *infrav1beta3.VCDCluster, vcdRes
 // compare the oldOvdcName and newOvdcName.
 // Return changed, vdc object, error.
 func checkIfOvdcNameChange(vcdCluster *infrav1beta3.VCDCluster, client *vcdsdk.Client) (bool, *govcd.Vdc, error) {
-	orgName := vcdCluster.Spec.Org
-	ovdcSpecName := vcdCluster.Spec.Ovdc
+	orgName := getOrgName(vcdCluster)
+	ovdcSpecName := getOvdcName(vcdCluster)
 
 	ovdcStatusName := vcdCluster.Status.Ovdc
 	if ovdcStatusName == "" {
@@ -331,12 +372,26 @@ func getAllCRSBindingForCluster(ctx context.Context, cli client.Client,
 	return crsBindingList, nil
 }
 
+// getVCDMachineTemplateKey returns the key of the VCDMachineTemplate referenced by an object of the namespace, e.g. a KCP
+// or a MachineDeployment. A reference without namespace is in the namespace of the object. As in Cluster API, a
+// reference to a VCDMachineTemplate of another namespace is rejected.
+func getVCDMachineTemplateKey(namespace string, ref v1.ObjectReference) (client.ObjectKey, error) {
+	if ref.Name == "" {
+		return client.ObjectKey{}, fmt.Errorf("VCDMachineTemplate reference has no name")
+	}
+	if ref.Namespace != "" && ref.Namespace != namespace {
+		return client.ObjectKey{}, fmt.Errorf("VCDMachineTemplate [%s/%s] is not in namespace [%s]: "+
+			"references to VCDMachineTemplates of other namespaces are not supported", ref.Namespace, ref.Name, namespace)
+	}
+	return client.ObjectKey{Namespace: namespace, Name: ref.Name}, nil
+}
+
 func getVCDMachineTemplateFromKCP(ctx context.Context, cli client.Client, kcp kcpv1.KubeadmControlPlane) (*infrav1beta3.VCDMachineTemplate, error) {
 	vcdMachineTemplateRef := kcp.Spec.MachineTemplate.InfrastructureRef
 	vcdMachineTemplate := &infrav1beta3.VCDMachineTemplate{}
-	vcdMachineTemplateKey := types.NamespacedName{
-		Namespace: vcdMachineTemplateRef.Namespace,
-		Name:      vcdMachineTemplateRef.Name,
+	vcdMachineTemplateKey, err := getVCDMachineTemplateKey(kcp.Namespace, vcdMachineTemplateRef)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VCDMachineTemplate reference of KCP [%s/%s]: [%v]", kcp.Namespace, kcp.Name, err)
 	}
 	if err := cli.Get(ctx, vcdMachineTemplateKey, vcdMachineTemplate); err != nil {
 		return nil, fmt.Errorf("failed to get VCDMachineTemplate by name [%s] from KCP [%s]: [%v]", vcdMachineTemplateRef.Name, kcp.Name, err)
@@ -348,9 +403,10 @@ func getVCDMachineTemplateFromKCP(ctx context.Context, cli client.Client, kcp kc
 func getVCDMachineTemplateFromMachineDeployment(ctx context.Context, cli client.Client, md clusterv1.MachineDeployment) (*infrav1beta3.VCDMachineTemplate, error) {
 	vcdMachineTemplateRef := md.Spec.Template.Spec.InfrastructureRef
 	vcdMachineTemplate := &infrav1beta3.VCDMachineTemplate{}
-	vcdMachineTemplateKey := client.ObjectKey{
-		Namespace: vcdMachineTemplateRef.Namespace,
-		Name:      vcdMachineTemplateRef.Name,
+	vcdMachineTemplateKey, err := getVCDMachineTemplateKey(md.Namespace, vcdMachineTemplateRef)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VCDMachineTemplate reference of machine deployment [%s/%s]: [%v]",
+			md.Namespace, md.Name, err)
 	}
 	if err := cli.Get(ctx, vcdMachineTemplateKey, vcdMachineTemplate); err != nil {
 		return nil, fmt.Errorf("failed to get VCDMachineTemplate by name [%s] from machine deployment [%s]: [%v]", vcdMachineTemplateRef.Name, md.Name, err)
@@ -368,11 +424,12 @@ func getMachineListFromCluster(ctx context.Context, cli client.Client, cluster c
 	return machineList, nil
 }
 
-func getVCDMachineTemplateByObjRef(ctx context.Context, cli client.Client, objRef v1.ObjectReference) (*infrav1beta3.VCDMachineTemplate, error) {
+func getVCDMachineTemplateByObjRef(ctx context.Context, cli client.Client, namespace string,
+	objRef v1.ObjectReference) (*infrav1beta3.VCDMachineTemplate, error) {
 	vcdMachineTemplate := &infrav1beta3.VCDMachineTemplate{}
-	vcdMachineTemplateKey := client.ObjectKey{
-		Namespace: objRef.Namespace,
-		Name:      objRef.Name,
+	vcdMachineTemplateKey, err := getVCDMachineTemplateKey(namespace, objRef)
+	if err != nil {
+		return nil, err
 	}
 	if err := cli.Get(ctx, vcdMachineTemplateKey, vcdMachineTemplate); err != nil {
 		return nil, fmt.Errorf("failed to get VCDMachineTemplate by ObjectReference [%v]: [%v]", objRef, err)
@@ -422,7 +479,69 @@ func getAllMachinesInKCP(ctx context.Context, cli client.Client, kcp kcpv1.Kubea
 	return machinesWithKCPOwnerRef, nil
 }
 
-func getNodePoolList(ctx context.Context, cli client.Client, cluster clusterv1.Cluster) ([]rdeType.NodePool, error) {
+// getMachinePolicies returns the sizing, placement and storage policies to be used by the VM of a VCDMachine. Policies
+// which are not set in the VCDMachineSpec are inherited from the DefaultMachinePolicies of the VCDCluster.
+func getMachinePolicies(vcdMachineSpec infrav1beta3.VCDMachineSpec, vcdCluster *infrav1beta3.VCDCluster) infrav1beta3.MachinePolicies {
+	policies := infrav1beta3.MachinePolicies{
+		SizingPolicy:    vcdMachineSpec.SizingPolicy,
+		PlacementPolicy: vcdMachineSpec.PlacementPolicy,
+		StorageProfile:  vcdMachineSpec.StorageProfile,
+	}
+	if vcdCluster == nil {
+		return policies
+	}
+	// machines sized by their CPUs and memory do not inherit the sizing policy of the cluster
+	if policies.SizingPolicy == "" && !vcdMachineSpec.HasExplicitHardware() {
+		policies.SizingPolicy = vcdCluster.Spec.DefaultMachinePolicies.SizingPolicy
+	}
+	if policies.PlacementPolicy == "" {
+		policies.PlacementPolicy = vcdCluster.Spec.DefaultMachinePolicies.PlacementPolicy
+	}
+	if policies.StorageProfile == "" {
+		policies.StorageProfile = vcdCluster.Spec.DefaultMachinePolicies.StorageProfile
+	}
+	return policies
+}
+
+// getTemplateHash returns the hash of the VCDMachineTemplate spec a VCDMachine was cloned from. The hash is computed
+// from the VCDMachineSpec with the fields set by CAPVCD after cloning (ProviderID and Bootstrapped) reset, so that it
+// identifies the configuration the VM was built from even if the template was modified or deleted later.
+func getTemplateHash(vcdMachineSpec infrav1beta3.VCDMachineSpec) (string, error) {
+	templateSpec := vcdMachineSpec.DeepCopy()
+	templateSpec.ProviderID = nil
+	templateSpec.Bootstrapped = false
+	// the metadata of the VM is kept in sync with the spec, so changing it does not change the VM
+	templateSpec.MetadataPropagation = nil
+	templateSpecBytes, err := json.Marshal(templateSpec)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal VCDMachine spec: [%v]", err)
+	}
+	hash := sha256.Sum256(templateSpecBytes)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// getAutoscalerReplicaRange returns the minimum and maximum replica counts set on the MachineDeployment for the
+// cluster-autoscaler. A nil value is returned for an annotation which is missing or is not a valid count.
+func getAutoscalerReplicaRange(md clusterv1.MachineDeployment) (minReplicas *int32, maxReplicas *int32) {
+	parseReplicaCount := func(annotation string) *int32 {
+		value, exists := md.Annotations[annotation]
+		if !exists {
+			return nil
+		}
+		count, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+		if err != nil || count < 0 {
+			klog.Errorf("ignoring invalid value [%s] of annotation [%s] on MachineDeployment [%s]",
+				value, annotation, md.Name)
+			return nil
+		}
+		replicaCount := int32(count)
+		return &replicaCount
+	}
+	return parseReplicaCount(AutoscalerMinSizeAnnotation), parseReplicaCount(AutoscalerMaxSizeAnnotation)
+}
+
+func getNodePoolList(ctx context.Context, cli client.Client, cluster clusterv1.Cluster,
+	vcdCluster *infrav1beta3.VCDCluster) ([]rdeType.NodePool, error) {
 	nodePoolList := make([]rdeType.NodePool, 0)
 	mds, err := getAllMachineDeploymentsForCluster(ctx, cli, cluster)
 	if err != nil {
@@ -440,23 +559,32 @@ func getNodePoolList(ctx context.Context, cli client.Client, cluster clusterv1.C
 			return nil, fmt.Errorf("failed to get MachineList for MachineDeployment [%s]: [%v]", md.Name, err)
 		}
 		nodeStatusMap := make(map[string]string)
+		nodeRoleMap := make(map[string]string)
 		for _, machine := range machineList.Items {
 			nodeStatusMap[machine.Name] = machine.Status.Phase
+			nodeRoleMap[machine.Name] = getMachineRole(&machine)
 		}
 		desiredReplicasCount := int32(0)
 		if md.Spec.Replicas != nil {
 			desiredReplicasCount = *md.Spec.Replicas
 		}
+		minReplicas, maxReplicas := getAutoscalerReplicaRange(md)
+		policies := getMachinePolicies(vcdMachineTemplate.Spec.Template.Spec, vcdCluster)
 		nodePool := rdeType.NodePool{
-			Name:              md.Name,
-			SizingPolicy:      vcdMachineTemplate.Spec.Template.Spec.SizingPolicy,
-			PlacementPolicy:   vcdMachineTemplate.Spec.Template.Spec.PlacementPolicy,
-			NvidiaGpuEnabled:  vcdMachineTemplate.Spec.Template.Spec.EnableNvidiaGPU,
-			StorageProfile:    vcdMachineTemplate.Spec.Template.Spec.StorageProfile,
-			DiskSizeMb:        int32(vcdMachineTemplate.Spec.Template.Spec.DiskSize.Value() / (1024 * 1024)),
-			DesiredReplicas:   desiredReplicasCount,
-			AvailableReplicas: md.Status.ReadyReplicas,
-			NodeStatus:        nodeStatusMap,
+			Name:               md.Name,
+			SizingPolicy:       policies.SizingPolicy,
+			PlacementPolicy:    policies.PlacementPolicy,
+			NvidiaGpuEnabled:   isNvidiaGPUEnabled(vcdMachineTemplate.Spec.Template.Spec),
+			StorageProfile:     policies.StorageProfile,
+			DiskSizeMb:         int32(vcdMachineTemplate.Spec.Template.Spec.DiskSize.Value() / (1024 * 1024)),
+			DesiredReplicas:    desiredReplicasCount,
+			AvailableReplicas:  md.Status.ReadyReplicas,
+			MinReplicas:        minReplicas,
+			MaxReplicas:        maxReplicas,
+			NodeStatus:         nodeStatusMap,
+			NodeRoles:          nodeRoleMap,
+			Generation:         md.Generation,
+			ObservedGeneration: md.Status.ObservedGeneration,
 		}
 		nodePoolList = append(nodePoolList, nodePool)
 	}
@@ -477,23 +605,29 @@ func getNodePoolList(ctx context.Context, cli client.Client, cluster clusterv1.C
 			return nil, fmt.Errorf("failed to get Machines associated with the KubeadmControlPlane [%s]: [%v]", kcp.Name, err)
 		}
 		nodeStatusMap := make(map[string]string)
+		nodeRoleMap := make(map[string]string)
 		for _, machine := range machineArr {
 			nodeStatusMap[machine.Name] = machine.Status.Phase
+			nodeRoleMap[machine.Name] = getMachineRole(&machine)
 		}
 		desiredReplicaCount := int32(0)
 		if kcp.Spec.Replicas != nil {
 			desiredReplicaCount = *kcp.Spec.Replicas
 		}
+		policies := getMachinePolicies(vcdMachineTemplate.Spec.Template.Spec, vcdCluster)
 		nodePool := rdeType.NodePool{
-			Name:              kcp.Name,
-			SizingPolicy:      vcdMachineTemplate.Spec.Template.Spec.SizingPolicy,
-			PlacementPolicy:   vcdMachineTemplate.Spec.Template.Spec.PlacementPolicy,
-			NvidiaGpuEnabled:  vcdMachineTemplate.Spec.Template.Spec.EnableNvidiaGPU,
-			StorageProfile:    vcdMachineTemplate.Spec.Template.Spec.StorageProfile,
-			DiskSizeMb:        int32(vcdMachineTemplate.Spec.Template.Spec.DiskSize.Value() / (1024 * 1024)),
-			DesiredReplicas:   desiredReplicaCount,
-			AvailableReplicas: kcp.Status.ReadyReplicas,
-			NodeStatus:        nodeStatusMap,
+			Name:               kcp.Name,
+			SizingPolicy:       policies.SizingPolicy,
+			PlacementPolicy:    policies.PlacementPolicy,
+			NvidiaGpuEnabled:   isNvidiaGPUEnabled(vcdMachineTemplate.Spec.Template.Spec),
+			StorageProfile:     policies.StorageProfile,
+			DiskSizeMb:         int32(vcdMachineTemplate.Spec.Template.Spec.DiskSize.Value() / (1024 * 1024)),
+			DesiredReplicas:    desiredReplicaCount,
+			AvailableReplicas:  kcp.Status.ReadyReplicas,
+			NodeStatus:         nodeStatusMap,
+			NodeRoles:          nodeRoleMap,
+			Generation:         kcp.Generation,
+			ObservedGeneration: kcp.Status.ObservedGeneration,
 		}
 		nodePoolList = append(nodePoolList, nodePool)
 	}
@@ -533,7 +667,7 @@ func getK8sClusterObjects(ctx context.Context, cli client.Client, cluster cluste
 
 	vcdMachineTemplates := make([]*infrav1beta3.VCDMachineTemplate, 0)
 	for _, objRef := range vcdMachineTemplateNameToObjRef {
-		vcdMachineTemplate, err := getVCDMachineTemplateByObjRef(ctx, cli, objRef)
+		vcdMachineTemplate, err := getVCDMachineTemplateByObjRef(ctx, cli, cluster.Namespace, objRef)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get VCDMachineTemplate by ObjectReference [%v]: [%v]", objRef, err)
 		}
@@ -625,6 +759,7 @@ func getUserCredentialsForCluster(ctx context.Context, cli client.Client, define
 		Username:     username,
 		Password:     password,
 		RefreshToken: refreshToken,
+		AuthType:     definedCreds.AuthType,
 	}
 
 	return userCredentials, nil
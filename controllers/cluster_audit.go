@@ -0,0 +1,177 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultAuditInterval is the default interval at which the infrastructure of each cluster is audited.
+	DefaultAuditInterval = 1 * time.Hour
+
+	// AuditRDEFreshnessThreshold is the age above which the last report of the status of a cluster in its RDE is
+	// considered stale by the audit.
+	AuditRDEFreshnessThreshold = 3 * SubsystemRunRecordInterval
+)
+
+// clusterAudit collects the checks of an audit of the infrastructure of a cluster which passed and failed.
+type clusterAudit struct {
+	verified []string
+	failed   []string
+}
+
+func (a *clusterAudit) check(ok bool, format string, args ...interface{}) {
+	if ok {
+		a.verified = append(a.verified, fmt.Sprintf(format, args...))
+	} else {
+		a.failed = append(a.failed, fmt.Sprintf(format, args...))
+	}
+}
+
+// reconcileAudit audits the infrastructure of the cluster every AuditInterval, and reports in the
+// InfrastructureAudited condition and in an Event what was verified: the control plane endpoint, the VMs of the
+// VCDMachines and the freshness of the RDE. It gives the operators a positive confirmation that the infrastructure is
+// in place, rather than only the absence of errors. It is called at the end of a successful reconciliation, whose
+// checks it reports along with its own.
+func (r *VCDClusterReconciler) reconcileAudit(ctx context.Context, vcdClient *vcdsdk.Client,
+	vcdCluster *infrav1beta3.VCDCluster) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	if r.AuditInterval <= 0 {
+		conditions.Delete(vcdCluster, InfrastructureAuditedCondition)
+		return
+	}
+	lastAudit := vcdCluster.Status.LastSubsystemRuns.Audit
+	if lastAudit != nil && time.Since(lastAudit.Time) < r.AuditInterval {
+		return
+	}
+
+	audit := &clusterAudit{}
+	endpoint := net.JoinHostPort(vcdCluster.Spec.ControlPlaneEndpoint.Host,
+		strconv.Itoa(vcdCluster.Spec.ControlPlaneEndpoint.Port))
+	endpointAvailable := conditions.IsTrue(vcdCluster, LoadBalancerAvailableCondition)
+	switch {
+	case isControlPlaneEndpointPassthrough(vcdCluster):
+		audit.check(endpointAvailable, "external load balancer of control plane endpoint [%s] set", endpoint)
+	case isControlPlaneEndpointDNAT(vcdCluster):
+		audit.check(endpointAvailable, "DNAT rule of control plane endpoint [%s] in place", endpoint)
+	case isControlPlaneEndpointRouted(vcdCluster):
+		audit.check(endpointAvailable, "control plane endpoint [%s] set on the OVDC network", endpoint)
+	case isControlPlaneEndpointKubeVip(vcdCluster):
+		audit.check(endpointAvailable, "kube-vip virtual IP of control plane endpoint [%s] set", endpoint)
+	default:
+		audit.check(endpointAvailable, "load balancer of control plane endpoint [%s] in place", endpoint)
+	}
+
+	if err := auditMachineVMs(ctx, r.Client, vcdClient, vcdCluster, audit); err != nil {
+		log.Error(err, "failed to audit the VMs of the cluster")
+		audit.check(false, "VMs of the machines could not be listed: %v", err)
+	}
+
+	rdeID := vcdCluster.Status.InfraId
+	if !strings.HasPrefix(rdeID, NoRdePrefix) {
+		lastRDEUpdate := vcdCluster.Status.LastSubsystemRuns.RDE
+		audit.check(conditions.IsTrue(vcdCluster, RDEReadyCondition) && lastRDEUpdate != nil &&
+			time.Since(lastRDEUpdate.Time) < AuditRDEFreshnessThreshold, "RDE [%s] updated in the last %s", rdeID,
+			AuditRDEFreshnessThreshold)
+	}
+
+	now := metav1.Now()
+	vcdCluster.Status.LastSubsystemRuns.Audit = &now
+	verified := "verified: " + strings.Join(audit.verified, "; ")
+	if len(audit.failed) == 0 {
+		conditions.Set(vcdCluster, &clusterv1.Condition{
+			Type:    InfrastructureAuditedCondition,
+			Status:  corev1.ConditionTrue,
+			Message: verified,
+		})
+		r.recordEvent(vcdCluster, corev1.EventTypeNormal, InfrastructureAuditPassedReason, verified)
+		return
+	}
+	message := "failed: " + strings.Join(audit.failed, "; ")
+	if len(audit.verified) > 0 {
+		message += "; " + verified
+	}
+	log.Info("Audit of the cluster infrastructure failed", "result", message)
+	conditions.MarkFalse(vcdCluster, InfrastructureAuditedCondition, InfrastructureAuditFailedReason,
+		clusterv1.ConditionSeverityWarning, message)
+	r.recordEvent(vcdCluster, corev1.EventTypeWarning, InfrastructureAuditFailedReason, message)
+}
+
+// auditMachineVMs checks that the VM of each provisioned VCDMachine of the cluster is in its vApp, by the ID of its
+// provider ID.
+func auditMachineVMs(ctx context.Context, cli client.Client, vcdClient *vcdsdk.Client,
+	vcdCluster *infrav1beta3.VCDCluster, audit *clusterAudit) error {
+
+	clusterName := vcdCluster.Labels[clusterv1.ClusterNameLabel]
+	if clusterName == "" {
+		return fmt.Errorf("VCDCluster [%s] has no label [%s]", vcdCluster.Name, clusterv1.ClusterNameLabel)
+	}
+	if vcdClient.VDC == nil || vcdClient.VDC.Vdc == nil {
+		return fmt.Errorf("no OVDC found in the VCD client to audit cluster [%s]", vcdCluster.Name)
+	}
+	vcdMachineList := &infrav1beta3.VCDMachineList{}
+	if err := cli.List(ctx, vcdMachineList, client.InNamespace(vcdCluster.Namespace),
+		client.MatchingLabels{clusterv1.ClusterNameLabel: clusterName}); err != nil {
+		return fmt.Errorf("failed to list the VCDMachines of cluster [%s/%s]: [%v]", vcdCluster.Namespace,
+			clusterName, err)
+	}
+
+	vAppVMIDs := make(map[string]map[string]bool)
+	provisioned, matched := 0, 0
+	var missing []string
+	for idx := range vcdMachineList.Items {
+		vcdMachine := &vcdMachineList.Items[idx]
+		vmID := getVMIDFromProviderID(vcdMachine.Status.ProviderID)
+		if vmID == "" || !vcdMachine.DeletionTimestamp.IsZero() {
+			continue
+		}
+		provisioned++
+		vAppName := getMachineVAppName(vcdMachine, vcdCluster)
+		vmIDs, ok := vAppVMIDs[vAppName]
+		if !ok {
+			vmIDs = make(map[string]bool)
+			vApp, err := vcdClient.VDC.GetVAppByName(vAppName, true)
+			if err != nil && err != govcd.ErrorEntityNotFound {
+				return fmt.Errorf("failed to get vApp [%s]: [%v]", vAppName, err)
+			}
+			if err == nil && vApp.VApp != nil && vApp.VApp.Children != nil {
+				for _, vm := range vApp.VApp.Children.VM {
+					vmIDs[vm.ID] = true
+				}
+			}
+			vAppVMIDs[vAppName] = vmIDs
+		}
+		if vmIDs[vmID] {
+			matched++
+		} else {
+			missing = append(missing, vcdMachine.Name)
+		}
+	}
+
+	audit.check(len(missing) == 0, "%d/%d VMs of the provisioned machines found in VCD", matched, provisioned)
+	if len(missing) > 0 {
+		audit.check(false, "VMs of machines [%s] not found in VCD", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,422 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const firewallRuleDirectionInOut = "IN_OUT"
+
+// edgeGatewayFirewallLocks serializes the updates of the gateway firewall of an edge gateway, which are a
+// read-modify-write of all its rules, so that the clusters sharing the edge gateway do not lose each other's updates.
+var (
+	edgeGatewayFirewallLocks     = make(map[string]*sync.Mutex)
+	edgeGatewayFirewallLocksLock sync.Mutex
+)
+
+func getEdgeGatewayFirewallLock(key string) *sync.Mutex {
+	edgeGatewayFirewallLocksLock.Lock()
+	defer edgeGatewayFirewallLocksLock.Unlock()
+	lock, ok := edgeGatewayFirewallLocks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		edgeGatewayFirewallLocks[key] = lock
+	}
+	return lock
+}
+
+// getClusterFirewallNamePrefix returns the prefix of the names of the firewall rules of the cluster on the edge
+// gateway, and of the IP sets and application port profiles they reference.
+func getClusterFirewallNamePrefix(vcdCluster *infrav1beta3.VCDCluster) string {
+	return capisdk.GetVirtualServiceNamePrefix(vcdCluster.Name, vcdCluster.Status.InfraId) + "-fw-"
+}
+
+// getEdgeGatewayFirewallLockKey returns the key of the lock of the gateway firewall of the edge gateway of the cluster.
+func getEdgeGatewayFirewallLockKey(vcdCluster *infrav1beta3.VCDCluster, edgeGateway *govcd.NsxtEdgeGateway) string {
+	return fmt.Sprintf("%s/%s", vcdCluster.Spec.Site, edgeGateway.EdgeGateway.ID)
+}
+
+// getClusterFirewallGroupAddresses returns the sorted addresses of the groups of addresses of the cluster the
+// firewall rules apply to, by group name. The host of the control plane endpoint is resolved if it is a host name.
+func getClusterFirewallGroupAddresses(ctx context.Context, cli client.Client, cluster *clusterv1.Cluster,
+	vcdCluster *infrav1beta3.VCDCluster) (map[string][]string, error) {
+
+	controlPlaneAddresses, workerAddresses, err := getNodeAddresses(ctx, cli, cluster)
+	if err != nil {
+		return nil, err
+	}
+	nodeAddresses := append(append([]string{}, controlPlaneAddresses...), workerAddresses...)
+	sort.Strings(nodeAddresses)
+	endpointAddresses := []string{}
+	if vcdCluster.Spec.ControlPlaneEndpoint.Host != "" {
+		if endpointAddresses, err = resolveEgressDestinations([]infrav1beta3.EgressDestination{{
+			Host: vcdCluster.Spec.ControlPlaneEndpoint.Host,
+		}}); err != nil {
+			return nil, err
+		}
+	}
+	return map[string][]string{
+		NetworkFlowGroupControlPlaneEndpoint: endpointAddresses,
+		NetworkFlowGroupControlPlaneNodes:    append([]string{}, controlPlaneAddresses...),
+		NetworkFlowGroupWorkerNodes:          append([]string{}, workerAddresses...),
+		NetworkFlowGroupNodes:                nodeAddresses,
+	}, nil
+}
+
+// reconcileFirewallIPSet ensures that the IP set of the edge gateway exists with the addresses.
+func reconcileFirewallIPSet(ctx context.Context, edgeGateway *govcd.NsxtEdgeGateway, name string,
+	description string, ips []string) (*govcd.NsxtFirewallGroup, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	ipSet, err := edgeGateway.GetNsxtFirewallGroupByName(name, types.FirewallGroupTypeIpSet)
+	switch {
+	case govcd.ContainsNotFound(err):
+		ipSet, err = edgeGateway.CreateNsxtFirewallGroup(&types.NsxtFirewallGroup{
+			Name:        name,
+			Description: description,
+			IpAddresses: ips,
+			OwnerRef:    &types.OpenApiReference{ID: edgeGateway.EdgeGateway.ID},
+			TypeValue:   types.FirewallGroupTypeIpSet,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create IP set [%s]: [%v]", name, err)
+		}
+		log.Info("Created IP set of the firewall rules of the cluster", "ipSet", name)
+	case err != nil:
+		return nil, fmt.Errorf("failed to get IP set [%s]: [%v]", name, err)
+	case len(ipSet.NsxtFirewallGroup.IpAddresses) != len(ips) ||
+		(len(ips) > 0 && !reflect.DeepEqual(ipSet.NsxtFirewallGroup.IpAddresses, ips)):
+
+		ipSetConfig := *ipSet.NsxtFirewallGroup
+		ipSetConfig.IpAddresses = ips
+		if ipSet, err = ipSet.Update(&ipSetConfig); err != nil {
+			return nil, fmt.Errorf("failed to update IP set [%s]: [%v]", name, err)
+		}
+		log.Info("Updated IP set of the firewall rules of the cluster", "ipSet", name)
+	}
+	return ipSet, nil
+}
+
+// reconcileFirewallAppPortProfile ensures that the tenant application port profile exists with the destination ports.
+func reconcileFirewallAppPortProfile(ctx context.Context, vcdClient *vcdsdk.Client, org *govcd.Org, name string,
+	protocol string, ports []string) (*govcd.NsxtAppPortProfile, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	applicationPorts := []types.NsxtAppPortProfilePort{{Protocol: protocol, DestinationPorts: ports}}
+	appPortProfile, err := org.GetNsxtAppPortProfileByName(name, types.ApplicationPortProfileScopeTenant)
+	switch {
+	case govcd.ContainsNotFound(err):
+		appPortProfile, err = org.CreateNsxtAppPortProfile(&types.NsxtAppPortProfile{
+			Name:             name,
+			Description:      fmt.Sprintf("App Port Profile [%s]", name),
+			ApplicationPorts: applicationPorts,
+			OrgRef:           &types.OpenApiReference{Name: org.Org.Name, ID: org.Org.ID},
+			ContextEntityId:  vcdClient.VDC.Vdc.ID,
+			Scope:            types.ApplicationPortProfileScopeTenant,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create app port profile [%s]: [%v]", name, err)
+		}
+		log.Info("Created app port profile of the firewall rules of the cluster", "appPortProfile", name)
+	case err != nil:
+		return nil, fmt.Errorf("failed to get app port profile [%s]: [%v]", name, err)
+	case !reflect.DeepEqual(appPortProfile.NsxtAppPortProfile.ApplicationPorts, applicationPorts):
+		appPortProfileConfig := *appPortProfile.NsxtAppPortProfile
+		appPortProfileConfig.ApplicationPorts = applicationPorts
+		if appPortProfile, err = appPortProfile.Update(&appPortProfileConfig); err != nil {
+			return nil, fmt.Errorf("failed to update app port profile [%s]: [%v]", name, err)
+		}
+		log.Info("Updated app port profile of the firewall rules of the cluster", "appPortProfile", name)
+	}
+	return appPortProfile, nil
+}
+
+// isSameFirewallRule checks if the firewall rule of the edge gateway matches the desired rule.
+func isSameFirewallRule(rule *types.NsxtFirewallRule, desiredRule *types.NsxtFirewallRule) bool {
+	sameRefs := func(refs []types.OpenApiReference, desiredRefs []types.OpenApiReference) bool {
+		if len(refs) != len(desiredRefs) {
+			return false
+		}
+		for idx := range refs {
+			if refs[idx].ID != desiredRefs[idx].ID {
+				return false
+			}
+		}
+		return true
+	}
+	return rule.Name == desiredRule.Name && rule.Action == desiredRule.Action && rule.Enabled == desiredRule.Enabled &&
+		rule.IpProtocol == desiredRule.IpProtocol && rule.Direction == desiredRule.Direction &&
+		sameRefs(rule.SourceFirewallGroups, desiredRule.SourceFirewallGroups) &&
+		sameRefs(rule.DestinationFirewallGroups, desiredRule.DestinationFirewallGroups) &&
+		sameRefs(rule.ApplicationPortProfiles, desiredRule.ApplicationPortProfiles)
+}
+
+// mergeClusterFirewallRules returns the user defined rules of the gateway firewall with the rules of the cluster, named
+// with the prefix, replaced by the desired rules, and whether the rules of the cluster are already the desired rules in
+// the same order. Only the relative order of the rules of the cluster matters, so that the clusters sharing the edge
+// gateway do not keep moving their rules ahead of each other's: the desired rules take the place of the first rule of
+// the cluster, or are put before the other rules when the cluster has none yet.
+func mergeClusterFirewallRules(userDefinedRules []*types.NsxtFirewallRule, prefix string,
+	desiredRules []*types.NsxtFirewallRule) ([]*types.NsxtFirewallRule, bool) {
+
+	clusterRules := make([]*types.NsxtFirewallRule, 0, len(desiredRules))
+	rules := make([]*types.NsxtFirewallRule, 0, len(userDefinedRules)+len(desiredRules))
+	for _, rule := range userDefinedRules {
+		if !strings.HasPrefix(rule.Name, prefix) {
+			rules = append(rules, rule)
+			continue
+		}
+		if len(clusterRules) == 0 {
+			rules = append(rules, desiredRules...)
+		}
+		clusterRules = append(clusterRules, rule)
+	}
+	if len(clusterRules) == 0 {
+		rules = append(append([]*types.NsxtFirewallRule{}, desiredRules...), rules...)
+	}
+
+	inPlace := len(clusterRules) == len(desiredRules)
+	for idx := 0; inPlace && idx < len(desiredRules); idx++ {
+		inPlace = isSameFirewallRule(clusterRules[idx], desiredRules[idx])
+	}
+	return rules, inPlace
+}
+
+// reconcileClusterFirewallRules ensures that the VCDClusterSpec.FirewallRules are in place, in order, on the gateway
+// firewall of the edge gateway of the cluster network. They are put before the other user defined rules when they are
+// first programmed, and are then kept where they are. The destinations of the rules
+// are IP sets of the groups of addresses of the cluster, kept up to date as the machines come and go, and their sources
+// and ports are an IP set and an application port profile of each rule. The rules, IP sets and profiles no longer in
+// the spec are removed. The names of the rules in place are recorded in VCDClusterStatus.FirewallRules.
+func reconcileClusterFirewallRules(ctx context.Context, cli client.Client, vcdClient *vcdsdk.Client,
+	cluster *clusterv1.Cluster, vcdCluster *infrav1beta3.VCDCluster) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	if len(vcdCluster.Spec.FirewallRules) == 0 {
+		if len(vcdCluster.Status.FirewallRules) == 0 {
+			return nil
+		}
+		return deleteClusterFirewallRules(ctx, vcdClient, vcdCluster)
+	}
+
+	_, edgeGateway, err := getEdgeGatewayOfNetwork(vcdClient, getOvdcNetworkName(vcdCluster))
+	if err != nil {
+		return err
+	}
+	if edgeGateway == nil {
+		return fmt.Errorf("OVDC network [%s] is not connected to an edge gateway to program the firewall rules on",
+			getOvdcNetworkName(vcdCluster))
+	}
+	org, err := getOrgByName(vcdClient, vcdClient.ClusterOrgName)
+	if err != nil {
+		return err
+	}
+	groupAddresses, err := getClusterFirewallGroupAddresses(ctx, cli, cluster, vcdCluster)
+	if err != nil {
+		return err
+	}
+
+	prefix := getClusterFirewallNamePrefix(vcdCluster)
+	ipSetIDs := make(map[string]string)
+	appPortProfileIDs := make(map[string]string)
+	desiredRules := make([]*types.NsxtFirewallRule, 0, len(vcdCluster.Spec.FirewallRules))
+	ruleNames := make([]string, 0, len(vcdCluster.Spec.FirewallRules))
+	for _, firewallRule := range vcdCluster.Spec.FirewallRules {
+		addresses, ok := groupAddresses[firewallRule.Destination]
+		if !ok {
+			return fmt.Errorf("invalid destination [%s] of firewall rule [%s]", firewallRule.Destination,
+				firewallRule.Name)
+		}
+		destinationName := prefix + firewallRule.Destination
+		if _, ok = ipSetIDs[destinationName]; !ok {
+			ipSet, err := reconcileFirewallIPSet(ctx, edgeGateway, destinationName,
+				fmt.Sprintf("Addresses [%s] of cluster [%s]", firewallRule.Destination, vcdCluster.Name), addresses)
+			if err != nil {
+				return err
+			}
+			ipSetIDs[destinationName] = ipSet.NsxtFirewallGroup.ID
+		}
+
+		name := prefix + firewallRule.Name
+		action := firewallRule.Action
+		if action == "" {
+			action = "Allow"
+		}
+		desiredRule := &types.NsxtFirewallRule{
+			Name:                      name,
+			Action:                    strings.ToUpper(action),
+			Enabled:                   true,
+			DestinationFirewallGroups: []types.OpenApiReference{{ID: ipSetIDs[destinationName]}},
+			IpProtocol:                firewallRuleIpProtocolAnyIP,
+			Direction:                 firewallRuleDirectionInOut,
+		}
+		if len(firewallRule.Sources) > 0 {
+			sourceName := name + "-sources"
+			ipSet, err := reconcileFirewallIPSet(ctx, edgeGateway, sourceName,
+				fmt.Sprintf("Sources of firewall rule [%s] of cluster [%s]", firewallRule.Name, vcdCluster.Name),
+				firewallRule.Sources)
+			if err != nil {
+				return err
+			}
+			ipSetIDs[sourceName] = ipSet.NsxtFirewallGroup.ID
+			desiredRule.SourceFirewallGroups = []types.OpenApiReference{{ID: ipSet.NsxtFirewallGroup.ID}}
+		}
+		if len(firewallRule.Ports) > 0 {
+			protocol := firewallRule.Protocol
+			if protocol == "" {
+				protocol = egressProtocolTCP
+			}
+			appPortProfile, err := reconcileFirewallAppPortProfile(ctx, vcdClient, org, name, protocol,
+				firewallRule.Ports)
+			if err != nil {
+				return err
+			}
+			appPortProfileIDs[name] = appPortProfile.NsxtAppPortProfile.ID
+			desiredRule.ApplicationPortProfiles = []types.OpenApiReference{{ID: appPortProfile.NsxtAppPortProfile.ID}}
+		}
+		desiredRules = append(desiredRules, desiredRule)
+		ruleNames = append(ruleNames, firewallRule.Name)
+	}
+
+	lock := getEdgeGatewayFirewallLock(getEdgeGatewayFirewallLockKey(vcdCluster, edgeGateway))
+	lock.Lock()
+	defer lock.Unlock()
+	firewall, err := edgeGateway.GetNsxtFirewall()
+	if err != nil {
+		return fmt.Errorf("failed to get firewall rules of edge gateway [%s]: [%v]", edgeGateway.EdgeGateway.Name, err)
+	}
+	rules, inPlace := mergeClusterFirewallRules(firewall.NsxtFirewallRuleContainer.UserDefinedRules, prefix,
+		desiredRules)
+	if !inPlace {
+		if _, err = edgeGateway.UpdateNsxtFirewall(&types.NsxtFirewallRuleContainer{UserDefinedRules: rules}); err != nil {
+			return fmt.Errorf("failed to update the firewall rules of cluster [%s] on edge gateway [%s]: [%v]",
+				vcdCluster.Name, edgeGateway.EdgeGateway.Name, err)
+		}
+		log.Info("Updated the firewall rules of the cluster on the edge gateway", "rules", ruleNames)
+	}
+	vcdCluster.Status.FirewallRules = ruleNames
+
+	return deleteClusterFirewallObjects(ctx, vcdClient, org, edgeGateway, prefix, ipSetIDs, appPortProfileIDs)
+}
+
+// deleteClusterFirewallObjects deletes the IP sets and application port profiles of the firewall rules of the cluster
+// which are not in use, given by name.
+func deleteClusterFirewallObjects(ctx context.Context, vcdClient *vcdsdk.Client, org *govcd.Org,
+	edgeGateway *govcd.NsxtEdgeGateway, prefix string, ipSetIDs map[string]string,
+	appPortProfileIDs map[string]string) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	ipSets, err := edgeGateway.GetAllNsxtFirewallGroups(nil, types.FirewallGroupTypeIpSet)
+	if err != nil {
+		return fmt.Errorf("failed to list IP sets of edge gateway [%s]: [%v]", edgeGateway.EdgeGateway.Name, err)
+	}
+	for _, ipSet := range ipSets {
+		if ipSet.NsxtFirewallGroup == nil || !strings.HasPrefix(ipSet.NsxtFirewallGroup.Name, prefix) {
+			continue
+		}
+		if _, ok := ipSetIDs[ipSet.NsxtFirewallGroup.Name]; ok {
+			continue
+		}
+		if err = ipSet.Delete(); err != nil {
+			return fmt.Errorf("failed to delete IP set [%s]: [%v]", ipSet.NsxtFirewallGroup.Name, err)
+		}
+		log.Info("Deleted IP set of the firewall rules of the cluster", "ipSet", ipSet.NsxtFirewallGroup.Name)
+	}
+
+	appPortProfiles, err := org.GetAllNsxtAppPortProfiles(nil, types.ApplicationPortProfileScopeTenant)
+	if err != nil {
+		return fmt.Errorf("unable to list app port profiles of org [%s]: [%v]", vcdClient.ClusterOrgName, err)
+	}
+	for _, appPortProfile := range appPortProfiles {
+		if appPortProfile.NsxtAppPortProfile == nil ||
+			!strings.HasPrefix(appPortProfile.NsxtAppPortProfile.Name, prefix) {
+			continue
+		}
+		if _, ok := appPortProfileIDs[appPortProfile.NsxtAppPortProfile.Name]; ok {
+			continue
+		}
+		if err = appPortProfile.Delete(); err != nil {
+			return fmt.Errorf("unable to delete app port profile [%s]: [%v]", appPortProfile.NsxtAppPortProfile.Name,
+				err)
+		}
+		log.Info("Deleted app port profile of the firewall rules of the cluster", "appPortProfile",
+			appPortProfile.NsxtAppPortProfile.Name)
+	}
+	return nil
+}
+
+// deleteClusterFirewallRules removes the firewall rules of the cluster from the edge gateway, along with their IP sets
+// and application port profiles.
+func deleteClusterFirewallRules(ctx context.Context, vcdClient *vcdsdk.Client,
+	vcdCluster *infrav1beta3.VCDCluster) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	_, edgeGateway, err := getEdgeGatewayOfNetwork(vcdClient, getOvdcNetworkName(vcdCluster))
+	if err != nil {
+		return err
+	}
+	if edgeGateway == nil {
+		vcdCluster.Status.FirewallRules = nil
+		return nil
+	}
+	org, err := getOrgByName(vcdClient, vcdClient.ClusterOrgName)
+	if err != nil {
+		return err
+	}
+
+	prefix := getClusterFirewallNamePrefix(vcdCluster)
+	lock := getEdgeGatewayFirewallLock(getEdgeGatewayFirewallLockKey(vcdCluster, edgeGateway))
+	lock.Lock()
+	defer lock.Unlock()
+	firewall, err := edgeGateway.GetNsxtFirewall()
+	if err != nil {
+		return fmt.Errorf("failed to get firewall rules of edge gateway [%s]: [%v]", edgeGateway.EdgeGateway.Name, err)
+	}
+	rules := make([]*types.NsxtFirewallRule, 0, len(firewall.NsxtFirewallRuleContainer.UserDefinedRules))
+	for _, rule := range firewall.NsxtFirewallRuleContainer.UserDefinedRules {
+		if !strings.HasPrefix(rule.Name, prefix) {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) != len(firewall.NsxtFirewallRuleContainer.UserDefinedRules) {
+		if len(rules) == 0 {
+			err = firewall.DeleteAllRules()
+		} else {
+			_, err = edgeGateway.UpdateNsxtFirewall(&types.NsxtFirewallRuleContainer{UserDefinedRules: rules})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to remove the firewall rules of cluster [%s] from edge gateway [%s]: [%v]",
+				vcdCluster.Name, edgeGateway.EdgeGateway.Name, err)
+		}
+		log.Info("Removed the firewall rules of the cluster from the edge gateway")
+	}
+
+	if err = deleteClusterFirewallObjects(ctx, vcdClient, org, edgeGateway, prefix, nil, nil); err != nil {
+		return err
+	}
+	vcdCluster.Status.FirewallRules = nil
+	return nil
+}
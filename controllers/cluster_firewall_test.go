@@ -0,0 +1,143 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+func newTestFirewallRule(name string, destinationID string) *types.NsxtFirewallRule {
+	return &types.NsxtFirewallRule{
+		Name:                      name,
+		Action:                    "ALLOW",
+		Enabled:                   true,
+		IpProtocol:                "IPV4",
+		Direction:                 "IN",
+		SourceFirewallGroups:      []types.OpenApiReference{{ID: "urn:vcloud:firewallGroup:source"}},
+		DestinationFirewallGroups: []types.OpenApiReference{{ID: destinationID}},
+		ApplicationPortProfiles:   []types.OpenApiReference{{ID: "urn:vcloud:applicationPortProfile:ssh"}},
+	}
+}
+
+func TestIsSameFirewallRule(t *testing.T) {
+	desiredRule := newTestFirewallRule("capvcd-cluster-ssh", "urn:vcloud:firewallGroup:nodes")
+
+	testCases := []struct {
+		name   string
+		modify func(rule *types.NsxtFirewallRule)
+		want   bool
+	}{
+		{name: "same rule", modify: func(rule *types.NsxtFirewallRule) {}, want: true},
+		{name: "ID and logging are ignored", modify: func(rule *types.NsxtFirewallRule) {
+			rule.ID = "urn:vcloud:firewallRule:1"
+			rule.Logging = true
+		}, want: true},
+		{name: "another name", modify: func(rule *types.NsxtFirewallRule) { rule.Name = "capvcd-cluster-http" }},
+		{name: "another action", modify: func(rule *types.NsxtFirewallRule) { rule.Action = "DROP" }},
+		{name: "disabled", modify: func(rule *types.NsxtFirewallRule) { rule.Enabled = false }},
+		{name: "another protocol", modify: func(rule *types.NsxtFirewallRule) { rule.IpProtocol = "IPV6" }},
+		{name: "another direction", modify: func(rule *types.NsxtFirewallRule) { rule.Direction = "OUT" }},
+		{name: "another source", modify: func(rule *types.NsxtFirewallRule) {
+			rule.SourceFirewallGroups = []types.OpenApiReference{{ID: "urn:vcloud:firewallGroup:other"}}
+		}},
+		{name: "additional destination", modify: func(rule *types.NsxtFirewallRule) {
+			rule.DestinationFirewallGroups = append(rule.DestinationFirewallGroups,
+				types.OpenApiReference{ID: "urn:vcloud:firewallGroup:other"})
+		}},
+		{name: "no port profile", modify: func(rule *types.NsxtFirewallRule) { rule.ApplicationPortProfiles = nil }},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := newTestFirewallRule("capvcd-cluster-ssh", "urn:vcloud:firewallGroup:nodes")
+			tc.modify(rule)
+			if got := isSameFirewallRule(rule, desiredRule); got != tc.want {
+				t.Errorf("got [%t], want [%t]", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMergeClusterFirewallRules(t *testing.T) {
+	const prefix = "capvcd-a-"
+	userRule := newTestFirewallRule("user-rule", "urn:vcloud:firewallGroup:user")
+	otherClusterRule := newTestFirewallRule("capvcd-b-ssh", "urn:vcloud:firewallGroup:b")
+	ssh := newTestFirewallRule("capvcd-a-ssh", "urn:vcloud:firewallGroup:a")
+	http := newTestFirewallRule("capvcd-a-http", "urn:vcloud:firewallGroup:a")
+	staleSSH := newTestFirewallRule("capvcd-a-ssh", "urn:vcloud:firewallGroup:stale")
+
+	testCases := []struct {
+		name         string
+		rules        []*types.NsxtFirewallRule
+		desiredRules []*types.NsxtFirewallRule
+		want         []*types.NsxtFirewallRule
+		wantInPlace  bool
+	}{
+		{
+			name:         "rules of a new cluster go before the other rules",
+			rules:        []*types.NsxtFirewallRule{userRule, otherClusterRule},
+			desiredRules: []*types.NsxtFirewallRule{ssh, http},
+			want:         []*types.NsxtFirewallRule{ssh, http, userRule, otherClusterRule},
+		},
+		{
+			name:         "rules in place behind the rules of another cluster are kept there",
+			rules:        []*types.NsxtFirewallRule{otherClusterRule, ssh, http, userRule},
+			desiredRules: []*types.NsxtFirewallRule{ssh, http},
+			want:         []*types.NsxtFirewallRule{otherClusterRule, ssh, http, userRule},
+			wantInPlace:  true,
+		},
+		{
+			name:         "changed rules take the place of the first rule of the cluster",
+			rules:        []*types.NsxtFirewallRule{userRule, staleSSH, otherClusterRule},
+			desiredRules: []*types.NsxtFirewallRule{ssh, http},
+			want:         []*types.NsxtFirewallRule{userRule, ssh, http, otherClusterRule},
+		},
+		{
+			name:         "reordered rules are not in place",
+			rules:        []*types.NsxtFirewallRule{http, ssh},
+			desiredRules: []*types.NsxtFirewallRule{ssh, http},
+			want:         []*types.NsxtFirewallRule{ssh, http},
+		},
+		{
+			name:         "rules removed from the spec are dropped",
+			rules:        []*types.NsxtFirewallRule{ssh, userRule, http},
+			desiredRules: []*types.NsxtFirewallRule{ssh},
+			want:         []*types.NsxtFirewallRule{ssh, userRule},
+		},
+		{
+			name:         "no desired rules removes the rules of the cluster",
+			rules:        []*types.NsxtFirewallRule{ssh, userRule},
+			desiredRules: []*types.NsxtFirewallRule{},
+			want:         []*types.NsxtFirewallRule{userRule},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, inPlace := mergeClusterFirewallRules(tc.rules, prefix, tc.desiredRules)
+			if inPlace != tc.wantInPlace {
+				t.Errorf("got in place [%t], want [%t]", inPlace, tc.wantInPlace)
+			}
+			if !reflect.DeepEqual(getFirewallRuleNames(got), getFirewallRuleNames(tc.want)) {
+				t.Fatalf("got rules %v, want %v", getFirewallRuleNames(got), getFirewallRuleNames(tc.want))
+			}
+			for idx := range got {
+				if got[idx] != tc.want[idx] {
+					t.Errorf("rule [%d] [%s] is not the expected one", idx, got[idx].Name)
+				}
+			}
+		})
+	}
+}
+
+func getFirewallRuleNames(rules []*types.NsxtFirewallRule) []string {
+	names := make([]string, len(rules))
+	for i, rule := range rules {
+		names[i] = rule.Name
+	}
+	return names
+}
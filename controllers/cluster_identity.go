@@ -0,0 +1,55 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vcdclusteridentities,verbs=get;list;watch
+
+// getDefinedCredentialsForCluster returns the credentials defined for the VCDCluster: its userContext, or the Secret
+// and auth type of the VCDClusterIdentity it references. The namespace of the VCDCluster must still be allowed by the
+// VCDClusterIdentity, as the allowed namespaces may have changed since the webhook admitted the VCDCluster.
+func getDefinedCredentialsForCluster(ctx context.Context, cli client.Client,
+	vcdCluster *infrav1beta3.VCDCluster) (infrav1beta3.UserCredentialsContext, error) {
+
+	identityRef := vcdCluster.Spec.IdentityRef
+	if identityRef == nil {
+		return vcdCluster.Spec.UserCredentialsContext, nil
+	}
+	identity := &infrav1beta3.VCDClusterIdentity{}
+	if err := cli.Get(ctx, types.NamespacedName{Name: identityRef.Name}, identity); err != nil {
+		return infrav1beta3.UserCredentialsContext{}, fmt.Errorf("error getting VCDClusterIdentity [%s]: [%v]",
+			identityRef.Name, err)
+	}
+	namespace := &corev1.Namespace{}
+	if err := cli.Get(ctx, types.NamespacedName{Name: vcdCluster.Namespace}, namespace); err != nil {
+		return infrav1beta3.UserCredentialsContext{}, fmt.Errorf("error getting namespace [%s]: [%v]",
+			vcdCluster.Namespace, err)
+	}
+	allowed, err := identity.AllowsNamespace(namespace)
+	if err != nil {
+		return infrav1beta3.UserCredentialsContext{}, err
+	}
+	if !allowed {
+		return infrav1beta3.UserCredentialsContext{}, fmt.Errorf(
+			"VCDClusterIdentity [%s] does not allow namespace [%s] of VCDCluster [%s]", identityRef.Name,
+			vcdCluster.Namespace, vcdCluster.Name)
+	}
+
+	secretRef := identity.Spec.SecretRef
+	return infrav1beta3.UserCredentialsContext{
+		SecretRef: &secretRef,
+		AuthType:  identity.Spec.AuthType,
+	}, nil
+}
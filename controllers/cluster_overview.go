@@ -0,0 +1,106 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	kcpv1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// updateClusterOverviewStatus publishes in the status of the VCDCluster the Kubernetes version and the number of
+// machines of the cluster, shown by `kubectl get vcdclusters`. The counts are those of the KubeadmControlPlane and
+// MachineDeployments of the cluster.
+func updateClusterOverviewStatus(ctx context.Context, cli client.Client, cluster clusterv1.Cluster,
+	vcdCluster *infrav1beta3.VCDCluster) error {
+
+	kcpList, err := getAllKubeadmControlPlaneForCluster(ctx, cli, cluster)
+	if err != nil {
+		return fmt.Errorf("failed to query all KubeadmControlPlane objects for the cluster [%s]: [%v]", cluster.Name, err)
+	}
+	mds, err := getAllMachineDeploymentsForCluster(ctx, cli, cluster)
+	if err != nil {
+		return fmt.Errorf("failed to query all machine deployments for the cluster [%s]: [%v]", cluster.Name, err)
+	}
+
+	kubernetesVersion := ""
+	controlPlaneReplicas, readyControlPlaneReplicas := int32(0), int32(0)
+	for idx := range kcpList.Items {
+		replicas, readyReplicas, version := getReplicasAndVersion(&kcpList.Items[idx])
+		controlPlaneReplicas += replicas
+		readyControlPlaneReplicas += readyReplicas
+		kubernetesVersion = version
+	}
+	workerReplicas, readyWorkerReplicas := int32(0), int32(0)
+	for idx := range mds.Items {
+		replicas, readyReplicas, _ := getReplicasAndVersion(&mds.Items[idx])
+		workerReplicas += replicas
+		readyWorkerReplicas += readyReplicas
+	}
+
+	vcdCluster.Status.KubernetesVersion = kubernetesVersion
+	vcdCluster.Status.ControlPlaneReplicas = controlPlaneReplicas
+	vcdCluster.Status.ReadyControlPlaneReplicas = readyControlPlaneReplicas
+	vcdCluster.Status.WorkerReplicas = workerReplicas
+	vcdCluster.Status.ReadyWorkerReplicas = readyWorkerReplicas
+	return nil
+}
+
+// ClusterMachinesToVCDCluster maps a KubeadmControlPlane or MachineDeployment to the VCDCluster of its cluster, so that
+// the machine counts of the VCDCluster follow the scaling of the cluster.
+func (r *VCDClusterReconciler) ClusterMachinesToVCDCluster(o client.Object) []ctrl.Request {
+	clusterName, ok := o.GetLabels()[clusterv1.ClusterNameLabel]
+	if !ok {
+		return nil
+	}
+	cluster, err := util.GetClusterByName(context.TODO(), r.Client, o.GetNamespace(), clusterName)
+	if err != nil || cluster.Spec.InfrastructureRef == nil || cluster.Spec.InfrastructureRef.Kind != "VCDCluster" {
+		return nil
+	}
+	return []ctrl.Request{{
+		NamespacedName: types.NamespacedName{Namespace: cluster.Namespace, Name: cluster.Spec.InfrastructureRef.Name},
+	}}
+}
+
+// getReplicasAndVersion returns the replicas, ready replicas and Kubernetes version of a KubeadmControlPlane or
+// MachineDeployment, as reported in the status of the VCDCluster.
+func getReplicasAndVersion(o client.Object) (int32, int32, string) {
+	switch obj := o.(type) {
+	case *kcpv1.KubeadmControlPlane:
+		version := obj.Spec.Version
+		if obj.Status.Version != nil {
+			version = *obj.Status.Version
+		}
+		return obj.Status.Replicas, obj.Status.ReadyReplicas, version
+	case *clusterv1.MachineDeployment:
+		return obj.Status.Replicas, obj.Status.ReadyReplicas, ""
+	}
+	return 0, 0, ""
+}
+
+// replicasOrVersionChanged filters the updates of the KubeadmControlPlanes and MachineDeployments down to the changes
+// of their replicas or version.
+func replicasOrVersionChanged() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldReplicas, oldReadyReplicas, oldVersion := getReplicasAndVersion(e.ObjectOld)
+			newReplicas, newReadyReplicas, newVersion := getReplicasAndVersion(e.ObjectNew)
+			return oldReplicas != newReplicas || oldReadyReplicas != newReadyReplicas || oldVersion != newVersion
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
@@ -0,0 +1,89 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Phases of the clusters reported by the capvcd_cluster_phase_transition_seconds metric
+const (
+	ClusterPhaseInfrastructureReady     = "InfrastructureReady"
+	ClusterPhaseControlPlaneInitialized = "ControlPlaneInitialized"
+	ClusterPhaseFirstWorkerReady        = "FirstWorkerReady"
+	ClusterPhaseDeletionStarted         = "DeletionStarted"
+	ClusterPhaseDeletionCompleted       = "DeletionCompleted"
+)
+
+// clusterPhaseTransitionDuration observes how long the clusters took to reach each phase, so that the provisioning
+// and deletion SLOs can be computed per tenant and per OVDC.
+var clusterPhaseTransitionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name: "capvcd_cluster_phase_transition_seconds",
+	Help: "Seconds from the creation of the clusters to their phase transitions, by org, OVDC and phase. The " +
+		"DeletionCompleted phase is measured from the DeletionStarted phase instead.",
+	Buckets: prometheus.ExponentialBuckets(30, 2, 10),
+}, []string{"org", "ovdc", "phase"})
+
+func init() {
+	metrics.Registry.MustRegister(clusterPhaseTransitionDuration)
+}
+
+// recordClusterPhaseTransition records the time of a phase transition of the VCDCluster in its status, unless it is
+// already recorded, and observes the time elapsed since the start of the cluster, or since its deletion started.
+func recordClusterPhaseTransition(vcdCluster *infrav1beta3.VCDCluster, transitionTime **metav1.Time, phase string,
+	at metav1.Time) {
+
+	if *transitionTime != nil {
+		return
+	}
+	*transitionTime = &at
+	clusterPhaseTransitionDuration.WithLabelValues(getOrgName(vcdCluster), getOvdcName(vcdCluster), phase).
+		Observe(at.Sub(vcdCluster.CreationTimestamp.Time).Seconds())
+}
+
+// recordClusterPhaseTransitions records the phase transitions of the cluster observed since the last reconciliation
+// of its VCDCluster. The control plane initialization is timed by the condition of the Cluster; the other transitions
+// are timed by their first observation.
+func recordClusterPhaseTransitions(cluster *clusterv1.Cluster, vcdCluster *infrav1beta3.VCDCluster) {
+	transitions := &vcdCluster.Status.PhaseTransitions
+	now := metav1.Now()
+	if !vcdCluster.DeletionTimestamp.IsZero() {
+		recordClusterPhaseTransition(vcdCluster, &transitions.DeletionStarted, ClusterPhaseDeletionStarted,
+			*vcdCluster.DeletionTimestamp)
+		return
+	}
+	if vcdCluster.Status.Ready {
+		recordClusterPhaseTransition(vcdCluster, &transitions.InfrastructureReady, ClusterPhaseInfrastructureReady, now)
+	}
+	if cluster == nil {
+		return
+	}
+	if conditions.IsTrue(cluster, clusterv1.ControlPlaneInitializedCondition) {
+		recordClusterPhaseTransition(vcdCluster, &transitions.ControlPlaneInitialized,
+			ClusterPhaseControlPlaneInitialized,
+			*conditions.GetLastTransitionTime(cluster, clusterv1.ControlPlaneInitializedCondition))
+	}
+	if vcdCluster.Status.ReadyWorkerReplicas > 0 {
+		recordClusterPhaseTransition(vcdCluster, &transitions.FirstWorkerReady, ClusterPhaseFirstWorkerReady, now)
+	}
+}
+
+// recordClusterDeletionCompleted observes the time taken to delete the infrastructure of the cluster, once its
+// finalizer is removed. The completion is not recorded in the status of the VCDCluster, which is deleted with it.
+func recordClusterDeletionCompleted(vcdCluster *infrav1beta3.VCDCluster) {
+	if vcdCluster.DeletionTimestamp.IsZero() {
+		return
+	}
+	clusterPhaseTransitionDuration.WithLabelValues(getOrgName(vcdCluster), getOvdcName(vcdCluster),
+		ClusterPhaseDeletionCompleted).Observe(time.Since(vcdCluster.DeletionTimestamp.Time).Seconds())
+}
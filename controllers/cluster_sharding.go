@@ -0,0 +1,322 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ClusterShardLeasePrefix is the prefix of the names of the Leases renewed by the replicas sharing the clusters.
+	ClusterShardLeasePrefix = "capvcd-shard-"
+	// ClusterShardLabel marks the Leases of the replicas sharing the clusters.
+	ClusterShardLabel = "infrastructure.cluster.x-k8s.io/capvcd-shard"
+	// ClusterClaimLeasePrefix is the prefix of the names of the Leases held, in the namespace of a cluster, by the
+	// replica reconciling the cluster.
+	ClusterClaimLeasePrefix = "capvcd-cluster-claim-"
+
+	// DefaultClusterShardLeaseDuration is the default duration after which a replica which stopped renewing its Lease
+	// is no longer a member, and its clusters are taken over by the other replicas.
+	DefaultClusterShardLeaseDuration = 60 * time.Second
+)
+
+//+kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
+
+// ClusterShardManager splits the clusters between the active replicas of the manager. Each replica renews a Lease in
+// the manager namespace; every replica computes the members from the Leases and assigns each cluster to a member by
+// rendezvous hashing, so that the replicas agree on the owner of a cluster without coordination and only the clusters
+// of a joining or leaving member move.
+//
+// A joining member becomes a member only once its Lease has been held for a lease duration, and a leaving member stops
+// being one only once its Lease expired, which leaves the other replicas time to observe the change before its
+// clusters move. A replica which failed to renew its Lease for a lease duration owns no cluster.
+//
+// As the replicas may briefly disagree on the members, the owner of a cluster also claims it with a Lease of the
+// cluster before touching its VCD resources, so that a single replica reconciles a cluster at a time: a cluster is only
+// taken over once the claim of its previous owner, which it no longer renews, expired.
+type ClusterShardManager struct {
+	Client client.Client
+	// APIReader reads the Leases without starting an informer on them.
+	APIReader     client.Reader
+	Namespace     string
+	Identity      string
+	LeaseDuration time.Duration
+
+	lock        sync.RWMutex
+	members     []string
+	lastRenewal time.Time
+	// claims are the times the claims of the clusters held by the replica were last renewed, keyed by the
+	// namespace/name of the clusters.
+	claims map[string]time.Time
+}
+
+// NeedLeaderElection makes all the replicas renew their Lease, as the clusters are shared between them instead of
+// being reconciled by the leader.
+func (m *ClusterShardManager) NeedLeaderElection() bool {
+	return false
+}
+
+// Start renews the Lease of the replica and refreshes the members until the manager stops.
+func (m *ClusterShardManager) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithValues("identity", m.Identity)
+	log.Info("Sharing the clusters with the other replicas", "namespace", m.Namespace,
+		"leaseDuration", m.LeaseDuration)
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := m.renewLease(ctx); err != nil {
+			log.Error(err, "failed to renew the cluster shard lease")
+		}
+		if err := m.refreshMembers(ctx); err != nil {
+			log.Error(err, "failed to refresh the cluster shard members")
+		}
+		// the VMs of the clusters taken over by another replica are no longer created by this one
+		cancelVMCreations(func(creation *vmCreation) bool {
+			namespace, clusterName, _ := strings.Cut(creation.cluster, "/")
+			return !m.OwnsCluster(namespace, clusterName)
+		})
+	}, m.LeaseDuration/3)
+	return nil
+}
+
+// renewLease creates or renews the Lease of the replica.
+func (m *ClusterShardManager) renewLease(ctx context.Context) error {
+	now := metav1.NewMicroTime(time.Now())
+	leaseDurationSeconds := int32(m.LeaseDuration.Seconds())
+	leaseName := ClusterShardLeasePrefix + m.Identity
+
+	lease := &coordinationv1.Lease{}
+	err := m.APIReader.Get(ctx, client.ObjectKey{Namespace: m.Namespace, Name: leaseName}, lease)
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      leaseName,
+				Namespace: m.Namespace,
+				Labels:    map[string]string{ClusterShardLabel: "true"},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &m.Identity,
+				LeaseDurationSeconds: &leaseDurationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		if err = m.Client.Create(ctx, lease); err != nil {
+			return fmt.Errorf("failed to create lease [%s/%s]: [%v]", m.Namespace, leaseName, err)
+		}
+		m.markRenewed(now.Time)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get lease [%s/%s]: [%v]", m.Namespace, leaseName, err)
+	}
+
+	// a lease which expired is acquired again, so that the replica rejoins only after a lease duration
+	if isClusterShardLeaseExpired(lease, now.Time) {
+		lease.Spec.AcquireTime = &now
+	}
+	lease.Spec.HolderIdentity = &m.Identity
+	lease.Spec.LeaseDurationSeconds = &leaseDurationSeconds
+	lease.Spec.RenewTime = &now
+	if err = m.Client.Update(ctx, lease); err != nil {
+		return fmt.Errorf("failed to renew lease [%s/%s]: [%v]", m.Namespace, leaseName, err)
+	}
+	m.markRenewed(now.Time)
+	return nil
+}
+
+func (m *ClusterShardManager) markRenewed(renewTime time.Time) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.lastRenewal = renewTime
+}
+
+// refreshMembers computes the members from the Leases of the replicas.
+func (m *ClusterShardManager) refreshMembers(ctx context.Context) error {
+	leaseList := &coordinationv1.LeaseList{}
+	if err := m.APIReader.List(ctx, leaseList, client.InNamespace(m.Namespace),
+		client.MatchingLabels{ClusterShardLabel: "true"}); err != nil {
+		return fmt.Errorf("failed to list the cluster shard leases in namespace [%s]: [%v]", m.Namespace, err)
+	}
+	now := time.Now()
+	members := make([]string, 0, len(leaseList.Items))
+	for i := range leaseList.Items {
+		lease := &leaseList.Items[i]
+		if lease.Spec.HolderIdentity == nil || isClusterShardLeaseExpired(lease, now) {
+			continue
+		}
+		if lease.Spec.AcquireTime == nil || now.Sub(lease.Spec.AcquireTime.Time) < m.LeaseDuration {
+			continue
+		}
+		members = append(members, *lease.Spec.HolderIdentity)
+	}
+	sort.Strings(members)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if fmt.Sprint(members) != fmt.Sprint(m.members) {
+		ctrl.LoggerFrom(ctx).Info("Cluster shard members changed", "previousMembers", m.members, "members", members)
+	}
+	m.members = members
+	return nil
+}
+
+// isClusterShardLeaseExpired checks if the holder of the Lease stopped renewing it.
+func isClusterShardLeaseExpired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	leaseDuration := time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	return now.After(lease.Spec.RenewTime.Add(leaseDuration))
+}
+
+// getClusterShardOwner returns the member with the highest rendezvous hash for the cluster.
+func getClusterShardOwner(members []string, namespace string, clusterName string) string {
+	owner := ""
+	var ownerWeight uint64
+	for _, member := range members {
+		// SHA-256 spreads the weights of members differing by a single character, e.g. by their ordinal
+		hash := sha256.Sum256([]byte(member + "/" + namespace + "/" + clusterName))
+		if weight := binary.BigEndian.Uint64(hash[:8]); owner == "" || weight > ownerWeight {
+			owner, ownerWeight = member, weight
+		}
+	}
+	return owner
+}
+
+// OwnsCluster checks if the cluster is assigned to this replica. All the clusters are owned when sharding is disabled,
+// i.e. when the ClusterShardManager is nil.
+func (m *ClusterShardManager) OwnsCluster(namespace string, clusterName string) bool {
+	if m == nil {
+		return true
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if time.Since(m.lastRenewal) > m.LeaseDuration {
+		return false
+	}
+	return getClusterShardOwner(m.members, namespace, clusterName) == m.Identity
+}
+
+// ClaimCluster checks if the cluster is assigned to this replica and claims it with the Lease of the cluster, which
+// must be done before touching the VCD resources of the cluster. The claim is refused while another replica holds it,
+// e.g. the previous owner of a cluster taken over. The Cluster, if not nil, owns the Lease so that it is deleted with it.
+// All the clusters are claimed when sharding is disabled, i.e. when the ClusterShardManager is nil.
+func (m *ClusterShardManager) ClaimCluster(ctx context.Context, namespace string, clusterName string,
+	cluster *clusterv1.Cluster) (bool, error) {
+
+	if m == nil {
+		return true, nil
+	}
+	key := fmt.Sprintf("%s/%s", namespace, clusterName)
+	if !m.OwnsCluster(namespace, clusterName) {
+		m.lock.Lock()
+		delete(m.claims, key)
+		m.lock.Unlock()
+		return false, nil
+	}
+	m.lock.RLock()
+	claimRenewal, claimed := m.claims[key]
+	m.lock.RUnlock()
+	if claimed && time.Since(claimRenewal) < m.LeaseDuration/3 {
+		return true, nil
+	}
+
+	now := metav1.NewMicroTime(time.Now())
+	claimed, err := m.renewClaim(ctx, namespace, clusterName, cluster, now)
+	if err != nil || !claimed {
+		m.lock.Lock()
+		delete(m.claims, key)
+		m.lock.Unlock()
+		return false, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.claims == nil {
+		m.claims = make(map[string]time.Time)
+	}
+	m.claims[key] = now.Time
+	return true, nil
+}
+
+// renewClaim creates, renews or takes over the Lease of the cluster, and reports if the replica holds it. The Lease is
+// updated with its resource version, so that a single replica takes over a claim which expired.
+func (m *ClusterShardManager) renewClaim(ctx context.Context, namespace string, clusterName string,
+	cluster *clusterv1.Cluster, now metav1.MicroTime) (bool, error) {
+
+	leaseDurationSeconds := int32(m.LeaseDuration.Seconds())
+	leaseName := ClusterClaimLeasePrefix + clusterName
+
+	lease := &coordinationv1.Lease{}
+	err := m.APIReader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: leaseName}, lease)
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      leaseName,
+				Namespace: namespace,
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &m.Identity,
+				LeaseDurationSeconds: &leaseDurationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		if cluster != nil {
+			lease.OwnerReferences = []metav1.OwnerReference{
+				*metav1.NewControllerRef(cluster, clusterv1.GroupVersion.WithKind("Cluster")),
+			}
+		}
+		if err = m.Client.Create(ctx, lease); apierrors.IsAlreadyExists(err) {
+			return false, nil
+		} else if err != nil {
+			return false, fmt.Errorf("failed to create lease [%s/%s]: [%v]", namespace, leaseName, err)
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get lease [%s/%s]: [%v]", namespace, leaseName, err)
+	}
+
+	heldByReplica := lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity == m.Identity
+	if !heldByReplica && !isClusterShardLeaseExpired(lease, now.Time) {
+		return false, nil
+	}
+	if !heldByReplica {
+		lease.Spec.HolderIdentity = &m.Identity
+		lease.Spec.AcquireTime = &now
+		lease.Spec.LeaseTransitions = pointer.Int32(pointer.Int32Deref(lease.Spec.LeaseTransitions, 0) + 1)
+	}
+	lease.Spec.LeaseDurationSeconds = &leaseDurationSeconds
+	lease.Spec.RenewTime = &now
+	if err = m.Client.Update(ctx, lease); apierrors.IsConflict(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to renew lease [%s/%s]: [%v]", namespace, leaseName, err)
+	}
+	return true, nil
+}
+
+// GetRequeueAfter returns the delay after which a cluster owned by another replica is checked again, in case its owner
+// left.
+func (m *ClusterShardManager) GetRequeueAfter() time.Duration {
+	return m.LeaseDuration
+}
@@ -0,0 +1,106 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestGetClusterShardOwner(t *testing.T) {
+	members := []string{"replica-0", "replica-1", "replica-2"}
+	reversedMembers := []string{"replica-2", "replica-1", "replica-0"}
+	remainingMembers := []string{"replica-0", "replica-2"}
+
+	if owner := getClusterShardOwner(nil, "ns", "cluster"); owner != "" {
+		t.Errorf("got owner [%s] without members, want none", owner)
+	}
+
+	clustersOfMember := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		clusterName := fmt.Sprintf("cluster-%d", i)
+		owner := getClusterShardOwner(members, "ns", clusterName)
+		clustersOfMember[owner]++
+		if reversedOwner := getClusterShardOwner(reversedMembers, "ns", clusterName); reversedOwner != owner {
+			t.Errorf("cluster [%s] is owned by [%s] or [%s] depending on the order of the members", clusterName,
+				owner, reversedOwner)
+		}
+		// only the clusters of the member leaving move
+		remainingOwner := getClusterShardOwner(remainingMembers, "ns", clusterName)
+		if owner != "replica-1" && remainingOwner != owner {
+			t.Errorf("cluster [%s] moved from [%s] to [%s] when replica-1 left", clusterName, owner,
+				remainingOwner)
+		}
+	}
+	for _, member := range members {
+		if clustersOfMember[member] < 50 {
+			t.Errorf("member [%s] owns [%d] of 300 clusters", member, clustersOfMember[member])
+		}
+	}
+}
+
+func TestOwnsCluster(t *testing.T) {
+	var disabledShards *ClusterShardManager
+	if !disabledShards.OwnsCluster("ns", "cluster") {
+		t.Errorf("cluster is not owned with sharding disabled")
+	}
+
+	owner := getClusterShardOwner([]string{"replica-0", "replica-1"}, "ns", "cluster")
+	testCases := []struct {
+		name        string
+		identity    string
+		lastRenewal time.Time
+		want        bool
+	}{
+		{name: "owner", identity: owner, lastRenewal: time.Now(), want: true},
+		{name: "other member", identity: map[string]string{"replica-0": "replica-1", "replica-1": "replica-0"}[owner],
+			lastRenewal: time.Now()},
+		{name: "owner which failed to renew its lease", identity: owner, lastRenewal: time.Now().Add(-2 * time.Minute)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			shards := &ClusterShardManager{
+				Identity:      tc.identity,
+				LeaseDuration: time.Minute,
+				members:       []string{"replica-0", "replica-1"},
+				lastRenewal:   tc.lastRenewal,
+			}
+			if got := shards.OwnsCluster("ns", "cluster"); got != tc.want {
+				t.Errorf("got [%t], want [%t]", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsClusterShardLeaseExpired(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name      string
+		renewTime *metav1.MicroTime
+		want      bool
+	}{
+		{name: "renewed", renewTime: &metav1.MicroTime{Time: now.Add(-30 * time.Second)}},
+		{name: "not renewed for a lease duration", renewTime: &metav1.MicroTime{Time: now.Add(-2 * time.Minute)},
+			want: true},
+		{name: "never renewed", want: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lease := &coordinationv1.Lease{Spec: coordinationv1.LeaseSpec{
+				LeaseDurationSeconds: pointer.Int32(60),
+				RenewTime:            tc.renewTime,
+			}}
+			if got := isClusterShardLeaseExpired(lease, now); got != tc.want {
+				t.Errorf("got [%t], want [%t]", got, tc.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,9 @@ package controllers
 
 import clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 
+// All the conditions have a positive polarity, as the ones of Cluster API: a condition is true when its subject is in
+// the expected state, and false with a reason and a severity telling what is wrong otherwise.
+
 // Conditions and condition Reasons for the DockerMachine object
 
 const (
@@ -31,6 +34,29 @@ const (
 	// an error while provisioning the container that provides the DockerMachine infrastructure; those kind of
 	// errors are usually transient and failed provisioning are automatically re-tried by the controller.
 	ContainerProvisioningFailedReason = "ContainerProvisioningFailed"
+
+	// WaitingForTaskCapacityReason (Severity=Info) documents a VCDMachine waiting to create its VM because VCD
+	// rejected tasks of the org of the cluster as its task queue was saturated; the VM creations in the org are paced
+	// and resume automatically as the queue frees up.
+	WaitingForTaskCapacityReason = "WaitingForTaskCapacity"
+
+	// TemplateNotReadyReason (Severity=Info) documents a VCDMachine waiting to create its VM because its template is
+	// still being uploaded, imported or synced into its catalog; the VM is created automatically once the template is
+	// ready.
+	TemplateNotReadyReason = "TemplateNotReady"
+
+	// WaitingForPreTerminateHooksReason (Severity=Info) documents a VCDMachine being deleted whose VM is kept until the
+	// pre-terminate hooks of its Machine are removed by their owners.
+	WaitingForPreTerminateHooksReason = "WaitingForPreTerminateHooks"
+
+	// VMShuttingDownReason (Severity=Info) documents a VCDMachine being deleted whose VM is given time for its guest OS
+	// to shut down before it is powered off and deleted.
+	VMShuttingDownReason = "VMShuttingDown"
+
+	// VCDReferenceNotFoundReason (Severity=Error) documents a VCDMachine or VCDMachineTemplate referencing a catalog,
+	// template, compute policy or storage profile which does not exist in VCD or is not available to the org or OVDC of
+	// the cluster; the spec must be fixed, or the entity created, for the VM to be created.
+	VCDReferenceNotFoundReason = "VCDReferenceNotFound"
 )
 
 const (
@@ -51,6 +77,56 @@ const (
 	// bootstrapping the Kubernetes node on the machine just provisioned; those kind of errors are usually
 	// transient and failed bootstrap are automatically re-tried by the controller.
 	BootstrapFailedReason = "BootstrapFailed"
+
+	// BootstrapTimedOutReason documents a VCDMachine whose VM was not bootstrapped within its bootstrap timeout. The
+	// bootstrap is retried in place (Severity=Warning) while the machine has retries left, then the machine is failed
+	// for its owner to replace it (Severity=Error).
+	BootstrapTimedOutReason = "BootstrapTimedOut"
+)
+
+// Conditions and condition Reasons of the steps of the provisioning of a VCDMachine, summarized with
+// ContainerProvisionedCondition and BootstrapExecSucceededCondition into its Ready condition. As with the other
+// Cluster API conditions, they are True once the step completed.
+
+const (
+	// VAppReadyCondition documents that the vApp holding the VM of the VCDMachine exists.
+	VAppReadyCondition clusterv1.ConditionType = "VAppReady"
+
+	// VAppCreationFailedReason (Severity=Warning) documents a VCDMachine controller failing to create or find the vApp
+	// of the machine; the creation is retried by the controller.
+	VAppCreationFailedReason = "VAppCreationFailed"
+
+	// VMProvisionedCondition documents that the VM of the VCDMachine was created, attached to its networks and got an
+	// address.
+	VMProvisionedCondition clusterv1.ConditionType = "VMProvisioned"
+
+	// VMProvisioningReason (Severity=Info) documents a VCDMachine whose VM is being created or is waiting for its
+	// networks or address.
+	VMProvisioningReason = "VMProvisioning"
+
+	// WaitingForDHCPLeaseReason (Severity=Info) documents a VCDMachine whose VM was powered on to get its address from
+	// the DHCP service of its network, and which waits for the guest to report the leased address.
+	WaitingForDHCPLeaseReason = "WaitingForDHCPLease"
+
+	// VMProvisioningFailedReason (Severity=Warning) documents a VCDMachine controller failing to create or configure
+	// the VM of the machine; the provisioning is retried by the controller.
+	VMProvisioningFailedReason = "VMProvisioningFailed"
+
+	// VMProvisioningTimedOutReason (Severity=Error) documents a VCDMachine whose VM was not provisioned within its
+	// provisioning timeout; the machine is failed for its owner to replace it.
+	VMProvisioningTimedOutReason = "VMProvisioningTimedOut"
+
+	// VMAdoptionFailedReason (Severity=Error) documents a VCDMachine created with the provider ID of an existing VM
+	// which cannot be adopted, e.g. because the VM is not in the vApp of the machine or is the VM of another machine.
+	VMAdoptionFailedReason = "VMAdoptionFailed"
+
+	// BootstrapDeliveredCondition documents that the bootstrap data of the VCDMachine was passed to its VM through the
+	// guestinfo properties and the VM was powered on.
+	BootstrapDeliveredCondition clusterv1.ConditionType = "BootstrapDelivered"
+
+	// BootstrapDeliveryFailedReason (Severity=Warning) documents a VCDMachine controller failing to pass the bootstrap
+	// data to the VM or to power it on; the delivery is retried by the controller.
+	BootstrapDeliveryFailedReason = "BootstrapDeliveryFailed"
 )
 
 // Conditions and condition Reasons for the DockerCluster object
@@ -67,4 +143,402 @@ const (
 	// an error while provisioning the container that provides the cluster load balancer.; those kind of
 	// errors are usually transient and failed provisioning are automatically re-tried by the controller.
 	LoadBalancerProvisioningFailedReason = "LoadBalancerProvisioningFailed"
+
+	// WaitingForExternalLoadBalancerReason (Severity=Info) documents a VCDCluster whose infrastructure is managed by an
+	// external system, waiting for its control plane endpoint to be set or for its load balancer to be created.
+	WaitingForExternalLoadBalancerReason = "WaitingForExternalLoadBalancer"
+
+	// ControlPlaneEndpointNotSetReason (Severity=Error) documents a VCDCluster in the Passthrough control plane endpoint
+	// mode whose control plane endpoint is not set; the cluster is not reconciled further until it is set.
+	ControlPlaneEndpointNotSetReason = "ControlPlaneEndpointNotSet"
+
+	// ControlPlaneEndpointInvalidReason (Severity=Error) documents a VCDCluster in the Routed control plane endpoint
+	// mode whose control plane endpoint is a host name rather than the virtual IP announced by kube-vip.
+	ControlPlaneEndpointInvalidReason = "ControlPlaneEndpointInvalid"
+
+	// RDEReadyCondition documents that the RDE of the VCDCluster exists and reflects the state of the cluster. The
+	// condition is not set for the clusters without RDE.
+	RDEReadyCondition clusterv1.ConditionType = "RDEReady"
+
+	// RDEReconcileFailedReason (Severity=Warning) documents a VCDCluster controller failing to create, upgrade or
+	// update the RDE of the cluster; the RDE is reconciled again with the cluster.
+	RDEReconcileFailedReason = "RDEReconcileFailed"
+
+	// SiteCapabilitiesVerifiedCondition documents that the VCD site of the cluster meets the minimum version required
+	// by CAPVCD and supports the features requested by the VCDCluster.
+	SiteCapabilitiesVerifiedCondition clusterv1.ConditionType = "SiteCapabilitiesVerified"
+
+	// SiteCapabilitiesUnsupportedReason (Severity=Error) documents a VCDCluster controller detecting a VCD site which is
+	// too old or lacks a capability required by the VCDCluster; the cluster is not reconciled further until the site
+	// is upgraded or reconfigured.
+	SiteCapabilitiesUnsupportedReason = "SiteCapabilitiesUnsupported"
+
+	// CredentialsValidCondition documents that VCD accepts the credentials of the VCDCluster. The condition is false
+	// when VCD rejected them, typically because the password of the org user expired or the user was disabled or
+	// locked. Each VCDCluster using the same credentials on the same site gets the condition on its own reconciliation.
+	CredentialsValidCondition clusterv1.ConditionType = "CredentialsValid"
+
+	// CredentialsRejectedReason (Severity=Error) documents a VCDCluster controller detecting that VCD rejected the
+	// credentials of the cluster; the cluster is retried periodically until the credentials are updated.
+	CredentialsRejectedReason = "CredentialsRejected"
+
+	// CredentialsAcceptedReason documents the credentials of a VCDCluster being accepted again by VCD.
+	CredentialsAcceptedReason = "CredentialsAccepted"
+
+	// VCDAuthHealthyCondition documents that the controllers log into VCD for the VCDCluster. The condition is false
+	// when its credentials cannot be read or VCD rejected them; the cluster is reconciled again as soon as its
+	// credentials Secret changes.
+	VCDAuthHealthyCondition clusterv1.ConditionType = "VCDAuthHealthy"
+
+	// CredentialsUnavailableReason (Severity=Error) documents a VCDCluster controller failing to read the credentials
+	// Secret or the VCDClusterIdentity of the cluster.
+	CredentialsUnavailableReason = "CredentialsUnavailable"
+
+	// RefreshTokenRejectedReason (Severity=Error) documents a VCDCluster controller detecting that VCD rejected the API
+	// refresh token of the cluster, which expired or was revoked; the token is not sent to VCD again until the
+	// credentials Secret changes or RefreshTokenRetryInterval elapses.
+	RefreshTokenRejectedReason = "RefreshTokenRejected"
+
+	// SiteCertificateTrustedCondition documents that the VCD site of a VCDCluster with PinSiteCertificate enabled
+	// presented a pinned certificate, or that the VCD site of a VCDCluster with a VCDTrustBundleSecretRef presented a
+	// certificate issued by a CA of the bundle.
+	SiteCertificateTrustedCondition clusterv1.ConditionType = "SiteCertificateTrusted"
+
+	// SiteCertificateMismatchReason (Severity=Error) documents a VCDCluster controller detecting a VCD site presenting
+	// a certificate chain without any pinned certificate or not issued by a CA of the trust bundle; the credentials of
+	// the cluster are not sent to the site.
+	SiteCertificateMismatchReason = "SiteCertificateMismatch"
+)
+
+// Condition Reasons of the Ready condition of the VCDCluster and VCDMachine objects
+
+const (
+	// PausedReason (Severity=Info) documents a VCDCluster or VCDMachine which is not reconciled because the object or
+	// its Cluster is paused; status.ready keeps the value it had when the object was paused.
+	PausedReason = "Paused"
+
+	// DegradedReason (Severity=Warning) documents a provisioned VCDCluster or VCDMachine whose last reconciliation
+	// failed; status.ready stays true as the infrastructure still exists, but it may not match the desired state.
+	DegradedReason = "Degraded"
+)
+
+const (
+	// OvdcEnabledCondition documents that the OVDC of the VCDCluster is enabled. The condition is false while the
+	// OVDC is disabled by the provider, during which no new VMs are created; the VCDMachines waiting for a VM get the
+	// condition as well.
+	OvdcEnabledCondition clusterv1.ConditionType = "OvdcEnabled"
+
+	// OvdcDisabledByProviderReason (Severity=Warning) documents a VCDCluster or VCDMachine controller detecting that the
+	// OVDC of the cluster is disabled; the OVDC is checked periodically and provisioning resumes once it is enabled.
+	OvdcDisabledByProviderReason = "OvdcDisabledByProvider"
+
+	// OvdcEnabledReason documents the OVDC of a VCDCluster being enabled again by the provider.
+	OvdcEnabledReason = "OvdcEnabled"
+)
+
+const (
+	// OvdcInServiceCondition documents that the OVDC of the VCDCluster is not marked as under maintenance by an
+	// operator. The condition is false during the maintenance, during which no new VMs are created; the VCDMachines
+	// waiting for a VM get the condition as well.
+	OvdcInServiceCondition clusterv1.ConditionType = "OvdcInService"
+
+	// OvdcMaintenanceReason (Severity=Info) documents the OVDC of a VCDCluster being marked as under maintenance by the
+	// annotation of the VCDCluster; provisioning resumes once the annotation is removed.
+	OvdcMaintenanceReason = "OvdcMaintenance"
+
+	// OvdcMaintenanceCompletedReason documents the end of the maintenance of the OVDC of a VCDCluster.
+	OvdcMaintenanceCompletedReason = "OvdcMaintenanceCompleted"
+)
+
+const (
+	// ProviderApprovedCondition documents that the creation of the VM of a VCDMachine is not held by a blocking task of
+	// the provider, e.g. an approval workflow of VM creations. The condition is false while the task is blocked and is
+	// set to true once the provider resumes or aborts it.
+	ProviderApprovedCondition clusterv1.ConditionType = "ProviderApproved"
+
+	// TaskBlockedByProviderReason (Severity=Info) documents a VCDMachine whose VM creation task is blocked until the
+	// provider approves it; the task is checked periodically and the provisioning resumes once it is approved.
+	TaskBlockedByProviderReason = "TaskBlockedByProvider"
+
+	// TaskApprovedByProviderReason documents the blocked VM creation task of a VCDMachine being resumed by the
+	// provider.
+	TaskApprovedByProviderReason = "TaskApprovedByProvider"
+
+	// TaskRejectedByProviderReason documents the blocked VM creation task of a VCDMachine failing once the provider
+	// handled it, typically because the provider aborted it.
+	TaskRejectedByProviderReason = "TaskRejectedByProvider"
+)
+
+const (
+	// DeletionAllowedCondition documents that the VCDMachine being deleted can delete its VM. The condition is false
+	// while the deletion is blocked, its reason telling why and its message naming the blocking VCD entity, e.g. the URN
+	// of a running task or the attached independent disks.
+	DeletionAllowedCondition clusterv1.ConditionType = "DeletionAllowed"
+
+	// DeleteProtectedReason (Severity=Info) documents a VCDMachine being deleted whose VM is protected by the
+	// VCDMachineDeleteProtectionAnnotation, or by the DeletionProtectedAnnotation of its cluster being deleted.
+	DeleteProtectedReason = "DeleteProtected"
+
+	// IndependentDisksAttachedReason (Severity=Warning) documents a VCDMachine being deleted whose VM still has
+	// independent disks attached, e.g. by CSI; the VM is deleted once they are detached.
+	IndependentDisksAttachedReason = "IndependentDisksAttached"
+
+	// VAppBusyReason (Severity=Info) documents a VCDMachine being deleted whose vApp runs a VCD task; the VM is
+	// deleted once the task completes.
+	VAppBusyReason = "VAppBusy"
+
+	// VMTaskRunningReason (Severity=Info) documents a VCDMachine being deleted whose VM runs a VCD task; the VM is
+	// deleted once the task completes.
+	VMTaskRunningReason = "VMTaskRunning"
+)
+
+const (
+	// IPPoolAvailableCondition documents that the static IP pool of the OVDC network of the VCDCluster has free
+	// addresses. The condition is false while the pool is exhausted, during which no new VMs are created; the
+	// VCDMachines waiting for a VM are reported with the IPPoolExhaustedReason.
+	IPPoolAvailableCondition clusterv1.ConditionType = "IPPoolAvailable"
+
+	// IPPoolExhaustedReason (Severity=Warning) documents a VCDCluster or VCDMachine controller detecting that the IP
+	// pool of the OVDC network of the cluster is exhausted; the VM creations resume once addresses are released or
+	// added to the pool.
+	IPPoolExhaustedReason = "IPPoolExhausted"
+
+	// IPPoolExtendedReason documents the IP pool of the OVDC network of a VCDCluster being extended with
+	// VCDClusterSpec.IPPoolExtension.
+	IPPoolExtendedReason = "IPPoolExtended"
+
+	// IPPoolReplenishedReason documents the IP pool of the OVDC network of a VCDCluster having free addresses again.
+	IPPoolReplenishedReason = "IPPoolReplenished"
+
+	// ControlPlaneVIPReservedReason documents the virtual IP of the control plane endpoint of a VCDCluster in the
+	// KubeVip mode being removed from the IP pool of its OVDC network.
+	ControlPlaneVIPReservedReason = "ControlPlaneVIPReserved"
+
+	// ControlPlaneVIPReleasedReason documents the virtual IP of the control plane endpoint of a deleted VCDCluster being
+	// returned to the IP pool of its OVDC network.
+	ControlPlaneVIPReleasedReason = "ControlPlaneVIPReleased"
+
+	// ManagedNetworkCreatedReason documents the OVDC network of VCDClusterSpec.ManagedNetwork being created.
+	ManagedNetworkCreatedReason = "ManagedNetworkCreated"
+
+	// DHCPPoolEnabledReason documents the DHCP service of the OVDC network of a VCDCluster being enabled with the pool
+	// of VCDClusterSpec.IPAllocation.
+	DHCPPoolEnabledReason = "DHCPPoolEnabled"
+)
+
+const (
+	// OwnershipClaimVerifiedCondition documents that the VCD resources of the VCDCluster (vApp and RDE) are claimed by
+	// the management cluster running this controller, which is the only one allowed to mutate them.
+	OwnershipClaimVerifiedCondition clusterv1.ConditionType = "OwnershipClaimVerified"
+
+	// ClaimedByOtherManagementClusterReason (Severity=Error) documents a VCDCluster whose VCD resources are claimed by a
+	// different management cluster, e.g. after a failed move; the resources are not mutated until the VCDCluster is
+	// annotated with the takeover annotation.
+	ClaimedByOtherManagementClusterReason = "ClaimedByOtherManagementCluster"
+
+	// OwnershipTakenOverReason documents the VCD resources of a VCDCluster being claimed from another management cluster.
+	OwnershipTakenOverReason = "OwnershipTakenOver"
+
+	// OwnershipMovedReason documents the VCD resources of a VCDCluster being claimed by the management cluster the
+	// VCDCluster was moved to, e.g. by clusterctl move.
+	OwnershipMovedReason = "OwnershipMoved"
+)
+
+const (
+	// OptionalFeaturesAvailableCondition documents that the user of the VCDCluster has the rights for all the optional
+	// features of CAPVCD. It is only set in the minimal-rights mode, where the features the user lacks the rights for
+	// are disabled instead of failing the cluster.
+	OptionalFeaturesAvailableCondition clusterv1.ConditionType = "OptionalFeaturesAvailable"
+
+	// MissingRightsReason (Severity=Warning) documents a VCDCluster whose user lacks the rights for some optional
+	// features, e.g. the capvcdCluster RDE or the gateway firewall; the features are disabled and the rights are
+	// detected again periodically.
+	MissingRightsReason = "MissingRights"
+)
+
+const (
+	// EgressFirewallErrorReason documents a failure to program the egress allowlist of a VCDCluster on the gateway
+	// firewall of its edge gateway.
+	EgressFirewallErrorReason = "EgressFirewallError"
+
+	// EgressSNATErrorReason documents a failure to maintain the SNAT rule of VCDClusterSpec.EgressConfig on the edge
+	// gateway of a VCDCluster.
+	EgressSNATErrorReason = "EgressSNATError"
+
+	// FirewallRulesErrorReason documents a failure to program VCDClusterSpec.FirewallRules on the gateway firewall of
+	// the edge gateway of a VCDCluster.
+	FirewallRulesErrorReason = "FirewallRulesError"
+
+	// DHCPPoolErrorReason documents the OVDC network of a VCDCluster whose machines get their address from DHCP having
+	// no DHCP pool which CAPVCD can enable.
+	DHCPPoolErrorReason = "DHCPPoolError"
+)
+
+const (
+	// ControlPlaneEndpointReachableCondition documents that the VM of a VCDMachine reached the API server through the
+	// control plane endpoint of the cluster while bootstrapping. It is only set when the VCDCluster verifies the
+	// control plane endpoint.
+	ControlPlaneEndpointReachableCondition clusterv1.ConditionType = "ControlPlaneEndpointReachable"
+
+	// ControlPlaneEndpointUnreachableReason (Severity=Warning) documents a VM which could not reach the API server
+	// through the control plane endpoint, typically because the load balancer, its virtual service or the routing
+	// between the node network and the VIP is misconfigured.
+	ControlPlaneEndpointUnreachableReason = "ControlPlaneEndpointUnreachable"
+)
+
+const (
+	// ControlPlaneSizedCondition documents that the control plane machines of a VCDCluster use the control plane sizing
+	// policy of the VCDCluster. It is only set when the control plane sizing policy is set.
+	ControlPlaneSizedCondition clusterv1.ConditionType = "ControlPlaneSized"
+
+	// WaitingForControlPlaneHealthReason (Severity=Info) documents a control plane whose resize waits for the etcd
+	// cluster and the control plane components to be healthy and for any other rollout to complete.
+	WaitingForControlPlaneHealthReason = "WaitingForControlPlaneHealth"
+
+	// ControlPlaneResizingReason (Severity=Info) documents the control plane machines being replaced one at a time by
+	// machines of the new sizing policy.
+	ControlPlaneResizingReason = "ControlPlaneResizing"
+
+	// ControlPlaneResizeFailedReason (Severity=Warning) documents a failure to resize the control plane, e.g. a
+	// KubeadmControlPlane managed by a ClusterClass or a failure to clone its VCDMachineTemplate.
+	ControlPlaneResizeFailedReason = "ControlPlaneResizeFailed"
+)
+
+const (
+	// VMHardwareScaledCondition documents that the CPUs and memory of the VM of a VCDMachine match its spec. It is only
+	// set on provisioned VCDMachines with the InPlace vertical scaling policy.
+	VMHardwareScaledCondition clusterv1.ConditionType = "VMHardwareScaled"
+
+	// HotAddUnsupportedReason (Severity=Warning) documents a change of the CPUs or memory of a VCDMachine which cannot
+	// be applied to its running VM, e.g. a reduction, a change of the cores per socket or a VM without hot-add; the
+	// machine must be replaced to be scaled.
+	HotAddUnsupportedReason = "HotAddUnsupported"
+)
+
+const (
+	// BootDiskBusTypeAppliedCondition documents that the boot disk of the VM of a VCDMachine is attached to a controller
+	// of its BootDiskBusType. It is only set on VCDMachines with a BootDiskBusType.
+	BootDiskBusTypeAppliedCondition clusterv1.ConditionType = "BootDiskBusTypeApplied"
+
+	// BootDiskBusTypeDriftedReason (Severity=Warning) documents a boot disk attached to a controller of another bus
+	// type than the BootDiskBusType of the VCDMachine once its VM was powered on, e.g. because the controller was changed
+	// in VCD; the controller is only changed before the first power on of the VM, so the machine must be replaced.
+	BootDiskBusTypeDriftedReason = "BootDiskBusTypeDrifted"
+)
+
+const (
+	// MachineTemplatesResolvedCondition documents that the VCDMachineTemplates referenced by the KubeadmControlPlanes
+	// and MachineDeployments of a VCDCluster exist in the namespace of the cluster.
+	MachineTemplatesResolvedCondition clusterv1.ConditionType = "MachineTemplatesResolved"
+
+	// CrossNamespaceTemplateReferenceReason (Severity=Error) documents a KubeadmControlPlane or MachineDeployment
+	// referencing a VCDMachineTemplate of another namespace, which is not supported.
+	CrossNamespaceTemplateReferenceReason = "CrossNamespaceTemplateReference"
+
+	// VCDMachineTemplateNotFoundReason (Severity=Error) documents a KubeadmControlPlane or MachineDeployment
+	// referencing a VCDMachineTemplate which does not exist.
+	VCDMachineTemplateNotFoundReason = "VCDMachineTemplateNotFound"
+)
+
+const (
+	// VCDReferencesVerifiedCondition documents that the catalogs, templates, compute policies and storage profiles
+	// referenced by the VCDMachineTemplates of the KubeadmControlPlanes and MachineDeployments of a VCDCluster exist in
+	// VCD, so that an invalid reference is reported before the machines are created with it. The VCDReferenceNotFound
+	// reason is used when they don't.
+	VCDReferencesVerifiedCondition clusterv1.ConditionType = "VCDReferencesVerified"
+)
+
+const (
+	// InfrastructureAuditedCondition documents the outcome of the last periodic audit of the infrastructure of a
+	// VCDCluster. Its message lists what was verified, e.g. the load balancer, the VMs of the machines and the
+	// freshness of the RDE. It is only set when the audit is enabled.
+	InfrastructureAuditedCondition clusterv1.ConditionType = "InfrastructureAudited"
+
+	// InfrastructureAuditFailedReason (Severity=Warning) documents an audit of the infrastructure of a VCDCluster which
+	// found a missing or stale resource. It is also the reason of the Event recorded for the audit.
+	InfrastructureAuditFailedReason = "InfrastructureAuditFailed"
+
+	// InfrastructureAuditPassedReason is the reason of the Event recorded for an audit of the infrastructure of a
+	// VCDCluster which verified all its resources.
+	InfrastructureAuditPassedReason = "InfrastructureAuditPassed"
+)
+
+const (
+	// InstancesReadyCondition documents that the VMs of a VCDMachinePool match the replicas and the template of its
+	// MachinePool and are all powered on with their bootstrap data.
+	InstancesReadyCondition clusterv1.ConditionType = "InstancesReady"
+
+	// ScalingUpReason (Severity=Info) documents a VCDMachinePool creating VMs to reach the replicas of its MachinePool.
+	ScalingUpReason = "ScalingUp"
+
+	// ScalingDownReason (Severity=Info) documents a VCDMachinePool deleting VMs to reach the replicas of its
+	// MachinePool.
+	ScalingDownReason = "ScalingDown"
+
+	// RollingUpdateReason (Severity=Info) documents a VCDMachinePool replacing the VMs created from a previous
+	// template or bootstrap data secret.
+	RollingUpdateReason = "RollingUpdate"
+
+	// InstancesProvisioningFailedReason (Severity=Warning) documents a VCDMachinePool failing to create, bootstrap or
+	// delete its VMs.
+	InstancesProvisioningFailedReason = "InstancesProvisioningFailed"
+)
+
+// Reasons of the Events recorded on the VCDCluster and VCDMachine objects for the steps of the lifecycle of their
+// infrastructure, so that `kubectl describe` shows what the controllers did in VCD.
+
+const (
+	// VAppCreatedReason documents the creation of the vApp of a VCDCluster.
+	VAppCreatedReason = "VAppCreated"
+
+	// VAppDeletedReason documents the deletion of an empty vApp of the vApp strategy of a VCDCluster.
+	VAppDeletedReason = "VAppDeleted"
+
+	// LoadBalancerCreatedReason documents the creation of the load balancer of the control plane endpoint of a
+	// VCDCluster.
+	LoadBalancerCreatedReason = "LoadBalancerCreated"
+
+	// RDEUpgradedReason documents the upgrade of the RDE of a VCDCluster to the RDE version of CAPVCD.
+	RDEUpgradedReason = "RDEUpgraded"
+
+	// DeletionBlockedReason documents a VCDCluster or VCDMachine whose deletion cannot proceed, e.g. because the vApp
+	// of the cluster still has VMs, its VCD resources are claimed by another management cluster or the cluster is
+	// protected by the DeletionProtectedAnnotation.
+	DeletionBlockedReason = "DeletionBlocked"
+
+	// VMClonedReason documents the creation of the VM of a VCDMachine from its template.
+	VMClonedReason = "VMCloned"
+
+	// VMSnapshottedReason documents the VM of a VCDMachine replaced by an upgrade being kept powered off with a
+	// snapshot instead of being deleted.
+	VMSnapshottedReason = "VMSnapshotted"
+
+	// VMAdoptedReason documents an existing VM being adopted as the VM of a VCDMachine created with its provider ID.
+	VMAdoptedReason = "VMAdopted"
+
+	// TemplateImportedReason documents a template of the TemplateSources of a VCDCluster being imported into its
+	// catalog.
+	TemplateImportedReason = "TemplateImported"
+
+	// TemplateImportFailedReason documents a failure to import a template of the TemplateSources of a VCDCluster; the
+	// import is retried periodically.
+	TemplateImportFailedReason = "TemplateImportFailed"
+
+	// VMHardwareScaledReason documents the CPUs and memory of the VM of a VCDMachine being changed in place.
+	VMHardwareScaledReason = "VMHardwareScaled"
+
+	// GuestCustomizationCompletedReason documents the VM of a VCDMachine completing the phases of its bootstrap.
+	GuestCustomizationCompletedReason = "GuestCustomizationCompleted"
+
+	// LoadBalancerPoolMemberAddedReason documents the address of a control plane VCDMachine being added to the load
+	// balancer pool of the control plane endpoint.
+	LoadBalancerPoolMemberAddedReason = "LoadBalancerPoolMemberAdded"
+
+	// BootstrapRetriedReason documents the VM of a VCDMachine being power cycled to retry its bootstrap, which did not
+	// complete within the bootstrap timeout of the machine.
+	BootstrapRetriedReason = "BootstrapRetried"
+
+	// MachineFailedReason documents a VCDMachine being failed, e.g. because its VM was not provisioned or bootstrapped
+	// in time, so that its owner replaces it.
+	MachineFailedReason = "MachineFailed"
 )
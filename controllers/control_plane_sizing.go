@@ -0,0 +1,203 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	kcpv1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ControlPlaneSizingLabel marks the VCDMachineTemplates cloned to resize the control plane of the cluster it is
+	// set to.
+	ControlPlaneSizingLabel = "infrastructure.cluster.x-k8s.io/capvcd-control-plane-sizing"
+	// ControlPlaneSizingSourceAnnotation records the VCDMachineTemplate a control plane sizing clone was cloned from.
+	ControlPlaneSizingSourceAnnotation = "infrastructure.cluster.x-k8s.io/capvcd-control-plane-sizing-source"
+
+	// ControlPlaneSizingRequeueInterval is the interval at which the progress of a control plane resize is checked.
+	ControlPlaneSizingRequeueInterval = 30 * time.Second
+)
+
+// getControlPlaneSizingTemplateName returns the name of the clone of the VCDMachineTemplate with the sizing policy.
+// Sizing policy names are not valid object names, so the clone is suffixed with a hash of the policy.
+func getControlPlaneSizingTemplateName(sourceTemplateName string, sizingPolicy string) string {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(sizingPolicy))
+	return fmt.Sprintf("%s-%08x", sourceTemplateName, hash.Sum32())
+}
+
+// isControlPlaneHealthy checks that the etcd cluster and the control plane components of the KubeadmControlPlane are
+// healthy and that no rollout is in progress, i.e. that the next control plane machine can be replaced.
+func isControlPlaneHealthy(kcp *kcpv1.KubeadmControlPlane) (bool, string) {
+	desiredReplicas := int32(1)
+	if kcp.Spec.Replicas != nil {
+		desiredReplicas = *kcp.Spec.Replicas
+	}
+	if !conditions.IsTrue(kcp, kcpv1.EtcdClusterHealthyCondition) {
+		return false, "etcd cluster is not healthy"
+	}
+	if !conditions.IsTrue(kcp, kcpv1.ControlPlaneComponentsHealthyCondition) {
+		return false, "control plane components are not healthy"
+	}
+	if kcp.Status.Replicas != desiredReplicas || kcp.Status.ReadyReplicas != desiredReplicas ||
+		kcp.Status.UnavailableReplicas > 0 {
+		return false, fmt.Sprintf("[%d/%d] control plane machines are ready", kcp.Status.ReadyReplicas,
+			desiredReplicas)
+	}
+	return true, ""
+}
+
+// reconcileControlPlaneSizing moves the control plane machines to the control plane sizing policy of the VCDCluster.
+// The VCDMachineTemplate of the KubeadmControlPlane is cloned with the sizing policy once the control plane is healthy,
+// and the KubeadmControlPlane is pointed at the clone; the KubeadmControlPlane then replaces one machine at a time,
+// running its etcd and control plane health checks before each replacement. The ControlPlaneSized condition reports
+// the progress, and the clones of earlier resizes are deleted once the rollout completes.
+func (r *VCDClusterReconciler) reconcileControlPlaneSizing(ctx context.Context, cluster *clusterv1.Cluster,
+	vcdCluster *infrav1beta3.VCDCluster) (ctrl.Result, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+	sizingPolicy := vcdCluster.Spec.ControlPlaneSizingPolicy
+	if sizingPolicy == "" {
+		conditions.Delete(vcdCluster, ControlPlaneSizedCondition)
+		return ctrl.Result{}, nil
+	}
+	if cluster.Spec.Topology != nil {
+		conditions.MarkFalse(vcdCluster, ControlPlaneSizedCondition, ControlPlaneResizeFailedReason,
+			clusterv1.ConditionSeverityWarning,
+			"the control plane of a cluster with a topology must be resized through its ClusterClass")
+		return ctrl.Result{}, nil
+	}
+
+	kcpList, err := getAllKubeadmControlPlaneForCluster(ctx, r.Client, *cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if len(kcpList.Items) != 1 {
+		conditions.MarkFalse(vcdCluster, ControlPlaneSizedCondition, ControlPlaneResizeFailedReason,
+			clusterv1.ConditionSeverityWarning, "expected one KubeadmControlPlane for cluster [%s], found [%d]",
+			cluster.Name, len(kcpList.Items))
+		return ctrl.Result{}, nil
+	}
+	kcp := &kcpList.Items[0]
+	vcdMachineTemplate, err := getVCDMachineTemplateFromKCP(ctx, r.Client, *kcp)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	healthy, unhealthyMessage := isControlPlaneHealthy(kcp)
+	if vcdMachineTemplate.Spec.Template.Spec.SizingPolicy == sizingPolicy {
+		desiredReplicas := int32(1)
+		if kcp.Spec.Replicas != nil {
+			desiredReplicas = *kcp.Spec.Replicas
+		}
+		if kcp.Status.UpdatedReplicas < desiredReplicas || !healthy {
+			message := fmt.Sprintf("[%d/%d] control plane machines use sizing policy [%s]",
+				kcp.Status.UpdatedReplicas, desiredReplicas, sizingPolicy)
+			if !healthy {
+				message = fmt.Sprintf("%s; %s", message, unhealthyMessage)
+			}
+			conditions.MarkFalse(vcdCluster, ControlPlaneSizedCondition, ControlPlaneResizingReason,
+				clusterv1.ConditionSeverityInfo, "%s", message)
+			return ctrl.Result{RequeueAfter: ControlPlaneSizingRequeueInterval}, nil
+		}
+		conditions.MarkTrue(vcdCluster, ControlPlaneSizedCondition)
+		if err = r.deleteStaleControlPlaneSizingTemplates(ctx, cluster, vcdMachineTemplate.Name); err != nil {
+			log.Error(err, "failed to delete the VCDMachineTemplates of earlier control plane resizes")
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !healthy {
+		conditions.MarkFalse(vcdCluster, ControlPlaneSizedCondition, WaitingForControlPlaneHealthReason,
+			clusterv1.ConditionSeverityInfo, "waiting to resize the control plane: %s", unhealthyMessage)
+		return ctrl.Result{RequeueAfter: ControlPlaneSizingRequeueInterval}, nil
+	}
+
+	sourceTemplateName := vcdMachineTemplate.Name
+	if source, ok := vcdMachineTemplate.Annotations[ControlPlaneSizingSourceAnnotation]; ok {
+		sourceTemplateName = source
+	}
+	sizingTemplate := &infrav1beta3.VCDMachineTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getControlPlaneSizingTemplateName(sourceTemplateName, sizingPolicy),
+			Namespace: vcdMachineTemplate.Namespace,
+			Labels: map[string]string{
+				clusterv1.ClusterNameLabel: cluster.Name,
+				ControlPlaneSizingLabel:    cluster.Name,
+			},
+			Annotations: map[string]string{
+				ControlPlaneSizingSourceAnnotation: sourceTemplateName,
+			},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: clusterv1.GroupVersion.String(),
+				Kind:       "Cluster",
+				Name:       cluster.Name,
+				UID:        cluster.UID,
+			}},
+		},
+		Spec: *vcdMachineTemplate.Spec.DeepCopy(),
+	}
+	sizingTemplate.Spec.Template.Spec.SizingPolicy = sizingPolicy
+	if err = r.Client.Create(ctx, sizingTemplate); err != nil && !apierrors.IsAlreadyExists(err) {
+		conditions.MarkFalse(vcdCluster, ControlPlaneSizedCondition, ControlPlaneResizeFailedReason,
+			clusterv1.ConditionSeverityWarning, "failed to clone VCDMachineTemplate [%s]: %v", vcdMachineTemplate.Name,
+			err)
+		return ctrl.Result{}, fmt.Errorf("failed to create VCDMachineTemplate [%s/%s]: [%v]",
+			sizingTemplate.Namespace, sizingTemplate.Name, err)
+	}
+
+	kcpPatchHelper, err := patch.NewHelper(kcp, r.Client)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to create patch helper for KubeadmControlPlane [%s/%s]: [%v]",
+			kcp.Namespace, kcp.Name, err)
+	}
+	kcp.Spec.MachineTemplate.InfrastructureRef.Name = sizingTemplate.Name
+	if err = kcpPatchHelper.Patch(ctx, kcp); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to point KubeadmControlPlane [%s/%s] at VCDMachineTemplate [%s]: [%v]",
+			kcp.Namespace, kcp.Name, sizingTemplate.Name, err)
+	}
+	log.Info("Resizing the control plane", "sizingPolicy", sizingPolicy, "kcp", kcp.Name,
+		"vcdMachineTemplate", sizingTemplate.Name)
+	conditions.MarkFalse(vcdCluster, ControlPlaneSizedCondition, ControlPlaneResizingReason,
+		clusterv1.ConditionSeverityInfo, "rolling out sizing policy [%s] to the control plane machines", sizingPolicy)
+	return ctrl.Result{RequeueAfter: ControlPlaneSizingRequeueInterval}, nil
+}
+
+// deleteStaleControlPlaneSizingTemplates deletes the VCDMachineTemplates cloned by earlier control plane resizes of the
+// cluster, except the one in use.
+func (r *VCDClusterReconciler) deleteStaleControlPlaneSizingTemplates(ctx context.Context, cluster *clusterv1.Cluster,
+	currentTemplateName string) error {
+
+	templateList := &infrav1beta3.VCDMachineTemplateList{}
+	if err := r.Client.List(ctx, templateList, client.InNamespace(cluster.Namespace),
+		client.MatchingLabels{ControlPlaneSizingLabel: cluster.Name}); err != nil {
+		return fmt.Errorf("failed to list the control plane sizing VCDMachineTemplates of cluster [%s/%s]: [%v]",
+			cluster.Namespace, cluster.Name, err)
+	}
+	for i := range templateList.Items {
+		template := &templateList.Items[i]
+		if template.Name == currentTemplateName {
+			continue
+		}
+		if err := r.Client.Delete(ctx, template); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete VCDMachineTemplate [%s/%s]: [%v]", template.Namespace,
+				template.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// independentDiskBusTypes maps the data disk bus types of a VCDMachine to the VCD bus type and sub-type of an
+// independent disk.
+var independentDiskBusTypes = map[string][2]string{
+	"paravirtual": {"6", "VirtualSCSI"},
+	"sata":        {"20", "vmware.sata.ahci"},
+	"nvme":        {"20", "vmware.nvme.controller"},
+}
+
+// getDataDiskName returns the name of the independent disk of a data disk of a VM.
+func getDataDiskName(vmName string, dataDisk infrav1beta3.DiskSpec) string {
+	return fmt.Sprintf("%s-%s", vmName, dataDisk.Name)
+}
+
+// getDataDisk returns the independent disk named diskName, or nil if there is none. If several disks share the name,
+// the one attached to the VM is returned.
+func getDataDisk(vdc *govcd.Vdc, vm *govcd.VM, diskName string) (*govcd.Disk, error) {
+	disks, err := vdc.GetDisksByName(diskName, true)
+	if err == govcd.ErrorEntityNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get independent disk [%s]: [%v]", diskName, err)
+	}
+	if len(*disks) == 1 {
+		return &(*disks)[0], nil
+	}
+	for idx := range *disks {
+		disk := &(*disks)[idx]
+		attachedVM, err := disk.AttachedVM()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get VM attached to independent disk [%s]: [%v]", diskName, err)
+		}
+		if attachedVM != nil && attachedVM.HREF == vm.VM.HREF {
+			return disk, nil
+		}
+	}
+	return nil, fmt.Errorf("found [%d] independent disks named [%s], none of which is attached to VM [%s]",
+		len(*disks), diskName, vm.VM.Name)
+}
+
+// createDataDisk creates the independent disk of a data disk of a VM.
+func createDataDisk(vdc *govcd.Vdc, diskName string, dataDisk infrav1beta3.DiskSpec,
+	defaultStorageProfile string) (*govcd.Disk, error) {
+
+	size, ok := dataDisk.Size.AsInt64()
+	if !ok {
+		return nil, fmt.Errorf("failed to parse size [%s] of data disk [%s]", dataDisk.Size.String(), dataDisk.Name)
+	}
+	busType := dataDisk.BusType
+	if busType == "" {
+		busType = "paravirtual"
+	}
+	bus, ok := independentDiskBusTypes[busType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported bus type [%s] of data disk [%s]", busType, dataDisk.Name)
+	}
+	diskConfig := &types.Disk{
+		Name:       diskName,
+		SizeMb:     int64(math.Ceil(float64(size) / float64(Mebibyte))),
+		BusType:    bus[0],
+		BusSubType: bus[1],
+	}
+	if dataDisk.Iops != nil {
+		iops := int(*dataDisk.Iops)
+		diskConfig.Iops = &iops
+	}
+	storageProfile := dataDisk.StorageProfile
+	if storageProfile == "" {
+		storageProfile = defaultStorageProfile
+	}
+	if storageProfile != "" {
+		storageProfileRef, err := findStorageProfileReference(vdc, storageProfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find storage profile [%s] of data disk [%s]: [%v]", storageProfile,
+				dataDisk.Name, err)
+		}
+		diskConfig.StorageProfile = &storageProfileRef
+	}
+
+	task, err := vdc.CreateDisk(&types.DiskCreateParams{Disk: diskConfig})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create independent disk [%s]: [%v]", diskName, err)
+	}
+	if err = task.WaitTaskCompletion(); err != nil {
+		return nil, fmt.Errorf("failed to wait for creation of independent disk [%s]: [%v]", diskName, err)
+	}
+	if task.Task.Owner == nil {
+		return nil, fmt.Errorf("no disk found in the creation task of independent disk [%s]", diskName)
+	}
+	disk, err := vdc.GetDiskByHref(task.Task.Owner.HREF)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get created independent disk [%s]: [%v]", diskName, err)
+	}
+	return disk, nil
+}
+
+// reconcileDataDisks creates the independent disks of the data disks of the VM and attaches them to the VM, then
+// verifies their provisioning.
+func reconcileDataDisks(ctx context.Context, vdcManager *vcdsdk.VdcManager, vm *govcd.VM,
+	dataDisks []infrav1beta3.DiskSpec, defaultStorageProfile string) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	disks := make([]*govcd.Disk, len(dataDisks))
+	for idx, dataDisk := range dataDisks {
+		diskName := getDataDiskName(vm.VM.Name, dataDisk)
+		disk, err := getDataDisk(vdcManager.Vdc, vm, diskName)
+		if err != nil {
+			return err
+		}
+		if disk == nil {
+			if disk, err = createDataDisk(vdcManager.Vdc, diskName, dataDisk, defaultStorageProfile); err != nil {
+				return err
+			}
+			log.Info("Created data disk", "disk", diskName)
+		}
+		disks[idx] = disk
+
+		attachedVM, err := disk.AttachedVM()
+		if err != nil {
+			return fmt.Errorf("failed to get VM attached to independent disk [%s]: [%v]", diskName, err)
+		}
+		if attachedVM != nil {
+			if attachedVM.HREF != vm.VM.HREF {
+				return fmt.Errorf("independent disk [%s] is attached to VM [%s] instead of [%s]", diskName,
+					attachedVM.Name, vm.VM.Name)
+			}
+			continue
+		}
+
+		attachParams := &types.DiskAttachOrDetachParams{
+			Disk: &types.Reference{HREF: disk.Disk.HREF},
+		}
+		if dataDisk.BusNumber != nil {
+			busNumber := int(*dataDisk.BusNumber)
+			attachParams.BusNumber = &busNumber
+		}
+		if dataDisk.UnitNumber != nil {
+			unitNumber := int(*dataDisk.UnitNumber)
+			attachParams.UnitNumber = &unitNumber
+		}
+		task, err := vm.AttachDisk(attachParams)
+		if err == nil {
+			err = task.WaitTaskCompletion()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to attach independent disk [%s] to VM [%s]: [%v]", diskName, vm.VM.Name, err)
+		}
+		log.Info("Attached data disk", "disk", diskName, "vm", vm.VM.Name)
+	}
+
+	if !hasDataDiskProvisioningTypes(dataDisks) {
+		return nil
+	}
+	if err := vm.Refresh(); err != nil {
+		return fmt.Errorf("failed to refresh VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	for idx, dataDisk := range dataDisks {
+		if err := verifyDataDiskProvisioning(vm, disks[idx], dataDisk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteDataDisks detaches the independent disks of the data disks of the VM and deletes them, so that the VM can be
+// deleted.
+func deleteDataDisks(ctx context.Context, vdcManager *vcdsdk.VdcManager, vm *govcd.VM,
+	dataDisks []infrav1beta3.DiskSpec) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	for _, dataDisk := range dataDisks {
+		diskName := getDataDiskName(vm.VM.Name, dataDisk)
+		disk, err := getDataDisk(vdcManager.Vdc, vm, diskName)
+		if err != nil {
+			return err
+		}
+		if disk == nil {
+			continue
+		}
+
+		attachedVM, err := disk.AttachedVM()
+		if err != nil {
+			return fmt.Errorf("failed to get VM attached to independent disk [%s]: [%v]", diskName, err)
+		}
+		if attachedVM != nil {
+			if attachedVM.HREF != vm.VM.HREF {
+				return fmt.Errorf("independent disk [%s] is attached to VM [%s] instead of [%s]", diskName,
+					attachedVM.Name, vm.VM.Name)
+			}
+			task, err := vm.DetachDisk(&types.DiskAttachOrDetachParams{
+				Disk: &types.Reference{HREF: disk.Disk.HREF},
+			})
+			if err == nil {
+				err = task.WaitTaskCompletion()
+			}
+			if err != nil {
+				return fmt.Errorf("failed to detach independent disk [%s] from VM [%s]: [%v]", diskName,
+					vm.VM.Name, err)
+			}
+			if err = disk.Refresh(); err != nil {
+				return fmt.Errorf("failed to refresh independent disk [%s]: [%v]", diskName, err)
+			}
+		}
+
+		task, err := disk.Delete()
+		if err == nil {
+			err = task.WaitTaskCompletion()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete independent disk [%s]: [%v]", diskName, err)
+		}
+		log.Info("Deleted data disk", "disk", diskName)
+	}
+
+	if len(dataDisks) > 0 {
+		if err := vm.Refresh(); err != nil {
+			return fmt.Errorf("failed to refresh VM [%s]: [%v]", vm.VM.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+const (
+	// DeletionProtectedAnnotation protects the VCD infrastructure of a cluster from deletion when set on its Cluster or
+	// VCDCluster: the controllers do not delete the VMs, load balancer, vApp or RDE of the cluster being deleted until
+	// the annotation is removed or set to "false".
+	DeletionProtectedAnnotation = infrav1beta3.DeletionProtectedAnnotation
+
+	// VCDMachineDeleteProtectionAnnotation protects the VM of a VCDMachine from deletion when set on the VCDMachine:
+	// the VCDMachine being deleted keeps its VM, and its finalizer, until the annotation is removed or set to "false".
+	VCDMachineDeleteProtectionAnnotation = "vcdmachine.infrastructure.cluster.x-k8s.io/delete-protection"
+
+	// DeletionProtectedRequeueInterval is the interval at which the deletion of a protected cluster is checked again.
+	DeletionProtectedRequeueInterval = 1 * time.Minute
+
+	// DeletionBlockedRequeueInterval is the interval at which the deletion of a VM blocked by its disks or by a
+	// running VCD task is attempted again.
+	DeletionBlockedRequeueInterval = 30 * time.Second
+)
+
+// runningTaskStatuses are the statuses of the VCD tasks which have not completed yet.
+var runningTaskStatuses = []string{"queued", BlockedTaskStatus, "running"}
+
+// isDeletionProtected checks if the cluster is protected from deletion by the annotation of its Cluster or VCDCluster.
+// The Cluster may be nil, e.g. once it is deleted.
+func isDeletionProtected(cluster *clusterv1.Cluster, vcdCluster *infrav1beta3.VCDCluster) bool {
+	return (cluster != nil && infrav1beta3.HasDeletionProtectedAnnotation(cluster)) ||
+		(vcdCluster != nil && infrav1beta3.HasDeletionProtectedAnnotation(vcdCluster))
+}
+
+// isClusterBeingDeleted checks if the Cluster or VCDCluster is being deleted, in which case the machines are deleted
+// along with the cluster rather than by a scale down or a rollout.
+func isClusterBeingDeleted(cluster *clusterv1.Cluster, vcdCluster *infrav1beta3.VCDCluster) bool {
+	return (cluster != nil && !cluster.DeletionTimestamp.IsZero()) ||
+		(vcdCluster != nil && !vcdCluster.DeletionTimestamp.IsZero())
+}
+
+// isMachineDeleteProtected checks if the VM of the machine is protected from deletion by the annotation of its
+// VCDMachine.
+func isMachineDeleteProtected(vcdMachine *infrav1beta3.VCDMachine) bool {
+	value, ok := vcdMachine.Annotations[VCDMachineDeleteProtectionAnnotation]
+	return ok && value != "false"
+}
+
+// markDeletionBlocked sets the DeletionAllowed condition to false and reports if it was newly set or blocked for another
+// reason.
+func markDeletionBlocked(obj conditions.Setter, severity clusterv1.ConditionSeverity, reason string,
+	message string) bool {
+
+	changed := !conditions.IsFalse(obj, DeletionAllowedCondition) ||
+		conditions.GetReason(obj, DeletionAllowedCondition) != reason
+	conditions.MarkFalse(obj, DeletionAllowedCondition, reason, severity, "%s", message)
+	return changed
+}
+
+// markDeletionAllowed sets the DeletionAllowed condition to true.
+func markDeletionAllowed(obj conditions.Setter) {
+	conditions.MarkTrue(obj, DeletionAllowedCondition)
+}
+
+// getRunningTask returns the first task which has not completed yet, or nil if there is none.
+func getRunningTask(tasks *types.TasksInProgress) *types.Task {
+	if tasks == nil {
+		return nil
+	}
+	for _, task := range tasks.Task {
+		if task != nil && strInSlice(task.Status, runningTaskStatuses) {
+			return task
+		}
+	}
+	return nil
+}
+
+// getAttachedIndependentDisks returns the independent disks attached to the VM, e.g. by CSI, which VCD does not
+// delete the VM with.
+func getAttachedIndependentDisks(vm *govcd.VM) []string {
+	var disks []string
+	if vm.VM.VmSpecSection == nil || vm.VM.VmSpecSection.DiskSection == nil {
+		return disks
+	}
+	for _, diskSettings := range vm.VM.VmSpecSection.DiskSection.DiskSettings {
+		if diskSettings.Disk != nil {
+			disks = append(disks, fmt.Sprintf("%s(%s)", diskSettings.Disk.Name, diskSettings.Disk.ID))
+		}
+	}
+	return disks
+}
+
+// getVMDeletionBlocker returns the reason and the message of the DeletionBlocked condition if the VM cannot be deleted
+// yet: a VCD task of the VM or its vApp is running, or independent disks are still attached to it. An empty reason is
+// returned if the VM can be deleted.
+func getVMDeletionBlocker(vApp *govcd.VApp, vm *govcd.VM) (string, string, error) {
+	if err := vApp.Refresh(); err != nil {
+		return "", "", fmt.Errorf("unable to refresh vApp [%s]: [%v]", vApp.VApp.Name, err)
+	}
+	if err := vm.Refresh(); err != nil {
+		return "", "", fmt.Errorf("unable to refresh VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	if task := getRunningTask(vm.VM.Tasks); task != nil {
+		return VMTaskRunningReason, fmt.Sprintf("task [%s] of VM [%s] is [%s]: [%s]", task.ID, vm.VM.Name,
+			task.Status, task.Operation), nil
+	}
+	if task := getRunningTask(vApp.VApp.Tasks); task != nil {
+		return VAppBusyReason, fmt.Sprintf("task [%s] of vApp [%s] is [%s]: [%s]", task.ID, vApp.VApp.Name,
+			task.Status, task.Operation), nil
+	}
+	if disks := getAttachedIndependentDisks(vm); len(disks) > 0 {
+		return IndependentDisksAttachedReason, fmt.Sprintf("independent disks [%s] are attached to VM [%s]; "+
+			"they must be detached, e.g. by CSI once the pods using them are gone", strings.Join(disks, ", "),
+			vm.VM.Name), nil
+	}
+	return "", "", nil
+}
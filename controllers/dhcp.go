@@ -0,0 +1,219 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// dhcpModeRelay is the mode of a DHCP service relaying the requests to the DHCP forwarder of the edge gateway,
+	// which has no pool on the network.
+	dhcpModeRelay = "RELAY"
+
+	// DHCPLeaseRequeueInterval is the interval after which a machine still waiting for its DHCP lease is reconciled
+	// again.
+	DHCPLeaseRequeueInterval = 30 * time.Second
+
+	// dhcpLeaseCloudInit is the cloud-init user data of a VM powered on to lease its address before it is given its
+	// bootstrap data. It cleans the state of cloud-init, so that the bootstrap data is processed as a new instance at
+	// the next boot of the VM.
+	dhcpLeaseCloudInit = "#cloud-config\nruncmd:\n- 'cloud-init clean'\n"
+)
+
+// isDHCPRequired checks if a role of the machines of the cluster gets its address on the OVDC network from DHCP.
+func isDHCPRequired(vcdCluster *infrav1beta3.VCDCluster) bool {
+	return vcdCluster.Spec.IPAllocation.ControlPlane == IPAllocationModeDHCP ||
+		vcdCluster.Spec.IPAllocation.Workers == IPAllocationModeDHCP
+}
+
+// hasDHCPPool checks if the DHCP service of a network leases addresses, from an enabled pool of the network or relayed
+// to the DHCP forwarder of its edge gateway.
+func hasDHCPPool(dhcp *types.OpenApiOrgVdcNetworkDhcp) bool {
+	if dhcp == nil || dhcp.Enabled == nil || !*dhcp.Enabled {
+		return false
+	}
+	if dhcp.Mode == dhcpModeRelay {
+		return true
+	}
+	for _, pool := range dhcp.DhcpPools {
+		if pool.Enabled == nil || *pool.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// getNetworkDHCP returns the OVDC network and the configuration of its DHCP service.
+func getNetworkDHCP(vdc *govcd.Vdc, ovdcNetworkName string) (*govcd.OpenApiOrgVdcNetwork,
+	*types.OpenApiOrgVdcNetworkDhcp, error) {
+
+	ovdcNetwork, err := vdc.GetOpenApiOrgVdcNetworkByName(ovdcNetworkName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get OVDC network [%s]: [%v]", ovdcNetworkName, err)
+	}
+	dhcp, err := ovdcNetwork.GetOpenApiOrgVdcNetworkDhcp()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get the DHCP configuration of OVDC network [%s]: [%v]",
+			ovdcNetworkName, err)
+	}
+	return ovdcNetwork, dhcp.OpenApiOrgVdcNetworkDhcp, nil
+}
+
+// validateNetworkDHCP checks that the DHCP service of the OVDC network leases addresses to the VMs attached to it in
+// DHCP mode.
+func validateNetworkDHCP(vdc *govcd.Vdc, ovdcNetworkName string) error {
+	_, dhcp, err := getNetworkDHCP(vdc, ovdcNetworkName)
+	if err != nil {
+		return err
+	}
+	if !hasDHCPPool(dhcp) {
+		return fmt.Errorf("DHCP is not enabled with a pool on OVDC network [%s]", ovdcNetworkName)
+	}
+	return nil
+}
+
+// reconcileDHCPPool checks that the OVDC network of the cluster leases addresses when a role of its machines gets its
+// address from DHCP, and otherwise enables the DHCP service of the network with VCDClusterSpec.IPAllocation.DHCPPool
+// if it is set and the user has the rights to edit the network. The DHCP service is left as is once it leases
+// addresses.
+func (r *VCDClusterReconciler) reconcileDHCPPool(ctx context.Context, vcdClient *vcdsdk.Client,
+	vcdCluster *infrav1beta3.VCDCluster, userRights *vcdUserRights) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	if !isDHCPRequired(vcdCluster) {
+		return nil
+	}
+	if vcdClient.VDC == nil {
+		return fmt.Errorf("no OVDC found in the VCD client to check the DHCP service of the OVDC network")
+	}
+	ovdcNetworkName := getOvdcNetworkName(vcdCluster)
+	ovdcNetwork, dhcp, err := getNetworkDHCP(vcdClient.VDC, ovdcNetworkName)
+	if err != nil {
+		return err
+	}
+	if hasDHCPPool(dhcp) {
+		return nil
+	}
+
+	ipRange := vcdCluster.Spec.IPAllocation.DHCPPool
+	if ipRange == nil {
+		return fmt.Errorf("DHCP is not enabled with a pool on OVDC network [%s], and no DHCP pool is set to enable "+
+			"it; the machines in DHCP mode get no address", ovdcNetworkName)
+	}
+	if !userRights.isFeatureEnabled(OptionalFeatureNetworkEdit) {
+		return fmt.Errorf("DHCP pool [%s-%s] is not enabled on OVDC network [%s] as the user lacks the rights to "+
+			"edit the network", ipRange.StartAddress, ipRange.EndAddress, ovdcNetworkName)
+	}
+	if !ovdcNetwork.IsRouted() {
+		return fmt.Errorf("DHCP pool [%s-%s] can only be enabled on an OVDC network connected to an edge gateway, "+
+			"which [%s] is not", ipRange.StartAddress, ipRange.EndAddress, ovdcNetworkName)
+	}
+	if len(ovdcNetwork.OpenApiOrgVdcNetwork.Subnets.Values) > 0 {
+		subnet := ovdcNetwork.OpenApiOrgVdcNetwork.Subnets.Values[0]
+		prefix, err := netip.ParsePrefix(fmt.Sprintf("%s/%d", subnet.Gateway, subnet.PrefixLength))
+		if err != nil {
+			return fmt.Errorf("invalid subnet [%s/%d] of OVDC network [%s]: [%v]", subnet.Gateway,
+				subnet.PrefixLength, ovdcNetworkName, err)
+		}
+		startIP, startErr := netip.ParseAddr(ipRange.StartAddress)
+		endIP, endErr := netip.ParseAddr(ipRange.EndAddress)
+		if startErr != nil || endErr != nil || !prefix.Contains(startIP) || !prefix.Contains(endIP) {
+			return fmt.Errorf("DHCP pool [%s-%s] is not in subnet [%s] of OVDC network [%s]", ipRange.StartAddress,
+				ipRange.EndAddress, prefix.Masked().String(), ovdcNetworkName)
+		}
+	}
+
+	enabled := true
+	dhcpConfig := *dhcp
+	dhcpConfig.Enabled = &enabled
+	dhcpConfig.DhcpPools = append(dhcpConfig.DhcpPools, types.OpenApiOrgVdcNetworkDhcpPools{
+		Enabled: &enabled,
+		IPRange: types.OpenApiOrgVdcNetworkDhcpIpRange{
+			StartAddress: ipRange.StartAddress,
+			EndAddress:   ipRange.EndAddress,
+		},
+	})
+	if _, err = vcdClient.VDC.UpdateOpenApiOrgVdcNetworkDhcp(ovdcNetwork.OpenApiOrgVdcNetwork.ID,
+		&dhcpConfig); err != nil {
+		return fmt.Errorf("failed to enable DHCP pool [%s-%s] on OVDC network [%s]: [%v]", ipRange.StartAddress,
+			ipRange.EndAddress, ovdcNetworkName, err)
+	}
+	log.Info("Enabled the DHCP pool of the OVDC network", "ovdcNetwork", ovdcNetworkName,
+		"startAddress", ipRange.StartAddress, "endAddress", ipRange.EndAddress)
+	r.recordEvent(vcdCluster, corev1.EventTypeNormal, DHCPPoolEnabledReason,
+		fmt.Sprintf("DHCP of OVDC network [%s] enabled with pool [%s-%s]", ovdcNetworkName, ipRange.StartAddress,
+			ipRange.EndAddress))
+	return nil
+}
+
+// getDHCPLease returns the address leased by DHCP to the primary NIC of a powered on VM, as reported by its guest, or
+// an empty address if none is reported yet. The machine is requeued rather than waited for, so that the workers of the
+// controller are not held by the VMs waiting for their lease.
+func getDHCPLease(ctx context.Context, vm *govcd.VM) (string, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	if err := vm.Refresh(); err != nil {
+		return "", errors.Wrapf(err, "unable to refresh vm [%s]", vm.VM.Name)
+	}
+	if vm.VM.NetworkConnectionSection != nil {
+		if primaryNetwork := getPrimaryNetwork(vm.VM); primaryNetwork != nil && primaryNetwork.IPAddress != "" {
+			log.Info("Obtained the address leased to the VM by DHCP", "address", primaryNetwork.IPAddress)
+			return primaryNetwork.IPAddress, nil
+		}
+	}
+	return "", nil
+}
+
+// powerOnVMForDHCPLease powers on the VM with the user data of dhcpLeaseCloudInit instead of its bootstrap data, so that
+// its guest leases its address by DHCP, unless it is already powered on.
+func powerOnVMForDHCPLease(vdcManager *vcdsdk.VdcManager, vm *govcd.VM) error {
+	vmStatus, err := vm.GetStatus()
+	if err != nil {
+		return fmt.Errorf("failed to get the status of VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	if vmStatus == "POWERED_ON" {
+		return nil
+	}
+	for key, val := range getCloudInitGuestInfo([]byte(dhcpLeaseCloudInit)) {
+		if err = vdcManager.SetVmExtraConfigKeyValue(vm, key, val, true); err != nil {
+			return fmt.Errorf("failed to set the extra config key [%s] of VM [%s]: [%v]", key, vm.VM.Name, err)
+		}
+	}
+	task, err := vm.PowerOn()
+	if err == nil {
+		err = task.WaitTaskCompletion()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to power on VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	if err = vm.Refresh(); err != nil {
+		return fmt.Errorf("failed to refresh VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	return nil
+}
+
+// isPoweredOnForDHCPLease checks if the VM was powered on by powerOnVMForDHCPLease, i.e. without its bootstrap data.
+func isPoweredOnForDHCPLease(vdcManager *vcdsdk.VdcManager, vm *govcd.VM) (bool, error) {
+	userData, err := vdcManager.GetExtraConfigValue(vm, "guestinfo.userdata")
+	if err != nil {
+		return false, fmt.Errorf("unable to get extra config value for key [guestinfo.userdata] for vm [%s]: [%v]",
+			vm.VM.Name, err)
+	}
+	return userData == getCloudInitGuestInfo([]byte(dhcpLeaseCloudInit))["guestinfo.userdata"], nil
+}
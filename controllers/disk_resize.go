@@ -0,0 +1,113 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// getClonedFromVCDMachineTemplate returns the VCDMachineTemplate the VCDMachine was cloned from, or nil if the
+// VCDMachine was not cloned from a VCDMachineTemplate or the template was deleted.
+func getClonedFromVCDMachineTemplate(ctx context.Context, cli client.Client,
+	vcdMachine *infrav1beta3.VCDMachine) (*infrav1beta3.VCDMachineTemplate, error) {
+
+	templateName, ok := vcdMachine.Annotations[clusterv1.TemplateClonedFromNameAnnotation]
+	if !ok || templateName == "" {
+		return nil, nil
+	}
+	groupKind := schema.ParseGroupKind(vcdMachine.Annotations[clusterv1.TemplateClonedFromGroupKindAnnotation])
+	if groupKind.Group != infrav1beta3.GroupVersion.Group || groupKind.Kind != "VCDMachineTemplate" {
+		return nil, nil
+	}
+
+	vcdMachineTemplate := &infrav1beta3.VCDMachineTemplate{}
+	err := cli.Get(ctx, client.ObjectKey{Namespace: vcdMachine.Namespace, Name: templateName}, vcdMachineTemplate)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get VCDMachineTemplate [%s] of VCDMachine [%s]: [%v]", templateName,
+			vcdMachine.Name, err)
+	}
+	return vcdMachineTemplate, nil
+}
+
+// reconcileBootDiskResize grows the boot disk of the VM of a provisioned machine when the disk size of the VCDMachine,
+// or of the VCDMachineTemplate it was cloned from, was increased. The larger disk size of the template is copied to the
+// VCDMachine. The partition and file system of the root disk are grown by the guest once the disk is resized. The size
+// of the boot disk is recorded in the status of the VCDMachine so that VCD is only queried when the disk size changes.
+func (r *VCDMachineReconciler) reconcileBootDiskResize(ctx context.Context, vcdClient *vcdsdk.Client,
+	machine *clusterv1.Machine, vcdMachine *infrav1beta3.VCDMachine, vcdCluster *infrav1beta3.VCDCluster) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	vcdMachineTemplate, err := getClonedFromVCDMachineTemplate(ctx, r.Client, vcdMachine)
+	if err != nil {
+		return err
+	}
+	if vcdMachineTemplate != nil &&
+		vcdMachineTemplate.Spec.Template.Spec.DiskSize.Cmp(vcdMachine.Spec.DiskSize) > 0 {
+		log.Info("Growing the disk size of the machine to the disk size of its template",
+			"template", vcdMachineTemplate.Name, "diskSize", vcdMachineTemplate.Spec.Template.Spec.DiskSize.String())
+		vcdMachine.Spec.DiskSize = vcdMachineTemplate.Spec.Template.Spec.DiskSize.DeepCopy()
+	}
+	if vcdMachine.Spec.DiskSize.IsZero() || vcdMachine.Spec.DiskSize.Cmp(vcdMachine.Status.DiskSize) <= 0 {
+		return nil
+	}
+
+	if err = verifyVAppOwnershipClaim(ctx, r.Client, vcdClient, vcdCluster); err != nil {
+		return err
+	}
+	diskSize, ok := vcdMachine.Spec.DiskSize.AsInt64()
+	if !ok {
+		return fmt.Errorf("failed to parse disk size quantity [%s] of machine [%s]", vcdMachine.Spec.DiskSize.String(),
+			machine.Name)
+	}
+	// go-vcd expects value in MB (2^10 = 1024 * 1024 bytes), so we scale it as such
+	diskSizeMb := int64(math.Floor(float64(diskSize) / float64(Mebibyte)))
+
+	vAppName := getMachineVAppName(vcdMachine, vcdCluster)
+	vApp, err := vcdClient.VDC.GetVAppByName(vAppName, true)
+	if err != nil {
+		return fmt.Errorf("failed to get vApp [%s]: [%v]", vAppName, err)
+	}
+	vmID := getVMIDFromProviderID(vcdMachine.Status.ProviderID)
+	vm, err := vApp.GetVMById(vmID, true)
+	if err != nil {
+		return fmt.Errorf("failed to get VM [%s] of machine [%s]: [%v]", vmID, machine.Name, err)
+	}
+	if vm.VM.VmSpecSection == nil || vm.VM.VmSpecSection.DiskSection == nil ||
+		len(vm.VM.VmSpecSection.DiskSection.DiskSettings) == 0 {
+		return fmt.Errorf("no boot disk found on VM [%s]", vm.VM.Name)
+	}
+
+	diskSettings := vm.VM.VmSpecSection.DiskSection.DiskSettings
+	// VCD does not shrink disks: a smaller disk size is ignored as it is when the VM is created
+	if diskSettings[0].SizeMb < diskSizeMb {
+		log.Info("Resizing the boot disk of a provisioned machine", "vm", vm.VM.Name,
+			"fromMb", diskSettings[0].SizeMb, "toMb", diskSizeMb)
+		diskSettings[0].SizeMb = diskSizeMb
+		if _, err = vm.UpdateInternalDisks(vm.VM.VmSpecSection); err != nil {
+			capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineError, "", machine.Name, fmt.Sprintf("%v", err))
+			return fmt.Errorf("failed to resize the boot disk of VM [%s] to [%dMB]: [%v]", vm.VM.Name, diskSizeMb, err)
+		}
+	}
+	vcdMachine.Status.DiskSize = *resource.NewQuantity(diskSettings[0].SizeMb*Mebibyte, resource.BinarySI)
+	return nil
+}
@@ -0,0 +1,130 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+// Provisioning types of the disks of a VCDMachine
+const (
+	DiskProvisioningThin        = "Thin"
+	DiskProvisioningThick       = "Thick"
+	DiskProvisioningEagerZeroed = "EagerZeroed"
+)
+
+// isThinProvisioning checks if disks of the provisioning type are thin provisioned. VCD only distinguishes thin and
+// thick disks; the zeroing of thick disks is set by the vSphere storage policy of their storage profile.
+func isThinProvisioning(provisioningType string) bool {
+	return provisioningType == DiskProvisioningThin
+}
+
+// getDiskProvisioningName returns the provisioning VCD reports for a disk, for the messages of the controller.
+func getDiskProvisioningName(thinProvisioned bool) string {
+	if thinProvisioned {
+		return DiskProvisioningThin
+	}
+	return DiskProvisioningThick
+}
+
+// reconcileBootDiskSettings sets the provisioning and IOPS limit of the boot disk of the VM. They can only be changed
+// while the VM is powered off, i.e. before the VM is bootstrapped.
+func reconcileBootDiskSettings(vm *govcd.VM, provisioningType string, iops *int64) error {
+	if provisioningType == "" && iops == nil {
+		return nil
+	}
+	if vm.VM.VmSpecSection == nil || vm.VM.VmSpecSection.DiskSection == nil ||
+		len(vm.VM.VmSpecSection.DiskSection.DiskSettings) == 0 {
+		return fmt.Errorf("no disks found on VM [%s]", vm.VM.Name)
+	}
+	bootDisk := vm.VM.VmSpecSection.DiskSection.DiskSettings[0]
+	thinProvisioned := isThinProvisioning(provisioningType)
+	provisioningChanged := provisioningType != "" &&
+		(bootDisk.ThinProvisioned == nil || *bootDisk.ThinProvisioned != thinProvisioned)
+	iopsChanged := iops != nil && (bootDisk.Iops == nil || *bootDisk.Iops != *iops)
+	if !provisioningChanged && !iopsChanged {
+		return nil
+	}
+
+	vmStatus, err := vm.GetStatus()
+	if err != nil {
+		return fmt.Errorf("failed to get status of VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	if vmStatus != "POWERED_OFF" {
+		return fmt.Errorf("provisioning or IOPS of the boot disk of VM [%s] differ from the VCDMachine and the VM is in "+
+			"state [%s]", vm.VM.Name, vmStatus)
+	}
+
+	if provisioningChanged {
+		bootDisk.ThinProvisioned = &thinProvisioned
+	}
+	if iopsChanged {
+		bootDiskIops := *iops
+		bootDisk.Iops = &bootDiskIops
+	}
+	vmSpecSection, err := vm.UpdateInternalDisks(vm.VM.VmSpecSection)
+	if err != nil {
+		return fmt.Errorf("failed to update the provisioning and IOPS of the boot disk of VM [%s]: [%v]",
+			vm.VM.Name, err)
+	}
+	// VCD ignores a provisioning it cannot apply to the disk, e.g. on a storage profile which only allows thin disks
+	if provisioningChanged && vmSpecSection != nil && vmSpecSection.DiskSection != nil &&
+		len(vmSpecSection.DiskSection.DiskSettings) != 0 {
+		updatedThinProvisioned := vmSpecSection.DiskSection.DiskSettings[0].ThinProvisioned
+		if updatedThinProvisioned != nil && *updatedThinProvisioned != thinProvisioned {
+			return fmt.Errorf("boot disk of VM [%s] is still provisioned [%s] instead of [%s]", vm.VM.Name,
+				getDiskProvisioningName(*updatedThinProvisioned), provisioningType)
+		}
+	}
+	return nil
+}
+
+// hasDataDiskProvisioningTypes checks if the provisioning of one of the data disks is set.
+func hasDataDiskProvisioningTypes(dataDisks []infrav1beta3.DiskSpec) bool {
+	for _, dataDisk := range dataDisks {
+		if dataDisk.ProvisioningType != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// getAttachedDiskSettings returns the settings of the independent disk in the disks of the VM, or nil if the disk is
+// not attached to the VM.
+func getAttachedDiskSettings(vm *govcd.VM, disk *govcd.Disk) *types.DiskSettings {
+	if vm.VM.VmSpecSection == nil || vm.VM.VmSpecSection.DiskSection == nil {
+		return nil
+	}
+	for _, diskSettings := range vm.VM.VmSpecSection.DiskSection.DiskSettings {
+		if diskSettings.Disk != nil && diskSettings.Disk.HREF == disk.Disk.HREF {
+			return diskSettings
+		}
+	}
+	return nil
+}
+
+// verifyDataDiskProvisioning checks that the independent disk of the data disk attached to the VM is provisioned as
+// set in the data disk. VCD provisions independent disks as set by their OVDC, so the provisioning of a data disk
+// cannot be changed by CAPVCD.
+func verifyDataDiskProvisioning(vm *govcd.VM, disk *govcd.Disk, dataDisk infrav1beta3.DiskSpec) error {
+	if dataDisk.ProvisioningType == "" {
+		return nil
+	}
+	diskSettings := getAttachedDiskSettings(vm, disk)
+	if diskSettings == nil || diskSettings.ThinProvisioned == nil {
+		return nil
+	}
+	if *diskSettings.ThinProvisioned != isThinProvisioning(dataDisk.ProvisioningType) {
+		return fmt.Errorf("independent disk [%s] of data disk [%s] is provisioned [%s] by the OVDC instead of [%s]",
+			disk.Disk.Name, dataDisk.Name, getDiskProvisioningName(*diskSettings.ThinProvisioned),
+			dataDisk.ProvisioningType)
+	}
+	return nil
+}
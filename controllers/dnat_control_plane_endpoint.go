@@ -0,0 +1,195 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// isControlPlaneEndpointDNAT checks if the control plane endpoint of the cluster is a DNAT rule of the edge gateway
+// instead of an NSX-T ALB virtual service.
+func isControlPlaneEndpointDNAT(vcdCluster *infrav1beta3.VCDCluster) bool {
+	return vcdCluster.Spec.ControlPlaneEndpointMode == ControlPlaneEndpointModeDNAT
+}
+
+// getControlPlaneDNATRuleName returns the name of the DNAT rule of the control plane endpoint of the cluster, which is
+// the name of the DNAT rule of a one-arm virtual service of the cluster.
+func getControlPlaneDNATRuleName(vcdCluster *infrav1beta3.VCDCluster) string {
+	return vcdsdk.GetDNATRuleName(capisdk.GetVirtualServiceNameUsingPrefix(
+		capisdk.GetVirtualServiceNamePrefix(vcdCluster.Name, vcdCluster.Status.InfraId), "tcp"))
+}
+
+// getControlPlaneMachineIPs returns the sorted internal IPs of the control plane VCDMachines of the cluster which are
+// not being deleted.
+func getControlPlaneMachineIPs(ctx context.Context, cl client.Client, namespace string,
+	clusterName string) ([]string, error) {
+
+	vcdMachineList := &infrav1beta3.VCDMachineList{}
+	if err := cl.List(ctx, vcdMachineList, client.InNamespace(namespace), client.MatchingLabels{
+		clusterv1.ClusterNameLabel: clusterName,
+	}, client.HasLabels{clusterv1.MachineControlPlaneLabel}); err != nil {
+		return nil, fmt.Errorf("failed to list the control plane VCDMachines of cluster [%s/%s]: [%v]", namespace,
+			clusterName, err)
+	}
+	controlPlaneIPs := make([]string, 0, len(vcdMachineList.Items))
+	for _, vcdMachine := range vcdMachineList.Items {
+		if !vcdMachine.DeletionTimestamp.IsZero() {
+			continue
+		}
+		for _, address := range vcdMachine.Status.Addresses {
+			if address.Type == clusterv1.MachineInternalIP && address.Address != "" {
+				controlPlaneIPs = append(controlPlaneIPs, address.Address)
+				break
+			}
+		}
+	}
+	sort.Strings(controlPlaneIPs)
+	return controlPlaneIPs, nil
+}
+
+// reconcileControlPlaneDNATRule ensures that the DNAT rule of the control plane endpoint forwards to one of the
+// control plane IPs. A DNAT rule forwards to a single internal IP, so the rule keeps forwarding to its current control
+// plane machine as long as it is in the list, and otherwise is moved to the first control plane IP. The rule is left
+// untouched while the list is empty.
+func reconcileControlPlaneDNATRule(ctx context.Context, gateway *vcdsdk.GatewayManager,
+	vcdCluster *infrav1beta3.VCDCluster, controlPlaneIPs []string) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	if len(controlPlaneIPs) == 0 {
+		return nil
+	}
+	externalIP := vcdCluster.Spec.ControlPlaneEndpoint.Host
+	port := int32(vcdCluster.Spec.ControlPlaneEndpoint.Port)
+	dnatRuleName := getControlPlaneDNATRuleName(vcdCluster)
+	dnatRuleRef, err := gateway.GetNATRuleRef(ctx, dnatRuleName)
+	if err != nil {
+		return fmt.Errorf("unable to get dnat rule [%s]: [%v]", dnatRuleName, err)
+	}
+
+	if dnatRuleRef != nil && dnatRuleRef.ExternalIP == externalIP && dnatRuleRef.ExternalPort == int(port) {
+		for _, controlPlaneIP := range controlPlaneIPs {
+			if dnatRuleRef.InternalIP == controlPlaneIP {
+				return nil
+			}
+		}
+	}
+
+	internalIP := controlPlaneIPs[0]
+	if dnatRuleRef == nil {
+		appPortProfileName := vcdsdk.GetAppPortProfileName(dnatRuleName)
+		appPortProfile, err := gateway.CreateAppPortProfile(appPortProfileName, port)
+		if err != nil {
+			return fmt.Errorf("unable to create app port profile [%s]: [%v]", appPortProfileName, err)
+		}
+		if err = gateway.CreateDNATRule(ctx, dnatRuleName, externalIP, internalIP, port, port,
+			appPortProfile); err != nil {
+			return fmt.Errorf("unable to create dnat rule [%s]: [%v]", dnatRuleName, err)
+		}
+		log.Info("Created the DNAT rule of the control plane endpoint", "dnatRule", dnatRuleName,
+			"externalIP", externalIP, "internalIP", internalIP)
+		return nil
+	}
+	if _, err = gateway.UpdateDNATRule(ctx, dnatRuleName, externalIP, internalIP, port); err != nil {
+		return fmt.Errorf("unable to update dnat rule [%s]: [%v]", dnatRuleName, err)
+	}
+	log.Info("Moved the DNAT rule of the control plane endpoint to another control plane machine",
+		"dnatRule", dnatRuleName, "externalIP", externalIP, "previousInternalIP", dnatRuleRef.InternalIP,
+		"internalIP", internalIP)
+	return nil
+}
+
+// reconcileDNATLoadBalancer exposes the control plane endpoint through a DNAT rule of the edge gateway, for edge
+// gateways without an ALB service engine group. The external IP is the one set in the spec, or else an unused IP of
+// the edge gateway in the VIP subnet. The rule is created by the VCDMachine controller once the first control plane
+// machine has an IP, and is moved to another control plane machine by both controllers when its target goes away.
+func (r *VCDClusterReconciler) reconcileDNATLoadBalancer(ctx context.Context, vcdCluster *infrav1beta3.VCDCluster,
+	vcdClient *vcdsdk.Client, skipRDEEventUpdates bool) (ctrl.Result, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+	capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
+
+	gateway, err := vcdsdk.NewGatewayManager(ctx, vcdClient, getOvdcNetworkName(vcdCluster),
+		vcdCluster.Spec.LoadBalancerConfigSpec.VipSubnet, getOvdcName(vcdCluster))
+	if err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDClusterError, "", vcdCluster.Name,
+			fmt.Sprintf("failed to create new gateway manager: [%v]", err))
+		return ctrl.Result{}, fmt.Errorf("failed to create gateway manager to reconcile the DNAT rule of cluster [%s]: [%v]",
+			vcdCluster.Name, err)
+	}
+
+	if vcdCluster.Spec.ControlPlaneEndpoint.Port == 0 {
+		vcdCluster.Spec.ControlPlaneEndpoint.Port = TcpPort
+	}
+	dnatRuleName := getControlPlaneDNATRuleName(vcdCluster)
+	if vcdCluster.Spec.ControlPlaneEndpoint.Host == "" {
+		dnatRuleRef, err := gateway.GetNATRuleRef(ctx, dnatRuleName)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("unable to get dnat rule [%s]: [%v]", dnatRuleName, err)
+		}
+		externalIP := ""
+		if dnatRuleRef != nil {
+			externalIP = dnatRuleRef.ExternalIP
+		} else {
+			externalIP, err = gateway.GetUnusedExternalIPAddress(ctx, vcdCluster.Spec.LoadBalancerConfigSpec.VipSubnet)
+			if err != nil {
+				capvcdRdeManager.AddToErrorSet(ctx, capisdk.LoadBalancerError, "", "",
+					fmt.Sprintf("failed to get an external IP for the DNAT rule of cluster [%s]: [%v]",
+						vcdCluster.Name, err))
+				return ctrl.Result{}, fmt.Errorf("unable to get an unused external IP for dnat rule [%s]: [%v]",
+					dnatRuleName, err)
+			}
+		}
+		vcdCluster.Spec.ControlPlaneEndpoint.Host = externalIP
+	}
+
+	// the app port profile is created upfront so that it is recorded in the resource map of the cluster
+	appPortProfileName := vcdsdk.GetAppPortProfileName(dnatRuleName)
+	appPortProfile, err := gateway.CreateAppPortProfile(appPortProfileName,
+		int32(vcdCluster.Spec.ControlPlaneEndpoint.Port))
+	if err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.LoadBalancerError, "", "", fmt.Sprintf("%v", err))
+		return ctrl.Result{}, fmt.Errorf("unable to create app port profile [%s]: [%v]", appPortProfileName, err)
+	}
+	if err = updateVdcResourceToVcdCluster(vcdCluster, ResourceTypeAppPortProfile,
+		appPortProfile.NsxtAppPortProfile.ID, appPortProfile.NsxtAppPortProfile.Name); err != nil {
+		log.Error(err, "failed to record the app port profile of the DNAT rule", "appPortProfile", appPortProfileName)
+	}
+
+	cluster, err := util.GetOwnerCluster(ctx, r.Client, vcdCluster.ObjectMeta)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get the owner cluster of [%s]: [%v]", vcdCluster.Name, err)
+	}
+	if cluster != nil {
+		controlPlaneIPs, err := getControlPlaneMachineIPs(ctx, r.Client, vcdCluster.Namespace, cluster.Name)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if err = reconcileControlPlaneDNATRule(ctx, gateway, vcdCluster, controlPlaneIPs); err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.LoadBalancerError, "", "", fmt.Sprintf("%v", err))
+			return ctrl.Result{}, err
+		}
+	}
+	log.Info(fmt.Sprintf("Control plane endpoint for the cluster is the DNAT rule [%s] of [%s]", dnatRuleName,
+		vcdCluster.Spec.ControlPlaneEndpoint.Host))
+
+	if err = capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD,
+		capisdk.LoadBalancerError, "", ""); err != nil {
+		log.Error(err, "failed to remove LoadBalancerError from RDE", "rdeID", vcdCluster.Status.InfraId)
+	}
+	capvcdRdeManager.AddToEventSet(ctx, capisdk.LoadBalancerAvailable, dnatRuleName, "", "", skipRDEEventUpdates)
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,283 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Purposes of the destinations of the egress allowlist of a cluster
+const (
+	EgressPurposeVCD            = "vcd"
+	EgressPurposeProxy          = "proxy"
+	EgressPurposeRegistryMirror = "registry-mirror"
+	EgressPurposeNTP            = "ntp"
+	EgressPurposeOSRepository   = "os-repository"
+)
+
+const (
+	egressProtocolTCP = "TCP"
+	egressProtocolUDP = "UDP"
+
+	firewallRuleActionAllow     = "ALLOW"
+	firewallRuleDirectionOut    = "OUT"
+	firewallRuleIpProtocolAnyIP = "IPV4_IPV6"
+)
+
+// parseEgressDestination parses an endpoint given as a host, host:port or URL into a destination. The port of the URL
+// scheme, or defaultPort, is used if the endpoint has no port.
+func parseEgressDestination(endpoint string, defaultPort int32, protocol string,
+	purpose string) (infrav1beta3.EgressDestination, error) {
+
+	destination := infrav1beta3.EgressDestination{Port: defaultPort, Protocol: protocol, Purpose: purpose}
+	hostPort := endpoint
+	if strings.Contains(endpoint, "://") {
+		endpointURL, err := url.Parse(endpoint)
+		if err != nil {
+			return destination, fmt.Errorf("invalid URL [%s]: [%v]", endpoint, err)
+		}
+		switch endpointURL.Scheme {
+		case "http":
+			destination.Port = 80
+		case "https":
+			destination.Port = 443
+		}
+		hostPort = endpointURL.Host
+	}
+
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		// no port
+		destination.Host = strings.Trim(hostPort, "[]")
+	} else {
+		portNumber, err := strconv.ParseInt(port, 10, 32)
+		if err != nil {
+			return destination, fmt.Errorf("invalid port [%s] of endpoint [%s]: [%v]", port, endpoint, err)
+		}
+		destination.Host = host
+		destination.Port = int32(portNumber)
+	}
+	if destination.Host == "" {
+		return destination, fmt.Errorf("no host found in endpoint [%s]", endpoint)
+	}
+	return destination, nil
+}
+
+// getEgressAllowlist returns the destinations the nodes of the cluster must reach: the VCD endpoint, the proxies and
+// the destinations of the egress allowlist configuration of the cluster.
+func getEgressAllowlist(vcdCluster *infrav1beta3.VCDCluster) ([]infrav1beta3.EgressDestination, error) {
+	type endpointSpec struct {
+		endpoints   []string
+		defaultPort int32
+		protocol    string
+		purpose     string
+	}
+	proxies := make([]string, 0)
+	for _, proxy := range []string{vcdCluster.Spec.ProxyConfigSpec.HTTPProxy,
+		vcdCluster.Spec.ProxyConfigSpec.HTTPSProxy} {
+		if proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	allowlistConfig := vcdCluster.Spec.EgressAllowlist
+	endpointSpecs := []endpointSpec{
+		{[]string{vcdCluster.Spec.Site}, 443, egressProtocolTCP, EgressPurposeVCD},
+		{proxies, 3128, egressProtocolTCP, EgressPurposeProxy},
+		{allowlistConfig.RegistryMirrors, 443, egressProtocolTCP, EgressPurposeRegistryMirror},
+		{allowlistConfig.NTPServers, 123, egressProtocolUDP, EgressPurposeNTP},
+		{allowlistConfig.OSRepositories, 443, egressProtocolTCP, EgressPurposeOSRepository},
+	}
+
+	allowlist := make([]infrav1beta3.EgressDestination, 0)
+	seen := make(map[infrav1beta3.EgressDestination]bool)
+	for _, spec := range endpointSpecs {
+		for _, endpoint := range spec.endpoints {
+			destination, err := parseEgressDestination(endpoint, spec.defaultPort, spec.protocol, spec.purpose)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s destination: [%v]", spec.purpose, err)
+			}
+			if seen[destination] {
+				continue
+			}
+			seen[destination] = true
+			allowlist = append(allowlist, destination)
+		}
+	}
+	return allowlist, nil
+}
+
+// getEgressAllowlistName returns the name of the IP set and of the firewall rule allowing the egress allowlist of the
+// cluster on the edge gateway.
+func getEgressAllowlistName(vcdCluster *infrav1beta3.VCDCluster) string {
+	return capisdk.GetVirtualServiceNamePrefix(vcdCluster.Name, vcdCluster.Status.InfraId) + "-egress-allowlist"
+}
+
+// resolveEgressDestinations returns the sorted IP addresses of the destinations.
+func resolveEgressDestinations(destinations []infrav1beta3.EgressDestination) ([]string, error) {
+	ipSet := make(map[string]bool)
+	for _, destination := range destinations {
+		if net.ParseIP(destination.Host) != nil {
+			ipSet[destination.Host] = true
+			continue
+		}
+		ips, err := net.LookupHost(destination.Host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve host [%s]: [%v]", destination.Host, err)
+		}
+		for _, ip := range ips {
+			ipSet[ip] = true
+		}
+	}
+	ips := make([]string, 0, len(ipSet))
+	for ip := range ipSet {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips, nil
+}
+
+// reconcileEgressFirewall allows the traffic to the egress allowlist of the cluster on the gateway firewall of the
+// edge gateway of the cluster network, with an IP set of the resolved destinations and a firewall rule placed before
+// the other user defined rules.
+func reconcileEgressFirewall(ctx context.Context, vcdClient *vcdsdk.Client, vcdCluster *infrav1beta3.VCDCluster,
+	destinations []infrav1beta3.EgressDestination) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	_, edgeGateway, err := getEdgeGatewayOfNetwork(vcdClient, getOvdcNetworkName(vcdCluster))
+	if err != nil {
+		return err
+	}
+	if edgeGateway == nil {
+		return fmt.Errorf("OVDC network [%s] is not connected to an edge gateway to program the egress allowlist on",
+			getOvdcNetworkName(vcdCluster))
+	}
+	ips, err := resolveEgressDestinations(destinations)
+	if err != nil {
+		return err
+	}
+
+	name := getEgressAllowlistName(vcdCluster)
+	ipSet, err := edgeGateway.GetNsxtFirewallGroupByName(name, types.FirewallGroupTypeIpSet)
+	switch {
+	case govcd.ContainsNotFound(err):
+		ipSet, err = edgeGateway.CreateNsxtFirewallGroup(&types.NsxtFirewallGroup{
+			Name:        name,
+			Description: fmt.Sprintf("Egress allowlist of cluster [%s]", vcdCluster.Name),
+			IpAddresses: ips,
+			OwnerRef:    &types.OpenApiReference{ID: edgeGateway.EdgeGateway.ID},
+			TypeValue:   types.FirewallGroupTypeIpSet,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create IP set [%s]: [%v]", name, err)
+		}
+		log.Info("Created IP set of the egress allowlist", "ipSet", name)
+	case err != nil:
+		return fmt.Errorf("failed to get IP set [%s]: [%v]", name, err)
+	case !reflect.DeepEqual(ipSet.NsxtFirewallGroup.IpAddresses, ips):
+		ipSetConfig := *ipSet.NsxtFirewallGroup
+		ipSetConfig.IpAddresses = ips
+		if ipSet, err = ipSet.Update(&ipSetConfig); err != nil {
+			return fmt.Errorf("failed to update IP set [%s]: [%v]", name, err)
+		}
+		log.Info("Updated IP set of the egress allowlist", "ipSet", name)
+	}
+
+	lock := getEdgeGatewayFirewallLock(getEdgeGatewayFirewallLockKey(vcdCluster, edgeGateway))
+	lock.Lock()
+	defer lock.Unlock()
+	firewall, err := edgeGateway.GetNsxtFirewall()
+	if err != nil {
+		return fmt.Errorf("failed to get firewall rules of edge gateway [%s]: [%v]", edgeGateway.EdgeGateway.Name, err)
+	}
+	for _, rule := range firewall.NsxtFirewallRuleContainer.UserDefinedRules {
+		if rule.Name == name {
+			return nil
+		}
+	}
+	rules := append([]*types.NsxtFirewallRule{{
+		Name:                      name,
+		Action:                    firewallRuleActionAllow,
+		Enabled:                   true,
+		DestinationFirewallGroups: []types.OpenApiReference{{ID: ipSet.NsxtFirewallGroup.ID}},
+		IpProtocol:                firewallRuleIpProtocolAnyIP,
+		Direction:                 firewallRuleDirectionOut,
+	}}, firewall.NsxtFirewallRuleContainer.UserDefinedRules...)
+	if _, err = edgeGateway.UpdateNsxtFirewall(&types.NsxtFirewallRuleContainer{UserDefinedRules: rules}); err != nil {
+		return fmt.Errorf("failed to add firewall rule [%s] to edge gateway [%s]: [%v]", name,
+			edgeGateway.EdgeGateway.Name, err)
+	}
+	log.Info("Added firewall rule of the egress allowlist", "rule", name)
+	return nil
+}
+
+// deleteEgressFirewall removes the firewall rule and the IP set of the egress allowlist of the cluster from the edge
+// gateway, if present.
+func deleteEgressFirewall(ctx context.Context, vcdClient *vcdsdk.Client, vcdCluster *infrav1beta3.VCDCluster) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	_, edgeGateway, err := getEdgeGatewayOfNetwork(vcdClient, getOvdcNetworkName(vcdCluster))
+	if err != nil {
+		return err
+	}
+	if edgeGateway == nil {
+		return nil
+	}
+
+	name := getEgressAllowlistName(vcdCluster)
+	lock := getEdgeGatewayFirewallLock(getEdgeGatewayFirewallLockKey(vcdCluster, edgeGateway))
+	lock.Lock()
+	defer lock.Unlock()
+	firewall, err := edgeGateway.GetNsxtFirewall()
+	if err != nil {
+		return fmt.Errorf("failed to get firewall rules of edge gateway [%s]: [%v]", edgeGateway.EdgeGateway.Name, err)
+	}
+	rules := make([]*types.NsxtFirewallRule, 0, len(firewall.NsxtFirewallRuleContainer.UserDefinedRules))
+	for _, rule := range firewall.NsxtFirewallRuleContainer.UserDefinedRules {
+		if rule.Name != name {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) != len(firewall.NsxtFirewallRuleContainer.UserDefinedRules) {
+		if len(rules) == 0 {
+			err = firewall.DeleteAllRules()
+		} else {
+			_, err = edgeGateway.UpdateNsxtFirewall(&types.NsxtFirewallRuleContainer{UserDefinedRules: rules})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to remove firewall rule [%s] from edge gateway [%s]: [%v]", name,
+				edgeGateway.EdgeGateway.Name, err)
+		}
+		log.Info("Removed firewall rule of the egress allowlist", "rule", name)
+	}
+
+	ipSet, err := edgeGateway.GetNsxtFirewallGroupByName(name, types.FirewallGroupTypeIpSet)
+	if govcd.ContainsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get IP set [%s]: [%v]", name, err)
+	}
+	if err = ipSet.Delete(); err != nil {
+		return fmt.Errorf("failed to delete IP set [%s]: [%v]", name, err)
+	}
+	log.Info("Deleted IP set of the egress allowlist", "ipSet", name)
+	return nil
+}
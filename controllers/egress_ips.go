@@ -0,0 +1,127 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+const natRuleTypeSNAT = "SNAT"
+
+// parseNATAddress parses an address of a NAT rule, which is a single IP or a network CIDR, into a network.
+func parseNATAddress(address string) (*net.IPNet, error) {
+	if strings.Contains(address, "/") {
+		_, ipNet, err := net.ParseCIDR(address)
+		return ipNet, err
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address [%s]", address)
+	}
+	bits := 8 * net.IPv4len
+	if ip.To4() == nil {
+		bits = 8 * net.IPv6len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// isSNATRuleForSubnets checks if an enabled SNAT rule translates the traffic of any of the subnets. A rule without
+// internal addresses translates the traffic of all the networks of the gateway.
+func isSNATRuleForSubnets(natRule *types.NsxtNatRule, subnets []*net.IPNet) bool {
+	if !natRule.Enabled || (natRule.Type != natRuleTypeSNAT && natRule.RuleType != natRuleTypeSNAT) {
+		return false
+	}
+	if natRule.InternalAddresses == "" {
+		return true
+	}
+	internalNet, err := parseNATAddress(natRule.InternalAddresses)
+	if err != nil {
+		return false
+	}
+	for _, subnet := range subnets {
+		if internalNet.Contains(subnet.IP) || subnet.Contains(internalNet.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// getEdgeGatewayOfNetwork returns the OVDC network and the edge gateway the network is connected to. No edge gateway
+// is returned if the network is not connected to one.
+func getEdgeGatewayOfNetwork(vcdClient *vcdsdk.Client, ovdcNetworkName string) (*govcd.OpenApiOrgVdcNetwork,
+	*govcd.NsxtEdgeGateway, error) {
+
+	if vcdClient.VDC == nil || vcdClient.VDC.Vdc == nil {
+		return nil, nil, fmt.Errorf("no OVDC found in the VCD client to get the edge gateway of network [%s]",
+			ovdcNetworkName)
+	}
+	ovdcNetwork, err := vcdClient.VDC.GetOpenApiOrgVdcNetworkByName(ovdcNetworkName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get OVDC network [%s]: [%v]", ovdcNetworkName, err)
+	}
+	if ovdcNetwork.OpenApiOrgVdcNetwork.Connection == nil ||
+		ovdcNetwork.OpenApiOrgVdcNetwork.Connection.RouterRef.ID == "" {
+		return ovdcNetwork, nil, nil
+	}
+
+	gatewayID := ovdcNetwork.OpenApiOrgVdcNetwork.Connection.RouterRef.ID
+	edgeGateway, err := vcdClient.VDC.GetNsxtEdgeGatewayById(gatewayID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get edge gateway [%s] of OVDC network [%s]: [%v]", gatewayID,
+			ovdcNetworkName, err)
+	}
+	return ovdcNetwork, edgeGateway, nil
+}
+
+// getEgressIPs returns the external addresses of the SNAT rules of the edge gateway which translate the traffic leaving
+// the OVDC network of the cluster. No addresses are returned if the network is not connected to an edge gateway or if
+// no SNAT rule applies to it.
+func getEgressIPs(vcdClient *vcdsdk.Client, ovdcNetworkName string) ([]string, error) {
+	ovdcNetwork, edgeGateway, err := getEdgeGatewayOfNetwork(vcdClient, ovdcNetworkName)
+	if err != nil {
+		return nil, err
+	}
+	if edgeGateway == nil {
+		return nil, nil
+	}
+
+	subnets := make([]*net.IPNet, 0)
+	for _, subnet := range ovdcNetwork.OpenApiOrgVdcNetwork.Subnets.Values {
+		_, ipNet, err := net.ParseCIDR(fmt.Sprintf("%s/%d", subnet.Gateway, subnet.PrefixLength))
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet [%s/%d] of OVDC network [%s]: [%v]", subnet.Gateway,
+				subnet.PrefixLength, ovdcNetworkName, err)
+		}
+		subnets = append(subnets, ipNet)
+	}
+
+	gatewayID := edgeGateway.EdgeGateway.ID
+	natRules, err := edgeGateway.GetAllNatRules(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get NAT rules of edge gateway [%s]: [%v]", gatewayID, err)
+	}
+
+	egressIPSet := make(map[string]bool)
+	for _, natRule := range natRules {
+		if natRule == nil || natRule.NsxtNatRule == nil || !isSNATRuleForSubnets(natRule.NsxtNatRule, subnets) {
+			continue
+		}
+		egressIPSet[natRule.NsxtNatRule.ExternalAddresses] = true
+	}
+	egressIPs := make([]string, 0, len(egressIPSet))
+	for egressIP := range egressIPSet {
+		egressIPs = append(egressIPs, egressIP)
+	}
+	sort.Strings(egressIPs)
+	return egressIPs, nil
+}
@@ -0,0 +1,165 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// getEgressSNATRuleName returns the name of the SNAT rule of the edge gateway dedicated to the cluster.
+func getEgressSNATRuleName(vcdCluster *infrav1beta3.VCDCluster) string {
+	return capisdk.GetVirtualServiceNamePrefix(vcdCluster.Name, vcdCluster.Status.InfraId) + "-egress-snat"
+}
+
+// getEgressSNATRuleConfig returns the configuration of the SNAT rule of the cluster translating the traffic of the
+// primary subnet of its OVDC network to the external IP.
+func getEgressSNATRuleConfig(vcdCluster *infrav1beta3.VCDCluster, ovdcNetwork *types.OpenApiOrgVdcNetwork,
+	externalIP string) (*types.NsxtNatRule, error) {
+
+	if len(ovdcNetwork.Subnets.Values) == 0 {
+		return nil, fmt.Errorf("OVDC network [%s] has no subnet", ovdcNetwork.Name)
+	}
+	subnet := ovdcNetwork.Subnets.Values[0]
+	_, ipNet, err := net.ParseCIDR(fmt.Sprintf("%s/%d", subnet.Gateway, subnet.PrefixLength))
+	if err != nil {
+		return nil, fmt.Errorf("invalid subnet [%s/%d] of OVDC network [%s]: [%v]", subnet.Gateway,
+			subnet.PrefixLength, ovdcNetwork.Name, err)
+	}
+	priority := 0
+	if vcdCluster.Spec.EgressConfig.Priority != nil {
+		priority = *vcdCluster.Spec.EgressConfig.Priority
+	}
+	return &types.NsxtNatRule{
+		Name:              getEgressSNATRuleName(vcdCluster),
+		Description:       fmt.Sprintf("Egress SNAT rule of cluster [%s]", vcdCluster.Name),
+		Enabled:           true,
+		Type:              natRuleTypeSNAT,
+		ExternalAddresses: externalIP,
+		InternalAddresses: ipNet.String(),
+		Priority:          &priority,
+	}, nil
+}
+
+// getUnusedEdgeGatewayExternalIP returns an unused external IP of the edge gateway, in the VIP subnet if set.
+func getUnusedEdgeGatewayExternalIP(edgeGateway *govcd.NsxtEdgeGateway, vipSubnet string) (string, error) {
+	subnet := netip.Prefix{}
+	if vipSubnet != "" {
+		var err error
+		if subnet, err = netip.ParsePrefix(vipSubnet); err != nil {
+			return "", fmt.Errorf("invalid VIP subnet [%s]: [%v]", vipSubnet, err)
+		}
+	}
+	ips, err := edgeGateway.GetUnusedExternalIPAddresses(1, subnet, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to get an unused external IP of edge gateway [%s]: [%v]",
+			edgeGateway.EdgeGateway.Name, err)
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("no unused external IP in edge gateway [%s]", edgeGateway.EdgeGateway.Name)
+	}
+	return ips[0].String(), nil
+}
+
+// reconcileEgressSNATRule ensures that the SNAT rule of the cluster translates the traffic leaving its OVDC network to
+// the external IP of VCDClusterSpec.EgressConfig, or to the external IP allocated for the rule when it was created,
+// and records the external IP in VCDClusterStatus.EgressSNATIP. The rule is removed if the EgressConfig is unset.
+func reconcileEgressSNATRule(ctx context.Context, vcdClient *vcdsdk.Client, vcdCluster *infrav1beta3.VCDCluster) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	if vcdCluster.Spec.EgressConfig == nil {
+		if vcdCluster.Status.EgressSNATIP == "" {
+			return nil
+		}
+		return deleteEgressSNATRule(ctx, vcdClient, vcdCluster)
+	}
+
+	ovdcNetwork, edgeGateway, err := getEdgeGatewayOfNetwork(vcdClient, getOvdcNetworkName(vcdCluster))
+	if err != nil {
+		return err
+	}
+	if edgeGateway == nil {
+		return fmt.Errorf("OVDC network [%s] is not connected to an edge gateway to create the egress SNAT rule on",
+			getOvdcNetworkName(vcdCluster))
+	}
+
+	name := getEgressSNATRuleName(vcdCluster)
+	natRule, err := edgeGateway.GetNatRuleByName(name)
+	if err != nil && !govcd.ContainsNotFound(err) {
+		return fmt.Errorf("failed to get SNAT rule [%s]: [%v]", name, err)
+	}
+	externalIP := vcdCluster.Spec.EgressConfig.ExternalIP
+	if externalIP == "" && natRule != nil {
+		externalIP = natRule.NsxtNatRule.ExternalAddresses
+	}
+	if externalIP == "" {
+		if externalIP, err = getUnusedEdgeGatewayExternalIP(edgeGateway,
+			vcdCluster.Spec.LoadBalancerConfigSpec.VipSubnet); err != nil {
+			return err
+		}
+	}
+	natRuleConfig, err := getEgressSNATRuleConfig(vcdCluster, ovdcNetwork.OpenApiOrgVdcNetwork, externalIP)
+	if err != nil {
+		return err
+	}
+
+	if natRule == nil {
+		if _, err = edgeGateway.CreateNatRule(natRuleConfig); err != nil {
+			return fmt.Errorf("failed to create SNAT rule [%s] on edge gateway [%s]: [%v]", name,
+				edgeGateway.EdgeGateway.Name, err)
+		}
+		log.Info("Created the egress SNAT rule of the cluster", "snatRule", name, "externalIP", externalIP,
+			"internalAddresses", natRuleConfig.InternalAddresses)
+	} else if !natRule.IsEqualTo(natRuleConfig) || natRule.NsxtNatRule.Priority == nil ||
+		*natRule.NsxtNatRule.Priority != *natRuleConfig.Priority {
+
+		natRuleConfig.ID = natRule.NsxtNatRule.ID
+		natRuleConfig.Version = natRule.NsxtNatRule.Version
+		if _, err = natRule.Update(natRuleConfig); err != nil {
+			return fmt.Errorf("failed to update SNAT rule [%s] on edge gateway [%s]: [%v]", name,
+				edgeGateway.EdgeGateway.Name, err)
+		}
+		log.Info("Updated the egress SNAT rule of the cluster", "snatRule", name, "externalIP", externalIP,
+			"internalAddresses", natRuleConfig.InternalAddresses)
+	}
+	vcdCluster.Status.EgressSNATIP = externalIP
+	return nil
+}
+
+// deleteEgressSNATRule removes the SNAT rule of the cluster from the edge gateway, if present.
+func deleteEgressSNATRule(ctx context.Context, vcdClient *vcdsdk.Client, vcdCluster *infrav1beta3.VCDCluster) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	_, edgeGateway, err := getEdgeGatewayOfNetwork(vcdClient, getOvdcNetworkName(vcdCluster))
+	if err != nil {
+		return err
+	}
+	if edgeGateway != nil {
+		name := getEgressSNATRuleName(vcdCluster)
+		natRule, err := edgeGateway.GetNatRuleByName(name)
+		if err != nil && !govcd.ContainsNotFound(err) {
+			return fmt.Errorf("failed to get SNAT rule [%s]: [%v]", name, err)
+		}
+		if natRule != nil {
+			if err = natRule.Delete(); err != nil {
+				return fmt.Errorf("failed to delete SNAT rule [%s] from edge gateway [%s]: [%v]", name,
+					edgeGateway.EdgeGateway.Name, err)
+			}
+			log.Info("Deleted the egress SNAT rule of the cluster", "snatRule", name)
+		}
+	}
+	vcdCluster.Status.EgressSNATIP = ""
+	return nil
+}
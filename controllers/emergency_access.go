@@ -0,0 +1,59 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultEmergencyUserName is the name of the emergency user of a VCDMachine which does not set its name.
+	DefaultEmergencyUserName = "capvcd"
+	// EmergencyUserPasswordHashKey is the key of the password hash in the Secret of an emergency user.
+	EmergencyUserPasswordHashKey = "passwordHash"
+)
+
+// getEmergencyUserName returns the name of the emergency user of the machine, or an empty string if there is none.
+func getEmergencyUserName(vcdMachine *infrav1beta3.VCDMachine) string {
+	if vcdMachine.Spec.EmergencyUser == nil {
+		return ""
+	}
+	if vcdMachine.Spec.EmergencyUser.Name == "" {
+		return DefaultEmergencyUserName
+	}
+	return vcdMachine.Spec.EmergencyUser.Name
+}
+
+// getEmergencyUserPasswordHash returns the password hash of the emergency user of the machine, or an empty string if
+// the user has no password.
+func getEmergencyUserPasswordHash(ctx context.Context, cli client.Client,
+	vcdMachine *infrav1beta3.VCDMachine) (string, error) {
+
+	if vcdMachine.Spec.EmergencyUser == nil || vcdMachine.Spec.EmergencyUser.PasswordHashSecretName == "" {
+		return "", nil
+	}
+	secretName := vcdMachine.Spec.EmergencyUser.PasswordHashSecretName
+	passwordSecret := &v1.Secret{}
+	if err := cli.Get(ctx, types.NamespacedName{Name: secretName, Namespace: vcdMachine.Namespace},
+		passwordSecret); err != nil {
+		return "", fmt.Errorf("error getting password hash secret [%s] in namespace [%s]: [%v]", secretName,
+			vcdMachine.Namespace, err)
+	}
+	passwordHash := strings.TrimSpace(string(passwordSecret.Data[EmergencyUserPasswordHashKey]))
+	// a clear text password would be set as is by cloud-init and never match at login
+	if !strings.HasPrefix(passwordHash, "$") {
+		return "", fmt.Errorf("password hash secret [%s] in namespace [%s] has no crypt hash in its [%s] key",
+			secretName, vcdMachine.Namespace, EmergencyUserPasswordHashKey)
+	}
+	return passwordHash, nil
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"fmt"
 	"runtime/debug"
+	"strings"
 )
 
 // NoRDEError is an error used when the InfraID value in the VCDCluster object does not point to a valid RDE in VCD
@@ -20,3 +21,131 @@ func (nre *NoRDEError) Error() string {
 func NewNoRDEError(message string) *NoRDEError {
 	return &NoRDEError{msg: message}
 }
+
+// CredentialsExpiredError is an error used when VCD rejects the credentials of a VCDCluster, e.g. because the password
+// of the org user expired or the user was disabled, or because the API refresh token expired or was revoked
+type CredentialsExpiredError struct {
+	msg          string
+	refreshToken bool
+}
+
+func (cee *CredentialsExpiredError) Error() string {
+	if cee == nil {
+		return fmt.Sprintf("error is unexpectedly nil at stack [%s]", string(debug.Stack()))
+	}
+	return cee.msg
+}
+
+func NewCredentialsExpiredError(message string) *CredentialsExpiredError {
+	return &CredentialsExpiredError{msg: message}
+}
+
+func NewRefreshTokenExpiredError(message string) *CredentialsExpiredError {
+	return &CredentialsExpiredError{msg: message, refreshToken: true}
+}
+
+// CredentialsUnavailableError is an error used when the credentials of a VCDCluster cannot be read, e.g. because its
+// credentials Secret or VCDClusterIdentity is missing
+type CredentialsUnavailableError struct {
+	msg string
+}
+
+func (cue *CredentialsUnavailableError) Error() string {
+	if cue == nil {
+		return fmt.Sprintf("error is unexpectedly nil at stack [%s]", string(debug.Stack()))
+	}
+	return cue.msg
+}
+
+func NewCredentialsUnavailableError(message string) *CredentialsUnavailableError {
+	return &CredentialsUnavailableError{msg: message}
+}
+
+// OwnershipClaimedError is an error used when the VCD resources of a cluster are claimed by a different management
+// cluster
+type OwnershipClaimedError struct {
+	msg string
+}
+
+func (oce *OwnershipClaimedError) Error() string {
+	if oce == nil {
+		return fmt.Sprintf("error is unexpectedly nil at stack [%s]", string(debug.Stack()))
+	}
+	return oce.msg
+}
+
+func NewOwnershipClaimedError(message string) *OwnershipClaimedError {
+	return &OwnershipClaimedError{msg: message}
+}
+
+// SiteCertificateMismatchError is an error used when the VCD site of a VCDCluster presents a certificate chain without
+// any pinned certificate
+type SiteCertificateMismatchError struct {
+	msg string
+}
+
+func (scme *SiteCertificateMismatchError) Error() string {
+	if scme == nil {
+		return fmt.Sprintf("error is unexpectedly nil at stack [%s]", string(debug.Stack()))
+	}
+	return scme.msg
+}
+
+func NewSiteCertificateMismatchError(message string) *SiteCertificateMismatchError {
+	return &SiteCertificateMismatchError{msg: message}
+}
+
+// authenticationFailureMessages are fragments of the messages returned when logging into VCD fails
+var authenticationFailureMessages = []string{
+	"authenticate",
+	"authorization",
+	"bearer token",
+}
+
+// credentialsRejectedMessages are fragments of the messages returned by VCD when it rejects the credentials of a user
+var credentialsRejectedMessages = []string{
+	"401",
+	"unauthorized",
+	"expired",
+	"disabled",
+	"locked",
+	"incorrect",
+	"failed to set authorization header",
+}
+
+func containsAny(msg string, fragments []string) bool {
+	for _, fragment := range fragments {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCredentialsRejectedError checks if an error returned while logging into VCD is caused by the credentials being
+// rejected rather than by the site being unreachable
+func isCredentialsRejectedError(err error) bool {
+	if err == nil {
+		return false
+	}
+	errMsg := strings.ToLower(err.Error())
+	return containsAny(errMsg, authenticationFailureMessages) && containsAny(errMsg, credentialsRejectedMessages)
+}
+
+// VCDReferenceNotFoundError is an error used when an entity referenced by the spec of a VCDMachine or
+// VCDMachineTemplate, i.e. its catalog, template, compute policies or storage profile, does not exist in VCD or is not
+// available to the org or OVDC of the cluster
+type VCDReferenceNotFoundError struct {
+	msg string
+}
+
+func (vrnfe *VCDReferenceNotFoundError) Error() string {
+	if vrnfe == nil {
+		return fmt.Sprintf("error is unexpectedly nil at stack [%s]", string(debug.Stack()))
+	}
+	return vrnfe.msg
+}
+
+func NewVCDReferenceNotFoundError(message string) *VCDReferenceNotFoundError {
+	return &VCDReferenceNotFoundError{msg: message}
+}
@@ -0,0 +1,68 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// ExternalLoadBalancerRequeueInterval is the interval at which an externally managed VCDCluster is requeued while
+// its control plane endpoint cannot be discovered.
+const ExternalLoadBalancerRequeueInterval = 30 * time.Second
+
+// reconcileExternalLoadBalancer discovers the control plane endpoint of a VCDCluster whose infrastructure is managed
+// by an external system, honouring the Cluster API `managed-by` contract: the load balancer is neither created nor
+// modified. An endpoint set in the spec is kept; otherwise the VIP of the load balancer named after the cluster is
+// used, and the cluster is requeued until the load balancer exists.
+func (r *VCDClusterReconciler) reconcileExternalLoadBalancer(ctx context.Context, vcdCluster *infrav1beta3.VCDCluster,
+	vcdClient *vcdsdk.Client, skipRDEEventUpdates bool) (ctrl.Result, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	if vcdCluster.Spec.ControlPlaneEndpoint.Port == 0 {
+		vcdCluster.Spec.ControlPlaneEndpoint.Port = TcpPort
+	}
+	if vcdCluster.Spec.ControlPlaneEndpoint.Host == "" {
+		var oneArm *vcdsdk.OneArm = nil
+		if vcdCluster.Spec.LoadBalancerConfigSpec.UseOneArm {
+			oneArm = &OneArmDefault
+		}
+		gateway, err := vcdsdk.NewGatewayManager(ctx, vcdClient, getOvdcNetworkName(vcdCluster),
+			vcdCluster.Spec.LoadBalancerConfigSpec.VipSubnet, getOvdcName(vcdCluster))
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to create gateway manager to discover the load balancer of cluster [%s]: [%v]",
+				vcdCluster.Name, err)
+		}
+		virtualServiceNamePrefix := capisdk.GetVirtualServiceNamePrefix(vcdCluster.Name, vcdCluster.Status.InfraId)
+		lbPoolNamePrefix := capisdk.GetLoadBalancerPoolNamePrefix(vcdCluster.Name, vcdCluster.Status.InfraId)
+		controlPlaneNodeIP, _, err := gateway.GetLoadBalancer(ctx, fmt.Sprintf("%s-tcp", virtualServiceNamePrefix),
+			fmt.Sprintf("%s-tcp", lbPoolNamePrefix), oneArm)
+		if err != nil || controlPlaneNodeIP == "" {
+			log.Info("Waiting for the externally managed load balancer of the cluster",
+				"virtualServiceName", fmt.Sprintf("%s-tcp", virtualServiceNamePrefix), "error", err)
+			conditions.MarkFalse(vcdCluster, LoadBalancerAvailableCondition, WaitingForExternalLoadBalancerReason,
+				clusterv1.ConditionSeverityInfo, "control plane endpoint not set and virtual service [%s-tcp] not found",
+				virtualServiceNamePrefix)
+			return ctrl.Result{RequeueAfter: ExternalLoadBalancerRequeueInterval}, nil
+		}
+		vcdCluster.Spec.ControlPlaneEndpoint.Host = controlPlaneNodeIP
+	}
+	log.Info(fmt.Sprintf("Control plane endpoint for the externally managed cluster is [%s]",
+		vcdCluster.Spec.ControlPlaneEndpoint.Host))
+
+	capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
+	capvcdRdeManager.AddToEventSet(ctx, capisdk.LoadBalancerAvailable, "", "", "", skipRDEEventUpdates)
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,281 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	swaggerClient "github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdswaggerclient_36_0"
+	swaggerClient37 "github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdswaggerclient_37_2"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Ways a user logs into VCD
+const (
+	AuthTypeLocal         = "local"
+	AuthTypeSamlAdfs      = "saml-adfs"
+	AuthTypeSamlAssertion = "saml-assertion"
+)
+
+// Keys of the SAML credentials in the user credentials Secret of a VCDCluster
+const (
+	SamlAssertionSecretKey      = "samlAssertion"
+	SamlPrivateKeySecretKey     = "samlPrivateKey"
+	AdfsRelyingPartyIdSecretKey = "adfsRelyingPartyId"
+)
+
+// samlSignatureAlgorithm is the algorithm of the signature proving the possession of a holder-of-key assertion
+const samlSignatureAlgorithm = "SHA512withRSA"
+
+type samlCredentials struct {
+	assertion          []byte
+	privateKey         *rsa.PrivateKey
+	adfsRelyingPartyId string
+}
+
+// isFederatedAuth checks if the user of the credentials logs into VCD through the SAML identity provider of the org.
+func isFederatedAuth(userCreds infrav1beta3.UserCredentialsContext) bool {
+	return userCreds.AuthType == AuthTypeSamlAdfs || userCreds.AuthType == AuthTypeSamlAssertion
+}
+
+// getSamlCredentialsForCluster returns the SAML credentials of the user credentials Secret of the VCDCluster. The
+// Secret is read at every reconciliation so that the assertions renewed by an external issuer log in again.
+func getSamlCredentialsForCluster(ctx context.Context, cli client.Client,
+	definedCreds infrav1beta3.UserCredentialsContext) (*samlCredentials, error) {
+
+	samlCreds := &samlCredentials{}
+	if definedCreds.SecretRef == nil {
+		if definedCreds.AuthType == AuthTypeSamlAssertion {
+			return nil, fmt.Errorf("auth type [%s] requires a secret carrying the [%s] key", AuthTypeSamlAssertion,
+				SamlAssertionSecretKey)
+		}
+		return samlCreds, nil
+	}
+	secretNamespacedName := types.NamespacedName{
+		Name:      definedCreds.SecretRef.Name,
+		Namespace: definedCreds.SecretRef.Namespace,
+	}
+	userCredsSecret := &v1.Secret{}
+	if err := cli.Get(ctx, secretNamespacedName, userCredsSecret); err != nil {
+		return nil, fmt.Errorf("error getting secret [%s] in namespace [%s]: [%v]",
+			secretNamespacedName.Name, secretNamespacedName.Namespace, err)
+	}
+	if b, exists := userCredsSecret.Data[AdfsRelyingPartyIdSecretKey]; exists {
+		samlCreds.adfsRelyingPartyId = strings.TrimSpace(string(b))
+	}
+	if definedCreds.AuthType != AuthTypeSamlAssertion {
+		return samlCreds, nil
+	}
+
+	assertion := bytes.TrimSpace(userCredsSecret.Data[SamlAssertionSecretKey])
+	if len(assertion) == 0 {
+		return nil, fmt.Errorf("secret [%s] in namespace [%s] has no [%s] key", secretNamespacedName.Name,
+			secretNamespacedName.Namespace, SamlAssertionSecretKey)
+	}
+	samlCreds.assertion = assertion
+	if b, exists := userCredsSecret.Data[SamlPrivateKeySecretKey]; exists {
+		privateKey, err := parseRSAPrivateKey(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid [%s] key in secret [%s] in namespace [%s]: [%v]", SamlPrivateKeySecretKey,
+				secretNamespacedName.Name, secretNamespacedName.Namespace, err)
+		}
+		samlCreds.privateKey = privateKey
+	}
+	return samlCreds, nil
+}
+
+// parseRSAPrivateKey parses a PEM RSA private key in the PKCS #1 or PKCS #8 format.
+func parseRSAPrivateKey(pemBytes []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM private key found")
+	}
+	if privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return privateKey, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse private key: [%v]", err)
+	}
+	privateKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return privateKey, nil
+}
+
+// getSignTokenAuthorization returns the SIGN authorization exchanging the SAML assertion for a VCD session in the org.
+// A holder-of-key assertion is sent with the signature of the assertion by the private key it is bound to.
+func getSignTokenAuthorization(samlCreds *samlCredentials, org string) (string, error) {
+	var gzippedAssertion bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzippedAssertion)
+	if _, err := gzipWriter.Write(samlCreds.assertion); err != nil {
+		return "", fmt.Errorf("unable to compress the SAML assertion: [%v]", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return "", fmt.Errorf("unable to compress the SAML assertion: [%v]", err)
+	}
+
+	authorization := fmt.Sprintf(`SIGN token="%s",org="%s"`,
+		base64.StdEncoding.EncodeToString(gzippedAssertion.Bytes()), org)
+	if samlCreds.privateKey == nil {
+		return authorization, nil
+	}
+	digest := sha512.Sum512(samlCreds.assertion)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, samlCreds.privateKey, crypto.SHA512, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("unable to sign the SAML assertion: [%v]", err)
+	}
+	return fmt.Sprintf(`%s,signature="%s",signature_alg="%s"`, authorization,
+		base64.StdEncoding.EncodeToString(signature), samlSignatureAlgorithm), nil
+}
+
+// loginWithSamlAssertion exchanges the SAML assertion for a VCD session of the user in the org and authenticates the
+// client with the access token of the session.
+func loginWithSamlAssertion(govcdClient *govcd.VCDClient, samlCreds *samlCredentials, org string) error {
+	authorization, err := getSignTokenAuthorization(samlCreds, org)
+	if err != nil {
+		return err
+	}
+	sessionsURL := fmt.Sprintf("%s://%s/api/sessions", govcdClient.Client.VCDHREF.Scheme,
+		govcdClient.Client.VCDHREF.Host)
+	req, err := http.NewRequest(http.MethodPost, sessionsURL, nil)
+	if err != nil {
+		return fmt.Errorf("unable to create the request to [%s]: [%v]", sessionsURL, err)
+	}
+	req.Header.Set("Accept", fmt.Sprintf("application/*+xml;version=%s", govcdClient.Client.APIVersion))
+	req.Header.Set("Authorization", authorization)
+	resp, err := govcdClient.Client.Http.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to reach [%s]: [%v]", sessionsURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to authenticate in org [%s] with the SAML assertion: [%s]", org, resp.Status)
+	}
+
+	accessToken := resp.Header.Get(govcd.BearerTokenHeader)
+	if accessToken == "" {
+		return fmt.Errorf("no [%s] header in the response of [%s]", govcd.BearerTokenHeader, sessionsURL)
+	}
+	if err = govcdClient.SetToken(org, govcd.BearerTokenHeader, accessToken); err != nil {
+		return fmt.Errorf("unable to authenticate in org [%s] with the access token of the SAML session: [%v]",
+			org, err)
+	}
+	return nil
+}
+
+// newSwaggerClients returns the OpenAPI clients sharing the session of the GoVCD client, whose transports verify the
+// site with the TLS configuration. The 37.2 client is only set if the site supports the API version 37.2.
+func newSwaggerClients(govcdClient *govcd.VCDClient, site string, tlsConfig *tls.Config) (*swaggerClient.APIClient,
+	*swaggerClient37.APIClient) {
+
+	// the sessions opened through ADFS are identified by a legacy token instead of a bearer token
+	authHeader, authValue := "Authorization", fmt.Sprintf("Bearer %s", govcdClient.Client.VCDToken)
+	if govcdClient.Client.VCDAuthHeader != govcd.BearerTokenHeader {
+		authHeader, authValue = govcdClient.Client.VCDAuthHeader, govcdClient.Client.VCDToken
+	}
+	newHTTPClient := func() *http.Client {
+		return &http.Client{
+			Transport: newVCDAPIMetricsTransport(&http.Transport{
+				TLSClientConfig: tlsConfig.Clone(),
+				Proxy:           getVCDSiteProxyFunc(site),
+			}),
+		}
+	}
+
+	swaggerConfig := swaggerClient.NewConfiguration()
+	swaggerConfig.BasePath = fmt.Sprintf("%s/cloudapi", site)
+	swaggerConfig.AddDefaultHeader(authHeader, authValue)
+	swaggerConfig.HTTPClient = newHTTPClient()
+	apiClient := swaggerClient.NewAPIClient(swaggerConfig)
+
+	var apiClient37 *swaggerClient37.APIClient
+	if govcdClient.Client.APIVCDMaxVersionIs(fmt.Sprintf(">=%s", vcdsdk.VCloudApiVersion_37_2)) {
+		swaggerConfig37 := swaggerClient37.NewConfiguration()
+		swaggerConfig37.BasePath = fmt.Sprintf("%s/cloudapi", site)
+		swaggerConfig37.AddDefaultHeader(authHeader, authValue)
+		swaggerConfig37.HTTPClient = newHTTPClient()
+		apiClient37 = swaggerClient37.NewAPIClient(swaggerConfig37)
+	}
+	return apiClient, apiClient37
+}
+
+// newFederatedVCDClient logs a SAML user into VCD, either by exchanging the username and password of the user for an
+// assertion with the ADFS server of the org, or with the assertion of the Secret, and returns a VCD client for the
+// session whose requests to the site are all verified with the TLS configuration. The user of the session is recorded
+// in the auth config of the client in place of a local username.
+func newFederatedVCDClient(site string, orgName string, vdcName string, userOrg string,
+	userCreds infrav1beta3.UserCredentialsContext, samlCreds *samlCredentials, insecure bool,
+	tlsConfig *tls.Config) (*vcdsdk.Client, error) {
+
+	href := fmt.Sprintf("%s/api", site)
+	u, err := url.ParseRequestURI(href)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse url [%s]: [%v]", href, err)
+	}
+	// the username of an assertion may be empty or only name the org of the user, i.e. org/
+	userOrg, username, err := vcdsdk.GetUserAndOrg(userCreds.Username, orgName, userOrg)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing username before authenticating to VCD: [%v]", err)
+	}
+
+	var govcdClient *govcd.VCDClient
+	switch userCreds.AuthType {
+	case AuthTypeSamlAdfs:
+		govcdClient = govcd.NewVCDClient(*u, insecure, govcd.WithSamlAdfs(true, samlCreds.adfsRelyingPartyId))
+		govcdClient.Client.APIVersion = vcdsdk.VCloudApiVersion_36_0
+		setVCDClientTransport(govcdClient, site, tlsConfig)
+		if _, err = govcdClient.GetAuthResponse(username, userCreds.Password, userOrg); err != nil {
+			return nil, fmt.Errorf("unable to authenticate [%s/%s] through ADFS for url [%s]: [%v]", userOrg, username,
+				href, err)
+		}
+	case AuthTypeSamlAssertion:
+		govcdClient = govcd.NewVCDClient(*u, insecure)
+		govcdClient.Client.APIVersion = vcdsdk.VCloudApiVersion_36_0
+		setVCDClientTransport(govcdClient, site, tlsConfig)
+		if err = loginWithSamlAssertion(govcdClient, samlCreds, userOrg); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("auth type [%s] is not a federated auth type", userCreds.AuthType)
+	}
+
+	sessionInfo, err := govcdClient.Client.GetSessionInfo()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the session of the SAML user in org [%s]: [%v]", userOrg, err)
+	}
+	vcdAuthConfig := vcdsdk.NewVCDAuthConfigFromSecrets(site, sessionInfo.User.Name, "", "", userOrg, insecure)
+	vcdAuthConfig.IsSysAdmin = govcdClient.Client.IsSysAdmin
+	apiClient, apiClient37 := newSwaggerClients(govcdClient, site, tlsConfig)
+	return &vcdsdk.Client{
+		VCDAuthConfig:   vcdAuthConfig,
+		ClusterOrgName:  orgName,
+		ClusterOVDCName: vdcName,
+		VCDClient:       govcdClient,
+		APIClient:       apiClient,
+		APIClient37_2:   apiClient37,
+	}, nil
+}
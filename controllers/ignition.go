@@ -0,0 +1,64 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	b64 "encoding/base64"
+	"fmt"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+)
+
+const (
+	// IgnitionConfigData is the guestinfo key the Ignition of the VM reads its config from.
+	IgnitionConfigData = "guestinfo.ignition.config.data"
+	// IgnitionConfigDataEncoding is the guestinfo key of the encoding of the Ignition config of the VM.
+	IgnitionConfigDataEncoding = "guestinfo.ignition.config.data.encoding"
+)
+
+// isIgnitionBootstrap checks if the bootstrap data of the machine is an Ignition config.
+func isIgnitionBootstrap(vcdMachine *infrav1beta3.VCDMachine) bool {
+	return vcdMachine.Spec.BootstrapFormat == string(bootstrapv1.Ignition)
+}
+
+// validateBootstrapFormat checks that the format of the bootstrap data secret, which is cloud-config when unset,
+// matches the bootstrap format of the machine.
+func validateBootstrapFormat(vcdMachine *infrav1beta3.VCDMachine, secretFormat string) error {
+	if secretFormat == "" {
+		secretFormat = string(bootstrapv1.CloudConfig)
+	}
+	machineFormat := vcdMachine.Spec.BootstrapFormat
+	if machineFormat == "" {
+		machineFormat = string(bootstrapv1.CloudConfig)
+	}
+	if secretFormat != machineFormat {
+		return fmt.Errorf("bootstrap data of machine [%s] has format [%s] while the VCDMachine expects [%s]",
+			vcdMachine.Name, secretFormat, machineFormat)
+	}
+	return nil
+}
+
+// getBootstrapGuestInfo returns the guestinfo keys passing the bootstrap data to the VM.
+func getBootstrapGuestInfo(vcdMachine *infrav1beta3.VCDMachine, bootstrapData []byte) map[string]string {
+	if isIgnitionBootstrap(vcdMachine) {
+		return map[string]string{
+			IgnitionConfigData:         b64.StdEncoding.EncodeToString(bootstrapData),
+			IgnitionConfigDataEncoding: "base64",
+			"disk.enableUUID":          "1",
+		}
+	}
+	return getCloudInitGuestInfo(bootstrapData)
+}
+
+// getCloudInitGuestInfo returns the guestinfo keys passing a cloud-init script to the VM.
+func getCloudInitGuestInfo(cloudInitData []byte) map[string]string {
+	return map[string]string{
+		"guestinfo.userdata":          b64.StdEncoding.EncodeToString(cloudInitData),
+		"guestinfo.userdata.encoding": "base64",
+		"disk.enableUUID":             "1",
+	}
+}
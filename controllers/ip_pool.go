@@ -0,0 +1,363 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// IPPoolExhaustedRetryInterval is the time after which a VM is created again on an OVDC network whose IP pool was
+	// found exhausted, in case addresses were released or added to the pool in the meantime.
+	IPPoolExhaustedRetryInterval = 5 * time.Minute
+	// IPPoolExhaustedRequeueInterval is the interval at which a machine waiting for addresses in the IP pool of its
+	// OVDC network checks it again.
+	IPPoolExhaustedRequeueInterval = time.Minute
+)
+
+// ipPoolExhaustionMessages are the fragments of the VCD errors failing a VM creation because the static IP pool of the
+// network of the VM has no free address.
+var ipPoolExhaustionMessages = []string{
+	"no more ip addresses",
+	"no ip addresses available",
+	"no free ip addresses",
+	"insufficient ip addresses",
+	"ip pool is exhausted",
+	"unable to allocate ip address",
+}
+
+var (
+	// exhaustedIPPools records when the IP pools of the OVDC networks were found exhausted by a VM creation, keyed by
+	// site, org and network, so that the machines of all the clusters on the network stop creating VMs.
+	exhaustedIPPools     = make(map[string]time.Time)
+	exhaustedIPPoolsLock sync.Mutex
+)
+
+// isIPPoolExhaustedError checks if VCD failed to create a VM because the IP pool of its network is exhausted.
+func isIPPoolExhaustedError(err error) bool {
+	if err == nil {
+		return false
+	}
+	errMessage := strings.ToLower(err.Error())
+	for _, exhaustionMessage := range ipPoolExhaustionMessages {
+		if strings.Contains(errMessage, exhaustionMessage) {
+			return true
+		}
+	}
+	return false
+}
+
+func getIPPoolKey(site string, org string, ovdcNetworkName string) string {
+	return fmt.Sprintf("%s/%s/%s", site, org, ovdcNetworkName)
+}
+
+// recordIPPoolExhausted records that a VM creation found the IP pool of the network exhausted.
+func recordIPPoolExhausted(key string) {
+	exhaustedIPPoolsLock.Lock()
+	defer exhaustedIPPoolsLock.Unlock()
+	exhaustedIPPools[key] = time.Now()
+}
+
+// isIPPoolExhausted checks if a VM creation found the IP pool of the network exhausted less than
+// IPPoolExhaustedRetryInterval ago.
+func isIPPoolExhausted(key string) bool {
+	exhaustedIPPoolsLock.Lock()
+	defer exhaustedIPPoolsLock.Unlock()
+	exhaustedAt, ok := exhaustedIPPools[key]
+	if !ok {
+		return false
+	}
+	if time.Since(exhaustedAt) >= IPPoolExhaustedRetryInterval {
+		delete(exhaustedIPPools, key)
+		return false
+	}
+	return true
+}
+
+// forgetIPPoolExhausted lets the machines create VMs on the network again, once addresses are available in its pool.
+func forgetIPPoolExhausted(key string) {
+	exhaustedIPPoolsLock.Lock()
+	defer exhaustedIPPoolsLock.Unlock()
+	delete(exhaustedIPPools, key)
+}
+
+// getFreeIPCount returns the number of free addresses of the IP pool of the network, or -1 if VCD does not report it.
+func getFreeIPCount(ovdcNetwork *types.OpenApiOrgVdcNetwork) int {
+	if ovdcNetwork.TotalIpCount == nil || ovdcNetwork.UsedIpCount == nil {
+		return -1
+	}
+	return *ovdcNetwork.TotalIpCount - *ovdcNetwork.UsedIpCount
+}
+
+// addIPRangeToPool adds the range to the static IP pool of the subnet of the network containing it, and reports if it
+// was added. A range already in the pool is not added again.
+func addIPRangeToPool(ovdcNetwork *types.OpenApiOrgVdcNetwork, ipRange *infrav1beta3.IPRange) (bool, error) {
+	startIP, endIP := net.ParseIP(ipRange.StartAddress), net.ParseIP(ipRange.EndAddress)
+	if startIP == nil || endIP == nil {
+		return false, fmt.Errorf("invalid IP range [%s-%s]", ipRange.StartAddress, ipRange.EndAddress)
+	}
+	if bytes.Compare(startIP.To16(), endIP.To16()) > 0 {
+		return false, fmt.Errorf("start of IP range [%s-%s] is after its end", ipRange.StartAddress,
+			ipRange.EndAddress)
+	}
+	for idx := range ovdcNetwork.Subnets.Values {
+		subnet := &ovdcNetwork.Subnets.Values[idx]
+		_, ipNet, err := net.ParseCIDR(fmt.Sprintf("%s/%d", subnet.Gateway, subnet.PrefixLength))
+		if err != nil || !ipNet.Contains(startIP) || !ipNet.Contains(endIP) {
+			continue
+		}
+		for _, poolRange := range subnet.IPRanges.Values {
+			if poolRange.StartAddress == ipRange.StartAddress && poolRange.EndAddress == ipRange.EndAddress {
+				return false, nil
+			}
+		}
+		subnet.IPRanges.Values = append(subnet.IPRanges.Values, types.OrgVdcNetworkSubnetIPRangeValues{
+			StartAddress: ipRange.StartAddress,
+			EndAddress:   ipRange.EndAddress,
+		})
+		return true, nil
+	}
+	return false, fmt.Errorf("IP range [%s-%s] is not in a subnet of OVDC network [%s]", ipRange.StartAddress,
+		ipRange.EndAddress, ovdcNetwork.Name)
+}
+
+// markIPPoolExhausted sets the IPPoolAvailable condition to false and reports if it was newly set.
+func markIPPoolExhausted(obj conditions.Setter, message string) bool {
+	newlyExhausted := !conditions.IsFalse(obj, IPPoolAvailableCondition)
+	conditions.MarkFalse(obj, IPPoolAvailableCondition, IPPoolExhaustedReason, clusterv1.ConditionSeverityWarning, "%s",
+		message)
+	return newlyExhausted
+}
+
+// reconcileIPPool reports on the VCDCluster whether the IP pool of its OVDC network is exhausted, either as reported
+// by VCD or as found by a VM creation, and extends the pool with VCDClusterSpec.IPPoolExtension if it is set and the
+// user has the rights to edit the network.
+func (r *VCDClusterReconciler) reconcileIPPool(ctx context.Context, vcdClient *vcdsdk.Client,
+	vcdCluster *infrav1beta3.VCDCluster, userRights *vcdUserRights) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	ovdcNetworkName := getOvdcNetworkName(vcdCluster)
+	key := getIPPoolKey(vcdCluster.Spec.Site, vcdClient.ClusterOrgName, ovdcNetworkName)
+	if vcdClient.VDC == nil {
+		return
+	}
+	ovdcNetwork, err := vcdClient.VDC.GetOpenApiOrgVdcNetworkByName(ovdcNetworkName)
+	if err != nil {
+		log.Error(err, "Unable to get the OVDC network to check its IP pool", "ovdcNetwork", ovdcNetworkName)
+		return
+	}
+	freeIPCount := getFreeIPCount(ovdcNetwork.OpenApiOrgVdcNetwork)
+	if freeIPCount > 0 {
+		forgetIPPoolExhausted(key)
+	}
+	if freeIPCount != 0 && !isIPPoolExhausted(key) {
+		if conditions.IsFalse(vcdCluster, IPPoolAvailableCondition) {
+			log.Info("IP pool of the OVDC network has free addresses again", "ovdcNetwork", ovdcNetworkName)
+			r.recordEvent(vcdCluster, corev1.EventTypeNormal, IPPoolReplenishedReason,
+				fmt.Sprintf("IP pool of OVDC network [%s] has free addresses again", ovdcNetworkName))
+		}
+		conditions.MarkTrue(vcdCluster, IPPoolAvailableCondition)
+		return
+	}
+
+	message := fmt.Sprintf("IP pool of OVDC network [%s] is exhausted; no VMs are created until addresses are "+
+		"available", ovdcNetworkName)
+	if ipRange := vcdCluster.Spec.IPPoolExtension; ipRange != nil {
+		if !userRights.isFeatureEnabled(OptionalFeatureNetworkEdit) {
+			message = fmt.Sprintf("%s; the pool is not extended as the user lacks the rights to edit the network",
+				message)
+		} else if added, err := addIPRangeToPool(ovdcNetwork.OpenApiOrgVdcNetwork, ipRange); err != nil {
+			message = fmt.Sprintf("%s; unable to extend the pool: [%v]", message, err)
+		} else if !added {
+			message = fmt.Sprintf("%s; the pool was already extended with [%s-%s]", message, ipRange.StartAddress,
+				ipRange.EndAddress)
+		} else if _, err = ovdcNetwork.Update(ovdcNetwork.OpenApiOrgVdcNetwork); err != nil {
+			message = fmt.Sprintf("%s; unable to extend the pool with [%s-%s]: [%v]", message, ipRange.StartAddress,
+				ipRange.EndAddress, err)
+		} else {
+			forgetIPPoolExhausted(key)
+			conditions.MarkTrue(vcdCluster, IPPoolAvailableCondition)
+			log.Info("Extended the IP pool of the OVDC network", "ovdcNetwork", ovdcNetworkName, "startAddress",
+				ipRange.StartAddress, "endAddress", ipRange.EndAddress)
+			r.recordEvent(vcdCluster, corev1.EventTypeNormal, IPPoolExtendedReason,
+				fmt.Sprintf("IP pool of OVDC network [%s] extended with [%s-%s]", ovdcNetworkName,
+					ipRange.StartAddress, ipRange.EndAddress))
+			return
+		}
+	}
+	if markIPPoolExhausted(vcdCluster, message) {
+		log.Info("IP pool of the OVDC network is exhausted", "ovdcNetwork", ovdcNetworkName, "details", message)
+		r.recordEvent(vcdCluster, corev1.EventTypeWarning, IPPoolExhaustedReason, message)
+	}
+}
+
+// allocatedIPAddress is an address of an OVDC network allocated to a VM, an edge gateway or a manual reservation.
+type allocatedIPAddress struct {
+	IPAddress string `json:"ipAddress"`
+}
+
+// getAllocatedIPAddresses returns the addresses of the OVDC network which are allocated.
+func getAllocatedIPAddresses(vcdClient *vcdsdk.Client,
+	ovdcNetwork *types.OpenApiOrgVdcNetwork) (map[string]bool, error) {
+
+	client := &vcdClient.VCDClient.Client
+	urlRef, err := client.OpenApiBuildEndpoint(fmt.Sprintf(
+		types.OpenApiPathVersion1_0_0+types.OpenApiEndpointOrgVdcNetworks+"%s/allocatedAddresses", ovdcNetwork.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the allocated addresses endpoint of OVDC network [%s]: [%v]",
+			ovdcNetwork.Name, err)
+	}
+	addresses := []*allocatedIPAddress{{}}
+	if err = client.OpenApiGetAllItems(client.APIVersion, urlRef, nil, &addresses, nil); err != nil {
+		return nil, fmt.Errorf("failed to get the allocated addresses of OVDC network [%s]: [%v]", ovdcNetwork.Name,
+			err)
+	}
+	allocated := make(map[string]bool)
+	for _, address := range addresses {
+		if address != nil && address.IPAddress != "" {
+			allocated[address.IPAddress] = true
+		}
+	}
+	return allocated, nil
+}
+
+// getUnusedPoolIPAddress returns the first address of the static IP pool of the OVDC network which is not allocated.
+func getUnusedPoolIPAddress(vcdClient *vcdsdk.Client, ovdcNetwork *types.OpenApiOrgVdcNetwork) (string, error) {
+
+	allocated, err := getAllocatedIPAddresses(vcdClient, ovdcNetwork)
+	if err != nil {
+		return "", err
+	}
+	for _, subnet := range ovdcNetwork.Subnets.Values {
+		for _, poolRange := range subnet.IPRanges.Values {
+			startIP, err := netip.ParseAddr(poolRange.StartAddress)
+			if err != nil {
+				return "", fmt.Errorf("invalid start address [%s] of the IP pool of OVDC network [%s]: [%v]",
+					poolRange.StartAddress, ovdcNetwork.Name, err)
+			}
+			endIP, err := netip.ParseAddr(poolRange.EndAddress)
+			if err != nil {
+				return "", fmt.Errorf("invalid end address [%s] of the IP pool of OVDC network [%s]: [%v]",
+					poolRange.EndAddress, ovdcNetwork.Name, err)
+			}
+			for ip := startIP; ip.IsValid() && ip.Compare(endIP) <= 0; ip = ip.Next() {
+				if !allocated[ip.String()] {
+					return ip.String(), nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("no unused address in the IP pool of OVDC network [%s]", ovdcNetwork.Name)
+}
+
+// removeIPAddressFromPool removes the address from the static IP pool of the network, splitting the range containing
+// it, so that VCD does not allocate it to a VM. It reports if the address was in the pool.
+func removeIPAddressFromPool(ovdcNetwork *types.OpenApiOrgVdcNetwork, address string) (bool, error) {
+	ip, err := netip.ParseAddr(address)
+	if err != nil {
+		return false, fmt.Errorf("invalid IP address [%s]: [%v]", address, err)
+	}
+	for subnetIdx := range ovdcNetwork.Subnets.Values {
+		subnet := &ovdcNetwork.Subnets.Values[subnetIdx]
+		for rangeIdx, poolRange := range subnet.IPRanges.Values {
+			startIP, err := netip.ParseAddr(poolRange.StartAddress)
+			if err != nil {
+				continue
+			}
+			endIP, err := netip.ParseAddr(poolRange.EndAddress)
+			if err != nil || ip.Compare(startIP) < 0 || ip.Compare(endIP) > 0 {
+				continue
+			}
+			var splitRanges []types.OrgVdcNetworkSubnetIPRangeValues
+			if ip != startIP {
+				splitRanges = append(splitRanges, types.OrgVdcNetworkSubnetIPRangeValues{
+					StartAddress: poolRange.StartAddress,
+					EndAddress:   ip.Prev().String(),
+				})
+			}
+			if ip != endIP {
+				splitRanges = append(splitRanges, types.OrgVdcNetworkSubnetIPRangeValues{
+					StartAddress: ip.Next().String(),
+					EndAddress:   poolRange.EndAddress,
+				})
+			}
+			poolRanges := append([]types.OrgVdcNetworkSubnetIPRangeValues{}, subnet.IPRanges.Values[:rangeIdx]...)
+			poolRanges = append(poolRanges, splitRanges...)
+			subnet.IPRanges.Values = append(poolRanges, subnet.IPRanges.Values[rangeIdx+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// returnIPAddressToPool adds the address removed by removeIPAddressFromPool back to the static IP pool of the subnet of
+// the network containing it, merging it with the adjacent ranges. It reports if the address was added.
+func returnIPAddressToPool(ovdcNetwork *types.OpenApiOrgVdcNetwork, address string) (bool, error) {
+	ip, err := netip.ParseAddr(address)
+	if err != nil {
+		return false, fmt.Errorf("invalid IP address [%s]: [%v]", address, err)
+	}
+	for subnetIdx := range ovdcNetwork.Subnets.Values {
+		subnet := &ovdcNetwork.Subnets.Values[subnetIdx]
+		prefix, err := netip.ParsePrefix(fmt.Sprintf("%s/%d", subnet.Gateway, subnet.PrefixLength))
+		if err != nil || !prefix.Contains(ip) {
+			continue
+		}
+		previousIdx, nextIdx := -1, -1
+		for rangeIdx, poolRange := range subnet.IPRanges.Values {
+			startIP, err := netip.ParseAddr(poolRange.StartAddress)
+			if err != nil {
+				continue
+			}
+			endIP, err := netip.ParseAddr(poolRange.EndAddress)
+			if err != nil {
+				continue
+			}
+			if ip.Compare(startIP) >= 0 && ip.Compare(endIP) <= 0 {
+				return false, nil
+			}
+			if endIP.Next() == ip {
+				previousIdx = rangeIdx
+			}
+			if ip.Next() == startIP {
+				nextIdx = rangeIdx
+			}
+		}
+		ipRanges := subnet.IPRanges.Values
+		switch {
+		case previousIdx >= 0 && nextIdx >= 0:
+			ipRanges[previousIdx].EndAddress = ipRanges[nextIdx].EndAddress
+			subnet.IPRanges.Values = append(ipRanges[:nextIdx], ipRanges[nextIdx+1:]...)
+		case previousIdx >= 0:
+			ipRanges[previousIdx].EndAddress = address
+		case nextIdx >= 0:
+			ipRanges[nextIdx].StartAddress = address
+		default:
+			subnet.IPRanges.Values = append(ipRanges, types.OrgVdcNetworkSubnetIPRangeValues{
+				StartAddress: address,
+				EndAddress:   address,
+			})
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("IP address [%s] is not in a subnet of OVDC network [%s]", address, ovdcNetwork.Name)
+}
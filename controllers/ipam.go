@@ -0,0 +1,99 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ipamv1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// IPAddressClaimRequeueInterval is the interval after which a machine still waiting for the IPAM providers to allocate
+// the addresses of its IPAddressClaims is reconciled again.
+const IPAddressClaimRequeueInterval = 10 * time.Second
+
+// +kubebuilder:rbac:groups=ipam.cluster.x-k8s.io,resources=ipaddressclaims,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=ipam.cluster.x-k8s.io,resources=ipaddresses,verbs=get;list;watch
+
+// getIPAddressClaimName returns the name of the IPAddressClaim of the NIC at index nicIndex of a machine.
+func getIPAddressClaimName(vcdMachine *infrav1beta3.VCDMachine, nicIndex int) string {
+	return fmt.Sprintf("%s-%d", vcdMachine.Name, nicIndex)
+}
+
+// reconcileIPAddressClaims creates an IPAddressClaim for every network of the machine referencing an IPAM pool and
+// returns the networks with the addresses allocated by the IPAM providers, in MANUAL IP allocation mode. It returns
+// false if an address is not allocated yet. The claims are owned by the VCDMachine so that the addresses are released
+// when the machine is deleted.
+func (r *VCDMachineReconciler) reconcileIPAddressClaims(ctx context.Context, machine *clusterv1.Machine,
+	vcdMachine *infrav1beta3.VCDMachine, networks []infrav1beta3.NetworkSpec) ([]infrav1beta3.NetworkSpec, bool, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	claimedNetworks := make([]infrav1beta3.NetworkSpec, len(networks))
+	allocated := true
+	for nicIndex, network := range networks {
+		claimedNetworks[nicIndex] = network
+		if network.IPAddressPool == nil {
+			continue
+		}
+
+		claimName := getIPAddressClaimName(vcdMachine, nicIndex)
+		claim := &ipamv1.IPAddressClaim{}
+		err := r.Client.Get(ctx, client.ObjectKey{Namespace: vcdMachine.Namespace, Name: claimName}, claim)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, false, fmt.Errorf("failed to get IPAddressClaim [%s/%s]: [%v]", vcdMachine.Namespace,
+				claimName, err)
+		}
+		if apierrors.IsNotFound(err) {
+			claim = &ipamv1.IPAddressClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      claimName,
+					Namespace: vcdMachine.Namespace,
+					Labels: map[string]string{
+						clusterv1.ClusterNameLabel: machine.Spec.ClusterName,
+					},
+				},
+				Spec: ipamv1.IPAddressClaimSpec{
+					PoolRef: *network.IPAddressPool,
+				},
+			}
+			if err = controllerutil.SetControllerReference(vcdMachine, claim, r.Client.Scheme()); err != nil {
+				return nil, false, fmt.Errorf("failed to set owner of IPAddressClaim [%s/%s]: [%v]",
+					vcdMachine.Namespace, claimName, err)
+			}
+			if err = r.Client.Create(ctx, claim); err != nil {
+				return nil, false, fmt.Errorf("failed to create IPAddressClaim [%s/%s] for network [%s]: [%v]",
+					vcdMachine.Namespace, claimName, network.Name, err)
+			}
+			log.Info("Created IPAddressClaim", "claim", claimName, "network", network.Name,
+				"pool", network.IPAddressPool.Name)
+		}
+
+		if claim.Status.AddressRef.Name == "" {
+			log.Info("Waiting for IP address to be allocated", "claim", claimName, "network", network.Name)
+			allocated = false
+			continue
+		}
+		address := &ipamv1.IPAddress{}
+		addressKey := client.ObjectKey{Namespace: vcdMachine.Namespace, Name: claim.Status.AddressRef.Name}
+		if err = r.Client.Get(ctx, addressKey, address); err != nil {
+			return nil, false, fmt.Errorf("failed to get IPAddress [%s] allocated for IPAddressClaim [%s/%s]: [%v]",
+				claim.Status.AddressRef.Name, vcdMachine.Namespace, claimName, err)
+		}
+		claimedNetworks[nicIndex].IPAllocationMode = IPAllocationModeManual
+		claimedNetworks[nicIndex].IPAddress = address.Spec.Address
+	}
+	return claimedNetworks, allocated, nil
+}
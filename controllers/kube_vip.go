@@ -0,0 +1,242 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DefaultKubeVipImage is the kube-vip image run on the control plane machines unless the VCDCluster sets its own.
+	DefaultKubeVipImage = "ghcr.io/kube-vip/kube-vip:v0.6.4"
+
+	// KubeVipManifestPath is the path of the static pod manifest of kube-vip on the control plane machines.
+	KubeVipManifestPath = "/etc/kubernetes/manifests/kube-vip.yaml"
+
+	// kubeVipKubeConfigPath is the kubeconfig kube-vip reads the leader election lease with. The kubeconfig is
+	// switched to super-admin.conf during the kubeadm init of Kubernetes 1.29 and later, before admin.conf is granted
+	// its rights.
+	kubeVipKubeConfigPath = "/etc/kubernetes/admin.conf"
+)
+
+// isControlPlaneEndpointKubeVip checks if the control plane endpoint of the cluster is a virtual IP announced by
+// kube-vip, reserved from the static IP pool of its OVDC network unless set by the user.
+func isControlPlaneEndpointKubeVip(vcdCluster *infrav1beta3.VCDCluster) bool {
+	return vcdCluster.Spec.ControlPlaneEndpointMode == ControlPlaneEndpointModeKubeVip
+}
+
+// isKubeVipRequired checks if the virtual IP of the control plane endpoint of the cluster is announced by kube-vip from
+// its control plane machines, which is the case in the KubeVip mode, and in the Routed mode when the endpoint is set
+// by the user.
+func isKubeVipRequired(vcdCluster *infrav1beta3.VCDCluster) bool {
+	if vcdCluster.Spec.ControlPlaneEndpoint.Host == "" {
+		return false
+	}
+	return isControlPlaneEndpointKubeVip(vcdCluster) ||
+		(isControlPlaneEndpointRouted(vcdCluster) && !vcdCluster.Status.ControlPlaneMachineEndpoint)
+}
+
+// reconcileKubeVipControlPlaneEndpoint uses a virtual IP announced by kube-vip from the control plane machines as the
+// control plane endpoint of the cluster, without provisioning any load balancer or DNAT rule on the edge gateway.
+// Unless the endpoint is set by the user, an unused address of the static IP pool of the OVDC network is reserved as
+// the virtual IP by removing it from the pool, so that VCD does not allocate it to a VM.
+func (r *VCDClusterReconciler) reconcileKubeVipControlPlaneEndpoint(ctx context.Context,
+	vcdCluster *infrav1beta3.VCDCluster, vcdClient *vcdsdk.Client, skipRDEEventUpdates bool) (ctrl.Result, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+	capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
+
+	if vcdCluster.Spec.ControlPlaneEndpoint.Port == 0 {
+		vcdCluster.Spec.ControlPlaneEndpoint.Port = TcpPort
+	}
+	if vcdCluster.Spec.ControlPlaneEndpoint.Host == "" && vcdCluster.Status.ReservedControlPlaneVIP != "" {
+		vcdCluster.Spec.ControlPlaneEndpoint.Host = vcdCluster.Status.ReservedControlPlaneVIP
+	}
+	if vcdCluster.Spec.ControlPlaneEndpoint.Host == "" {
+		address, err := r.reserveKubeVipControlPlaneVIP(ctx, vcdCluster, vcdClient)
+		if err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.LoadBalancerError, "", vcdCluster.Name,
+				fmt.Sprintf("failed to reserve the control plane endpoint of cluster [%s]: [%v]", vcdCluster.Name, err))
+			return ctrl.Result{}, fmt.Errorf("failed to reserve the control plane endpoint of cluster [%s]: [%v]",
+				vcdCluster.Name, err)
+		}
+		vcdCluster.Spec.ControlPlaneEndpoint.Host = address
+	} else if err := validateVirtualIPControlPlaneEndpoint(vcdCluster); err != nil {
+		return ctrl.Result{}, err
+	}
+	log.Info(fmt.Sprintf("Control plane endpoint for the cluster is the kube-vip virtual IP [%s]",
+		vcdCluster.Spec.ControlPlaneEndpoint.Host), "reserved", vcdCluster.Status.ReservedControlPlaneVIP != "")
+
+	if err := capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD,
+		capisdk.LoadBalancerError, "", vcdCluster.Name); err != nil {
+		log.Error(err, "failed to remove LoadBalancerError from RDE", "rdeID", vcdCluster.Status.InfraId)
+	}
+	capvcdRdeManager.AddToEventSet(ctx, capisdk.LoadBalancerAvailable, "", "", "", skipRDEEventUpdates)
+	return ctrl.Result{}, nil
+}
+
+// reserveKubeVipControlPlaneVIP removes an unused address from the static IP pool of the OVDC network of the cluster
+// and records it in VCDClusterStatus.ReservedControlPlaneVIP. Editing the pool requires the rights to edit the network.
+func (r *VCDClusterReconciler) reserveKubeVipControlPlaneVIP(ctx context.Context, vcdCluster *infrav1beta3.VCDCluster,
+	vcdClient *vcdsdk.Client) (string, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	if vcdClient.VDC == nil {
+		return "", fmt.Errorf("no OVDC found in the VCD client")
+	}
+	ovdcNetworkName := getOvdcNetworkName(vcdCluster)
+	if userRights, _ := getUserRights(vcdClient); !userRights.isFeatureEnabled(OptionalFeatureNetworkEdit) {
+		return "", fmt.Errorf("the user lacks the rights to edit OVDC network [%s] to reserve the virtual IP; "+
+			"set the control plane endpoint instead", ovdcNetworkName)
+	}
+	ovdcNetwork, err := vcdClient.VDC.GetOpenApiOrgVdcNetworkByName(ovdcNetworkName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get OVDC network [%s]: [%v]", ovdcNetworkName, err)
+	}
+	address, err := getUnusedPoolIPAddress(vcdClient, ovdcNetwork.OpenApiOrgVdcNetwork)
+	if err != nil {
+		return "", err
+	}
+	if _, err = removeIPAddressFromPool(ovdcNetwork.OpenApiOrgVdcNetwork, address); err != nil {
+		return "", err
+	}
+	if _, err = ovdcNetwork.Update(ovdcNetwork.OpenApiOrgVdcNetwork); err != nil {
+		return "", fmt.Errorf("failed to remove [%s] from the IP pool of OVDC network [%s]: [%v]", address,
+			ovdcNetworkName, err)
+	}
+	vcdCluster.Status.ReservedControlPlaneVIP = address
+	log.Info("Reserved the virtual IP of the control plane endpoint from the IP pool of the OVDC network",
+		"address", address, "ovdcNetwork", ovdcNetworkName)
+	r.recordEvent(vcdCluster, corev1.EventTypeNormal, ControlPlaneVIPReservedReason,
+		fmt.Sprintf("Reserved [%s] from the IP pool of OVDC network [%s] as the control plane endpoint", address,
+			ovdcNetworkName))
+	return address, nil
+}
+
+// releaseKubeVipControlPlaneVIP returns the virtual IP reserved by reserveKubeVipControlPlaneVIP to the static IP pool
+// of the OVDC network of the cluster.
+func (r *VCDClusterReconciler) releaseKubeVipControlPlaneVIP(ctx context.Context, vcdCluster *infrav1beta3.VCDCluster,
+	vcdClient *vcdsdk.Client) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	address := vcdCluster.Status.ReservedControlPlaneVIP
+	if address == "" || vcdClient.VDC == nil {
+		return nil
+	}
+	ovdcNetworkName := getOvdcNetworkName(vcdCluster)
+	ovdcNetwork, err := vcdClient.VDC.GetOpenApiOrgVdcNetworkByName(ovdcNetworkName)
+	if err != nil {
+		return fmt.Errorf("failed to get OVDC network [%s] to release the control plane endpoint [%s]: [%v]",
+			ovdcNetworkName, address, err)
+	}
+	returned, err := returnIPAddressToPool(ovdcNetwork.OpenApiOrgVdcNetwork, address)
+	if err != nil {
+		return fmt.Errorf("failed to release the control plane endpoint [%s]: [%v]", address, err)
+	}
+	if returned {
+		if _, err = ovdcNetwork.Update(ovdcNetwork.OpenApiOrgVdcNetwork); err != nil {
+			return fmt.Errorf("failed to return [%s] to the IP pool of OVDC network [%s]: [%v]", address,
+				ovdcNetworkName, err)
+		}
+		log.Info("Returned the virtual IP of the control plane endpoint to the IP pool of the OVDC network",
+			"address", address, "ovdcNetwork", ovdcNetworkName)
+		r.recordEvent(vcdCluster, corev1.EventTypeNormal, ControlPlaneVIPReleasedReason,
+			fmt.Sprintf("Returned [%s] to the IP pool of OVDC network [%s]", address, ovdcNetworkName))
+	}
+	vcdCluster.Status.ReservedControlPlaneVIP = ""
+	return nil
+}
+
+// getKubeVipManifest returns the static pod manifest of kube-vip announcing the virtual IP of the control plane
+// endpoint of the cluster with ARP, from the control plane machine holding the leader election lease.
+func getKubeVipManifest(vcdCluster *infrav1beta3.VCDCluster) ([]byte, error) {
+	image := vcdCluster.Spec.KubeVip.Image
+	if image == "" {
+		image = DefaultKubeVipImage
+	}
+	port := vcdCluster.Spec.ControlPlaneEndpoint.Port
+	if port == 0 {
+		port = TcpPort
+	}
+	env := []corev1.EnvVar{
+		{Name: "vip_arp", Value: "true"},
+		{Name: "port", Value: strconv.Itoa(port)},
+		{Name: "vip_cidr", Value: "32"},
+		{Name: "cp_enable", Value: "true"},
+		{Name: "cp_namespace", Value: metav1.NamespaceSystem},
+		{Name: "vip_leaderelection", Value: "true"},
+		{Name: "vip_leasename", Value: "plndr-cp-lock"},
+		{Name: "vip_leaseduration", Value: "15"},
+		{Name: "vip_renewdeadline", Value: "10"},
+		{Name: "vip_retryperiod", Value: "2"},
+		{Name: "address", Value: vcdCluster.Spec.ControlPlaneEndpoint.Host},
+	}
+	if vcdCluster.Spec.KubeVip.Interface != "" {
+		env = append(env, corev1.EnvVar{Name: "vip_interface", Value: vcdCluster.Spec.KubeVip.Interface})
+	}
+	hostPathFile := corev1.HostPathFile
+	pod := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Pod",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kube-vip",
+			Namespace: metav1.NamespaceSystem,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:            "kube-vip",
+					Image:           image,
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					Args:            []string{"manager"},
+					Env:             env,
+					SecurityContext: &corev1.SecurityContext{
+						Capabilities: &corev1.Capabilities{
+							Add: []corev1.Capability{"NET_ADMIN", "NET_RAW"},
+						},
+					},
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "kubeconfig", MountPath: kubeVipKubeConfigPath},
+					},
+				},
+			},
+			HostAliases: []corev1.HostAlias{
+				{IP: "127.0.0.1", Hostnames: []string{"kubernetes"}},
+			},
+			HostNetwork: true,
+			Volumes: []corev1.Volume{
+				{
+					Name: "kubeconfig",
+					VolumeSource: corev1.VolumeSource{
+						HostPath: &corev1.HostPathVolumeSource{
+							Path: kubeVipKubeConfigPath,
+							Type: &hostPathFile,
+						},
+					},
+				},
+			},
+		},
+	}
+	manifest, err := yaml.Marshal(pod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the kube-vip manifest of cluster [%s]: [%v]", vcdCluster.Name, err)
+	}
+	return manifest, nil
+}
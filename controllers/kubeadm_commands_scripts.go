@@ -0,0 +1,56 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultScriptRefKey is the key of the script in the Secret of a ScriptRef which does not set its key.
+const DefaultScriptRefKey = "script"
+
+// getScript returns the script referenced by the ScriptRef in the namespace, or nil if there is no ScriptRef.
+func getScript(ctx context.Context, cli client.Client, namespace string, scriptRef *infrav1beta3.ScriptRef) ([]byte,
+	error) {
+
+	if scriptRef == nil {
+		return nil, nil
+	}
+	key := scriptRef.Key
+	if key == "" {
+		key = DefaultScriptRefKey
+	}
+	scriptSecret := &v1.Secret{}
+	if err := cli.Get(ctx, types.NamespacedName{Name: scriptRef.Name, Namespace: namespace}, scriptSecret); err != nil {
+		return nil, fmt.Errorf("error getting script secret [%s] in namespace [%s]: [%v]", scriptRef.Name, namespace,
+			err)
+	}
+	script, ok := scriptSecret.Data[key]
+	if !ok || len(script) == 0 {
+		return nil, fmt.Errorf("script secret [%s] in namespace [%s] has no [%s] key", scriptRef.Name, namespace, key)
+	}
+	return script, nil
+}
+
+// getKubeadmPhases returns the bootstrap phases of the kubeadm command of the machine, preceded and followed by the
+// phases of its pre and post kubeadm commands scripts, if any.
+func getKubeadmPhases(vcdMachine *infrav1beta3.VCDMachine, kubeadmPhase string) []string {
+	phases := make([]string, 0, 3)
+	if vcdMachine.Spec.PreKubeadmCommandsScriptRef != nil {
+		phases = append(phases, PreKubeadmCommands)
+	}
+	phases = append(phases, kubeadmPhase)
+	if vcdMachine.Spec.PostKubeadmCommandsScriptRef != nil {
+		phases = append(phases, PostKubeadmCommands)
+	}
+	return phases
+}
@@ -0,0 +1,148 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	swagger "github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdswaggerclient_36_0"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	HealthMonitorTypeTCP = "TCP"
+
+	PersistenceProfileTypeClientIP = "ClientIP"
+)
+
+// persistenceProfileTypes maps the persistence profile types of a VCDCluster to the VCD persistence profile types.
+var persistenceProfileTypes = map[string]string{
+	PersistenceProfileTypeClientIP: "CLIENT_IP",
+}
+
+// getLoadBalancerPoolSettings returns the health monitors and the persistence profile of the load balancer pool of the
+// control plane endpoint of the cluster.
+func getLoadBalancerPoolSettings(
+	vcdCluster *infrav1beta3.VCDCluster) ([]swagger.EdgeLoadBalancerHealthMonitor,
+	*swagger.EdgeLoadBalancerPersistenceProfile, error) {
+
+	lbConfig := vcdCluster.Spec.LoadBalancerConfigSpec
+	healthMonitorType := HealthMonitorTypeTCP
+	if lbConfig.HealthMonitor != nil && lbConfig.HealthMonitor.Type != "" {
+		healthMonitorType = lbConfig.HealthMonitor.Type
+	}
+	healthMonitors := []swagger.EdgeLoadBalancerHealthMonitor{{Type_: healthMonitorType}}
+
+	if lbConfig.PersistenceProfile == nil {
+		return healthMonitors, nil, nil
+	}
+	persistenceProfileType := lbConfig.PersistenceProfile.Type
+	if persistenceProfileType == "" {
+		persistenceProfileType = PersistenceProfileTypeClientIP
+	}
+	vcdPersistenceProfileType, ok := persistenceProfileTypes[persistenceProfileType]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported persistence profile type [%s]", persistenceProfileType)
+	}
+	return healthMonitors, &swagger.EdgeLoadBalancerPersistenceProfile{Type_: vcdPersistenceProfileType}, nil
+}
+
+// hasLoadBalancerPoolSettings checks if the load balancer pool has the health monitors and persistence profile. The
+// names of the monitors and profiles, which are set by VCD, are ignored.
+func hasLoadBalancerPoolSettings(lbPool *swagger.EdgeLoadBalancerPool,
+	healthMonitors []swagger.EdgeLoadBalancerHealthMonitor,
+	persistenceProfile *swagger.EdgeLoadBalancerPersistenceProfile) bool {
+
+	poolHealthMonitorTypes := make([]string, 0, len(lbPool.HealthMonitors))
+	for _, healthMonitor := range lbPool.HealthMonitors {
+		poolHealthMonitorTypes = append(poolHealthMonitorTypes, healthMonitor.Type_)
+	}
+	healthMonitorTypes := make([]string, 0, len(healthMonitors))
+	for _, healthMonitor := range healthMonitors {
+		healthMonitorTypes = append(healthMonitorTypes, healthMonitor.Type_)
+	}
+	if !reflect.DeepEqual(poolHealthMonitorTypes, healthMonitorTypes) {
+		return false
+	}
+	if lbPool.PersistenceProfile == nil || persistenceProfile == nil {
+		return lbPool.PersistenceProfile == nil && persistenceProfile == nil
+	}
+	return lbPool.PersistenceProfile.Type_ == persistenceProfile.Type_
+}
+
+// reconcileLoadBalancerPoolSettings applies the health monitor and the persistence profile of the VCDCluster to the
+// load balancer pool of the control plane endpoint. The pool is created, and its members are updated, with a TCP
+// health monitor and no persistence profile, so the settings are applied again after each change of the pool.
+func reconcileLoadBalancerPoolSettings(ctx context.Context, vcdClient *vcdsdk.Client,
+	gateway *vcdsdk.GatewayManager, vcdCluster *infrav1beta3.VCDCluster) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	healthMonitors, persistenceProfile, err := getLoadBalancerPoolSettings(vcdCluster)
+	if err != nil {
+		return err
+	}
+
+	lbPoolName := capisdk.GetLoadBalancerPoolNameUsingPrefix(
+		capisdk.GetLoadBalancerPoolNamePrefix(vcdCluster.Name, vcdCluster.Status.InfraId), "tcp")
+	lbPoolRef, err := gateway.GetLoadBalancerPool(ctx, lbPoolName)
+	if err == govcd.ErrorEntityNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get load balancer pool [%s]: [%v]", lbPoolName, err)
+	}
+
+	org, err := vcdClient.VCDClient.GetOrgByName(vcdClient.ClusterOrgName)
+	if err != nil {
+		return fmt.Errorf("error getting org by name for org [%s]: [%v]", vcdClient.ClusterOrgName, err)
+	}
+	if org == nil || org.Org == nil {
+		return fmt.Errorf("obtained nil org when getting org by name [%s]", vcdClient.ClusterOrgName)
+	}
+	lbPool, resp, err := vcdClient.APIClient.EdgeGatewayLoadBalancerPoolApi.GetLoadBalancerPool(ctx, lbPoolRef.Id,
+		org.Org.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get load balancer pool [%s]: [%v]", lbPoolName, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get load balancer pool [%s]; expected http response [%v], obtained [%v]",
+			lbPoolName, http.StatusOK, resp.StatusCode)
+	}
+	if hasLoadBalancerPoolSettings(&lbPool, healthMonitors, persistenceProfile) {
+		return nil
+	}
+
+	lbPool.HealthMonitors = healthMonitors
+	lbPool.PersistenceProfile = persistenceProfile
+	resp, err = vcdClient.APIClient.EdgeGatewayLoadBalancerPoolApi.UpdateLoadBalancerPool(ctx, lbPool, lbPoolRef.Id,
+		org.Org.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update health monitor and persistence profile of load balancer pool [%s]: [%v]",
+			lbPoolName, err)
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to update load balancer pool [%s]; expected http response [%v], obtained [%v]",
+			lbPoolName, http.StatusAccepted, resp.StatusCode)
+	}
+	taskURL := resp.Header.Get(VCDLocationHeader)
+	task := govcd.NewTask(&vcdClient.VCDClient.Client)
+	task.Task.HREF = taskURL
+	if err = task.WaitTaskCompletion(); err != nil {
+		return fmt.Errorf("failed to update load balancer pool [%s]; update task [%s] did not complete: [%v]",
+			lbPoolName, taskURL, err)
+	}
+	log.Info("Updated the health monitor and persistence profile of the load balancer pool", "lbPool", lbPoolName,
+		"healthMonitor", healthMonitors[0].Type_, "persistenceProfile", persistenceProfile != nil)
+	return nil
+}
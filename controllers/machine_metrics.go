@@ -0,0 +1,122 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Phases of the VCDMachines reported by the capvcd_machines metric
+const (
+	MachinePhaseProvisioning = "Provisioning"
+	MachinePhaseRunning      = "Running"
+	MachinePhaseFailed       = "Failed"
+	MachinePhaseDeleting     = "Deleting"
+)
+
+var machinePhases = []string{MachinePhaseProvisioning, MachinePhaseRunning, MachinePhaseFailed, MachinePhaseDeleting}
+
+// machinesDesc describes the per-cluster metric reporting the number of VCDMachines in each phase.
+var machinesDesc = prometheus.NewDesc(
+	"capvcd_machines",
+	"Number of VCDMachines of the cluster in each phase, as of their last reconciliation by this controller "+
+		"instance. Failed machines are not provisioned yet and their last reconciliation failed.",
+	[]string{"namespace", "cluster", "phase"},
+	nil,
+)
+
+type trackedMachine struct {
+	cluster string
+	phase   string
+}
+
+// machinePhaseCollector counts the VCDMachines of every cluster per phase when the metrics are scraped. The phase of a
+// VCDMachine is recorded by the VCDMachine controller after each reconciliation.
+type machinePhaseCollector struct {
+	lock     sync.Mutex
+	machines map[types.NamespacedName]trackedMachine
+}
+
+var machinePhaseMetrics = &machinePhaseCollector{
+	machines: make(map[types.NamespacedName]trackedMachine),
+}
+
+func init() {
+	metrics.Registry.MustRegister(machinePhaseMetrics)
+}
+
+func (c *machinePhaseCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- machinesDesc
+}
+
+func (c *machinePhaseCollector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	counts := make(map[types.NamespacedName]map[string]int)
+	for machineKey, tracked := range c.machines {
+		clusterKey := types.NamespacedName{Namespace: machineKey.Namespace, Name: tracked.cluster}
+		if _, ok := counts[clusterKey]; !ok {
+			counts[clusterKey] = make(map[string]int)
+		}
+		counts[clusterKey][tracked.phase]++
+	}
+	// all the phases are reported so that the machines of a cluster leaving a phase bring its count down to zero
+	for clusterKey, phaseCounts := range counts {
+		for _, phase := range machinePhases {
+			ch <- prometheus.MustNewConstMetric(machinesDesc, prometheus.GaugeValue, float64(phaseCounts[phase]),
+				clusterKey.Namespace, clusterKey.Name, phase)
+		}
+	}
+}
+
+// getMachinePhase returns the phase of the VCDMachine after a reconciliation which returned reconcileErr.
+func getMachinePhase(vcdMachine *infrav1beta3.VCDMachine, reconcileErr error) string {
+	readySeverity := conditions.GetSeverity(vcdMachine, clusterv1.ReadyCondition)
+	switch {
+	case !vcdMachine.DeletionTimestamp.IsZero():
+		return MachinePhaseDeleting
+	case vcdMachine.Status.Ready:
+		return MachinePhaseRunning
+	case reconcileErr != nil || (readySeverity != nil && *readySeverity == clusterv1.ConditionSeverityError):
+		return MachinePhaseFailed
+	default:
+		return MachinePhaseProvisioning
+	}
+}
+
+// trackMachinePhase records the phase of the VCDMachine of the cluster after a reconciliation. The VCDMachine is no
+// longer reported once its finalizer is removed.
+func trackMachinePhase(clusterName string, vcdMachine *infrav1beta3.VCDMachine, reconcileErr error) {
+	machineKey := types.NamespacedName{Namespace: vcdMachine.Namespace, Name: vcdMachine.Name}
+	if !vcdMachine.DeletionTimestamp.IsZero() &&
+		!controllerutil.ContainsFinalizer(vcdMachine, infrav1beta3.MachineFinalizer) {
+		untrackMachinePhase(machineKey)
+		return
+	}
+
+	machinePhaseMetrics.lock.Lock()
+	defer machinePhaseMetrics.lock.Unlock()
+	machinePhaseMetrics.machines[machineKey] = trackedMachine{
+		cluster: clusterName,
+		phase:   getMachinePhase(vcdMachine, reconcileErr),
+	}
+}
+
+// untrackMachinePhase stops reporting the VCDMachine once it is deleted.
+func untrackMachinePhase(machineKey types.NamespacedName) {
+	machinePhaseMetrics.lock.Lock()
+	defer machinePhaseMetrics.lock.Unlock()
+	delete(machinePhaseMetrics.machines, machineKey)
+}
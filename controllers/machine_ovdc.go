@@ -0,0 +1,60 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+)
+
+// getMachineOvdc returns the OVDC set in the VCDMachine, looked up by URN or name in the org of the VCD client.
+func getMachineOvdc(vcdClient *vcdsdk.Client, vcdMachine *infrav1beta3.VCDMachine) (*govcd.Vdc, error) {
+	if isVCDUrn(vcdMachine.Spec.Ovdc) {
+		return getOvdcByID(vcdClient, vcdClient.ClusterOrgName, vcdMachine.Spec.Ovdc)
+	}
+	return getOvdcByName(vcdClient, vcdClient.ClusterOrgName, vcdMachine.Spec.Ovdc)
+}
+
+// useMachineOvdc points the VCD client of a reconciliation of the machine at the OVDC set in the VCDMachine, if it is
+// not the OVDC of the cluster, so that the VM of the machine is managed in that OVDC. It returns whether the client
+// was pointed at the OVDC of the machine.
+func useMachineOvdc(vcdClient *vcdsdk.Client, vcdMachine *infrav1beta3.VCDMachine) (bool, error) {
+	if vcdMachine.Spec.Ovdc == "" {
+		return false, nil
+	}
+	ovdc, err := getMachineOvdc(vcdClient, vcdMachine)
+	if err != nil {
+		return false, fmt.Errorf("failed to get OVDC [%s] of machine [%s]: [%v]", vcdMachine.Spec.Ovdc,
+			vcdMachine.Name, err)
+	}
+	if ovdc == nil || ovdc.Vdc == nil {
+		return false, fmt.Errorf("found nil value for OVDC [%s] of machine [%s]", vcdMachine.Spec.Ovdc,
+			vcdMachine.Name)
+	}
+	if vcdClient.VDC != nil && vcdClient.VDC.Vdc != nil && vcdClient.VDC.Vdc.ID == ovdc.Vdc.ID {
+		return false, nil
+	}
+	vcdClient.VDC = ovdc
+	vcdClient.ClusterOVDCName = ovdc.Vdc.Name
+	return true, nil
+}
+
+// getOvdcVAppName returns the name of the vApp of the cluster in the OVDC of a machine which is not the OVDC of the
+// cluster.
+func getOvdcVAppName(vcdCluster *infrav1beta3.VCDCluster, ovdcName string) string {
+	return fmt.Sprintf("%s-%s", CreateFullVAppName(vcdCluster), ovdcName)
+}
+
+// isMachineOvdcVApp checks if the VM of the machine is in the vApp of the cluster in the OVDC set in the VCDMachine.
+func isMachineOvdcVApp(vcdMachine *infrav1beta3.VCDMachine, vcdCluster *infrav1beta3.VCDCluster,
+	ovdcName string) bool {
+
+	return vcdMachine.Spec.Ovdc != "" && vcdMachine.Spec.VAppName == "" &&
+		vcdMachine.Status.VAppName == getOvdcVAppName(vcdCluster, ovdcName)
+}
@@ -0,0 +1,64 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// Annotations overriding the policies of the VCDMachineTemplate for the VM of a single machine, so that the machines of
+// a MachineDeployment can be sized differently without one VCDMachineTemplate per size. They are read from the
+// VCDMachine, then from its Machine, which gets the annotations of the template of its MachineDeployment, and only
+// apply to VMs created after they are set.
+const (
+	SizingPolicyAnnotation    = "capvcd.vmware.com/sizing-policy"
+	PlacementPolicyAnnotation = "capvcd.vmware.com/placement-policy"
+	StorageProfileAnnotation  = "capvcd.vmware.com/storage-profile"
+)
+
+// getMachinePolicyOverride returns the value of the annotation of the VCDMachine, or of its Machine if the VCDMachine
+// does not set it.
+func getMachinePolicyOverride(machine *clusterv1.Machine, vcdMachine *infrav1beta3.VCDMachine,
+	annotation string) string {
+
+	if value := vcdMachine.Annotations[annotation]; value != "" {
+		return value
+	}
+	if machine != nil {
+		return machine.Annotations[annotation]
+	}
+	return ""
+}
+
+// applyMachinePolicyOverrides sets the policies of the VCDMachineSpec overridden by the annotations of the VCDMachine
+// or of its Machine, so that the spec records the policies the VM is created with. It is called before the VM is
+// created, and returns the overrides applied.
+func applyMachinePolicyOverrides(machine *clusterv1.Machine, vcdMachine *infrav1beta3.VCDMachine) []string {
+	var overrides []string
+	for _, override := range []struct {
+		annotation string
+		policy     *string
+	}{
+		{annotation: SizingPolicyAnnotation, policy: &vcdMachine.Spec.SizingPolicy},
+		{annotation: PlacementPolicyAnnotation, policy: &vcdMachine.Spec.PlacementPolicy},
+		{annotation: StorageProfileAnnotation, policy: &vcdMachine.Spec.StorageProfile},
+	} {
+		value := getMachinePolicyOverride(machine, vcdMachine, override.annotation)
+		if value == "" || value == *override.policy {
+			continue
+		}
+		*override.policy = value
+		overrides = append(overrides, fmt.Sprintf("%s=%s", override.annotation, value))
+	}
+	// an overridden sizing policy replaces the CPUs and memory set by the template
+	if vcdMachine.Spec.SizingPolicy != "" {
+		vcdMachine.Spec.NumCPUs, vcdMachine.Spec.CoresPerSocket, vcdMachine.Spec.MemoryMiB = nil, nil, nil
+	}
+	return overrides
+}
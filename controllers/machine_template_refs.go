@@ -0,0 +1,113 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// VCDMachineTemplateKind is the kind of the infrastructure templates of the KCPs and MachineDeployments of CAPVCD.
+const VCDMachineTemplateKind = "VCDMachineTemplate"
+
+// ownedMachineTemplate is a VCDMachineTemplate and the KCP or MachineDeployment referencing it.
+type ownedMachineTemplate struct {
+	owner    string
+	template *infrav1beta3.VCDMachineTemplate
+}
+
+// reconcileMachineTemplateRefs checks the VCDMachineTemplates referenced by the KCPs and MachineDeployments of the
+// cluster, so that a reference to another namespace or to a missing template is reported on the VCDCluster in the
+// MachineTemplatesResolved condition instead of failing deep in the reconciliation of the machines or of the RDE. The
+// infrastructure of the cluster is reconciled regardless. The VCDMachineTemplates found are returned.
+func (r *VCDClusterReconciler) reconcileMachineTemplateRefs(ctx context.Context, cluster *clusterv1.Cluster,
+	vcdCluster *infrav1beta3.VCDCluster) []ownedMachineTemplate {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	kcpList, err := getAllKubeadmControlPlaneForCluster(ctx, r.Client, *cluster)
+	if err != nil {
+		log.Error(err, "failed to list the KCPs to check their VCDMachineTemplate references")
+		return nil
+	}
+	mdList, err := getAllMachineDeploymentsForCluster(ctx, r.Client, *cluster)
+	if err != nil {
+		log.Error(err, "failed to list the MachineDeployments to check their VCDMachineTemplate references")
+		return nil
+	}
+
+	type ownedRef struct {
+		owner string
+		ref   corev1.ObjectReference
+	}
+	refs := make([]ownedRef, 0, len(kcpList.Items)+len(mdList.Items))
+	for _, kcp := range kcpList.Items {
+		refs = append(refs, ownedRef{
+			owner: fmt.Sprintf("KubeadmControlPlane [%s]", kcp.Name),
+			ref:   kcp.Spec.MachineTemplate.InfrastructureRef,
+		})
+	}
+	for _, md := range mdList.Items {
+		refs = append(refs, ownedRef{
+			owner: fmt.Sprintf("MachineDeployment [%s]", md.Name),
+			ref:   md.Spec.Template.Spec.InfrastructureRef,
+		})
+	}
+
+	reason := ""
+	var failures []string
+	machineTemplates := make([]ownedMachineTemplate, 0, len(refs))
+	for _, ownedRef := range refs {
+		if ownedRef.ref.Kind != VCDMachineTemplateKind {
+			continue
+		}
+		vcdMachineTemplateKey, err := getVCDMachineTemplateKey(cluster.Namespace, ownedRef.ref)
+		if err != nil {
+			reason = CrossNamespaceTemplateReferenceReason
+			failures = append(failures, fmt.Sprintf("%s: %v", ownedRef.owner, err))
+			continue
+		}
+		vcdMachineTemplate := &infrav1beta3.VCDMachineTemplate{}
+		if err = r.Client.Get(ctx, vcdMachineTemplateKey, vcdMachineTemplate); err != nil {
+			if !apierrors.IsNotFound(err) {
+				log.Error(err, "failed to get the VCDMachineTemplate", "template", vcdMachineTemplateKey)
+				return machineTemplates
+			}
+			if reason == "" {
+				reason = VCDMachineTemplateNotFoundReason
+			}
+			failures = append(failures, fmt.Sprintf("%s: VCDMachineTemplate [%s] not found", ownedRef.owner,
+				vcdMachineTemplateKey))
+			continue
+		}
+		machineTemplates = append(machineTemplates, ownedMachineTemplate{
+			owner:    ownedRef.owner,
+			template: vcdMachineTemplate,
+		})
+	}
+
+	if len(failures) == 0 {
+		conditions.MarkTrue(vcdCluster, MachineTemplatesResolvedCondition)
+		return machineTemplates
+	}
+	message := strings.Join(failures, "; ")
+	if !conditions.IsFalse(vcdCluster, MachineTemplatesResolvedCondition) ||
+		conditions.GetMessage(vcdCluster, MachineTemplatesResolvedCondition) != message {
+		r.recordEvent(vcdCluster, corev1.EventTypeWarning, reason, message)
+	}
+	log.Info("VCDMachineTemplate references of the cluster cannot be resolved", "failures", message)
+	conditions.MarkFalse(vcdCluster, MachineTemplatesResolvedCondition, reason, clusterv1.ConditionSeverityError,
+		message)
+	return machineTemplates
+}
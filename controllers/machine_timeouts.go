@@ -0,0 +1,188 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// BootstrapMetadata is the guestinfo key of the cloud-init metadata of a VM, which sets its instance-id.
+	BootstrapMetadata = "guestinfo.metadata"
+	// BootstrapMetadataEncoding is the guestinfo key of the encoding of BootstrapMetadata.
+	BootstrapMetadataEncoding = "guestinfo.metadata.encoding"
+)
+
+// isMachineFailed checks if the machine was failed, e.g. because its VM was not provisioned or bootstrapped in time.
+// A failed machine is not reconciled anymore until it is deleted by its owner.
+func isMachineFailed(vcdMachine *infrav1beta3.VCDMachine) bool {
+	return vcdMachine.Status.FailureReason != nil
+}
+
+// failMachine records the terminal failure of the machine in the status of the VCDMachine, from which CAPI fails the
+// Machine so that it is replaced, e.g. by a MachineHealthCheck.
+func (r *VCDMachineReconciler) failMachine(vcdMachine *infrav1beta3.VCDMachine,
+	failureReason capierrors.MachineStatusError, failureMessage string) {
+
+	vcdMachine.Status.FailureReason = &failureReason
+	vcdMachine.Status.FailureMessage = &failureMessage
+	r.recordEvent(vcdMachine, corev1.EventTypeWarning, MachineFailedReason, failureMessage)
+}
+
+// isProvisioningTimedOut checks if the VM of the machine was not provisioned within the provisioning timeout of the
+// machine. The timeout no longer applies once the VM was powered on with its bootstrap data.
+func isProvisioningTimedOut(vcdMachine *infrav1beta3.VCDMachine) bool {
+	if vcdMachine.Spec.ProvisioningTimeout == nil || vcdMachine.Status.BootstrapStartTime != nil ||
+		conditions.IsTrue(vcdMachine, VMProvisionedCondition) {
+		return false
+	}
+	return time.Since(vcdMachine.CreationTimestamp.Time) > vcdMachine.Spec.ProvisioningTimeout.Duration
+}
+
+// failProvisioningTimedOutMachine fails the machine whose VM was not provisioned within its provisioning timeout.
+func (r *VCDMachineReconciler) failProvisioningTimedOutMachine(vcdMachine *infrav1beta3.VCDMachine) {
+	message := fmt.Sprintf("VM of machine [%s] was not provisioned within [%s]", vcdMachine.Name,
+		vcdMachine.Spec.ProvisioningTimeout.Duration)
+	conditions.MarkFalse(vcdMachine, VMProvisionedCondition, VMProvisioningTimedOutReason,
+		clusterv1.ConditionSeverityError, "%s", message)
+	r.failMachine(vcdMachine, capierrors.CreateMachineError, message)
+}
+
+// setBootstrapStartTime records when the VM of the machine was first seen powered on with its bootstrap data.
+func setBootstrapStartTime(vcdMachine *infrav1beta3.VCDMachine) {
+	if vcdMachine.Status.BootstrapStartTime == nil {
+		bootstrapStartTime := metav1.Now()
+		vcdMachine.Status.BootstrapStartTime = &bootstrapStartTime
+	}
+}
+
+// getBootstrapDeadline returns when the bootstrap of the VM of the machine times out, or the zero time if it does not.
+func getBootstrapDeadline(vcdMachine *infrav1beta3.VCDMachine) time.Time {
+	if vcdMachine.Spec.BootstrapTimeout == nil || vcdMachine.Status.BootstrapStartTime == nil ||
+		isIgnitionBootstrap(vcdMachine) {
+		return time.Time{}
+	}
+	return vcdMachine.Status.BootstrapStartTime.Add(vcdMachine.Spec.BootstrapTimeout.Duration)
+}
+
+// isBootstrapTimedOut checks if the VM of the machine was not bootstrapped within the bootstrap timeout of the
+// machine.
+func isBootstrapTimedOut(vcdMachine *infrav1beta3.VCDMachine) bool {
+	deadline := getBootstrapDeadline(vcdMachine)
+	return !deadline.IsZero() && time.Now().After(deadline)
+}
+
+// isBootstrapRetried checks if the bootstrap of the VM of the machine is being retried in place.
+func isBootstrapRetried(vcdMachine *infrav1beta3.VCDMachine) bool {
+	return vcdMachine.Status.BootstrapRetries > 0 && !vcdMachine.Spec.Bootstrapped
+}
+
+// getBootstrapRetryGuestInfo returns the guestinfo keys giving the VM of a machine whose bootstrap is retried a new
+// cloud-init instance-id, so that cloud-init runs its per-instance modules, including the bootstrap script, again
+// rather than considering the boot a reboot of the instance which failed. The network configuration of the VM is not
+// part of the metadata either way.
+func getBootstrapRetryGuestInfo(vm *govcd.VM, vcdMachine *infrav1beta3.VCDMachine) map[string]string {
+	if !isBootstrapRetried(vcdMachine) || isIgnitionBootstrap(vcdMachine) {
+		return nil
+	}
+	metadata := fmt.Sprintf("instance-id: %s-retry-%d\n", vm.VM.ID, vcdMachine.Status.BootstrapRetries)
+	return map[string]string{
+		BootstrapMetadata:         b64.StdEncoding.EncodeToString([]byte(metadata)),
+		BootstrapMetadataEncoding: "base64",
+	}
+}
+
+// powerOnVMWithCustomization undeploys the VM if needed and powers it on forcing its guest customization to run again.
+func powerOnVMWithCustomization(vm *govcd.VM) error {
+	deployed, err := vm.IsDeployed()
+	if err != nil {
+		return fmt.Errorf("failed to check if VM [%s] is deployed: [%v]", vm.VM.Name, err)
+	}
+	if deployed {
+		if err = undeployVM(vm); err != nil {
+			return err
+		}
+	}
+	if err = vm.PowerOnAndForceCustomization(); err != nil {
+		return fmt.Errorf("failed to power on VM [%s] with a new guest customization: [%v]", vm.VM.Name, err)
+	}
+	if err = vm.Refresh(); err != nil {
+		return fmt.Errorf("failed to refresh VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	return nil
+}
+
+// resetBootstrapGuestInfo powers off and undeploys the VM and clears the statuses its bootstrap reported in its
+// guestinfo, so that the next power on, with a new guest customization and cloud-init instance-id, runs the cloud-init
+// script of the VM again and reports the progress of the new attempt.
+func resetBootstrapGuestInfo(vdcManager *vcdsdk.VdcManager, vm *govcd.VM, phases []string) error {
+	deployed, err := vm.IsDeployed()
+	if err != nil {
+		return fmt.Errorf("failed to check if VM [%s] is deployed: [%v]", vm.VM.Name, err)
+	}
+	if deployed {
+		if err = undeployVM(vm); err != nil {
+			return err
+		}
+	}
+	// an empty value removes the key from the guestinfo of the VM
+	keys := append([]string{PostCustomizationScriptExecutionStatus, PostCustomizationScriptFailureReason,
+		PostCustomizationCloudInitOutput}, phases...)
+	for _, key := range keys {
+		if err = vdcManager.SetVmExtraConfigKeyValue(vm, key, "", false); err != nil {
+			return fmt.Errorf("failed to clear the extra config key [%s] of VM [%s]: [%v]", key, vm.VM.Name, err)
+		}
+	}
+	if err = vm.Refresh(); err != nil {
+		return fmt.Errorf("failed to refresh VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	return nil
+}
+
+// reconcileBootstrapTimeout retries the bootstrap of the VM of the machine in place, by power cycling the VM, as long
+// as the machine has retries left, and fails the machine otherwise.
+func (r *VCDMachineReconciler) reconcileBootstrapTimeout(ctx context.Context, vdcManager *vcdsdk.VdcManager,
+	vm *govcd.VM, vcdMachine *infrav1beta3.VCDMachine, phases []string) (ctrl.Result, error) {
+
+	log := ctrl.LoggerFrom(ctx, "vmName", vm.VM.Name)
+
+	message := fmt.Sprintf("VM [%s] was not bootstrapped within [%s]", vm.VM.Name,
+		vcdMachine.Spec.BootstrapTimeout.Duration)
+	if vcdMachine.Status.BootstrapRetries >= vcdMachine.Spec.BootstrapRetries {
+		log.Info("Failing the machine whose bootstrap timed out", "retries", vcdMachine.Status.BootstrapRetries)
+		conditions.MarkFalse(vcdMachine, BootstrapExecSucceededCondition, BootstrapTimedOutReason,
+			clusterv1.ConditionSeverityError, "%s", message)
+		r.failMachine(vcdMachine, capierrors.JoinClusterTimeoutMachineError, message)
+		return ctrl.Result{}, nil
+	}
+
+	if err := resetBootstrapGuestInfo(vdcManager, vm, phases); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to retry the bootstrap of VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	vcdMachine.Status.BootstrapRetries++
+	vcdMachine.Status.BootstrapStartTime = nil
+	message = fmt.Sprintf("%s; retrying its bootstrap (%d/%d)", message, vcdMachine.Status.BootstrapRetries,
+		vcdMachine.Spec.BootstrapRetries)
+	log.Info("Retrying the bootstrap of the VM", "retry", vcdMachine.Status.BootstrapRetries)
+	conditions.MarkFalse(vcdMachine, BootstrapExecSucceededCondition, BootstrapTimedOutReason,
+		clusterv1.ConditionSeverityWarning, "%s", message)
+	r.recordEvent(vcdMachine, corev1.EventTypeWarning, BootstrapRetriedReason, message)
+	// the VM is powered on again with its bootstrap data by the next reconciliation
+	return ctrl.Result{Requeue: true}, nil
+}
@@ -0,0 +1,157 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	b64 "encoding/base64"
+	"testing"
+	"time"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+// newTestVCDMachine returns a VCDMachine created at the time, with the timeouts, whose bootstrap started at the
+// bootstrap start time unless it is zero.
+func newTestVCDMachine(creationTime time.Time, provisioningTimeout time.Duration, bootstrapTimeout time.Duration,
+	bootstrapStartTime time.Time) *infrav1beta3.VCDMachine {
+
+	vcdMachine := &infrav1beta3.VCDMachine{
+		ObjectMeta: metav1.ObjectMeta{Name: "machine", CreationTimestamp: metav1.NewTime(creationTime)},
+	}
+	if provisioningTimeout != 0 {
+		vcdMachine.Spec.ProvisioningTimeout = &metav1.Duration{Duration: provisioningTimeout}
+	}
+	if bootstrapTimeout != 0 {
+		vcdMachine.Spec.BootstrapTimeout = &metav1.Duration{Duration: bootstrapTimeout}
+	}
+	if !bootstrapStartTime.IsZero() {
+		startTime := metav1.NewTime(bootstrapStartTime)
+		vcdMachine.Status.BootstrapStartTime = &startTime
+	}
+	return vcdMachine
+}
+
+func TestIsProvisioningTimedOut(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name       string
+		vcdMachine *infrav1beta3.VCDMachine
+		want       bool
+	}{
+		{name: "no provisioning timeout", vcdMachine: newTestVCDMachine(now.Add(-time.Hour), 0, 0, time.Time{})},
+		{name: "within the provisioning timeout",
+			vcdMachine: newTestVCDMachine(now.Add(-time.Minute), 10*time.Minute, 0, time.Time{})},
+		{name: "past the provisioning timeout",
+			vcdMachine: newTestVCDMachine(now.Add(-time.Hour), 10*time.Minute, 0, time.Time{}), want: true},
+		{name: "bootstrap started",
+			vcdMachine: newTestVCDMachine(now.Add(-time.Hour), 10*time.Minute, 0, now.Add(-50*time.Minute))},
+		{name: "VM provisioned", vcdMachine: func() *infrav1beta3.VCDMachine {
+			vcdMachine := newTestVCDMachine(now.Add(-time.Hour), 10*time.Minute, 0, time.Time{})
+			conditions.MarkTrue(vcdMachine, VMProvisionedCondition)
+			return vcdMachine
+		}()},
+		{name: "VM not provisioned", vcdMachine: func() *infrav1beta3.VCDMachine {
+			vcdMachine := newTestVCDMachine(now.Add(-time.Hour), 10*time.Minute, 0, time.Time{})
+			conditions.MarkFalse(vcdMachine, VMProvisionedCondition, VMProvisioningTimedOutReason,
+				clusterv1.ConditionSeverityWarning, "")
+			return vcdMachine
+		}(), want: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isProvisioningTimedOut(tc.vcdMachine); got != tc.want {
+				t.Errorf("got [%t], want [%t]", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetBootstrapDeadline(t *testing.T) {
+	now := time.Now()
+	startTime := now.Add(-5 * time.Minute)
+
+	testCases := []struct {
+		name         string
+		vcdMachine   *infrav1beta3.VCDMachine
+		want         time.Time
+		wantTimedOut bool
+	}{
+		{name: "no bootstrap timeout", vcdMachine: newTestVCDMachine(now, 0, 0, startTime)},
+		{name: "bootstrap not started", vcdMachine: newTestVCDMachine(now, 0, time.Minute, time.Time{})},
+		{name: "within the bootstrap timeout", vcdMachine: newTestVCDMachine(now, 0, time.Hour, startTime),
+			want: startTime.Add(time.Hour)},
+		{name: "past the bootstrap timeout", vcdMachine: newTestVCDMachine(now, 0, time.Minute, startTime),
+			want: startTime.Add(time.Minute), wantTimedOut: true},
+		{name: "ignition bootstrap", vcdMachine: func() *infrav1beta3.VCDMachine {
+			vcdMachine := newTestVCDMachine(now, 0, time.Minute, startTime)
+			vcdMachine.Spec.BootstrapFormat = "ignition"
+			return vcdMachine
+		}()},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := getBootstrapDeadline(tc.vcdMachine); !got.Equal(tc.want) {
+				t.Errorf("got deadline [%v], want [%v]", got, tc.want)
+			}
+			if got := isBootstrapTimedOut(tc.vcdMachine); got != tc.wantTimedOut {
+				t.Errorf("got timed out [%t], want [%t]", got, tc.wantTimedOut)
+			}
+		})
+	}
+}
+
+func TestGetBootstrapRetryGuestInfo(t *testing.T) {
+	vm := &govcd.VM{VM: &types.Vm{ID: "urn:vcloud:vm:1234"}}
+
+	testCases := []struct {
+		name             string
+		bootstrapRetries int32
+		bootstrapped     bool
+		bootstrapFormat  string
+		wantMetadata     string
+	}{
+		{name: "bootstrap not retried"},
+		{name: "first retry", bootstrapRetries: 1, wantMetadata: "instance-id: urn:vcloud:vm:1234-retry-1\n"},
+		{name: "second retry", bootstrapRetries: 2, wantMetadata: "instance-id: urn:vcloud:vm:1234-retry-2\n"},
+		{name: "bootstrapped after a retry", bootstrapRetries: 1, bootstrapped: true},
+		{name: "ignition bootstrap", bootstrapRetries: 1, bootstrapFormat: "ignition"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vcdMachine := &infrav1beta3.VCDMachine{}
+			vcdMachine.Status.BootstrapRetries = tc.bootstrapRetries
+			vcdMachine.Spec.Bootstrapped = tc.bootstrapped
+			vcdMachine.Spec.BootstrapFormat = tc.bootstrapFormat
+
+			if got := isBootstrapRetried(vcdMachine); got != (tc.bootstrapRetries > 0 && !tc.bootstrapped) {
+				t.Errorf("got bootstrap retried [%t]", got)
+			}
+			guestInfo := getBootstrapRetryGuestInfo(vm, vcdMachine)
+			if tc.wantMetadata == "" {
+				if guestInfo != nil {
+					t.Errorf("got guestinfo %v, want none", guestInfo)
+				}
+				return
+			}
+			if guestInfo[BootstrapMetadataEncoding] != "base64" {
+				t.Errorf("got metadata encoding [%s], want [base64]", guestInfo[BootstrapMetadataEncoding])
+			}
+			metadata, err := b64.StdEncoding.DecodeString(guestInfo[BootstrapMetadata])
+			if err != nil {
+				t.Fatalf("unable to decode metadata [%s]: [%v]", guestInfo[BootstrapMetadata], err)
+			}
+			if string(metadata) != tc.wantMetadata {
+				t.Errorf("got metadata [%s], want [%s]", metadata, tc.wantMetadata)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	"github.com/vmware/cluster-api-provider-cloud-director/release"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// VAppOwnershipManaged is the ownership of a vApp created, tagged and deleted by CAPVCD.
+	VAppOwnershipManaged = "Managed"
+	// VAppOwnershipUnmanaged is the ownership of a pre-existing vApp CAPVCD only creates and deletes VMs in.
+	VAppOwnershipUnmanaged = "Unmanaged"
+)
+
+// getMachineVAppName returns the name of the vApp the VM of the machine is created in: the vApp set in the VCDMachine,
+// else the vApp it was placed in by the vApp strategy of the cluster or in the OVDC of the machine, else the vApp of
+// the cluster.
+func getMachineVAppName(vcdMachine *infrav1beta3.VCDMachine, vcdCluster *infrav1beta3.VCDCluster) string {
+	if vcdMachine.Spec.VAppName != "" {
+		return vcdMachine.Spec.VAppName
+	}
+	if vcdMachine.Status.VAppName != "" {
+		return vcdMachine.Status.VAppName
+	}
+	return CreateFullVAppName(vcdCluster)
+}
+
+// isMachineVAppUnmanaged checks if the VM of the machine is created in a pre-existing vApp not managed by CAPVCD.
+func isMachineVAppUnmanaged(vcdMachine *infrav1beta3.VCDMachine) bool {
+	return vcdMachine.Spec.VAppName != "" && vcdMachine.Spec.VAppOwnership == VAppOwnershipUnmanaged
+}
+
+// isVAppConnectedToNetwork checks if the OVDC network is connected to the vApp.
+func isVAppConnectedToNetwork(vApp *govcd.VApp, ovdcNetworkName string) bool {
+	if vApp.VApp.NetworkConfigSection == nil {
+		return false
+	}
+	for _, vAppNetworkName := range vApp.VApp.NetworkConfigSection.NetworkNames() {
+		if vAppNetworkName == ovdcNetworkName {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileMachineVApp ensures that the vApp set in the VCDMachine, or of the vApp strategy of the cluster, exists. A
+// Managed vApp is created and tagged with the infra ID of the cluster if it does not exist, and an existing Managed
+// vApp must be tagged with it, so that a vApp of another cluster or created outside of CAPVCD is never deleted with
+// the machines of the cluster. An Unmanaged vApp must exist and be connected to the OVDC network of the cluster.
+func reconcileMachineVApp(ctx context.Context, vcdClient *vcdsdk.Client, vdcManager *vcdsdk.VdcManager,
+	vcdMachine *infrav1beta3.VCDMachine, vcdCluster *infrav1beta3.VCDCluster, ovdcNetworkName string) error {
+
+	log := ctrl.LoggerFrom(ctx)
+	vAppName := getMachineVAppName(vcdMachine, vcdCluster)
+
+	vApp, err := vdcManager.Vdc.GetVAppByName(vAppName, true)
+	if err != nil && err != govcd.ErrorEntityNotFound {
+		return fmt.Errorf("failed to get vApp [%s]: [%v]", vAppName, err)
+	}
+
+	if isMachineVAppUnmanaged(vcdMachine) {
+		if err == govcd.ErrorEntityNotFound {
+			return fmt.Errorf("unmanaged vApp [%s] of machine [%s] does not exist in OVDC [%s]", vAppName,
+				vcdMachine.Name, vdcManager.Client.ClusterOVDCName)
+		}
+		if !isVAppConnectedToNetwork(vApp, ovdcNetworkName) {
+			return fmt.Errorf("unmanaged vApp [%s] of machine [%s] is not connected to the OVDC network [%s]",
+				vAppName, vcdMachine.Name, ovdcNetworkName)
+		}
+		return nil
+	}
+
+	if err == nil {
+		infraID, err := vdcManager.GetMetadataByKey(vApp, CapvcdInfraId)
+		if err != nil {
+			return fmt.Errorf("failed to get metadata [%s] of vApp [%s]: [%v]", CapvcdInfraId, vAppName, err)
+		}
+		if infraID != vcdCluster.Status.InfraId {
+			return fmt.Errorf("managed vApp [%s] of machine [%s] exists and is not managed by cluster [%s]; set vAppOwnership to [%s] to use it",
+				vAppName, vcdMachine.Name, vcdCluster.Name, VAppOwnershipUnmanaged)
+		}
+		return nil
+	}
+
+	log.Info("Creating the vApp of the machine", "vAppName", vAppName)
+	vApp, err = vdcManager.GetOrCreateVApp(vAppName, ovdcNetworkName)
+	if err != nil {
+		return fmt.Errorf("failed to create vApp [%s] of machine [%s]: [%v]", vAppName, vcdMachine.Name, err)
+	}
+	if err = vdcManager.AddMetadataToVApp(vAppName, map[string]string{
+		CapvcdInfraId: vcdCluster.Status.InfraId,
+	}); err != nil {
+		return fmt.Errorf("unable to add metadata [%s] to vApp [%s]: [%v]", CapvcdInfraId, vAppName, err)
+	}
+	rdeManager := vcdsdk.NewRDEManager(vcdClient, vcdCluster.Status.InfraId, capisdk.StatusComponentNameCAPVCD,
+		release.Version)
+	if err = rdeManager.AddToVCDResourceSet(ctx, vcdsdk.ComponentCAPVCD, VCDResourceVApp, vAppName,
+		vApp.VApp.ID, nil); err != nil {
+		log.Error(err, "failed to add the vApp of the machine to the VCDResourceSet of the RDE", "vAppName", vAppName)
+	}
+	return nil
+}
+
+// deleteEmptyMachineVApp deletes the Managed vApp set in the VCDMachine, or the vApp of the cluster in the OVDC set in
+// the VCDMachine, once the VM of the last machine in it is deleted. The vApp of the cluster and the vApps of its vApp
+// strategy are deleted by the VCDCluster controller instead.
+func deleteEmptyMachineVApp(ctx context.Context, vcdClient *vcdsdk.Client, vdcManager *vcdsdk.VdcManager,
+	vApp *govcd.VApp, vcdMachine *infrav1beta3.VCDMachine, vcdCluster *infrav1beta3.VCDCluster) error {
+
+	log := ctrl.LoggerFrom(ctx)
+	ovdcVApp := isMachineOvdcVApp(vcdMachine, vcdCluster, vdcManager.Vdc.Vdc.Name)
+	if !ovdcVApp && (vcdMachine.Spec.VAppName == "" || vcdMachine.Spec.VAppName == CreateFullVAppName(vcdCluster) ||
+		isMachineVAppUnmanaged(vcdMachine)) {
+		return nil
+	}
+	vAppName := vApp.VApp.Name
+	if err := vApp.Refresh(); err != nil {
+		return fmt.Errorf("failed to refresh vApp [%s]: [%v]", vAppName, err)
+	}
+	if vApp.VApp.Children != nil && len(vApp.VApp.Children.VM) > 0 {
+		return nil
+	}
+	log.Info("Deleting the empty vApp of the machine", "vAppName", vAppName)
+	if err := vdcManager.DeleteVApp(vAppName); err != nil && err != govcd.ErrorEntityNotFound {
+		return fmt.Errorf("failed to delete vApp [%s]: [%v]", vAppName, err)
+	}
+	rdeManager := vcdsdk.NewRDEManager(vcdClient, vcdCluster.Status.InfraId, capisdk.StatusComponentNameCAPVCD,
+		release.Version)
+	if err := rdeManager.RemoveFromVCDResourceSet(ctx, vcdsdk.ComponentCAPVCD, VCDResourceVApp,
+		vAppName); err != nil {
+		log.Error(err, "failed to remove the vApp of the machine from the VCDResourceSet of the RDE",
+			"vAppName", vAppName)
+	}
+	return nil
+}
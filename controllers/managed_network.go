@@ -0,0 +1,161 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// isManagedNetworkPending checks if the OVDC network of the cluster is to be created by CAPVCD and is not resolved
+// yet, in which case it may not exist until the VCDCluster controller creates it.
+func isManagedNetworkPending(vcdCluster *infrav1beta3.VCDCluster) bool {
+	return vcdCluster.Spec.ManagedNetwork != nil && !isReferenceResolved(vcdCluster.Status.ResolvedReferences,
+		ResourceTypeOvdcNetwork, vcdCluster.Spec.OvdcNetwork)
+}
+
+// getManagedNetworkConfig returns the configuration of the OVDC network to create for the cluster, connected to the
+// edge gateway if one is set.
+func getManagedNetworkConfig(vcdClient *vcdsdk.Client,
+	vcdCluster *infrav1beta3.VCDCluster) (*types.OpenApiOrgVdcNetwork, error) {
+
+	managedNetwork := vcdCluster.Spec.ManagedNetwork
+	gatewayCIDR, err := netip.ParsePrefix(managedNetwork.GatewayCIDR)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gateway CIDR [%s]: [%v]", managedNetwork.GatewayCIDR, err)
+	}
+	subnet := types.OrgVdcNetworkSubnetValues{
+		Gateway:      gatewayCIDR.Addr().String(),
+		PrefixLength: gatewayCIDR.Bits(),
+		DNSSuffix:    managedNetwork.DNSSuffix,
+	}
+	if len(managedNetwork.DNSServers) > 0 {
+		subnet.DNSServer1 = managedNetwork.DNSServers[0]
+	}
+	if len(managedNetwork.DNSServers) > 1 {
+		subnet.DNSServer2 = managedNetwork.DNSServers[1]
+	}
+	for _, ipRange := range managedNetwork.StaticIPPool {
+		startIP, startErr := netip.ParseAddr(ipRange.StartAddress)
+		endIP, endErr := netip.ParseAddr(ipRange.EndAddress)
+		if startErr != nil || endErr != nil || !gatewayCIDR.Contains(startIP) || !gatewayCIDR.Contains(endIP) {
+			return nil, fmt.Errorf("IP range [%s-%s] of the static IP pool is not in subnet [%s]",
+				ipRange.StartAddress, ipRange.EndAddress, gatewayCIDR.Masked().String())
+		}
+		subnet.IPRanges.Values = append(subnet.IPRanges.Values, types.OrgVdcNetworkSubnetIPRangeValues{
+			StartAddress: ipRange.StartAddress,
+			EndAddress:   ipRange.EndAddress,
+		})
+	}
+
+	networkConfig := &types.OpenApiOrgVdcNetwork{
+		Name:        vcdCluster.Spec.OvdcNetwork,
+		Description: fmt.Sprintf("Network of cluster [%s] created by CAPVCD", vcdCluster.Name),
+		OwnerRef:    &types.OpenApiReference{ID: vcdClient.VDC.Vdc.ID},
+		NetworkType: types.OrgVdcNetworkTypeIsolated,
+		Subnets:     types.OrgVdcNetworkSubnets{Values: []types.OrgVdcNetworkSubnetValues{subnet}},
+	}
+	if managedNetwork.EdgeGateway != "" {
+		edgeGateway, err := vcdClient.VDC.GetNsxtEdgeGatewayByName(managedNetwork.EdgeGateway)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get edge gateway [%s]: [%v]", managedNetwork.EdgeGateway, err)
+		}
+		networkConfig.NetworkType = types.OrgVdcNetworkTypeRouted
+		networkConfig.Connection = &types.Connection{
+			RouterRef:      types.OpenApiReference{ID: edgeGateway.EdgeGateway.ID},
+			ConnectionType: "INTERNAL",
+		}
+	}
+	return networkConfig, nil
+}
+
+// reconcileManagedNetwork creates the OVDC network of VCDClusterSpec.ManagedNetwork if it does not exist, records it
+// in the VcdResourceMap of the cluster to delete it with the cluster, and resolves the reference to the network.
+func (r *VCDClusterReconciler) reconcileManagedNetwork(ctx context.Context, vcdClient *vcdsdk.Client,
+	vcdCluster *infrav1beta3.VCDCluster) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	if !isManagedNetworkPending(vcdCluster) {
+		return nil
+	}
+	if isVCDUrn(vcdCluster.Spec.OvdcNetwork) {
+		return fmt.Errorf("OVDC network [%s] must be referenced by name to be created by CAPVCD",
+			vcdCluster.Spec.OvdcNetwork)
+	}
+	if vcdClient.VDC == nil || vcdClient.VDC.Vdc == nil {
+		return fmt.Errorf("no OVDC found in the VCD client to create OVDC network [%s]", vcdCluster.Spec.OvdcNetwork)
+	}
+
+	_, err := vcdClient.VDC.GetOpenApiOrgVdcNetworkByName(vcdCluster.Spec.OvdcNetwork)
+	if err != nil && !govcd.ContainsNotFound(err) {
+		return fmt.Errorf("failed to get OVDC network [%s]: [%v]", vcdCluster.Spec.OvdcNetwork, err)
+	}
+	if err == nil {
+		log.Info("Using the existing OVDC network of the cluster", "ovdcNetwork", vcdCluster.Spec.OvdcNetwork)
+		return resolveOvdcNetworkReference(vcdClient, vcdCluster)
+	}
+
+	networkConfig, err := getManagedNetworkConfig(vcdClient, vcdCluster)
+	if err != nil {
+		return fmt.Errorf("invalid configuration of OVDC network [%s]: [%v]", vcdCluster.Spec.OvdcNetwork, err)
+	}
+	ovdcNetwork, err := vcdClient.VDC.CreateOpenApiOrgVdcNetwork(networkConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create OVDC network [%s]: [%v]", vcdCluster.Spec.OvdcNetwork, err)
+	}
+	if err = updateVdcResourceToVcdCluster(vcdCluster, ResourceTypeOvdcNetwork, ovdcNetwork.OpenApiOrgVdcNetwork.ID,
+		ovdcNetwork.OpenApiOrgVdcNetwork.Name); err != nil {
+		return fmt.Errorf("failed to record OVDC network [%s] in the resource map of the cluster: [%v]",
+			ovdcNetwork.OpenApiOrgVdcNetwork.Name, err)
+	}
+	log.Info("Created the OVDC network of the cluster", "ovdcNetwork", ovdcNetwork.OpenApiOrgVdcNetwork.Name,
+		"networkType", networkConfig.NetworkType, "gatewayCIDR", vcdCluster.Spec.ManagedNetwork.GatewayCIDR)
+	r.recordEvent(vcdCluster, corev1.EventTypeNormal, ManagedNetworkCreatedReason,
+		fmt.Sprintf("Created OVDC network [%s] with gateway [%s]", ovdcNetwork.OpenApiOrgVdcNetwork.Name,
+			vcdCluster.Spec.ManagedNetwork.GatewayCIDR))
+	return resolveOvdcNetworkReference(vcdClient, vcdCluster)
+}
+
+// deleteManagedNetworks deletes the OVDC networks created for the cluster, once the vApps of the cluster are deleted.
+func deleteManagedNetworks(ctx context.Context, vcdClient *vcdsdk.Client, vcdCluster *infrav1beta3.VCDCluster) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	trackedNetworks := append(infrav1beta3.VCDResources{}, vcdCluster.Status.VcdResourceMap.OvdcNetworks...)
+	if len(trackedNetworks) == 0 {
+		return nil
+	}
+	if vcdClient.VDC == nil {
+		return fmt.Errorf("no OVDC found in the VCD client to delete the OVDC networks of the cluster")
+	}
+	for _, trackedNetwork := range trackedNetworks {
+		ovdcNetwork, err := vcdClient.VDC.GetOpenApiOrgVdcNetworkById(trackedNetwork.ID)
+		if err != nil && !govcd.ContainsNotFound(err) {
+			return fmt.Errorf("failed to get OVDC network [%s(%s)]: [%v]", trackedNetwork.Name, trackedNetwork.ID,
+				err)
+		}
+		if err == nil {
+			if err = ovdcNetwork.Delete(); err != nil {
+				return fmt.Errorf("failed to delete OVDC network [%s(%s)]: [%v]", trackedNetwork.Name,
+					trackedNetwork.ID, err)
+			}
+			log.Info("Deleted the OVDC network of the cluster", "ovdcNetwork", trackedNetwork.Name)
+		}
+		if err = removeVcdResourceFromVcdCluster(vcdCluster, ResourceTypeOvdcNetwork, trackedNetwork.ID); err != nil {
+			log.Error(err, "failed to remove OVDC network from the resource map of the cluster",
+				"ovdcNetwork", trackedNetwork.Name)
+		}
+	}
+	return nil
+}
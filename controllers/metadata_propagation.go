@@ -0,0 +1,117 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CapvcdPropagatedMetadataKeys is the key of the metadata entry listing the metadata keys propagated from CAPI labels
+// and annotations, so that an entry is removed once its label or annotation is removed or no longer selected.
+const CapvcdPropagatedMetadataKeys = "CapvcdPropagatedMetadataKeys"
+
+// metadataHolder is a VCD object with metadata, i.e. a vApp or a VM.
+type metadataHolder interface {
+	GetMetadata() (*types.Metadata, error)
+	AddMetadataEntryWithVisibility(key, value, typedValue, visibility string, isSystem bool) error
+	DeleteMetadataEntryWithDomain(key string, isSystem bool) error
+}
+
+// getPropagatedMetadata returns the labels and annotations of the object selected by the MetadataPropagationSpec.
+func getPropagatedMetadata(objectMeta metav1.ObjectMeta, metadataPropagation *infrav1beta3.MetadataPropagationSpec,
+	propagatedMetadata map[string]string) map[string]string {
+
+	if propagatedMetadata == nil {
+		propagatedMetadata = make(map[string]string)
+	}
+	if metadataPropagation == nil {
+		return propagatedMetadata
+	}
+	for _, key := range metadataPropagation.Labels {
+		if value, ok := objectMeta.Labels[key]; ok {
+			propagatedMetadata[key] = value
+		}
+	}
+	for _, key := range metadataPropagation.Annotations {
+		if value, ok := objectMeta.Annotations[key]; ok {
+			propagatedMetadata[key] = value
+		}
+	}
+	return propagatedMetadata
+}
+
+// reconcilePropagatedMetadata sets the metadata entries of the vApp or VM to the propagated labels and annotations,
+// and removes the entries propagated earlier which are no longer propagated. Other metadata entries are left untouched.
+func reconcilePropagatedMetadata(holder metadataHolder, name string, propagatedMetadata map[string]string) error {
+	metadata, err := holder.GetMetadata()
+	if err != nil {
+		return fmt.Errorf("failed to get metadata of [%s]: [%v]", name, err)
+	}
+	currentMetadata := make(map[string]string)
+	if metadata != nil {
+		for _, metadataEntry := range metadata.MetadataEntry {
+			if metadataEntry.TypedValue != nil {
+				currentMetadata[metadataEntry.Key] = metadataEntry.TypedValue.Value
+			}
+		}
+	}
+
+	for key, value := range propagatedMetadata {
+		if key == CapvcdPropagatedMetadataKeys {
+			continue
+		}
+		if currentValue, ok := currentMetadata[key]; ok && currentValue == value {
+			continue
+		}
+		if err = holder.AddMetadataEntryWithVisibility(key, value, types.MetadataStringValue,
+			types.MetadataReadWriteVisibility, false); err != nil {
+			return fmt.Errorf("failed to add metadata [%s: %s] to [%s]: [%v]", key, value, name, err)
+		}
+	}
+
+	if previousKeys, ok := currentMetadata[CapvcdPropagatedMetadataKeys]; ok && previousKeys != "" {
+		for _, key := range strings.Split(previousKeys, ",") {
+			if _, propagated := propagatedMetadata[key]; propagated {
+				continue
+			}
+			if _, exists := currentMetadata[key]; !exists {
+				continue
+			}
+			if err = holder.DeleteMetadataEntryWithDomain(key, false); err != nil {
+				return fmt.Errorf("failed to delete metadata [%s] of [%s]: [%v]", key, name, err)
+			}
+		}
+	}
+
+	propagatedKeys := make([]string, 0, len(propagatedMetadata))
+	for key := range propagatedMetadata {
+		propagatedKeys = append(propagatedKeys, key)
+	}
+	sort.Strings(propagatedKeys)
+	joinedKeys := strings.Join(propagatedKeys, ",")
+	if currentMetadata[CapvcdPropagatedMetadataKeys] == joinedKeys {
+		return nil
+	}
+	if joinedKeys == "" {
+		if err = holder.DeleteMetadataEntryWithDomain(CapvcdPropagatedMetadataKeys, false); err != nil {
+			return fmt.Errorf("failed to delete metadata [%s] of [%s]: [%v]", CapvcdPropagatedMetadataKeys, name,
+				err)
+		}
+		return nil
+	}
+	if err = holder.AddMetadataEntryWithVisibility(CapvcdPropagatedMetadataKeys, joinedKeys, types.MetadataStringValue,
+		types.MetadataReadWriteVisibility, false); err != nil {
+		return fmt.Errorf("failed to add metadata [%s: %s] to [%s]: [%v]", CapvcdPropagatedMetadataKeys, joinedKeys,
+			name, err)
+	}
+	return nil
+}
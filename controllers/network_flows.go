@@ -0,0 +1,148 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	rdeType "github.com/vmware/cluster-api-provider-cloud-director/pkg/vcdtypes/rde_type_1_3_0"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Groups of addresses the network flows of a cluster are defined between
+const (
+	NetworkFlowGroupAny                  = "any"
+	NetworkFlowGroupControlPlaneEndpoint = "controlPlaneEndpoint"
+	NetworkFlowGroupControlPlaneNodes    = "controlPlaneNodes"
+	NetworkFlowGroupWorkerNodes          = "workerNodes"
+	NetworkFlowGroupNodes                = "nodes"
+)
+
+const (
+	etcdPorts        = "2379-2380"
+	kubeletPort      = "10250"
+	nodePortRange    = "30000-32767"
+	antreaGenevePort = "6081"
+)
+
+// getNodeAddresses returns the sorted internal IP addresses of the control plane and worker machines of the cluster.
+func getNodeAddresses(ctx context.Context, cli client.Client, cluster *clusterv1.Cluster) ([]string, []string, error) {
+	machineList, err := getMachineListFromCluster(ctx, cli, *cluster)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get the machines of cluster [%s]: [%v]", cluster.Name, err)
+	}
+	// the groups without addresses are left nil to compare equal to the groups read back from the RDE
+	var controlPlaneAddresses, workerAddresses []string
+	for i := range machineList.Items {
+		machine := &machineList.Items[i]
+		for _, address := range machine.Status.Addresses {
+			if address.Type != clusterv1.MachineInternalIP || address.Address == "" {
+				continue
+			}
+			if util.IsControlPlaneMachine(machine) {
+				controlPlaneAddresses = append(controlPlaneAddresses, address.Address)
+			} else {
+				workerAddresses = append(workerAddresses, address.Address)
+			}
+		}
+	}
+	sort.Strings(controlPlaneAddresses)
+	sort.Strings(workerAddresses)
+	return controlPlaneAddresses, workerAddresses, nil
+}
+
+// getNetworkFlows returns a machine-readable summary of the network flows the cluster needs, between named groups of
+// addresses, for the provider to derive distributed firewall policies of the cluster from: the Kubernetes API through
+// the control plane endpoint and on the control plane nodes, etcd, the kubelets, the NodePorts and the pod network of
+// the Antrea CNI of the cluster templates. The egress of the cluster is summarized in the egress allowlist instead.
+func getNetworkFlows(ctx context.Context, cli client.Client, cluster *clusterv1.Cluster,
+	vcdCluster *infrav1beta3.VCDCluster) (*rdeType.NetworkFlows, error) {
+
+	controlPlaneAddresses, workerAddresses, err := getNodeAddresses(ctx, cli, cluster)
+	if err != nil {
+		return nil, err
+	}
+	var nodeAddresses []string
+	nodeAddresses = append(nodeAddresses, controlPlaneAddresses...)
+	nodeAddresses = append(nodeAddresses, workerAddresses...)
+	sort.Strings(nodeAddresses)
+
+	var controlPlaneEndpointAddresses []string
+	if vcdCluster.Spec.ControlPlaneEndpoint.Host != "" {
+		controlPlaneEndpointAddresses = append(controlPlaneEndpointAddresses, vcdCluster.Spec.ControlPlaneEndpoint.Host)
+	}
+	controlPlanePort := vcdCluster.Spec.ControlPlaneEndpoint.Port
+	if controlPlanePort == 0 {
+		controlPlanePort = TcpPort
+	}
+
+	return &rdeType.NetworkFlows{
+		OvdcNetwork: getOvdcNetworkName(vcdCluster),
+		Groups: []rdeType.NetworkFlowGroup{
+			{Name: NetworkFlowGroupControlPlaneEndpoint, Addresses: controlPlaneEndpointAddresses},
+			{Name: NetworkFlowGroupControlPlaneNodes, Addresses: controlPlaneAddresses},
+			{Name: NetworkFlowGroupWorkerNodes, Addresses: workerAddresses},
+			{Name: NetworkFlowGroupNodes, Addresses: nodeAddresses},
+		},
+		Flows: []rdeType.NetworkFlow{
+			{
+				Name:        "kube-apiserver-endpoint",
+				Source:      NetworkFlowGroupAny,
+				Destination: NetworkFlowGroupControlPlaneEndpoint,
+				Protocol:    egressProtocolTCP,
+				Ports:       fmt.Sprintf("%d", controlPlanePort),
+			},
+			{
+				// reached from the nodes and from the load balancer or DNAT rule of the control plane endpoint
+				Name:        "kube-apiserver",
+				Source:      NetworkFlowGroupAny,
+				Destination: NetworkFlowGroupControlPlaneNodes,
+				Protocol:    egressProtocolTCP,
+				Ports:       fmt.Sprintf("%d", TcpPort),
+			},
+			{
+				Name:        "etcd",
+				Source:      NetworkFlowGroupControlPlaneNodes,
+				Destination: NetworkFlowGroupControlPlaneNodes,
+				Protocol:    egressProtocolTCP,
+				Ports:       etcdPorts,
+			},
+			{
+				Name:        "kubelet",
+				Source:      NetworkFlowGroupNodes,
+				Destination: NetworkFlowGroupNodes,
+				Protocol:    egressProtocolTCP,
+				Ports:       kubeletPort,
+			},
+			{
+				Name:        "nodeports-tcp",
+				Source:      NetworkFlowGroupAny,
+				Destination: NetworkFlowGroupNodes,
+				Protocol:    egressProtocolTCP,
+				Ports:       nodePortRange,
+			},
+			{
+				Name:        "nodeports-udp",
+				Source:      NetworkFlowGroupAny,
+				Destination: NetworkFlowGroupNodes,
+				Protocol:    egressProtocolUDP,
+				Ports:       nodePortRange,
+			},
+			{
+				Name:        "pod-network-geneve",
+				Source:      NetworkFlowGroupNodes,
+				Destination: NetworkFlowGroupNodes,
+				Protocol:    egressProtocolUDP,
+				Ports:       antreaGenevePort,
+			},
+		},
+	}, nil
+}
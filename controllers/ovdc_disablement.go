@@ -0,0 +1,64 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// OvdcDisabledRequeueInterval is the interval at which VCDMachines waiting for a disabled OVDC are retried.
+const OvdcDisabledRequeueInterval = 2 * time.Minute
+
+// isOvdcDisabled checks if the OVDC of the client was disabled by the provider.
+func isOvdcDisabled(vcdClient *vcdsdk.Client) bool {
+	return vcdClient.VDC != nil && vcdClient.VDC.Vdc != nil && !vcdClient.VDC.Vdc.IsEnabled
+}
+
+// markOvdcDisabledCondition sets the OvdcEnabled condition to false and reports if it was newly set.
+func markOvdcDisabledCondition(obj conditions.Setter, ovdcName string) bool {
+	newlyDisabled := !conditions.IsFalse(obj, OvdcEnabledCondition)
+	conditions.MarkFalse(obj, OvdcEnabledCondition, OvdcDisabledByProviderReason, clusterv1.ConditionSeverityWarning,
+		"OVDC [%s] is disabled; no VMs are created until it is enabled", ovdcName)
+	return newlyDisabled
+}
+
+// markOvdcEnabledCondition sets the OvdcEnabled condition to true and reports if the OVDC was disabled before.
+func markOvdcEnabledCondition(obj conditions.Setter) bool {
+	reenabled := conditions.IsFalse(obj, OvdcEnabledCondition)
+	conditions.MarkTrue(obj, OvdcEnabledCondition)
+	return reenabled
+}
+
+// reconcileOvdcDisablement reports on the VCDCluster whether the provider disabled its OVDC.
+func (r *VCDClusterReconciler) reconcileOvdcDisablement(ctx context.Context, vcdCluster *infrav1beta3.VCDCluster,
+	vcdClient *vcdsdk.Client) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	ovdcName := vcdClient.ClusterOVDCName
+	if isOvdcDisabled(vcdClient) {
+		if markOvdcDisabledCondition(vcdCluster, ovdcName) {
+			log.Info("OVDC of the cluster is disabled", "ovdc", ovdcName)
+			r.recordEvent(vcdCluster, corev1.EventTypeWarning, OvdcDisabledByProviderReason,
+				fmt.Sprintf("OVDC [%s] was disabled by the provider", ovdcName))
+		}
+		return
+	}
+	if markOvdcEnabledCondition(vcdCluster) {
+		log.Info("OVDC of the cluster is enabled again", "ovdc", ovdcName)
+		r.recordEvent(vcdCluster, corev1.EventTypeNormal, OvdcEnabledReason,
+			fmt.Sprintf("OVDC [%s] was enabled by the provider", ovdcName))
+	}
+}
@@ -0,0 +1,90 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// OvdcMaintenanceAnnotation marks the OVDC of a cluster as under maintenance when set on its VCDCluster, e.g. for a
+	// host maintenance window of the provider. The value is an optional description of the maintenance. No new VMs are
+	// created for the cluster until the annotation is removed or set to "false"; the existing VMs are left untouched and
+	// can still be deleted.
+	OvdcMaintenanceAnnotation = "capvcd.vmware.com/ovdc-maintenance"
+
+	// OvdcMaintenanceRequeueInterval is the interval at which VCDMachines waiting for the end of the maintenance of
+	// their OVDC are retried.
+	OvdcMaintenanceRequeueInterval = 2 * time.Minute
+)
+
+// isOvdcUnderMaintenance checks if the OVDC of the cluster is marked as under maintenance by the annotation of its
+// VCDCluster, and returns the description of the maintenance.
+func isOvdcUnderMaintenance(vcdCluster *infrav1beta3.VCDCluster) (bool, string) {
+	value, ok := vcdCluster.Annotations[OvdcMaintenanceAnnotation]
+	if !ok || value == "false" {
+		return false, ""
+	}
+	if value == "true" {
+		value = ""
+	}
+	return true, value
+}
+
+// getOvdcMaintenanceMessage returns the message of the false OvdcInService condition of the objects of the cluster.
+func getOvdcMaintenanceMessage(vcdCluster *infrav1beta3.VCDCluster, description string) string {
+	message := fmt.Sprintf("OVDC [%s] is under maintenance; no VMs are created until the annotation [%s] is removed",
+		getOvdcName(vcdCluster), OvdcMaintenanceAnnotation)
+	if description != "" {
+		message = fmt.Sprintf("%s: %s", message, description)
+	}
+	return message
+}
+
+// setOvdcMaintenanceCondition sets the OvdcInService condition to false and reports if it was newly set.
+func setOvdcMaintenanceCondition(obj conditions.Setter, vcdCluster *infrav1beta3.VCDCluster, description string) bool {
+	newlyUnderMaintenance := !conditions.IsFalse(obj, OvdcInServiceCondition)
+	conditions.MarkFalse(obj, OvdcInServiceCondition, OvdcMaintenanceReason, clusterv1.ConditionSeverityInfo, "%s",
+		getOvdcMaintenanceMessage(vcdCluster, description))
+	return newlyUnderMaintenance
+}
+
+// clearOvdcMaintenanceCondition sets the OvdcInService condition back to true and reports if it was false.
+func clearOvdcMaintenanceCondition(obj conditions.Setter) bool {
+	if !conditions.IsFalse(obj, OvdcInServiceCondition) {
+		return false
+	}
+	conditions.MarkTrue(obj, OvdcInServiceCondition)
+	return true
+}
+
+// reconcileOvdcMaintenance reports on the VCDCluster whether its OVDC is marked as under maintenance.
+func (r *VCDClusterReconciler) reconcileOvdcMaintenance(ctx context.Context, vcdCluster *infrav1beta3.VCDCluster) {
+	log := ctrl.LoggerFrom(ctx)
+
+	ovdcName := getOvdcName(vcdCluster)
+	if underMaintenance, description := isOvdcUnderMaintenance(vcdCluster); underMaintenance {
+		if setOvdcMaintenanceCondition(vcdCluster, vcdCluster, description) {
+			log.Info("OVDC of the cluster is under maintenance", "ovdc", ovdcName, "maintenance", description)
+			r.recordEvent(vcdCluster, corev1.EventTypeNormal, OvdcMaintenanceReason,
+				getOvdcMaintenanceMessage(vcdCluster, description))
+		}
+		return
+	}
+	if clearOvdcMaintenanceCondition(vcdCluster) {
+		log.Info("Maintenance of the OVDC of the cluster is over", "ovdc", ovdcName)
+		r.recordEvent(vcdCluster, corev1.EventTypeNormal, OvdcMaintenanceCompletedReason,
+			fmt.Sprintf("maintenance of OVDC [%s] is over; VMs are created again", ovdcName))
+	}
+}
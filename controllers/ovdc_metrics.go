@@ -0,0 +1,164 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var ovdcLabels = []string{"site", "org", "ovdc"}
+
+// ovdcClustersDesc describes the per-OVDC metric reporting the number of VCDClusters in the OVDC.
+var ovdcClustersDesc = prometheus.NewDesc(
+	"capvcd_ovdc_clusters",
+	"Number of VCDClusters in the OVDC reconciled by this controller instance.",
+	ovdcLabels,
+	nil,
+)
+
+// ovdcMachinesDesc describes the per-OVDC metric reporting the number of VCDMachines of the clusters in the OVDC.
+var ovdcMachinesDesc = prometheus.NewDesc(
+	"capvcd_ovdc_machines",
+	"Number of VCDMachines of the clusters in the OVDC reconciled by this controller instance.",
+	ovdcLabels,
+	nil,
+)
+
+// ovdcInFlightTasksDesc describes the per-OVDC metric reporting the number of VM creations in progress in the OVDC.
+var ovdcInFlightTasksDesc = prometheus.NewDesc(
+	"capvcd_ovdc_inflight_tasks",
+	"Number of VM creation tasks of this controller instance in progress in the OVDC.",
+	ovdcLabels,
+	nil,
+)
+
+// ovdcConcurrencyLimitDesc describes the per-OVDC metric reporting the maximum number of VMs created at once.
+var ovdcConcurrencyLimitDesc = prometheus.NewDesc(
+	"capvcd_ovdc_concurrency_limit",
+	"Maximum number of VCDMachines this controller instance reconciles at once, which bounds the VM creation tasks "+
+		"in progress in the OVDC.",
+	ovdcLabels,
+	nil,
+)
+
+type ovdcKey struct {
+	site string
+	org  string
+	ovdc string
+}
+
+// ovdcStatsCollector aggregates the clusters, machines and VM creation tasks of every OVDC when the metrics are
+// scraped. The OVDC of a VCDCluster is recorded by the VCDCluster controller after each reconciliation, and its
+// machines are the ones tracked by the machinePhaseCollector.
+type ovdcStatsCollector struct {
+	lock             sync.Mutex
+	clusters         map[types.NamespacedName]ovdcKey
+	inFlightTasks    map[ovdcKey]int
+	concurrencyLimit int
+}
+
+var ovdcStats = &ovdcStatsCollector{
+	clusters:      make(map[types.NamespacedName]ovdcKey),
+	inFlightTasks: make(map[ovdcKey]int),
+}
+
+func init() {
+	metrics.Registry.MustRegister(ovdcStats)
+}
+
+func (c *ovdcStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ovdcClustersDesc
+	ch <- ovdcMachinesDesc
+	ch <- ovdcInFlightTasksDesc
+	ch <- ovdcConcurrencyLimitDesc
+}
+
+func (c *ovdcStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	machineCounts := make(map[types.NamespacedName]int)
+	machinePhaseMetrics.lock.Lock()
+	for machineKey, tracked := range machinePhaseMetrics.machines {
+		machineCounts[types.NamespacedName{Namespace: machineKey.Namespace, Name: tracked.cluster}]++
+	}
+	machinePhaseMetrics.lock.Unlock()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	clusterCounts := make(map[ovdcKey]int)
+	ovdcMachineCounts := make(map[ovdcKey]int)
+	for clusterKey, ovdc := range c.clusters {
+		clusterCounts[ovdc]++
+		ovdcMachineCounts[ovdc] += machineCounts[clusterKey]
+	}
+	// the OVDCs without a cluster are reported until their tasks complete
+	for ovdc := range c.inFlightTasks {
+		if _, ok := clusterCounts[ovdc]; !ok {
+			clusterCounts[ovdc] = 0
+		}
+	}
+	for ovdc, clusterCount := range clusterCounts {
+		ch <- prometheus.MustNewConstMetric(ovdcClustersDesc, prometheus.GaugeValue, float64(clusterCount),
+			ovdc.site, ovdc.org, ovdc.ovdc)
+		ch <- prometheus.MustNewConstMetric(ovdcMachinesDesc, prometheus.GaugeValue, float64(ovdcMachineCounts[ovdc]),
+			ovdc.site, ovdc.org, ovdc.ovdc)
+		ch <- prometheus.MustNewConstMetric(ovdcInFlightTasksDesc, prometheus.GaugeValue,
+			float64(c.inFlightTasks[ovdc]), ovdc.site, ovdc.org, ovdc.ovdc)
+		ch <- prometheus.MustNewConstMetric(ovdcConcurrencyLimitDesc, prometheus.GaugeValue,
+			float64(c.concurrencyLimit), ovdc.site, ovdc.org, ovdc.ovdc)
+	}
+}
+
+func getOvdcKey(vcdCluster *infrav1beta3.VCDCluster) ovdcKey {
+	return ovdcKey{site: vcdCluster.Spec.Site, org: getOrgName(vcdCluster), ovdc: getOvdcName(vcdCluster)}
+}
+
+// SetOvdcConcurrencyLimit records the maximum number of VCDMachines reconciled at once, reported for every OVDC.
+func SetOvdcConcurrencyLimit(concurrencyLimit int) {
+	ovdcStats.lock.Lock()
+	defer ovdcStats.lock.Unlock()
+	ovdcStats.concurrencyLimit = concurrencyLimit
+}
+
+// trackClusterOvdc records the OVDC of the VCDCluster after a reconciliation.
+func trackClusterOvdc(vcdCluster *infrav1beta3.VCDCluster) {
+	clusterKey := types.NamespacedName{Namespace: vcdCluster.Namespace, Name: vcdCluster.Name}
+
+	ovdcStats.lock.Lock()
+	defer ovdcStats.lock.Unlock()
+	ovdcStats.clusters[clusterKey] = getOvdcKey(vcdCluster)
+}
+
+// untrackClusterOvdc stops reporting the VCDCluster once it is deleted.
+func untrackClusterOvdc(vcdCluster *infrav1beta3.VCDCluster) {
+	clusterKey := types.NamespacedName{Namespace: vcdCluster.Namespace, Name: vcdCluster.Name}
+
+	ovdcStats.lock.Lock()
+	defer ovdcStats.lock.Unlock()
+	delete(ovdcStats.clusters, clusterKey)
+}
+
+// startOvdcTask counts a VM creation task in the OVDC of the VCDCluster as in progress until the returned function is
+// called.
+func startOvdcTask(vcdCluster *infrav1beta3.VCDCluster) func() {
+	ovdc := getOvdcKey(vcdCluster)
+
+	ovdcStats.lock.Lock()
+	defer ovdcStats.lock.Unlock()
+	ovdcStats.inFlightTasks[ovdc]++
+	return func() {
+		ovdcStats.lock.Lock()
+		defer ovdcStats.lock.Unlock()
+		ovdcStats.inFlightTasks[ovdc]--
+		if ovdcStats.inFlightTasks[ovdc] <= 0 {
+			delete(ovdcStats.inFlightTasks, ovdc)
+		}
+	}
+}
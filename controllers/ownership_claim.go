@@ -0,0 +1,214 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// CapvcdManagementClusterId is the key of the vApp metadata entry claiming the vApp for a management cluster.
+	CapvcdManagementClusterId = "CapvcdManagementClusterId"
+
+	// TakeoverOwnershipAnnotation allows the management cluster to claim the VCD resources of a VCDCluster which are
+	// claimed by another management cluster. The annotation is removed once the resources are claimed.
+	TakeoverOwnershipAnnotation = "infrastructure.cluster.x-k8s.io/takeover-ownership"
+
+	// ClaimedByManagementClusterAnnotation records on the VCDCluster the management cluster which claimed its VCD
+	// resources. It is moved along with the VCDCluster by clusterctl move, so that the target management cluster takes
+	// over the resources claimed by the source management cluster without the TakeoverOwnershipAnnotation.
+	ClaimedByManagementClusterAnnotation = "capvcd.vmware.com/claimed-by-management-cluster"
+
+	// managementClusterIDNamespace is the namespace whose UID identifies the management cluster.
+	managementClusterIDNamespace = "kube-system"
+)
+
+var (
+	managementClusterID     string
+	managementClusterIDLock sync.Mutex
+)
+
+// getManagementClusterID returns the identifier of the management cluster CAPVCD is running in, which is the UID of
+// the kube-system namespace.
+func getManagementClusterID(ctx context.Context, cli client.Client) (string, error) {
+	managementClusterIDLock.Lock()
+	defer managementClusterIDLock.Unlock()
+
+	if managementClusterID != "" {
+		return managementClusterID, nil
+	}
+	namespace := &corev1.Namespace{}
+	if err := cli.Get(ctx, client.ObjectKey{Name: managementClusterIDNamespace}, namespace); err != nil {
+		return "", fmt.Errorf("failed to get namespace [%s] identifying the management cluster: [%v]",
+			managementClusterIDNamespace, err)
+	}
+	managementClusterID = string(namespace.UID)
+	return managementClusterID, nil
+}
+
+// getVAppOwnershipClaim returns the management cluster claiming the vApp, or an empty string if the vApp is unclaimed.
+func getVAppOwnershipClaim(vApp *govcd.VApp) (string, error) {
+	metadata, err := vApp.GetMetadata()
+	if err != nil {
+		return "", fmt.Errorf("failed to get metadata of vApp [%s]: [%v]", vApp.VApp.Name, err)
+	}
+	for _, metadataEntry := range metadata.MetadataEntry {
+		if metadataEntry.Key == CapvcdManagementClusterId && metadataEntry.TypedValue != nil {
+			return metadataEntry.TypedValue.Value, nil
+		}
+	}
+	return "", nil
+}
+
+// checkOwnershipClaim returns an OwnershipClaimedError if a resource is claimed by another management cluster.
+func checkOwnershipClaim(claimID string, ownID string, resource string) error {
+	if claimID == "" || claimID == ownID {
+		return nil
+	}
+	return NewOwnershipClaimedError(fmt.Sprintf(
+		"%s is claimed by management cluster [%s] instead of [%s]; annotate the VCDCluster with [%s] to take it over",
+		resource, claimID, ownID, TakeoverOwnershipAnnotation))
+}
+
+// verifyVAppOwnershipClaim checks that the vApp of a cluster is not claimed by another management cluster before
+// mutating the VMs of the cluster. The vApp is claimed by the VCDCluster controller.
+func verifyVAppOwnershipClaim(ctx context.Context, cli client.Client, vcdClient *vcdsdk.Client,
+	vcdCluster *infrav1beta3.VCDCluster) error {
+
+	ownID, err := getManagementClusterID(ctx, cli)
+	if err != nil {
+		return err
+	}
+	if vcdClient.VDC == nil || vcdClient.VDC.Vdc == nil {
+		return fmt.Errorf("no OVDC found in the VCD client to verify the ownership of cluster [%s]", vcdCluster.Name)
+	}
+	vAppName := CreateFullVAppName(vcdCluster)
+	vApp, err := vcdClient.VDC.GetVAppByName(vAppName, true)
+	if err == govcd.ErrorEntityNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get vApp [%s]: [%v]", vAppName, err)
+	}
+	claimID, err := getVAppOwnershipClaim(vApp)
+	if err != nil {
+		return err
+	}
+	return checkOwnershipClaim(claimID, ownID, fmt.Sprintf("vApp [%s]", vAppName))
+}
+
+// reconcileOwnershipClaim ensures that the vApp and the RDE of the VCDCluster are claimed by this management cluster.
+// Resources claimed by another management cluster are only claimed if the VCDCluster has the takeover annotation, or
+// if the VCDCluster was moved from that management cluster, as recorded by the ClaimedByManagementClusterAnnotation.
+func (r *VCDClusterReconciler) reconcileOwnershipClaim(ctx context.Context, vcdCluster *infrav1beta3.VCDCluster,
+	vcdClient *vcdsdk.Client) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	ownID, err := getManagementClusterID(ctx, r.Client)
+	if err != nil {
+		return err
+	}
+	_, takeover := vcdCluster.Annotations[TakeoverOwnershipAnnotation]
+	movedFrom := vcdCluster.Annotations[ClaimedByManagementClusterAnnotation]
+	if movedFrom == ownID {
+		movedFrom = ""
+	}
+
+	if vcdClient.VDC == nil || vcdClient.VDC.Vdc == nil {
+		return fmt.Errorf("no OVDC found in the VCD client to verify the ownership of cluster [%s]", vcdCluster.Name)
+	}
+	vAppName := CreateFullVAppName(vcdCluster)
+	vApp, err := vcdClient.VDC.GetVAppByName(vAppName, true)
+	if err != nil && err != govcd.ErrorEntityNotFound {
+		return fmt.Errorf("failed to get vApp [%s]: [%v]", vAppName, err)
+	}
+	vAppClaimID := ""
+	if vApp != nil && vApp.VApp != nil {
+		if vAppClaimID, err = getVAppOwnershipClaim(vApp); err != nil {
+			return err
+		}
+	} else {
+		vApp = nil
+	}
+
+	hasRDE := vcdCluster.Status.InfraId != "" && !strings.HasPrefix(vcdCluster.Status.InfraId, NoRdePrefix)
+	capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
+	rdeClaimID := ""
+	if hasRDE {
+		_, _, _, capvcdStatus, err := capvcdRdeManager.GetCAPVCDEntity(ctx, vcdCluster.Status.InfraId)
+		if err != nil {
+			return fmt.Errorf("failed to get RDE [%s] to verify the ownership of cluster [%s]: [%v]",
+				vcdCluster.Status.InfraId, vcdCluster.Name, err)
+		}
+		rdeClaimID = capvcdStatus.ManagementClusterID
+	}
+
+	if !takeover {
+		// the resources of a moved cluster are claimed by the management cluster it was moved from
+		if vAppClaimID != movedFrom {
+			if err = checkOwnershipClaim(vAppClaimID, ownID, fmt.Sprintf("vApp [%s]", vAppName)); err != nil {
+				return err
+			}
+		}
+		if rdeClaimID != movedFrom {
+			if err = checkOwnershipClaim(rdeClaimID, ownID, fmt.Sprintf("RDE [%s]", vcdCluster.Status.InfraId)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if vApp != nil && vAppClaimID != ownID {
+		task, err := vApp.AddMetadata(CapvcdManagementClusterId, ownID)
+		if err == nil {
+			err = task.WaitTaskCompletion()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to claim vApp [%s] for management cluster [%s]: [%v]", vAppName, ownID, err)
+		}
+	}
+	if hasRDE && rdeClaimID != ownID {
+		_, err = capvcdRdeManager.PatchRDE(ctx, nil, nil, map[string]interface{}{
+			"ManagementClusterID": ownID,
+		}, vcdCluster.Status.InfraId, "", false)
+		if err != nil {
+			return fmt.Errorf("failed to claim RDE [%s] for management cluster [%s]: [%v]",
+				vcdCluster.Status.InfraId, ownID, err)
+		}
+	}
+
+	if takeover {
+		previousOwners := fmt.Sprintf("vApp claimed by [%s], RDE claimed by [%s]", vAppClaimID, rdeClaimID)
+		log.Info("Took over the ownership of the cluster", "managementClusterID", ownID, "previousClaims", previousOwners)
+		r.recordEvent(vcdCluster, corev1.EventTypeNormal, OwnershipTakenOverReason,
+			fmt.Sprintf("management cluster [%s] took over the VCD resources of the cluster (%s)", ownID, previousOwners))
+		delete(vcdCluster.Annotations, TakeoverOwnershipAnnotation)
+	} else if movedFrom != "" && (vAppClaimID == movedFrom || rdeClaimID == movedFrom) {
+		log.Info("Claimed the VCD resources of the cluster moved from another management cluster",
+			"managementClusterID", ownID, "previousManagementClusterID", movedFrom)
+		r.recordEvent(vcdCluster, corev1.EventTypeNormal, OwnershipMovedReason, fmt.Sprintf(
+			"management cluster [%s] claimed the VCD resources of the cluster moved from management cluster [%s]",
+			ownID, movedFrom))
+	}
+	if vcdCluster.Annotations == nil {
+		vcdCluster.Annotations = make(map[string]string)
+	}
+	vcdCluster.Annotations[ClaimedByManagementClusterAnnotation] = ownID
+	conditions.MarkTrue(vcdCluster, OwnershipClaimVerifiedCondition)
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Modes of the control plane endpoint of a VCDCluster
+const (
+	ControlPlaneEndpointModeManaged     = "Managed"
+	ControlPlaneEndpointModePassthrough = "Passthrough"
+	ControlPlaneEndpointModeDNAT        = "DNAT"
+	ControlPlaneEndpointModeRouted      = "Routed"
+	ControlPlaneEndpointModeKubeVip     = "KubeVip"
+)
+
+// isControlPlaneEndpointPassthrough checks if the control plane endpoint of the cluster is a load balancer supplied by
+// the user.
+func isControlPlaneEndpointPassthrough(vcdCluster *infrav1beta3.VCDCluster) bool {
+	return vcdCluster.Spec.ControlPlaneEndpointMode == ControlPlaneEndpointModePassthrough
+}
+
+// isLoadBalancerManagedByCAPVCD checks if CAPVCD creates and maintains the load balancer of the control plane endpoint
+// of the cluster, which is neither the case for externally managed clusters nor in the passthrough, routed and
+// kube-vip modes.
+func isLoadBalancerManagedByCAPVCD(vcdCluster *infrav1beta3.VCDCluster) bool {
+	return !annotations.IsExternallyManaged(vcdCluster) && !isControlPlaneEndpointPassthrough(vcdCluster) &&
+		!isControlPlaneEndpointRouted(vcdCluster) && !isControlPlaneEndpointKubeVip(vcdCluster)
+}
+
+// isALBRequired checks if the control plane endpoint of the cluster is an NSX-T ALB virtual service, which is not the
+// case in the passthrough, DNAT, routed and kube-vip modes.
+func isALBRequired(vcdCluster *infrav1beta3.VCDCluster) bool {
+	return !isControlPlaneEndpointPassthrough(vcdCluster) && !isControlPlaneEndpointDNAT(vcdCluster) &&
+		!isControlPlaneEndpointRouted(vcdCluster) && !isControlPlaneEndpointKubeVip(vcdCluster)
+}
+
+// reconcilePassthroughLoadBalancer uses the control plane endpoint supplied by the user as is. No virtual service,
+// pool, DNAT rule or IP is created or allocated on the edge gateway.
+func (r *VCDClusterReconciler) reconcilePassthroughLoadBalancer(ctx context.Context,
+	vcdCluster *infrav1beta3.VCDCluster, vcdClient *vcdsdk.Client, skipRDEEventUpdates bool) (ctrl.Result, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	if vcdCluster.Spec.ControlPlaneEndpoint.Host == "" {
+		conditions.MarkFalse(vcdCluster, LoadBalancerAvailableCondition, ControlPlaneEndpointNotSetReason,
+			clusterv1.ConditionSeverityError, "control plane endpoint host must be set in the [%s] mode",
+			ControlPlaneEndpointModePassthrough)
+		return ctrl.Result{}, fmt.Errorf("control plane endpoint host of cluster [%s] must be set in the [%s] mode",
+			vcdCluster.Name, ControlPlaneEndpointModePassthrough)
+	}
+	if vcdCluster.Spec.ControlPlaneEndpoint.Port == 0 {
+		vcdCluster.Spec.ControlPlaneEndpoint.Port = TcpPort
+	}
+	log.Info(fmt.Sprintf("Control plane endpoint for the cluster is [%s] supplied by the user",
+		vcdCluster.Spec.ControlPlaneEndpoint.Host))
+
+	capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
+	capvcdRdeManager.AddToEventSet(ctx, capisdk.LoadBalancerAvailable, "", "", "", skipRDEEventUpdates)
+	return ctrl.Result{}, nil
+}
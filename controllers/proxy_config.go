@@ -0,0 +1,61 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"strings"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// DefaultClusterServiceDomain is the service domain of a cluster whose ClusterNetwork does not set it.
+const DefaultClusterServiceDomain = "cluster.local"
+
+// isProxyConfigured checks if the nodes of the cluster reach the internet through a proxy.
+func isProxyConfigured(vcdCluster *infrav1beta3.VCDCluster) bool {
+	return vcdCluster.Spec.ProxyConfigSpec.HTTPProxy != "" || vcdCluster.Spec.ProxyConfigSpec.HTTPSProxy != ""
+}
+
+// getNoProxy returns the destinations the nodes of the cluster reach without the proxy: the noProxy entries of the
+// VCDCluster followed by the addresses internal to the cluster, i.e. localhost, the control plane endpoint, the pod and
+// service CIDRs and the service domain, which the containerd and kubelet of the nodes must never reach through the
+// proxy. It is empty when no proxy is configured.
+func getNoProxy(cluster *clusterv1.Cluster, vcdCluster *infrav1beta3.VCDCluster) string {
+	if !isProxyConfigured(vcdCluster) {
+		return vcdCluster.Spec.ProxyConfigSpec.NoProxy
+	}
+
+	noProxy := make([]string, 0)
+	seen := make(map[string]bool)
+	addNoProxy := func(entries ...string) {
+		for _, entry := range entries {
+			entry = strings.TrimSpace(entry)
+			if entry == "" || seen[entry] {
+				continue
+			}
+			seen[entry] = true
+			noProxy = append(noProxy, entry)
+		}
+	}
+
+	addNoProxy(strings.Split(vcdCluster.Spec.ProxyConfigSpec.NoProxy, ",")...)
+	addNoProxy("localhost", "127.0.0.1", vcdCluster.Spec.ControlPlaneEndpoint.Host)
+	serviceDomain := DefaultClusterServiceDomain
+	if cluster != nil && cluster.Spec.ClusterNetwork != nil {
+		if cluster.Spec.ClusterNetwork.Pods != nil {
+			addNoProxy(cluster.Spec.ClusterNetwork.Pods.CIDRBlocks...)
+		}
+		if cluster.Spec.ClusterNetwork.Services != nil {
+			addNoProxy(cluster.Spec.ClusterNetwork.Services.CIDRBlocks...)
+		}
+		if cluster.Spec.ClusterNetwork.ServiceDomain != "" {
+			serviceDomain = cluster.Spec.ClusterNetwork.ServiceDomain
+		}
+	}
+	addNoProxy(".svc", "."+serviceDomain)
+	return strings.Join(noProxy, ",")
+}
@@ -0,0 +1,268 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	rdeType "github.com/vmware/cluster-api-provider-cloud-director/pkg/vcdtypes/rde_type_1_3_0"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	addonsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=delete
+//+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=clusterresourcesets,verbs=get;list;watch;create;update;patch;delete
+
+const (
+	// RDEAddonsHashAnnotation records on the VCDCluster the hash of the last addons in the RDE spec which were
+	// processed by the RDEDesiredStateReconciler.
+	RDEAddonsHashAnnotation = "infrastructure.cluster.x-k8s.io/rde-addons-hash"
+
+	// RDEAddonOfLabel labels the objects applied from the addons of the RDE of a cluster with the name of the cluster.
+	RDEAddonOfLabel = "infrastructure.cluster.x-k8s.io/rde-addon-of"
+
+	// RDEAddonAnnotation records on the objects applied from the addons of an RDE the name of their addon.
+	RDEAddonAnnotation = "infrastructure.cluster.x-k8s.io/rde-addon"
+
+	// rdeAddonsFieldManager is the field manager of the server-side applies of the addons of the RDEs.
+	rdeAddonsFieldManager = "capvcd-rde-addons"
+)
+
+// rdeAddonKinds are the kinds of the objects the addons of an RDE can hold: the ConfigMaps holding the manifests of the
+// addons and the ClusterResourceSets installing them in the workload cluster. The objects of other kinds are rejected
+// so that VCD-side tooling cannot change the rest of the management cluster.
+var rdeAddonKinds = []schema.GroupVersionKind{
+	{Version: "v1", Kind: "ConfigMap"},
+	addonsv1.GroupVersion.WithKind("ClusterResourceSet"),
+}
+
+// getRDEAddonsHash returns the hash of the addons of the RDE, or an empty string if there are none.
+func getRDEAddonsHash(addons []rdeType.Addon) (string, error) {
+	if len(addons) == 0 {
+		return "", nil
+	}
+	addonsBytes, err := json.Marshal(addons)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal the addons of the RDE: [%v]", err)
+	}
+	return getCapiYamlHash(string(addonsBytes)), nil
+}
+
+func isRDEAddonKind(gvk schema.GroupVersionKind) bool {
+	for _, addonKind := range rdeAddonKinds {
+		if gvk == addonKind {
+			return true
+		}
+	}
+	return false
+}
+
+// getRDEAddonObjects parses the manifests of the addons of the RDE into the objects to apply in the namespace of the
+// cluster, labelled and owned by the cluster.
+func getRDEAddonObjects(addons []rdeType.Addon, cluster *clusterv1.Cluster) ([]*unstructured.Unstructured, error) {
+	objs := make([]*unstructured.Unstructured, 0)
+	seen := make(map[string]string)
+	for _, addon := range addons {
+		if addon.Name == "" {
+			return nil, fmt.Errorf("an addon of the RDE has no name")
+		}
+		yamlReader := k8syaml.NewYAMLReader(bufio.NewReader(bytes.NewReader([]byte(addon.Manifest))))
+		for {
+			yamlBytes, err := yamlReader.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to read the manifest of addon [%s]: [%v]", addon.Name, err)
+			}
+			if len(bytes.TrimSpace(yamlBytes)) == 0 {
+				continue
+			}
+
+			obj := &unstructured.Unstructured{}
+			if err = k8syaml.Unmarshal(yamlBytes, &obj.Object); err != nil {
+				return nil, fmt.Errorf("failed to parse object in the manifest of addon [%s]: [%v]", addon.Name, err)
+			}
+			if obj.Object == nil {
+				continue
+			}
+			gvk := obj.GroupVersionKind()
+			if !isRDEAddonKind(gvk) {
+				return nil, fmt.Errorf("addon [%s] holds a [%s]; only ConfigMaps and ClusterResourceSets of [%s] can be "+
+					"applied from the RDE", addon.Name, gvk.String(), addonsv1.GroupVersion.String())
+			}
+			if obj.GetName() == "" {
+				return nil, fmt.Errorf("addon [%s] holds a %s without a name", addon.Name, gvk.Kind)
+			}
+			if obj.GetNamespace() != "" && obj.GetNamespace() != cluster.Namespace {
+				return nil, fmt.Errorf("%s [%s] of addon [%s] is in namespace [%s] instead of the cluster namespace [%s]",
+					gvk.Kind, obj.GetName(), addon.Name, obj.GetNamespace(), cluster.Namespace)
+			}
+			key := gvk.Kind + "/" + obj.GetName()
+			if otherAddon, ok := seen[key]; ok {
+				return nil, fmt.Errorf("%s [%s] is held by both addons [%s] and [%s]", gvk.Kind, obj.GetName(),
+					otherAddon, addon.Name)
+			}
+			seen[key] = addon.Name
+
+			obj.SetNamespace(cluster.Namespace)
+			labels := obj.GetLabels()
+			if labels == nil {
+				labels = make(map[string]string)
+			}
+			labels[RDEAddonOfLabel] = cluster.Name
+			labels[clusterv1.ClusterNameLabel] = cluster.Name
+			obj.SetLabels(labels)
+			objAnnotations := obj.GetAnnotations()
+			if objAnnotations == nil {
+				objAnnotations = make(map[string]string)
+			}
+			objAnnotations[RDEAddonAnnotation] = addon.Name
+			obj.SetAnnotations(objAnnotations)
+			// the objects are garbage collected with the cluster, and moved along with it by clusterctl
+			obj.SetOwnerReferences([]metav1.OwnerReference{{
+				APIVersion: clusterv1.GroupVersion.String(),
+				Kind:       "Cluster",
+				Name:       cluster.Name,
+				UID:        cluster.UID,
+			}})
+			objs = append(objs, obj)
+		}
+	}
+	return objs, nil
+}
+
+// validateRDEAddonObjects checks that the objects of the addons do not take over objects of the namespace which were
+// not applied from the addons of the RDE of the cluster.
+func (r *RDEDesiredStateReconciler) validateRDEAddonObjects(ctx context.Context, cluster *clusterv1.Cluster,
+	objs []*unstructured.Unstructured) error {
+
+	for _, obj := range objs {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(obj.GroupVersionKind())
+		err := r.Client.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get %s [%s/%s]: [%v]", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+		}
+		if existing.GetLabels()[RDEAddonOfLabel] != cluster.Name {
+			return fmt.Errorf("%s [%s/%s] already exists and was not applied from the addons of the RDE of cluster [%s]",
+				obj.GetKind(), obj.GetNamespace(), obj.GetName(), cluster.Name)
+		}
+	}
+	return nil
+}
+
+// applyRDEAddons applies the objects of the addons of the RDE with server-side apply, and deletes the objects applied
+// from previous addons which are no longer part of them.
+func (r *RDEDesiredStateReconciler) applyRDEAddons(ctx context.Context, cluster *clusterv1.Cluster,
+	objs []*unstructured.Unstructured) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	desired := make(map[string]bool)
+	for _, obj := range objs {
+		if err := r.Client.Patch(ctx, obj, client.Apply, client.FieldOwner(rdeAddonsFieldManager),
+			client.ForceOwnership); err != nil {
+			return fmt.Errorf("failed to apply %s [%s/%s]: [%v]", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+		}
+		desired[obj.GetKind()+"/"+obj.GetName()] = true
+		log.V(3).Info("applied object of addon from RDE", "kind", obj.GetKind(), "name", obj.GetName())
+	}
+
+	for _, gvk := range rdeAddonKinds {
+		objList := &unstructured.UnstructuredList{}
+		objList.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := r.Client.List(ctx, objList, client.InNamespace(cluster.Namespace),
+			client.MatchingLabels{RDEAddonOfLabel: cluster.Name}); err != nil {
+			return fmt.Errorf("failed to list the %ss applied from the addons of the RDE: [%v]", gvk.Kind, err)
+		}
+		for i := range objList.Items {
+			obj := &objList.Items[i]
+			if desired[gvk.Kind+"/"+obj.GetName()] {
+				continue
+			}
+			if err := r.Client.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete %s [%s/%s] pruned from the addons of the RDE: [%v]", gvk.Kind,
+					obj.GetNamespace(), obj.GetName(), err)
+			}
+			log.Info("pruned object of addon removed from RDE", "kind", gvk.Kind, "name", obj.GetName())
+		}
+	}
+	return nil
+}
+
+// reconcileRDEAddons applies the addons of the RDE spec to the namespace of the cluster when they change. Invalid
+// addons are rejected as a whole and reported in the RDE, and not retried until they are changed in VCD.
+func (r *RDEDesiredStateReconciler) reconcileRDEAddons(ctx context.Context, cluster *clusterv1.Cluster,
+	vcdCluster *infrav1beta3.VCDCluster, capvcdRdeManager *capisdk.CapvcdRdeManager, addons []rdeType.Addon) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	addonsHash, err := getRDEAddonsHash(addons)
+	if err != nil {
+		return err
+	}
+	if addonsHash == vcdCluster.Annotations[RDEAddonsHashAnnotation] {
+		return nil
+	}
+
+	objs, err := getRDEAddonObjects(addons, cluster)
+	if err == nil {
+		err = r.validateRDEAddonObjects(ctx, cluster, objs)
+	}
+	if err != nil {
+		log.Error(err, "rejected addons from RDE", "rdeID", vcdCluster.Status.InfraId)
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.RdeAddonsError, vcdCluster.Status.InfraId, vcdCluster.Name,
+			fmt.Sprintf("rejected addons from RDE: [%v]", err))
+		// record the hash so that the same addons are not retried until they are changed in VCD
+		return r.recordRDEAddonsHash(ctx, vcdCluster, addonsHash)
+	}
+	if err = r.applyRDEAddons(ctx, cluster, objs); err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.RdeAddonsError, vcdCluster.Status.InfraId, vcdCluster.Name,
+			fmt.Sprintf("failed to apply addons from RDE: [%v]", err))
+		return errors.Wrapf(err, "failed to apply the addons of the RDE of cluster [%s]", vcdCluster.Name)
+	}
+	log.Info("applied addons from RDE", "rdeID", vcdCluster.Status.InfraId, "objects", len(objs))
+	capvcdRdeManager.AddToEventSet(ctx, capisdk.RdeAddonsApplied, vcdCluster.Status.InfraId, vcdCluster.Name,
+		"", false)
+	return r.recordRDEAddonsHash(ctx, vcdCluster, addonsHash)
+}
+
+// recordRDEAddonsHash records on the VCDCluster the hash of the addons of the RDE which were processed.
+func (r *RDEDesiredStateReconciler) recordRDEAddonsHash(ctx context.Context, vcdCluster *infrav1beta3.VCDCluster,
+	addonsHash string) error {
+
+	patch := client.MergeFrom(vcdCluster.DeepCopy())
+	if addonsHash == "" {
+		delete(vcdCluster.Annotations, RDEAddonsHashAnnotation)
+	} else {
+		if vcdCluster.Annotations == nil {
+			vcdCluster.Annotations = make(map[string]string)
+		}
+		vcdCluster.Annotations[RDEAddonsHashAnnotation] = addonsHash
+	}
+	if err := r.Client.Patch(ctx, vcdCluster, patch); err != nil {
+		return errors.Wrapf(err, "failed to record addons hash on VCDCluster [%s]", vcdCluster.Name)
+	}
+	return nil
+}
@@ -0,0 +1,407 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	kcpv1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/predicates"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// RDEDesiredStateHashAnnotation records on the VCDCluster the hash of the last CAPI yaml in the RDE spec which was
+	// processed by the RDEDesiredStateReconciler.
+	RDEDesiredStateHashAnnotation = "infrastructure.cluster.x-k8s.io/rde-desired-state-hash"
+
+	DefaultRDEDesiredStateSyncPeriod = time.Minute
+
+	machineDeploymentKind   = "MachineDeployment"
+	kubeadmControlPlaneKind = "KubeadmControlPlane"
+)
+
+// desiredNodePoolState is the part of a MachineDeployment or KubeadmControlPlane in the CAPI yaml of the RDE which
+// can be changed from VCD.
+type desiredNodePoolState struct {
+	Kind        string
+	Name        string
+	Replicas    *int32
+	Version     string
+	Annotations map[string]string
+}
+
+// RDEDesiredStateReconciler applies changes made to the CAPI yaml in the RDE spec by VCD-side tooling (scaling a node
+// pool, upgrading the kubernetes version) to the CAPI objects of the cluster, the replicas of the node pools of the RDE
+// spec to the node pools of the cluster, and the addons of the RDE spec to the namespace of the cluster.
+type RDEDesiredStateReconciler struct {
+	client.Client
+	SyncPeriod time.Duration
+	// Shards splits the clusters between the active replicas of the manager; all the clusters are synced if nil.
+	Shards *ClusterShardManager
+}
+
+func (r *RDEDesiredStateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	vcdCluster := &infrav1beta3.VCDCluster{}
+	if err := r.Client.Get(ctx, req.NamespacedName, vcdCluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if !vcdCluster.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	if vcdCluster.Status.InfraId == "" || strings.HasPrefix(vcdCluster.Status.InfraId, NoRdePrefix) {
+		log.V(4).Info("Skipping RDE desired state sync as cluster has no RDE", "InfraID", vcdCluster.Status.InfraId)
+		return ctrl.Result{}, nil
+	}
+
+	cluster, err := util.GetOwnerCluster(ctx, r.Client, vcdCluster.ObjectMeta)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if cluster == nil {
+		return ctrl.Result{}, nil
+	}
+	claimed, err := r.Shards.ClaimCluster(ctx, cluster.Namespace, cluster.Name, cluster)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Error claiming Cluster [%s]", cluster.Name)
+	}
+	if !claimed {
+		return ctrl.Result{RequeueAfter: r.SyncPeriod}, nil
+	}
+	if annotations.IsPaused(cluster, vcdCluster) {
+		// the sync is not scheduled while the cluster is paused, and resumes when it is unpaused
+		log.V(3).Info("Skipping RDE desired state sync as cluster is paused")
+		return ctrl.Result{}, nil
+	}
+	if !vcdCluster.Status.Ready || !cluster.Status.ControlPlaneReady {
+		return ctrl.Result{RequeueAfter: r.SyncPeriod}, nil
+	}
+
+	vcdClient, err := createVCDClientFromSecrets(ctx, r.Client, vcdCluster)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Error creating VCD client to sync desired state of Cluster [%s] from RDE",
+			vcdCluster.Name)
+	}
+	defer func() {
+		if vcdClient != nil && vcdClient.VCDClient != nil {
+			vcdClient.VCDClient.Client.Http.CloseIdleConnections()
+		}
+	}()
+
+	capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
+	_, capvcdSpec, _, _, err := capvcdRdeManager.GetCAPVCDEntity(ctx, vcdCluster.Status.InfraId)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get RDE with ID [%s] for cluster [%s]: [%v]",
+			vcdCluster.Status.InfraId, vcdCluster.Name, err)
+	}
+	statusPatch := client.MergeFrom(vcdCluster.DeepCopy())
+	if recordSubsystemRun(&vcdCluster.Status.LastSubsystemRuns.RDEDesiredStateSync) {
+		if err = r.Client.Status().Patch(ctx, vcdCluster, statusPatch); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to record RDE desired state sync on VCDCluster [%s]",
+				vcdCluster.Name)
+		}
+	}
+	if err = r.reconcileRDEAddons(ctx, cluster, vcdCluster, capvcdRdeManager, capvcdSpec.Addons); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err = r.reconcileRDENodePools(ctx, cluster, vcdCluster, capvcdRdeManager, capvcdSpec.NodePools); err != nil {
+		return ctrl.Result{}, err
+	}
+	if capvcdSpec.CapiYaml == "" {
+		return ctrl.Result{RequeueAfter: r.SyncPeriod}, nil
+	}
+
+	capiYamlHash := getCapiYamlHash(capvcdSpec.CapiYaml)
+	lastHash, synced := vcdCluster.Annotations[RDEDesiredStateHashAnnotation]
+	if lastHash == capiYamlHash {
+		return ctrl.Result{RequeueAfter: r.SyncPeriod}, nil
+	}
+	if synced {
+		desiredStates, err := getDesiredNodePoolStates(capvcdSpec.CapiYaml, vcdCluster.Namespace)
+		if err == nil {
+			err = r.applyDesiredNodePoolStates(ctx, cluster, desiredStates)
+		}
+		if err != nil {
+			log.Error(err, "rejected desired state from RDE", "rdeID", vcdCluster.Status.InfraId)
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.RdeDesiredStateError, vcdCluster.Status.InfraId,
+				vcdCluster.Name, fmt.Sprintf("rejected desired state from RDE: [%v]", err))
+		} else {
+			log.Info("applied desired state from RDE", "rdeID", vcdCluster.Status.InfraId)
+			capvcdRdeManager.AddToEventSet(ctx, capisdk.RdeDesiredStateApplied, vcdCluster.Status.InfraId,
+				vcdCluster.Name, "", false)
+		}
+	} else {
+		// the CAPI yaml present when the sync starts reflects the state the cluster was created with; use it as the
+		// baseline so that only subsequent changes from VCD are applied.
+		log.Info("recording baseline desired state from RDE", "rdeID", vcdCluster.Status.InfraId)
+	}
+
+	// record the hash even if the desired state was rejected so that the same desired state is not retried until
+	// it is changed in VCD
+	patch := client.MergeFrom(vcdCluster.DeepCopy())
+	if vcdCluster.Annotations == nil {
+		vcdCluster.Annotations = make(map[string]string)
+	}
+	vcdCluster.Annotations[RDEDesiredStateHashAnnotation] = capiYamlHash
+	if err = r.Client.Patch(ctx, vcdCluster, patch); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to record desired state hash on VCDCluster [%s]", vcdCluster.Name)
+	}
+
+	return ctrl.Result{RequeueAfter: r.SyncPeriod}, nil
+}
+
+func getCapiYamlHash(capiYaml string) string {
+	hash := sha256.Sum256([]byte(capiYaml))
+	return hex.EncodeToString(hash[:])
+}
+
+// getDesiredNodePoolStates extracts the desired state of the MachineDeployments and KubeadmControlPlanes from the
+// CAPI yaml of the RDE.
+func getDesiredNodePoolStates(capiYaml string, namespace string) ([]desiredNodePoolState, error) {
+	desiredStates := make([]desiredNodePoolState, 0)
+	yamlReader := k8syaml.NewYAMLReader(bufio.NewReader(bytes.NewReader([]byte(capiYaml))))
+	for {
+		yamlBytes, err := yamlReader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read CAPI yaml: [%v]", err)
+		}
+		if len(bytes.TrimSpace(yamlBytes)) == 0 {
+			continue
+		}
+
+		obj := unstructured.Unstructured{}
+		if err = k8syaml.Unmarshal(yamlBytes, &obj.Object); err != nil {
+			return nil, fmt.Errorf("failed to parse object in CAPI yaml: [%v]", err)
+		}
+		kind := obj.GetKind()
+		if kind != machineDeploymentKind && kind != kubeadmControlPlaneKind {
+			continue
+		}
+		if obj.GetNamespace() != "" && obj.GetNamespace() != namespace {
+			return nil, fmt.Errorf("%s [%s] in CAPI yaml is in namespace [%s] instead of the cluster namespace [%s]",
+				kind, obj.GetName(), obj.GetNamespace(), namespace)
+		}
+
+		desiredState := desiredNodePoolState{
+			Kind:        kind,
+			Name:        obj.GetName(),
+			Annotations: make(map[string]string),
+		}
+		if replicas, found, err := unstructured.NestedInt64(obj.Object, "spec", "replicas"); err != nil {
+			return nil, fmt.Errorf("invalid replicas of %s [%s] in CAPI yaml: [%v]", kind, obj.GetName(), err)
+		} else if found {
+			if replicas < 0 || replicas > math.MaxInt32 {
+				return nil, fmt.Errorf("replicas [%d] of %s [%s] in CAPI yaml are out of range [0, %d]", replicas,
+					kind, obj.GetName(), math.MaxInt32)
+			}
+			replicaCount := int32(replicas)
+			desiredState.Replicas = &replicaCount
+		}
+		versionPath := []string{"spec", "version"}
+		if kind == machineDeploymentKind {
+			versionPath = []string{"spec", "template", "spec", "version"}
+		}
+		if version, _, err := unstructured.NestedString(obj.Object, versionPath...); err != nil {
+			return nil, fmt.Errorf("invalid version of %s [%s] in CAPI yaml: [%v]", kind, obj.GetName(), err)
+		} else {
+			desiredState.Version = version
+		}
+		for _, annotation := range retainedAnnotations {
+			if value, exists := obj.GetAnnotations()[annotation]; exists {
+				desiredState.Annotations[annotation] = value
+			}
+		}
+		desiredStates = append(desiredStates, desiredState)
+	}
+
+	return desiredStates, nil
+}
+
+// validateDesiredVersion ensures that a kubernetes version from the RDE is valid and is not a downgrade.
+func validateDesiredVersion(kind string, name string, currentVersion string, desiredVersion string) error {
+	desiredSemVer, err := semver.ParseTolerant(desiredVersion)
+	if err != nil {
+		return fmt.Errorf("invalid version [%s] for %s [%s]: [%v]", desiredVersion, kind, name, err)
+	}
+	currentSemVer, err := semver.ParseTolerant(currentVersion)
+	if err != nil {
+		// nothing to compare against
+		return nil
+	}
+	if desiredSemVer.LT(currentSemVer) {
+		return fmt.Errorf("version of %s [%s] cannot be downgraded from [%s] to [%s]",
+			kind, name, currentVersion, desiredVersion)
+	}
+	return nil
+}
+
+// applyDesiredNodePoolStates validates all the desired states first and then patches the CAPI objects so that an
+// invalid desired state is rejected as a whole.
+func (r *RDEDesiredStateReconciler) applyDesiredNodePoolStates(ctx context.Context, cluster *clusterv1.Cluster,
+	desiredStates []desiredNodePoolState) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	// The replicas and versions of a managed topology are owned by the topology controller, which would revert them.
+	if cluster.Spec.Topology != nil {
+		return fmt.Errorf("cluster [%s] has a managed topology; the replicas and versions should be changed in "+
+			"the topology of the Cluster", cluster.Name)
+	}
+
+	kcps := make(map[string]*kcpv1.KubeadmControlPlane)
+	mds := make(map[string]*clusterv1.MachineDeployment)
+	controlPlaneVersion := ""
+	for _, desiredState := range desiredStates {
+		key := types.NamespacedName{Namespace: cluster.Namespace, Name: desiredState.Name}
+		switch desiredState.Kind {
+		case kubeadmControlPlaneKind:
+			kcp := &kcpv1.KubeadmControlPlane{}
+			if err := r.Client.Get(ctx, key, kcp); err != nil {
+				return fmt.Errorf("failed to get KubeadmControlPlane [%s]: [%v]", key.String(), err)
+			}
+			if kcp.Labels[clusterv1.ClusterNameLabel] != cluster.Name {
+				return fmt.Errorf("KubeadmControlPlane [%s] does not belong to cluster [%s]", key.String(), cluster.Name)
+			}
+			if desiredState.Replicas != nil && (*desiredState.Replicas < 1 || *desiredState.Replicas%2 == 0) {
+				return fmt.Errorf("invalid replicas [%d] for KubeadmControlPlane [%s]; control plane replicas should be an odd number",
+					*desiredState.Replicas, key.String())
+			}
+			if desiredState.Version != "" {
+				if err := validateDesiredVersion(desiredState.Kind, key.String(), kcp.Spec.Version,
+					desiredState.Version); err != nil {
+					return err
+				}
+				controlPlaneVersion = desiredState.Version
+			}
+			kcps[desiredState.Name] = kcp
+		case machineDeploymentKind:
+			md := &clusterv1.MachineDeployment{}
+			if err := r.Client.Get(ctx, key, md); err != nil {
+				return fmt.Errorf("failed to get MachineDeployment [%s]: [%v]", key.String(), err)
+			}
+			if md.Spec.ClusterName != cluster.Name {
+				return fmt.Errorf("MachineDeployment [%s] does not belong to cluster [%s]", key.String(), cluster.Name)
+			}
+			if desiredState.Replicas != nil && *desiredState.Replicas < 0 {
+				return fmt.Errorf("invalid replicas [%d] for MachineDeployment [%s]", *desiredState.Replicas, key.String())
+			}
+			if desiredState.Version != "" && md.Spec.Template.Spec.Version != nil {
+				if err := validateDesiredVersion(desiredState.Kind, key.String(), *md.Spec.Template.Spec.Version,
+					desiredState.Version); err != nil {
+					return err
+				}
+			}
+			mds[desiredState.Name] = md
+		}
+	}
+
+	// workers should not be upgraded past the control plane
+	if controlPlaneVersion != "" {
+		controlPlaneSemVer, _ := semver.ParseTolerant(controlPlaneVersion)
+		for _, desiredState := range desiredStates {
+			if desiredState.Kind != machineDeploymentKind || desiredState.Version == "" {
+				continue
+			}
+			if workerSemVer, _ := semver.ParseTolerant(desiredState.Version); workerSemVer.GT(controlPlaneSemVer) {
+				return fmt.Errorf("version [%s] of MachineDeployment [%s] is newer than the control plane version [%s]",
+					desiredState.Version, desiredState.Name, controlPlaneVersion)
+			}
+		}
+	}
+
+	for _, desiredState := range desiredStates {
+		switch desiredState.Kind {
+		case kubeadmControlPlaneKind:
+			kcp := kcps[desiredState.Name]
+			patch := client.MergeFrom(kcp.DeepCopy())
+			if desiredState.Replicas != nil {
+				kcp.Spec.Replicas = desiredState.Replicas
+			}
+			if desiredState.Version != "" {
+				kcp.Spec.Version = desiredState.Version
+			}
+			if err := r.Client.Patch(ctx, kcp, patch); err != nil {
+				return fmt.Errorf("failed to patch KubeadmControlPlane [%s]: [%v]", kcp.Name, err)
+			}
+			log.V(3).Info("applied desired state from RDE to KubeadmControlPlane", "kcp", kcp.Name)
+		case machineDeploymentKind:
+			md := mds[desiredState.Name]
+			patch := client.MergeFrom(md.DeepCopy())
+			if desiredState.Replicas != nil {
+				md.Spec.Replicas = desiredState.Replicas
+			}
+			if desiredState.Version != "" {
+				version := desiredState.Version
+				md.Spec.Template.Spec.Version = &version
+			}
+			for annotation, value := range desiredState.Annotations {
+				if md.Annotations == nil {
+					md.Annotations = make(map[string]string)
+				}
+				md.Annotations[annotation] = value
+			}
+			if err := r.Client.Patch(ctx, md, patch); err != nil {
+				return fmt.Errorf("failed to patch MachineDeployment [%s]: [%v]", md.Name, err)
+			}
+			log.V(3).Info("applied desired state from RDE to MachineDeployment", "md", md.Name)
+		}
+	}
+
+	return nil
+}
+
+func (r *RDEDesiredStateReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager,
+	options controller.Options) error {
+	if r.SyncPeriod == 0 {
+		r.SyncPeriod = DefaultRDEDesiredStateSyncPeriod
+	}
+	c, err := ctrl.NewControllerManagedBy(mgr).
+		Named("rdedesiredstate").
+		For(&infrav1beta3.VCDCluster{}, builder.WithPredicates(ignoreSubsystemRunUpdates())).
+		WithOptions(options).
+		Build(r)
+	if err != nil {
+		return err
+	}
+	return c.Watch(
+		&source.Kind{Type: &clusterv1.Cluster{}},
+		handler.EnqueueRequestsFromMapFunc(util.ClusterToInfrastructureMapFunc(ctx,
+			infrav1beta3.GroupVersion.WithKind("VCDCluster"), mgr.GetClient(), &infrav1beta3.VCDCluster{})),
+		predicates.ClusterUnpaused(ctrl.LoggerFrom(ctx)),
+	)
+}
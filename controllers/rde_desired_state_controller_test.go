@@ -0,0 +1,53 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetDesiredNodePoolStatesReplicas(t *testing.T) {
+	testCases := []struct {
+		replicas string
+		want     int32
+		wantErr  bool
+	}{
+		{replicas: "3", want: 3},
+		{replicas: "0", want: 0},
+		{replicas: "2147483647", want: 2147483647},
+		{replicas: "2147483648", wantErr: true},
+		{replicas: "-1", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.replicas, func(t *testing.T) {
+			capiYaml := fmt.Sprintf(`apiVersion: cluster.x-k8s.io/v1beta1
+kind: MachineDeployment
+metadata:
+  name: md-0
+  namespace: ns
+spec:
+  replicas: %s
+`, tc.replicas)
+			desiredStates, err := getDesiredNodePoolStates(capiYaml, "ns")
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("replicas were accepted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: [%v]", err)
+			}
+			if len(desiredStates) != 1 || desiredStates[0].Replicas == nil {
+				t.Fatalf("got desired states [%v], want the replicas of md-0", desiredStates)
+			}
+			if *desiredStates[0].Replicas != tc.want {
+				t.Errorf("got replicas [%d], want [%d]", *desiredStates[0].Replicas, tc.want)
+			}
+		})
+	}
+}
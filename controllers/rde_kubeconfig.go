@@ -0,0 +1,170 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	rdeType "github.com/vmware/cluster-api-provider-cloud-director/pkg/vcdtypes/rde_type_1_3_0"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	kcfg "sigs.k8s.io/cluster-api/util/kubeconfig"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// RDEKubeConfigKeySecretKey is the key of the Secret referenced by --rde-kubeconfig-key-secret holding the 32-byte
+	// AES-256 key the kubeconfigs are encrypted with in the RDEs.
+	RDEKubeConfigKeySecretKey = "key"
+
+	// RDEKubeConfigKeyIDSecretKey is the optional key of the Secret referenced by --rde-kubeconfig-key-secret holding
+	// the ID of the key reported in the RDEs, so that VCD-side tooling picks the key to decrypt the kubeconfigs with.
+	// The ID defaults to a digest of the key.
+	RDEKubeConfigKeyIDSecretKey = "keyId"
+)
+
+// rdeKubeConfigKey is the key the kubeconfigs are encrypted with in the RDEs.
+type rdeKubeConfigKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// getRDEKubeConfigKey reads the key the kubeconfigs are encrypted with in the RDEs, or returns nil if they are stored
+// in clear. The Secret is read at every use so that the key can be rotated without restarting the manager.
+func (r *VCDClusterReconciler) getRDEKubeConfigKey(ctx context.Context) (*rdeKubeConfigKey, error) {
+	if r.RDEKubeConfigKeySecret == nil {
+		return nil, nil
+	}
+	secret := &corev1.Secret{}
+	if err := r.Client.Get(ctx, *r.RDEKubeConfigKeySecret, secret); err != nil {
+		return nil, fmt.Errorf("failed to get the Secret [%s] of the key of the kubeconfigs in the RDEs: [%v]",
+			r.RDEKubeConfigKeySecret.String(), err)
+	}
+	keyBytes := secret.Data[RDEKubeConfigKeySecretKey]
+	if len(keyBytes) != 32 {
+		return nil, fmt.Errorf("the [%s] key of the Secret [%s] must hold a 32-byte key, found [%d] bytes",
+			RDEKubeConfigKeySecretKey, r.RDEKubeConfigKeySecret.String(), len(keyBytes))
+	}
+	block, err := aes.NewCipher(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key of the kubeconfigs in the RDEs: [%v]", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key of the kubeconfigs in the RDEs: [%v]", err)
+	}
+	keyID := string(secret.Data[RDEKubeConfigKeyIDSecretKey])
+	if keyID == "" {
+		digest := sha256.Sum256(keyBytes)
+		keyID = hex.EncodeToString(digest[:8])
+	}
+	return &rdeKubeConfigKey{id: keyID, aead: aead}, nil
+}
+
+// encryptRDEKubeConfig encrypts the kubeconfig with AES-256-GCM, bound to the RDE. The result is the base64 encoding
+// of the nonce followed by the ciphertext.
+func encryptRDEKubeConfig(key *rdeKubeConfigKey, kubeConfig string, rdeID string) (string, error) {
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate the nonce of the kubeconfig: [%v]", err)
+	}
+	sealed := key.aead.Seal(nonce, nonce, []byte(kubeConfig), []byte(rdeID))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptRDEKubeConfig decrypts a kubeconfig encrypted by encryptRDEKubeConfig.
+func decryptRDEKubeConfig(key *rdeKubeConfigKey, encrypted string, rdeID string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted kubeconfig: [%v]", err)
+	}
+	nonceSize := key.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", fmt.Errorf("invalid encrypted kubeconfig: too short")
+	}
+	kubeConfig, err := key.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(rdeID))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the kubeconfig: [%v]", err)
+	}
+	return string(kubeConfig), nil
+}
+
+// getRDEPrivateSection returns the private section of the RDE holding the kubeconfig of the cluster, encrypted with
+// the provider-managed key if one is configured, and whether the kubeconfig changed since it was published, e.g.
+// because its certificates were rotated. The private section is emptied if the publishing of the kubeconfigs is
+// disabled.
+func (r *VCDClusterReconciler) getRDEPrivateSection(ctx context.Context, cluster *clusterv1.Cluster, rdeID string,
+	private rdeType.PrivateSection) (rdeType.PrivateSection, bool, error) {
+
+	if r.DisableRDEKubeConfig {
+		return rdeType.PrivateSection{}, false, nil
+	}
+	kubeConfigBytes, err := kcfg.FromSecret(ctx, r.Client, client.ObjectKeyFromObject(cluster))
+	if err != nil {
+		return private, false, fmt.Errorf("failed to get the kubeconfig of cluster [%s]: [%v]", cluster.Name, err)
+	}
+	kubeConfig := string(kubeConfigBytes)
+	key, err := r.getRDEKubeConfigKey(ctx)
+	if err != nil {
+		return private, false, err
+	}
+
+	// the kubeconfig published is decrypted to be compared as its encryption changes every time
+	publishedKubeConfig := private.KubeConfig
+	if private.KubeConfigEncryptionKeyID != "" {
+		publishedKubeConfig = ""
+		if key != nil && key.id == private.KubeConfigEncryptionKeyID {
+			publishedKubeConfig, _ = decryptRDEKubeConfig(key, private.KubeConfig, rdeID)
+		}
+	}
+	keyID := ""
+	if key != nil {
+		keyID = key.id
+	}
+	if publishedKubeConfig == kubeConfig && private.KubeConfigEncryptionKeyID == keyID {
+		return private, false, nil
+	}
+	// the kubeconfig published with a key no longer known is considered rotated
+	rotated := private.KubeConfig != "" && publishedKubeConfig != kubeConfig &&
+		(private.KubeConfigEncryptionKeyID == "" || publishedKubeConfig != "")
+
+	if key == nil {
+		return rdeType.PrivateSection{KubeConfig: kubeConfig}, rotated, nil
+	}
+	encrypted, err := encryptRDEKubeConfig(key, kubeConfig, rdeID)
+	if err != nil {
+		return private, false, err
+	}
+	return rdeType.PrivateSection{KubeConfig: encrypted, KubeConfigEncryptionKeyID: key.id}, rotated, nil
+}
+
+// reconcileRDEKubeConfig patches the private section of the RDE with the kubeconfig of the cluster, and records the
+// rotations of the kubeconfig in the eventSet of the RDE.
+func (r *VCDClusterReconciler) reconcileRDEKubeConfig(ctx context.Context, cluster *clusterv1.Cluster,
+	capvcdRdeManager *capisdk.CapvcdRdeManager, rdeID string, capvcdStatus *rdeType.CAPVCDStatus,
+	capvcdStatusPatch map[string]interface{}) error {
+
+	private, rotated, err := r.getRDEPrivateSection(ctx, cluster, rdeID, capvcdStatus.Private)
+	if err != nil {
+		return err
+	}
+	if private != capvcdStatus.Private {
+		capvcdStatusPatch["Private"] = private
+	}
+	if rotated {
+		capvcdRdeManager.QueueRDEEvent(capisdk.KubeConfigRotated, "", cluster.Name, nil)
+	}
+	return nil
+}
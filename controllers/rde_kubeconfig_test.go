@@ -0,0 +1,79 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+)
+
+func newTestRDEKubeConfigKey(t *testing.T, id string, keyByte byte) *rdeKubeConfigKey {
+	block, err := aes.NewCipher(bytes.Repeat([]byte{keyByte}, 32))
+	if err != nil {
+		t.Fatalf("unable to create the cipher: [%v]", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("unable to create the AEAD: [%v]", err)
+	}
+	return &rdeKubeConfigKey{id: id, aead: aead}
+}
+
+func TestEncryptRDEKubeConfig(t *testing.T) {
+	const kubeConfig = "apiVersion: v1\nkind: Config\n"
+	const rdeID = "urn:vcloud:entity:vmware:capvcdCluster:1234"
+	key := newTestRDEKubeConfigKey(t, "key-1", 1)
+
+	encrypted, err := encryptRDEKubeConfig(key, kubeConfig, rdeID)
+	if err != nil {
+		t.Fatalf("unexpected error: [%v]", err)
+	}
+	if encrypted == kubeConfig {
+		t.Fatalf("kubeconfig was not encrypted")
+	}
+	reencrypted, err := encryptRDEKubeConfig(key, kubeConfig, rdeID)
+	if err != nil {
+		t.Fatalf("unexpected error: [%v]", err)
+	}
+	if reencrypted == encrypted {
+		t.Errorf("kubeconfig was encrypted twice with the same nonce")
+	}
+
+	testCases := []struct {
+		name      string
+		key       *rdeKubeConfigKey
+		rdeID     string
+		encrypted string
+		wantErr   bool
+	}{
+		{name: "same key and RDE", key: key, rdeID: rdeID, encrypted: encrypted},
+		{name: "other RDE", key: key, rdeID: "urn:vcloud:entity:vmware:capvcdCluster:5678", encrypted: encrypted,
+			wantErr: true},
+		{name: "other key", key: newTestRDEKubeConfigKey(t, "key-2", 2), rdeID: rdeID, encrypted: encrypted,
+			wantErr: true},
+		{name: "not base64", key: key, rdeID: rdeID, encrypted: "not base64!", wantErr: true},
+		{name: "too short", key: key, rdeID: rdeID, encrypted: "AAAA", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decrypted, err := decryptRDEKubeConfig(tc.key, tc.encrypted, tc.rdeID)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("got kubeconfig [%s], want an error", decrypted)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: [%v]", err)
+			}
+			if decrypted != kubeConfig {
+				t.Errorf("got kubeconfig [%s], want [%s]", decrypted, kubeConfig)
+			}
+		})
+	}
+}
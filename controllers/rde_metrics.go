@@ -0,0 +1,95 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// rdeFreshnessDesc describes the per-cluster metric reporting how long ago the RDE of the cluster was last updated.
+var rdeFreshnessDesc = prometheus.NewDesc(
+	"capvcd_rde_seconds_since_last_update",
+	"Seconds since the RDE of the cluster was last successfully updated. Until the first successful update, the "+
+		"seconds since the cluster was first reconciled by this controller instance are reported.",
+	[]string{"namespace", "cluster", "rde_id"},
+	nil,
+)
+
+type trackedRDE struct {
+	rdeID        string
+	trackingFrom time.Time
+}
+
+// rdeFreshnessCollector computes the freshness of the RDE of every tracked cluster when the metrics are scraped, so
+// that the metric keeps growing while the RDE of a cluster is not updated.
+type rdeFreshnessCollector struct {
+	lock     sync.Mutex
+	clusters map[types.NamespacedName]trackedRDE
+}
+
+var rdeFreshness = &rdeFreshnessCollector{
+	clusters: make(map[types.NamespacedName]trackedRDE),
+}
+
+func init() {
+	metrics.Registry.MustRegister(rdeFreshness)
+}
+
+func (c *rdeFreshnessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- rdeFreshnessDesc
+}
+
+func (c *rdeFreshnessCollector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+	for clusterKey, tracked := range c.clusters {
+		lastUpdateTime, ok := capisdk.GetRDELastUpdateTime(tracked.rdeID)
+		if !ok {
+			lastUpdateTime = tracked.trackingFrom
+		}
+		ch <- prometheus.MustNewConstMetric(rdeFreshnessDesc, prometheus.GaugeValue, now.Sub(lastUpdateTime).Seconds(),
+			clusterKey.Namespace, clusterKey.Name, tracked.rdeID)
+	}
+}
+
+// trackRDEFreshness starts reporting the freshness of the RDE of the VCDCluster. Clusters without an RDE are not
+// reported.
+func trackRDEFreshness(vcdCluster *infrav1beta3.VCDCluster) {
+	rdeID := vcdCluster.Status.InfraId
+	if rdeID == "" || strings.HasPrefix(rdeID, NoRdePrefix) {
+		return
+	}
+	clusterKey := types.NamespacedName{Namespace: vcdCluster.Namespace, Name: vcdCluster.Name}
+
+	rdeFreshness.lock.Lock()
+	defer rdeFreshness.lock.Unlock()
+	if tracked, ok := rdeFreshness.clusters[clusterKey]; ok && tracked.rdeID == rdeID {
+		return
+	}
+	rdeFreshness.clusters[clusterKey] = trackedRDE{rdeID: rdeID, trackingFrom: time.Now()}
+}
+
+// untrackRDEFreshness stops reporting the freshness of the RDE of the VCDCluster once the cluster is deleted.
+func untrackRDEFreshness(vcdCluster *infrav1beta3.VCDCluster) {
+	clusterKey := types.NamespacedName{Namespace: vcdCluster.Namespace, Name: vcdCluster.Name}
+
+	rdeFreshness.lock.Lock()
+	defer rdeFreshness.lock.Unlock()
+	if tracked, ok := rdeFreshness.clusters[clusterKey]; ok {
+		capisdk.ForgetRDEUpdates(tracked.rdeID)
+		delete(rdeFreshness.clusters, clusterKey)
+	}
+}
@@ -0,0 +1,180 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	rdeType "github.com/vmware/cluster-api-provider-cloud-director/pkg/vcdtypes/rde_type_1_3_0"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	kcpv1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RDENodePoolsHashAnnotation records on the VCDCluster the hash of the last node pools in the RDE spec which were
+// processed by the RDEDesiredStateReconciler.
+const RDENodePoolsHashAnnotation = "infrastructure.cluster.x-k8s.io/rde-node-pools-hash"
+
+// getRDENodePoolsHash returns the hash of the node pools of the RDE spec, or an empty string if there are none.
+func getRDENodePoolsHash(nodePools []rdeType.NodePoolReplicas) (string, error) {
+	if len(nodePools) == 0 {
+		return "", nil
+	}
+	nodePoolsBytes, err := json.Marshal(nodePools)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal the node pools of the RDE: [%v]", err)
+	}
+	return getCapiYamlHash(string(nodePoolsBytes)), nil
+}
+
+// getNodePoolReplicas returns the replicas of the KubeadmControlPlanes and MachineDeployments of the cluster by name.
+func getNodePoolReplicas(kcpList *kcpv1.KubeadmControlPlaneList,
+	mdList *clusterv1.MachineDeploymentList) map[string]int32 {
+
+	replicas := make(map[string]int32)
+	for _, kcp := range kcpList.Items {
+		if kcp.Spec.Replicas != nil {
+			replicas[kcp.Name] = *kcp.Spec.Replicas
+		}
+	}
+	for _, md := range mdList.Items {
+		if md.Spec.Replicas != nil {
+			replicas[md.Name] = *md.Spec.Replicas
+		}
+	}
+	return replicas
+}
+
+// getPublishedRDENodePools returns the node pools of the RDE spec with the replicas the node pools were scaled to in
+// the management cluster, or nil if they are up to date. The node pools are only published once the latest node pools
+// of the RDE spec were processed, so that a scaling requested from VCD is not overwritten before it is applied.
+func getPublishedRDENodePools(vcdCluster *infrav1beta3.VCDCluster, nodePools []rdeType.NodePoolReplicas,
+	kcpList *kcpv1.KubeadmControlPlaneList, mdList *clusterv1.MachineDeploymentList) []rdeType.NodePoolReplicas {
+
+	nodePoolsHash, err := getRDENodePoolsHash(nodePools)
+	if err != nil || nodePoolsHash == "" || nodePoolsHash != vcdCluster.Annotations[RDENodePoolsHashAnnotation] {
+		return nil
+	}
+	replicas := getNodePoolReplicas(kcpList, mdList)
+	publishedNodePools := make([]rdeType.NodePoolReplicas, 0, len(nodePools))
+	for _, nodePool := range nodePools {
+		if nodePoolReplicas, ok := replicas[nodePool.Name]; ok {
+			nodePool.DesiredReplicas = nodePoolReplicas
+		}
+		publishedNodePools = append(publishedNodePools, nodePool)
+	}
+	if reflect.DeepEqual(publishedNodePools, nodePools) {
+		return nil
+	}
+	return publishedNodePools
+}
+
+// getRDENodePoolStates converts the node pools of the RDE spec to the desired states of the KubeadmControlPlanes and
+// MachineDeployments of the cluster, and returns whether any of them changes the replicas of its node pool.
+func (r *RDEDesiredStateReconciler) getRDENodePoolStates(ctx context.Context, cluster *clusterv1.Cluster,
+	nodePools []rdeType.NodePoolReplicas) ([]desiredNodePoolState, bool, error) {
+
+	kcpList, err := getAllKubeadmControlPlaneForCluster(ctx, r.Client, *cluster)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list the KubeadmControlPlanes of cluster [%s]: [%v]", cluster.Name, err)
+	}
+	mdList, err := getAllMachineDeploymentsForCluster(ctx, r.Client, *cluster)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list the MachineDeployments of cluster [%s]: [%v]", cluster.Name, err)
+	}
+	kinds := make(map[string]string)
+	autoscaled := make(map[string]bool)
+	for _, kcp := range kcpList.Items {
+		kinds[kcp.Name] = kubeadmControlPlaneKind
+	}
+	for _, md := range mdList.Items {
+		kinds[md.Name] = machineDeploymentKind
+		_, autoscaled[md.Name] = md.Annotations[AutoscalerMinSizeAnnotation]
+	}
+	replicas := getNodePoolReplicas(kcpList, mdList)
+
+	desiredStates := make([]desiredNodePoolState, 0, len(nodePools))
+	changed := false
+	for _, nodePool := range nodePools {
+		kind, ok := kinds[nodePool.Name]
+		if !ok {
+			return nil, false, fmt.Errorf("node pool [%s] is not a KubeadmControlPlane or MachineDeployment of cluster [%s]",
+				nodePool.Name, cluster.Name)
+		}
+		currentReplicas, hasReplicas := replicas[nodePool.Name]
+		if hasReplicas && currentReplicas == nodePool.DesiredReplicas {
+			continue
+		}
+		// the cluster autoscaler owns the replicas of the node pools it scales, and would revert them
+		if autoscaled[nodePool.Name] {
+			return nil, false, fmt.Errorf("node pool [%s] is scaled by the cluster autoscaler; its minimum and "+
+				"maximum size should be changed instead", nodePool.Name)
+		}
+		desiredReplicas := nodePool.DesiredReplicas
+		desiredStates = append(desiredStates, desiredNodePoolState{
+			Kind:     kind,
+			Name:     nodePool.Name,
+			Replicas: &desiredReplicas,
+		})
+		changed = true
+	}
+	return desiredStates, changed, nil
+}
+
+// reconcileRDENodePools scales the node pools of the cluster to the replicas of the node pools of the RDE spec when
+// they change. Invalid replicas are rejected as a whole and reported in the RDE, and not retried until they are changed
+// in VCD.
+func (r *RDEDesiredStateReconciler) reconcileRDENodePools(ctx context.Context, cluster *clusterv1.Cluster,
+	vcdCluster *infrav1beta3.VCDCluster, capvcdRdeManager *capisdk.CapvcdRdeManager,
+	nodePools []rdeType.NodePoolReplicas) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	nodePoolsHash, err := getRDENodePoolsHash(nodePools)
+	if err != nil {
+		return err
+	}
+	if nodePoolsHash == vcdCluster.Annotations[RDENodePoolsHashAnnotation] {
+		return nil
+	}
+
+	desiredStates, changed, err := r.getRDENodePoolStates(ctx, cluster, nodePools)
+	if err == nil && changed {
+		err = r.applyDesiredNodePoolStates(ctx, cluster, desiredStates)
+	}
+	if err != nil {
+		log.Error(err, "rejected node pools from RDE", "rdeID", vcdCluster.Status.InfraId)
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.RdeDesiredStateError, vcdCluster.Status.InfraId,
+			vcdCluster.Name, fmt.Sprintf("rejected node pools from RDE: [%v]", err))
+	} else if changed {
+		log.Info("scaled node pools from RDE", "rdeID", vcdCluster.Status.InfraId)
+		capvcdRdeManager.AddToEventSet(ctx, capisdk.RdeDesiredStateApplied, vcdCluster.Status.InfraId,
+			vcdCluster.Name, "node pools scaled", false)
+	}
+
+	// record the hash even if the node pools were rejected so that the same replicas are not retried until they are
+	// changed in VCD
+	patch := client.MergeFrom(vcdCluster.DeepCopy())
+	if nodePoolsHash == "" {
+		delete(vcdCluster.Annotations, RDENodePoolsHashAnnotation)
+	} else {
+		if vcdCluster.Annotations == nil {
+			vcdCluster.Annotations = make(map[string]string)
+		}
+		vcdCluster.Annotations[RDENodePoolsHashAnnotation] = nodePoolsHash
+	}
+	if err = r.Client.Patch(ctx, vcdCluster, patch); err != nil {
+		return errors.Wrapf(err, "failed to record node pools hash on VCDCluster [%s]", vcdCluster.Name)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+// readinessObject is a VCDCluster or VCDMachine whose readiness is reported through status.ready and the Ready condition.
+type readinessObject interface {
+	conditions.Setter
+	metav1.Object
+}
+
+// setReadiness overrides the Ready condition computed from the summary of the other conditions so that a deleting,
+// paused or degraded object is distinguishable from one which is still being provisioned. It returns the value
+// status.ready should take: status.ready follows the Cluster API contract and stays true while a provisioned object
+// is paused or degraded, as its infrastructure still exists, and is cleared as soon as the object is being deleted.
+//
+// The Ready condition must have been computed by conditions.SetSummary before calling this function.
+func setReadiness(obj readinessObject, ready bool, paused bool, reconcileErr error) bool {
+	switch {
+	case !obj.GetDeletionTimestamp().IsZero():
+		conditions.MarkFalse(obj, clusterv1.ReadyCondition, clusterv1.DeletingReason, clusterv1.ConditionSeverityInfo,
+			"infrastructure is being deleted")
+		return false
+	case paused:
+		conditions.MarkFalse(obj, clusterv1.ReadyCondition, PausedReason, clusterv1.ConditionSeverityInfo,
+			"reconciliation is paused")
+	case reconcileErr != nil && ready:
+		conditions.MarkFalse(obj, clusterv1.ReadyCondition, DegradedReason, clusterv1.ConditionSeverityWarning,
+			fmt.Sprintf("provisioned infrastructure failed to reconcile: [%v]", reconcileErr))
+	}
+	return ready
+}
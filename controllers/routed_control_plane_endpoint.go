@@ -0,0 +1,129 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// isControlPlaneEndpointRouted checks if the control plane endpoint of the cluster is an address of its OVDC network,
+// which may be routed without an edge gateway.
+func isControlPlaneEndpointRouted(vcdCluster *infrav1beta3.VCDCluster) bool {
+	return vcdCluster.Spec.ControlPlaneEndpointMode == ControlPlaneEndpointModeRouted
+}
+
+// isEdgeGatewayRequired checks if the control plane endpoint of the cluster is provided by the edge gateway of its OVDC
+// network, which is not the case in the Routed and KubeVip modes.
+func isEdgeGatewayRequired(vcdCluster *infrav1beta3.VCDCluster) bool {
+	return !isControlPlaneEndpointRouted(vcdCluster) && !isControlPlaneEndpointKubeVip(vcdCluster)
+}
+
+// reconcileRoutedControlPlaneEndpoint uses an address of the OVDC network of the cluster as its control plane endpoint,
+// without looking up an edge gateway. The endpoint set by the user is a virtual IP announced by kube-vip from the
+// control plane machines. Otherwise an unused address of the static IP pool of the network is picked, which the first
+// control plane machine is created with.
+func (r *VCDClusterReconciler) reconcileRoutedControlPlaneEndpoint(ctx context.Context,
+	vcdCluster *infrav1beta3.VCDCluster, vcdClient *vcdsdk.Client, skipRDEEventUpdates bool) (ctrl.Result, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+	capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
+
+	if vcdCluster.Spec.ControlPlaneEndpoint.Port == 0 {
+		vcdCluster.Spec.ControlPlaneEndpoint.Port = TcpPort
+	}
+	if vcdCluster.Spec.ControlPlaneEndpoint.Host == "" {
+		if vcdClient.VDC == nil {
+			return ctrl.Result{}, fmt.Errorf("no OVDC found in the VCD client to pick the control plane endpoint of cluster [%s]",
+				vcdCluster.Name)
+		}
+		ovdcNetworkName := getOvdcNetworkName(vcdCluster)
+		ovdcNetwork, err := vcdClient.VDC.GetOpenApiOrgVdcNetworkByName(ovdcNetworkName)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to get OVDC network [%s] of cluster [%s]: [%v]", ovdcNetworkName,
+				vcdCluster.Name, err)
+		}
+		address, err := getUnusedPoolIPAddress(vcdClient, ovdcNetwork.OpenApiOrgVdcNetwork)
+		if err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.LoadBalancerError, "", vcdCluster.Name,
+				fmt.Sprintf("failed to pick the control plane endpoint of cluster [%s]: [%v]", vcdCluster.Name, err))
+			return ctrl.Result{}, fmt.Errorf("failed to pick the control plane endpoint of cluster [%s]: [%v]",
+				vcdCluster.Name, err)
+		}
+		vcdCluster.Spec.ControlPlaneEndpoint.Host = address
+		vcdCluster.Status.ControlPlaneMachineEndpoint = true
+		log.Info("Picked the address of the first control plane machine from the IP pool of the OVDC network as "+
+			"the control plane endpoint", "address", address, "ovdcNetwork", ovdcNetworkName)
+	} else if !vcdCluster.Status.ControlPlaneMachineEndpoint {
+		if err := validateVirtualIPControlPlaneEndpoint(vcdCluster); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	log.Info(fmt.Sprintf("Control plane endpoint for the cluster is [%s] on the OVDC network",
+		vcdCluster.Spec.ControlPlaneEndpoint.Host), "kubeVip", isKubeVipRequired(vcdCluster))
+
+	if err := capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD,
+		capisdk.LoadBalancerError, "", vcdCluster.Name); err != nil {
+		log.Error(err, "failed to remove LoadBalancerError from RDE", "rdeID", vcdCluster.Status.InfraId)
+	}
+	capvcdRdeManager.AddToEventSet(ctx, capisdk.LoadBalancerAvailable, "", "", "", skipRDEEventUpdates)
+	return ctrl.Result{}, nil
+}
+
+// validateVirtualIPControlPlaneEndpoint checks that the control plane endpoint host of the cluster is an IP address, as
+// kube-vip announces the virtual IP with ARP, which needs an address rather than a host name.
+func validateVirtualIPControlPlaneEndpoint(vcdCluster *infrav1beta3.VCDCluster) error {
+	if net.ParseIP(vcdCluster.Spec.ControlPlaneEndpoint.Host) != nil {
+		return nil
+	}
+	conditions.MarkFalse(vcdCluster, LoadBalancerAvailableCondition, ControlPlaneEndpointInvalidReason,
+		clusterv1.ConditionSeverityError, "control plane endpoint host must be an IP address in the [%s] mode",
+		vcdCluster.Spec.ControlPlaneEndpointMode)
+	return fmt.Errorf("control plane endpoint host [%s] of cluster [%s] must be an IP address in the [%s] mode",
+		vcdCluster.Spec.ControlPlaneEndpoint.Host, vcdCluster.Name, vcdCluster.Spec.ControlPlaneEndpointMode)
+}
+
+// getControlPlaneEndpointMachineAddress returns the address the VM of the machine is created with when the control
+// plane endpoint of the cluster is the address of its first control plane machine: the endpoint is given to the
+// control plane machines which are not bootstrapped yet while no other control plane machine has it, e.g. after the
+// machine having it was deleted. An empty address is returned otherwise, and for the machines setting their networks.
+func getControlPlaneEndpointMachineAddress(ctx context.Context, cl client.Client, machine *clusterv1.Machine,
+	vcdMachine *infrav1beta3.VCDMachine, vcdCluster *infrav1beta3.VCDCluster) (string, error) {
+
+	if !isControlPlaneEndpointRouted(vcdCluster) || !vcdCluster.Status.ControlPlaneMachineEndpoint ||
+		!util.IsControlPlaneMachine(machine) || vcdMachine.Spec.Bootstrapped || len(vcdMachine.Spec.Networks) > 0 {
+		return "", nil
+	}
+	address := vcdCluster.Spec.ControlPlaneEndpoint.Host
+	vcdMachineList := &infrav1beta3.VCDMachineList{}
+	if err := cl.List(ctx, vcdMachineList, client.InNamespace(vcdMachine.Namespace), client.MatchingLabels{
+		clusterv1.ClusterNameLabel: machine.Spec.ClusterName,
+	}, client.HasLabels{clusterv1.MachineControlPlaneLabel}); err != nil {
+		return "", fmt.Errorf("failed to list the control plane VCDMachines of cluster [%s/%s]: [%v]",
+			vcdMachine.Namespace, machine.Spec.ClusterName, err)
+	}
+	for _, otherVCDMachine := range vcdMachineList.Items {
+		if otherVCDMachine.Name == vcdMachine.Name {
+			continue
+		}
+		for _, machineAddress := range otherVCDMachine.Status.Addresses {
+			if machineAddress.Type == clusterv1.MachineInternalIP && machineAddress.Address == address {
+				return "", nil
+			}
+		}
+	}
+	return address, nil
+}
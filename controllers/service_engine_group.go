@@ -0,0 +1,182 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/antihax/optional"
+	vcdsdkutil "github.com/vmware/cloud-provider-for-cloud-director/pkg/util"
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	swagger "github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdswaggerclient_36_0"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// getServiceEngineGroupRef returns the service engine group with the name among the service engine groups assigned to
+// the edge gateway.
+func getServiceEngineGroupRef(ctx context.Context, vcdClient *vcdsdk.Client, gateway *vcdsdk.GatewayManager,
+	serviceEngineGroupName string) (*swagger.EntityReference, error) {
+
+	org, err := vcdClient.VCDClient.GetOrgByName(vcdClient.ClusterOrgName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting org by name for org [%s]: [%v]", vcdClient.ClusterOrgName, err)
+	}
+	if org == nil || org.Org == nil {
+		return nil, fmt.Errorf("obtained nil org when getting org by name [%s]", vcdClient.ClusterOrgName)
+	}
+	segAssignments, resp, err := vcdClient.APIClient.LoadBalancerServiceEngineGroupAssignmentsApi.GetServiceEngineGroupAssignments(
+		ctx, 1, 25, org.Org.ID,
+		&swagger.LoadBalancerServiceEngineGroupAssignmentsApiGetServiceEngineGroupAssignmentsOpts{
+			Filter: optional.NewString(fmt.Sprintf("gatewayRef.id==%s;serviceEngineGroupRef.name==%s",
+				gateway.GatewayRef.Id, serviceEngineGroupName)),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get service engine group [%s] of gateway [%s]: resp: [%v]: [%v]",
+			serviceEngineGroupName, gateway.GatewayRef.Name, resp, err)
+	}
+	for _, segAssignment := range segAssignments.Values {
+		if segAssignment.ServiceEngineGroupRef == nil || segAssignment.ServiceEngineGroupRef.Name != serviceEngineGroupName {
+			continue
+		}
+		if segAssignment.MaxVirtualServices > 0 &&
+			segAssignment.NumDeployedVirtualServices >= segAssignment.MaxVirtualServices {
+			return nil, fmt.Errorf("service engine group [%s] of gateway [%s] has no free virtual service",
+				serviceEngineGroupName, gateway.GatewayRef.Name)
+		}
+		return segAssignment.ServiceEngineGroupRef, nil
+	}
+	return nil, fmt.Errorf("service engine group [%s] is not assigned to gateway [%s]", serviceEngineGroupName,
+		gateway.GatewayRef.Name)
+}
+
+// createLoadBalancerOnServiceEngineGroup creates the load balancer of the control plane endpoint like
+// GatewayManager.CreateLoadBalancer does, but places its virtual service on the service engine group of the
+// VCDCluster instead of the first service engine group of the edge gateway with free capacity. It returns the external
+// IP of the load balancer.
+func createLoadBalancerOnServiceEngineGroup(ctx context.Context, vcdClient *vcdsdk.Client,
+	gateway *vcdsdk.GatewayManager, vcdCluster *infrav1beta3.VCDCluster, virtualServiceNamePrefix string,
+	lbPoolNamePrefix string, port int32, oneArm *vcdsdk.OneArm, providedIP string,
+	resourcesAllocated *vcdsdkutil.AllocatedResourcesMap) (string, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	vcdClient.RWLock.Lock()
+	defer vcdClient.RWLock.Unlock()
+
+	virtualServiceName := fmt.Sprintf("%s-tcp", virtualServiceNamePrefix)
+	lbPoolName := fmt.Sprintf("%s-tcp", lbPoolNamePrefix)
+	dnatRuleName := vcdsdk.GetDNATRuleName(virtualServiceName)
+
+	// reuse the external IP claimed by the DNAT rule of a partially created load balancer
+	externalIP := providedIP
+	var dnatRuleRef *vcdsdk.NatRuleRef
+	if oneArm != nil {
+		var err error
+		dnatRuleRef, err = gateway.GetNATRuleRef(ctx, dnatRuleName)
+		if err != nil {
+			return "", fmt.Errorf("unable to retrieve dnat rule [%s]: [%v]", dnatRuleName, err)
+		}
+		if dnatRuleRef != nil {
+			externalIP = dnatRuleRef.ExternalIP
+		}
+	}
+	vsSummary, err := gateway.GetVirtualService(ctx, virtualServiceName)
+	if err != nil {
+		return "", fmt.Errorf("unexpected error while querying for virtual service [%s]: [%v]", virtualServiceName,
+			err)
+	}
+	if vsSummary != nil {
+		if vsSummary.LoadBalancerPoolRef.Name != lbPoolName {
+			return "", fmt.Errorf("virtual Service [%s] found with unexpected loadbalancer pool [%s]",
+				virtualServiceName, lbPoolName)
+		}
+		resourcesAllocated.Insert(vcdsdk.VcdResourceVirtualService, &swagger.EntityReference{
+			Name: vsSummary.Name,
+			Id:   vsSummary.Id,
+		})
+		if err = gateway.CheckIfVirtualServiceIsPending(ctx, virtualServiceName); err != nil {
+			return "", err
+		}
+		if oneArm != nil && dnatRuleRef != nil {
+			return dnatRuleRef.ExternalIP, nil
+		}
+		return vsSummary.VirtualIpAddress, nil
+	}
+
+	if externalIP == "" {
+		externalIP, err = gateway.GetUnusedExternalIPAddress(ctx, vcdCluster.Spec.LoadBalancerConfigSpec.VipSubnet)
+		if err != nil {
+			return "", fmt.Errorf("unable to get unused IP address from subnet [%s]: [%v]",
+				vcdCluster.Spec.LoadBalancerConfigSpec.VipSubnet, err)
+		}
+	}
+
+	virtualServiceIP := externalIP
+	if oneArm != nil {
+		if dnatRuleRef == nil {
+			internalIP, err := gateway.GetUnusedInternalIPAddress(ctx, oneArm)
+			if err != nil {
+				return "", fmt.Errorf("unable to get internal IP address for one-arm mode: [%v]", err)
+			}
+			appPortProfileName := vcdsdk.GetAppPortProfileName(dnatRuleName)
+			appPortProfile, err := gateway.CreateAppPortProfile(appPortProfileName, port)
+			if err != nil {
+				return "", fmt.Errorf("failed to create App Port Profile: [%v]", err)
+			}
+			resourcesAllocated.Insert(vcdsdk.VcdResourceAppPortProfile, &swagger.EntityReference{
+				Name: appPortProfile.NsxtAppPortProfile.Name,
+				Id:   appPortProfile.NsxtAppPortProfile.ID,
+			})
+			if err = gateway.CreateDNATRule(ctx, dnatRuleName, externalIP, internalIP, port, port,
+				appPortProfile); err != nil {
+				return "", fmt.Errorf("unable to create dnat rule [%s:%d]=>[%s:%d]: [%v]", externalIP, port,
+					internalIP, port, err)
+			}
+			dnatRuleRef, err = gateway.GetNATRuleRef(ctx, dnatRuleName)
+			if err != nil {
+				return "", fmt.Errorf("unable to retrieve created dnat rule [%s]: [%v]", dnatRuleName, err)
+			}
+			if dnatRuleRef == nil {
+				return "", fmt.Errorf("retrieved dnat rule ref is nil")
+			}
+		}
+		resourcesAllocated.Insert(vcdsdk.VcdResourceDNATRule, &swagger.EntityReference{
+			Name: dnatRuleRef.Name,
+			Id:   dnatRuleRef.ID,
+		})
+		// the virtual service listens on the internal IP the DNAT rule forwards to
+		virtualServiceIP = dnatRuleRef.InternalIP
+	}
+
+	segRef, err := getServiceEngineGroupRef(ctx, vcdClient, gateway,
+		vcdCluster.Spec.LoadBalancerConfigSpec.ServiceEngineGroup)
+	if err != nil {
+		return "", err
+	}
+	lbPoolRef, err := gateway.CreateLoadBalancerPool(ctx, lbPoolName, []string{}, port, "TCP")
+	if err != nil {
+		return "", fmt.Errorf("unable to create load balancer pool [%s]: [%v]", lbPoolName, err)
+	}
+	resourcesAllocated.Insert(vcdsdk.VcdResourceLoadBalancerPool, lbPoolRef)
+
+	virtualServiceRef, err := gateway.CreateVirtualService(ctx, virtualServiceName, lbPoolRef, segRef,
+		virtualServiceIP, "TCP", port, false, "")
+	if err != nil {
+		if _, ok := err.(*vcdsdk.VirtualServicePendingError); ok {
+			resourcesAllocated.Insert(vcdsdk.VcdResourceVirtualService, virtualServiceRef)
+			log.Info("Load balancer virtual service is pending", "virtualService", virtualServiceName)
+			return externalIP, nil
+		}
+		return "", err
+	}
+	resourcesAllocated.Insert(vcdsdk.VcdResourceVirtualService, virtualServiceRef)
+	log.Info("Created the load balancer on the service engine group", "virtualService", virtualServiceName,
+		"serviceEngineGroup", segRef.Name)
+	return externalIP, nil
+}
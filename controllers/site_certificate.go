@@ -0,0 +1,208 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// SiteCertificateDialTimeout is the timeout of the TLS handshake fetching the certificate chain of a VCD site.
+const SiteCertificateDialTimeout = 30 * time.Second
+
+// siteCertificateUntrustedMessage starts the error of the TLS connections to a site presenting a certificate which is
+// neither pinned nor issued by a CA of the trust bundle of the cluster.
+const siteCertificateUntrustedMessage = "certificate of the site is not trusted"
+
+// getCertificateFingerprint returns the SHA-256 fingerprint of the certificate in the format of
+// `openssl x509 -noout -fingerprint -sha256`, e.g. `AB:CD:...`.
+func getCertificateFingerprint(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.Raw)
+	hexBytes := make([]string, 0, len(sum))
+	for _, b := range sum {
+		hexBytes = append(hexBytes, fmt.Sprintf("%02X", b))
+	}
+	return strings.Join(hexBytes, ":")
+}
+
+// normalizeFingerprint makes fingerprints given with or without colons, in lower or upper case, comparable.
+func normalizeFingerprint(fingerprint string) string {
+	fingerprint = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(fingerprint)), "SHA256 FINGERPRINT=")
+	return strings.ReplaceAll(fingerprint, ":", "")
+}
+
+// getSiteCertificateFingerprints fetches the certificate chain presented by the VCD site and returns the fingerprints
+// of its certificates, leaf first.
+func getSiteCertificateFingerprints(site string) ([]string, error) {
+	siteURL, err := url.Parse(site)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse site [%s]: [%v]", site, err)
+	}
+
+	conn, err := dialSiteTLS(site, &tls.Config{InsecureSkipVerify: true, ServerName: siteURL.Hostname()})
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch the certificate chain of site [%s]: [%v]", site, err)
+	}
+	defer conn.Close()
+
+	peerCertificates := conn.ConnectionState().PeerCertificates
+	if len(peerCertificates) == 0 {
+		return nil, fmt.Errorf("site [%s] presented no certificate", site)
+	}
+	fingerprints := make([]string, 0, len(peerCertificates))
+	for _, certificate := range peerCertificates {
+		fingerprints = append(fingerprints, getCertificateFingerprint(certificate))
+	}
+	return fingerprints, nil
+}
+
+// hasPinnedFingerprint checks if one of the presented fingerprints is pinned. Pinning any certificate of the chain, e.g.
+// the issuing CA, keeps the site trusted when its leaf certificate is renewed by the same CA.
+func hasPinnedFingerprint(presentedFingerprints []string, pinnedFingerprints []string) bool {
+	for _, presentedFingerprint := range presentedFingerprints {
+		for _, pinnedFingerprint := range pinnedFingerprints {
+			if normalizeFingerprint(presentedFingerprint) == normalizeFingerprint(pinnedFingerprint) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// verifySiteCertificate checks the certificate chain of the site of the VCDCluster against the pinned fingerprints,
+// pinning the chain presented on first use. It is called before the credentials of the cluster are sent to the site.
+func verifySiteCertificate(ctx context.Context, vcdCluster *infrav1beta3.VCDCluster) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	presentedFingerprints, err := getSiteCertificateFingerprints(vcdCluster.Spec.Site)
+	if err != nil {
+		return err
+	}
+	if len(vcdCluster.Spec.SiteCertificateFingerprints) == 0 {
+		log.Info("Trusting the certificate chain presented by the site on first use", "site", vcdCluster.Spec.Site,
+			"fingerprints", presentedFingerprints)
+		vcdCluster.Spec.SiteCertificateFingerprints = presentedFingerprints
+		return nil
+	}
+	if !hasPinnedFingerprint(presentedFingerprints, vcdCluster.Spec.SiteCertificateFingerprints) {
+		return NewSiteCertificateMismatchError(fmt.Sprintf(
+			"site [%s] presented certificates with fingerprints [%s], none of which is pinned in the VCDCluster [%s]; clear siteCertificateFingerprints to trust a renewed certificate",
+			vcdCluster.Spec.Site, strings.Join(presentedFingerprints, ", "), vcdCluster.Name))
+	}
+	return nil
+}
+
+// verifyPinnedCertificates checks that one of the certificates presented by the site is pinned.
+func verifyPinnedCertificates(peerCertificates []*x509.Certificate, pinnedFingerprints []string) error {
+	presentedFingerprints := make([]string, 0, len(peerCertificates))
+	for _, certificate := range peerCertificates {
+		presentedFingerprints = append(presentedFingerprints, getCertificateFingerprint(certificate))
+	}
+	if !hasPinnedFingerprint(presentedFingerprints, pinnedFingerprints) {
+		return fmt.Errorf("%s: presented certificates with fingerprints [%s], none of which is pinned",
+			siteCertificateUntrustedMessage, strings.Join(presentedFingerprints, ", "))
+	}
+	return nil
+}
+
+// getSiteServerName returns the server name sent by the TLS connections to the site, which is empty for a site
+// addressed by IP.
+func getSiteServerName(site string) string {
+	siteURL, err := url.Parse(site)
+	if err != nil || net.ParseIP(siteURL.Hostname()) != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(siteURL.Hostname()), ".")
+}
+
+// verifyTrustedCertificates checks that the certificate presented by the site is issued for the site by a CA of the
+// trust bundle.
+func verifyTrustedCertificates(site string, peerCertificates []*x509.Certificate,
+	trustBundlePool *x509.CertPool) error {
+
+	if len(peerCertificates) == 0 {
+		return fmt.Errorf("%s: no certificate presented", siteCertificateUntrustedMessage)
+	}
+	siteURL, err := url.Parse(site)
+	if err != nil {
+		return fmt.Errorf("unable to parse site [%s]: [%v]", site, err)
+	}
+	intermediates := x509.NewCertPool()
+	for _, certificate := range peerCertificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	if _, err = peerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       siteURL.Hostname(),
+		Roots:         trustBundlePool,
+		Intermediates: intermediates,
+	}); err != nil {
+		return fmt.Errorf("%s by the trust bundle of the cluster: [%v]", siteCertificateUntrustedMessage, err)
+	}
+	return nil
+}
+
+// newSiteTLSConfig returns the TLS configuration of the transports of the VCD clients of the site, i.e. of the GoVCD
+// client and of both OpenAPI clients, which verifies the certificate of the site against the CAs of the trust bundle
+// and the certificate chain of the site against the pinned fingerprints, if any, before the credentials or the token
+// of the session are sent. The connections to other servers, e.g. to the ADFS server of the org, are not checked
+// against the trust bundle and the fingerprints of the site.
+func newSiteTLSConfig(site string, insecure bool, trustBundlePool *x509.CertPool,
+	pinnedFingerprints []string) *tls.Config {
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecure}
+	if trustBundlePool == nil && len(pinnedFingerprints) == 0 {
+		return tlsConfig
+	}
+	serverName := getSiteServerName(site)
+	tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+		if !strings.EqualFold(state.ServerName, serverName) {
+			return nil
+		}
+		if trustBundlePool != nil {
+			if err := verifyTrustedCertificates(site, state.PeerCertificates, trustBundlePool); err != nil {
+				return err
+			}
+		}
+		if len(pinnedFingerprints) != 0 {
+			return verifyPinnedCertificates(state.PeerCertificates, pinnedFingerprints)
+		}
+		return nil
+	}
+	return tlsConfig
+}
+
+// isSiteCertificateRejectedError checks if the error, possibly flattened into a message by the VCD SDK, is the
+// rejection of the certificate of the site by the TLS configuration of the VCD clients.
+func isSiteCertificateRejectedError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), siteCertificateUntrustedMessage)
+}
+
+// reconcileSiteCertificateCondition reports whether the certificate chain of the site is trusted, i.e. pinned or issued
+// by a CA of the trust bundle of the cluster.
+func reconcileSiteCertificateCondition(vcdCluster *infrav1beta3.VCDCluster, err error) {
+	if !vcdCluster.Spec.PinSiteCertificate && vcdCluster.Spec.VCDTrustBundleSecretRef == nil {
+		conditions.Delete(vcdCluster, SiteCertificateTrustedCondition)
+		return
+	}
+	if err != nil {
+		conditions.MarkFalse(vcdCluster, SiteCertificateTrustedCondition, SiteCertificateMismatchReason,
+			clusterv1.ConditionSeverityError, err.Error())
+		return
+	}
+	conditions.MarkTrue(vcdCluster, SiteCertificateTrustedCondition)
+}
@@ -0,0 +1,152 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+)
+
+func TestGetCertificateFingerprint(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	fingerprint := getCertificateFingerprint(server.Certificate())
+	if !regexp.MustCompile(`^([0-9A-F]{2}:){31}[0-9A-F]{2}$`).MatchString(fingerprint) {
+		t.Errorf("fingerprint [%s] is not in the format of openssl", fingerprint)
+	}
+}
+
+func TestHasPinnedFingerprint(t *testing.T) {
+	const leaf = "AB:CD:EF:01"
+	const ca = "12:34:56:78"
+
+	testCases := []struct {
+		name                  string
+		presentedFingerprints []string
+		pinnedFingerprints    []string
+		want                  bool
+	}{
+		{name: "leaf pinned", presentedFingerprints: []string{leaf, ca}, pinnedFingerprints: []string{leaf},
+			want: true},
+		{name: "CA pinned", presentedFingerprints: []string{leaf, ca}, pinnedFingerprints: []string{ca}, want: true},
+		{name: "lower case without colons", presentedFingerprints: []string{leaf},
+			pinnedFingerprints: []string{"abcdef01"}, want: true},
+		{name: "output of openssl", presentedFingerprints: []string{leaf},
+			pinnedFingerprints: []string{" sha256 Fingerprint=AB:CD:EF:01 "}, want: true},
+		{name: "nothing pinned", presentedFingerprints: []string{leaf, ca}},
+		{name: "other certificate pinned", presentedFingerprints: []string{leaf, ca},
+			pinnedFingerprints: []string{"AB:CD:EF:02"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := hasPinnedFingerprint(tc.presentedFingerprints, tc.pinnedFingerprints); got != tc.want {
+				t.Errorf("got [%t], want [%t]", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetSiteServerName(t *testing.T) {
+	testCases := []struct {
+		site string
+		want string
+	}{
+		{site: "https://VCD.example.com", want: "vcd.example.com"},
+		{site: "https://vcd.example.com.:8443/", want: "vcd.example.com"},
+		{site: "https://10.0.0.1", want: ""},
+		{site: "https://[fd00::1]:443", want: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.site, func(t *testing.T) {
+			if got := getSiteServerName(tc.site); got != tc.want {
+				t.Errorf("got server name [%s], want [%s]", got, tc.want)
+			}
+		})
+	}
+}
+
+// newTestSite returns a site serving the OpenAPI of VCD, which counts the requests carrying a bearer token.
+func newTestSite(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	authorizedRequests := &atomic.Int32{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/cloudapi/") {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			authorizedRequests.Add(1)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "urn:vcloud:entity:vmware:capvcdCluster:1234"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, authorizedRequests
+}
+
+// getTestDefinedEntity gets an RDE of the site with the OpenAPI client of a session verified with the TLS
+// configuration.
+func getTestDefinedEntity(t *testing.T, site string, tlsConfig *tls.Config) error {
+	siteURL, err := url.ParseRequestURI(site + "/api")
+	if err != nil {
+		t.Fatalf("unable to parse site [%s]: [%v]", site, err)
+	}
+	govcdClient := govcd.NewVCDClient(*siteURL, true)
+	govcdClient.Client.VCDToken = "token"
+	govcdClient.Client.VCDAuthHeader = govcd.BearerTokenHeader
+	setVCDClientTransport(govcdClient, site, tlsConfig)
+	apiClient, _ := newSwaggerClients(govcdClient, site, tlsConfig)
+
+	_, _, _, err = apiClient.DefinedEntityApi.GetDefinedEntity(context.Background(),
+		"urn:vcloud:entity:vmware:capvcdCluster:1234", "")
+	return err
+}
+
+func TestSiteTLSConfigPinsCloudAPICertificate(t *testing.T) {
+	server, authorizedRequests := newTestSite(t)
+	fingerprint := getCertificateFingerprint(server.Certificate())
+
+	testCases := []struct {
+		name               string
+		pinnedFingerprints []string
+		wantErr            bool
+	}{
+		{name: "certificate not pinned", pinnedFingerprints: nil},
+		{name: "certificate pinned", pinnedFingerprints: []string{fingerprint}},
+		{name: "other certificate pinned", pinnedFingerprints: []string{strings.Repeat("00:", 31) + "00"},
+			wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			authorizedRequests.Store(0)
+			err := getTestDefinedEntity(t, server.URL, newSiteTLSConfig(server.URL, true, nil, tc.pinnedFingerprints))
+			if !tc.wantErr {
+				if err != nil {
+					t.Fatalf("unexpected error: [%v]", err)
+				}
+				if authorizedRequests.Load() != 1 {
+					t.Errorf("got [%d] requests with the bearer token, want 1", authorizedRequests.Load())
+				}
+				return
+			}
+			if !isSiteCertificateRejectedError(err) {
+				t.Errorf("got error [%v], want the certificate of the site to be rejected", err)
+			}
+			if authorizedRequests.Load() != 0 {
+				t.Errorf("bearer token was sent to a site presenting a certificate which is not pinned")
+			}
+		})
+	}
+}
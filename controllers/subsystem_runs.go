@@ -0,0 +1,55 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"time"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// SubsystemRunRecordInterval is the minimum delay between two recordings of the last run time of a subsystem, which
+// bounds the updates of the VCDCluster made to record them.
+const SubsystemRunRecordInterval = 5 * time.Minute
+
+// recordSubsystemRun sets the last run time of a subsystem to now, unless it was recorded less than
+// SubsystemRunRecordInterval ago. It returns true if the last run time was changed.
+func recordSubsystemRun(lastRun **metav1.Time) bool {
+	if *lastRun != nil && time.Since((*lastRun).Time) < SubsystemRunRecordInterval {
+		return false
+	}
+	now := metav1.Now()
+	*lastRun = &now
+	return true
+}
+
+// ignoreSubsystemRunUpdates filters out the updates of a VCDCluster which only record the last run times of its
+// subsystems, so that recording them does not trigger reconciliations of the cluster and its machines.
+func ignoreSubsystemRunUpdates() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldVCDCluster, ok := e.ObjectOld.(*infrav1beta3.VCDCluster)
+			if !ok {
+				return true
+			}
+			newVCDCluster, ok := e.ObjectNew.(*infrav1beta3.VCDCluster)
+			if !ok {
+				return true
+			}
+			oldVCDCluster, newVCDCluster = oldVCDCluster.DeepCopy(), newVCDCluster.DeepCopy()
+			for _, vcdCluster := range []*infrav1beta3.VCDCluster{oldVCDCluster, newVCDCluster} {
+				vcdCluster.ResourceVersion = ""
+				vcdCluster.ManagedFields = nil
+				vcdCluster.Status.LastSubsystemRuns = infrav1beta3.SubsystemRunTimes{}
+			}
+			return !reflect.DeepEqual(oldVCDCluster, newVCDCluster)
+		},
+	}
+}
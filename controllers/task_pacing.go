@@ -0,0 +1,122 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// TaskPacingMinInterval is the interval between two VM creations in an org once VCD rejected a task of the org
+	// because its task queue was saturated.
+	TaskPacingMinInterval = 15 * time.Second
+	// TaskPacingMaxInterval is the maximum interval between two VM creations in an org whose task queue stays
+	// saturated.
+	TaskPacingMaxInterval = 5 * time.Minute
+)
+
+// taskQueueSaturationMessages are the fragments of the VCD errors rejecting a task because the task queue or the
+// operation limits of the user or org are saturated.
+var taskQueueSaturationMessages = []string{
+	"operation limit",
+	"maximum number of simultaneous operations",
+	"limit has been reached",
+	"too many requests",
+	"task queue is full",
+}
+
+// orgTaskPacer spaces the VM creations in an org whose task queue was saturated. The interval is doubled each time VCD
+// rejects a task and halved each time a VM is created, until the org is no longer paced.
+type orgTaskPacer struct {
+	interval time.Duration
+	nextSlot time.Time
+}
+
+var (
+	orgTaskPacers     = make(map[string]*orgTaskPacer)
+	orgTaskPacersLock sync.Mutex
+)
+
+// isTaskQueueSaturatedError checks if VCD rejected a task because its task queue or operation limits are saturated.
+func isTaskQueueSaturatedError(err error) bool {
+	if err == nil {
+		return false
+	}
+	errMessage := strings.ToLower(err.Error())
+	for _, saturationMessage := range taskQueueSaturationMessages {
+		if strings.Contains(errMessage, saturationMessage) {
+			return true
+		}
+	}
+	return false
+}
+
+func getOrgTaskPacerKey(site string, org string) string {
+	return fmt.Sprintf("%s/%s", site, org)
+}
+
+// reserveTaskSlot returns how long a VM creation in the org must wait, or zero if the VM can be created now, in which
+// case the next VM creation in the org is delayed by the pacing interval.
+func reserveTaskSlot(site string, org string) time.Duration {
+	orgTaskPacersLock.Lock()
+	defer orgTaskPacersLock.Unlock()
+
+	pacer, ok := orgTaskPacers[getOrgTaskPacerKey(site, org)]
+	if !ok {
+		return 0
+	}
+	now := time.Now()
+	if now.Before(pacer.nextSlot) {
+		// spread the retries of the waiting machines
+		return pacer.nextSlot.Sub(now) + time.Duration(rand.Int63n(int64(pacer.interval)))
+	}
+	pacer.nextSlot = now.Add(pacer.interval)
+	return 0
+}
+
+// recordTaskQueueSaturation slows down the VM creations in the org after VCD rejected a task, and returns the delay
+// after which the rejected VM creation is retried.
+func recordTaskQueueSaturation(site string, org string) time.Duration {
+	orgTaskPacersLock.Lock()
+	defer orgTaskPacersLock.Unlock()
+
+	key := getOrgTaskPacerKey(site, org)
+	pacer, ok := orgTaskPacers[key]
+	if !ok {
+		pacer = &orgTaskPacer{}
+		orgTaskPacers[key] = pacer
+	}
+	pacer.interval *= 2
+	if pacer.interval < TaskPacingMinInterval {
+		pacer.interval = TaskPacingMinInterval
+	}
+	if pacer.interval > TaskPacingMaxInterval {
+		pacer.interval = TaskPacingMaxInterval
+	}
+	pacer.nextSlot = time.Now().Add(pacer.interval)
+	return pacer.interval
+}
+
+// recordTaskAccepted speeds up the VM creations in the org after VCD accepted a task, and stops pacing the org once the
+// interval drops below the minimum.
+func recordTaskAccepted(site string, org string) {
+	orgTaskPacersLock.Lock()
+	defer orgTaskPacersLock.Unlock()
+
+	key := getOrgTaskPacerKey(site, org)
+	pacer, ok := orgTaskPacers[key]
+	if !ok {
+		return
+	}
+	pacer.interval /= 2
+	if pacer.interval < TaskPacingMinInterval {
+		delete(orgTaskPacers, key)
+	}
+}
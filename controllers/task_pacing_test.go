@@ -0,0 +1,102 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+const (
+	testPacingSite = "https://vcd.example.com"
+	testPacingOrg  = "org1"
+)
+
+// resetTestOrgTaskPacers drops the pacers of the orgs at the end of the test.
+func resetTestOrgTaskPacers(t *testing.T) {
+	t.Cleanup(func() {
+		orgTaskPacersLock.Lock()
+		defer orgTaskPacersLock.Unlock()
+		for key := range orgTaskPacers {
+			delete(orgTaskPacers, key)
+		}
+	})
+}
+
+func TestIsTaskQueueSaturatedError(t *testing.T) {
+	testCases := []struct {
+		err  error
+		want bool
+	}{
+		{err: nil},
+		{err: fmt.Errorf("[400:BAD_REQUEST] - [ ] The maximum number of simultaneous operations for user has been reached"),
+			want: true},
+		{err: fmt.Errorf("Too Many Requests"), want: true},
+		{err: fmt.Errorf("[403:ACCESS_TO_RESOURCE_IS_FORBIDDEN] - [ ] Access is forbidden")},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%v", tc.err), func(t *testing.T) {
+			if got := isTaskQueueSaturatedError(tc.err); got != tc.want {
+				t.Errorf("got [%t], want [%t]", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReserveTaskSlot(t *testing.T) {
+	resetTestOrgTaskPacers(t)
+
+	if retryAfter := reserveTaskSlot(testPacingSite, testPacingOrg); retryAfter != 0 {
+		t.Fatalf("org which is not paced waits [%v]", retryAfter)
+	}
+
+	if retryAfter := recordTaskQueueSaturation(testPacingSite, testPacingOrg); retryAfter != TaskPacingMinInterval {
+		t.Errorf("got retry after [%v] on the first saturation, want [%v]", retryAfter, TaskPacingMinInterval)
+	}
+	retryAfter := reserveTaskSlot(testPacingSite, testPacingOrg)
+	if retryAfter <= 0 || retryAfter > 2*TaskPacingMinInterval {
+		t.Errorf("got retry after [%v] right after the saturation, want up to [%v]", retryAfter,
+			2*TaskPacingMinInterval)
+	}
+	if retryAfter := reserveTaskSlot(testPacingSite, "org2"); retryAfter != 0 {
+		t.Errorf("other org which is not paced waits [%v]", retryAfter)
+	}
+
+	// the slot is taken once the interval elapsed, which delays the next creation by the interval
+	orgTaskPacersLock.Lock()
+	orgTaskPacers[getOrgTaskPacerKey(testPacingSite, testPacingOrg)].nextSlot = time.Now().Add(-time.Second)
+	orgTaskPacersLock.Unlock()
+	if retryAfter := reserveTaskSlot(testPacingSite, testPacingOrg); retryAfter != 0 {
+		t.Errorf("got retry after [%v] once the interval elapsed, want 0", retryAfter)
+	}
+	if retryAfter := reserveTaskSlot(testPacingSite, testPacingOrg); retryAfter <= 0 {
+		t.Errorf("slot was taken twice within the interval")
+	}
+}
+
+func TestTaskPacingInterval(t *testing.T) {
+	resetTestOrgTaskPacers(t)
+
+	var retryAfter time.Duration
+	for i := 0; i < 10; i++ {
+		retryAfter = recordTaskQueueSaturation(testPacingSite, testPacingOrg)
+	}
+	if retryAfter != TaskPacingMaxInterval {
+		t.Errorf("got retry after [%v] once saturated repeatedly, want [%v]", retryAfter, TaskPacingMaxInterval)
+	}
+
+	// the pacing stops once the interval halved below the minimum
+	for i := 0; i < 10; i++ {
+		recordTaskAccepted(testPacingSite, testPacingOrg)
+	}
+	orgTaskPacersLock.Lock()
+	_, paced := orgTaskPacers[getOrgTaskPacerKey(testPacingSite, testPacingOrg)]
+	orgTaskPacersLock.Unlock()
+	if paced {
+		t.Errorf("org is still paced once its tasks are accepted")
+	}
+}
@@ -0,0 +1,245 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// CapvcdCachedTemplateSource is the key of the metadata entry of a cached template identifying the version of the
+	// source template it was copied from.
+	CapvcdCachedTemplateSource = "CapvcdCachedTemplateSource"
+
+	// MimeCopyOrMoveCatalogItemParams is the content type of the request copying a catalog item to a catalog.
+	MimeCopyOrMoveCatalogItemParams = "application/vnd.vmware.vcloud.copyOrMoveCatalogItemParams+xml"
+)
+
+// copyOrMoveCatalogItemParams is the payload of the request copying a catalog item to a catalog.
+type copyOrMoveCatalogItemParams struct {
+	XMLName     xml.Name         `xml:"CopyOrMoveCatalogItemParams"`
+	Xmlns       string           `xml:"xmlns,attr"`
+	Name        string           `xml:"name,attr"`
+	Description string           `xml:"Description,omitempty"`
+	Source      *types.Reference `xml:"Source"`
+}
+
+// templateCacheLocks serializes the caching of a template, so that the machines created concurrently from the same
+// template copy it once.
+var (
+	templateCacheLocks     = make(map[string]*sync.Mutex)
+	templateCacheLocksLock sync.Mutex
+)
+
+func getTemplateCacheLock(key string) *sync.Mutex {
+	templateCacheLocksLock.Lock()
+	defer templateCacheLocksLock.Unlock()
+	lock, ok := templateCacheLocks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		templateCacheLocks[key] = lock
+	}
+	return lock
+}
+
+// getCachedTemplateName returns the name of the copy of the template in the cache catalog. The source catalog is part
+// of the name as templates of the same name can be cached from several catalogs.
+func getCachedTemplateName(catalogName string, templateName string) string {
+	return fmt.Sprintf("%s-%s", catalogName, templateName)
+}
+
+// getTemplateSourceVersion identifies the version of the source template, which changes when the template is
+// replaced or updated in the source catalog.
+func getTemplateSourceVersion(catalogItem *govcd.CatalogItem) string {
+	entityID := ""
+	if catalogItem.CatalogItem.Entity != nil {
+		entityID = catalogItem.CatalogItem.Entity.ID
+		if entityID == "" {
+			entityID = catalogItem.CatalogItem.Entity.HREF
+		}
+	}
+	return fmt.Sprintf("%s/%s/%d", catalogItem.CatalogItem.ID, entityID, catalogItem.CatalogItem.VersionNumber)
+}
+
+// getMachineTemplateSource returns the org of the catalog, empty for the org of the cluster, the catalog and the
+// template the VM of the machine is created from. When the template cache of the cluster is enabled, the template is
+// copied to the cache catalog on first use and the cached copy is returned; the copy is replaced once the source
+// template changes.
+func getMachineTemplateSource(ctx context.Context, vcdClient *vcdsdk.Client, vdcManager *vcdsdk.VdcManager,
+	vcdMachine *infrav1beta3.VCDMachine, vcdCluster *infrav1beta3.VCDCluster) (string, string, string, error) {
+
+	catalogOrg := vcdMachine.Spec.CatalogOrg
+	if catalogOrg == vcdClient.ClusterOrgName {
+		catalogOrg = ""
+	}
+	catalogName := getMachineCatalogName(vcdMachine)
+	templateName := getMachineTemplateName(vcdMachine)
+	templateCache := vcdCluster.Spec.TemplateCache
+	if templateCache == nil || templateCache.Catalog == "" ||
+		(catalogOrg == "" && templateCache.Catalog == catalogName) {
+		return catalogOrg, catalogName, templateName, nil
+	}
+
+	log := ctrl.LoggerFrom(ctx)
+	// the org is part of the name of the copy of a template of a catalog of another org
+	cachedTemplateName := getCachedTemplateName(catalogName, templateName)
+	if catalogOrg != "" {
+		cachedTemplateName = getCachedTemplateName(catalogOrg, cachedTemplateName)
+	}
+	lock := getTemplateCacheLock(fmt.Sprintf("%s/%s/%s/%s", vcdCluster.Spec.Site, vcdClient.ClusterOrgName,
+		templateCache.Catalog, cachedTemplateName))
+	lock.Lock()
+	defer lock.Unlock()
+
+	org, err := vcdClient.VCDClient.GetOrgByName(vcdClient.ClusterOrgName)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to get org [%s]: [%v]", vcdClient.ClusterOrgName, err)
+	}
+
+	sourceCatalog, err := getCatalog(vcdClient, catalogOrg, catalogName)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to get catalog [%s] in org [%s]: [%v]", catalogName,
+			getCatalogOrgName(vcdClient, catalogOrg), err)
+	}
+	sourceItem, err := sourceCatalog.GetCatalogItemByName(templateName, true)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to get template [%s] in catalog [%s]: [%v]", templateName,
+			catalogName, err)
+	}
+	sourceVersion := getTemplateSourceVersion(sourceItem)
+
+	cacheCatalog, err := getOrCreateTemplateCacheCatalog(ctx, vcdClient, org, vdcManager, templateCache)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	cachedItem, err := cacheCatalog.GetCatalogItemByName(cachedTemplateName, true)
+	if err != nil && !govcd.ContainsNotFound(err) {
+		return "", "", "", fmt.Errorf("failed to get template [%s] in catalog [%s]: [%v]", cachedTemplateName,
+			templateCache.Catalog, err)
+	}
+	if err == nil {
+		cachedVersion := ""
+		metadataValue, err := cachedItem.GetMetadataByKey(CapvcdCachedTemplateSource, false)
+		if err != nil && !govcd.ContainsNotFound(err) {
+			return "", "", "", fmt.Errorf("failed to get metadata [%s] of template [%s] in catalog [%s]: [%v]",
+				CapvcdCachedTemplateSource, cachedTemplateName, templateCache.Catalog, err)
+		}
+		if err == nil && metadataValue != nil && metadataValue.TypedValue != nil {
+			cachedVersion = metadataValue.TypedValue.Value
+		}
+		if cachedVersion == sourceVersion {
+			return "", templateCache.Catalog, cachedTemplateName, nil
+		}
+		// the copy is of an earlier version of the source template, or its copy did not complete
+		log.Info("Deleting the outdated cached template", "catalog", templateCache.Catalog,
+			"template", cachedTemplateName, "cachedVersion", cachedVersion, "sourceVersion", sourceVersion)
+		if err = cachedItem.Delete(); err != nil {
+			return "", "", "", fmt.Errorf("failed to delete outdated template [%s] in catalog [%s]: [%v]",
+				cachedTemplateName, templateCache.Catalog, err)
+		}
+	}
+
+	log.Info("Caching the template", "sourceCatalogOrg", getCatalogOrgName(vcdClient, catalogOrg),
+		"sourceCatalog", catalogName, "template", templateName,
+		"catalog", templateCache.Catalog, "cachedTemplate", cachedTemplateName)
+	if err = copyCatalogItem(vcdClient, cacheCatalog, sourceItem, cachedTemplateName); err != nil {
+		return "", "", "", err
+	}
+	cachedItem, err = cacheCatalog.GetCatalogItemByName(cachedTemplateName, true)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to get cached template [%s] in catalog [%s]: [%v]", cachedTemplateName,
+			templateCache.Catalog, err)
+	}
+	if err = cachedItem.AddMetadataEntryWithVisibility(CapvcdCachedTemplateSource, sourceVersion,
+		types.MetadataStringValue, types.MetadataReadWriteVisibility, false); err != nil {
+		return "", "", "", fmt.Errorf("failed to add metadata [%s: %s] to template [%s] in catalog [%s]: [%v]",
+			CapvcdCachedTemplateSource, sourceVersion, cachedTemplateName, templateCache.Catalog, err)
+	}
+	return "", templateCache.Catalog, cachedTemplateName, nil
+}
+
+// getOrCreateTemplateCacheCatalog returns the cache catalog, and creates it on the storage profile of the template
+// cache if it does not exist.
+func getOrCreateTemplateCacheCatalog(ctx context.Context, vcdClient *vcdsdk.Client, org *govcd.Org,
+	vdcManager *vcdsdk.VdcManager, templateCache *infrav1beta3.TemplateCacheSpec) (*govcd.Catalog, error) {
+
+	catalog, err := org.GetCatalogByName(templateCache.Catalog, true)
+	if err == nil {
+		return catalog, nil
+	}
+	if !govcd.ContainsNotFound(err) {
+		return nil, fmt.Errorf("failed to get catalog [%s] in org [%s]: [%v]", templateCache.Catalog,
+			org.Org.Name, err)
+	}
+
+	var storageProfiles *types.CatalogStorageProfiles
+	if templateCache.StorageProfile != "" {
+		storageProfileRef, err := vdcManager.Vdc.FindStorageProfileReference(templateCache.StorageProfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find storage profile [%s] of catalog [%s]: [%v]",
+				templateCache.StorageProfile, templateCache.Catalog, err)
+		}
+		storageProfiles = &types.CatalogStorageProfiles{
+			VdcStorageProfile: []*types.Reference{&storageProfileRef},
+		}
+	}
+
+	ctrl.LoggerFrom(ctx).Info("Creating the template cache catalog", "catalog", templateCache.Catalog,
+		"storageProfile", templateCache.StorageProfile)
+	catalog, err = org.CreateCatalogWithStorageProfile(templateCache.Catalog,
+		"Templates cached by CAPVCD", storageProfiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create catalog [%s] in org [%s]: [%v]", templateCache.Catalog,
+			org.Org.Name, err)
+	}
+	if catalog.Catalog.Tasks != nil {
+		for _, taskInProgress := range catalog.Catalog.Tasks.Task {
+			task := govcd.NewTask(&vcdClient.VCDClient.Client)
+			task.Task = taskInProgress
+			if err = task.WaitTaskCompletion(); err != nil {
+				return nil, fmt.Errorf("failed to wait for the creation of catalog [%s]: [%v]",
+					templateCache.Catalog, err)
+			}
+		}
+	}
+	return catalog, nil
+}
+
+// copyCatalogItem copies the catalog item to the catalog under a new name, and waits for the copy to complete.
+func copyCatalogItem(vcdClient *vcdsdk.Client, catalog *govcd.Catalog, sourceItem *govcd.CatalogItem,
+	name string) error {
+
+	params := &copyOrMoveCatalogItemParams{
+		Xmlns:       types.XMLNamespaceVCloud,
+		Name:        name,
+		Description: fmt.Sprintf("Cached copy of [%s]", sourceItem.CatalogItem.Name),
+		Source: &types.Reference{
+			HREF: sourceItem.CatalogItem.HREF,
+		},
+	}
+	task, err := vcdClient.VCDClient.Client.ExecuteTaskRequest(catalog.Catalog.HREF+"/action/copy",
+		http.MethodPost, MimeCopyOrMoveCatalogItemParams, "error copying catalog item: %s", params)
+	if err != nil {
+		return fmt.Errorf("failed to copy template [%s] to catalog [%s]: [%v]", sourceItem.CatalogItem.Name,
+			catalog.Catalog.Name, err)
+	}
+	if err = task.WaitTaskCompletion(); err != nil {
+		return fmt.Errorf("failed to wait for the copy of template [%s] to catalog [%s]: [%v]",
+			sourceItem.CatalogItem.Name, catalog.Catalog.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,502 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Phases of the imports of the TemplateSources of a VCDCluster
+const (
+	TemplateImportPhaseDownloading = "Downloading"
+	TemplateImportPhaseUploading   = "Uploading"
+	TemplateImportPhaseImporting   = "Importing"
+	TemplateImportPhaseReady       = "Ready"
+	TemplateImportPhaseFailed      = "Failed"
+)
+
+const (
+	// TemplateImportRequeueInterval is the interval at which the progress of the imports of a VCDCluster is reported
+	// while they run.
+	TemplateImportRequeueInterval = 30 * time.Second
+
+	// TemplateImportRetryInterval is the time after which a failed import is started again.
+	TemplateImportRetryInterval = 10 * time.Minute
+
+	// TemplateImportDownloadTimeout is how long the download of an OVA may take.
+	TemplateImportDownloadTimeout = 2 * time.Hour
+
+	// TemplateImportUploadPieceSize is the size of the pieces the disks of an OVA are uploaded to VCD in.
+	TemplateImportUploadPieceSize = 64 * 1024 * 1024
+
+	// TemplateImportProgressInterval is the interval at which the progress of the upload of an OVA is recorded.
+	TemplateImportProgressInterval = 5 * time.Second
+
+	// DefaultTemplateImportMaxSize is the default maximum size of the OVAs downloaded by the imports, in bytes.
+	DefaultTemplateImportMaxSize = int64(20 * 1024 * 1024 * 1024)
+
+	templateImportChecksumPrefix = "sha256:"
+)
+
+var (
+	// TemplateImportMaxSize is the maximum size of the OVAs downloaded by the imports, in bytes. A larger OVA fails
+	// the import before filling the ephemeral volume of the controller.
+	TemplateImportMaxSize = DefaultTemplateImportMaxSize
+
+	// TemplateSourceAllowedHosts are the hosts the OVAs of the TemplateSources may be downloaded from, a host starting
+	// with a dot allowing its subdomains. Any host is allowed when empty, except the loopback and link-local addresses.
+	TemplateSourceAllowedHosts []string
+)
+
+// templateImport is the import of a template from its source, which runs in the background as downloading and
+// uploading an OVA takes much longer than a reconciliation. An import is shared by the clusters importing the same
+// template into the same catalog.
+type templateImport struct {
+	lock       sync.Mutex
+	phase      string
+	progress   string
+	err        error
+	finishedAt time.Time
+}
+
+var (
+	templateImports     = make(map[string]*templateImport)
+	templateImportsLock sync.Mutex
+)
+
+// getTemplateImportKey returns the key of the import of the template into the catalog of the org.
+func getTemplateImportKey(site string, org string, source infrav1beta3.TemplateSource) string {
+	return fmt.Sprintf("%s/%s/%s/%s", site, org, source.Catalog, source.Template)
+}
+
+// getTemplateImport returns the import of the key started by an earlier reconciliation, or nil if there is none.
+func getTemplateImport(key string) *templateImport {
+	templateImportsLock.Lock()
+	defer templateImportsLock.Unlock()
+	return templateImports[key]
+}
+
+// forgetTemplateImport drops the import of the key once its result was handled.
+func forgetTemplateImport(key string) {
+	templateImportsLock.Lock()
+	defer templateImportsLock.Unlock()
+	delete(templateImports, key)
+}
+
+// startTemplateImport starts the import of the source into the catalog, unless an import of the key is running.
+func startTemplateImport(ctx context.Context, key string, catalog *govcd.Catalog,
+	source infrav1beta3.TemplateSource) *templateImport {
+
+	templateImportsLock.Lock()
+	defer templateImportsLock.Unlock()
+	if running, ok := templateImports[key]; ok {
+		return running
+	}
+	imp := &templateImport{phase: TemplateImportPhaseDownloading}
+	templateImports[key] = imp
+
+	log := ctrl.LoggerFrom(ctx).WithValues("catalog", source.Catalog, "template", source.Template)
+	go func() {
+		err := imp.run(ctrl.LoggerInto(context.Background(), log), catalog, source)
+		imp.lock.Lock()
+		defer imp.lock.Unlock()
+		imp.err = err
+		imp.finishedAt = time.Now()
+		if err != nil {
+			imp.phase = TemplateImportPhaseFailed
+			log.Error(err, "failed to import the template from its source")
+			return
+		}
+		imp.phase = TemplateImportPhaseReady
+		log.Info("Imported the template from its source")
+	}()
+	return imp
+}
+
+// get returns the phase and progress of the import, and the time it finished at and its error once it finished.
+func (imp *templateImport) get() (string, string, time.Time, error) {
+	imp.lock.Lock()
+	defer imp.lock.Unlock()
+	return imp.phase, imp.progress, imp.finishedAt, imp.err
+}
+
+// set records the phase and progress of the import.
+func (imp *templateImport) set(phase string, progress string) {
+	imp.lock.Lock()
+	defer imp.lock.Unlock()
+	imp.phase = phase
+	imp.progress = progress
+}
+
+// downloadProgress records the progress of the download of the OVA of an import.
+type downloadProgress struct {
+	imp        *templateImport
+	size       int64
+	downloaded int64
+}
+
+func (p *downloadProgress) Write(data []byte) (int, error) {
+	p.downloaded += int64(len(data))
+	if p.size > 0 {
+		p.imp.set(TemplateImportPhaseDownloading, fmt.Sprintf("%.2f", float64(p.downloaded)*100/float64(p.size)))
+	}
+	return len(data), nil
+}
+
+// checkTemplateSourceURL checks that the OVA of a TemplateSource may be downloaded from the URL, which must be an HTTP
+// or HTTPS URL of one of the TemplateSourceAllowedHosts if they are set.
+func checkTemplateSourceURL(sourceURL *url.URL) error {
+	if sourceURL.Scheme != "http" && sourceURL.Scheme != "https" {
+		return fmt.Errorf("scheme [%s] of URL [%s] is not http or https", sourceURL.Scheme,
+			getRedactedTemplateSourceURL(sourceURL.String()))
+	}
+	host := strings.ToLower(sourceURL.Hostname())
+	if host == "" {
+		return fmt.Errorf("URL [%s] has no host", getRedactedTemplateSourceURL(sourceURL.String()))
+	}
+	if len(TemplateSourceAllowedHosts) == 0 {
+		return nil
+	}
+	for _, allowedHost := range TemplateSourceAllowedHosts {
+		allowedHost = strings.ToLower(allowedHost)
+		if host == allowedHost || (strings.HasPrefix(allowedHost, ".") && strings.HasSuffix(host, allowedHost)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("host [%s] of URL [%s] is not allowed to download templates from", host,
+		getRedactedTemplateSourceURL(sourceURL.String()))
+}
+
+// checkTemplateSourceAddress refuses the connections of the downloads of the OVAs to the loopback and link-local
+// addresses, e.g. the metadata service of the cloud the controller runs in, once the host is resolved.
+func checkTemplateSourceAddress(_ string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("address [%s] is not allowed to download templates from", host)
+	}
+	return nil
+}
+
+// newTemplateSourceHTTPClient returns the HTTP client downloading the OVAs, which only follows the redirects to URLs
+// passing checkTemplateSourceURL and only connects to the addresses passing checkTemplateSourceAddress.
+func newTemplateSourceHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   checkTemplateSourceAddress,
+	}).DialContext
+	return &http.Client{
+		Transport: transport,
+		Timeout:   TemplateImportDownloadTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return checkTemplateSourceURL(req.URL)
+		},
+	}
+}
+
+// checkTemplateImportVolume checks that the volume of the directory has room for an OVA of the size, which is
+// extracted next to the OVA while it is uploaded.
+func checkTemplateImportVolume(dir string, size int64) error {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return fmt.Errorf("failed to get the free space of the volume of [%s]: [%v]", dir, err)
+	}
+	available := stat.Bavail * uint64(stat.Bsize)
+	if required := uint64(2 * size); available < required {
+		return fmt.Errorf("volume of [%s] has [%d] bytes free, less than the [%d] bytes needed to import an OVA of "+
+			"[%d] bytes", dir, available, required, size)
+	}
+	return nil
+}
+
+// run downloads the OVA of the source, verifies its checksum and uploads it to the catalog. The catalog item left by
+// a failed upload is deleted so that the import can be retried.
+func (imp *templateImport) run(ctx context.Context, catalog *govcd.Catalog, source infrav1beta3.TemplateSource) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	sourceURL, err := url.Parse(source.URL)
+	if err != nil {
+		return fmt.Errorf("invalid URL [%s]: [%v]", getRedactedTemplateSourceURL(source.URL), err)
+	}
+	if err = checkTemplateSourceURL(sourceURL); err != nil {
+		return err
+	}
+
+	log.Info("Downloading the OVA of the template", "url", getRedactedTemplateSourceURL(source.URL))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to download the OVA from [%s]: [%v]", getRedactedTemplateSourceURL(source.URL), err)
+	}
+	resp, err := newTemplateSourceHTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download the OVA from [%s]: [%v]", getRedactedTemplateSourceURL(source.URL), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download the OVA from [%s]: unexpected status [%s]",
+			getRedactedTemplateSourceURL(source.URL), resp.Status)
+	}
+	// an OVA of unknown size may be as large as the maximum size
+	size := resp.ContentLength
+	if size > TemplateImportMaxSize {
+		return fmt.Errorf("OVA of [%s] is [%d] bytes, more than the maximum size of [%d] bytes",
+			getRedactedTemplateSourceURL(source.URL), size, TemplateImportMaxSize)
+	} else if size < 0 {
+		size = TemplateImportMaxSize
+	}
+	if err = checkTemplateImportVolume(os.TempDir(), size); err != nil {
+		return err
+	}
+
+	ovaFile, err := os.CreateTemp("", "capvcd-template-*.ova")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary file to download the OVA: [%v]", err)
+	}
+	defer func() {
+		_ = ovaFile.Close()
+		_ = os.Remove(ovaFile.Name())
+	}()
+	digest := sha256.New()
+	progress := &downloadProgress{imp: imp, size: resp.ContentLength}
+	// one more byte than the maximum size is read to detect a larger OVA
+	written, err := io.Copy(io.MultiWriter(ovaFile, digest, progress),
+		io.LimitReader(resp.Body, TemplateImportMaxSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to download the OVA from [%s]: [%v]", getRedactedTemplateSourceURL(source.URL), err)
+	}
+	if written > TemplateImportMaxSize {
+		return fmt.Errorf("OVA of [%s] is more than the maximum size of [%d] bytes",
+			getRedactedTemplateSourceURL(source.URL), TemplateImportMaxSize)
+	}
+	checksum := hex.EncodeToString(digest.Sum(nil))
+	if !strings.EqualFold(templateImportChecksumPrefix+checksum, source.Checksum) {
+		return fmt.Errorf("checksum [%s%s] of the OVA downloaded from [%s] does not match checksum [%s]",
+			templateImportChecksumPrefix, checksum, getRedactedTemplateSourceURL(source.URL), source.Checksum)
+	}
+	if err = ovaFile.Close(); err != nil {
+		return fmt.Errorf("failed to write the OVA: [%v]", err)
+	}
+
+	log.Info("Uploading the OVA of the template")
+	imp.set(TemplateImportPhaseUploading, "0.00")
+	uploadTask, err := catalog.UploadOvf(ovaFile.Name(), source.Template,
+		fmt.Sprintf("Imported by CAPVCD from [%s]", getRedactedTemplateSourceURL(source.URL)),
+		TemplateImportUploadPieceSize)
+	if err != nil {
+		return fmt.Errorf("failed to upload the OVA to catalog [%s]: [%v]", source.Catalog, err)
+	}
+	// the progress and the errors of the upload are recorded by the uploader, so that following the upload makes no
+	// request to VCD; an upload cancelled in VCD fails with an upload error
+	ticker := time.NewTicker(TemplateImportProgressInterval)
+	defer ticker.Stop()
+	for {
+		if err = uploadTask.GetUploadError(); err != nil {
+			deleteFailedTemplateImport(ctx, catalog, source)
+			return fmt.Errorf("failed to upload the OVA to catalog [%s]: [%v]", source.Catalog, err)
+		}
+		uploadProgress := uploadTask.GetUploadProgress()
+		imp.set(TemplateImportPhaseUploading, uploadProgress)
+		if uploadProgress == "100.00" {
+			break
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("gave up the upload of the OVA to catalog [%s]: [%v]", source.Catalog, ctx.Err())
+		}
+	}
+
+	imp.set(TemplateImportPhaseImporting, "")
+	if err = uploadTask.WaitTaskCompletion(); err != nil {
+		deleteFailedTemplateImport(ctx, catalog, source)
+		return fmt.Errorf("failed to import the OVA into catalog [%s]: [%v]", source.Catalog, err)
+	}
+	return nil
+}
+
+// deleteFailedTemplateImport deletes the catalog item left by a failed upload of the template.
+func deleteFailedTemplateImport(ctx context.Context, catalog *govcd.Catalog, source infrav1beta3.TemplateSource) {
+	catalogItem, err := catalog.GetCatalogItemByName(source.Template, true)
+	if err != nil {
+		return
+	}
+	if err = catalogItem.Delete(); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to delete the template left by a failed import")
+	}
+}
+
+// getRedactedTemplateSourceURL returns the URL of the source without its query, which holds the signature of a
+// pre-signed URL.
+func getRedactedTemplateSourceURL(sourceURL string) string {
+	if i := strings.Index(sourceURL, "?"); i >= 0 {
+		return sourceURL[:i]
+	}
+	return sourceURL
+}
+
+// isTemplateImportPending checks if the template of the catalog is provided by a TemplateSource of the VCDCluster
+// whose import is not done, and returns the phase of the import.
+func isTemplateImportPending(vcdCluster *infrav1beta3.VCDCluster, catalogName string, templateName string) (bool,
+	string) {
+
+	for _, source := range vcdCluster.Spec.TemplateSources {
+		if source.Catalog != catalogName || source.Template != templateName {
+			continue
+		}
+		status := getTemplateImportStatus(vcdCluster.Status.TemplateImports, source)
+		switch {
+		case status == nil:
+			return true, ""
+		case status.Phase == TemplateImportPhaseReady || status.Phase == TemplateImportPhaseFailed:
+			return false, status.Phase
+		default:
+			return true, status.Phase
+		}
+	}
+	return false, ""
+}
+
+// getTemplateImportStatus returns the status of the import of the source, or nil if there is none.
+func getTemplateImportStatus(statuses []infrav1beta3.TemplateImportStatus,
+	source infrav1beta3.TemplateSource) *infrav1beta3.TemplateImportStatus {
+
+	for i := range statuses {
+		if statuses[i].Catalog == source.Catalog && statuses[i].Template == source.Template {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+// reconcileTemplateImports imports the templates of the TemplateSources of the VCDCluster which are missing from their
+// catalogs, and reports the progress of the imports in its status. It reports if imports are running.
+func (r *VCDClusterReconciler) reconcileTemplateImports(ctx context.Context, vcdClient *vcdsdk.Client,
+	vcdCluster *infrav1beta3.VCDCluster, userRights *vcdUserRights) bool {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	inProgress := false
+	statuses := make([]infrav1beta3.TemplateImportStatus, 0, len(vcdCluster.Spec.TemplateSources))
+	for _, source := range vcdCluster.Spec.TemplateSources {
+		status := r.reconcileTemplateImport(ctx, vcdClient, vcdCluster, source, userRights)
+		previous := getTemplateImportStatus(vcdCluster.Status.TemplateImports, source)
+		if previous != nil && previous.Phase == status.Phase {
+			status.LastTransitionTime = previous.LastTransitionTime
+		} else {
+			now := metav1.Now()
+			status.LastTransitionTime = &now
+			log.Info("Import of the template from its source changed phase", "catalog", source.Catalog,
+				"template", source.Template, "phase", status.Phase, "message", status.Message)
+			if previous != nil && status.Phase == TemplateImportPhaseReady {
+				r.recordEvent(vcdCluster, corev1.EventTypeNormal, TemplateImportedReason, fmt.Sprintf(
+					"imported template [%s] into catalog [%s]", source.Template, source.Catalog))
+			} else if status.Phase == TemplateImportPhaseFailed {
+				r.recordEvent(vcdCluster, corev1.EventTypeWarning, TemplateImportFailedReason, fmt.Sprintf(
+					"failed to import template [%s] into catalog [%s]: %s", source.Template, source.Catalog,
+					status.Message))
+			}
+		}
+		if status.Phase != TemplateImportPhaseReady && status.Phase != TemplateImportPhaseFailed {
+			inProgress = true
+		}
+		statuses = append(statuses, status)
+	}
+	vcdCluster.Status.TemplateImports = statuses
+	return inProgress
+}
+
+// reconcileTemplateImport returns the status of the import of the source, and starts the import if the template is
+// missing from its catalog. A failed import is retried after the TemplateImportRetryInterval.
+func (r *VCDClusterReconciler) reconcileTemplateImport(ctx context.Context, vcdClient *vcdsdk.Client,
+	vcdCluster *infrav1beta3.VCDCluster, source infrav1beta3.TemplateSource,
+	userRights *vcdUserRights) infrav1beta3.TemplateImportStatus {
+
+	status := infrav1beta3.TemplateImportStatus{
+		Catalog:  source.Catalog,
+		Template: source.Template,
+	}
+	key := getTemplateImportKey(vcdCluster.Spec.Site, vcdClient.ClusterOrgName, source)
+	if imp := getTemplateImport(key); imp != nil {
+		phase, progress, finishedAt, err := imp.get()
+		switch {
+		case finishedAt.IsZero():
+			status.Phase, status.Progress = phase, progress
+			return status
+		case err != nil && time.Since(finishedAt) < TemplateImportRetryInterval:
+			status.Phase, status.Message = TemplateImportPhaseFailed, err.Error()
+			return status
+		}
+		forgetTemplateImport(key)
+	}
+
+	templateStatus, err := getTemplateStatus(vcdClient, "", source.Catalog, source.Template)
+	if err != nil {
+		status.Phase, status.Message = TemplateImportPhaseFailed, err.Error()
+		return status
+	}
+	switch {
+	case isTemplateImporting(templateStatus):
+		status.Phase = TemplateImportPhaseImporting
+		return status
+	case templateStatus == VAppTemplateFailedCreationStatus:
+		status.Phase = TemplateImportPhaseFailed
+		status.Message = fmt.Sprintf("template [%s] of catalog [%s] failed to be created; delete it to import it again",
+			source.Template, source.Catalog)
+		return status
+	case templateStatus != "":
+		status.Phase = TemplateImportPhaseReady
+		return status
+	}
+
+	if !userRights.isFeatureEnabled(OptionalFeatureCatalogUpload) {
+		status.Phase = TemplateImportPhaseFailed
+		status.Message = fmt.Sprintf("the user of the cluster lacks the rights %v to upload templates",
+			optionalFeatureRights[OptionalFeatureCatalogUpload])
+		return status
+	}
+	org, err := getOrgByName(vcdClient, vcdClient.ClusterOrgName)
+	if err != nil {
+		status.Phase, status.Message = TemplateImportPhaseFailed, err.Error()
+		return status
+	}
+	catalog, err := org.GetCatalogByName(source.Catalog, true)
+	if err != nil {
+		status.Phase = TemplateImportPhaseFailed
+		status.Message = fmt.Sprintf("failed to get catalog [%s] in org [%s]: [%v]", source.Catalog,
+			vcdClient.ClusterOrgName, err)
+		return status
+	}
+	imp := startTemplateImport(ctx, key, catalog, source)
+	status.Phase, status.Progress, _, _ = imp.get()
+	return status
+}
@@ -0,0 +1,75 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCheckTemplateSourceURL(t *testing.T) {
+	testCases := []struct {
+		name         string
+		url          string
+		allowedHosts []string
+		wantErr      bool
+	}{
+		{name: "any host", url: "https://downloads.example.com/ubuntu.ova"},
+		{name: "http", url: "http://downloads.example.com/ubuntu.ova"},
+		{name: "file", url: "file:///etc/passwd", wantErr: true},
+		{name: "no host", url: "https:///ubuntu.ova", wantErr: true},
+		{name: "allowed host", url: "https://Downloads.example.com/ubuntu.ova",
+			allowedHosts: []string{"downloads.example.com"}},
+		{name: "allowed subdomain", url: "https://bucket.s3.amazonaws.com/ubuntu.ova?X-Amz-Signature=abc",
+			allowedHosts: []string{"downloads.example.com", ".s3.amazonaws.com"}},
+		{name: "other host", url: "https://other.example.com/ubuntu.ova",
+			allowedHosts: []string{"downloads.example.com"}, wantErr: true},
+		{name: "suffix of an allowed host", url: "https://evilexample.com/ubuntu.ova",
+			allowedHosts: []string{".example.com"}, wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			TemplateSourceAllowedHosts = tc.allowedHosts
+			t.Cleanup(func() {
+				TemplateSourceAllowedHosts = nil
+			})
+			sourceURL, err := url.Parse(tc.url)
+			if err != nil {
+				t.Fatalf("unexpected error: [%v]", err)
+			}
+			err = checkTemplateSourceURL(sourceURL)
+			if tc.wantErr && err == nil {
+				t.Errorf("URL was allowed")
+			} else if !tc.wantErr && err != nil {
+				t.Errorf("unexpected error: [%v]", err)
+			}
+		})
+	}
+}
+
+func TestCheckTemplateSourceAddress(t *testing.T) {
+	testCases := []struct {
+		address string
+		wantErr bool
+	}{
+		{address: "203.0.113.10:443"},
+		{address: "10.0.0.5:80"},
+		{address: "127.0.0.1:443", wantErr: true},
+		{address: "[::1]:443", wantErr: true},
+		{address: "169.254.169.254:80", wantErr: true},
+		{address: "0.0.0.0:443", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.address, func(t *testing.T) {
+			err := checkTemplateSourceAddress("tcp", tc.address, nil)
+			if tc.wantErr && err == nil {
+				t.Errorf("address was allowed")
+			} else if !tc.wantErr && err != nil {
+				t.Errorf("unexpected error: [%v]", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+)
+
+// TemplateNotReadyRequeueInterval is the interval at which a machine waiting for its template to be imported checks it
+// again.
+const TemplateNotReadyRequeueInterval = 30 * time.Second
+
+const (
+	// VAppTemplateFailedCreationStatus is the status of a vApp template whose upload or import failed.
+	VAppTemplateFailedCreationStatus = "FAILED_CREATION"
+)
+
+// importingVAppTemplateStatuses are the statuses of a vApp template which is still being uploaded, imported or synced
+// from the catalog it is subscribed to, and cannot be instantiated yet.
+var importingVAppTemplateStatuses = []string{
+	"UNRESOLVED",
+	"DESCRIPTOR_PENDING",
+	"COPYING_CONTENTS",
+	"DISK_CONTENTS_PENDING",
+	"LOCAL_COPY_UNAVAILABLE",
+}
+
+// getTemplateStatus returns the status of the vApp template of the catalog, e.g. UNRESOLVED while it is imported. An
+// empty status is returned if the template is not found, which is reported when instantiating it. The catalog of
+// another org is set by catalogOrg.
+func getTemplateStatus(vcdClient *vcdsdk.Client, catalogOrg string, catalogName string,
+	templateName string) (string, error) {
+
+	catalog, err := getCatalog(vcdClient, catalogOrg, catalogName)
+	if err != nil {
+		return "", fmt.Errorf("unable to find catalog [%s] of org [%s]: [%v]", catalogName,
+			getCatalogOrgName(vcdClient, catalogOrg), err)
+	}
+	vAppTemplates, err := catalog.QueryVappTemplateList()
+	if err != nil {
+		return "", fmt.Errorf("unable to query templates of catalog [%s]: [%v]", catalogName, err)
+	}
+	for _, vAppTemplate := range vAppTemplates {
+		if vAppTemplate.Name == templateName {
+			return vAppTemplate.Status, nil
+		}
+	}
+	return "", nil
+}
+
+// isTemplateImporting checks if a vApp template of the status is still being imported.
+func isTemplateImporting(status string) bool {
+	return strInSlice(status, importingVAppTemplateStatuses)
+}
@@ -0,0 +1,168 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RefreshTokenRetryInterval is the interval after which an API refresh token rejected by VCD is tried again, in case it
+// was rejected by mistake. A rotated token is tried as soon as the credentials Secret changes.
+const RefreshTokenRetryInterval = 15 * time.Minute
+
+type rejectedRefreshToken struct {
+	rejectedAt time.Time
+	message    string
+}
+
+// vcdTokenManager tracks the API refresh tokens rejected by VCD, so that the controllers of all the clusters sharing a
+// rejected token stop logging in with it instead of each getting it rejected again. The tokens are identified by their
+// hash: a token rotated in the credentials Secret is a new token, which is tried right away.
+type vcdTokenManager struct {
+	lock     sync.Mutex
+	rejected map[string]rejectedRefreshToken
+}
+
+var vcdTokens = &vcdTokenManager{
+	rejected: make(map[string]rejectedRefreshToken),
+}
+
+func getRefreshTokenKey(site string, refreshToken string) string {
+	return fmt.Sprintf("%s/%x", site, sha256.Sum256([]byte(refreshToken)))
+}
+
+// checkRefreshToken returns an error if the API refresh token was rejected by the site less than
+// RefreshTokenRetryInterval ago.
+func (m *vcdTokenManager) checkRefreshToken(site string, refreshToken string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	rejected, ok := m.rejected[getRefreshTokenKey(site, refreshToken)]
+	if !ok || time.Since(rejected.rejectedAt) >= RefreshTokenRetryInterval {
+		return nil
+	}
+	return NewRefreshTokenExpiredError(fmt.Sprintf("API refresh token was rejected by site [%s] at [%s]: [%s]",
+		site, rejected.rejectedAt.Format(time.RFC3339), rejected.message))
+}
+
+// recordRefreshTokenRejected records the rejection of the API refresh token by the site, and drops the rights detected
+// for its user.
+func (m *vcdTokenManager) recordRefreshTokenRejected(site string, refreshToken string, message string) {
+	m.lock.Lock()
+	m.rejected[getRefreshTokenKey(site, refreshToken)] = rejectedRefreshToken{
+		rejectedAt: time.Now(),
+		message:    message,
+	}
+	m.lock.Unlock()
+
+	forgetUserRightsOfToken(site, refreshToken)
+}
+
+// recordRefreshTokenAccepted forgets a previous rejection of the API refresh token by the site.
+func (m *vcdTokenManager) recordRefreshTokenAccepted(site string, refreshToken string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.rejected, getRefreshTokenKey(site, refreshToken))
+}
+
+// reconcileVCDAuthHealthyCondition reports in the VCDAuthHealthy condition why the VCD client of the VCDCluster could
+// not be created, and sets the condition to true once it is created. The other failures, e.g. an unreachable site, leave
+// the condition unchanged.
+func reconcileVCDAuthHealthyCondition(vcdCluster *infrav1beta3.VCDCluster, clientErr error) {
+	if clientErr == nil {
+		conditions.MarkTrue(vcdCluster, VCDAuthHealthyCondition)
+		return
+	}
+	reason := ""
+	var credentialsErr *CredentialsExpiredError
+	var unavailableErr *CredentialsUnavailableError
+	switch {
+	case errors.As(clientErr, &credentialsErr) && credentialsErr.refreshToken:
+		reason = RefreshTokenRejectedReason
+	case errors.As(clientErr, &credentialsErr):
+		reason = CredentialsRejectedReason
+	case errors.As(clientErr, &unavailableErr):
+		reason = CredentialsUnavailableReason
+	default:
+		return
+	}
+	conditions.MarkFalse(vcdCluster, VCDAuthHealthyCondition, reason, clusterv1.ConditionSeverityError, "%s",
+		clientErr.Error())
+}
+
+// getCredentialsSecretRef returns the credentials Secret of the VCDCluster, referenced by its userContext or by its
+// VCDClusterIdentity, or nil if the credentials are inlined in the userContext.
+func getCredentialsSecretRef(vcdCluster *infrav1beta3.VCDCluster,
+	identities map[string]*infrav1beta3.VCDClusterIdentity) *types.NamespacedName {
+
+	if vcdCluster.Spec.IdentityRef != nil {
+		identity, ok := identities[vcdCluster.Spec.IdentityRef.Name]
+		if !ok {
+			return nil
+		}
+		return &types.NamespacedName{
+			Namespace: identity.Spec.SecretRef.Namespace,
+			Name:      identity.Spec.SecretRef.Name,
+		}
+	}
+	secretRef := vcdCluster.Spec.UserCredentialsContext.SecretRef
+	if secretRef == nil {
+		return nil
+	}
+	return &types.NamespacedName{Namespace: secretRef.Namespace, Name: secretRef.Name}
+}
+
+// SecretToVCDClusters maps a Secret to the VCDClusters using it as credentials Secret, so that the VCDClusters pick up
+// rotated credentials right away.
+func (r *VCDClusterReconciler) SecretToVCDClusters(o client.Object) []ctrl.Request {
+	ctx := context.TODO()
+	log := ctrl.LoggerFrom(ctx)
+
+	secret, ok := o.(*corev1.Secret)
+	if !ok {
+		return nil
+	}
+	identityList := &infrav1beta3.VCDClusterIdentityList{}
+	if err := r.Client.List(ctx, identityList); err != nil {
+		log.Error(err, "failed to list the VCDClusterIdentities")
+		return nil
+	}
+	identities := make(map[string]*infrav1beta3.VCDClusterIdentity, len(identityList.Items))
+	for idx := range identityList.Items {
+		identities[identityList.Items[idx].Name] = &identityList.Items[idx]
+	}
+	vcdClusterList := &infrav1beta3.VCDClusterList{}
+	if err := r.Client.List(ctx, vcdClusterList); err != nil {
+		log.Error(err, "failed to list the VCDClusters")
+		return nil
+	}
+
+	var result []ctrl.Request
+	for idx := range vcdClusterList.Items {
+		vcdCluster := &vcdClusterList.Items[idx]
+		secretRef := getCredentialsSecretRef(vcdCluster, identities)
+		if secretRef == nil || secretRef.Name != secret.Name || secretRef.Namespace != secret.Namespace {
+			continue
+		}
+		result = append(result, ctrl.Request{
+			NamespacedName: types.NamespacedName{Namespace: vcdCluster.Namespace, Name: vcdCluster.Name},
+		})
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TrustBundleSecretKey is the key of the PEM certificates in the trust bundle Secret of a VCDCluster.
+const TrustBundleSecretKey = "ca.crt"
+
+// getTrustBundle returns the PEM certificates of the trust bundle Secret of the VCDCluster, or nil if the VCDCluster
+// has no trust bundle.
+func getTrustBundle(ctx context.Context, cli client.Client, vcdCluster *infrav1beta3.VCDCluster) ([]byte, error) {
+	secretRef := vcdCluster.Spec.VCDTrustBundleSecretRef
+	if secretRef == nil {
+		return nil, nil
+	}
+	secretNamespacedName := types.NamespacedName{
+		Name:      secretRef.Name,
+		Namespace: secretRef.Namespace,
+	}
+	if secretNamespacedName.Namespace == "" {
+		secretNamespacedName.Namespace = vcdCluster.Namespace
+	}
+	trustBundleSecret := &v1.Secret{}
+	if err := cli.Get(ctx, secretNamespacedName, trustBundleSecret); err != nil {
+		return nil, fmt.Errorf("error getting trust bundle secret [%s] in namespace [%s]: [%v]",
+			secretNamespacedName.Name, secretNamespacedName.Namespace, err)
+	}
+	trustBundle, ok := trustBundleSecret.Data[TrustBundleSecretKey]
+	if !ok || len(trustBundle) == 0 {
+		return nil, fmt.Errorf("trust bundle secret [%s] in namespace [%s] has no [%s] key",
+			secretNamespacedName.Name, secretNamespacedName.Namespace, TrustBundleSecretKey)
+	}
+	return trustBundle, nil
+}
+
+// getTrustBundlePool returns the pool of the CAs of the trust bundle of the VCDCluster, or nil if the VCDCluster has
+// no trust bundle.
+func getTrustBundlePool(ctx context.Context, cli client.Client, vcdCluster *infrav1beta3.VCDCluster) (*x509.CertPool,
+	error) {
+
+	trustBundle, err := getTrustBundle(ctx, cli, vcdCluster)
+	if err != nil || trustBundle == nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(trustBundle) {
+		return nil, fmt.Errorf("trust bundle secret [%s] of VCDCluster [%s] has no valid PEM certificate",
+			vcdCluster.Spec.VCDTrustBundleSecretRef.Name, vcdCluster.Name)
+	}
+	return pool, nil
+}
+
+// verifySiteTrustBundle checks that the certificate of the site is issued by a CA of the trust bundle. It is called
+// before the credentials of the cluster are sent to the site.
+func verifySiteTrustBundle(site string, pool *x509.CertPool) error {
+	siteURL, err := url.Parse(site)
+	if err != nil {
+		return fmt.Errorf("unable to parse site [%s]: [%v]", site, err)
+	}
+
+	conn, err := dialSiteTLS(site, &tls.Config{RootCAs: pool, ServerName: siteURL.Hostname()})
+	if err != nil && isCertificateVerificationError(err) {
+		return NewSiteCertificateMismatchError(fmt.Sprintf(
+			"certificate of site [%s] is not trusted by the trust bundle of the cluster: [%v]", site, err))
+	}
+	if err != nil {
+		return fmt.Errorf("unable to verify the certificate of site [%s] against the trust bundle of the cluster: [%v]",
+			site, err)
+	}
+	return conn.Close()
+}
+
+// isCertificateVerificationError checks if the TLS handshake failed on the verification of the certificate of the
+// server, rather than on the network, e.g. on a DNS error, a timeout or a proxy refusing the connection.
+func isCertificateVerificationError(err error) bool {
+	var verificationErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &verificationErr) || errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr)
+}
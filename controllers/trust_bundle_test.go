@@ -0,0 +1,127 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestCACertificate returns a self-signed CA certificate, which did not issue the certificate of the test sites.
+func newTestCACertificate(t *testing.T) *x509.Certificate {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate a private key: [%v]", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "other-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		t.Fatalf("unable to create a certificate: [%v]", err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unable to parse the certificate: [%v]", err)
+	}
+	return certificate
+}
+
+func TestSiteTLSConfigTrustsCloudAPICertificateOfTrustBundle(t *testing.T) {
+	server, authorizedRequests := newTestSite(t)
+	otherCACertificate := newTestCACertificate(t)
+
+	sitePool := x509.NewCertPool()
+	sitePool.AddCert(server.Certificate())
+	otherPool := x509.NewCertPool()
+	otherPool.AddCert(otherCACertificate)
+
+	testCases := []struct {
+		name               string
+		trustBundlePool    *x509.CertPool
+		pinnedFingerprints []string
+		wantErr            bool
+	}{
+		{name: "no trust bundle"},
+		{name: "certificate issued by a CA of the trust bundle", trustBundlePool: sitePool},
+		{name: "certificate issued by another CA", trustBundlePool: otherPool, wantErr: true},
+		{name: "trusted certificate which is not pinned", trustBundlePool: sitePool,
+			pinnedFingerprints: []string{getCertificateFingerprint(otherCACertificate)}, wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			authorizedRequests.Store(0)
+			err := getTestDefinedEntity(t, server.URL,
+				newSiteTLSConfig(server.URL, true, tc.trustBundlePool, tc.pinnedFingerprints))
+			if !tc.wantErr {
+				if err != nil {
+					t.Fatalf("unexpected error: [%v]", err)
+				}
+				if authorizedRequests.Load() != 1 {
+					t.Errorf("got [%d] requests with the bearer token, want 1", authorizedRequests.Load())
+				}
+				return
+			}
+			if !isSiteCertificateRejectedError(err) {
+				t.Errorf("got error [%v], want the certificate of the site to be rejected", err)
+			}
+			if authorizedRequests.Load() != 0 {
+				t.Errorf("bearer token was sent to a site presenting a certificate which is not trusted")
+			}
+		})
+	}
+}
+
+func TestVerifySiteTrustBundle(t *testing.T) {
+	server, _ := newTestSite(t)
+	closedServer := httptest.NewTLSServer(http.NotFoundHandler())
+	closedServer.Close()
+
+	sitePool := x509.NewCertPool()
+	sitePool.AddCert(server.Certificate())
+	otherPool := x509.NewCertPool()
+	otherPool.AddCert(newTestCACertificate(t))
+
+	testCases := []struct {
+		name         string
+		site         string
+		pool         *x509.CertPool
+		wantErr      bool
+		wantMismatch bool
+	}{
+		{name: "certificate issued by a CA of the trust bundle", site: server.URL, pool: sitePool},
+		{name: "certificate issued by another CA", site: server.URL, pool: otherPool, wantErr: true,
+			wantMismatch: true},
+		{name: "site unreachable", site: closedServer.URL, pool: sitePool, wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verifySiteTrustBundle(tc.site, tc.pool)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error [%v], want error [%t]", err, tc.wantErr)
+			}
+			var mismatchErr *SiteCertificateMismatchError
+			if errors.As(err, &mismatchErr) != tc.wantMismatch {
+				t.Errorf("got error [%v] of type [%T], want a certificate mismatch [%t]", err, err, tc.wantMismatch)
+			}
+		})
+	}
+}
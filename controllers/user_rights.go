@@ -0,0 +1,240 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	vcdutil "github.com/vmware/cluster-api-provider-cloud-director/pkg/util"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// In the minimal-rights mode, CAPVCD only requires the rights needed to provision the VMs and the load balancer of a
+// cluster (see docs/VCD_SETUP.md). The rights of the user of each VCDCluster are detected, and the optional features
+// whose rights the user lacks are disabled instead of failing the cluster:
+//   - rde: the capvcdCluster RDE of the cluster; the cluster gets a self-generated infra ID as with CAPVCD_SKIP_RDE.
+//   - edge-firewall: programming the egress allowlist and the firewall rules of a cluster on the gateway firewall of the
+//     edge gateway.
+//   - catalog-upload: uploading templates to a catalog.
+//   - network-edit: extending the IP pool of the OVDC network of a cluster with VCDClusterSpec.IPPoolExtension, and
+//     enabling its DHCP pool.
+const (
+	EnvMinimalRights = "CAPVCD_MINIMAL_RIGHTS"
+
+	OptionalFeatureRDE           = "rde"
+	OptionalFeatureEdgeFirewall  = "edge-firewall"
+	OptionalFeatureCatalogUpload = "catalog-upload"
+	OptionalFeatureNetworkEdit   = "network-edit"
+
+	// UserRightsRefreshInterval is the interval after which the rights of a user are detected again, so that rights
+	// granted to or revoked from the role of the user are eventually taken into account.
+	UserRightsRefreshInterval = 10 * time.Minute
+)
+
+var (
+	MinimalRights = vcdutil.Str2Bool(os.Getenv(EnvMinimalRights))
+
+	// optionalFeatureRights maps each optional feature to the VCD rights enabling it; any one of them is enough.
+	optionalFeatureRights = map[string][]string{
+		OptionalFeatureRDE: {
+			"vmware:capvcdCluster: Full Access",
+			"vmware:capvcdCluster: Modify",
+			"vmware:capvcdCluster: Administrator Full access",
+		},
+		OptionalFeatureEdgeFirewall: {
+			"Organization vDC Gateway: Configure Firewall",
+		},
+		OptionalFeatureCatalogUpload: {
+			"vApp Template / Media: Create / Upload",
+		},
+		OptionalFeatureNetworkEdit: {
+			"Organization vDC Network: Edit Properties",
+		},
+	}
+
+	userRightsCache     = make(map[string]*vcdUserRights)
+	userRightsCacheLock sync.Mutex
+)
+
+// vcdUserRights records the optional features available to a VCD user, as detected from the rights of its roles.
+type vcdUserRights struct {
+	// DisabledFeatures maps each disabled optional feature to the rights the user lacks to enable it.
+	DisabledFeatures map[string][]string
+	// DetectionError records why the rights of the user could not be detected, in which case all the optional
+	// features are disabled.
+	DetectionError string
+	DetectedAt     time.Time
+}
+
+// isFeatureEnabled checks if an optional feature is available to the user. All the features are available when the
+// minimal-rights mode is disabled, in which case the rights are nil.
+func (userRights *vcdUserRights) isFeatureEnabled(feature string) bool {
+	if userRights == nil {
+		return true
+	}
+	_, disabled := userRights.DisabledFeatures[feature]
+	return !disabled
+}
+
+// skipRDECreation checks if the cluster must get a self-generated infra ID instead of an RDE.
+func skipRDECreation(vcdCluster *infrav1beta3.VCDCluster, userRights *vcdUserRights) bool {
+	return SkipRDE || vcdCluster.Spec.RDEManagementDisabled || !userRights.isFeatureEnabled(OptionalFeatureRDE)
+}
+
+// getUserRightsCacheKey returns the key identifying the user of the client in the rights cache.
+func getUserRightsCacheKey(vcdClient *vcdsdk.Client) string {
+	authConfig := vcdClient.VCDAuthConfig
+	if authConfig.User != "" {
+		return fmt.Sprintf("%s/%s/%s", authConfig.Host, authConfig.UserOrg, authConfig.User)
+	}
+	// users authenticating with an API token are only identified by the token
+	return fmt.Sprintf("%s/%s/%x", authConfig.Host, authConfig.UserOrg, sha256.Sum256([]byte(authConfig.RefreshToken)))
+}
+
+// getRightsOfUser returns the names of the rights of the roles of the session of the client.
+func getRightsOfUser(vcdClient *vcdsdk.Client) (map[string]bool, error) {
+	govcdClient := &vcdClient.VCDClient.Client
+	sessionInfo, err := govcdClient.GetSessionInfo()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the session of user [%s]: [%v]", vcdClient.VCDAuthConfig.User, err)
+	}
+	roleIDs := make(map[string]bool)
+	for _, roleRef := range sessionInfo.RoleRefs {
+		roleIDs[roleRef.ID] = true
+	}
+
+	roles, err := govcdClient.GetAllRoles(nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the roles of user [%s]: [%v]", sessionInfo.User.Name, err)
+	}
+	rights := make(map[string]bool)
+	for _, role := range roles {
+		if role.Role == nil || !roleIDs[role.Role.ID] {
+			continue
+		}
+		roleRights, err := role.GetRights(nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get the rights of role [%s] of user [%s]: [%v]", role.Role.Name,
+				sessionInfo.User.Name, err)
+		}
+		for _, right := range roleRights {
+			rights[right.Name] = true
+		}
+	}
+	return rights, nil
+}
+
+// detectUserRights detects the optional features available to the user of the client. System administrators have
+// all the rights.
+func detectUserRights(vcdClient *vcdsdk.Client) *vcdUserRights {
+	userRights := &vcdUserRights{
+		DisabledFeatures: make(map[string][]string),
+		DetectedAt:       time.Now(),
+	}
+	if vcdClient.VCDAuthConfig.IsSysAdmin {
+		return userRights
+	}
+
+	rights, err := getRightsOfUser(vcdClient)
+	if err != nil {
+		userRights.DetectionError = err.Error()
+		rights = make(map[string]bool)
+	}
+	for feature, featureRights := range optionalFeatureRights {
+		hasRight := false
+		for _, right := range featureRights {
+			hasRight = hasRight || rights[right]
+		}
+		if !hasRight {
+			userRights.DisabledFeatures[feature] = featureRights
+		}
+	}
+	return userRights
+}
+
+// getUserRights returns the optional features available to the user of the client, or nil if the minimal-rights mode
+// is disabled. The rights are detected on first use of a user's credentials and refreshed after
+// UserRightsRefreshInterval. The returned boolean indicates if the rights were freshly detected.
+func getUserRights(vcdClient *vcdsdk.Client) (*vcdUserRights, bool) {
+	if !MinimalRights {
+		return nil, false
+	}
+	key := getUserRightsCacheKey(vcdClient)
+
+	userRightsCacheLock.Lock()
+	defer userRightsCacheLock.Unlock()
+
+	userRights, ok := userRightsCache[key]
+	if ok && time.Since(userRights.DetectedAt) < UserRightsRefreshInterval {
+		return userRights, false
+	}
+	userRights = detectUserRights(vcdClient)
+	userRightsCache[key] = userRights
+	return userRights, true
+}
+
+// forgetUserRightsOfToken drops the rights detected for the user of an API refresh token on the site, which are
+// detected again once a session is established with the token.
+func forgetUserRightsOfToken(site string, refreshToken string) {
+	suffix := fmt.Sprintf("/%x", sha256.Sum256([]byte(refreshToken)))
+
+	userRightsCacheLock.Lock()
+	defer userRightsCacheLock.Unlock()
+	for key := range userRightsCache {
+		if strings.HasPrefix(key, site+"/") && strings.HasSuffix(key, suffix) {
+			delete(userRightsCache, key)
+		}
+	}
+}
+
+// reconcileUserRights detects the optional features available to the user of the VCDCluster in the minimal-rights
+// mode, and reports the disabled ones in the OptionalFeaturesAvailable condition.
+func (r *VCDClusterReconciler) reconcileUserRights(ctx context.Context, vcdCluster *infrav1beta3.VCDCluster,
+	vcdClient *vcdsdk.Client) *vcdUserRights {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	userRights, detected := getUserRights(vcdClient)
+	if userRights == nil {
+		conditions.Delete(vcdCluster, OptionalFeaturesAvailableCondition)
+		return nil
+	}
+	if len(userRights.DisabledFeatures) == 0 {
+		conditions.MarkTrue(vcdCluster, OptionalFeaturesAvailableCondition)
+		return userRights
+	}
+
+	disabledFeatures := make([]string, 0, len(userRights.DisabledFeatures))
+	for feature, rights := range userRights.DisabledFeatures {
+		disabledFeatures = append(disabledFeatures, fmt.Sprintf("%s (requires one of [%s])", feature,
+			strings.Join(rights, ", ")))
+	}
+	sort.Strings(disabledFeatures)
+	message := fmt.Sprintf("optional features disabled for user [%s]: %s", vcdClient.VCDAuthConfig.User,
+		strings.Join(disabledFeatures, "; "))
+	if userRights.DetectionError != "" {
+		message = fmt.Sprintf("%s; rights could not be detected: %s", message, userRights.DetectionError)
+	}
+	if detected {
+		log.Info("Disabling the optional features the user lacks the rights for", "user",
+			vcdClient.VCDAuthConfig.User, "disabledFeatures", disabledFeatures, "detectionError",
+			userRights.DetectionError)
+	}
+	conditions.MarkFalse(vcdCluster, OptionalFeaturesAvailableCondition, MissingRightsReason,
+		clusterv1.ConditionSeverityWarning, message)
+	return userRights
+}
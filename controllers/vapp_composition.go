@@ -0,0 +1,447 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/sethvargo/go-password/password"
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// DefaultVAppCompositionBatchWindow is the default time during which the VMs requested in a vApp are collected
+	// before being created together. A single VM is created at once, the VMs requested while a composition of the vApp
+	// is running being batched nonetheless.
+	DefaultVAppCompositionBatchWindow = time.Duration(0)
+	// DefaultVAppCompositionMaxBatchSize is the default maximum number of VMs created in a vApp by a single
+	// composition.
+	DefaultVAppCompositionMaxBatchSize = 10
+
+	// tkgGuestCustomizationScript tells cloud-init the datasource of the TKG templates >= 1.6.0, which lack the file
+	// listing it; it is named 98-* so as not to conflict with the 99-* file of the TKG templates < 1.6.0. This is the
+	// guest customization script of the VMs created by vcdsdk.VdcManager.AddNewTkgVM.
+	tkgGuestCustomizationScript = `#!/usr/bin/env bash
+cat > /etc/cloud/cloud.cfg.d/98-cse-vmware-datasource.cfg <<EOF
+datasource_list: [ "VMware" ]
+EOF`
+)
+
+var (
+	// VAppCompositionBatchWindow is the time during which the VMs requested in a vApp are collected before being
+	// created together. With 0, the VMs are created as soon as they are requested, the ones requested while VMs are
+	// being created in the vApp still being batched.
+	VAppCompositionBatchWindow = DefaultVAppCompositionBatchWindow
+	// VAppCompositionMaxBatchSize is the maximum number of VMs created in a vApp by a single composition; 1 creates
+	// them one at a time.
+	VAppCompositionMaxBatchSize = DefaultVAppCompositionMaxBatchSize
+)
+
+// vmCompositionRequest is the request of a machine to create its VM in a vApp.
+type vmCompositionRequest struct {
+	vmName              string
+	catalogOrg          string
+	catalogName         string
+	templateName        string
+	placementPolicyName string
+	sizingPolicyName    string
+	storageProfileName  string
+
+	done chan struct{}
+	err  error
+}
+
+// vAppCompositionBatch is a set of VMs created in a vApp by a single recomposition of the vApp, with the VCD session
+// of the machines which requested them.
+type vAppCompositionBatch struct {
+	vdcManager *vcdsdk.VdcManager
+	vAppHref   string
+	requests   []*vmCompositionRequest
+	full       chan struct{}
+	ready      bool
+}
+
+// vAppCompositionQueue holds the batches of VMs waiting to be created in a vApp. VCD runs a single recomposition of a
+// vApp at a time, so that the batches are composed one after the other.
+type vAppCompositionQueue struct {
+	key       string
+	batches   []*vAppCompositionBatch
+	composing bool
+}
+
+var (
+	vAppCompositionQueues     = make(map[string]*vAppCompositionQueue)
+	vAppCompositionQueuesLock sync.Mutex
+
+	vAppCompositionLog = ctrl.Log.WithName("vapp-composition")
+)
+
+// getVAppCompositionQueueKey returns the key of the queue of the compositions of the vApp of the HREF with the VCD
+// session of the VDC manager, so that only the VMs requested with the same session are created together.
+func getVAppCompositionQueueKey(vdcManager *vcdsdk.VdcManager, vAppHref string) string {
+	return fmt.Sprintf("%s|%p", vAppHref, vdcManager.Client.VCDClient)
+}
+
+// composeVM creates the VM of the request in the vApp of the HREF, together with the VMs requested in the vApp with
+// the same VCD session by the other machines within VAppCompositionBatchWindow or while a composition of the vApp is
+// running, and returns once the VM is created or the context is done. The VMs of a batch share the failure of the
+// recomposition of the vApp, e.g. on an exhausted IP pool, which fails the creation of all of them.
+func composeVM(ctx context.Context, vdcManager *vcdsdk.VdcManager, vAppHref string,
+	request *vmCompositionRequest) error {
+
+	request.done = make(chan struct{})
+	queueKey := getVAppCompositionQueueKey(vdcManager, vAppHref)
+
+	vAppCompositionQueuesLock.Lock()
+	queue, ok := vAppCompositionQueues[queueKey]
+	if !ok {
+		queue = &vAppCompositionQueue{key: queueKey}
+		vAppCompositionQueues[queueKey] = queue
+	}
+	var batch *vAppCompositionBatch
+	if len(queue.batches) > 0 {
+		batch = queue.batches[len(queue.batches)-1]
+	}
+	if batch == nil || len(batch.requests) >= VAppCompositionMaxBatchSize {
+		batch = &vAppCompositionBatch{
+			vdcManager: vdcManager,
+			vAppHref:   vAppHref,
+			full:       make(chan struct{}),
+		}
+		queue.batches = append(queue.batches, batch)
+		go queue.waitBatch(batch, VAppCompositionBatchWindow)
+	}
+	batch.requests = append(batch.requests, request)
+	if len(batch.requests) == VAppCompositionMaxBatchSize {
+		// a batch which was full may have been filled again once requests were withdrawn from it
+		select {
+		case <-batch.full:
+		default:
+			close(batch.full)
+		}
+	}
+	vAppCompositionQueuesLock.Unlock()
+
+	select {
+	case <-request.done:
+		return request.err
+	case <-ctx.Done():
+		queue.withdraw(batch, request)
+		return fmt.Errorf("gave up waiting for the creation of VM [%s] in vApp [%s]: [%v]", request.vmName, vAppHref,
+			ctx.Err())
+	}
+}
+
+// withdraw removes the request from the batch if the batch is still waiting to be composed, so that a VM given up on
+// is not created.
+func (q *vAppCompositionQueue) withdraw(batch *vAppCompositionBatch, request *vmCompositionRequest) {
+	vAppCompositionQueuesLock.Lock()
+	defer vAppCompositionQueuesLock.Unlock()
+
+	for _, queuedBatch := range q.batches {
+		if queuedBatch != batch {
+			continue
+		}
+		for i, batchRequest := range batch.requests {
+			if batchRequest == request {
+				batch.requests = append(batch.requests[:i], batch.requests[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// waitBatch waits for the batch to be full or for the batch window to elapse, and then composes the batches of the
+// vApp ready to be composed unless a composition of the vApp is already running.
+func (q *vAppCompositionQueue) waitBatch(batch *vAppCompositionBatch, batchWindow time.Duration) {
+	select {
+	case <-batch.full:
+	case <-time.After(batchWindow):
+	}
+
+	vAppCompositionQueuesLock.Lock()
+	batch.ready = true
+	if q.composing {
+		vAppCompositionQueuesLock.Unlock()
+		return
+	}
+	q.composing = true
+	vAppCompositionQueuesLock.Unlock()
+
+	for {
+		vAppCompositionQueuesLock.Lock()
+		if len(q.batches) == 0 || !q.batches[0].ready {
+			q.composing = false
+			if len(q.batches) == 0 {
+				delete(vAppCompositionQueues, q.key)
+			}
+			vAppCompositionQueuesLock.Unlock()
+			return
+		}
+		nextBatch := q.batches[0]
+		q.batches = q.batches[1:]
+		vAppCompositionQueuesLock.Unlock()
+
+		nextBatch.compose()
+	}
+}
+
+// compose creates the VMs of the batch in a single recomposition of the vApp and reports the result to the machines
+// which requested them. A VM whose template or policies cannot be found fails on its own and is left out.
+func (b *vAppCompositionBatch) compose() {
+	var composedRequests []*vmCompositionRequest
+	defer func() {
+		for _, request := range b.requests {
+			close(request.done)
+		}
+	}()
+	failAll := func(err error) {
+		for _, request := range b.requests {
+			request.err = err
+		}
+	}
+	// all the VMs of the batch may have been given up on
+	if len(b.requests) == 0 {
+		return
+	}
+
+	if b.vdcManager.Vdc == nil {
+		failAll(fmt.Errorf("no Vdc created with name [%s]", b.vdcManager.VdcName))
+		return
+	}
+	vApp, err := b.vdcManager.Vdc.GetVAppByHref(b.vAppHref)
+	if err != nil {
+		failAll(fmt.Errorf("unable to get vApp [%s]: [%v]", b.vAppHref, err))
+		return
+	}
+	if vApp.VApp.NetworkConfigSection == nil || len(vApp.VApp.NetworkConfigSection.NetworkNames()) == 0 {
+		failAll(fmt.Errorf("no network found in vApp [%s] to connect the VMs to", vApp.VApp.Name))
+		return
+	}
+	orgManager, err := vcdsdk.NewOrgManager(b.vdcManager.Client, b.vdcManager.Client.ClusterOrgName)
+	if err != nil {
+		failAll(fmt.Errorf("error creating an orgManager object: [%v]", err))
+		return
+	}
+
+	templateHrefs := make(map[string]string)
+	policyHrefs := make(map[string]string)
+	var sourcedItems []*types.SourcedCompositionItemParam
+	for _, request := range b.requests {
+		sourcedItem, err := getVMSourcedItem(b.vdcManager, orgManager, vApp, request, templateHrefs, policyHrefs)
+		if err != nil {
+			request.err = err
+			continue
+		}
+		sourcedItems = append(sourcedItems, sourcedItem)
+		composedRequests = append(composedRequests, request)
+	}
+	if len(composedRequests) == 0 {
+		return
+	}
+
+	vmNames := make([]string, len(composedRequests))
+	for i, request := range composedRequests {
+		vmNames[i] = request.vmName
+	}
+	vAppCompositionLog.Info("Creating VMs in the vApp", "vApp", vApp.VApp.Name, "vms", vmNames)
+	err = recomposeVApp(b.vdcManager, vApp, sourcedItems)
+	if err != nil {
+		err = fmt.Errorf("unable to create VMs %v in vApp [%s]: [%v]", vmNames, vApp.VApp.Name, err)
+	}
+	for _, request := range composedRequests {
+		request.err = err
+	}
+}
+
+// getVMSourcedItem returns the item of the recomposition of the vApp creating the VM of the request. The HREFs of the
+// templates and of the compute policies already found in the batch are reused from templateHrefs and policyHrefs.
+func getVMSourcedItem(vdcManager *vcdsdk.VdcManager, orgManager *vcdsdk.OrgManager, vApp *govcd.VApp,
+	request *vmCompositionRequest, templateHrefs map[string]string,
+	policyHrefs map[string]string) (*types.SourcedCompositionItemParam, error) {
+
+	templateKey := fmt.Sprintf("%s/%s/%s", request.catalogOrg, request.catalogName, request.templateName)
+	templateHref, ok := templateHrefs[templateKey]
+	if !ok {
+		var err error
+		if templateHref, err = getVAppTemplateSourceHref(vdcManager, request.catalogOrg, request.catalogName,
+			request.templateName); err != nil {
+			return nil, err
+		}
+		templateHrefs[templateKey] = templateHref
+	}
+
+	getPolicyReference := func(policyName string) (*types.Reference, error) {
+		policyHref, ok := policyHrefs[policyName]
+		if !ok {
+			policy, err := orgManager.GetComputePolicyDetailsFromName(policyName)
+			if err != nil {
+				return nil, fmt.Errorf("unable to find compute policy [%s]: [%v]", policyName, err)
+			}
+			policyHref = policy.ID
+			policyHrefs[policyName] = policyHref
+		}
+		return &types.Reference{HREF: policyHref}, nil
+	}
+	var computePolicy *types.ComputePolicy
+	if request.placementPolicyName != "" || request.sizingPolicyName != "" {
+		computePolicy = &types.ComputePolicy{}
+	}
+	if request.placementPolicyName != "" {
+		policyReference, err := getPolicyReference(request.placementPolicyName)
+		if err != nil {
+			return nil, err
+		}
+		computePolicy.VmPlacementPolicy = policyReference
+	}
+	if request.sizingPolicyName != "" {
+		policyReference, err := getPolicyReference(request.sizingPolicyName)
+		if err != nil {
+			return nil, err
+		}
+		computePolicy.VmSizingPolicy = policyReference
+	}
+
+	var storageProfile *types.Reference
+	if request.storageProfileName != "" {
+		if vdcManager.Vdc.Vdc.VdcStorageProfiles != nil {
+			for _, profile := range vdcManager.Vdc.Vdc.VdcStorageProfiles.VdcStorageProfile {
+				if profile.Name == request.storageProfileName {
+					storageProfile = profile
+					break
+				}
+			}
+		}
+		if storageProfile == nil {
+			return nil, fmt.Errorf("storage profile [%s] chosen to create the VM in vApp [%s] does not exist",
+				request.storageProfileName, vApp.VApp.Name)
+		}
+	}
+
+	adminPassword, err := password.Generate(15, 5, 3, false, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate a password to create VM [%s] in vApp [%s]: [%v]",
+			request.vmName, vApp.VApp.Name, err)
+	}
+	enabled, disabled := true, false
+	return &types.SourcedCompositionItemParam{
+		Source: &types.Reference{
+			HREF: templateHref,
+			Name: request.vmName,
+		},
+		VMGeneralParams: &types.VMGeneralParams{
+			Name:               request.vmName,
+			Description:        "Auto-created VM",
+			NeedsCustomization: true,
+			RegenerateBiosUuid: true,
+		},
+		VAppScopedLocalID: request.vmName,
+		InstantiationParams: &types.InstantiationParams{
+			GuestCustomizationSection: &types.GuestCustomizationSection{
+				Enabled:               &enabled,
+				AdminPasswordEnabled:  &enabled,
+				AdminPasswordAuto:     &disabled,
+				AdminPassword:         adminPassword,
+				ResetPasswordRequired: &disabled,
+				ComputerName:          request.vmName,
+				CustomizationScript:   tkgGuestCustomizationScript,
+			},
+			NetworkConnectionSection: &types.NetworkConnectionSection{
+				NetworkConnection: []*types.NetworkConnection{
+					{
+						Network:                 vApp.VApp.NetworkConfigSection.NetworkNames()[0],
+						NeedsCustomization:      false,
+						IsConnected:             true,
+						IPAddressAllocationMode: "POOL",
+						NetworkAdapterType:      "VMXNET3",
+					},
+				},
+			},
+		},
+		StorageProfile: storageProfile,
+		ComputePolicy:  computePolicy,
+	}, nil
+}
+
+// getVAppTemplateSourceHref returns the HREF of the VM of the vApp template of the catalog, from which the VMs are
+// created. The catalog of another org is set by catalogOrg.
+func getVAppTemplateSourceHref(vdcManager *vcdsdk.VdcManager, catalogOrg string, catalogName string,
+	templateName string) (string, error) {
+
+	catalog, err := getCatalog(vdcManager.Client, catalogOrg, catalogName)
+	if err != nil {
+		return "", fmt.Errorf("unable to find catalog [%s] of org [%s]: [%v]", catalogName,
+			getCatalogOrgName(vdcManager.Client, catalogOrg), err)
+	}
+	vAppTemplates, err := catalog.QueryVappTemplateList()
+	if err != nil {
+		return "", fmt.Errorf("unable to query templates of catalog [%s]: [%v]", catalogName, err)
+	}
+	var queryVAppTemplate *types.QueryResultVappTemplateType
+	for _, vAppTemplate := range vAppTemplates {
+		if vAppTemplate.Name == templateName {
+			queryVAppTemplate = vAppTemplate
+			break
+		}
+	}
+	if queryVAppTemplate == nil {
+		return "", fmt.Errorf("unable to get template of name [%s] in catalog [%s]", templateName, catalogName)
+	}
+
+	client := &vdcManager.Client.VCDClient.Client
+	vAppTemplate := govcd.NewVAppTemplate(client)
+	if _, err = client.ExecuteRequest(queryVAppTemplate.HREF, http.MethodGet, "", "error retrieving vApp template: %s",
+		nil, vAppTemplate.VAppTemplate); err != nil {
+		return "", fmt.Errorf("unable to issue get for template with HREF [%s]: [%v]", queryVAppTemplate.HREF, err)
+	}
+	// status 8 is resolved and powered off
+	if vAppTemplate.VAppTemplate.Status != 8 {
+		return "", fmt.Errorf("vApp Template status [%d] is not ok", vAppTemplate.VAppTemplate.Status)
+	}
+	if vAppTemplate.VAppTemplate.Children != nil && len(vAppTemplate.VAppTemplate.Children.VM) != 0 {
+		return vAppTemplate.VAppTemplate.Children.VM[0].HREF, nil
+	}
+	return vAppTemplate.VAppTemplate.HREF, nil
+}
+
+// recomposeVApp adds the VMs of the sourced items to the vApp in a single task and waits for its completion.
+func recomposeVApp(vdcManager *vcdsdk.VdcManager, vApp *govcd.VApp,
+	sourcedItems []*types.SourcedCompositionItemParam) error {
+
+	vAppComposition := &vcdsdk.ComposeVAppWithVMs{
+		Ovf:              types.XMLNamespaceOVF,
+		Xsi:              types.XMLNamespaceXSI,
+		Xmlns:            types.XMLNamespaceVCloud,
+		Name:             vApp.VApp.Name,
+		Description:      vApp.VApp.Description,
+		SourcedItemList:  sourcedItems,
+		AllEULAsAccepted: false,
+	}
+	apiEndpoint, err := url.ParseRequestURI(vApp.VApp.HREF)
+	if err != nil {
+		return fmt.Errorf("invalid HREF [%s] of vApp [%s]: [%v]", vApp.VApp.HREF, vApp.VApp.Name, err)
+	}
+	apiEndpoint.Path += "/action/recomposeVApp"
+
+	task, err := vdcManager.Client.VCDClient.Client.ExecuteTaskRequest(apiEndpoint.String(), http.MethodPost,
+		types.MimeRecomposeVappParams, "error instantiating a new VM: %s", vAppComposition)
+	if err != nil {
+		return fmt.Errorf("unable to issue call to recompose vApp [%s]: [%v]", vApp.VApp.Name, err)
+	}
+	if err = task.WaitTaskCompletion(); err != nil {
+		return fmt.Errorf("failed to wait for task [%s] recomposing vApp [%s]: [%v]", task.Task.HREF,
+			vApp.VApp.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+)
+
+// newTestVdcManager returns a VDC manager of a VCD session without VDC, with which every composition fails.
+func newTestVdcManager() *vcdsdk.VdcManager {
+	return &vcdsdk.VdcManager{
+		VdcName: "vdc",
+		Client:  &vcdsdk.Client{VCDClient: &govcd.VCDClient{}},
+	}
+}
+
+// setVAppCompositionBatching sets the batching of the compositions for the test.
+func setVAppCompositionBatching(t *testing.T, batchWindow time.Duration, maxBatchSize int) {
+	previousBatchWindow, previousMaxBatchSize := VAppCompositionBatchWindow, VAppCompositionMaxBatchSize
+	VAppCompositionBatchWindow, VAppCompositionMaxBatchSize = batchWindow, maxBatchSize
+	t.Cleanup(func() {
+		VAppCompositionBatchWindow, VAppCompositionMaxBatchSize = previousBatchWindow, previousMaxBatchSize
+	})
+}
+
+func TestGetVAppCompositionQueueKey(t *testing.T) {
+	vdcManager, otherVdcManager := newTestVdcManager(), newTestVdcManager()
+	sameSessionVdcManager := &vcdsdk.VdcManager{Client: &vcdsdk.Client{VCDClient: vdcManager.Client.VCDClient}}
+
+	testCases := []struct {
+		name          string
+		vdcManager    *vcdsdk.VdcManager
+		vAppHref      string
+		wantSameQueue bool
+	}{
+		{name: "same vApp and session", vdcManager: vdcManager, vAppHref: "https://vcd/api/vApp/vapp-1",
+			wantSameQueue: true},
+		{name: "same session of another VDC manager", vdcManager: sameSessionVdcManager,
+			vAppHref: "https://vcd/api/vApp/vapp-1", wantSameQueue: true},
+		{name: "another vApp", vdcManager: vdcManager, vAppHref: "https://vcd/api/vApp/vapp-2"},
+		{name: "another session", vdcManager: otherVdcManager, vAppHref: "https://vcd/api/vApp/vapp-1"},
+	}
+	key := getVAppCompositionQueueKey(vdcManager, "https://vcd/api/vApp/vapp-1")
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := getVAppCompositionQueueKey(tc.vdcManager, tc.vAppHref) == key
+			if got != tc.wantSameQueue {
+				t.Errorf("got same queue [%t], want [%t]", got, tc.wantSameQueue)
+			}
+		})
+	}
+}
+
+func TestComposeVMBatches(t *testing.T) {
+	testCases := []struct {
+		name         string
+		batchWindow  time.Duration
+		maxBatchSize int
+		vmCount      int
+	}{
+		{name: "single VM", batchWindow: 0, maxBatchSize: DefaultVAppCompositionMaxBatchSize, vmCount: 1},
+		{name: "VMs collected within the batch window", batchWindow: 50 * time.Millisecond,
+			maxBatchSize: DefaultVAppCompositionMaxBatchSize, vmCount: 5},
+		{name: "VMs split in full batches", batchWindow: time.Hour, maxBatchSize: 2, vmCount: 4},
+		{name: "VMs created one at a time", batchWindow: time.Hour, maxBatchSize: 1, vmCount: 3},
+	}
+	for idx, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setVAppCompositionBatching(t, tc.batchWindow, tc.maxBatchSize)
+			vdcManager := newTestVdcManager()
+			vAppHref := fmt.Sprintf("https://vcd/api/vApp/vapp-batches-%d", idx)
+
+			errs := make([]error, tc.vmCount)
+			var wg sync.WaitGroup
+			for i := 0; i < tc.vmCount; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+					defer cancel()
+					errs[i] = composeVM(ctx, vdcManager, vAppHref, &vmCompositionRequest{vmName: fmt.Sprintf("vm-%d", i)})
+				}(i)
+			}
+			wg.Wait()
+
+			for i, err := range errs {
+				if err == nil || !strings.Contains(err.Error(), "no Vdc created with name [vdc]") {
+					t.Errorf("got error [%v] for VM [%d], want the failure of its batch", err, i)
+				}
+			}
+			vAppCompositionQueuesLock.Lock()
+			defer vAppCompositionQueuesLock.Unlock()
+			if _, ok := vAppCompositionQueues[getVAppCompositionQueueKey(vdcManager, vAppHref)]; ok {
+				t.Errorf("queue of vApp [%s] was not removed once its batches were composed", vAppHref)
+			}
+		})
+	}
+}
+
+func TestComposeVMBatchSize(t *testing.T) {
+	setVAppCompositionBatching(t, time.Hour, 3)
+	vdcManager := newTestVdcManager()
+	vAppHref := "https://vcd/api/vApp/vapp-batch-size"
+	queueKey := getVAppCompositionQueueKey(vdcManager, vAppHref)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func(i int) {
+			results <- composeVM(ctx, vdcManager, vAppHref, &vmCompositionRequest{vmName: fmt.Sprintf("vm-%d", i)})
+		}(i)
+	}
+
+	// the batch which is not full waits for the batch window
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		vAppCompositionQueuesLock.Lock()
+		queue := vAppCompositionQueues[queueKey]
+		batchSize := 0
+		if queue != nil && len(queue.batches) == 1 {
+			batchSize = len(queue.batches[0].requests)
+		}
+		vAppCompositionQueuesLock.Unlock()
+		if batchSize == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("VMs were not collected in a single batch")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case err := <-results:
+		t.Fatalf("VM created before its batch was full: [%v]", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// the VM filling the batch composes it right away
+	if err := composeVM(ctx, vdcManager, vAppHref, &vmCompositionRequest{vmName: "vm-2"}); err == nil {
+		t.Errorf("expected the VM filling the batch to fail with its batch")
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-results; err == nil {
+			t.Errorf("expected the VMs of the batch to fail with their batch")
+		}
+	}
+}
+
+func TestComposeVMContextDone(t *testing.T) {
+	setVAppCompositionBatching(t, time.Hour, DefaultVAppCompositionMaxBatchSize)
+	vdcManager := newTestVdcManager()
+	vAppHref := "https://vcd/api/vApp/vapp-context-done"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := composeVM(ctx, vdcManager, vAppHref, &vmCompositionRequest{vmName: "vm"})
+	if err == nil || !strings.Contains(err.Error(), "gave up waiting for the creation of VM [vm]") {
+		t.Errorf("got error [%v], want the creation to be given up", err)
+	}
+}
+
+func TestComposeVMContextDoneWithdrawsRequest(t *testing.T) {
+	setVAppCompositionBatching(t, time.Hour, DefaultVAppCompositionMaxBatchSize)
+	vdcManager := newTestVdcManager()
+	vAppHref := "https://vcd/api/vApp/vapp-withdrawn"
+	queueKey := getVAppCompositionQueueKey(vdcManager, vAppHref)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results := make(chan error, 1)
+	go func() {
+		results <- composeVM(context.Background(), vdcManager, vAppHref, &vmCompositionRequest{vmName: "vm-0"})
+	}()
+	go func() {
+		results <- composeVM(ctx, vdcManager, vAppHref, &vmCompositionRequest{vmName: "vm-1"})
+	}()
+
+	getBatchVMNames := func() []string {
+		vAppCompositionQueuesLock.Lock()
+		defer vAppCompositionQueuesLock.Unlock()
+		var vmNames []string
+		if queue := vAppCompositionQueues[queueKey]; queue != nil && len(queue.batches) == 1 {
+			for _, request := range queue.batches[0].requests {
+				vmNames = append(vmNames, request.vmName)
+			}
+		}
+		return vmNames
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(getBatchVMNames()) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("VMs were not collected in a single batch")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the VM given up on leaves the batch, which still creates the other VM
+	cancel()
+	if err := <-results; err == nil || !strings.Contains(err.Error(), "gave up waiting for the creation of VM [vm-1]") {
+		t.Fatalf("got error [%v], want the creation of the VM to be given up", err)
+	}
+	if vmNames := getBatchVMNames(); len(vmNames) != 1 || vmNames[0] != "vm-0" {
+		t.Errorf("got VMs %v in the batch, want [vm-0]", vmNames)
+	}
+}
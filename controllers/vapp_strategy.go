@@ -0,0 +1,292 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	"github.com/vmware/cluster-api-provider-cloud-director/release"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Types of VCDClusterSpec.VAppStrategy
+const (
+	VAppStrategySingle               = "Single"
+	VAppStrategyPerMachineDeployment = "PerMachineDeployment"
+	VAppStrategySharded              = "Sharded"
+)
+
+// ResourceTypeVApp is the type of the vApps created for VCDClusterSpec.VAppStrategy in the VcdResourceMap of the
+// cluster.
+const ResourceTypeVApp = "vApp"
+
+// getVAppStrategyType returns the type of the vApp strategy of the cluster, Single by default.
+func getVAppStrategyType(vcdCluster *infrav1beta3.VCDCluster) string {
+	if vcdCluster.Spec.VAppStrategy == nil || vcdCluster.Spec.VAppStrategy.Type == "" {
+		return VAppStrategySingle
+	}
+	return vcdCluster.Spec.VAppStrategy.Type
+}
+
+// hasStrategyVApps checks if the machines of the cluster may be spread across the vApps of its vApp strategy, either
+// because of the current strategy or because vApps of an earlier strategy are still tracked.
+func hasStrategyVApps(vcdCluster *infrav1beta3.VCDCluster) bool {
+	return getVAppStrategyType(vcdCluster) != VAppStrategySingle || len(vcdCluster.Status.VcdResourceMap.VApps) > 0
+}
+
+// getStrategyVAppPrefix returns the prefix of the names of the vApps of the vApp strategy of the cluster.
+func getStrategyVAppPrefix(vcdCluster *infrav1beta3.VCDCluster) string {
+	return CreateFullVAppName(vcdCluster) + "-"
+}
+
+// getMachineDeploymentVAppName returns the name of the vApp of the machines of the MachineDeployment with the
+// PerMachineDeployment strategy.
+func getMachineDeploymentVAppName(vcdCluster *infrav1beta3.VCDCluster, machineDeploymentName string) string {
+	return getStrategyVAppPrefix(vcdCluster) + machineDeploymentName
+}
+
+// getShardVAppName returns the name of the vApp of the shard with the Sharded strategy.
+func getShardVAppName(vcdCluster *infrav1beta3.VCDCluster, shard int) string {
+	return fmt.Sprintf("%sshard-%d", getStrategyVAppPrefix(vcdCluster), shard)
+}
+
+// discoverStrategyVApps returns the vApps of the OVDC named after the vApp of the cluster and tagged with its infra ID,
+// which were created for its vApp strategy.
+func discoverStrategyVApps(vdcManager *vcdsdk.VdcManager, vcdCluster *infrav1beta3.VCDCluster) ([]*govcd.VApp,
+	error) {
+
+	prefix := getStrategyVAppPrefix(vcdCluster)
+	var vApps []*govcd.VApp
+	for _, vAppRef := range vdcManager.Vdc.GetVappList() {
+		if vAppRef == nil || !strings.HasPrefix(vAppRef.Name, prefix) {
+			continue
+		}
+		vApp, err := vdcManager.Vdc.GetVAppByHref(vAppRef.HREF)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get vApp [%s]: [%v]", vAppRef.Name, err)
+		}
+		infraID, err := vdcManager.GetMetadataByKey(vApp, CapvcdInfraId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get metadata [%s] of vApp [%s]: [%v]", CapvcdInfraId, vAppRef.Name, err)
+		}
+		if infraID == vcdCluster.Status.InfraId {
+			vApps = append(vApps, vApp)
+		}
+	}
+	return vApps, nil
+}
+
+// findMachineStrategyVApp returns the name of the vApp of the vApp strategy of the cluster holding the VM of the
+// machine, found by its provider ID or by its name, or an empty name if there is none.
+func findMachineStrategyVApp(vdcManager *vcdsdk.VdcManager, vcdCluster *infrav1beta3.VCDCluster,
+	vcdMachine *infrav1beta3.VCDMachine, vmName string) (string, error) {
+
+	vApps, err := discoverStrategyVApps(vdcManager, vcdCluster)
+	if err != nil {
+		return "", err
+	}
+	vmID := getVMIDFromProviderID(vcdMachine.Spec.ProviderID)
+	for _, vApp := range vApps {
+		if vmID != "" {
+			_, err = vApp.GetVMById(vmID, false)
+		} else {
+			_, err = vApp.GetVMByName(vmName, false)
+		}
+		if err == nil {
+			return vApp.VApp.Name, nil
+		}
+		if err != govcd.ErrorEntityNotFound {
+			return "", fmt.Errorf("failed to look for VM [%s] in vApp [%s]: [%v]", vmName, vApp.VApp.Name, err)
+		}
+	}
+	return "", nil
+}
+
+// getShardVAppNameForMachine returns the name of the first vApp of the Sharded strategy holding fewer machines than the
+// size of the shards. The machines are counted from the VAppName of the status of the VCDMachines of the cluster, so
+// that a shard may exceed its size by the number of machines placed at the same time.
+func (r *VCDMachineReconciler) getShardVAppNameForMachine(ctx context.Context, vcdCluster *infrav1beta3.VCDCluster,
+	vcdMachine *infrav1beta3.VCDMachine) (string, error) {
+
+	clusterName := vcdMachine.Labels[clusterv1.ClusterNameLabel]
+	vcdMachineList := &infrav1beta3.VCDMachineList{}
+	if err := r.Client.List(ctx, vcdMachineList, client.InNamespace(vcdMachine.Namespace),
+		client.MatchingLabels{clusterv1.ClusterNameLabel: clusterName}); err != nil {
+		return "", fmt.Errorf("failed to list the VCDMachines of cluster [%s]: [%v]", clusterName, err)
+	}
+	machineCounts := make(map[string]int32)
+	for _, other := range vcdMachineList.Items {
+		if other.Name != vcdMachine.Name && other.Status.VAppName != "" {
+			machineCounts[other.Status.VAppName]++
+		}
+	}
+	if vcdCluster.Spec.VAppStrategy.Sharded == nil {
+		return "", fmt.Errorf("no size is set for the vApps of the Sharded strategy of cluster [%s]", vcdCluster.Name)
+	}
+	size := vcdCluster.Spec.VAppStrategy.Sharded.Size
+	for shard := 0; ; shard++ {
+		vAppName := getShardVAppName(vcdCluster, shard)
+		if machineCounts[vAppName] < size {
+			return vAppName, nil
+		}
+	}
+}
+
+// assignMachineVApp records in VCDMachineStatus.VAppName the vApp of the VM of a worker machine of a cluster with a
+// vApp strategy. A machine whose VM already exists keeps the vApp it is found in, which is how the placement of the
+// machines is discovered again when their status is lost, e.g. after a move to another management cluster. The
+// control plane machines and the machines set to a vApp by their VCDMachine are not affected.
+func (r *VCDMachineReconciler) assignMachineVApp(ctx context.Context, vdcManager *vcdsdk.VdcManager,
+	vcdCluster *infrav1beta3.VCDCluster, machine *clusterv1.Machine, vcdMachine *infrav1beta3.VCDMachine,
+	vmName string) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	if vcdMachine.Spec.VAppName != "" || vcdMachine.Status.VAppName != "" || util.IsControlPlaneMachine(machine) ||
+		!hasStrategyVApps(vcdCluster) {
+		return nil
+	}
+
+	vAppName, err := findMachineStrategyVApp(vdcManager, vcdCluster, vcdMachine, vmName)
+	if err != nil {
+		return err
+	}
+	if vAppName == "" && vcdMachine.Spec.ProviderID != nil {
+		// the VM was created in the vApp of the cluster before the vApp strategy was set
+		vAppName = CreateFullVAppName(vcdCluster)
+	}
+	if vAppName == "" {
+		switch getVAppStrategyType(vcdCluster) {
+		case VAppStrategyPerMachineDeployment:
+			vAppName = CreateFullVAppName(vcdCluster)
+			if name := machine.Labels[clusterv1.MachineDeploymentNameLabel]; name != "" {
+				vAppName = getMachineDeploymentVAppName(vcdCluster, name)
+			}
+		case VAppStrategySharded:
+			if vAppName, err = r.getShardVAppNameForMachine(ctx, vcdCluster, vcdMachine); err != nil {
+				return err
+			}
+		default:
+			vAppName = CreateFullVAppName(vcdCluster)
+		}
+	}
+	log.Info("Placing the machine in the vApp of the vApp strategy of the cluster", "vAppName", vAppName,
+		"vAppStrategy", getVAppStrategyType(vcdCluster))
+	vcdMachine.Status.VAppName = vAppName
+	return nil
+}
+
+// reconcileStrategyVApps records the vApps of the vApp strategy of the cluster in its VcdResourceMap, and deletes the
+// ones left without VMs and without machines placed in them.
+func (r *VCDClusterReconciler) reconcileStrategyVApps(ctx context.Context, vcdClient *vcdsdk.Client,
+	vcdCluster *infrav1beta3.VCDCluster) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	if !hasStrategyVApps(vcdCluster) {
+		return nil
+	}
+	vdcManager, err := vcdsdk.NewVDCManager(vcdClient, vcdClient.ClusterOrgName, getOvdcName(vcdCluster))
+	if err != nil {
+		return fmt.Errorf("failed to create a vdc manager object: [%v]", err)
+	}
+	vApps, err := discoverStrategyVApps(vdcManager, vcdCluster)
+	if err != nil {
+		return err
+	}
+
+	clusterName, ok := vcdCluster.Labels[clusterv1.ClusterNameLabel]
+	if !ok {
+		return fmt.Errorf("VCDCluster [%s] has no label [%s]", vcdCluster.Name, clusterv1.ClusterNameLabel)
+	}
+	vcdMachineList := &infrav1beta3.VCDMachineList{}
+	if err = r.Client.List(ctx, vcdMachineList, client.InNamespace(vcdCluster.Namespace),
+		client.MatchingLabels{clusterv1.ClusterNameLabel: clusterName}); err != nil {
+		return fmt.Errorf("failed to list the VCDMachines of cluster [%s]: [%v]", clusterName, err)
+	}
+	usedVAppNames := make(map[string]bool)
+	for idx := range vcdMachineList.Items {
+		usedVAppNames[getMachineVAppName(&vcdMachineList.Items[idx], vcdCluster)] = true
+	}
+
+	existingVAppIDs := make(map[string]bool)
+	for _, vApp := range vApps {
+		if (vApp.VApp.Children != nil && len(vApp.VApp.Children.VM) > 0) || usedVAppNames[vApp.VApp.Name] {
+			existingVAppIDs[vApp.VApp.ID] = true
+			if err = updateVdcResourceToVcdCluster(vcdCluster, ResourceTypeVApp, vApp.VApp.ID,
+				vApp.VApp.Name); err != nil {
+				return fmt.Errorf("failed to record vApp [%s] in the resource map of the cluster: [%v]",
+					vApp.VApp.Name, err)
+			}
+			continue
+		}
+		log.Info("Deleting the empty vApp of the vApp strategy of the cluster", "vAppName", vApp.VApp.Name)
+		if err = vdcManager.DeleteVApp(vApp.VApp.Name); err != nil && err != govcd.ErrorEntityNotFound {
+			return fmt.Errorf("failed to delete empty vApp [%s]: [%v]", vApp.VApp.Name, err)
+		}
+		r.recordEvent(vcdCluster, corev1.EventTypeNormal, VAppDeletedReason,
+			fmt.Sprintf("Deleted empty vApp [%s] of vApp strategy [%s]", vApp.VApp.Name,
+				getVAppStrategyType(vcdCluster)))
+		rdeManager := vcdsdk.NewRDEManager(vcdClient, vcdCluster.Status.InfraId,
+			capisdk.StatusComponentNameCAPVCD, release.Version)
+		if err = rdeManager.RemoveFromVCDResourceSet(ctx, vcdsdk.ComponentCAPVCD, VCDResourceVApp,
+			vApp.VApp.Name); err != nil {
+			log.Error(err, "failed to remove the vApp from the VCDResourceSet of the RDE", "vAppName",
+				vApp.VApp.Name)
+		}
+	}
+	for _, trackedVApp := range append(infrav1beta3.VCDResources{}, vcdCluster.Status.VcdResourceMap.VApps...) {
+		if !existingVAppIDs[trackedVApp.ID] {
+			if err = removeVcdResourceFromVcdCluster(vcdCluster, ResourceTypeVApp, trackedVApp.ID); err != nil {
+				log.Error(err, "failed to remove vApp from the resource map of the cluster", "vAppName",
+					trackedVApp.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// reconcileDeleteStrategyVApps deletes the vApps of the vApp strategy of the cluster, once the VMs of its machines are
+// deleted.
+func (r *VCDClusterReconciler) reconcileDeleteStrategyVApps(ctx context.Context, vcdClient *vcdsdk.Client,
+	vcdCluster *infrav1beta3.VCDCluster) (ctrl.Result, error) {
+
+	if !hasStrategyVApps(vcdCluster) {
+		return ctrl.Result{}, nil
+	}
+	vdcManager, err := vcdsdk.NewVDCManager(vcdClient, vcdClient.ClusterOrgName, getOvdcName(vcdCluster))
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to create a vdc manager object to delete the vApps of "+
+			"cluster [%s]", vcdCluster.Name)
+	}
+	vApps, err := discoverStrategyVApps(vdcManager, vcdCluster)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to discover the vApps of cluster [%s]", vcdCluster.Name)
+	}
+
+	capvcdRDEManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
+	for _, vApp := range vApps {
+		result, err := r.reconcileDeleteSingleVApp(ctx, getOvdcName(vcdCluster), vApp.VApp.Name, vcdClient,
+			capvcdRDEManager, vcdCluster)
+		if err != nil {
+			return result, errors.Wrapf(err, "unable to delete vApp [%s] of cluster [%s]", vApp.VApp.Name,
+				vcdCluster.Name)
+		}
+	}
+	vcdCluster.Status.VcdResourceMap.VApps = nil
+	return ctrl.Result{}, nil
+}
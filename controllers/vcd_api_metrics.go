@@ -0,0 +1,123 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Results of the operations reported by the capvcd_vcd_logins_total and capvcd_vm_clone_duration_seconds metrics
+const (
+	OperationResultSuccess = "success"
+	OperationResultFailure = "failure"
+)
+
+var (
+	// vcdAPIRequestDuration observes the latency of the VCD API requests by operation.
+	vcdAPIRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "capvcd_vcd_api_request_duration_seconds",
+		Help: "Latency of the VCD API requests, by operation. The operation is the method and path of the " +
+			"request, with the IDs of the VCD entities replaced by {id}.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"operation"})
+
+	// vcdAPIRequestErrors counts the VCD API requests which failed, by operation and HTTP status code.
+	vcdAPIRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "capvcd_vcd_api_request_errors_total",
+		Help: "Number of VCD API requests which failed, by operation and HTTP status code. The code is " +
+			"\"transport\" for the requests which got no response.",
+	}, []string{"operation", "code"})
+
+	// vcdLogins counts the logins into VCD, which are only made when no cached session of the credentials is usable.
+	vcdLogins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "capvcd_vcd_logins_total",
+		Help: "Number of logins into VCD, by result. The sessions are reused across reconciliations.",
+	}, []string{"result"})
+
+	// vmCloneDuration observes the time taken by VCD to create the VMs of the machines from their template.
+	vmCloneDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "capvcd_vm_clone_duration_seconds",
+		Help:    "Time taken to create the VM of a machine from its template, by result.",
+		Buckets: prometheus.ExponentialBuckets(5, 2, 10),
+	}, []string{"result"})
+
+	// loadBalancerReconcileErrors counts the failed reconciliations of the control plane endpoints of the clusters.
+	loadBalancerReconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "capvcd_load_balancer_reconcile_errors_total",
+		Help: "Number of failed reconciliations of the control plane endpoints of the clusters, by type of " +
+			"endpoint: managed, external, passthrough or dnat.",
+	}, []string{"type"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(vcdAPIRequestDuration, vcdAPIRequestErrors, vcdLogins, vmCloneDuration,
+		loadBalancerReconcileErrors)
+}
+
+// vcdEntityIDPattern matches the UUIDs identifying the VCD entities in the paths of the API, e.g. in vm-<uuid> or
+// urn:vcloud:vm:<uuid>.
+var vcdEntityIDPattern = regexp.MustCompile(`[[:xdigit:]]{8}(-[[:xdigit:]]{4}){3}-[[:xdigit:]]{12}`)
+
+// getVCDAPIOperation returns the operation of a VCD API request, i.e. its method and path with the IDs of the VCD
+// entities replaced, so that the requests on different entities of the same type are reported together. The type of
+// the queries is kept.
+func getVCDAPIOperation(req *http.Request) string {
+	segments := strings.Split(req.URL.Path, "/")
+	for idx, segment := range segments {
+		if vcdEntityIDPattern.MatchString(segment) {
+			segments[idx] = "{id}"
+		}
+	}
+	operation := req.Method + " " + strings.Join(segments, "/")
+	if queryType := req.URL.Query().Get("type"); queryType != "" && strings.HasSuffix(req.URL.Path, "/query") {
+		operation += "?type=" + queryType
+	}
+	return operation
+}
+
+// vcdAPIMetricsTransport records the latency and errors of the VCD API requests.
+type vcdAPIMetricsTransport struct {
+	base http.RoundTripper
+}
+
+func newVCDAPIMetricsTransport(base http.RoundTripper) http.RoundTripper {
+	return &vcdAPIMetricsTransport{base: base}
+}
+
+func (t *vcdAPIMetricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	operation := getVCDAPIOperation(req)
+	startTime := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	vcdAPIRequestDuration.WithLabelValues(operation).Observe(time.Since(startTime).Seconds())
+	if err != nil {
+		vcdAPIRequestErrors.WithLabelValues(operation, "transport").Inc()
+	} else if resp.StatusCode >= http.StatusBadRequest {
+		vcdAPIRequestErrors.WithLabelValues(operation, strconv.Itoa(resp.StatusCode)).Inc()
+	}
+	return resp, err
+}
+
+// CloseIdleConnections closes the idle connections of the base transport, as done at the end of the reconciliations.
+func (t *vcdAPIMetricsTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// getOperationResult returns the result of an operation which returned err, as reported by the metrics.
+func getOperationResult(err error) string {
+	if err != nil {
+		return OperationResultFailure
+	}
+	return OperationResultSuccess
+}
@@ -0,0 +1,151 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+)
+
+// VCDClientIdleTimeout is the time after which a cached VCD session which was not used is dropped. It is below the
+// default idle timeout of the VCD sessions, 30 minutes, so that an expired session is not reused.
+const VCDClientIdleTimeout = 20 * time.Minute
+
+// cachedVCDClient is an authenticated VCD session shared by the reconciliations of the clusters and machines using the
+// same credentials on the same site.
+type cachedVCDClient struct {
+	client          *vcdsdk.Client
+	trustBundlePool *x509.CertPool
+	lastUsed        time.Time
+	// unauthorized is set once VCD rejects a request of the session with 401, e.g. after the session expired
+	unauthorized atomic.Bool
+}
+
+// vcdClientCache caches the VCD sessions so that each reconciliation does not log into VCD again. The sessions are
+// keyed by site, org, user and credentials: a rotated password, refresh token or SAML assertion logs in again.
+type vcdClientCache struct {
+	lock    sync.Mutex
+	clients map[string]*cachedVCDClient
+}
+
+var vcdClients = &vcdClientCache{
+	clients: make(map[string]*cachedVCDClient),
+}
+
+// unauthorizedSessionTransport flags the cached session of its requests as unauthorized once VCD responds with 401.
+type unauthorizedSessionTransport struct {
+	base   http.RoundTripper
+	cached *cachedVCDClient
+}
+
+func (t *unauthorizedSessionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		t.cached.unauthorized.Store(true)
+	}
+	return resp, err
+}
+
+// CloseIdleConnections closes the idle connections of the base transport, as done at the end of the reconciliations.
+func (t *unauthorizedSessionTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// getVCDClientCacheKey returns the key of the VCD session of the credentials of the VCDCluster. The credentials are
+// hashed so that they are not kept in memory by the cache.
+func getVCDClientCacheKey(vcdCluster *infrav1beta3.VCDCluster, orgName string, userOrg string,
+	userCreds infrav1beta3.UserCredentialsContext, samlCreds *samlCredentials) string {
+
+	credentials := sha256.New()
+	for _, secret := range []string{userCreds.Password, userCreds.RefreshToken} {
+		credentials.Write([]byte(secret))
+		credentials.Write([]byte{0})
+	}
+	if samlCreds != nil {
+		credentials.Write(samlCreds.assertion)
+		credentials.Write([]byte{0})
+		credentials.Write([]byte(samlCreds.adfsRelyingPartyId))
+	}
+	pinnedFingerprints := ""
+	if vcdCluster.Spec.PinSiteCertificate {
+		pinnedFingerprints = strings.Join(vcdCluster.Spec.SiteCertificateFingerprints, ",")
+	}
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%s|%x", vcdCluster.Spec.Site, orgName, userOrg, userCreds.Username,
+		userCreds.AuthType, pinnedFingerprints, credentials.Sum(nil))
+}
+
+// newSessionClient returns a client of the cached session to be used by a single reconciliation, which may then point
+// it at the org and OVDC of its cluster without affecting the other users of the session.
+func newSessionClient(cached *cachedVCDClient) *vcdsdk.Client {
+	return &vcdsdk.Client{
+		VCDAuthConfig:   cached.client.VCDAuthConfig,
+		ClusterOrgName:  cached.client.ClusterOrgName,
+		ClusterOVDCName: cached.client.ClusterOVDCName,
+		VCDClient:       cached.client.VCDClient,
+		APIClient:       cached.client.APIClient,
+		APIClient37_2:   cached.client.APIClient37_2,
+	}
+}
+
+// get returns a client of the cached session of the key, or nil if there is no session for the key which was used
+// less than VCDClientIdleTimeout ago, trusts the same CAs and was not rejected by VCD. The idle sessions of the other
+// keys are dropped.
+func (c *vcdClientCache) get(key string, trustBundlePool *x509.CertPool) *vcdsdk.Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for cachedKey, cached := range c.clients {
+		if time.Since(cached.lastUsed) >= VCDClientIdleTimeout || cached.unauthorized.Load() {
+			delete(c.clients, cachedKey)
+		}
+	}
+	cached, ok := c.clients[key]
+	if !ok {
+		return nil
+	}
+	if (cached.trustBundlePool == nil) != (trustBundlePool == nil) ||
+		(trustBundlePool != nil && !trustBundlePool.Equal(cached.trustBundlePool)) {
+		return nil
+	}
+	cached.lastUsed = time.Now()
+	return newSessionClient(cached)
+}
+
+// put caches the session of the client, which must have been set up with the trust bundle pool, and returns a client
+// of the session to be used by the reconciliation which logged in. The requests of the session are reported in the VCD
+// API metrics from then on.
+func (c *vcdClientCache) put(key string, trustBundlePool *x509.CertPool, vcdClient *vcdsdk.Client) *vcdsdk.Client {
+	cached := &cachedVCDClient{
+		client:          vcdClient,
+		trustBundlePool: trustBundlePool,
+		lastUsed:        time.Now(),
+	}
+	httpClient := &vcdClient.VCDClient.Client.Http
+	baseTransport := httpClient.Transport
+	if baseTransport == nil {
+		baseTransport = http.DefaultTransport
+	}
+	httpClient.Transport = &unauthorizedSessionTransport{
+		base:   newVCDAPIMetricsTransport(baseTransport),
+		cached: cached,
+	}
+
+	c.lock.Lock()
+	c.clients[key] = cached
+	c.lock.Unlock()
+	return newSessionClient(cached)
+}
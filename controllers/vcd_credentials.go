@@ -0,0 +1,48 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"time"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+// CredentialsExpiredRequeueInterval is the interval at which clusters with rejected credentials are retried. A longer
+// interval is used to avoid locking out the user by repeatedly logging in with the same stale credentials.
+const CredentialsExpiredRequeueInterval = 5 * time.Minute
+
+// markCredentialsExpired sets the CredentialsValid condition of the VCDCluster to false and records an event. Only the
+// reconciled VCDCluster is marked: the other VCDClusters logging into the same site as the same user find out on their
+// own reconciliation, through the rejected refresh token of vcdTokens or the failed login of their cached vcdClients.
+func (r *VCDClusterReconciler) markCredentialsExpired(vcdCluster *infrav1beta3.VCDCluster,
+	credentialsErr *CredentialsExpiredError) {
+	if !conditions.IsFalse(vcdCluster, CredentialsValidCondition) {
+		r.recordEvent(vcdCluster, corev1.EventTypeWarning, CredentialsRejectedReason, credentialsErr.Error())
+	}
+	conditions.MarkFalse(vcdCluster, CredentialsValidCondition, CredentialsRejectedReason,
+		clusterv1.ConditionSeverityError, credentialsErr.Error())
+}
+
+// markCredentialsValid sets the CredentialsValid condition once the credentials of the VCDCluster are accepted.
+func (r *VCDClusterReconciler) markCredentialsValid(vcdCluster *infrav1beta3.VCDCluster) {
+	if conditions.IsFalse(vcdCluster, CredentialsValidCondition) {
+		r.recordEvent(vcdCluster, corev1.EventTypeNormal, CredentialsAcceptedReason,
+			"credentials of the cluster were accepted by VCD")
+	}
+	conditions.MarkTrue(vcdCluster, CredentialsValidCondition)
+}
+
+func (r *VCDClusterReconciler) recordEvent(vcdCluster *infrav1beta3.VCDCluster, eventType string, reason string,
+	message string) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Event(vcdCluster, eventType, reason, message)
+}
@@ -0,0 +1,209 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"sigs.k8s.io/yaml"
+)
+
+// VCDSiteProxy is the outbound proxy through which the controllers reach a VCD site.
+type VCDSiteProxy struct {
+	// Site is the URL of the VCD site, as in the VCDClusters, e.g. https://vcd.example.com.
+	Site string `json:"site"`
+	// URL is the URL of the HTTP proxy, e.g. http://proxy.example.com:3128.
+	URL string `json:"url"`
+	// Username and Password authenticate the controllers to the proxy with basic auth, if set.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// VCDProxyConfig is the content of the file of the --vcd-proxy-config flag.
+type VCDProxyConfig struct {
+	Proxies []VCDSiteProxy `json:"proxies"`
+}
+
+// vcdSiteProxies are the proxies of the VCD sites, keyed by the address of the site. They are loaded before the
+// controllers start and never modified afterwards.
+var vcdSiteProxies = make(map[string]*url.URL)
+
+// getSiteAddress returns the host and port of the VCD site, the port defaulting to 443.
+func getSiteAddress(site string) (string, error) {
+	siteURL, err := url.Parse(site)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse site [%s]: [%v]", site, err)
+	}
+	if siteURL.Hostname() == "" {
+		return "", fmt.Errorf("site [%s] has no host", site)
+	}
+	port := siteURL.Port()
+	if port == "" {
+		port = "443"
+	}
+	return net.JoinHostPort(strings.ToLower(siteURL.Hostname()), port), nil
+}
+
+// LoadVCDSiteProxies loads the proxies of the VCD sites from the configuration file. The file is expected to be
+// mounted from a Secret, as it carries the credentials of the proxies.
+func LoadVCDSiteProxies(configFile string) error {
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("unable to read VCD proxy configuration [%s]: [%v]", configFile, err)
+	}
+	proxyConfig := &VCDProxyConfig{}
+	if err = yaml.UnmarshalStrict(content, proxyConfig); err != nil {
+		return fmt.Errorf("unable to parse VCD proxy configuration [%s]: [%v]", configFile, err)
+	}
+	for _, siteProxy := range proxyConfig.Proxies {
+		siteAddress, err := getSiteAddress(siteProxy.Site)
+		if err != nil {
+			return fmt.Errorf("invalid site of VCD proxy configuration [%s]: [%v]", configFile, err)
+		}
+		proxyURL, err := url.Parse(siteProxy.URL)
+		if err != nil {
+			return fmt.Errorf("unable to parse proxy [%s] of site [%s]: [%v]", siteProxy.URL, siteProxy.Site, err)
+		}
+		if (proxyURL.Scheme != "http" && proxyURL.Scheme != "https") || proxyURL.Hostname() == "" {
+			return fmt.Errorf("proxy [%s] of site [%s] is not an http or https URL", siteProxy.URL, siteProxy.Site)
+		}
+		if siteProxy.Username != "" {
+			proxyURL.User = url.UserPassword(siteProxy.Username, siteProxy.Password)
+		}
+		vcdSiteProxies[siteAddress] = proxyURL
+	}
+	return nil
+}
+
+// getVCDSiteProxy returns the proxy of the VCD site, or nil if the site is reached directly.
+func getVCDSiteProxy(site string) *url.URL {
+	siteAddress, err := getSiteAddress(site)
+	if err != nil {
+		return nil
+	}
+	return vcdSiteProxies[siteAddress]
+}
+
+// getVCDSiteProxyFunc returns the proxy function of the HTTP transports of the VCD site, or nil if the site is reached
+// directly. The proxy authorization is sent from the credentials of the proxy URL.
+func getVCDSiteProxyFunc(site string) func(*http.Request) (*url.URL, error) {
+	proxyURL := getVCDSiteProxy(site)
+	if proxyURL == nil {
+		return nil
+	}
+	return http.ProxyURL(proxyURL)
+}
+
+// setVCDClientTransport makes the GoVCD client verify the site with the TLS configuration and reach it through its
+// proxy, if it has one. It must be called before logging in.
+func setVCDClientTransport(govcdClient *govcd.VCDClient, site string, tlsConfig *tls.Config) {
+	transport, ok := govcdClient.Client.Http.Transport.(*http.Transport)
+	if !ok {
+		return
+	}
+	transport.TLSClientConfig = tlsConfig.Clone()
+	if proxyFunc := getVCDSiteProxyFunc(site); proxyFunc != nil {
+		transport.Proxy = proxyFunc
+	}
+}
+
+// dialSiteThroughProxy opens a tunnel to the address of the VCD site through the HTTP proxy.
+func dialSiteThroughProxy(proxyURL *url.URL, address string) (net.Conn, error) {
+	proxyAddress := proxyURL.Host
+	if proxyURL.Port() == "" && proxyURL.Scheme == "https" {
+		proxyAddress = net.JoinHostPort(proxyURL.Hostname(), "443")
+	} else if proxyURL.Port() == "" {
+		proxyAddress = net.JoinHostPort(proxyURL.Hostname(), "80")
+	}
+	dialer := &net.Dialer{Timeout: SiteCertificateDialTimeout}
+	var conn net.Conn
+	var err error
+	if proxyURL.Scheme == "https" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", proxyAddress, &tls.Config{ServerName: proxyURL.Hostname()})
+	} else {
+		conn, err = dialer.Dial("tcp", proxyAddress)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to proxy [%s]: [%v]", proxyURL.Redacted(), err)
+	}
+	if err = conn.SetDeadline(time.Now().Add(SiteCertificateDialTimeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	connectReq := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		connectReq.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err = connectReq.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unable to send CONNECT [%s] to proxy [%s]: [%v]", address, proxyURL.Redacted(), err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), connectReq)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unable to read the response of proxy [%s] to CONNECT [%s]: [%v]",
+			proxyURL.Redacted(), address, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy [%s] refused CONNECT [%s]: [%s]", proxyURL.Redacted(), address, resp.Status)
+	}
+	if err = conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// dialSiteTLS opens a TLS connection to the VCD site, through its proxy if it has one.
+func dialSiteTLS(site string, tlsConfig *tls.Config) (*tls.Conn, error) {
+	address, err := getSiteAddress(site)
+	if err != nil {
+		return nil, err
+	}
+	proxyURL := getVCDSiteProxy(site)
+	if proxyURL == nil {
+		return tls.DialWithDialer(&net.Dialer{Timeout: SiteCertificateDialTimeout}, "tcp", address, tlsConfig)
+	}
+
+	conn, err := dialSiteThroughProxy(proxyURL, address)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err = conn.SetDeadline(time.Now().Add(SiteCertificateDialTimeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err = tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err = conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
@@ -0,0 +1,132 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// VCDReferencesVerificationInterval is how long the VCD references of a VCDMachineTemplate found to exist are
+	// trusted before they are verified again, so that the catalogs and policies are not looked up at every
+	// reconciliation.
+	VCDReferencesVerificationInterval = 10 * time.Minute
+
+	// VCDReferenceNotFoundRequeueInterval is the interval at which VCDMachines referencing a VCD entity which does not
+	// exist are retried, e.g. once the catalog is shared or the template uploaded.
+	VCDReferenceNotFoundRequeueInterval = 2 * time.Minute
+)
+
+var (
+	// verifiedMachineTemplates records until when the VCD references of a generation of a VCDMachineTemplate are
+	// trusted, by getVerifiedMachineTemplateKey.
+	verifiedMachineTemplates     = make(map[string]time.Time)
+	verifiedMachineTemplatesLock sync.Mutex
+)
+
+// getVerifiedMachineTemplateKey returns the key of the verification of the VCD references of the VCDMachineTemplate
+// for the VCDCluster. The generation of the VCDCluster is part of the key as the policies of the machines default to
+// the ones of the cluster.
+func getVerifiedMachineTemplateKey(vcdMachineTemplate *infrav1beta3.VCDMachineTemplate,
+	vcdCluster *infrav1beta3.VCDCluster) string {
+
+	return fmt.Sprintf("%s/%d/%s/%d", vcdMachineTemplate.UID, vcdMachineTemplate.Generation, vcdCluster.UID,
+		vcdCluster.Generation)
+}
+
+// isMachineTemplateVerified checks if the VCD references of the VCDMachineTemplate were verified recently, and drops
+// the verifications which expired.
+func isMachineTemplateVerified(key string) bool {
+	verifiedMachineTemplatesLock.Lock()
+	defer verifiedMachineTemplatesLock.Unlock()
+
+	now := time.Now()
+	for verifiedKey, verifiedUntil := range verifiedMachineTemplates {
+		if now.After(verifiedUntil) {
+			delete(verifiedMachineTemplates, verifiedKey)
+		}
+	}
+	_, ok := verifiedMachineTemplates[key]
+	return ok
+}
+
+// recordMachineTemplateVerified records that the VCD references of the VCDMachineTemplate exist.
+func recordMachineTemplateVerified(key string) {
+	verifiedMachineTemplatesLock.Lock()
+	defer verifiedMachineTemplatesLock.Unlock()
+
+	verifiedMachineTemplates[key] = time.Now().Add(VCDReferencesVerificationInterval)
+}
+
+// reconcileMachineTemplateVCDReferences checks that the catalogs, templates, compute policies and storage profiles
+// referenced by the VCDMachineTemplates of the cluster exist in VCD, and reports the missing ones on the VCDCluster in
+// the VCDReferencesVerified condition, so that they are fixed before the machines are stuck on them. The
+// infrastructure of the cluster is reconciled regardless.
+func (r *VCDClusterReconciler) reconcileMachineTemplateVCDReferences(ctx context.Context, vcdClient *vcdsdk.Client,
+	vcdCluster *infrav1beta3.VCDCluster, machineTemplates []ownedMachineTemplate) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	var vdcManager *vcdsdk.VdcManager
+	var failures []string
+	for _, machineTemplate := range machineTemplates {
+		key := getVerifiedMachineTemplateKey(machineTemplate.template, vcdCluster)
+		if isMachineTemplateVerified(key) {
+			continue
+		}
+		// a template being imported from its source is verified once imported
+		spec := machineTemplate.template.Spec.Template.Spec
+		if pending, _ := isTemplateImportPending(vcdCluster, spec.Catalog, spec.Template); pending && spec.CatalogOrg == "" {
+			continue
+		}
+		if vdcManager == nil {
+			var err error
+			vdcManager, err = vcdsdk.NewVDCManager(vcdClient, vcdClient.ClusterOrgName, getOvdcName(vcdCluster))
+			if err != nil {
+				log.Error(err, "failed to create vdc manager to verify the VCD references of the VCDMachineTemplates")
+				return
+			}
+		}
+		_, err := resolveMachineSpecReferences(vcdClient, vdcManager, spec, vcdCluster)
+		var notFoundErr *VCDReferenceNotFoundError
+		if errors.As(err, &notFoundErr) {
+			failures = append(failures, fmt.Sprintf("%s: VCDMachineTemplate [%s]: %v", machineTemplate.owner,
+				machineTemplate.template.Name, err))
+			continue
+		}
+		if err != nil {
+			log.Error(err, "failed to verify the VCD references of the VCDMachineTemplate",
+				"template", machineTemplate.template.Name)
+			return
+		}
+		recordMachineTemplateVerified(key)
+	}
+
+	if len(failures) == 0 {
+		conditions.MarkTrue(vcdCluster, VCDReferencesVerifiedCondition)
+		return
+	}
+	message := strings.Join(failures, "; ")
+	if !conditions.IsFalse(vcdCluster, VCDReferencesVerifiedCondition) ||
+		conditions.GetMessage(vcdCluster, VCDReferencesVerifiedCondition) != message {
+		r.recordEvent(vcdCluster, corev1.EventTypeWarning, VCDReferenceNotFoundReason, message)
+	}
+	log.Info("VCD references of the VCDMachineTemplates of the cluster cannot be found", "failures", message)
+	conditions.MarkFalse(vcdCluster, VCDReferencesVerifiedCondition, VCDReferenceNotFoundReason,
+		clusterv1.ConditionSeverityError, message)
+}
@@ -0,0 +1,362 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+// Types of the VCD entities referenced by name or URN in the spec of a VCDCluster or VCDMachine
+const (
+	ResourceTypeOrg             = "org"
+	ResourceTypeOvdcNetwork     = "ovdcNetwork"
+	ResourceTypeCatalog         = "catalog"
+	ResourceTypeTemplate        = "template"
+	ResourceTypeSizingPolicy    = "sizingPolicy"
+	ResourceTypePlacementPolicy = "placementPolicy"
+	ResourceTypeStorageProfile  = "storageProfile"
+)
+
+const (
+	VCDUrnPrefix          = "urn:vcloud:"
+	VAppTemplateUrnPrefix = "urn:vcloud:vapptemplate:"
+)
+
+// isVCDUrn checks if a reference to a VCD entity is a URN rather than a name.
+func isVCDUrn(reference string) bool {
+	return strings.HasPrefix(reference, VCDUrnPrefix)
+}
+
+// getResolvedName returns the name of the entity of the type referenced by reference, which is looked up in the
+// resolved references when reference is a URN. The URN is returned as is until it is resolved.
+func getResolvedName(resolvedReferences infrav1beta3.VCDResources, resourceType string, reference string) string {
+	if !isVCDUrn(reference) {
+		return reference
+	}
+	for _, resolvedReference := range resolvedReferences {
+		if resolvedReference.Type == resourceType && resolvedReference.ID == reference {
+			return resolvedReference.Name
+		}
+	}
+	return reference
+}
+
+// isReferenceResolved checks if the entity of the type referenced by name is recorded in the resolved references.
+func isReferenceResolved(resolvedReferences infrav1beta3.VCDResources, resourceType string, name string) bool {
+	for _, resolvedReference := range resolvedReferences {
+		if resolvedReference.Type == resourceType && resolvedReference.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// setResolvedReference records the name and URN of the entity of the type, replacing the entity recorded earlier.
+func setResolvedReference(resolvedReferences *infrav1beta3.VCDResources, resourceType string, id string, name string) {
+	for i := range *resolvedReferences {
+		if (*resolvedReferences)[i].Type == resourceType {
+			(*resolvedReferences)[i].ID = id
+			(*resolvedReferences)[i].Name = name
+			return
+		}
+	}
+	*resolvedReferences = append(*resolvedReferences, infrav1beta3.VCDResource{
+		Type: resourceType,
+		ID:   id,
+		Name: name,
+	})
+}
+
+// getOrgName returns the name of the org of the VCDCluster.
+func getOrgName(vcdCluster *infrav1beta3.VCDCluster) string {
+	return getResolvedName(vcdCluster.Status.ResolvedReferences, ResourceTypeOrg, vcdCluster.Spec.Org)
+}
+
+// getOvdcName returns the name of the OVDC of the VCDCluster.
+func getOvdcName(vcdCluster *infrav1beta3.VCDCluster) string {
+	return getResolvedName(vcdCluster.Status.ResolvedReferences, ResourceTypeOvdc, vcdCluster.Spec.Ovdc)
+}
+
+// getOvdcNetworkName returns the name of the OVDC network of the VCDCluster.
+func getOvdcNetworkName(vcdCluster *infrav1beta3.VCDCluster) string {
+	return getResolvedName(vcdCluster.Status.ResolvedReferences, ResourceTypeOvdcNetwork, vcdCluster.Spec.OvdcNetwork)
+}
+
+// getUserOrgForOrgUrn returns the org to authenticate the user in when the org of the VCDCluster is referenced by a
+// URN which is not resolved yet. The URN can only be resolved once authenticated, so the username must name the org of
+// the user, i.e. org/user.
+func getUserOrgForOrgUrn(vcdCluster *infrav1beta3.VCDCluster, username string) (string, error) {
+	parts := strings.Split(username, "/")
+	if len(parts) != 2 || parts[0] == "" {
+		return "", fmt.Errorf("org [%s] of Cluster [%s] is referenced by URN, which requires the username of the credentials to name the org of the user, i.e. org/user",
+			vcdCluster.Spec.Org, vcdCluster.Name)
+	}
+	return parts[0], nil
+}
+
+// resolveOrgReference records the name and URN of the org of the VCDCluster and points the VCD client at the org.
+// An org referenced by URN is looked up at every reconciliation to follow renames.
+func resolveOrgReference(vcdClient *vcdsdk.Client, vcdCluster *infrav1beta3.VCDCluster) error {
+	orgReference := vcdCluster.Spec.Org
+	if !isVCDUrn(orgReference) && isReferenceResolved(vcdCluster.Status.ResolvedReferences, ResourceTypeOrg,
+		orgReference) {
+		return nil
+	}
+	org, err := vcdClient.VCDClient.GetOrgByNameOrId(orgReference)
+	if err != nil {
+		return fmt.Errorf("failed to get org [%s]: [%v]", orgReference, err)
+	}
+	if org == nil || org.Org == nil {
+		return fmt.Errorf("found nil org when getting org [%s]", orgReference)
+	}
+	vcdClient.ClusterOrgName = org.Org.Name
+	setResolvedReference(&vcdCluster.Status.ResolvedReferences, ResourceTypeOrg, org.Org.ID, org.Org.Name)
+	return nil
+}
+
+// resolveOvdcNetworkReference records the name and URN of the OVDC network of the VCDCluster. An OVDC network
+// referenced by URN is looked up at every reconciliation to follow renames.
+func resolveOvdcNetworkReference(vcdClient *vcdsdk.Client, vcdCluster *infrav1beta3.VCDCluster) error {
+	networkReference := vcdCluster.Spec.OvdcNetwork
+	if !isVCDUrn(networkReference) && isReferenceResolved(vcdCluster.Status.ResolvedReferences,
+		ResourceTypeOvdcNetwork, networkReference) {
+		return nil
+	}
+	if vcdClient.VDC == nil {
+		return fmt.Errorf("no Vdc found with name [%s] to look up OVDC network [%s]", vcdClient.ClusterOVDCName,
+			networkReference)
+	}
+	network, err := vcdClient.VDC.GetOrgVdcNetworkByNameOrId(networkReference, true)
+	if err != nil {
+		return fmt.Errorf("failed to get OVDC network [%s] in OVDC [%s]: [%v]", networkReference,
+			vcdClient.ClusterOVDCName, err)
+	}
+	setResolvedReference(&vcdCluster.Status.ResolvedReferences, ResourceTypeOvdcNetwork,
+		network.OrgVDCNetwork.ID, network.OrgVDCNetwork.Name)
+	return nil
+}
+
+// getMachineCatalogName returns the name of the catalog of the template of the VCDMachine.
+func getMachineCatalogName(vcdMachine *infrav1beta3.VCDMachine) string {
+	return getResolvedName(vcdMachine.Status.ResolvedReferences, ResourceTypeCatalog, vcdMachine.Spec.Catalog)
+}
+
+// getMachineTemplateName returns the name of the template of the VCDMachine.
+func getMachineTemplateName(vcdMachine *infrav1beta3.VCDMachine) string {
+	return getResolvedName(vcdMachine.Status.ResolvedReferences, ResourceTypeTemplate, vcdMachine.Spec.Template)
+}
+
+// getCatalogOrgName returns the name of the org owning the catalogs of catalogOrg, i.e. the org of the VCD client when
+// it is not set.
+func getCatalogOrgName(vcdClient *vcdsdk.Client, catalogOrg string) string {
+	if catalogOrg == "" {
+		return vcdClient.ClusterOrgName
+	}
+	return catalogOrg
+}
+
+// getCatalog returns the catalog referenced by name or URN, looked up in the org of the VCD client, or among the
+// catalogs of catalogOrg published or shared to the org of the VCD client when catalogOrg is another org.
+func getCatalog(vcdClient *vcdsdk.Client, catalogOrg string, catalogReference string) (*govcd.Catalog, error) {
+	if catalogOrg == "" || catalogOrg == vcdClient.ClusterOrgName {
+		org, err := getOrgByName(vcdClient, vcdClient.ClusterOrgName)
+		if err != nil {
+			return nil, err
+		}
+		return org.GetCatalogByNameOrId(catalogReference, true)
+	}
+	client := &vcdClient.VCDClient.Client
+	if isVCDUrn(catalogReference) {
+		return client.GetCatalogById(catalogReference)
+	}
+	catalog, err := client.GetCatalogByName(catalogOrg, catalogReference)
+	if err != nil && strings.HasPrefix(err.Error(), "no catalog") {
+		// the catalogs of the other orgs the user can read are listed, so a missing catalog is not found
+		return nil, fmt.Errorf("%v: [%v]", govcd.ErrorEntityNotFound, err)
+	}
+	return catalog, err
+}
+
+// getResolvedMachinePolicies returns the names of the sizing, placement and storage policies of the VM of the
+// VCDMachine, inherited from the DefaultMachinePolicies of the VCDCluster when they are not set in the VCDMachineSpec.
+func getResolvedMachinePolicies(vcdMachine *infrav1beta3.VCDMachine,
+	vcdCluster *infrav1beta3.VCDCluster) infrav1beta3.MachinePolicies {
+
+	policies := getMachinePolicies(vcdMachine.Spec, vcdCluster)
+	resolvedReferences := vcdMachine.Status.ResolvedReferences
+	return infrav1beta3.MachinePolicies{
+		SizingPolicy:    getResolvedName(resolvedReferences, ResourceTypeSizingPolicy, policies.SizingPolicy),
+		PlacementPolicy: getResolvedName(resolvedReferences, ResourceTypePlacementPolicy, policies.PlacementPolicy),
+		StorageProfile:  getResolvedName(resolvedReferences, ResourceTypeStorageProfile, policies.StorageProfile),
+	}
+}
+
+// resolveMachineReferences records the name and URN of the catalog, template, policies and storage profile the VM of
+// the VCDMachine is created with. It is called before the VM is created; the placement policy is only resolved when it
+// is not replaced by the one of a VM group or vGPU profile.
+func resolveMachineReferences(vcdClient *vcdsdk.Client, vdcManager *vcdsdk.VdcManager,
+	vcdMachine *infrav1beta3.VCDMachine, vcdCluster *infrav1beta3.VCDCluster) error {
+
+	resolvedReferences, err := resolveMachineSpecReferences(vcdClient, vdcManager, vcdMachine.Spec, vcdCluster)
+	if err != nil {
+		return err
+	}
+	vcdMachine.Status.ResolvedReferences = resolvedReferences
+	return nil
+}
+
+// resolveMachineSpecReferences returns the name and URN of the catalog, template, policies and storage profile
+// referenced by the VCDMachineSpec. A VCDReferenceNotFoundError is returned when one of them does not exist in VCD or
+// is not available to the org or OVDC of the cluster.
+func resolveMachineSpecReferences(vcdClient *vcdsdk.Client, vdcManager *vcdsdk.VdcManager,
+	spec infrav1beta3.VCDMachineSpec, vcdCluster *infrav1beta3.VCDCluster) (infrav1beta3.VCDResources, error) {
+
+	resolvedReferences := make(infrav1beta3.VCDResources, 0)
+	if spec.Catalog != "" {
+		catalogOrg := getCatalogOrgName(vcdClient, spec.CatalogOrg)
+		catalog, err := getCatalog(vcdClient, spec.CatalogOrg, spec.Catalog)
+		if govcd.ContainsNotFound(err) {
+			return nil, NewVCDReferenceNotFoundError(fmt.Sprintf(
+				"catalog [%s] not found in org [%s]; it must exist and be published or shared to org [%s]",
+				spec.Catalog, catalogOrg, vcdClient.ClusterOrgName))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get catalog [%s] in org [%s]: [%v]", spec.Catalog, catalogOrg, err)
+		}
+		setResolvedReference(&resolvedReferences, ResourceTypeCatalog, catalog.Catalog.ID, catalog.Catalog.Name)
+
+		if spec.Template != "" {
+			templateID, templateName, err := resolveTemplateReference(catalog, spec.Template)
+			if err != nil {
+				return nil, err
+			}
+			setResolvedReference(&resolvedReferences, ResourceTypeTemplate, templateID, templateName)
+		}
+	}
+
+	policies := getMachinePolicies(spec, vcdCluster)
+	placementPolicy := policies.PlacementPolicy
+	if spec.VmGroup != "" || spec.GPU != nil {
+		placementPolicy = ""
+	}
+	if policies.SizingPolicy != "" || placementPolicy != "" {
+		computePolicies, err := getAssignedVmComputePolicies(vdcManager)
+		if err != nil {
+			return nil, err
+		}
+		for _, policyReference := range []struct {
+			resourceType string
+			reference    string
+		}{
+			{resourceType: ResourceTypeSizingPolicy, reference: policies.SizingPolicy},
+			{resourceType: ResourceTypePlacementPolicy, reference: placementPolicy},
+		} {
+			if policyReference.reference == "" {
+				continue
+			}
+			policy := findComputePolicy(computePolicies, policyReference.reference)
+			if policy == nil {
+				return nil, NewVCDReferenceNotFoundError(fmt.Sprintf("%s [%s] is not assigned to OVDC [%s]",
+					policyReference.resourceType, policyReference.reference, vdcManager.VdcName))
+			}
+			setResolvedReference(&resolvedReferences, policyReference.resourceType, policy.ID, policy.Name)
+		}
+	}
+
+	if policies.StorageProfile != "" {
+		if vdcManager.Vdc == nil || vdcManager.Vdc.Vdc == nil {
+			return nil, fmt.Errorf("no Vdc found with name [%s] to look up storage profile [%s]", vdcManager.VdcName,
+				policies.StorageProfile)
+		}
+		storageProfileRef, err := findStorageProfileReference(vdcManager.Vdc, policies.StorageProfile)
+		if err != nil {
+			return nil, err
+		}
+		setResolvedReference(&resolvedReferences, ResourceTypeStorageProfile, storageProfileRef.ID,
+			storageProfileRef.Name)
+	}
+
+	return resolvedReferences, nil
+}
+
+// resolveTemplateReference returns the URN and name of the template of the catalog referenced by name, catalog item
+// URN or vApp template URN.
+func resolveTemplateReference(catalog *govcd.Catalog, templateReference string) (string, string, error) {
+	if strings.HasPrefix(templateReference, VAppTemplateUrnPrefix) {
+		vAppTemplate, err := catalog.GetVAppTemplateById(templateReference)
+		if govcd.ContainsNotFound(err) {
+			return "", "", NewVCDReferenceNotFoundError(fmt.Sprintf("vApp template [%s] not found in catalog [%s]",
+				templateReference, catalog.Catalog.Name))
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get vApp template [%s] in catalog [%s]: [%v]", templateReference,
+				catalog.Catalog.Name, err)
+		}
+		return vAppTemplate.VAppTemplate.ID, vAppTemplate.VAppTemplate.Name, nil
+	}
+	catalogItem, err := catalog.GetCatalogItemByNameOrId(templateReference, true)
+	if govcd.ContainsNotFound(err) {
+		return "", "", NewVCDReferenceNotFoundError(fmt.Sprintf("template [%s] not found in catalog [%s]",
+			templateReference, catalog.Catalog.Name))
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get template [%s] in catalog [%s]: [%v]", templateReference,
+			catalog.Catalog.Name, err)
+	}
+	return catalogItem.CatalogItem.ID, catalogItem.CatalogItem.Name, nil
+}
+
+// getAssignedVmComputePolicies returns the VM compute policies assigned to the OVDC.
+func getAssignedVmComputePolicies(vdcManager *vcdsdk.VdcManager) ([]*types.VdcComputePolicyV2, error) {
+	if vdcManager.Vdc == nil || vdcManager.Vdc.Vdc == nil {
+		return nil, fmt.Errorf("no Vdc found with name [%s] to look up its compute policies", vdcManager.VdcName)
+	}
+	policies, err := vdcManager.Client.VCDClient.GetAllAssignedVdcComputePoliciesV2(vdcManager.Vdc.Vdc.ID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get compute policies assigned to OVDC [%s]: [%v]", vdcManager.VdcName, err)
+	}
+	vmPolicies := make([]*types.VdcComputePolicyV2, 0)
+	for _, policy := range policies {
+		if policy != nil && policy.VdcComputePolicyV2 != nil && policy.VdcComputePolicyV2.PolicyType == VdcVmPolicyType {
+			vmPolicies = append(vmPolicies, policy.VdcComputePolicyV2)
+		}
+	}
+	return vmPolicies, nil
+}
+
+// findComputePolicy returns the compute policy referenced by name or URN, or nil if there is none.
+func findComputePolicy(policies []*types.VdcComputePolicyV2, policyReference string) *types.VdcComputePolicyV2 {
+	for _, policy := range policies {
+		if policy.ID == policyReference || policy.Name == policyReference {
+			return policy
+		}
+	}
+	return nil
+}
+
+// findStorageProfileReference returns the reference of the storage profile of the OVDC referenced by name or URN. A
+// VCDReferenceNotFoundError is returned when the OVDC has no such storage profile.
+func findStorageProfileReference(vdc *govcd.Vdc, storageProfile string) (types.Reference, error) {
+	if err := vdc.Refresh(); err != nil {
+		return types.Reference{}, fmt.Errorf("error refreshing vdc: [%v]", err)
+	}
+	if vdc.Vdc.VdcStorageProfiles != nil {
+		for _, storageProfileRef := range vdc.Vdc.VdcStorageProfiles.VdcStorageProfile {
+			if storageProfileRef != nil && (storageProfileRef.ID == storageProfile ||
+				storageProfileRef.Name == storageProfile) {
+				return *storageProfileRef, nil
+			}
+		}
+	}
+	return types.Reference{}, NewVCDReferenceNotFoundError(fmt.Sprintf("storage profile [%s] not found in OVDC [%s]",
+		storageProfile, vdc.Vdc.Name))
+}
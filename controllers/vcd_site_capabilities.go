@@ -0,0 +1,380 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// MinimumVCDAPIVersion is the oldest VCD API version (VCD 10.3) that CAPVCD can work with.
+	MinimumVCDAPIVersion = "36.0"
+	// IPSpacesMinimumAPIVersion is the VCD API version (VCD 10.4.1) which introduced IP Spaces.
+	IPSpacesMinimumAPIVersion = "37.1"
+
+	EnvPodNamespace         = "POD_NAMESPACE"
+	DefaultManagerNamespace = "capvcd-system"
+
+	SiteCapabilitiesConfigMapPrefix = "capvcd-site-"
+	SiteCapabilitiesLabel           = "infrastructure.cluster.x-k8s.io/vcd-site-capabilities"
+
+	siteCapabilityUnknown = "unknown"
+
+	// SiteCapabilitiesCacheTTL is the time after which the capabilities of a site are detected again, e.g. to notice
+	// that the site was upgraded or that a load balancer service engine group was assigned to an org.
+	SiteCapabilitiesCacheTTL = 10 * time.Minute
+)
+
+var (
+	siteCapabilitiesCache      = make(map[string]*vcdSiteCapabilities)
+	siteCapabilitiesDetections = make(map[string]*siteCapabilitiesDetection)
+	// siteCapabilitiesCacheLock guards siteCapabilitiesCache and siteCapabilitiesDetections, and is never held while
+	// querying a site.
+	siteCapabilitiesCacheLock sync.Mutex
+
+	invalidConfigMapNameChars = regexp.MustCompile(`[^a-z0-9.-]`)
+	invalidConfigMapKeyChars  = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+)
+
+// vcdSiteCapabilities records the version and the capabilities of a VCD site detected on first contact with the site.
+type vcdSiteCapabilities struct {
+	VCDVersion        string
+	APIVersion        string
+	IPSpacesSupported bool
+	RDESupported      bool
+	// ALBAvailable records for each org whether any load balancer service engine group is assigned to it.
+	ALBAvailable map[string]bool
+	// DetectedAt is the time at which the capabilities were detected.
+	DetectedAt time.Time
+	// Published records whether the capabilities were published in the ConfigMap of the site since they were last
+	// detected.
+	Published bool
+}
+
+// siteCapabilitiesDetection is a detection of the capabilities of a site, or of the availability of the load balancer
+// in an org of a site, in progress. The reconciliations needing the same detection wait for its result instead of
+// querying the site again.
+type siteCapabilitiesDetection struct {
+	done chan struct{}
+	err  error
+}
+
+// runSiteCapabilitiesDetection runs detect, unless a detection of the key is already in progress, in which case its
+// result is waited for.
+func runSiteCapabilitiesDetection(ctx context.Context, key string, detect func() error) error {
+	siteCapabilitiesCacheLock.Lock()
+	detection, inProgress := siteCapabilitiesDetections[key]
+	if !inProgress {
+		detection = &siteCapabilitiesDetection{done: make(chan struct{})}
+		siteCapabilitiesDetections[key] = detection
+	}
+	siteCapabilitiesCacheLock.Unlock()
+
+	if inProgress {
+		select {
+		case <-detection.done:
+			return detection.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	detection.err = detect()
+	siteCapabilitiesCacheLock.Lock()
+	delete(siteCapabilitiesDetections, key)
+	siteCapabilitiesCacheLock.Unlock()
+	close(detection.done)
+	return detection.err
+}
+
+// getManagerNamespace returns the namespace CAPVCD is running in.
+func getManagerNamespace() string {
+	if namespace := os.Getenv(EnvPodNamespace); namespace != "" {
+		return namespace
+	}
+	return DefaultManagerNamespace
+}
+
+// getSiteCapabilitiesConfigMapName returns the name of the ConfigMap publishing the capabilities of a VCD site.
+func getSiteCapabilitiesConfigMapName(site string) string {
+	host := site
+	if siteURL, err := url.Parse(site); err == nil && siteURL.Hostname() != "" {
+		host = siteURL.Hostname()
+	}
+	name := SiteCapabilitiesConfigMapPrefix +
+		strings.Trim(invalidConfigMapNameChars.ReplaceAllString(strings.ToLower(host), "-"), "-.")
+	if len(name) > 253 {
+		name = name[:253]
+	}
+	return name
+}
+
+// isOrgAssignedALB checks if any load balancer service engine group is assigned to the org of the client.
+func isOrgAssignedALB(ctx context.Context, vcdClient *vcdsdk.Client) (bool, error) {
+	org, err := vcdClient.VCDClient.GetOrgByName(vcdClient.ClusterOrgName)
+	if err != nil {
+		return false, fmt.Errorf("error getting org by name for org [%s]: [%v]", vcdClient.ClusterOrgName, err)
+	}
+	if org == nil || org.Org == nil {
+		return false, fmt.Errorf("obtained nil org when getting org by name [%s]", vcdClient.ClusterOrgName)
+	}
+	segAssignments, resp, err := vcdClient.APIClient.LoadBalancerServiceEngineGroupAssignmentsApi.GetServiceEngineGroupAssignments(
+		ctx, 1, 1, org.Org.ID, nil)
+	if err != nil {
+		return false, fmt.Errorf("unable to get service engine group assignments for org [%s]: resp: [%v]: [%v]",
+			vcdClient.ClusterOrgName, resp, err)
+	}
+	return len(segAssignments.Values) > 0, nil
+}
+
+// detectSiteCapabilities queries the version of the VCD site and the features available on it.
+func detectSiteCapabilities(ctx context.Context, vcdClient *vcdsdk.Client) (*vcdSiteCapabilities, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	govcdClient := &vcdClient.VCDClient.Client
+	apiVersion, err := govcdClient.MaxSupportedVersion()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the maximum API version supported by site [%s]: [%v]",
+			vcdClient.VCDAuthConfig.Host, err)
+	}
+
+	// the product version is only visible to users who can read the admin view of the site
+	vcdVersion, err := govcdClient.GetVcdShortVersion()
+	if err != nil {
+		log.V(3).Info("unable to get the VCD version of the site", "site", vcdClient.VCDAuthConfig.Host,
+			"error", err.Error())
+		vcdVersion = siteCapabilityUnknown
+	}
+
+	capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, "")
+	return &vcdSiteCapabilities{
+		VCDVersion:        vcdVersion,
+		APIVersion:        apiVersion,
+		IPSpacesSupported: govcdClient.APIVCDMaxVersionIs(">= " + IPSpacesMinimumAPIVersion),
+		RDESupported:      capvcdRdeManager.IsCapvcdEntityTypeRegistered(capisdk.CapvcdRDETypeVersion),
+		ALBAvailable:      make(map[string]bool),
+		DetectedAt:        time.Now(),
+	}, nil
+}
+
+// getSiteCapabilities returns a snapshot of the capabilities of the site of the client. The capabilities are detected
+// on first contact with a site (or org of the site) and cached for SiteCapabilitiesCacheTTL. The availability of the
+// load balancer in the org is only detected if detectALB is set, as the query fails in orgs without ALB entitlement.
+// The returned boolean indicates if the capabilities were freshly detected. The reconciliations of the same site share
+// a single detection, which does not hold the lock of the cache while querying the site.
+func getSiteCapabilities(ctx context.Context, vcdClient *vcdsdk.Client, detectALB bool) (*vcdSiteCapabilities, bool,
+	error) {
+	site := vcdClient.VCDAuthConfig.Host
+	org := vcdClient.ClusterOrgName
+
+	detected := false
+	siteCapabilitiesCacheLock.Lock()
+	siteCapabilities, ok := siteCapabilitiesCache[site]
+	siteCapabilitiesCacheLock.Unlock()
+	if !ok || time.Since(siteCapabilities.DetectedAt) > SiteCapabilitiesCacheTTL {
+		if err := runSiteCapabilitiesDetection(ctx, site, func() error {
+			detectedCapabilities, err := detectSiteCapabilities(ctx, vcdClient)
+			if err != nil {
+				return err
+			}
+			siteCapabilitiesCacheLock.Lock()
+			siteCapabilitiesCache[site] = detectedCapabilities
+			siteCapabilitiesCacheLock.Unlock()
+			return nil
+		}); err != nil {
+			return nil, false, err
+		}
+		siteCapabilitiesCacheLock.Lock()
+		siteCapabilities, ok = siteCapabilitiesCache[site]
+		siteCapabilitiesCacheLock.Unlock()
+		if !ok {
+			return nil, false, fmt.Errorf("capabilities of site [%s] were forgotten while being detected", site)
+		}
+		detected = true
+	}
+
+	siteCapabilitiesCacheLock.Lock()
+	_, albDetected := siteCapabilities.ALBAvailable[org]
+	siteCapabilitiesCacheLock.Unlock()
+	if !albDetected && detectALB {
+		if err := runSiteCapabilitiesDetection(ctx, site+"|alb|"+org, func() error {
+			albAvailable, err := isOrgAssignedALB(ctx, vcdClient)
+			if err != nil {
+				return err
+			}
+			siteCapabilitiesCacheLock.Lock()
+			siteCapabilities.ALBAvailable[org] = albAvailable
+			siteCapabilities.Published = false
+			siteCapabilitiesCacheLock.Unlock()
+			return nil
+		}); err != nil {
+			return nil, false, err
+		}
+		detected = true
+	}
+
+	siteCapabilitiesCacheLock.Lock()
+	defer siteCapabilitiesCacheLock.Unlock()
+	snapshot := *siteCapabilities
+	snapshot.ALBAvailable = make(map[string]bool, len(siteCapabilities.ALBAvailable))
+	for albOrg, albAvailable := range siteCapabilities.ALBAvailable {
+		snapshot.ALBAvailable[albOrg] = albAvailable
+	}
+	return &snapshot, detected, nil
+}
+
+// forgetSiteCapabilities removes the capabilities of the site from the cache, so that they are detected again.
+func forgetSiteCapabilities(site string) {
+	siteCapabilitiesCacheLock.Lock()
+	defer siteCapabilitiesCacheLock.Unlock()
+
+	delete(siteCapabilitiesCache, site)
+}
+
+// markSiteCapabilitiesPublished records that the capabilities of the site detected at detectedAt were published, unless
+// they have been detected again since.
+func markSiteCapabilitiesPublished(site string, detectedAt time.Time) {
+	siteCapabilitiesCacheLock.Lock()
+	defer siteCapabilitiesCacheLock.Unlock()
+
+	if siteCapabilities, ok := siteCapabilitiesCache[site]; ok && siteCapabilities.DetectedAt.Equal(detectedAt) {
+		siteCapabilities.Published = true
+	}
+}
+
+// publishSiteCapabilities records the capabilities of a site in a ConfigMap in the namespace of CAPVCD.
+func publishSiteCapabilities(ctx context.Context, cli client.Client, site string,
+	siteCapabilities *vcdSiteCapabilities) error {
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getSiteCapabilitiesConfigMapName(site),
+			Namespace: getManagerNamespace(),
+		},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, cli, configMap, func() error {
+		if configMap.Labels == nil {
+			configMap.Labels = make(map[string]string)
+		}
+		configMap.Labels[SiteCapabilitiesLabel] = "true"
+		configMap.Data = map[string]string{
+			"site":                       site,
+			"vcdVersion":                 siteCapabilities.VCDVersion,
+			"apiVersion":                 siteCapabilities.APIVersion,
+			"minimumApiVersion":          MinimumVCDAPIVersion,
+			"ipSpacesSupported":          strconv.FormatBool(siteCapabilities.IPSpacesSupported),
+			"capvcdEntityTypeRegistered": strconv.FormatBool(siteCapabilities.RDESupported),
+		}
+		for org, albAvailable := range siteCapabilities.ALBAvailable {
+			configMap.Data["albAvailable."+invalidConfigMapKeyChars.ReplaceAllString(org, "-")] =
+				strconv.FormatBool(albAvailable)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish capabilities of site [%s] in ConfigMap [%s/%s]: [%v]",
+			site, configMap.Namespace, configMap.Name, err)
+	}
+
+	return nil
+}
+
+// validateSiteCapabilities refuses VCDClusters that need features the site can't support.
+func validateSiteCapabilities(vcdCluster *infrav1beta3.VCDCluster, vcdClient *vcdsdk.Client,
+	siteCapabilities *vcdSiteCapabilities, userRights *vcdUserRights) error {
+
+	site := vcdClient.VCDAuthConfig.Host
+	if !vcdClient.VCDClient.Client.APIVCDMaxVersionIs(">= " + MinimumVCDAPIVersion) {
+		return fmt.Errorf("site [%s] supports API version up to [%s] (VCD version [%s]) but CAPVCD requires API version [%s] or later",
+			site, siteCapabilities.APIVersion, siteCapabilities.VCDVersion, MinimumVCDAPIVersion)
+	}
+
+	needsNewRDE := !skipRDECreation(vcdCluster, userRights) && vcdCluster.Status.InfraId == "" &&
+		vcdCluster.Spec.RDEId == ""
+	if needsNewRDE && !siteCapabilities.RDESupported {
+		return fmt.Errorf("site [%s] does not have the capvcdCluster entity type [%s] registered or the user lacks the capvcdCluster rights; "+
+			"register the entity type, set spec.rdeManagementDisabled of the VCDCluster or set [%s=true] to create "+
+			"clusters without RDEs",
+			site, capisdk.GetCAPVCDEntityTypeID(), EnvSkipRDE)
+	}
+
+	// the control plane endpoint is supplied by the user in the passthrough mode, and a DNAT rule in the DNAT mode
+	if isALBRequired(vcdCluster) && !siteCapabilities.ALBAvailable[vcdClient.ClusterOrgName] {
+		return fmt.Errorf("no load balancer service engine group is assigned to org [%s] of site [%s]; "+
+			"NSX-T Advanced Load Balancer is required for the control plane endpoint of cluster [%s]",
+			vcdClient.ClusterOrgName, site, vcdCluster.Name)
+	}
+
+	return nil
+}
+
+// reconcileSiteCapabilities detects the capabilities of the site of the VCDCluster on first contact, publishes them
+// and ensures that the VCDCluster only requests features the site supports. Cached capabilities which the VCDCluster
+// fails the validation against are detected again, so that e.g. an entity type registered since is noticed at once.
+func (r *VCDClusterReconciler) reconcileSiteCapabilities(ctx context.Context, vcdCluster *infrav1beta3.VCDCluster,
+	vcdClient *vcdsdk.Client, userRights *vcdUserRights) error {
+
+	site := vcdClient.VCDAuthConfig.Host
+	siteCapabilities, detected, err := r.getPublishedSiteCapabilities(ctx, vcdCluster, vcdClient)
+	if err != nil {
+		return err
+	}
+	err = validateSiteCapabilities(vcdCluster, vcdClient, siteCapabilities, userRights)
+	if err == nil || detected {
+		return err
+	}
+
+	forgetSiteCapabilities(site)
+	if siteCapabilities, _, err = r.getPublishedSiteCapabilities(ctx, vcdCluster, vcdClient); err != nil {
+		return err
+	}
+	return validateSiteCapabilities(vcdCluster, vcdClient, siteCapabilities, userRights)
+}
+
+// getPublishedSiteCapabilities returns the capabilities of the site of the VCDCluster like getSiteCapabilities, and
+// publishes them unless they were already. A failed publication is attempted again on the next reconciliation.
+func (r *VCDClusterReconciler) getPublishedSiteCapabilities(ctx context.Context, vcdCluster *infrav1beta3.VCDCluster,
+	vcdClient *vcdsdk.Client) (*vcdSiteCapabilities, bool, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	site := vcdClient.VCDAuthConfig.Host
+	siteCapabilities, detected, err := getSiteCapabilities(ctx, vcdClient, isALBRequired(vcdCluster))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to detect capabilities of site [%s]: [%v]", site, err)
+	}
+	if detected {
+		log.Info("detected capabilities of VCD site", "site", site,
+			"vcdVersion", siteCapabilities.VCDVersion, "apiVersion", siteCapabilities.APIVersion,
+			"ipSpacesSupported", siteCapabilities.IPSpacesSupported, "rdeSupported", siteCapabilities.RDESupported,
+			"albAvailable", siteCapabilities.ALBAvailable[vcdClient.ClusterOrgName])
+	}
+	if !siteCapabilities.Published {
+		if err = publishSiteCapabilities(ctx, r.Client, site, siteCapabilities); err != nil {
+			// the ConfigMap is informational; don't block the cluster on it
+			log.Error(err, "failed to publish capabilities of VCD site")
+		} else {
+			markSiteCapabilitiesPublished(site, siteCapabilities.DetectedAt)
+		}
+	}
+	return siteCapabilities, detected, nil
+}
@@ -0,0 +1,64 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunSiteCapabilitiesDetection(t *testing.T) {
+	detections := &atomic.Int32{}
+	release := make(chan struct{})
+	detect := func() error {
+		detections.Add(1)
+		<-release
+		return fmt.Errorf("site unreachable")
+	}
+
+	errs := make([]error, 5)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = runSiteCapabilitiesDetection(context.Background(), "https://vcd.example.com", detect)
+		}(i)
+	}
+	// the cache stays usable, e.g. by the other sites, while a site is queried
+	deadline := time.Now().Add(5 * time.Second)
+	for detections.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("site was not queried")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := runSiteCapabilitiesDetection(context.Background(), "https://other.example.com",
+		func() error { return nil }); err != nil {
+		t.Errorf("unexpected error detecting the capabilities of another site: [%v]", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if detections.Load() != 1 {
+		t.Errorf("site was queried [%d] times by concurrent reconciliations, want 1", detections.Load())
+	}
+	for i, err := range errs {
+		if err == nil || err.Error() != "site unreachable" {
+			t.Errorf("got error [%v] for reconciliation [%d], want the error of the detection", err, i)
+		}
+	}
+	siteCapabilitiesCacheLock.Lock()
+	defer siteCapabilitiesCacheLock.Unlock()
+	if len(siteCapabilitiesDetections) != 0 {
+		t.Errorf("detections [%d] were not dropped once completed", len(siteCapabilitiesDetections))
+	}
+}
@@ -7,16 +7,19 @@ package controllers
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	_ "embed"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"reflect"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	rdeType "github.com/vmware/cluster-api-provider-cloud-director/pkg/vcdtypes/rde_type_1_1_0"
+	rdeType "github.com/vmware/cluster-api-provider-cloud-director/pkg/vcdtypes/rde_type_1_3_0"
 
 	"github.com/antihax/optional"
 	"github.com/blang/semver"
@@ -30,19 +33,25 @@ import (
 	"github.com/vmware/cluster-api-provider-cloud-director/release"
 	"github.com/vmware/go-vcloud-director/v2/govcd"
 	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	kcpv1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/conditions"
-	kcfg "sigs.k8s.io/cluster-api/util/kubeconfig"
 	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/cluster-api/util/predicates"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 const (
@@ -51,19 +60,25 @@ const (
 	RDEStatusResolved             = "RESOLVED"
 	VCDLocationHeader             = "Location"
 	ResourceTypeOvdc              = "ovdc"
+	ResourceTypeAppPortProfile    = "appPortProfile"
 	ClusterApiStatusPhaseReady    = "Ready"
 	ClusterApiStatusPhaseNotReady = "Not Ready"
 	CapvcdInfraId                 = "CapvcdInfraId"
+	CapvcdTemplateHash            = "CapvcdTemplateHash"
+	CapvcdMachineRole             = "CapvcdMachineRole"
+	CapvcdMachineName             = "CapvcdMachineName"
 
-	NoRdePrefix     = `NO_RDE_`
+	MachineRoleControlPlane = "control-plane"
+	MachineRoleWorker       = "worker"
+
+	NoRdePrefix     = infrav1beta3.NoRDEInfraIDPrefix
 	VCDResourceVApp = "VApp"
 
 	TcpPort = 6443
 )
 
 var (
-	CAPVCDEntityTypeID = fmt.Sprintf("urn:vcloud:type:%s:%s:%s", capisdk.CAPVCDTypeVendor, capisdk.CAPVCDTypeNss, rdeType.CapvcdRDETypeVersion)
-	OneArmDefault      = vcdsdk.OneArm{
+	OneArmDefault = vcdsdk.OneArm{
 		StartIP: "192.168.8.2",
 		EndIP:   "192.168.8.100",
 	}
@@ -73,7 +88,17 @@ var (
 // VCDClusterReconciler reconciles a VCDCluster object
 type VCDClusterReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// Shards splits the clusters between the active replicas of the manager; all the clusters are reconciled if nil.
+	Shards *ClusterShardManager
+	// AuditInterval is the interval at which the infrastructure of each cluster is audited; no audit is made if zero.
+	AuditInterval time.Duration
+	// DisableRDEKubeConfig keeps the kubeconfigs of the clusters out of the private section of their RDEs.
+	DisableRDEKubeConfig bool
+	// RDEKubeConfigKeySecret is the Secret holding the key the kubeconfigs are encrypted with in the private section of
+	// the RDEs; they are stored in clear if nil.
+	RDEKubeConfigKeySecret *client.ObjectKey
 }
 
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vcdclusters,verbs=get;list;watch;create;update;patch;delete
@@ -82,6 +107,7 @@ type VCDClusterReconciler struct {
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters;clusters/status,verbs=get;list;watch
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines;machines/status,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=secrets;,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=events,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=clusterresourcesetbindings,verbs=get;list;watch
@@ -121,12 +147,29 @@ func (r *VCDClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 		log.Info("Continuing to delete cluster since DeletionTimestamp is set")
 	}
+	clusterName := vcdCluster.Name
+	if cluster != nil {
+		clusterName = cluster.Name
+	}
+	claimed, err := r.Shards.ClaimCluster(ctx, vcdCluster.Namespace, clusterName, cluster)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Error claiming Cluster [%s]", clusterName)
+	}
+	if !claimed {
+		log.V(4).Info("Skipping cluster owned by another replica")
+		return ctrl.Result{RequeueAfter: r.Shards.GetRequeueAfter()}, nil
+	}
 	patchHelper, err := patch.NewHelper(vcdCluster, r.Client)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	// the VCDCluster of a cluster being moved by clusterctl stays paused even if its Cluster is already gone
+	paused := annotations.HasPaused(vcdCluster) || (cluster != nil && annotations.IsPaused(cluster, vcdCluster))
 	defer func() {
-		if err := patchVCDCluster(ctx, patchHelper, vcdCluster); err != nil {
+		if !paused {
+			recordClusterPhaseTransitions(cluster, vcdCluster)
+		}
+		if err := patchVCDCluster(ctx, patchHelper, vcdCluster, paused, rerr); err != nil {
 			log.Error(err, "Failed to patch VCDCluster")
 			if rerr == nil {
 				rerr = err
@@ -135,25 +178,44 @@ func (r *VCDClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		log.V(3).Info("Cleanly patched VCD cluster.", "infra ID", vcdCluster.Status.InfraId)
 	}()
 
+	// Return early if the object or Cluster is paused.
+	if paused {
+		log.Info("Reconciliation is paused for this object")
+		return ctrl.Result{}, nil
+	}
+
 	if !controllerutil.ContainsFinalizer(vcdCluster, infrav1beta3.ClusterFinalizer) {
 		controllerutil.AddFinalizer(vcdCluster, infrav1beta3.ClusterFinalizer)
 		return ctrl.Result{}, nil
 	}
 
 	if clusterBeingDeleted {
+		if isDeletionProtected(cluster, vcdCluster) {
+			log.Info("Deletion of the cluster infrastructure is blocked by the deletion protection annotation",
+				"annotation", DeletionProtectedAnnotation)
+			r.recordEvent(vcdCluster, corev1.EventTypeWarning, DeletionBlockedReason, fmt.Sprintf(
+				"infrastructure of the cluster is protected from deletion; remove the annotation [%s] to delete it",
+				DeletionProtectedAnnotation))
+			return ctrl.Result{RequeueAfter: DeletionProtectedRequeueInterval}, nil
+		}
 		return r.reconcileDelete(ctx, vcdCluster)
 	}
 
 	return r.reconcileNormal(ctx, cluster, vcdCluster)
 }
 
-func patchVCDCluster(ctx context.Context, patchHelper *patch.Helper, vcdCluster *infrav1beta3.VCDCluster) error {
+// patchVCDCluster patches the VCDCluster after summarizing its conditions into the Ready condition. paused and
+// reconcileErr describe the outcome of the reconciliation and refine the Ready condition of a paused or degraded cluster.
+func patchVCDCluster(ctx context.Context, patchHelper *patch.Helper, vcdCluster *infrav1beta3.VCDCluster,
+	paused bool, reconcileErr error) error {
 	conditions.SetSummary(vcdCluster,
 		conditions.WithConditions(
 			LoadBalancerAvailableCondition,
+			RDEReadyCondition,
 		),
 		conditions.WithStepCounterIf(vcdCluster.ObjectMeta.DeletionTimestamp.IsZero()),
 	)
+	vcdCluster.Status.Ready = setReadiness(vcdCluster, vcdCluster.Status.Ready, paused, reconcileErr)
 
 	return patchHelper.Patch(
 		ctx,
@@ -161,6 +223,20 @@ func patchVCDCluster(ctx context.Context, patchHelper *patch.Helper, vcdCluster
 		patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{
 			clusterv1.ReadyCondition,
 			LoadBalancerAvailableCondition,
+			RDEReadyCondition,
+			SiteCapabilitiesVerifiedCondition,
+			CredentialsValidCondition,
+			OvdcEnabledCondition,
+			OwnershipClaimVerifiedCondition,
+			OptionalFeaturesAvailableCondition,
+			SiteCertificateTrustedCondition,
+			ControlPlaneSizedCondition,
+			VCDAuthHealthyCondition,
+			MachineTemplatesResolvedCondition,
+			InfrastructureAuditedCondition,
+			IPPoolAvailableCondition,
+			OvdcInServiceCondition,
+			VCDReferencesVerifiedCondition,
 		}},
 	)
 }
@@ -248,8 +324,20 @@ func validateDerivedRDEProperties(vcdCluster *infrav1beta3.VCDCluster, infraID s
 // Reminder: Although vcdcluster provides array for vcdResourceMap[ovdc], vcdcluster should use only one OVDC in CAPVCD 1.1
 func updateClientWithVDC(vcdCluster *infrav1beta3.VCDCluster, client *vcdsdk.Client) error {
 	log := ctrl.LoggerFrom(context.Background())
-	orgName := vcdCluster.Spec.Org
-	ovdcName := vcdCluster.Spec.Ovdc
+	orgName := getOrgName(vcdCluster)
+	ovdcName := getOvdcName(vcdCluster)
+	// an OVDC referenced by URN is looked up by its URN, which is not affected by renames
+	if isVCDUrn(vcdCluster.Spec.Ovdc) {
+		newOvdc, err := getOvdcByID(client, orgName, vcdCluster.Spec.Ovdc)
+		if err != nil {
+			return fmt.Errorf("failed to get the ovdc by the URN [%s]: [%v]", vcdCluster.Spec.Ovdc, err)
+		}
+		client.VDC = newOvdc
+		client.ClusterOVDCName = newOvdc.Vdc.Name
+		setResolvedReference(&vcdCluster.Status.ResolvedReferences, ResourceTypeOvdc, newOvdc.Vdc.ID,
+			newOvdc.Vdc.Name)
+		return nil
+	}
 	if vcdCluster.Status.VcdResourceMap.Ovdcs != nil && len(vcdCluster.Status.VcdResourceMap.Ovdcs) > 0 {
 		NameChanged, newOvdc, err := checkIfOvdcNameChange(vcdCluster, client)
 		if err != nil {
@@ -270,28 +358,186 @@ func updateClientWithVDC(vcdCluster *infrav1beta3.VCDCluster, client *vcdsdk.Cli
 	}
 	client.VDC = newOvdc
 	client.ClusterOVDCName = newOvdc.Vdc.Name
+	setResolvedReference(&vcdCluster.Status.ResolvedReferences, ResourceTypeOvdc, newOvdc.Vdc.ID, newOvdc.Vdc.Name)
 
 	return nil
 }
 
 func createVCDClientFromSecrets(ctx context.Context, client client.Client, vcdCluster *infrav1beta3.VCDCluster) (*vcdsdk.Client, error) {
-	userCreds, err := getUserCredentialsForCluster(ctx, client, vcdCluster.Spec.UserCredentialsContext)
+	definedCreds, err := getDefinedCredentialsForCluster(ctx, client, vcdCluster)
 	if err != nil {
-		return nil, fmt.Errorf("error getting client credentials to reconcile Cluster [%s] infrastructure: [%v]", vcdCluster.Name, err)
+		return nil, NewCredentialsUnavailableError(fmt.Sprintf("error getting the credentials of Cluster [%s]: [%v]",
+			vcdCluster.Name, err))
 	}
-	vcdClient, err := vcdsdk.NewVCDClientFromSecrets(vcdCluster.Spec.Site, vcdCluster.Spec.Org,
-		vcdCluster.Spec.Ovdc, vcdCluster.Spec.Org, userCreds.Username, userCreds.Password, userCreds.RefreshToken,
-		true, false)
+	userCreds, err := getUserCredentialsForCluster(ctx, client, definedCreds)
 	if err != nil {
-		return nil, fmt.Errorf("error creating VCD client from secrets to reconcile Cluster [%s] infrastructure: [%v]", vcdCluster.Name, err)
+		return nil, NewCredentialsUnavailableError(fmt.Sprintf(
+			"error getting client credentials to reconcile Cluster [%s] infrastructure: [%v]", vcdCluster.Name, err))
+	}
+	trustBundlePool, err := getTrustBundlePool(ctx, client, vcdCluster)
+	if err != nil {
+		return nil, err
+	}
+	orgName := getOrgName(vcdCluster)
+	userOrg := orgName
+	if isVCDUrn(orgName) {
+		// the URN of the org is resolved once authenticated in the org of the user
+		if userOrg, err = getUserOrgForOrgUrn(vcdCluster, userCreds.Username); err != nil {
+			return nil, err
+		}
+	}
+	var samlCreds *samlCredentials
+	if isFederatedAuth(userCreds) {
+		if samlCreds, err = getSamlCredentialsForCluster(ctx, client, definedCreds); err != nil {
+			return nil, NewCredentialsUnavailableError(fmt.Sprintf(
+				"error getting SAML credentials to reconcile Cluster [%s] infrastructure: [%v]", vcdCluster.Name, err))
+		}
+	}
+	// the session of the credentials is reused across reconciliations of the clusters and machines
+	cacheKey := getVCDClientCacheKey(vcdCluster, orgName, userOrg, userCreds, samlCreds)
+	vcdClient := vcdClients.get(cacheKey, trustBundlePool)
+	if vcdClient == nil {
+		// verify the certificate of the site before sending it the credentials of a new session
+		if trustBundlePool != nil {
+			if err = verifySiteTrustBundle(vcdCluster.Spec.Site, trustBundlePool); err != nil {
+				return nil, err
+			}
+		}
+		if vcdCluster.Spec.PinSiteCertificate {
+			if err = verifySiteCertificate(ctx, vcdCluster); err != nil {
+				return nil, err
+			}
+			// the certificate chain presented on first use is now pinned, and so part of the key of the session
+			cacheKey = getVCDClientCacheKey(vcdCluster, orgName, userOrg, userCreds, samlCreds)
+		}
+		vcdClient, err = newVCDClientFromCredentials(vcdCluster, orgName, userOrg, userCreds, samlCreds,
+			trustBundlePool)
+		vcdLogins.WithLabelValues(getOperationResult(err)).Inc()
+		if err != nil {
+			return nil, err
+		}
+		vcdClient = vcdClients.put(cacheKey, trustBundlePool, vcdClient)
+	}
+	if err = resolveOrgReference(vcdClient, vcdCluster); err != nil {
+		return nil, fmt.Errorf("error resolving the org of Cluster [%s]: [%v]", vcdCluster.Name, err)
 	}
 	err = updateClientWithVDC(vcdCluster, vcdClient)
 	if err != nil {
 		return nil, fmt.Errorf("error updating VCD client with VDC to reconcile Cluster [%s] infrastructure: [%v]", vcdCluster.Name, err)
 	}
+	// the OVDC network created by CAPVCD is resolved by the VCDCluster controller once it exists
+	if isManagedNetworkPending(vcdCluster) {
+		return vcdClient, nil
+	}
+	if err = resolveOvdcNetworkReference(vcdClient, vcdCluster); err != nil {
+		return nil, fmt.Errorf("error resolving the OVDC network of Cluster [%s]: [%v]", vcdCluster.Name, err)
+	}
+	return vcdClient, nil
+}
+
+// newVCDClientFromCredentials logs the user of the credentials into VCD and returns a VCD client for the session,
+// which trusts the CAs of the trust bundle pool, if any.
+func newVCDClientFromCredentials(vcdCluster *infrav1beta3.VCDCluster, orgName string, userOrg string,
+	userCreds infrav1beta3.UserCredentialsContext, samlCreds *samlCredentials,
+	trustBundlePool *x509.CertPool) (*vcdsdk.Client, error) {
+
+	// the rejected refresh tokens are not sent to VCD again until they are rotated or retried
+	usesRefreshToken := !isFederatedAuth(userCreds) && userCreds.RefreshToken != ""
+	if usesRefreshToken {
+		if err := vcdTokens.checkRefreshToken(vcdCluster.Spec.Site, userCreds.RefreshToken); err != nil {
+			return nil, err
+		}
+	}
+	// the certificate of the site is verified by every transport of the session before logging in
+	var pinnedFingerprints []string
+	if vcdCluster.Spec.PinSiteCertificate {
+		pinnedFingerprints = vcdCluster.Spec.SiteCertificateFingerprints
+	}
+	tlsConfig := newSiteTLSConfig(vcdCluster.Spec.Site, true, trustBundlePool, pinnedFingerprints)
+	var vcdClient *vcdsdk.Client
+	var err error
+	if isFederatedAuth(userCreds) {
+		vcdClient, err = newFederatedVCDClient(vcdCluster.Spec.Site, orgName, getOvdcName(vcdCluster), userOrg,
+			userCreds, samlCreds, true, tlsConfig)
+	} else {
+		vcdClient, err = newLocalVCDClient(vcdCluster.Spec.Site, orgName, getOvdcName(vcdCluster), userOrg,
+			userCreds, true, tlsConfig)
+	}
+	if isSiteCertificateRejectedError(err) {
+		return nil, NewSiteCertificateMismatchError(fmt.Sprintf(
+			"site [%s] of Cluster [%s] presented a certificate which is not trusted: [%v]", vcdCluster.Spec.Site,
+			vcdCluster.Name, err))
+	}
+	if err != nil {
+		if isCredentialsRejectedError(err) && usesRefreshToken {
+			message := fmt.Sprintf("API refresh token for Cluster [%s] was rejected by site [%s]; the token may have expired or been revoked: [%v]",
+				vcdCluster.Name, vcdCluster.Spec.Site, err)
+			vcdTokens.recordRefreshTokenRejected(vcdCluster.Spec.Site, userCreds.RefreshToken, message)
+			return nil, NewRefreshTokenExpiredError(message)
+		}
+		if isCredentialsRejectedError(err) {
+			return nil, NewCredentialsExpiredError(fmt.Sprintf(
+				"credentials of user [%s] for Cluster [%s] were rejected by site [%s]; the password may have expired or the user may be disabled: [%v]",
+				userCreds.Username, vcdCluster.Name, vcdCluster.Spec.Site, err))
+		}
+		return nil, fmt.Errorf("error creating VCD client from secrets to reconcile Cluster [%s] infrastructure: [%v]", vcdCluster.Name, err)
+	}
+	if usesRefreshToken {
+		vcdTokens.recordRefreshTokenAccepted(vcdCluster.Spec.Site, userCreds.RefreshToken)
+	}
 	return vcdClient, nil
 }
 
+// newLocalVCDClient logs a local user into the VCD site, through its proxy if it has one, as the VCD SDK does, and
+// returns a VCD client for the session whose requests are all verified with the TLS configuration.
+func newLocalVCDClient(site string, orgName string, vdcName string, userOrg string,
+	userCreds infrav1beta3.UserCredentialsContext, insecure bool, tlsConfig *tls.Config) (*vcdsdk.Client, error) {
+
+	href := fmt.Sprintf("%s/api", site)
+	u, err := url.ParseRequestURI(href)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse url [%s]: [%v]", href, err)
+	}
+	userOrg, username, err := vcdsdk.GetUserAndOrg(userCreds.Username, orgName, userOrg)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing username before authenticating to VCD: [%v]", err)
+	}
+
+	govcdClient := govcd.NewVCDClient(*u, insecure)
+	govcdClient.Client.APIVersion = vcdsdk.VCloudApiVersion_36_0
+	setVCDClientTransport(govcdClient, site, tlsConfig)
+	if userCreds.RefreshToken != "" {
+		// as with the VCD SDK, the refresh token of a system administrator is tried in the system org first
+		if err = govcdClient.SetToken("system", govcd.ApiTokenHeader, userCreds.RefreshToken); err == nil {
+			userOrg = "system"
+		} else if err = govcdClient.SetToken(userOrg, govcd.ApiTokenHeader, userCreds.RefreshToken); err != nil {
+			return nil, fmt.Errorf("failed to set authorization header: [%v]", err)
+		}
+	} else {
+		resp, err := govcdClient.GetAuthResponse(username, userCreds.Password, userOrg)
+		if err != nil {
+			return nil, fmt.Errorf("unable to authenticate [%s/%s] for url [%s]: [%v]", userOrg, username, href, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to authenticate with VCD with username [%s] and org [%s]: [%s]",
+				username, userOrg, resp.Status)
+		}
+	}
+
+	vcdAuthConfig := vcdsdk.NewVCDAuthConfigFromSecrets(site, username, userCreds.Password, userCreds.RefreshToken,
+		userOrg, insecure)
+	vcdAuthConfig.IsSysAdmin = govcdClient.Client.IsSysAdmin
+	apiClient, apiClient37 := newSwaggerClients(govcdClient, site, tlsConfig)
+	return &vcdsdk.Client{
+		VCDAuthConfig:   vcdAuthConfig,
+		ClusterOrgName:  orgName,
+		ClusterOVDCName: vdcName,
+		VCDClient:       govcdClient,
+		APIClient:       apiClient,
+		APIClient37_2:   apiClient37,
+	}, nil
+}
+
 // TODO: Remove uncommented code when decision to only keep capi.yaml as part of RDE spec is finalized
 func (r *VCDClusterReconciler) constructCapvcdRDE(ctx context.Context, cluster *clusterv1.Cluster,
 	vcdCluster *infrav1beta3.VCDCluster, vdc *types.Vdc, vcdOrg *types.Org) (*swagger.DefinedEntity, error) {
@@ -333,17 +579,17 @@ func (r *VCDClusterReconciler) constructCapvcdRDE(ctx context.Context, cluster *
 		{
 			Name:        vdc.Name,
 			ID:          vdc.ID,
-			OvdcNetwork: vcdCluster.Spec.OvdcNetwork,
+			OvdcNetwork: getOvdcNetworkName(vcdCluster),
 		},
 	}
 
 	rde := &swagger.DefinedEntity{
-		EntityType: CAPVCDEntityTypeID,
+		EntityType: capisdk.GetCAPVCDEntityTypeID(),
 		Name:       vcdCluster.Name,
 	}
 	capvcdEntity := rdeType.CAPVCDEntity{
 		Kind:       capisdk.CAPVCDClusterKind,
-		ApiVersion: capisdk.CAPVCDClusterEntityApiVersion,
+		ApiVersion: capisdk.GetCAPVCDClusterEntityApiVersion(),
 		Metadata: rdeType.Metadata{
 			Name: vcdCluster.Name,
 			Org:  vcdOrg.Name,
@@ -415,12 +661,12 @@ func (r *VCDClusterReconciler) constructAndCreateRDEFromCluster(ctx context.Cont
 		return "", fmt.Errorf("VDC client in vcdClient object is nil")
 	}
 
-	org, err := getOrgByName(vcdClient, vcdCluster.Spec.Org)
+	org, err := getOrgByName(vcdClient, getOrgName(vcdCluster))
 	if err != nil {
 		return "", fmt.Errorf("error occurred while constructing RDE from cluster [%s]", vcdCluster.Status.InfraId)
 	}
 	if org == nil || org.Org == nil {
-		return "", fmt.Errorf("unable to get the org by name [%s]", vcdCluster.Spec.Org)
+		return "", fmt.Errorf("unable to get the org by name [%s]", getOrgName(vcdCluster))
 	}
 
 	rde, err := r.constructCapvcdRDE(ctx, cluster, vcdCluster, vcdClient.VDC.Vdc, org.Org)
@@ -464,15 +710,15 @@ func (r *VCDClusterReconciler) reconcileRDE(ctx context.Context, cluster *cluste
 		return nil
 	}
 
-	org, err := vcdClient.VCDClient.GetOrgByName(vcdCluster.Spec.Org)
+	org, err := vcdClient.VCDClient.GetOrgByName(getOrgName(vcdCluster))
 	if err != nil {
-		return fmt.Errorf("failed to get org by name [%s]", vcdCluster.Spec.Org)
+		return fmt.Errorf("failed to get org by name [%s]", getOrgName(vcdCluster))
 	}
 	if org == nil || org.Org == nil {
-		return fmt.Errorf("found nil org when getting org by name [%s]", vcdCluster.Spec.Org)
+		return fmt.Errorf("found nil org when getting org by name [%s]", getOrgName(vcdCluster))
 	}
 	capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
-	_, capvcdSpec, capvcdMetadata, capvcdStatus, err := capvcdRdeManager.GetCAPVCDEntity(ctx, vcdCluster.Status.InfraId)
+	rde, capvcdSpec, capvcdMetadata, capvcdStatus, err := capvcdRdeManager.GetCAPVCDEntity(ctx, vcdCluster.Status.InfraId)
 	if err != nil {
 		return fmt.Errorf("failed to get RDE with ID [%s] for cluster [%s]: [%v]", vcdCluster.Status.InfraId, vcdCluster.Name, err)
 	}
@@ -483,7 +729,7 @@ func (r *VCDClusterReconciler) reconcileRDE(ctx context.Context, cluster *cluste
 		metadataPatch["Org"] = org.Org.Name
 	}
 
-	vdc := vcdCluster.Spec.Ovdc
+	vdc := getOvdcName(vcdCluster)
 	if vdc != capvcdMetadata.Vdc {
 		metadataPatch["Vdc"] = capvcdMetadata.Vdc
 	}
@@ -529,6 +775,11 @@ func (r *VCDClusterReconciler) reconcileRDE(ctx context.Context, cluster *cluste
 		}
 	}
 
+	// the replicas of the node pools scaled from the management cluster are reported back in the node pools of the spec
+	if nodePools := getPublishedRDENodePools(vcdCluster, capvcdSpec.NodePools, kcpList, mdList); nodePools != nil {
+		specPatch["NodePools"] = nodePools
+	}
+
 	// Updating status portion of the RDE in the following code
 	capvcdStatusPatch := make(map[string]interface{})
 	if capvcdStatus.Phase != cluster.Status.Phase {
@@ -570,6 +821,7 @@ func (r *VCDClusterReconciler) reconcileRDE(ctx context.Context, cluster *cluste
 
 	if !reflect.DeepEqual(upgradeObject, capvcdStatus.Upgrade) {
 		capvcdStatusPatch["Upgrade"] = upgradeObject
+		queueRDEUpgradeEvents(capvcdRdeManager, capvcdStatus.Upgrade, upgradeObject)
 	}
 
 	// TODO: Delete "kubernetes" string in RDE. Discuss with Sahithi
@@ -635,6 +887,25 @@ func (r *VCDClusterReconciler) reconcileRDE(ctx context.Context, cluster *cluste
 		capvcdStatusPatch["K8sNetwork.Services"] = services
 	}
 
+	var proxyConfig *rdeType.ProxyConfig
+	if isProxyConfigured(vcdCluster) {
+		proxyConfig = &rdeType.ProxyConfig{
+			HttpProxy:  vcdCluster.Spec.ProxyConfigSpec.HTTPProxy,
+			HttpsProxy: vcdCluster.Spec.ProxyConfigSpec.HTTPSProxy,
+			NoProxy:    getNoProxy(cluster, vcdCluster),
+		}
+	}
+	if !reflect.DeepEqual(capvcdStatus.ProxyConfig, proxyConfig) {
+		capvcdStatusPatch["ProxyConfig"] = proxyConfig
+	}
+
+	networkFlows, err := getNetworkFlows(ctx, r.Client, cluster, vcdCluster)
+	if err != nil {
+		log.Error(err, "failed to get the network flows of the cluster")
+	} else if !reflect.DeepEqual(capvcdStatus.NetworkFlows, networkFlows) {
+		capvcdStatusPatch["NetworkFlows"] = networkFlows
+	}
+
 	clusterApiStatusPhase := ClusterApiStatusPhaseNotReady
 	if cluster.Status.ControlPlaneReady {
 		clusterApiStatusPhase = ClusterApiStatusPhaseReady
@@ -654,15 +925,26 @@ func (r *VCDClusterReconciler) reconcileRDE(ctx context.Context, cluster *cluste
 	}
 	if !reflect.DeepEqual(clusterApiStatus, capvcdStatus.ClusterAPIStatus) {
 		capvcdStatusPatch["ClusterAPIStatus"] = clusterApiStatus
+		// the endpoint set once the load balancer of the cluster is created is not reported
+		if len(capvcdStatus.ClusterAPIStatus.ApiEndpoints) > 0 && capvcdStatus.ClusterAPIStatus.ApiEndpoints[0].Host != "" &&
+			!reflect.DeepEqual(clusterApiStatus.ApiEndpoints, capvcdStatus.ClusterAPIStatus.ApiEndpoints) {
+			previousEndpoint := capvcdStatus.ClusterAPIStatus.ApiEndpoints[0]
+			capvcdRdeManager.QueueRDEEvent(capisdk.ControlPlaneEndpointChanged, "", vcdCluster.Name,
+				map[string]interface{}{
+					"previousEndpoint": fmt.Sprintf("%s:%d", previousEndpoint.Host, previousEndpoint.Port),
+					"endpoint":         fmt.Sprintf("%s:%d", vcdCluster.Spec.ControlPlaneEndpoint.Host, controlPlanePort),
+				})
+		}
 	}
 
 	// update node status. Needed to remove stray nodes which were already deleted
-	nodePoolList, err := getNodePoolList(ctx, r.Client, *cluster)
+	nodePoolList, err := getNodePoolList(ctx, r.Client, *cluster, vcdCluster)
 	if err != nil {
 		klog.Errorf("failed to get node pool list from cluster [%s]: [%v]", cluster.Name, err)
 	}
 	if !reflect.DeepEqual(nodePoolList, capvcdStatus.NodePool) {
 		capvcdStatusPatch["NodePool"] = nodePoolList
+		queueRDEScaleEvents(capvcdRdeManager, capvcdStatus.NodePool, nodePoolList)
 	}
 
 	vcdResources := rdeType.VCDProperties{
@@ -677,34 +959,36 @@ func (r *VCDClusterReconciler) reconcileRDE(ctx context.Context, cluster *cluste
 			{
 				Name:        vcdClient.VDC.Vdc.Name,
 				ID:          vcdClient.VDC.Vdc.Name,
-				OvdcNetwork: vcdCluster.Spec.OvdcNetwork,
+				OvdcNetwork: getOvdcNetworkName(vcdCluster),
 			},
 		},
+		EgressIPs: vcdCluster.Status.EgressIPs,
 	}
 	if !reflect.DeepEqual(vcdResources, capvcdStatus.VcdProperties) {
 		capvcdStatusPatch["VcdProperties"] = vcdResources
 	}
 
-	obj := client.ObjectKey{
-		Namespace: cluster.Namespace,
-		Name:      cluster.Name,
-	}
-	kubeConfigBytes, err := kcfg.FromSecret(ctx, r.Client, obj)
-	if err != nil {
+	if err = r.reconcileRDEKubeConfig(ctx, cluster, capvcdRdeManager, vcdCluster.Status.InfraId, capvcdStatus,
+		capvcdStatusPatch); err != nil {
 		log.Error(err, "failed to update RDE private section with kubeconfig")
-	} else {
-		if !reflect.DeepEqual(string(kubeConfigBytes), capvcdStatus.Private.KubeConfig) {
-			capvcdStatusPatch["Private.KubeConfig"] = string(kubeConfigBytes)
-		}
 	}
 	if release.Version != capvcdStatus.CapvcdVersion {
 		capvcdStatusPatch["CapvcdVersion"] = release.Version
 	}
 
-	updatedRDE, err := capvcdRdeManager.PatchRDE(ctx, specPatch, metadataPatch, capvcdStatusPatch, vcdCluster.Status.InfraId, vappID, updateExternalID)
+	// the changes are batched with the events of the cluster and written at most once per minimum write interval,
+	// except for the external ID and the node pools of the spec which are written right away, the latter so that they
+	// do not overwrite a scaling requested from VCD in the meantime
+	capvcdRdeManager.QueueRDEPatch(specPatch, metadataPatch, capvcdStatusPatch, vcdCluster.Status.InfraId, vappID,
+		updateExternalID)
+	_, publishNodePools := specPatch["NodePools"]
+	updatedRDE, err := capvcdRdeManager.FlushRDE(ctx, vcdCluster.Status.InfraId, updateExternalID || publishNodePools)
 	if err != nil {
 		return fmt.Errorf("failed to update defined entity with ID [%s] for cluster [%s]: [%v]", vcdCluster.Status.InfraId, vcdCluster.Name, err)
 	}
+	if updatedRDE == nil {
+		updatedRDE = rde
+	}
 
 	if updatedRDE.State != swagger.RDEStateResolved {
 		// try to resolve the defined entity
@@ -724,8 +1008,51 @@ func (r *VCDClusterReconciler) reconcileRDE(ctx context.Context, cluster *cluste
 
 }
 
+// queueRDEUpgradeEvents records the start and the completion of the upgrades of the Kubernetes version of the cluster
+// in the eventSet of its RDE.
+func queueRDEUpgradeEvents(capvcdRdeManager *capisdk.CapvcdRdeManager, previousUpgrade rdeType.Upgrade,
+	upgrade rdeType.Upgrade) {
+
+	if previousUpgrade.Current == nil || upgrade.Current == nil || upgrade.Previous == nil {
+		return
+	}
+	details := map[string]interface{}{
+		"previousVersion": upgrade.Previous.K8sVersion,
+		"version":         upgrade.Current.K8sVersion,
+	}
+	if previousUpgrade.Current.K8sVersion != upgrade.Current.K8sVersion {
+		capvcdRdeManager.QueueRDEEvent(capisdk.KubernetesUpgradeStarted, "", "", details)
+	}
+	if upgrade.Ready && (!previousUpgrade.Ready || previousUpgrade.Current.K8sVersion != upgrade.Current.K8sVersion) {
+		capvcdRdeManager.QueueRDEEvent(capisdk.KubernetesUpgradeCompleted, "", "", details)
+	}
+}
+
+// queueRDEScaleEvents records the changes of the desired replicas of the node pools of the cluster in the eventSet of
+// its RDE. The node pools created with the cluster are not reported.
+func queueRDEScaleEvents(capvcdRdeManager *capisdk.CapvcdRdeManager, previousNodePools []rdeType.NodePool,
+	nodePools []rdeType.NodePool) {
+
+	previousReplicas := make(map[string]int32)
+	for _, nodePool := range previousNodePools {
+		previousReplicas[nodePool.Name] = nodePool.DesiredReplicas
+	}
+	for _, nodePool := range nodePools {
+		replicas, ok := previousReplicas[nodePool.Name]
+		if !ok || replicas == nodePool.DesiredReplicas {
+			continue
+		}
+		capvcdRdeManager.QueueRDEEvent(capisdk.ClusterScaled, "", nodePool.Name, map[string]interface{}{
+			"nodePool":         nodePool.Name,
+			"previousReplicas": replicas,
+			"replicas":         nodePool.DesiredReplicas,
+		})
+	}
+}
+
 func (r *VCDClusterReconciler) reconcileInfraID(ctx context.Context, cluster *clusterv1.Cluster,
-	vcdCluster *infrav1beta3.VCDCluster, vcdClient *vcdsdk.Client, skipRDEEventUpdates bool) error {
+	vcdCluster *infrav1beta3.VCDCluster, vcdClient *vcdsdk.Client, userRights *vcdUserRights,
+	skipRDEEventUpdates bool) error {
 
 	log := ctrl.LoggerFrom(ctx)
 
@@ -747,6 +1074,8 @@ func (r *VCDClusterReconciler) reconcileInfraID(ctx context.Context, cluster *cl
 	// creating RDEs for the clusters -
 	// 1. clusters already created will have NO_RDE_ prefix in the infra ID. We should make sure that the cluster can co-exist
 	// 2. Clusters which are newly created should check for CAPVCD_SKIP_RDE environment variable to determine if an RDE should be created for the cluster
+	// 3. In the minimal-rights mode, clusters whose user lacks the capvcdCluster rights are created without an RDE
+	// 4. Clusters setting spec.rdeManagementDisabled are created without an RDE
 
 	// NOTE: If CAPVCD_SKIP_RDE is not set, CAPVCD will error out if there is any error in RDE creation
 	// rdeVersionInUseByCluster is the current version of the RDE associated with the cluster. Note that this version can be different from the latest RDE version used by the CAPVCD product.
@@ -754,16 +1083,16 @@ func (r *VCDClusterReconciler) reconcileInfraID(ctx context.Context, cluster *cl
 	rdeVersionInUseByCluster := vcdCluster.Status.RdeVersionInUse
 	if infraID == "" {
 		// Create RDE for the cluster or generate a NO_RDE infra ID for the cluster.
-		if !SkipRDE {
+		if !skipRDECreation(vcdCluster, userRights) {
 			// Create an RDE for the cluster. If RDE creation results in a failure, error out cluster creation.
 			// check rights for RDE creation and create an RDE
-			if !capvcdRdeManager.IsCapvcdEntityTypeRegistered(rdeType.CapvcdRDETypeVersion) {
+			if !capvcdRdeManager.IsCapvcdEntityTypeRegistered(capisdk.CapvcdRDETypeVersion) {
 				capvcdRdeManager.AddToErrorSet(ctx, capisdk.RdeError, "", vcdCluster.Name,
 					"CapvcdCluster entity type not registered or capvcdCluster rights missing from the user's role")
 				return fmt.Errorf(
 					"capvcdCluster entity type not registered or capvcdCluster rights missing from the user's role"+
 						"cluster create issued with executeWithoutRDE=[%v] but unable to create capvcdCluster entity at version [%s]",
-					SkipRDE, rdeType.CapvcdRDETypeVersion)
+					SkipRDE, capisdk.CapvcdRDETypeVersion)
 			}
 			// create RDE
 			nameFilter := &swagger.DefinedEntityApiGetDefinedEntitiesByEntityTypeOpts{
@@ -784,10 +1113,10 @@ func (r *VCDClusterReconciler) reconcileInfraID(ctx context.Context, cluster *cl
 				capvcdRdeManager.AddToErrorSet(ctx, capisdk.RdeError, "", vcdCluster.Name,
 					fmt.Sprintf("Error fetching RDE: [%v]", err))
 				log.Error(err, "Error while checking if RDE is already present for the cluster",
-					"entityTypeId", CAPVCDEntityTypeID)
+					"entityTypeId", capisdk.GetCAPVCDEntityTypeID())
 				return fmt.Errorf(
 					"error checking if RDE is already present for the cluster [%s] with entity type ID [%s]: [%v]",
-					vcdCluster.Name, CAPVCDEntityTypeID, err)
+					vcdCluster.Name, capisdk.GetCAPVCDEntityTypeID(), err)
 			}
 			if resp == nil {
 				msg := fmt.Sprintf("Error while checking if RDE for the cluster [%s] is already present for the cluster; "+
@@ -798,7 +1127,7 @@ func (r *VCDClusterReconciler) reconcileInfraID(ctx context.Context, cluster *cl
 
 			} else if resp.StatusCode != http.StatusOK {
 				msg := fmt.Sprintf("Invalid status code [%d] while checking if RDE is already present for the cluster using the entityTYpeID [%s]",
-					resp.StatusCode, CAPVCDEntityTypeID)
+					resp.StatusCode, capisdk.GetCAPVCDEntityTypeID())
 				log.Error(nil, msg)
 				capvcdRdeManager.AddToErrorSet(ctx, capisdk.RdeError, "", vcdCluster.Name, msg)
 				return fmt.Errorf(msg)
@@ -816,7 +1145,7 @@ func (r *VCDClusterReconciler) reconcileInfraID(ctx context.Context, cluster *cl
 						return fmt.Errorf("error creating RDE for the cluster [%s]: [%v]", vcdCluster.Name, err)
 					}
 					infraID = rdeID
-					rdeVersionInUseByCluster = rdeType.CapvcdRDETypeVersion
+					rdeVersionInUseByCluster = capisdk.CapvcdRDETypeVersion
 				} else {
 					log.Info("RDE for the cluster is already present; skipping RDE creation", "InfraId",
 						definedEntities.Values[0].Id)
@@ -859,17 +1188,17 @@ func (r *VCDClusterReconciler) reconcileInfraID(ctx context.Context, cluster *cl
 		//2. If vcdCluster.Status.RdeVersionInUse is empty, skip the RDE upgrade process.
 		//3. If version outdated is detected, proceed with the RDE upgrade process.
 		if !strings.Contains(infraID, NoRdePrefix) && vcdCluster.Status.RdeVersionInUse != "" &&
-			vcdCluster.Status.RdeVersionInUse != rdeType.CapvcdRDETypeVersion {
+			vcdCluster.Status.RdeVersionInUse != capisdk.CapvcdRDETypeVersion {
 			capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, infraID)
 			// 4. Skip the RDE upgrade process if the VCDKECluster flag is set to true
-			//    and current_rde_version < RDE should be in use for a given CAPVCD version- rdeType.CapvcdRDETypeVersion always be the latest RDE version
-			if !capvcdRdeManager.IsVCDKECluster(ctx, infraID) && capisdk.CheckIfClusterRdeNeedsUpgrade(rdeVersionInUseByCluster, rdeType.CapvcdRDETypeVersion) {
+			//    and current_rde_version < RDE should be in use for a given CAPVCD version- capisdk.CapvcdRDETypeVersion, the latest RDE version unless configured otherwise
+			if !capvcdRdeManager.IsVCDKECluster(ctx, infraID) && capisdk.CheckIfClusterRdeNeedsUpgrade(rdeVersionInUseByCluster, capisdk.CapvcdRDETypeVersion) {
 				log.Info("Upgrading RDE", "rdeID", infraID,
-					"targetRDEVersion", rdeType.CapvcdRDETypeVersion)
+					"targetRDEVersion", capisdk.CapvcdRDETypeVersion)
 				if _, err := capvcdRdeManager.ConvertToLatestRDEVersionFormat(ctx, infraID); err != nil {
 					log.Error(err, "failed to upgrade RDE", "rdeID", infraID,
 						"sourceVersion", vcdCluster.Status.RdeVersionInUse,
-						"targetVersion", rdeType.CapvcdRDETypeVersion)
+						"targetVersion", capisdk.CapvcdRDETypeVersion)
 					capvcdRdeManager.AddToErrorSet(ctx, capisdk.RdeError, "", vcdCluster.Name, fmt.Sprintf("RDE upgrade failed: [%v]", err))
 					return fmt.Errorf("failed to upgrade RDE [%s] for cluster [%s]: [%v]", vcdCluster.Name,
 						infraID, err)
@@ -883,11 +1212,13 @@ func (r *VCDClusterReconciler) reconcileInfraID(ctx context.Context, cluster *cl
 				}
 				capvcdRdeManager.AddToEventSet(ctx, capisdk.RdeUpgraded, infraID, "", "",
 					skipRDEEventUpdates)
+				r.recordEvent(vcdCluster, corev1.EventTypeNormal, RDEUpgradedReason,
+					fmt.Sprintf("RDE [%s] upgraded to version [%s]", infraID, capisdk.CapvcdRDETypeVersion))
 				if err := capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD,
 					capisdk.RdeError, "", ""); err != nil {
 					log.Error(err, "failed to remove RdeError (RDE upgraded successfully) ", "rdeID", infraID)
 				}
-				rdeVersionInUseByCluster = rdeType.CapvcdRDETypeVersion
+				rdeVersionInUseByCluster = capisdk.CapvcdRDETypeVersion
 			}
 		}
 	}
@@ -910,7 +1241,7 @@ func (r *VCDClusterReconciler) reconcileInfraID(ctx context.Context, cluster *cl
 			capvcdRdeManager.AddToErrorSet(ctx, capisdk.CAPVCDObjectPatchError, "",
 				vcdCluster.Name, fmt.Sprintf("failed to patch vcdcluster: [%v]", err))
 			return fmt.Errorf("unable to patch status of vcdCluster [%s] with InfraID [%s], RDEVersion [%s]: [%v]",
-				vcdCluster.Name, infraID, rdeType.CapvcdRDETypeVersion, err)
+				vcdCluster.Name, infraID, capisdk.CapvcdRDETypeVersion, err)
 		}
 		if err := capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD,
 			capisdk.CAPVCDObjectPatchError, "", vcdCluster.Name); err != nil {
@@ -943,8 +1274,8 @@ func (r *VCDClusterReconciler) reconcileLoadBalancer(ctx context.Context, vcdClu
 	var resourcesAllocated *vcdsdkutil.AllocatedResourcesMap
 
 	capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
-	gateway, err := vcdsdk.NewGatewayManager(ctx, vcdClient, vcdCluster.Spec.OvdcNetwork,
-		vcdCluster.Spec.LoadBalancerConfigSpec.VipSubnet, vcdCluster.Spec.Ovdc)
+	gateway, err := vcdsdk.NewGatewayManager(ctx, vcdClient, getOvdcNetworkName(vcdCluster),
+		vcdCluster.Spec.LoadBalancerConfigSpec.VipSubnet, getOvdcName(vcdCluster))
 	if err != nil {
 		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDClusterError, "", vcdCluster.Name,
 			fmt.Sprintf("failed to create gateway manager: [%v]", err))
@@ -989,16 +1320,26 @@ func (r *VCDClusterReconciler) reconcileLoadBalancer(ctx context.Context, vcdClu
 		resourcesAllocated = &vcdsdkutil.AllocatedResourcesMap{}
 		// here we set enableVirtualServiceSharedIP to ensure that we don't use a DNAT rule. The variable is possibly
 		// badly named. Though the user-facing name is good, the internal variable name could be better.
-		controlPlaneNodeIP, err = gateway.CreateLoadBalancer(ctx, virtualServiceNamePrefix, lbPoolNamePrefix,
-			[]string{}, []vcdsdk.PortDetails{
-				{
-					Protocol:     "TCP",
-					PortSuffix:   "tcp",
-					ExternalPort: int32(controlPlanePort),
-					InternalPort: int32(controlPlanePort),
-				},
-			}, oneArm, !vcdCluster.Spec.LoadBalancerConfigSpec.UseOneArm,
-			nil, vcdCluster.Spec.ControlPlaneEndpoint.Host, resourcesAllocated)
+		if vcdCluster.Spec.LoadBalancerConfigSpec.ServiceEngineGroup != "" {
+			controlPlaneNodeIP, err = createLoadBalancerOnServiceEngineGroup(ctx, vcdClient, gateway, vcdCluster,
+				virtualServiceNamePrefix, lbPoolNamePrefix, int32(controlPlanePort), oneArm,
+				vcdCluster.Spec.ControlPlaneEndpoint.Host, resourcesAllocated)
+		} else {
+			controlPlaneNodeIP, err = gateway.CreateLoadBalancer(ctx, virtualServiceNamePrefix, lbPoolNamePrefix,
+				[]string{}, []vcdsdk.PortDetails{
+					{
+						Protocol:     "TCP",
+						PortSuffix:   "tcp",
+						ExternalPort: int32(controlPlanePort),
+						InternalPort: int32(controlPlanePort),
+					},
+				}, oneArm, !vcdCluster.Spec.LoadBalancerConfigSpec.UseOneArm,
+				nil, vcdCluster.Spec.ControlPlaneEndpoint.Host, resourcesAllocated)
+		}
+		// Record the app port profiles even if the creation has failed, so that they are deleted with the cluster
+		if trackErr := trackAppPortProfiles(vcdCluster, resourcesAllocated); trackErr != nil {
+			log.Error(trackErr, "failed to record the app port profiles of the load balancer")
+		}
 		if err != nil {
 			capvcdRdeManager.AddToErrorSet(ctx, capisdk.LoadBalancerError, "", "",
 				fmt.Sprintf("failed to create load balancer for the cluster [%s(%s)]: [%v]",
@@ -1042,6 +1383,8 @@ func (r *VCDClusterReconciler) reconcileLoadBalancer(ctx context.Context, vcdClu
 				virtualServiceNamePrefix, vcdCluster.Name, err)
 		}
 		log.Info("Resources Allocated in creation of load balancer", "resourcesAllocated", resourcesAllocated)
+		r.recordEvent(vcdCluster, corev1.EventTypeNormal, LoadBalancerCreatedReason,
+			fmt.Sprintf("load balancer of the control plane endpoint created with virtual IP [%s]", controlPlaneNodeIP))
 	}
 
 	if err = addLBResourcesToVCDResourceSet(ctx, rdeManager, resourcesAllocated, controlPlaneNodeIP); err != nil {
@@ -1060,6 +1403,14 @@ func (r *VCDClusterReconciler) reconcileLoadBalancer(ctx context.Context, vcdClu
 		virtualServiceHref = resourcesAllocated.Get(vcdsdk.VcdResourceVirtualService)[0].Id
 	}
 
+	if err = reconcileLoadBalancerPoolSettings(ctx, vcdClient, gateway, vcdCluster); err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.LoadBalancerError, "", "",
+			fmt.Sprintf("failed to configure load balancer pool of the cluster [%s(%s)]: [%v]",
+				vcdCluster.Name, vcdCluster.Status.InfraId, err))
+		return ctrl.Result{}, fmt.Errorf("failed to configure load balancer pool of the cluster [%s(%s)]: [%v]",
+			vcdCluster.Name, vcdCluster.Status.InfraId, err)
+	}
+
 	vcdCluster.Spec.ControlPlaneEndpoint = infrav1beta3.APIEndpoint{
 		Host: controlPlaneNodeIP,
 		Port: controlPlanePort,
@@ -1085,13 +1436,31 @@ func (r *VCDClusterReconciler) reconcileNormal(ctx context.Context, cluster *clu
 
 	log := ctrl.LoggerFrom(ctx)
 
+	// report invalid VCDMachineTemplate references before the machines fail on them
+	machineTemplates := r.reconcileMachineTemplateRefs(ctx, cluster, vcdCluster)
+
 	// To avoid spamming RDEs with updates, only update the RDE with events when machine creation is ongoing
 	skipRDEEventUpdates := clusterv1.ClusterPhase(cluster.Status.Phase) == clusterv1.ClusterPhaseProvisioned
 	vcdClient, err := createVCDClientFromSecrets(ctx, r.Client, vcdCluster)
+	reconcileVCDAuthHealthyCondition(vcdCluster, err)
+	var certificateErr *SiteCertificateMismatchError
+	if errors.As(err, &certificateErr) {
+		reconcileSiteCertificateCondition(vcdCluster, certificateErr)
+	} else if err == nil {
+		reconcileSiteCertificateCondition(vcdCluster, nil)
+	}
 	if err != nil {
+		var credentialsErr *CredentialsExpiredError
+		if errors.As(err, &credentialsErr) {
+			r.markCredentialsExpired(vcdCluster, credentialsErr)
+			log.Error(err, "Credentials of the cluster were rejected by VCD; waiting for the credentials to be updated")
+			return ctrl.Result{RequeueAfter: CredentialsExpiredRequeueInterval}, nil
+		}
 		return ctrl.Result{}, errors.Wrapf(err, "Error creating VCD client to reconcile Cluster [%s] infrastructure",
 			vcdCluster.Name)
 	}
+	r.markCredentialsValid(vcdCluster)
+	recordSubsystemRun(&vcdCluster.Status.LastSubsystemRuns.Infrastructure)
 	// close all idle connections when reconciliation is done
 	defer func() {
 		if vcdClient != nil && vcdClient.VCDClient != nil {
@@ -1101,6 +1470,22 @@ func (r *VCDClusterReconciler) reconcileNormal(ctx context.Context, cluster *clu
 		}
 	}()
 
+	// in the minimal-rights mode, disable the optional features the user lacks the rights for
+	userRights := r.reconcileUserRights(ctx, vcdCluster, vcdClient)
+
+	// refuse features the site can't support before reconciling any infrastructure
+	if err := r.reconcileSiteCapabilities(ctx, vcdCluster, vcdClient, userRights); err != nil {
+		conditions.MarkFalse(vcdCluster, SiteCapabilitiesVerifiedCondition, SiteCapabilitiesUnsupportedReason,
+			clusterv1.ConditionSeverityError, err.Error())
+		return ctrl.Result{}, errors.Wrapf(err, "VCD site of cluster [%s] cannot support the cluster", vcdCluster.Name)
+	}
+	conditions.MarkTrue(vcdCluster, SiteCapabilitiesVerifiedCondition)
+
+	// the VCDMachine controller stops creating VMs while the provider has the OVDC disabled; the rest of the cluster
+	// infrastructure lives on the edge gateway and is still reconciled
+	r.reconcileOvdcDisablement(ctx, vcdCluster, vcdClient)
+	r.reconcileOvdcMaintenance(ctx, vcdCluster)
+
 	// updating the VCD cluster resource with any VDC name changes to is necessary in VCD cluster controller because
 	// the OVDC name is used to get the OVDC network
 	if vcdClient.VDC != nil && vcdClient.VDC.Vdc != nil {
@@ -1110,23 +1495,116 @@ func (r *VCDClusterReconciler) reconcileNormal(ctx context.Context, cluster *clu
 		}
 	}
 
-	if err := r.reconcileInfraID(ctx, cluster, vcdCluster, vcdClient, skipRDEEventUpdates); err != nil {
+	if err := r.reconcileInfraID(ctx, cluster, vcdCluster, vcdClient, userRights, skipRDEEventUpdates); err != nil {
+		conditions.MarkFalse(vcdCluster, RDEReadyCondition, RDEReconcileFailedReason,
+			clusterv1.ConditionSeverityWarning, err.Error())
 		return ctrl.Result{}, errors.Wrapf(err, "Unable to reconcile Infra ID for cluster [%s]", vcdCluster.Name)
 	}
+	trackRDEFreshness(vcdCluster)
+	trackClusterOvdc(vcdCluster)
+
+	// the vApp of the cluster is named once, so that a change of its naming template does not orphan it
+	if err := reconcileVAppName(vcdCluster); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to generate the vApp name of cluster [%s]", vcdCluster.Name)
+	}
+
+	// the VCD resources of an externally managed cluster are only observed: CAPVCD does not claim, create or modify
+	// them, and only populates the status of the VCDCluster and the RDE
+	externallyManaged := annotations.IsExternallyManaged(vcdCluster)
+
+	// refuse to mutate VCD resources claimed by another management cluster
+	if !externallyManaged {
+		if err := r.reconcileOwnershipClaim(ctx, vcdCluster, vcdClient); err != nil {
+			var claimedErr *OwnershipClaimedError
+			if errors.As(err, &claimedErr) {
+				conditions.MarkFalse(vcdCluster, OwnershipClaimVerifiedCondition, ClaimedByOtherManagementClusterReason,
+					clusterv1.ConditionSeverityError, err.Error())
+			}
+			return ctrl.Result{}, errors.Wrapf(err, "Unable to verify ownership of cluster [%s]", vcdCluster.Name)
+		}
+	}
+
+	// create the OVDC network of the cluster before anything is provisioned on it
+	if !externallyManaged {
+		if err := r.reconcileManagedNetwork(ctx, vcdClient, vcdCluster); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "Unable to create the OVDC network of cluster [%s]",
+				vcdCluster.Name)
+		}
+	}
+
+	// the VCDMachine controller stops creating VMs while the IP pool of the OVDC network is exhausted
+	if !externallyManaged {
+		r.reconcileIPPool(ctx, vcdClient, vcdCluster, userRights)
+	}
+
+	// the machines in DHCP mode need the DHCP service of the OVDC network to lease their addresses
+	if !externallyManaged {
+		if err := r.reconcileDHCPPool(ctx, vcdClient, vcdCluster, userRights); err != nil {
+			log.Error(err, "failed to check the DHCP pool of the OVDC network", "ovdcNetwork",
+				getOvdcNetworkName(vcdCluster))
+			r.recordEvent(vcdCluster, corev1.EventTypeWarning, DHCPPoolErrorReason, err.Error())
+		}
+	}
+
+	// the vApps of the vApp strategy are created by the VCDMachine controller, and deleted once they are empty
+	if !externallyManaged {
+		if err := r.reconcileStrategyVApps(ctx, vcdClient, vcdCluster); err != nil {
+			log.Error(err, "failed to reconcile the vApps of the vApp strategy of the cluster",
+				"vAppStrategy", getVAppStrategyType(vcdCluster))
+		}
+	}
+
+	// import the templates missing from their catalogs before the machines are created with them
+	templateImportsInProgress := false
+	if !externallyManaged {
+		templateImportsInProgress = r.reconcileTemplateImports(ctx, vcdClient, vcdCluster, userRights)
+	}
+
+	// report catalogs, templates and policies missing in VCD before the machines are created with them
+	if !externallyManaged {
+		r.reconcileMachineTemplateVCDReferences(ctx, vcdClient, vcdCluster, machineTemplates)
+	}
+
+	// the VMs kept with a snapshot after an upgrade are deleted once their retention elapsed
+	if !externallyManaged {
+		if err := deleteRetainedVMs(ctx, vcdClient, vcdCluster, false); err != nil {
+			log.Error(err, "failed to delete the VMs kept with a snapshot whose retention elapsed")
+		}
+	}
 
 	// After InfraId has been set, we can update site, org, ovdcNetwork, parentUid, useAsManagementCluster
 	// proxyConfigSpec loadBalancerConfigSpec for vcdCluster status
 	vcdCluster.Status.Site = vcdCluster.Spec.Site
-	vcdCluster.Status.Org = vcdCluster.Spec.Org
-	vcdCluster.Status.Ovdc = vcdCluster.Spec.Ovdc
-	vcdCluster.Status.OvdcNetwork = vcdCluster.Spec.OvdcNetwork
+	vcdCluster.Status.Org = getOrgName(vcdCluster)
+	vcdCluster.Status.Ovdc = getOvdcName(vcdCluster)
+	vcdCluster.Status.OvdcNetwork = getOvdcNetworkName(vcdCluster)
 	vcdCluster.Status.UseAsManagementCluster = vcdCluster.Spec.UseAsManagementCluster
 	vcdCluster.Status.ParentUID = vcdCluster.Spec.ParentUID
 	vcdCluster.Status.ProxyConfig = vcdCluster.Spec.ProxyConfigSpec
 	vcdCluster.Status.LoadBalancerConfig = vcdCluster.Spec.LoadBalancerConfigSpec
-
-	// create load balancer for the cluster
-	if result, err := r.reconcileLoadBalancer(ctx, vcdCluster, vcdClient, skipRDEEventUpdates); err != nil {
+	if err := updateClusterOverviewStatus(ctx, r.Client, *cluster, vcdCluster); err != nil {
+		log.Error(err, "failed to update the machine counts and version of the cluster")
+	}
+
+	// create load balancer for the cluster, or discover the load balancer of an externally managed cluster, or use the
+	// load balancer supplied by the user, or expose the control plane through a DNAT rule, on the OVDC network or
+	// through a kube-vip virtual IP
+	reconcileControlPlaneEndpoint, controlPlaneEndpointType := r.reconcileLoadBalancer, "managed"
+	if externallyManaged {
+		reconcileControlPlaneEndpoint, controlPlaneEndpointType = r.reconcileExternalLoadBalancer, "external"
+	} else if isControlPlaneEndpointPassthrough(vcdCluster) {
+		reconcileControlPlaneEndpoint, controlPlaneEndpointType = r.reconcilePassthroughLoadBalancer, "passthrough"
+	} else if isControlPlaneEndpointDNAT(vcdCluster) {
+		reconcileControlPlaneEndpoint, controlPlaneEndpointType = r.reconcileDNATLoadBalancer, "dnat"
+	} else if isControlPlaneEndpointRouted(vcdCluster) {
+		reconcileControlPlaneEndpoint, controlPlaneEndpointType = r.reconcileRoutedControlPlaneEndpoint, "routed"
+	} else if isControlPlaneEndpointKubeVip(vcdCluster) {
+		reconcileControlPlaneEndpoint, controlPlaneEndpointType = r.reconcileKubeVipControlPlaneEndpoint, "kubevip"
+	}
+	if result, err := reconcileControlPlaneEndpoint(ctx, vcdCluster, vcdClient, skipRDEEventUpdates); err != nil {
+		loadBalancerReconcileErrors.WithLabelValues(controlPlaneEndpointType).Inc()
+		conditions.MarkFalse(vcdCluster, LoadBalancerAvailableCondition, LoadBalancerProvisioningFailedReason,
+			clusterv1.ConditionSeverityWarning, err.Error())
 		return result, errors.Wrapf(err, "Unable to reconcile Load Balancer for cluster [%s(%s)]",
 			vcdCluster.Name, vcdCluster.Status.InfraId)
 	} else if result.Requeue || result.RequeueAfter > 0 {
@@ -1134,22 +1612,86 @@ func (r *VCDClusterReconciler) reconcileNormal(ctx context.Context, cluster *clu
 			"result.Requeue", result.Requeue, "result.RequeueAfter", result.RequeueAfter.String())
 		return result, nil
 	}
+	vcdCluster.Status.ControlPlaneVIP = vcdCluster.Spec.ControlPlaneEndpoint.Host
+
+	// translate the egress of the cluster to its own external IP rather than to the SNAT IPs shared by the org
+	if !externallyManaged {
+		if err := reconcileEgressSNATRule(ctx, vcdClient, vcdCluster); err != nil {
+			log.Error(err, "failed to maintain the egress SNAT rule of the cluster on the edge gateway")
+			r.recordEvent(vcdCluster, corev1.EventTypeWarning, EgressSNATErrorReason, err.Error())
+		}
+	}
+
+	// publish the egress IPs of the cluster so that they can be allowlisted in external firewalls
+	egressIPs, err := getEgressIPs(vcdClient, getOvdcNetworkName(vcdCluster))
+	if err != nil {
+		log.Error(err, "failed to get the egress IPs of the cluster", "ovdcNetwork", getOvdcNetworkName(vcdCluster))
+	} else {
+		vcdCluster.Status.EgressIPs = egressIPs
+	}
+
+	// publish the destinations the nodes must reach so that they can be allowed in locked-down networks
+	egressAllowlist, err := getEgressAllowlist(vcdCluster)
+	if err != nil {
+		log.Error(err, "failed to generate the egress allowlist of the cluster")
+	} else {
+		vcdCluster.Status.EgressAllowlist = egressAllowlist
+		if vcdCluster.Spec.EgressAllowlist.ProgramEdgeGateway && !externallyManaged &&
+			userRights.isFeatureEnabled(OptionalFeatureEdgeFirewall) {
+			if err = reconcileEgressFirewall(ctx, vcdClient, vcdCluster, egressAllowlist); err != nil {
+				log.Error(err, "failed to program the egress allowlist of the cluster on the edge gateway")
+				r.recordEvent(vcdCluster, corev1.EventTypeWarning, EgressFirewallErrorReason, err.Error())
+			}
+		}
+	}
+
+	// restrict the traffic to the cluster on the gateway firewall, e.g. to the control plane endpoint
+	if !externallyManaged && (len(vcdCluster.Spec.FirewallRules) > 0 || len(vcdCluster.Status.FirewallRules) > 0) {
+		if !userRights.isFeatureEnabled(OptionalFeatureEdgeFirewall) {
+			r.recordEvent(vcdCluster, corev1.EventTypeWarning, FirewallRulesErrorReason,
+				"the firewall rules are not programmed as the user lacks the rights to configure the gateway firewall")
+		} else if err = reconcileClusterFirewallRules(ctx, r.Client, vcdClient, cluster, vcdCluster); err != nil {
+			log.Error(err, "failed to program the firewall rules of the cluster on the edge gateway")
+			r.recordEvent(vcdCluster, corev1.EventTypeWarning, FirewallRulesErrorReason, err.Error())
+		}
+	}
+
+	// move the control plane machines to the control plane sizing policy, one machine at a time
+	controlPlaneSizingResult, err := r.reconcileControlPlaneSizing(ctx, cluster, vcdCluster)
+	if err != nil {
+		log.Error(err, "failed to resize the control plane", "sizingPolicy",
+			vcdCluster.Spec.ControlPlaneSizingPolicy)
+	}
 
 	if err := r.reconcileRDE(ctx, cluster, vcdCluster, vcdClient, "", false); err != nil {
 		log.Error(err, "Error occurred during RDE reconciliation", "InfraId", vcdCluster.Status.InfraId)
+		conditions.MarkFalse(vcdCluster, RDEReadyCondition, RDEReconcileFailedReason,
+			clusterv1.ConditionSeverityWarning, err.Error())
+	} else {
+		recordSubsystemRun(&vcdCluster.Status.LastSubsystemRuns.RDE)
+		if strings.HasPrefix(vcdCluster.Status.InfraId, NoRdePrefix) {
+			conditions.Delete(vcdCluster, RDEReadyCondition)
+		} else {
+			conditions.MarkTrue(vcdCluster, RDEReadyCondition)
+		}
 	}
 
 	// Update the vcdCluster resource with updated information
 	// TODO Check if updating ovdcNetwork, Org and Vdc should be done somewhere earlier in the code.
 	vcdCluster.Status.Ready = true
 	conditions.MarkTrue(vcdCluster, LoadBalancerAvailableCondition)
+	r.reconcileAudit(ctx, vcdClient, vcdCluster)
 	if cluster.Status.ControlPlaneReady {
 		capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
 		capvcdRdeManager.AddToEventSet(ctx, capisdk.ControlplaneReady, vcdCluster.Status.InfraId,
 			"", "", skipRDEEventUpdates)
 	}
 
-	return ctrl.Result{}, nil
+	result := controlPlaneSizingResult
+	if templateImportsInProgress && result.IsZero() {
+		result = ctrl.Result{RequeueAfter: TemplateImportRequeueInterval}
+	}
+	return result, nil
 }
 
 func (r *VCDClusterReconciler) deleteLB(ctx context.Context, vcdClient *vcdsdk.Client, vcdCluster *infrav1beta3.VCDCluster,
@@ -1192,22 +1734,33 @@ func (r *VCDClusterReconciler) deleteLB(ctx context.Context, vcdClient *vcdsdk.C
 	if vcdCluster.Spec.LoadBalancerConfigSpec.UseOneArm {
 		oneArm = &OneArmDefault
 	}
-	resourcesAllocated := &vcdsdkutil.AllocatedResourcesMap{}
-	_, err = gateway.DeleteLoadBalancer(ctx, virtualServiceNamePrefix, lbPoolNamePrefix,
-		[]vcdsdk.PortDetails{
-			{
-				Protocol:     "TCP",
-				PortSuffix:   "tcp",
-				ExternalPort: int32(controlPlanePort),
-				InternalPort: int32(controlPlanePort),
-			},
-		}, oneArm, resourcesAllocated)
-	if err != nil {
+
+	// the DNAT rule of the control plane endpoint in the DNAT mode is deleted with its app port profile
+	if !isControlPlaneEndpointDNAT(vcdCluster) {
+		resourcesAllocated := &vcdsdkutil.AllocatedResourcesMap{}
+		_, err = gateway.DeleteLoadBalancer(ctx, virtualServiceNamePrefix, lbPoolNamePrefix,
+			[]vcdsdk.PortDetails{
+				{
+					Protocol:     "TCP",
+					PortSuffix:   "tcp",
+					ExternalPort: int32(controlPlanePort),
+					InternalPort: int32(controlPlanePort),
+				},
+			}, oneArm, resourcesAllocated)
+		if err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.LoadBalancerError, "", virtualServiceNamePrefix,
+				fmt.Sprintf("%v", err))
+			return errors.Wrapf(err,
+				"Error occurred during cluster [%s] deletion; unable to delete the load balancer [%s]: [%v]",
+				vcdCluster.Name, virtualServiceNamePrefix, err)
+		}
+	}
+	if err = deleteAppPortProfiles(ctx, vcdClient, gateway, vcdCluster, virtualServiceNamePrefix); err != nil {
 		capvcdRdeManager.AddToErrorSet(ctx, capisdk.LoadBalancerError, "", virtualServiceNamePrefix,
 			fmt.Sprintf("%v", err))
 		return errors.Wrapf(err,
-			"Error occurred during cluster [%s] deletion; unable to delete the load balancer [%s]: [%v]",
-			vcdCluster.Name, virtualServiceNamePrefix, err)
+			"Error occurred during cluster [%s] deletion; unable to delete the app port profiles of load balancer [%s]",
+			vcdCluster.Name, virtualServiceNamePrefix)
 	}
 	log.Info("Deleted the load balancer components (virtual service, lb pool, dnat rule) of the cluster",
 		"virtual service", virtualServiceNamePrefix, "lb pool", lbPoolNamePrefix)
@@ -1302,11 +1855,13 @@ func (r *VCDClusterReconciler) reconcileDeleteSingleVApp(ctx context.Context, ov
 		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDClusterVappDeleteError, "", vcdCluster.Name, fmt.Sprintf(
 			"Error occurred during cluster deletion; %d VMs detected in the vApp %s",
 			len(vApp.VApp.Children.VM), vcdCluster.Name))
+		r.recordEvent(vcdCluster, corev1.EventTypeWarning, DeletionBlockedReason,
+			fmt.Sprintf("vApp [%s] still has %d VMs", vAppName, len(vApp.VApp.Children.VM)))
 		return ctrl.Result{}, errors.Errorf(
 			"Error occurred during cluster deletion; %d VMs detected in the vApp %s",
 			len(vApp.VApp.Children.VM), vcdCluster.Name)
 	} else {
-		log.Info("Deleting vApp of the cluster", "vAppName", vcdCluster.Name)
+		log.Info("Deleting vApp of the cluster", "vAppName", vAppName)
 		err = vdcManager.DeleteVApp(vAppName)
 		if err != nil {
 			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDClusterVappDeleteError,
@@ -1320,7 +1875,7 @@ func (r *VCDClusterReconciler) reconcileDeleteSingleVApp(ctx context.Context, ov
 	// Remove vapp from VCDResourceSet in the RDE
 	rdeManager := vcdsdk.NewRDEManager(vcdClient, vcdCluster.Status.InfraId,
 		capisdk.StatusComponentNameCAPVCD, release.Version)
-	err = rdeManager.RemoveFromVCDResourceSet(ctx, vcdsdk.ComponentCAPVCD, VCDResourceVApp, vcdCluster.Name)
+	err = rdeManager.RemoveFromVCDResourceSet(ctx, vcdsdk.ComponentCAPVCD, VCDResourceVApp, vAppName)
 	if err != nil {
 		log.Error(
 			fmt.Errorf("failed to remove VCD resource [%s] from VCD resource set of RDE [%s]: [%v]",
@@ -1328,7 +1883,7 @@ func (r *VCDClusterReconciler) reconcileDeleteSingleVApp(ctx context.Context, ov
 			"error occurred while removing VCD resource from VCD resource set in RDE")
 		capvcdRdeManager.AddToErrorSet(ctx, capisdk.RdeError, "", vcdCluster.Name,
 			fmt.Sprintf("failed to delete VCD Resource [%s] of type [%s] from VCDResourceSet of RDE [%s]: [%v]",
-				vcdCluster.Name, VCDResourceVApp, vcdCluster.Status.InfraId, err))
+				vAppName, VCDResourceVApp, vcdCluster.Status.InfraId, err))
 	}
 	if err = capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD, capisdk.RdeError, "", vcdCluster.Name); err != nil {
 		log.Error(err, "failed to remove RdeError from RDE", "rdeID", vcdCluster.Status.InfraId)
@@ -1354,20 +1909,32 @@ func (r *VCDClusterReconciler) reconcileDeleteVApps(ctx context.Context,
 	}
 
 	capvcdRDEManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
+	vAppName := CreateFullVAppName(vcdCluster)
 
-	result, err := r.reconcileDeleteSingleVApp(ctx, vcdCluster.Spec.Ovdc, vcdCluster.Name,
+	// the VMs kept with a snapshot after an upgrade are deleted with the cluster
+	if err := deleteRetainedVMs(ctx, vcdClient, vcdCluster, true); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to delete the VMs kept with a snapshot in vApp [%s]",
+			vAppName)
+	}
+
+	// the vApps of the vApp strategy of the cluster are deleted before the vApp of the cluster
+	if result, err := r.reconcileDeleteStrategyVApps(ctx, vcdClient, vcdCluster); err != nil {
+		return result, err
+	}
+
+	result, err := r.reconcileDeleteSingleVApp(ctx, getOvdcName(vcdCluster), vAppName,
 		vcdClient, capvcdRDEManager, vcdCluster)
 	if err != nil {
 		// this is potentially an irrecoverable FATAL error
 		log.Error(err, "unable to delete single vApp",
-			"orgName", vcdClient.ClusterOrgName, "ovdcName", vcdCluster.Spec.Ovdc,
-			"vAppName", vcdCluster.Name)
+			"orgName", vcdClient.ClusterOrgName, "ovdcName", getOvdcName(vcdCluster),
+			"vAppName", vAppName)
 		return result, errors.Wrapf(err,
-			"unable to get delete single vApp [%s] in Org [%s], OVDC [%s]", vcdCluster.Name,
-			vcdClient.ClusterOrgName, vcdCluster.Spec.Ovdc)
+			"unable to get delete single vApp [%s] in Org [%s], OVDC [%s]", vAppName,
+			vcdClient.ClusterOrgName, getOvdcName(vcdCluster))
 	}
-	log.Info("Successfully deleted vApp", "vAppName", vcdCluster.Name,
-		"org", vcdClient.ClusterOrgName, "ovdc", vcdCluster.Spec.Ovdc)
+	log.Info("Successfully deleted vApp", "vAppName", vAppName,
+		"org", vcdClient.ClusterOrgName, "ovdc", getOvdcName(vcdCluster))
 	return ctrl.Result{}, nil
 }
 
@@ -1400,11 +1967,11 @@ func (r *VCDClusterReconciler) reconcileDeleteRDE(ctx context.Context, vcdClient
 			})
 		if err != nil {
 			capvcdRdeManager.AddToErrorSet(ctx, capisdk.RdeError, "", "", fmt.Sprintf("failed to get RDE [%s]: %v", vcdCluster.Status.InfraId, err))
-			return errors.Wrapf(err, "Error occurred during RDE deletion; failed to fetch defined entities by entity type [%s] and ID [%s] for cluster [%s]", CAPVCDEntityTypeID, vcdCluster.Status.InfraId, vcdCluster.Name)
+			return errors.Wrapf(err, "Error occurred during RDE deletion; failed to fetch defined entities by entity type [%s] and ID [%s] for cluster [%s]", capisdk.GetCAPVCDEntityTypeID(), vcdCluster.Status.InfraId, vcdCluster.Name)
 		}
 		if resp != nil && resp.StatusCode != http.StatusOK {
 			capvcdRdeManager.AddToErrorSet(ctx, capisdk.RdeError, "", "", fmt.Sprintf("Got wrong status code while fetching RDE [%s]: %v", vcdCluster.Status.InfraId, err))
-			return errors.Errorf("Error occurred during RDE deletion; error while fetching defined entities by entity type [%s] and ID [%s] for cluster [%s]", CAPVCDEntityTypeID, vcdCluster.Status.InfraId, vcdCluster.Name)
+			return errors.Errorf("Error occurred during RDE deletion; error while fetching defined entities by entity type [%s] and ID [%s] for cluster [%s]", capisdk.GetCAPVCDEntityTypeID(), vcdCluster.Status.InfraId, vcdCluster.Name)
 		}
 		if len(definedEntities.Values) > 0 {
 			// resolve defined entity before deleting
@@ -1449,7 +2016,7 @@ func (r *VCDClusterReconciler) reconcileDelete(ctx context.Context,
 
 	// restore vcdCluster status
 	vcdCluster.Status.VAppMetadataUpdated = true
-	if err := patchVCDCluster(ctx, patchHelper, vcdCluster); err != nil {
+	if err := patchVCDCluster(ctx, patchHelper, vcdCluster, false, nil); err != nil {
 		return ctrl.Result{}, errors.Wrap(err, "Error occurred during cluster deletion; failed to patch VCDCluster")
 	}
 
@@ -1474,17 +2041,76 @@ func (r *VCDClusterReconciler) reconcileDelete(ctx context.Context,
 		return ctrl.Result{}, errors.Wrapf(err, "Error updating vcdResource into vcdcluster.status to reconcile Cluster [%s] infrastructure", vcdCluster.Name)
 	}
 
-	ovdcName := vcdCluster.Spec.Ovdc
-	ovdcNetworkName := vcdCluster.Spec.OvdcNetwork
+	// the VCD resources of an externally managed cluster are deleted by the external system; only the RDE created by
+	// CAPVCD is deleted
+	if annotations.IsExternallyManaged(vcdCluster) {
+		if deleteErr := r.reconcileDeleteRDE(ctx, vcdClient, vcdCluster); deleteErr != nil {
+			return ctrl.Result{}, errors.Wrapf(deleteErr, "error occurred during deleting RDE for the cluster [%s]",
+				vcdCluster.Status.InfraId)
+		}
+		log.Info("Skipped the deletion of the externally managed infra resources of the cluster")
+		untrackRDEFreshness(vcdCluster)
+		untrackClusterOvdc(vcdCluster)
+		recordClusterDeletionCompleted(vcdCluster)
+		controllerutil.RemoveFinalizer(vcdCluster, infrav1beta3.ClusterFinalizer)
+		return ctrl.Result{}, nil
+	}
+
+	// never delete VCD resources claimed by another management cluster
+	if err = r.reconcileOwnershipClaim(ctx, vcdCluster, vcdClient); err != nil {
+		var claimedErr *OwnershipClaimedError
+		if errors.As(err, &claimedErr) {
+			conditions.MarkFalse(vcdCluster, OwnershipClaimVerifiedCondition, ClaimedByOtherManagementClusterReason,
+				clusterv1.ConditionSeverityError, err.Error())
+			r.recordEvent(vcdCluster, corev1.EventTypeWarning, DeletionBlockedReason, err.Error())
+		}
+		return ctrl.Result{}, errors.Wrapf(err, "Error occurred during cluster deletion; unable to verify ownership of cluster [%s]",
+			vcdCluster.Name)
+	}
+
+	ovdcName := getOvdcName(vcdCluster)
+	ovdcNetworkName := getOvdcNetworkName(vcdCluster)
 	controlPlaneHost := vcdCluster.Spec.ControlPlaneEndpoint.Host
 	controlPlanePort := vcdCluster.Spec.ControlPlaneEndpoint.Port
 	if controlPlanePort == 0 {
 		controlPlanePort = TcpPort
 	}
-	if err = r.deleteLB(ctx, vcdClient, vcdCluster, ovdcNetworkName, ovdcName, controlPlanePort); err != nil {
-		return ctrl.Result{}, errors.Wrapf(err,
-			"unable to delete LB with control plane host [%s], port[%d] in ovdc [%s] and network [%s]: [%v]",
-			controlPlaneHost, controlPlanePort, ovdcName, ovdcNetworkName, err)
+	// the load balancer supplied by the user in the passthrough mode is left untouched, and there is no load balancer
+	// in the routed and kube-vip modes
+	if !isControlPlaneEndpointPassthrough(vcdCluster) && !isControlPlaneEndpointRouted(vcdCluster) &&
+		!isControlPlaneEndpointKubeVip(vcdCluster) {
+		if err = r.deleteLB(ctx, vcdClient, vcdCluster, ovdcNetworkName, ovdcName, controlPlanePort); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err,
+				"unable to delete LB with control plane host [%s], port[%d] in ovdc [%s] and network [%s]: [%v]",
+				controlPlaneHost, controlPlanePort, ovdcName, ovdcNetworkName, err)
+		}
+	}
+
+	// the egress allowlist was not programmed if the user lacks the rights to configure the gateway firewall
+	userRights, _ := getUserRights(vcdClient)
+	if vcdCluster.Spec.EgressAllowlist.ProgramEdgeGateway && userRights.isFeatureEnabled(OptionalFeatureEdgeFirewall) {
+		if err = deleteEgressFirewall(ctx, vcdClient, vcdCluster); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err,
+				"error occurred during cluster deletion; failed to remove egress allowlist of cluster [%s]",
+				vcdCluster.Name)
+		}
+	}
+
+	hasFirewallRules := len(vcdCluster.Spec.FirewallRules) > 0 || len(vcdCluster.Status.FirewallRules) > 0
+	if hasFirewallRules && userRights.isFeatureEnabled(OptionalFeatureEdgeFirewall) {
+		if err = deleteClusterFirewallRules(ctx, vcdClient, vcdCluster); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err,
+				"error occurred during cluster deletion; failed to remove firewall rules of cluster [%s]",
+				vcdCluster.Name)
+		}
+	}
+
+	if vcdCluster.Spec.EgressConfig != nil || vcdCluster.Status.EgressSNATIP != "" {
+		if err = deleteEgressSNATRule(ctx, vcdClient, vcdCluster); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err,
+				"error occurred during cluster deletion; failed to remove egress SNAT rule of cluster [%s]",
+				vcdCluster.Name)
+		}
 	}
 
 	// Delete vApp
@@ -1495,6 +2121,18 @@ func (r *VCDClusterReconciler) reconcileDelete(ctx context.Context,
 			vcdCluster.Name)
 	}
 
+	// the virtual IP of the kube-vip mode is returned to the IP pool once no VM announces it anymore
+	if err = r.releaseKubeVipControlPlaneVIP(ctx, vcdCluster, vcdClient); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "error occurred during cluster deletion; failed to release the "+
+			"control plane endpoint of cluster [%s]", vcdCluster.Name)
+	}
+
+	// the OVDC networks created for the cluster are deleted once no vApp is connected to them
+	if err = deleteManagedNetworks(ctx, vcdClient, vcdCluster); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "error occurred during cluster deletion; failed to delete the "+
+			"OVDC network of cluster [%s]", vcdCluster.Name)
+	}
+
 	// Delete RDE
 	if deleteErr := r.reconcileDeleteRDE(ctx, vcdClient, vcdCluster); deleteErr != nil {
 		log.Error(err, "Error occurred while deleting RDE: [%v]", deleteErr)
@@ -1503,6 +2141,9 @@ func (r *VCDClusterReconciler) reconcileDelete(ctx context.Context,
 	}
 
 	log.Info("Successfully deleted all the infra resources of the cluster")
+	untrackRDEFreshness(vcdCluster)
+	untrackClusterOvdc(vcdCluster)
+	recordClusterDeletionCompleted(vcdCluster)
 	// Cluster is deleted so remove the finalizer.
 	controllerutil.RemoveFinalizer(vcdCluster, infrav1beta3.ClusterFinalizer)
 
@@ -1510,9 +2151,34 @@ func (r *VCDClusterReconciler) reconcileDelete(ctx context.Context,
 }
 
 // SetupWithManager sets up the controller with the Manager.
-func (r *VCDClusterReconciler) SetupWithManager(mgr ctrl.Manager, options controller.Options) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&infrav1beta3.VCDCluster{}).
+func (r *VCDClusterReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager,
+	options controller.Options) error {
+	c, err := ctrl.NewControllerManagedBy(mgr).
+		For(&infrav1beta3.VCDCluster{}, builder.WithPredicates(ignoreSubsystemRunUpdates())).
 		WithOptions(options).
-		Complete(r)
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(r.SecretToVCDClusters),
+		).
+		Build(r)
+	if err != nil {
+		return err
+	}
+	// keep the machine counts and version of the status of the VCDClusters up to date
+	for _, kind := range []client.Object{&kcpv1.KubeadmControlPlane{}, &clusterv1.MachineDeployment{}} {
+		if err = c.Watch(
+			&source.Kind{Type: kind},
+			handler.EnqueueRequestsFromMapFunc(r.ClusterMachinesToVCDCluster),
+			replicasOrVersionChanged(),
+		); err != nil {
+			return err
+		}
+	}
+	// a paused cluster is not requeued; resume its reconciliation as soon as the Cluster is unpaused
+	return c.Watch(
+		&source.Kind{Type: &clusterv1.Cluster{}},
+		handler.EnqueueRequestsFromMapFunc(util.ClusterToInfrastructureMapFunc(ctx,
+			infrav1beta3.GroupVersion.WithKind("VCDCluster"), mgr.GetClient(), &infrav1beta3.VCDCluster{})),
+		predicates.ClusterUnpaused(ctrl.LoggerFrom(ctx)),
+	)
 }
@@ -9,19 +9,17 @@ import (
 	"bytes"
 	"context"
 	_ "embed" // this needs go 1.16+
-	b64 "encoding/base64"
+	"encoding/base64"
 	"fmt"
 	"math"
 	"reflect"
 	"sigs.k8s.io/yaml"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
-	"github.com/Masterminds/sprig/v3"
-	"github.com/go-logr/logr"
-
 	"github.com/pkg/errors"
 	cpiutil "github.com/vmware/cloud-provider-for-cloud-director/pkg/util"
 	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
@@ -32,6 +30,8 @@ import (
 	"github.com/vmware/go-vcloud-director/v2/types/v56"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util"
@@ -40,6 +40,7 @@ import (
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/cluster-api/util/predicates"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -48,23 +49,40 @@ import (
 )
 
 type CloudInitScriptInput struct {
-	ControlPlane        bool   // control plane node
-	NvidiaGPU           bool   // configure containerd for NVIDIA libraries
-	BootstrapRunCmd     string // bootstrap run command
-	HTTPProxy           string // httpProxy endpoint
-	HTTPSProxy          string // httpsProxy endpoint
-	NoProxy             string // no proxy values
-	MachineName         string // vm host name
-	ResizedControlPlane bool   // resized node type: worker | control_plane
-	VcdHostFormatted    string // vcd host
-	TKGVersion          string // tkgVersion
-	ClusterID           string //cluster id
+	ControlPlane         bool     // control plane node
+	NvidiaGPU            bool     // configure containerd for NVIDIA libraries
+	BootstrapRunCmd      string   // bootstrap run command
+	HTTPProxy            string   // httpProxy endpoint
+	HTTPSProxy           string   // httpsProxy endpoint
+	NoProxy              string   // no proxy values
+	MachineName          string   // vm host name
+	ResizedControlPlane  bool     // resized node type: worker | control_plane
+	VcdHostFormatted     string   // vcd host
+	TKGVersion           string   // tkgVersion
+	ClusterID            string   //cluster id
+	KubeletExtraArgs     string   // kubelet arguments taking precedence over the bootstrap ones
+	ControlPlaneEndpoint string   // host:port of the control plane endpoint checked from the guest, if any
+	TrustBundle          string   // base64 encoded PEM certificates trusted by the node, if any
+	PrePullImages        []string // images pulled before the node joins the cluster, if any
+	KubeVipManifest      string   // base64 encoded static pod manifest of kube-vip on control plane nodes, if any
+	PreKubeadmCommands   string   // base64 encoded script run before the kubeadm commands, if any
+	PostKubeadmCommands  string   // base64 encoded script run after the kubeadm commands, if any
+	SSHAuthorizedKeys    []string // SSH public keys authorized for root and the emergency user, if any
+	EmergencyUser        string   // name of the emergency user, if any
+	EmergencyUserPasswd  string   // crypt hash of the password of the emergency user, if any
 }
 
 const (
 	VcdResourceTypeVM = "virtual-machine"
 )
 
+// IP allocation modes of the NICs of a VM
+const (
+	IPAllocationModePool   = "POOL"
+	IPAllocationModeDHCP   = "DHCP"
+	IPAllocationModeManual = "MANUAL"
+)
+
 const Mebibyte = 1048576
 
 // The following `embed` directives read the file in the mentioned path and copy the content into the declared variable.
@@ -76,6 +94,9 @@ var cloudInitScriptTemplate string
 // VCDMachineReconciler reconciles a VCDMachine object
 type VCDMachineReconciler struct {
 	client.Client
+	Recorder record.EventRecorder
+	// Shards splits the clusters between the active replicas of the manager; all the clusters are reconciled if nil.
+	Shards *ClusterShardManager
 }
 
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vcdmachines,verbs=get;list;watch;create;update;patch;delete
@@ -88,6 +109,7 @@ func (r *VCDMachineReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	vcdMachine := &infrav1beta3.VCDMachine{}
 	if err := r.Client.Get(ctx, req.NamespacedName, vcdMachine); err != nil {
 		if apierrors.IsNotFound(err) {
+			untrackMachinePhase(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, err
@@ -116,11 +138,14 @@ func (r *VCDMachineReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 
 	log = log.WithValues("cluster", cluster.Name)
-
-	// Return early if the object or Cluster is paused.
-	if annotations.IsPaused(cluster, vcdMachine) {
-		log.Info("Reconciliation is paused for this object")
-		return ctrl.Result{}, nil
+	claimed, err := r.Shards.ClaimCluster(ctx, cluster.Namespace, cluster.Name, cluster)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Error claiming Cluster [%s]", cluster.Name)
+	}
+	if !claimed {
+		log.V(4).Info("Skipping machine of a cluster owned by another replica")
+		cancelClusterVMCreations(cluster.Namespace, cluster.Name)
+		return ctrl.Result{RequeueAfter: r.Shards.GetRequeueAfter()}, nil
 	}
 
 	machineBeingDeleted := !vcdMachine.ObjectMeta.DeletionTimestamp.IsZero()
@@ -146,15 +171,23 @@ func (r *VCDMachineReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 	// Always attempt to Patch the VCDMachine object and status after each reconciliation.
+	paused := annotations.IsPaused(cluster, vcdMachine)
 	defer func() {
-		if err := patchVCDMachine(ctx, patchHelper, vcdMachine); err != nil {
+		if err := patchVCDMachine(ctx, patchHelper, vcdMachine, paused, rerr); err != nil {
 			log.Error(err, "Failed to patch VCDMachine")
 			if rerr == nil {
 				rerr = err
 			}
 		}
+		trackMachinePhase(cluster.Name, vcdMachine, rerr)
 	}()
 
+	// Return early if the object or Cluster is paused.
+	if paused {
+		log.Info("Reconciliation is paused for this object")
+		return ctrl.Result{}, nil
+	}
+
 	// Add finalizer first if not exist to avoid the race condition between init and delete
 	if !controllerutil.ContainsFinalizer(vcdMachine, infrav1beta3.MachineFinalizer) {
 		controllerutil.AddFinalizer(vcdMachine, infrav1beta3.MachineFinalizer)
@@ -172,6 +205,36 @@ func (r *VCDMachineReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	// Handle deleted machines
 	if machineBeingDeleted {
+		// the machines are deleted along with their cluster before the VCDCluster; a scale down or a rollout of a
+		// protected cluster still deletes the machines it replaces
+		if isClusterBeingDeleted(cluster, vcdCluster) && isDeletionProtected(cluster, vcdCluster) {
+			log.Info("Deletion of the machine is blocked by the deletion protection annotation of the cluster",
+				"annotation", DeletionProtectedAnnotation)
+			message := fmt.Sprintf(
+				"cluster is protected from deletion; remove the annotation [%s] of the cluster to delete the VM",
+				DeletionProtectedAnnotation)
+			markDeletionBlocked(vcdMachine, clusterv1.ConditionSeverityInfo, DeleteProtectedReason, message)
+			r.recordEvent(vcdMachine, corev1.EventTypeWarning, DeletionBlockedReason, message)
+			return ctrl.Result{RequeueAfter: DeletionProtectedRequeueInterval}, nil
+		}
+		if isMachineDeleteProtected(vcdMachine) {
+			message := fmt.Sprintf("VM is protected from deletion; remove the annotation [%s] to delete it",
+				VCDMachineDeleteProtectionAnnotation)
+			if markDeletionBlocked(vcdMachine, clusterv1.ConditionSeverityInfo, DeleteProtectedReason,
+				message) {
+				log.Info("Deletion of the machine is blocked by its deletion protection annotation",
+					"annotation", VCDMachineDeleteProtectionAnnotation)
+				r.recordEvent(vcdMachine, corev1.EventTypeWarning, DeletionBlockedReason, message)
+			}
+			return ctrl.Result{RequeueAfter: DeletionProtectedRequeueInterval}, nil
+		}
+		// the owners of the pre-terminate hooks of the Machine act on the node before its VM goes away
+		if hasPreTerminateHooks(machine) {
+			log.Info("Waiting for the pre-terminate hooks of the machine to be removed")
+			conditions.MarkFalse(vcdMachine, ContainerProvisionedCondition, WaitingForPreTerminateHooksReason,
+				clusterv1.ConditionSeverityInfo, "waiting for the pre-terminate hooks of the Machine to be removed")
+			return ctrl.Result{RequeueAfter: PreTerminateHookRequeueInterval}, nil
+		}
 		return r.reconcileDelete(ctx, machine, vcdMachine, vcdCluster)
 	}
 
@@ -179,22 +242,39 @@ func (r *VCDMachineReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	return r.reconcileNormal(ctx, cluster, machine, vcdMachine, vcdCluster)
 }
 
-func patchVCDMachine(ctx context.Context, patchHelper *patch.Helper, vcdMachine *infrav1beta3.VCDMachine) error {
+// patchVCDMachine patches the VCDMachine after summarizing its conditions into the Ready condition. paused and
+// reconcileErr describe the outcome of the reconciliation and refine the Ready condition of a paused or degraded machine.
+func patchVCDMachine(ctx context.Context, patchHelper *patch.Helper, vcdMachine *infrav1beta3.VCDMachine,
+	paused bool, reconcileErr error) error {
 	conditions.SetSummary(vcdMachine,
 		conditions.WithConditions(
+			VAppReadyCondition,
+			VMProvisionedCondition,
 			ContainerProvisionedCondition,
+			BootstrapDeliveredCondition,
 			BootstrapExecSucceededCondition,
 		),
 		conditions.WithStepCounterIf(vcdMachine.ObjectMeta.DeletionTimestamp.IsZero()),
 	)
+	vcdMachine.Status.Ready = setReadiness(vcdMachine, vcdMachine.Status.Ready, paused, reconcileErr)
 
 	return patchHelper.Patch(
 		ctx,
 		vcdMachine,
 		patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{
 			clusterv1.ReadyCondition,
+			VAppReadyCondition,
+			VMProvisionedCondition,
 			ContainerProvisionedCondition,
+			BootstrapDeliveredCondition,
 			BootstrapExecSucceededCondition,
+			OvdcEnabledCondition,
+			ControlPlaneEndpointReachableCondition,
+			ProviderApprovedCondition,
+			OvdcInServiceCondition,
+			VMHardwareScaledCondition,
+			BootDiskBusTypeAppliedCondition,
+			DeletionAllowedCondition,
 		}},
 	)
 }
@@ -207,6 +287,9 @@ const (
 	KubeadmNodeJoin                        = "guestinfo.postcustomization.kubeadm.node.join.status"
 	PostCustomizationScriptExecutionStatus = "guestinfo.post_customization_script_execution_status"
 	PostCustomizationScriptFailureReason   = "guestinfo.post_customization_script_execution_failure_reason"
+	ControlPlaneEndpointCheck              = "guestinfo.postcustomization.controlplaneendpoint.check.status"
+	PreKubeadmCommands                     = "guestinfo.postcustomization.prekubeadmcommands.status"
+	PostKubeadmCommands                    = "guestinfo.postcustomization.postkubeadmcommands.status"
 )
 
 var postCustPhases = []string{
@@ -236,7 +319,7 @@ func strInSlice(findStr string, arr []string) bool {
 const phaseSecondTimeout = 600
 
 func (r *VCDMachineReconciler) waitForPostCustomizationPhase(ctx context.Context,
-	vcdClient *vcdsdk.Client, vm *govcd.VM, phase string) error {
+	vcdClient *vcdsdk.Client, vm *govcd.VM, phase string, deadline time.Time) error {
 	log := ctrl.LoggerFrom(ctx)
 
 	startTime := time.Now()
@@ -293,6 +376,11 @@ func (r *VCDMachineReconciler) waitForPostCustomizationPhase(ctx context.Context
 			}
 		}
 
+		// the bootstrap timeout of the machine is handled by the next reconciliation
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return fmt.Errorf("bootstrap of VM [%s] timed out while waiting for postcustomization status [%s]",
+				vm.VM.Name, phase)
+		}
 		if seconds := int(time.Since(startTime) / time.Second); seconds > phaseSecondTimeout {
 			return fmt.Errorf("time for postcustomization status [%s] exceeded timeout [%d]",
 				phase, phaseSecondTimeout)
@@ -333,7 +421,7 @@ func (r *VCDMachineReconciler) reconcileCloudInitScript(ctx context.Context, vcd
 	log := ctrl.LoggerFrom(ctx, "cluster", vcdCluster.Name, "machine", machine.Name, "vAppName", vAppName)
 	capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
 
-	bootstrapJinjaScript, err := r.getBootstrapData(ctx, machine)
+	bootstrapJinjaScript, err := r.getBootstrapData(ctx, machine, vcdMachine)
 	if err != nil {
 		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineScriptGenerationError, "", machine.Name, fmt.Sprintf("%v", err))
 
@@ -352,12 +440,18 @@ func (r *VCDMachineReconciler) reconcileCloudInitScript(ctx context.Context, vcd
 	// Hence we are checking if it contains the control plane label and has kubeadm join in the script
 	isResizedControlPlane := util.IsControlPlaneMachine(machine) && strings.Contains(bootstrapJinjaScript, "kubeadm join")
 
+	// an Ignition config is passed to the VM as is
+	if isIgnitionBootstrap(vcdMachine) {
+		capvcdRdeManager.AddToEventSet(ctx, capisdk.CloudInitScriptGenerated, "", machine.Name, "", skipRDEEventUpdates)
+		return []byte(bootstrapJinjaScript), isInitialControlPlane, isResizedControlPlane, nil
+	}
+
 	// Construct a CloudInitScriptInput struct to pass into template.Execute() function to generate the necessary
 	// cloud init script for the relevant node type, i.e. control plane or worker node
 	cloudInitInput := CloudInitScriptInput{
 		HTTPProxy:           vcdCluster.Spec.ProxyConfigSpec.HTTPProxy,
 		HTTPSProxy:          vcdCluster.Spec.ProxyConfigSpec.HTTPSProxy,
-		NoProxy:             vcdCluster.Spec.ProxyConfigSpec.NoProxy,
+		NoProxy:             getNoProxy(cluster, vcdCluster),
 		MachineName:         vmName,
 		VcdHostFormatted:    strings.ReplaceAll(vcdCluster.Spec.Site, "/", "\\/"),
 		NvidiaGPU:           false,
@@ -368,6 +462,67 @@ func (r *VCDMachineReconciler) reconcileCloudInitScript(ctx context.Context, vcd
 	if !vcdMachine.Spec.Bootstrapped && isInitialControlPlane {
 		cloudInitInput.ControlPlane = true
 	}
+	cloudInitInput.KubeletExtraArgs = getKubeletExtraArgs(vcdMachine.Spec.KubeletConfig)
+	if vcdCluster.Spec.VerifyControlPlaneEndpoint {
+		cloudInitInput.ControlPlaneEndpoint = fmt.Sprintf("%s:%d", vcdCluster.Spec.ControlPlaneEndpoint.Host,
+			vcdCluster.Spec.ControlPlaneEndpoint.Port)
+	}
+	if isKubeVipRequired(vcdCluster) && util.IsControlPlaneMachine(machine) {
+		kubeVipManifest, err := getKubeVipManifest(vcdCluster)
+		if err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineScriptGenerationError, "", machine.Name,
+				fmt.Sprintf("%v", err))
+			return nil, isInitialControlPlane, isResizedControlPlane, errors.Wrapf(err,
+				"Error generating the kube-vip manifest of cluster [%s] for [%s/%s]", vcdCluster.Name, vAppName,
+				machine.Name)
+		}
+		cloudInitInput.KubeVipManifest = base64.StdEncoding.EncodeToString(kubeVipManifest)
+	}
+
+	for _, image := range vcdMachine.Spec.PrePullImages {
+		cloudInitInput.PrePullImages = append(cloudInitInput.PrePullImages, string(image))
+	}
+
+	cloudInitInput.SSHAuthorizedKeys = vcdMachine.Spec.SSHAuthorizedKeys
+	cloudInitInput.EmergencyUser = getEmergencyUserName(vcdMachine)
+	cloudInitInput.EmergencyUserPasswd, err = getEmergencyUserPasswordHash(ctx, r.Client, vcdMachine)
+	if err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineScriptGenerationError, "", machine.Name, fmt.Sprintf("%v", err))
+
+		return nil, isInitialControlPlane, isResizedControlPlane, errors.Wrapf(err,
+			"Error getting the password of the emergency user of machine [%s/%s]", vAppName, machine.Name)
+	}
+
+	trustBundle, err := getTrustBundle(ctx, r.Client, vcdCluster)
+	if err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineScriptGenerationError, "", machine.Name, fmt.Sprintf("%v", err))
+
+		return nil, isInitialControlPlane, isResizedControlPlane, errors.Wrapf(err,
+			"Error getting the trust bundle of cluster [%s] for [%s/%s]", vcdCluster.Name, vAppName, machine.Name)
+	}
+	if trustBundle != nil {
+		cloudInitInput.TrustBundle = base64.StdEncoding.EncodeToString(trustBundle)
+	}
+
+	// the hardware specific setup of the machines of the template runs around the kubeadm commands
+	for _, script := range []struct {
+		scriptRef *infrav1beta3.ScriptRef
+		input     *string
+	}{
+		{scriptRef: vcdMachine.Spec.PreKubeadmCommandsScriptRef, input: &cloudInitInput.PreKubeadmCommands},
+		{scriptRef: vcdMachine.Spec.PostKubeadmCommandsScriptRef, input: &cloudInitInput.PostKubeadmCommands},
+	} {
+		scriptBytes, err := getScript(ctx, r.Client, vcdMachine.Namespace, script.scriptRef)
+		if err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineScriptGenerationError, "", machine.Name,
+				fmt.Sprintf("%v", err))
+			return nil, isInitialControlPlane, isResizedControlPlane, errors.Wrapf(err,
+				"Error getting the kubeadm commands scripts of machine [%s/%s]", vAppName, machine.Name)
+		}
+		if scriptBytes != nil {
+			*script.input = base64.StdEncoding.EncodeToString(scriptBytes)
+		}
+	}
 
 	mergedCloudInitBytes, err := MergeJinjaToCloudInitScript(cloudInitInput, bootstrapJinjaScript)
 	if err != nil {
@@ -394,7 +549,7 @@ func (r *VCDMachineReconciler) reconcileCloudInitScript(ctx context.Context, vcd
 
 func (r *VCDMachineReconciler) reconcileVMBoostrap(ctx context.Context, vcdClient *vcdsdk.Client,
 	vdcManager *vcdsdk.VdcManager, vApp *govcd.VApp, vm *govcd.VM, mergedCloudInitBytes []byte,
-	vcdCluster *infrav1beta3.VCDCluster, machine *clusterv1.Machine,
+	vcdCluster *infrav1beta3.VCDCluster, machine *clusterv1.Machine, vcdMachine *infrav1beta3.VCDMachine,
 	isInitialControlPlane, isResizedControlPlane, skipRDEEventUpdates bool) error {
 
 	if vApp == nil || vApp.VApp == nil {
@@ -403,6 +558,98 @@ func (r *VCDMachineReconciler) reconcileVMBoostrap(ctx context.Context, vcdClien
 	log := ctrl.LoggerFrom(ctx, "cluster", vcdCluster.Name, "machine", machine.Name, "vAppName", vApp.VApp.Name)
 	capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
 
+	err := r.reconcileVMPowerOn(ctx, vcdClient, vdcManager, vApp, vm, mergedCloudInitBytes, vcdCluster, machine,
+		vcdMachine)
+	if err != nil {
+		return err
+	}
+	vAppName := vApp.VApp.Name
+	phases := getBootstrapPhases(vcdCluster, vcdMachine, isInitialControlPlane)
+	if hasCloudInitFailedBefore, err := r.hasCloudInitExecutionFailedBefore(vcdClient, vm); hasCloudInitFailedBefore {
+		r.reconcileBootstrapDiagnostics(ctx, vcdClient, vdcManager, vm, vcdMachine, phases, "")
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineScriptExecutionError, "", machine.Name, fmt.Sprintf("%v", err))
+
+		return errors.Wrapf(err, "Error bootstrapping the machine [%s/%s]; machine is probably in unreconciliable state", vAppName, vm.VM.Name)
+	}
+	capvcdRdeManager.AddToEventSet(ctx, capisdk.InfraVmPoweredOn, "", machine.Name, "", skipRDEEventUpdates)
+
+	err = capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD, capisdk.VCDMachineCreationError, "", machine.Name)
+	if err != nil {
+		log.Error(err, "failed to remove VCDMachineCreationError from RDE", "rdeID", vcdCluster.Status.InfraId)
+	}
+
+	// the bootstrapping phases are reported by the cloud-init script of CAPVCD, which is not used with Ignition
+	if isIgnitionBootstrap(vcdMachine) {
+		log.Info("Powered on the machine bootstrapped by Ignition")
+		capvcdRdeManager.AddToEventSet(ctx, capisdk.InfraVmBootstrapped, "", machine.Name, "", skipRDEEventUpdates)
+		return nil
+	}
+
+	for _, phase := range phases {
+		if err = vApp.Refresh(); err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineScriptExecutionError, "", machine.Name, fmt.Sprintf("%v", err))
+
+			return errors.Wrapf(err, "Error while bootstrapping the machine [%s/%s]; unable to refresh vapp",
+				vAppName, vm.VM.Name)
+		}
+		log.Info(fmt.Sprintf("Start: waiting for the bootstrapping phase [%s] to complete", phase))
+		if err = r.waitForPostCustomizationPhase(ctx, vcdClient, vm, phase,
+			getBootstrapDeadline(vcdMachine)); err != nil {
+			log.Error(err, fmt.Sprintf("Error waiting for the bootstrapping phase [%s] to complete", phase))
+			if phase == ControlPlaneEndpointCheck {
+				conditions.MarkFalse(vcdMachine, ControlPlaneEndpointReachableCondition,
+					ControlPlaneEndpointUnreachableReason, clusterv1.ConditionSeverityWarning,
+					"API server not reachable from VM [%s] through control plane endpoint [%s:%d]: [%v]", vm.VM.Name,
+					vcdCluster.Spec.ControlPlaneEndpoint.Host, vcdCluster.Spec.ControlPlaneEndpoint.Port, err)
+			}
+			r.reconcileBootstrapDiagnostics(ctx, vcdClient, vdcManager, vm, vcdMachine, phases, phase)
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineScriptExecutionError, "", machine.Name, fmt.Sprintf("%v", err))
+
+			return errors.Wrapf(err, "Error while bootstrapping the machine [%s/%s]; unable to wait for post customization phase [%s]",
+				vAppName, vm.VM.Name, phase)
+		}
+		if phase == ControlPlaneEndpointCheck {
+			conditions.MarkTrue(vcdMachine, ControlPlaneEndpointReachableCondition)
+		}
+		log.Info(fmt.Sprintf("End: waiting for the bootstrapping phase [%s] to complete", phase))
+	}
+
+	err = capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD, capisdk.VCDMachineScriptExecutionError, "", "")
+	if err != nil {
+		log.Error(err, "failed to remove VCDMachineScriptExecutionError from RDE", "rdeID", vcdCluster.Status.InfraId)
+	}
+
+	log.Info("Successfully bootstrapped the machine")
+	vcdMachine.Status.BootstrapDiagnostics = nil
+	capvcdRdeManager.AddToEventSet(ctx, capisdk.InfraVmBootstrapped, "", machine.Name, "", skipRDEEventUpdates)
+
+	if err = vm.Refresh(); err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name, fmt.Sprintf("Unable to refresh vm: %v", err))
+
+		return errors.Wrapf(err, "Unexpected error after the machine [%s/%s] is bootstrapped; unable to refresh vm", vAppName, vm.VM.Name)
+	}
+	if err = vApp.Refresh(); err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name, fmt.Sprintf("Unable to refresh vApp: %v", err))
+
+		return errors.Wrapf(err, "Unexpected error after the machine [%s/%s] is bootstrapped; unable to refresh vapp", vAppName, vm.VM.Name)
+	}
+
+	err = capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD, capisdk.VCDMachineCreationError, "", machine.Name)
+	if err != nil {
+		log.Error(err, "failed to remove VCDMachineCreationError from RDE", "rdeID", vcdCluster.Status.InfraId)
+	}
+	return nil
+}
+
+// reconcileVMPowerOn passes the bootstrap data to the VM through its guestinfo properties and powers it on, unless it
+// is already powered on.
+func (r *VCDMachineReconciler) reconcileVMPowerOn(ctx context.Context, vcdClient *vcdsdk.Client,
+	vdcManager *vcdsdk.VdcManager, vApp *govcd.VApp, vm *govcd.VM, mergedCloudInitBytes []byte,
+	vcdCluster *infrav1beta3.VCDCluster, machine *clusterv1.Machine, vcdMachine *infrav1beta3.VCDMachine) error {
+
+	log := ctrl.LoggerFrom(ctx, "cluster", vcdCluster.Name, "machine", machine.Name, "vAppName", vApp.VApp.Name)
+	capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
+
 	vmStatus, err := vm.GetStatus()
 	if err != nil {
 		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name, fmt.Sprintf("%v", err))
@@ -412,14 +659,27 @@ func (r *VCDMachineReconciler) reconcileVMBoostrap(ctx context.Context, vcdClien
 	if err = capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD, capisdk.VCDMachineCreationError, "", ""); err != nil {
 		log.Error(err, "failed to remove VCDMachineCreationError from RDE")
 	}
-	vAppName := CreateFullVAppName(vcdCluster)
+	vAppName := vApp.VApp.Name
+	// a VM powered on to lease its address is powered off to be given its bootstrap data
+	if vmStatus == "POWERED_ON" && !vcdMachine.Spec.Bootstrapped && vcdMachine.Status.BootstrapStartTime == nil {
+		poweredOnForDHCPLease, err := isPoweredOnForDHCPLease(vdcManager, vm)
+		if err != nil {
+			return errors.Wrapf(err, "Error while delivering the bootstrap data of VM [%s/%s]", vAppName, vm.VM.Name)
+		}
+		if poweredOnForDHCPLease {
+			log.Info("Powering off the VM powered on to lease its address to deliver its bootstrap data")
+			if err = undeployVM(vm); err != nil {
+				return errors.Wrapf(err, "Error while delivering the bootstrap data of VM [%s/%s]", vAppName,
+					vm.VM.Name)
+			}
+			vmStatus = "POWERED_OFF"
+		}
+	}
 	if vmStatus != "POWERED_ON" {
 		// try to power on the VM
-		b64CloudInitScript := b64.StdEncoding.EncodeToString(mergedCloudInitBytes)
-		keyVals := map[string]string{
-			"guestinfo.userdata":          b64CloudInitScript,
-			"guestinfo.userdata.encoding": "base64",
-			"disk.enableUUID":             "1",
+		keyVals := getBootstrapGuestInfo(vcdMachine, mergedCloudInitBytes)
+		for key, val := range getBootstrapRetryGuestInfo(vm, vcdMachine) {
+			keyVals[key] = val
 		}
 
 		for key, val := range keyVals {
@@ -448,19 +708,30 @@ func (r *VCDMachineReconciler) reconcileVMBoostrap(ctx context.Context, vcdClien
 				log.Error(err, "failed to remove VCDMachineCreationError from RDE", "rdeID", vcdCluster.Status.InfraId)
 			}
 
-			log.Info(fmt.Sprintf("Configured the infra machine with variable [%s] to enable cloud-init", key))
+			log.Info(fmt.Sprintf("Configured the infra machine with variable [%s] to pass the bootstrap data", key))
 		}
 
-		task, err := vm.PowerOn()
-		if err != nil {
-			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name, fmt.Sprintf("%v", err))
+		if isBootstrapRetried(vcdMachine) {
+			log.Info("Powering on the VM with a new guest customization to retry its bootstrap",
+				"retry", vcdMachine.Status.BootstrapRetries)
+			if err = powerOnVMWithCustomization(vm); err != nil {
+				capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name, fmt.Sprintf("%v", err))
 
-			return errors.Wrapf(err, "Error while deploying infra for the machine [%s/%s]; unable to power on VM", vcdCluster.Name, vm.VM.Name)
-		}
-		if err = task.WaitTaskCompletion(); err != nil {
-			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name, fmt.Sprintf("%v", err))
+				return errors.Wrapf(err, "Error while retrying the bootstrap of the machine [%s/%s]", vcdCluster.Name,
+					vm.VM.Name)
+			}
+		} else {
+			task, err := vm.PowerOn()
+			if err != nil {
+				capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name, fmt.Sprintf("%v", err))
+
+				return errors.Wrapf(err, "Error while deploying infra for the machine [%s/%s]; unable to power on VM", vcdCluster.Name, vm.VM.Name)
+			}
+			if err = task.WaitTaskCompletion(); err != nil {
+				capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name, fmt.Sprintf("%v", err))
 
-			return errors.Wrapf(err, "Error while deploying infra for the machine [%s/%s]; error waiting for VM power-on task completion", vcdCluster.Name, vm.VM.Name)
+				return errors.Wrapf(err, "Error while deploying infra for the machine [%s/%s]; error waiting for VM power-on task completion", vcdCluster.Name, vm.VM.Name)
+			}
 		}
 
 		if err = vApp.Refresh(); err != nil {
@@ -469,84 +740,57 @@ func (r *VCDMachineReconciler) reconcileVMBoostrap(ctx context.Context, vcdClien
 			return errors.Wrapf(err, "Error while deploying infra for the machine [%s/%s]; unable to refresh vapp after VM power-on", vAppName, vm.VM.Name)
 		}
 	}
-	if hasCloudInitFailedBefore, err := r.hasCloudInitExecutionFailedBefore(vcdClient, vm); hasCloudInitFailedBefore {
-		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineScriptExecutionError, "", machine.Name, fmt.Sprintf("%v", err))
-
-		return errors.Wrapf(err, "Error bootstrapping the machine [%s/%s]; machine is probably in unreconciliable state", vAppName, vm.VM.Name)
-	}
-	capvcdRdeManager.AddToEventSet(ctx, capisdk.InfraVmPoweredOn, "", machine.Name, "", skipRDEEventUpdates)
-
-	err = capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD, capisdk.VCDMachineCreationError, "", machine.Name)
-	if err != nil {
-		log.Error(err, "failed to remove VCDMachineCreationError from RDE", "rdeID", vcdCluster.Status.InfraId)
-	}
-
-	phases := postCustPhases
-	if isInitialControlPlane {
-		phases = append(phases, KubeadmInit)
-	} else {
-		phases = append(phases, KubeadmNodeJoin)
-	}
-
-	if vcdCluster.Spec.ProxyConfigSpec.HTTPSProxy == "" &&
-		vcdCluster.Spec.ProxyConfigSpec.HTTPProxy == "" {
-		phases = removeFromSlice(ProxyConfiguration, phases)
-	}
-
-	for _, phase := range phases {
-		if err = vApp.Refresh(); err != nil {
-			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineScriptExecutionError, "", machine.Name, fmt.Sprintf("%v", err))
-
-			return errors.Wrapf(err, "Error while bootstrapping the machine [%s/%s]; unable to refresh vapp",
-				vAppName, vm.VM.Name)
-		}
-		log.Info(fmt.Sprintf("Start: waiting for the bootstrapping phase [%s] to complete", phase))
-		if err = r.waitForPostCustomizationPhase(ctx, vcdClient, vm, phase); err != nil {
-			log.Error(err, fmt.Sprintf("Error waiting for the bootstrapping phase [%s] to complete", phase))
-			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineScriptExecutionError, "", machine.Name, fmt.Sprintf("%v", err))
+	setBootstrapStartTime(vcdMachine)
+	conditions.MarkTrue(vcdMachine, BootstrapDeliveredCondition)
+	return nil
+}
 
-			return errors.Wrapf(err, "Error while bootstrapping the machine [%s/%s]; unable to wait for post customization phase [%s]",
-				vAppName, vm.VM.Name, phase)
-		}
-		log.Info(fmt.Sprintf("End: waiting for the bootstrapping phase [%s] to complete", phase))
+// undeployVM powers off the VM and undeploys it, which is required to power it on with a new guest customization.
+func undeployVM(vm *govcd.VM) error {
+	task, err := vm.Undeploy()
+	if err == nil {
+		err = task.WaitTaskCompletion()
 	}
-
-	err = capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD, capisdk.VCDMachineScriptExecutionError, "", "")
 	if err != nil {
-		log.Error(err, "failed to remove VCDMachineScriptExecutionError from RDE", "rdeID", vcdCluster.Status.InfraId)
+		return fmt.Errorf("failed to undeploy VM [%s]: [%v]", vm.VM.Name, err)
 	}
-
-	log.Info("Successfully bootstrapped the machine")
-	capvcdRdeManager.AddToEventSet(ctx, capisdk.InfraVmBootstrapped, "", machine.Name, "", skipRDEEventUpdates)
-
 	if err = vm.Refresh(); err != nil {
-		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name, fmt.Sprintf("Unable to refresh vm: %v", err))
-
-		return errors.Wrapf(err, "Unexpected error after the machine [%s/%s] is bootstrapped; unable to refresh vm", vAppName, vm.VM.Name)
-	}
-	if err = vApp.Refresh(); err != nil {
-		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name, fmt.Sprintf("Unable to refresh vApp: %v", err))
-
-		return errors.Wrapf(err, "Unexpected error after the machine [%s/%s] is bootstrapped; unable to refresh vapp", vAppName, vm.VM.Name)
-	}
-
-	err = capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD, capisdk.VCDMachineCreationError, "", machine.Name)
-	if err != nil {
-		log.Error(err, "failed to remove VCDMachineCreationError from RDE", "rdeID", vcdCluster.Status.InfraId)
+		return fmt.Errorf("failed to refresh VM [%s]: [%v]", vm.VM.Name, err)
 	}
 	return nil
 }
 
-func (r *VCDMachineReconciler) getOVDCDetailsForMachine(vcdCluster *infrav1beta3.VCDCluster) (string, string, error) {
+// getOVDCDetailsForMachine returns the OVDC the VCD client of the machine points at, i.e. the OVDC set in the
+// VCDMachine or the OVDC of the cluster, and the OVDC network of the machine.
+func (r *VCDMachineReconciler) getOVDCDetailsForMachine(vcdClient *vcdsdk.Client, vcdCluster *infrav1beta3.VCDCluster,
+	vcdMachine *infrav1beta3.VCDMachine) (string, string, error) {
 
 	// TODO: update this function to get OVDC details for a zone
 
-	return vcdCluster.Spec.Ovdc, vcdCluster.Spec.OvdcNetwork, nil
+	ovdcNetworkName := getOvdcNetworkName(vcdCluster)
+	if vcdMachine.Spec.OvdcNetwork != "" {
+		ovdcNetworkName = vcdMachine.Spec.OvdcNetwork
+	}
+	return vcdClient.ClusterOVDCName, ovdcNetworkName, nil
 }
 
 func CreateFullVAppName(vcdCluster *infrav1beta3.VCDCluster) string {
 
 	// TODO: need to update this function after the introduction of zone related fields in VCDCluster object
+	if vcdCluster.Spec.VAppName != "" {
+		return vcdCluster.Spec.VAppName
+	}
+	if vcdCluster.Status.VAppName != "" {
+		return vcdCluster.Status.VAppName
+	}
+	// the name is generated until it is recorded in the status, e.g. if the status of the VCDCluster was lost. A
+	// template which fails to generate a name is rejected by the webhook of the VCDCluster, and reported by the
+	// VCDCluster controller which does not provision the cluster until it is fixed.
+	if vcdCluster.Spec.VAppNamingTemplate != "" {
+		if vAppName, err := getVAppNameFromTemplate(vcdCluster); err == nil {
+			return vAppName
+		}
+	}
 	return vcdCluster.Name
 }
 
@@ -579,7 +823,8 @@ func (r *VCDMachineReconciler) reconcileVAppCreation(ctx context.Context, vcdCli
 	}
 
 	_, err = vdcManager.Vdc.GetVAppByName(vAppName, true)
-	if err != nil && err == govcd.ErrorEntityNotFound {
+	vAppMissing := err != nil && err == govcd.ErrorEntityNotFound
+	if vAppMissing {
 		vcdCluster.Status.VAppMetadataUpdated = false
 	}
 
@@ -595,6 +840,11 @@ func (r *VCDMachineReconciler) reconcileVAppCreation(ctx context.Context, vcdCli
 			vcdCluster.Name, fmt.Sprintf("%v", err))
 		return ctrl.Result{}, errors.Wrapf(err, "found nil value for VApp [%s]", vAppName)
 	}
+	if vAppMissing {
+		r.recordEvent(vcdCluster, corev1.EventTypeNormal, VAppCreatedReason,
+			fmt.Sprintf("vApp [%s] created in OVDC [%s] for machine [%s]", vAppName, vdcManager.Vdc.Vdc.Name,
+				machineName))
+	}
 
 	// AMK: TODO: this is likely not needed since the resourceset will get added later.
 	//if !strings.HasPrefix(vcdCluster.Status.InfraId, NoRdePrefix) {
@@ -671,13 +921,240 @@ func (r *VCDMachineReconciler) reconcileVM(
 	} else if err == govcd.ErrorEntityNotFound {
 		vmExists = false
 	}
+	creationKey := getVMCreationKey(vApp, vmName)
+	if creation := getVMCreation(creationKey); vmExists && creation != nil && creation.isDone() {
+		// the creation of a VM found by a later reconciliation is no longer checked
+		forgetVMCreation(creationKey)
+	}
 	if !vmExists {
 		log.Info("Adding infra VM for the machine")
 
-		// vcda-4391 fixed
-		err = vdcManager.AddNewTkgVM(vmName, vAppName, 1,
-			vcdMachine.Spec.Catalog, vcdMachine.Spec.Template, vcdMachine.Spec.PlacementPolicy,
-			vcdMachine.Spec.SizingPolicy, vcdMachine.Spec.StorageProfile, false)
+		// the policies of the template can be overridden for the VM of this machine
+		if overrides := applyMachinePolicyOverrides(machine, vcdMachine); len(overrides) > 0 {
+			log.Info("Overriding the policies of the VCDMachineTemplate for the VM of the machine",
+				"overrides", overrides)
+		}
+
+		// a template imported from its source by the VCDCluster, into a catalog of its org, is waited for
+		if pending, phase := isTemplateImportPending(vcdCluster, vcdMachine.Spec.Catalog,
+			vcdMachine.Spec.Template); pending && vcdMachine.Spec.CatalogOrg == "" {
+			log.Info("Waiting for the template of the machine to be imported from its source",
+				"catalog", vcdMachine.Spec.Catalog, "template", vcdMachine.Spec.Template, "phase", phase)
+			conditions.MarkFalse(vcdMachine, ContainerProvisionedCondition, TemplateNotReadyReason,
+				clusterv1.ConditionSeverityInfo, "template [%s] of catalog [%s] is being imported from its source: "+
+					"phase [%s]", vcdMachine.Spec.Template, vcdMachine.Spec.Catalog, phase)
+			return ctrl.Result{RequeueAfter: TemplateNotReadyRequeueInterval}, nil, "", nil
+		}
+
+		// the catalog, template and policies referenced by URN are resolved to their names
+		err = resolveMachineReferences(vcdClient, vdcManager, vcdMachine, vcdCluster)
+		var notFoundErr *VCDReferenceNotFoundError
+		if errors.As(err, &notFoundErr) {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+				fmt.Sprintf("%v", err))
+			log.Info("VM cannot be created as a VCD entity referenced by the machine is missing", "error", err.Error())
+			if conditions.GetReason(vcdMachine, ContainerProvisionedCondition) != VCDReferenceNotFoundReason ||
+				conditions.GetMessage(vcdMachine, ContainerProvisionedCondition) != err.Error() {
+				r.recordEvent(vcdMachine, corev1.EventTypeWarning, VCDReferenceNotFoundReason,
+					fmt.Sprintf("VM [%s] cannot be created: %v", vmName, err))
+			}
+			conditions.MarkFalse(vcdMachine, ContainerProvisionedCondition, VCDReferenceNotFoundReason,
+				clusterv1.ConditionSeverityError, err.Error())
+			return ctrl.Result{RequeueAfter: VCDReferenceNotFoundRequeueInterval}, nil, "", nil
+		}
+		if err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+				fmt.Sprintf("%v", err))
+			return ctrl.Result{}, nil, "", errors.Wrapf(err,
+				"Error provisioning infrastructure for the machine; unable to resolve the VCD references of VM [%s]",
+				machine.Name)
+		}
+
+		// policies omitted in the VCDMachine are inherited from the VCDCluster
+		policies := getResolvedMachinePolicies(vcdMachine, vcdCluster)
+
+		// a VM group is honoured through the placement policy referencing it
+		placementPolicy, err := resolveVmGroupPlacementPolicy(vdcManager, vcdMachine.Spec.VmGroup,
+			policies.PlacementPolicy)
+		if err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+				fmt.Sprintf("%v", err))
+			return ctrl.Result{}, nil, "", errors.Wrapf(err,
+				"Error provisioning infrastructure for the machine; unable to place VM [%s] in VM group [%s]",
+				machine.Name, vcdMachine.Spec.VmGroup)
+		}
+		// a vGPU profile is honoured through the vGPU policy providing it, which replaces the placement policy
+		placementPolicy, err = resolveVgpuPlacementPolicy(vdcManager, vcdMachine.Spec, placementPolicy)
+		if err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+				fmt.Sprintf("%v", err))
+			return ctrl.Result{}, nil, "", errors.Wrapf(err,
+				"Error provisioning infrastructure for the machine; unable to resolve the vGPU policy of VM [%s]",
+				machine.Name)
+		}
+		vcdMachine.Status.PlacementPolicy = placementPolicy
+
+		if err = validateNetworks(vcdMachine.Spec.Networks); err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+				fmt.Sprintf("%v", err))
+			return ctrl.Result{}, nil, "", errors.Wrapf(err,
+				"Error provisioning infrastructure for the machine; invalid networks of VM [%s]", machine.Name)
+		}
+
+		// record the configuration the VM is built from before creating it
+		templateHash, err := getTemplateHash(vcdMachine.Spec)
+		if err != nil {
+			return ctrl.Result{}, nil, "", errors.Wrapf(err,
+				"Error provisioning infrastructure for the machine; unable to compute template hash of VM [%s]",
+				machine.Name)
+		}
+		vcdMachine.Status.TemplateHash = templateHash
+
+		// a template still being imported is waited for instead of failing the creation of the VM
+		templateStatus, err := getTemplateStatus(vcdClient, vcdMachine.Spec.CatalogOrg,
+			getMachineCatalogName(vcdMachine), getMachineTemplateName(vcdMachine))
+		if err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+				fmt.Sprintf("%v", err))
+			return ctrl.Result{}, nil, "", errors.Wrapf(err,
+				"Error provisioning infrastructure for the machine; unable to get the status of template [%s/%s] of VM [%s]",
+				vcdMachine.Spec.Catalog, vcdMachine.Spec.Template, machine.Name)
+		}
+		if isTemplateImporting(templateStatus) {
+			log.Info("Waiting for the template of the machine to be imported", "catalog", getMachineCatalogName(vcdMachine),
+				"template", getMachineTemplateName(vcdMachine), "status", templateStatus)
+			conditions.MarkFalse(vcdMachine, ContainerProvisionedCondition, TemplateNotReadyReason,
+				clusterv1.ConditionSeverityInfo, "template [%s] of catalog [%s] is not ready: status [%s]",
+				getMachineTemplateName(vcdMachine), getMachineCatalogName(vcdMachine), templateStatus)
+			return ctrl.Result{RequeueAfter: TemplateNotReadyRequeueInterval}, nil, "", nil
+		}
+		if templateStatus == VAppTemplateFailedCreationStatus {
+			err = fmt.Errorf("template [%s] of catalog [%s] failed to be imported", getMachineTemplateName(vcdMachine),
+				getMachineCatalogName(vcdMachine))
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+				fmt.Sprintf("%v", err))
+			return ctrl.Result{}, nil, "", errors.Wrapf(err,
+				"Error provisioning infrastructure for the machine; unable to create VM [%s]", machine.Name)
+		}
+
+		// the template is cloned from the template cache of the cluster when it is enabled
+		catalogOrg, catalogName, templateName, err := getMachineTemplateSource(ctx, vcdClient, vdcManager, vcdMachine,
+			vcdCluster)
+		if err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+				fmt.Sprintf("%v", err))
+			return ctrl.Result{}, nil, "", errors.Wrapf(err,
+				"Error provisioning infrastructure for the machine; unable to cache template [%s/%s] of VM [%s]",
+				vcdMachine.Spec.Catalog, vcdMachine.Spec.Template, machine.Name)
+		}
+
+		// The VM is created in the background so that a creation held by a blocking task of the provider, e.g. an
+		// approval workflow, does not hold the reconciliation; the machine is requeued until the provider approves it.
+		// No VM is created while a task of the vApp is blocked, including after a restart of the controller.
+		ipPoolKey := getIPPoolKey(vcdCluster.Spec.Site, vcdClient.ClusterOrgName, ovdcNetworkName)
+		creation := getVMCreation(creationKey)
+		if creation == nil {
+			// no VM gets an address while the IP pool of the network is exhausted
+			if isIPPoolExhausted(ipPoolKey) {
+				conditions.MarkFalse(vcdMachine, ContainerProvisionedCondition, IPPoolExhaustedReason,
+					clusterv1.ConditionSeverityWarning, "IP pool of OVDC network [%s] is exhausted", ovdcNetworkName)
+				return ctrl.Result{RequeueAfter: IPPoolExhaustedRequeueInterval}, nil, "", nil
+			}
+			blocked, err := r.checkProviderApproval(ctx, vcdMachine, vApp)
+			if err != nil {
+				return ctrl.Result{}, nil, "", errors.Wrapf(err,
+					"Error provisioning infrastructure for the machine; unable to check the tasks of vApp [%s]",
+					vAppName)
+			}
+			if blocked {
+				return ctrl.Result{RequeueAfter: ProviderApprovalRequeueInterval}, nil, "", nil
+			}
+
+			// The creation outlives this reconciliation, so that it gets a context which is not cancelled with the
+			// reconciliation, and its own client of the cached VCD session, pointed at the OVDC of the cluster. The
+			// session is shared with the other reconciliations of the credentials: its requests in flight are not
+			// affected by the idle connections closed at the end of the reconciliations, nor by the session being
+			// dropped from the cache, which only stops it from being reused.
+			creationClient, err := createVCDClientFromSecrets(ctx, r.Client, vcdCluster)
+			if err != nil {
+				return ctrl.Result{}, nil, "", errors.Wrapf(err,
+					"Error provisioning infrastructure for the machine; unable to create a VCD client to create VM [%s]",
+					machine.Name)
+			}
+			creationVdcManager, err := vcdsdk.NewVDCManager(creationClient, creationClient.ClusterOrgName,
+				creationClient.ClusterOVDCName)
+			if err != nil {
+				return ctrl.Result{}, nil, "", errors.Wrapf(err,
+					"Error provisioning infrastructure for the machine; unable to create a vdc manager to create VM [%s]",
+					machine.Name)
+			}
+			// VM creations are spaced while the task queue of the org is saturated; the slot is only taken by a
+			// creation which starts
+			if retryAfter := reserveTaskSlot(vcdCluster.Spec.Site, vcdClient.ClusterOrgName); retryAfter > 0 {
+				log.Info("Waiting for the task queue of the org to free up before creating the VM",
+					"org", vcdClient.ClusterOrgName, "retryAfter", retryAfter)
+				conditions.MarkFalse(vcdMachine, ContainerProvisionedCondition, WaitingForTaskCapacityReason,
+					clusterv1.ConditionSeverityInfo, "task queue of org [%s] is saturated", vcdClient.ClusterOrgName)
+				return ctrl.Result{RequeueAfter: retryAfter}, nil, "", nil
+			}
+			vAppHref := vApp.VApp.HREF
+
+			// vcda-4391 fixed
+			// The VMs requested in the vApp by concurrent reconciliations are created together, since VCD runs a
+			// single recomposition of the vApp at a time.
+			completeOvdcTask := startOvdcTask(vcdCluster)
+			startVMCreation(ctrl.LoggerInto(context.Background(), log), creationKey, vcdMachine,
+				machine.Spec.ClusterName, func(creationCtx context.Context) error {
+					defer completeOvdcTask()
+					return composeVM(creationCtx, creationVdcManager, vAppHref, &vmCompositionRequest{
+						vmName:              vmName,
+						catalogOrg:          catalogOrg,
+						catalogName:         catalogName,
+						templateName:        templateName,
+						placementPolicyName: placementPolicy,
+						sizingPolicyName:    policies.SizingPolicy,
+						storageProfileName:  policies.StorageProfile,
+					})
+				})
+			log.Info("Creating the VM of the machine in the background", "vm", vmName)
+			return ctrl.Result{RequeueAfter: VMCreationBlockedCheckDelay}, nil, "", nil
+		}
+		if !creation.isDone() {
+			blocked, err := r.checkProviderApproval(ctx, vcdMachine, vApp)
+			if err != nil {
+				log.Error(err, "Unable to check the tasks of the vApp while creating the VM", "vApp", vAppName)
+			}
+			if blocked {
+				return ctrl.Result{RequeueAfter: ProviderApprovalRequeueInterval}, nil, "", nil
+			}
+			log.Info("Waiting for the VM of the machine to be created", "vm", vmName,
+				"elapsed", time.Since(creation.startTime).Round(time.Second))
+			return ctrl.Result{RequeueAfter: VMCreationRequeueInterval}, nil, "", nil
+		}
+		forgetVMCreation(creationKey)
+		cloneStartTime := creation.startTime
+		err = creation.err
+		vmCloneDuration.WithLabelValues(getOperationResult(err)).Observe(time.Since(cloneStartTime).Seconds())
+		r.reportProviderApproval(vcdMachine, vmName, err)
+		if isTaskQueueSaturatedError(err) {
+			retryAfter := recordTaskQueueSaturation(vcdCluster.Spec.Site, vcdClient.ClusterOrgName)
+			log.Info("VCD rejected the creation of the VM as the task queue of the org is saturated",
+				"org", vcdClient.ClusterOrgName, "retryAfter", retryAfter, "error", err.Error())
+			conditions.MarkFalse(vcdMachine, ContainerProvisionedCondition, WaitingForTaskCapacityReason,
+				clusterv1.ConditionSeverityInfo, "task queue of org [%s] is saturated", vcdClient.ClusterOrgName)
+			return ctrl.Result{RequeueAfter: retryAfter}, nil, "", nil
+		}
+		if isIPPoolExhaustedError(err) {
+			recordIPPoolExhausted(ipPoolKey)
+			log.Info("VCD failed to create the VM as the IP pool of the OVDC network is exhausted",
+				"ovdcNetwork", ovdcNetworkName, "error", err.Error())
+			r.recordEvent(vcdMachine, corev1.EventTypeWarning, IPPoolExhaustedReason,
+				fmt.Sprintf("VM [%s] was not created as the IP pool of OVDC network [%s] is exhausted", vmName,
+					ovdcNetworkName))
+			conditions.MarkFalse(vcdMachine, ContainerProvisionedCondition, IPPoolExhaustedReason,
+				clusterv1.ConditionSeverityWarning, "IP pool of OVDC network [%s] is exhausted", ovdcNetworkName)
+			return ctrl.Result{RequeueAfter: IPPoolExhaustedRequeueInterval}, nil, "", nil
+		}
 		if err != nil {
 			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
 				fmt.Sprintf("%v", err))
@@ -685,6 +1162,7 @@ func (r *VCDMachineReconciler) reconcileVM(
 				"Error provisioning infrastructure for the machine; unable to create VM [%s] in vApp [%s]",
 				machine.Name, vAppName)
 		}
+		recordTaskAccepted(vcdCluster.Spec.Site, vcdClient.ClusterOrgName)
 		vm, err = vApp.GetVMByName(vmName, true)
 		if err != nil {
 			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "",
@@ -700,13 +1178,57 @@ func (r *VCDMachineReconciler) reconcileVM(
 				vAppName, machine.Name)
 		}
 
+		r.recordEvent(vcdMachine, corev1.EventTypeNormal, VMClonedReason,
+			fmt.Sprintf("VM [%s] cloned from template [%s/%s] in [%s]", vmName, catalogName, templateName,
+				time.Since(cloneStartTime).Round(time.Second)))
+
 		// NOTE: VMs are not added to VCDResourceSet intentionally as the VMs can be obtained from the VApp and
 		// 	VCDResourceSet can get bloated with VMs if the cluster contains a large number of worker nodes
 	}
 
-	desiredNetworks := []string{ovdcNetworkName}
-	if vcdMachine.Spec.ExtraOvdcNetworks != nil {
-		desiredNetworks = append([]string{ovdcNetworkName}, vcdMachine.Spec.ExtraOvdcNetworks...)
+	if err = reconcileVMTemplateHash(vm, vcdMachine.Status.TemplateHash); err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+			fmt.Sprintf("%v", err))
+		return ctrl.Result{}, nil, "", errors.Wrapf(err,
+			"Error provisioning infrastructure for the machine; unable to record template hash of VM [%s]",
+			machine.Name)
+	}
+	if err = reconcileVMRole(vm, getMachineRole(machine)); err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+			fmt.Sprintf("%v", err))
+		return ctrl.Result{}, nil, "", errors.Wrapf(err,
+			"Error provisioning infrastructure for the machine; unable to record role of VM [%s]", machine.Name)
+	}
+	if err = reconcileVMMachineName(vm, machine.Name); err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+			fmt.Sprintf("%v", err))
+		return ctrl.Result{}, nil, "", errors.Wrapf(err,
+			"Error provisioning infrastructure for the machine; unable to record machine name of VM [%s]",
+			machine.Name)
+	}
+
+	desiredNetworks := getDesiredNetworks(vcdMachine.Spec, ovdcNetworkName,
+		getIPAllocationMode(machine, vcdMachine, vcdCluster))
+	endpointAddress, err := getControlPlaneEndpointMachineAddress(ctx, r.Client, machine, vcdMachine, vcdCluster)
+	if err != nil {
+		return ctrl.Result{}, nil, "", errors.Wrapf(err,
+			"Error provisioning infrastructure for the machine; unable to get the address of VM [%s]", machine.Name)
+	}
+	if endpointAddress != "" {
+		// the control plane endpoint is the address of the first control plane machine in the routed mode
+		desiredNetworks[0].IPAllocationMode = IPAllocationModeManual
+		desiredNetworks[0].IPAddress = endpointAddress
+	}
+	desiredNetworks, allocated, err := r.reconcileIPAddressClaims(ctx, machine, vcdMachine, desiredNetworks)
+	if err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+			fmt.Sprintf("%v", err))
+		return ctrl.Result{}, nil, "", errors.Wrapf(err,
+			"Error provisioning infrastructure for the machine; unable to claim IP addresses of VM [%s]",
+			machine.Name)
+	}
+	if !allocated {
+		return ctrl.Result{RequeueAfter: IPAddressClaimRequeueInterval}, nil, "", nil
 	}
 	if err = r.reconcileVMNetworks(vdcManager, vApp, vm, desiredNetworks); err != nil {
 		log.Error(err, "Error while attaching networks to vApp and VMs")
@@ -732,7 +1254,8 @@ func (r *VCDMachineReconciler) reconcileVM(
 		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil, "", nil
 	}
 
-	if primaryNetwork.IPAddress == "" {
+	// the address leased by DHCP is only reported by the guest once the VM is powered on
+	if primaryNetwork.IPAddress == "" && primaryNetwork.IPAddressAllocationMode != IPAllocationModeDHCP {
 		log.Error(nil,
 			fmt.Sprintf("Requeuing...; NetworkConnection[0] IP Address should not be empty for vm [%s(%s)]: [%#v]",
 				vm.VM.Name, vm.VM.ID, *vm.VM.NetworkConnectionSection.NetworkConnection[0]))
@@ -741,19 +1264,32 @@ func (r *VCDMachineReconciler) reconcileVM(
 
 	// set address in machine status
 	machineAddress = primaryNetwork.IPAddress
-	vcdMachine.Status.Addresses = []clusterv1.MachineAddress{
-		{
-			Type:    clusterv1.MachineHostName,
-			Address: vm.VM.Name,
-		},
-		{
-			Type:    clusterv1.MachineInternalIP,
-			Address: machineAddress,
-		},
-		{
-			Type:    clusterv1.MachineExternalIP,
-			Address: machineAddress,
-		},
+	setMachineAddresses(vcdMachine, vm.VM.Name, machineAddress)
+
+	if err = reconcileVMHardware(ctx, vcdClient, vm, vcdMachine,
+		getResolvedMachinePolicies(vcdMachine, vcdCluster).SizingPolicy); err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+			fmt.Sprintf("%v", err))
+		return ctrl.Result{}, nil, "", errors.Wrapf(err,
+			"Error while provisioning the infrastructure VM for the machine [%s] of the cluster [%s]; "+
+				"failed to set its CPUs and memory", vm.VM.Name, vApp.VApp.Name)
+	}
+
+	if err = reconcileBootDiskBusType(vm, vcdMachine); err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+			fmt.Sprintf("%v", err))
+		return ctrl.Result{}, nil, "", errors.Wrapf(err,
+			"Error while provisioning the infrastructure VM for the machine [%s] of the cluster [%s]; "+
+				"failed to set boot disk bus type", vm.VM.Name, vApp.VApp.Name)
+	}
+
+	if err = reconcileBootDiskSettings(vm, vcdMachine.Spec.BootDiskProvisioningType,
+		vcdMachine.Spec.BootDiskIops); err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+			fmt.Sprintf("%v", err))
+		return ctrl.Result{}, nil, "", errors.Wrapf(err,
+			"Error while provisioning the infrastructure VM for the machine [%s] of the cluster [%s]; "+
+				"failed to set boot disk provisioning and IOPS", vm.VM.Name, vApp.VApp.Name)
 	}
 
 	// only resize hard disk if the user has requested so by specifying such in the VCDMachineTemplate spec
@@ -794,6 +1330,23 @@ func (r *VCDMachineReconciler) reconcileVM(
 		}
 	}
 
+	if err = reconcileDataDisks(ctx, vdcManager, vm, vcdMachine.Spec.DataDisks,
+		getResolvedMachinePolicies(vcdMachine, vcdCluster).StorageProfile); err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+			fmt.Sprintf("%v", err))
+		return ctrl.Result{}, nil, "", errors.Wrapf(err,
+			"Error while provisioning the infrastructure VM for the machine [%s] of the cluster [%s]; "+
+				"failed to attach data disks", vm.VM.Name, vApp.VApp.Name)
+	}
+
+	if err = r.reconcileAntiAffinityRule(ctx, vdcManager.Vdc, vApp, machine, vcdMachine, vcdCluster); err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name,
+			fmt.Sprintf("%v", err))
+		return ctrl.Result{}, nil, "", errors.Wrapf(err,
+			"Error while provisioning the infrastructure VM for the machine [%s] of the cluster [%s]; "+
+				"failed to add VM to anti-affinity rule", vm.VM.Name, vApp.VApp.Name)
+	}
+
 	return ctrl.Result{}, vm, machineAddress, nil
 }
 
@@ -803,6 +1356,21 @@ func (r *VCDMachineReconciler) reconcileLBPool(ctx context.Context, machine *clu
 	log := ctrl.LoggerFrom(ctx, "cluster", vcdCluster.Name, "machine", machine.Name)
 	capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
 
+	if isControlPlaneEndpointDNAT(vcdCluster) {
+		controlPlaneIPs, err := getControlPlaneMachineIPs(ctx, r.Client, machine.Namespace, machine.Spec.ClusterName)
+		if err != nil {
+			return err
+		}
+		controlPlaneIPs = cpiutil.NewSet(append(controlPlaneIPs, machineAddress)).GetElements()
+		sort.Strings(controlPlaneIPs)
+		if err = reconcileControlPlaneDNATRule(ctx, gateway, vcdCluster, controlPlaneIPs); err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.LoadBalancerError, "", machine.Name, fmt.Sprintf("%v", err))
+			return fmt.Errorf("unable to reconcile the DNAT rule for the control plane machine [%s] of the cluster [%s]: [%v]",
+				machine.Name, vcdCluster.Name, err)
+		}
+		return nil
+	}
+
 	virtualServiceName := capisdk.GetVirtualServiceNameUsingPrefix(
 		capisdk.GetVirtualServiceNamePrefix(vcdCluster.Name, vcdCluster.Status.InfraId), "tcp")
 	lbPoolName := capisdk.GetLoadBalancerPoolNameUsingPrefix(
@@ -855,6 +1423,11 @@ func (r *VCDMachineReconciler) reconcileLBPool(ctx context.Context, machine *clu
 	}
 	log.Info("Updated the load balancer pool with the control plane machine IP",
 		"lbpool", lbPoolName)
+	if err = reconcileLoadBalancerPoolSettings(ctx, vcdClient, gateway, vcdCluster); err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.LoadBalancerError, "", machine.Name, fmt.Sprintf("%v", err))
+		return fmt.Errorf("unable to configure LB pool [%s] for the control plane machine [%s] of the cluster [%s]: [%v]",
+			lbPoolName, machine.Name, vcdCluster.Name, err)
+	}
 
 	return nil
 }
@@ -869,6 +1442,12 @@ func (r *VCDMachineReconciler) reconcileNormal(ctx context.Context, cluster *clu
 
 	vcdClient, err := createVCDClientFromSecrets(ctx, r.Client, vcdCluster)
 	if err != nil {
+		var credentialsErr *CredentialsExpiredError
+		if errors.As(err, &credentialsErr) {
+			// the VCDCluster reports the rejected credentials
+			log.Info("Waiting for the credentials of the cluster to be updated", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: CredentialsExpiredRequeueInterval}, nil
+		}
 		return ctrl.Result{}, errors.Wrapf(err, "Error creating VCD client to reconcile Cluster [%s] infrastructure", vcdCluster.Name)
 	}
 
@@ -889,6 +1468,16 @@ func (r *VCDMachineReconciler) reconcileNormal(ctx context.Context, cluster *clu
 		return ctrl.Result{}, errors.Wrapf(err, "Error updating vcdResource into vcdcluster.status to reconcile Cluster [%s] infrastructure", vcdCluster.Name)
 	}
 
+	// the VM of a machine with its own OVDC is created in a vApp of the cluster in that OVDC
+	machineOvdc, err := useMachineOvdc(vcdClient, vcdMachine)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Error getting the OVDC of the machine [%s] of cluster [%s]",
+			machine.Name, vcdCluster.Name)
+	}
+	if machineOvdc && vcdMachine.Spec.VAppName == "" && vcdMachine.Status.VAppName == "" {
+		vcdMachine.Status.VAppName = getOvdcVAppName(vcdCluster, vcdClient.ClusterOVDCName)
+	}
+
 	capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
 	if conditions.IsFalse(machine, clusterv1.MachineHealthCheckSucceededCondition) {
 		capvcdRdeManager.AddToEventSet(ctx, capisdk.NodeHealthCheckFailed, getVMIDFromProviderID(vcdMachine.Status.ProviderID), machine.Name, conditions.GetMessage(machine, clusterv1.MachineHealthCheckSucceededCondition), false)
@@ -896,12 +1485,75 @@ func (r *VCDMachineReconciler) reconcileNormal(ctx context.Context, cluster *clu
 	if checkIfMachineNodeIsUnhealthy(machine) {
 		capvcdRdeManager.AddToEventSet(ctx, capisdk.NodeUnhealthy, getVMIDFromProviderID(vcdMachine.Status.ProviderID), machine.Name, conditions.GetMessage(machine, clusterv1.MachineNodeHealthyCondition), false)
 	}
+	// a VCDMachine created with the provider ID of an existing VM adopts the VM instead of creating one
+	if isVMAdoptionPending(vcdMachine) {
+		if err = r.reconcileVMAdoption(ctx, vcdClient, machine, vcdMachine, vcdCluster); err != nil {
+			conditions.MarkFalse(vcdMachine, VMProvisionedCondition, VMAdoptionFailedReason,
+				clusterv1.ConditionSeverityError, err.Error())
+			return ctrl.Result{}, errors.Wrapf(err, "Error adopting the VM of the machine [%s] of cluster [%s]",
+				machine.Name, vcdCluster.Name)
+		}
+	}
 	if vcdMachine.Spec.ProviderID != nil && vcdMachine.Status.ProviderID != nil {
 		vcdMachine.Status.Ready = true
+		// the machines provisioned before these conditions existed get them as well
+		conditions.MarkTrue(vcdMachine, VAppReadyCondition)
+		conditions.MarkTrue(vcdMachine, VMProvisionedCondition)
 		conditions.MarkTrue(vcdMachine, ContainerProvisionedCondition)
+		conditions.MarkTrue(vcdMachine, BootstrapDeliveredCondition)
 		capvcdRdeManager.AddToEventSet(ctx, capisdk.InfraVmBootstrapped, "", machine.Name, "", skipRDEEventUpdates)
+		// the boot disk of a provisioned machine is grown when its disk size is increased
+		if err = r.reconcileBootDiskResize(ctx, vcdClient, machine, vcdMachine, vcdCluster); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "Error resizing the boot disk of the machine [%s] of cluster [%s]",
+				machine.Name, vcdCluster.Name)
+		}
+		// the CPUs and memory of a provisioned machine scaled in place are changed on its running VM
+		if err = r.reconcileVMHardwareInPlace(ctx, vcdClient, machine, vcdMachine, vcdCluster); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "Error scaling the VM of the machine [%s] of cluster [%s] in place",
+				machine.Name, vcdCluster.Name)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// a failed machine is left as is, e.g. for its VM to be inspected, until its owner replaces it
+	if isMachineFailed(vcdMachine) {
+		log.Info("Waiting for the failed machine to be replaced", "reason", *vcdMachine.Status.FailureReason)
 		return ctrl.Result{}, nil
 	}
+	if isProvisioningTimedOut(vcdMachine) {
+		log.Info("Failing the machine whose provisioning timed out")
+		r.failProvisioningTimedOutMachine(vcdMachine)
+		return ctrl.Result{}, nil
+	}
+
+	// don't attempt to create VMs in an OVDC disabled by the provider; resume once it is enabled again
+	if isOvdcDisabled(vcdClient) {
+		if markOvdcDisabledCondition(vcdMachine, vcdClient.ClusterOVDCName) {
+			log.Info("Waiting for the OVDC of the cluster to be enabled", "ovdc", vcdClient.ClusterOVDCName)
+		}
+		return ctrl.Result{RequeueAfter: OvdcDisabledRequeueInterval}, nil
+	}
+	if markOvdcEnabledCondition(vcdMachine) {
+		log.Info("Resuming provisioning of the machine as the OVDC is enabled", "ovdc", vcdClient.ClusterOVDCName)
+	}
+
+	// don't create VMs in an OVDC under maintenance either; the machine is provisioned once the maintenance is over
+	if underMaintenance, description := isOvdcUnderMaintenance(vcdCluster); underMaintenance {
+		if setOvdcMaintenanceCondition(vcdMachine, vcdCluster, description) {
+			log.Info("Waiting for the end of the maintenance of the OVDC of the cluster",
+				"ovdc", vcdClient.ClusterOVDCName, "maintenance", description)
+		}
+		return ctrl.Result{RequeueAfter: OvdcMaintenanceRequeueInterval}, nil
+	}
+	if clearOvdcMaintenanceCondition(vcdMachine) {
+		log.Info("Resuming provisioning of the machine as the maintenance of the OVDC is over",
+			"ovdc", vcdClient.ClusterOVDCName)
+	}
+
+	if err = verifyVAppOwnershipClaim(ctx, r.Client, vcdClient, vcdCluster); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Unable to provision the machine [%s] of cluster [%s]",
+			machine.Name, vcdCluster.Name)
+	}
 
 	patchHelper, err := patch.NewHelper(vcdMachine, r.Client)
 	if err != nil {
@@ -912,7 +1564,7 @@ func (r *VCDMachineReconciler) reconcileNormal(ctx context.Context, cluster *clu
 	if !conditions.Has(vcdMachine, BootstrapExecSucceededCondition) {
 		conditions.MarkFalse(vcdMachine, BootstrapExecSucceededCondition,
 			BootstrappingReason, clusterv1.ConditionSeverityInfo, "")
-		if err := patchVCDMachine(ctx, patchHelper, vcdMachine); err != nil {
+		if err := patchVCDMachine(ctx, patchHelper, vcdMachine, false, nil); err != nil {
 			capvcdRdeManager.AddToErrorSet(ctx, capisdk.CAPVCDObjectPatchError, "", machine.Name, fmt.Sprintf("%v", err))
 			return ctrl.Result{}, errors.Wrapf(err, "Error patching VCDMachine [%s] of cluster [%s]", vcdMachine.Name, vcdCluster.Name)
 		}
@@ -936,52 +1588,104 @@ func (r *VCDMachineReconciler) reconcileNormal(ctx context.Context, cluster *clu
 	// Create the vApp if it doesn't already exist. In the multi-AZ case, this has to be done in the machine controller
 	// since new zones could be added dynamically. In the non-AZ case, it can be done in the vcdCluster controller. However,
 	// we do it in one place for simplicity.
-	// TODO: should we add a field in VCDMachine to store the VApp name used for the machine ?
-	vAppName := CreateFullVAppName(vcdCluster)
+	// The worker machines of a cluster with a vApp strategy are placed in a vApp of the strategy, recorded in the
+	// status of the VCDMachine, before their VM is created.
+	if vcdMachine.Status.VAppName == "" && hasStrategyVApps(vcdCluster) {
+		vmName, err := getVMName(machine, vcdMachine, vcdCluster, log)
+		if err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "unable to get the VM name of machine [%s]", machine.Name)
+		}
+		if err = r.assignMachineVApp(ctx, vdcManager, vcdCluster, machine, vcdMachine, vmName); err != nil {
+			conditions.MarkFalse(vcdMachine, VAppReadyCondition, VAppCreationFailedReason,
+				clusterv1.ConditionSeverityWarning, err.Error())
+			return ctrl.Result{}, errors.Wrapf(err, "unable to place machine [%s] in a vApp of cluster [%s]",
+				machine.Name, vcdCluster.Name)
+		}
+		if err = patchVCDMachine(ctx, patchHelper, vcdMachine, false, nil); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "Error patching VCDMachine [%s] of cluster [%s]",
+				vcdMachine.Name, vcdCluster.Name)
+		}
+	}
+	vAppName := getMachineVAppName(vcdMachine, vcdCluster)
 	log.Info(fmt.Sprintf("Using VApp name [%s] for the machine [%s]", vAppName, machine.Name))
 
-	ovdcName, ovdcNetworkName, err := r.getOVDCDetailsForMachine(vcdCluster)
+	ovdcName, ovdcNetworkName, err := r.getOVDCDetailsForMachine(vcdClient, vcdCluster, vcdMachine)
 	if err != nil {
 		log.Error(err, "Unable to get OVDC details of machine")
 		return ctrl.Result{}, errors.Wrapf(err, "unable to get OVDC details of machine [%s]", vcdMachine.Name)
 	}
 
-	result, err := r.reconcileVAppCreation(ctx, vcdClient, machine.Name, vcdCluster, vAppName, ovdcNetworkName, false)
-	if err != nil {
-		log.Error(err, "failed to reconcile vApp", "vAppName", vAppName)
-		return result, errors.Wrapf(err, "unable to reconcile vApp [%s] for cluster [%s]", vAppName, vcdCluster.Name)
+	if vAppName == CreateFullVAppName(vcdCluster) {
+		result, err := r.reconcileVAppCreation(ctx, vcdClient, machine.Name, vcdCluster, vAppName, ovdcNetworkName, false)
+		if err != nil {
+			log.Error(err, "failed to reconcile vApp", "vAppName", vAppName)
+			conditions.MarkFalse(vcdMachine, VAppReadyCondition, VAppCreationFailedReason,
+				clusterv1.ConditionSeverityWarning, err.Error())
+			return result, errors.Wrapf(err, "unable to reconcile vApp [%s] for cluster [%s]", vAppName, vcdCluster.Name)
+		}
+	} else if err = reconcileMachineVApp(ctx, vcdClient, vdcManager, vcdMachine, vcdCluster,
+		ovdcNetworkName); err != nil {
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDClusterVappCreationError, "", machine.Name, fmt.Sprintf("%v", err))
+		conditions.MarkFalse(vcdMachine, VAppReadyCondition, VAppCreationFailedReason,
+			clusterv1.ConditionSeverityWarning, err.Error())
+		return ctrl.Result{}, errors.Wrapf(err, "unable to reconcile vApp [%s] of machine [%s]", vAppName, machine.Name)
 	}
 
 	vApp, err := vdcManager.Vdc.GetVAppByName(vAppName, true)
 	if err != nil {
 		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDClusterVappCreationError, "", machine.Name, fmt.Sprintf("%v", err))
+		conditions.MarkFalse(vcdMachine, VAppReadyCondition, VAppCreationFailedReason,
+			clusterv1.ConditionSeverityWarning, err.Error())
 		return ctrl.Result{}, errors.Wrapf(err,
 			"Error provisioning infrastructure VApp for the machine [%s] of the cluster [%s]",
 			machine.Name, vcdCluster.Name)
 	}
+	conditions.MarkTrue(vcdMachine, VAppReadyCondition)
 	err = capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD, capisdk.VCDClusterVappCreationError, "", "")
 	if err != nil {
 		log.Error(err, "failed to remove VCDClusterVappCreationError from RDE", "rdeID", vcdCluster.Status.InfraId)
 	}
 
-	vmName, err := getVMName(machine, vcdMachine, log)
+	vmName, err := getVMName(machine, vcdMachine, vcdCluster, log)
 	if err != nil {
 		return ctrl.Result{}, errors.Wrapf(err, "unable to get VM [%s] by name for cluster [%s]",
 			machine.Name, vcdCluster.Name)
 	}
+	if vmName, err = reconcileVMName(vcdClient, vApp, machine, vcdMachine, vmName); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "unable to get VM [%s] by name for cluster [%s]",
+			machine.Name, vcdCluster.Name)
+	}
 	log.Info(fmt.Sprintf("Using VM name [%s] in VApp [%s] for the machine [%s]", vmName, vAppName, machine.Name))
 
 	result, vm, machineAddress, err := r.reconcileVM(ctx, vcdClient, vdcManager, vApp, machine, vcdMachine,
 		vmName, ovdcNetworkName, vcdCluster)
 	if err != nil {
 		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineError, "", machine.Name, fmt.Sprintf("%v", err))
+		conditions.MarkFalse(vcdMachine, VMProvisionedCondition, VMProvisioningFailedReason,
+			clusterv1.ConditionSeverityWarning, err.Error())
 		return result, errors.Wrapf(err, "unable to provision infrastructure for VM [%s/%s] in ovdc[%s] with network [%s]",
 			vAppName, vmName, ovdcName, ovdcNetworkName)
 	} else if result.Requeue || result.RequeueAfter > 0 {
 		log.Info("Re queuing the request",
 			"result.Requeue", result.Requeue, "result.RequeueAfter", result.RequeueAfter.String())
+		conditions.MarkFalse(vcdMachine, VMProvisionedCondition, VMProvisioningReason,
+			clusterv1.ConditionSeverityInfo, "")
 		return result, nil
 	}
+	conditions.MarkTrue(vcdMachine, VMProvisionedCondition)
+
+	// the metadata used by tooling such as chargeback is best-effort and does not block the provisioning of the VM
+	if !isMachineVAppUnmanaged(vcdMachine) {
+		clusterMetadata := getPropagatedMetadata(cluster.ObjectMeta, vcdCluster.Spec.MetadataPropagation, nil)
+		if err = reconcilePropagatedMetadata(vApp, vAppName, clusterMetadata); err != nil {
+			log.Error(err, "failed to propagate the labels and annotations of the cluster to the vApp metadata")
+		}
+	}
+	vmMetadata := getPropagatedMetadata(machine.ObjectMeta, vcdMachine.Spec.MetadataPropagation,
+		getPropagatedMetadata(cluster.ObjectMeta, vcdCluster.Spec.MetadataPropagation, nil))
+	if err = reconcilePropagatedMetadata(vm, vm.VM.Name, vmMetadata); err != nil {
+		log.Error(err, "failed to propagate the labels and annotations of the machine to the VM metadata")
+	}
 
 	if machine.Spec.Bootstrap.DataSecretName == nil {
 		if !util.IsControlPlaneMachine(machine) && !conditions.IsTrue(cluster,
@@ -996,83 +1700,271 @@ func (r *VCDMachineReconciler) reconcileNormal(ctx context.Context, cluster *clu
 		log.Info("Waiting for the Bootstrap provider controller to set bootstrap data")
 		conditions.MarkFalse(vcdMachine, ContainerProvisionedCondition,
 			WaitingForBootstrapDataReason, clusterv1.ConditionSeverityInfo, "")
+		conditions.MarkFalse(vcdMachine, BootstrapDeliveredCondition,
+			WaitingForBootstrapDataReason, clusterv1.ConditionSeverityInfo, "")
 		return ctrl.Result{}, nil
 	}
 	conditions.MarkTrue(vcdMachine, ContainerProvisionedCondition)
 
 	mergedCloudInitBytes, isInitialControlPlane, isResizedControlPlane, err := r.reconcileCloudInitScript(
 		ctx, vcdClient, machine, cluster, vcdMachine, vcdCluster, vAppName, vmName, skipRDEEventUpdates)
-
-	gateway, err := vcdsdk.NewGatewayManager(ctx, vcdClient, vcdCluster.Spec.OvdcNetwork, vcdCluster.Spec.LoadBalancerConfigSpec.VipSubnet, vcdCluster.Spec.Ovdc)
 	if err != nil {
-		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name, fmt.Sprintf("%v", err))
+		conditions.MarkFalse(vcdMachine, BootstrapDeliveredCondition, BootstrapDeliveryFailedReason,
+			clusterv1.ConditionSeverityWarning, err.Error())
+		return ctrl.Result{}, errors.Wrapf(err, "unable to generate the bootstrap script of machine [%s]",
+			machine.Name)
+	}
+
+	// the OVDC network of a cluster in the routed mode may have no edge gateway
+	var gateway *vcdsdk.GatewayManager
+	if isEdgeGatewayRequired(vcdCluster) {
+		gateway, err = vcdsdk.NewGatewayManager(ctx, vcdClient, getOvdcNetworkName(vcdCluster), vcdCluster.Spec.LoadBalancerConfigSpec.VipSubnet, getOvdcName(vcdCluster))
+		if err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name, fmt.Sprintf("%v", err))
+
+			return ctrl.Result{}, errors.Wrapf(err, "failed to create gateway manager object while reconciling machine [%s]", vcdMachine.Name)
+		}
+	}
+
+	// the load balancer of an externally managed cluster is maintained by the external system, the one supplied in
+	// the passthrough mode by the user, and there is none in the routed mode
+	externalLoadBalancer := !isLoadBalancerManagedByCAPVCD(vcdCluster)
 
-		return ctrl.Result{}, errors.Wrapf(err, "failed to create gateway manager object while reconciling machine [%s]", vcdMachine.Name)
+	// the VM of a machine in DHCP mode is powered on to lease its address, which the load balancer pool is updated with
+	// before the machine is bootstrapped and marked ready. The initial control plane machine is powered on without its
+	// bootstrap data, as kubeadm init reaches the API server through the load balancer, and its bootstrap data is
+	// delivered once its address is in the pool. Ignition only runs at the first boot, so it is never held.
+	if machineAddress == "" {
+		if isInitialControlPlane && !externalLoadBalancer && !isIgnitionBootstrap(vcdMachine) {
+			err = powerOnVMForDHCPLease(vdcManager, vm)
+		} else {
+			err = r.reconcileVMPowerOn(ctx, vcdClient, vdcManager, vApp, vm, mergedCloudInitBytes, vcdCluster,
+				machine, vcdMachine)
+		}
+		if err != nil {
+			conditions.MarkFalse(vcdMachine, BootstrapDeliveredCondition, BootstrapDeliveryFailedReason,
+				clusterv1.ConditionSeverityWarning, err.Error())
+			return ctrl.Result{}, errors.Wrapf(err, "failed to power on VM [%s/%s] to lease its address", vAppName,
+				vmName)
+		}
+		if machineAddress, err = getDHCPLease(ctx, vm); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to get the address leased to VM [%s/%s]", vAppName, vmName)
+		}
+		if machineAddress == "" {
+			message := fmt.Sprintf("no address leased by DHCP to VM [%s] yet", vm.VM.Name)
+			if primaryNetwork := getPrimaryNetwork(vm.VM); primaryNetwork != nil {
+				if err = validateNetworkDHCP(vdcManager.Vdc, primaryNetwork.Network); err != nil {
+					message = fmt.Sprintf("%s: [%v]", message, err)
+				}
+			}
+			log.Info("Waiting for the DHCP lease of the VM", "details", message)
+			conditions.MarkFalse(vcdMachine, VMProvisionedCondition, WaitingForDHCPLeaseReason,
+				clusterv1.ConditionSeverityInfo, "%s", message)
+			return ctrl.Result{RequeueAfter: DHCPLeaseRequeueInterval}, nil
+		}
+		setMachineAddresses(vcdMachine, vm.VM.Name, machineAddress)
 	}
 
 	// Update loadbalancer pool with the IP of the control plane node as a new member.
-	// Note that this must be done before booting on the VM!
-	if isInitialControlPlane {
+	// Note that this must be done before the bootstrap data is delivered to the VM!
+	if isInitialControlPlane && !externalLoadBalancer {
 		if err := r.reconcileLBPool(ctx, machine, machineAddress, vcdCluster, vcdClient, gateway); err != nil {
 			return ctrl.Result{}, errors.Wrapf(err, "unable to add machine address [%s] into LB Pool for the "+
 				"control plane machine [%s] of the cluster [%s]", machineAddress, machine.Name, vcdCluster.Name)
 		}
+		if !vcdMachine.Spec.Bootstrapped {
+			r.recordEvent(vcdMachine, corev1.EventTypeNormal, LoadBalancerPoolMemberAddedReason,
+				fmt.Sprintf("address [%s] added to the load balancer pool of the control plane endpoint",
+					machineAddress))
+		}
+	}
+
+	// a bootstrap which does not complete in time is retried in place, then the machine is failed
+	if isBootstrapTimedOut(vcdMachine) {
+		return r.reconcileBootstrapTimeout(ctx, vdcManager, vm, vcdMachine,
+			getBootstrapPhases(vcdCluster, vcdMachine, isInitialControlPlane))
 	}
 
 	err = r.reconcileVMBoostrap(ctx, vcdClient, vdcManager, vApp, vm, mergedCloudInitBytes, vcdCluster, machine,
+		vcdMachine,
 		isInitialControlPlane, isResizedControlPlane, skipRDEEventUpdates)
 	if err != nil {
+		if conditions.IsTrue(vcdMachine, BootstrapDeliveredCondition) {
+			conditions.MarkFalse(vcdMachine, BootstrapExecSucceededCondition, BootstrapFailedReason,
+				clusterv1.ConditionSeverityWarning, err.Error())
+		} else {
+			conditions.MarkFalse(vcdMachine, BootstrapDeliveredCondition, BootstrapDeliveryFailedReason,
+				clusterv1.ConditionSeverityWarning, err.Error())
+		}
 		return ctrl.Result{}, errors.Wrapf(err, "failed to bootstrap VM [%s/%s]", vAppName, vmName)
 	}
 
 	// Update load-balancer pool with the IP of the control plane node as a new member.
 	// For joining nodes the LB Pool should be updated after the VM has joined.
-	if isResizedControlPlane {
+	if isResizedControlPlane && !externalLoadBalancer {
 		if err := r.reconcileLBPool(ctx, machine, machineAddress, vcdCluster, vcdClient, gateway); err != nil {
 			return ctrl.Result{}, errors.Wrapf(err, "unable to add machine address [%s] into LB Pool for the "+
 				"control plane machine [%s] of the cluster [%s]", machineAddress, machine.Name, vcdCluster.Name)
 		}
+		if !vcdMachine.Spec.Bootstrapped {
+			r.recordEvent(vcdMachine, corev1.EventTypeNormal, LoadBalancerPoolMemberAddedReason,
+				fmt.Sprintf("address [%s] added to the load balancer pool of the control plane endpoint",
+					machineAddress))
+		}
 	}
 
+	if !vcdMachine.Spec.Bootstrapped {
+		r.recordEvent(vcdMachine, corev1.EventTypeNormal, GuestCustomizationCompletedReason,
+			fmt.Sprintf("VM [%s] completed its guest customization and bootstrap", vm.VM.Name))
+	}
 	vcdMachine.Spec.Bootstrapped = true
 	conditions.MarkTrue(vcdMachine, BootstrapExecSucceededCondition)
 	// Set ProviderID so the Cluster API Machine Controller can pull it
 	providerID := fmt.Sprintf("%s://%s", infrav1beta3.VCDProviderID, vm.VM.ID)
 	vcdMachine.Spec.ProviderID = &providerID
 	vcdMachine.Status.Ready = true
-	vcdMachine.Status.Template = vcdMachine.Spec.Template
+	vcdMachine.Status.Template = getMachineTemplateName(vcdMachine)
 	vcdMachine.Status.ProviderID = vcdMachine.Spec.ProviderID
-	vcdMachine.Status.SizingPolicy = vcdMachine.Spec.SizingPolicy
-	vcdMachine.Status.PlacementPolicy = vcdMachine.Spec.PlacementPolicy
-	vcdMachine.Status.NvidiaGPUEnabled = vcdMachine.Spec.EnableNvidiaGPU
+	policies := getResolvedMachinePolicies(vcdMachine, vcdCluster)
+	vcdMachine.Status.SizingPolicy = policies.SizingPolicy
+	if vcdMachine.Spec.VmGroup == "" && vcdMachine.Spec.GPU == nil {
+		// the placement policy of a VM group or vGPU profile is recorded when the VM is created
+		vcdMachine.Status.PlacementPolicy = policies.PlacementPolicy
+	}
+	vcdMachine.Status.NvidiaGPUEnabled = isNvidiaGPUEnabled(vcdMachine.Spec)
 	conditions.MarkTrue(vcdMachine, ContainerProvisionedCondition)
 	return ctrl.Result{}, nil
 }
 
-func getVMName(machine *clusterv1.Machine, vcdMachine *infrav1beta3.VCDMachine, log logr.Logger) (string, error) {
-	if vcdMachine.Spec.VmNamingTemplate == "" {
-		return machine.Name, nil
+// bootDiskAdapterTypes maps the boot disk bus types of a VCDMachine to the VCD disk adapter types.
+var bootDiskAdapterTypes = map[string]string{
+	"paravirtual": "5",
+	"sata":        "6",
+	"nvme":        "7",
+}
+
+// getBootDisk returns the settings of the boot disk of the VM, i.e. the internal disk at unit 0 of bus 0, or nil if
+// there is none.
+func getBootDisk(vm *govcd.VM) *types.DiskSettings {
+	if vm.VM.VmSpecSection == nil || vm.VM.VmSpecSection.DiskSection == nil {
+		return nil
+	}
+	for _, diskSettings := range vm.VM.VmSpecSection.DiskSection.DiskSettings {
+		if diskSettings != nil && diskSettings.Disk == nil && diskSettings.BusNumber == 0 &&
+			diskSettings.UnitNumber == 0 {
+			return diskSettings
+		}
 	}
+	return nil
+}
 
-	vmNameTemplate, err := template.New("vmname").
-		Funcs(sprig.TxtFuncMap()).
-		Parse(vcdMachine.Spec.VmNamingTemplate)
-	if err != nil {
-		log.Error(err, "Error while parsing VmNamingTemplate of VCDMachine")
-		return "", errors.Wrapf(err, "Error while parsing VmNamingTemplate of VCDMachine")
+// reconcileBootDiskBusType moves the boot disk of the VM to a controller of the BootDiskBusType of the VCDMachine. The
+// controller is only changed between the creation of the VM and its first power on; a boot disk attached to another
+// controller afterwards is reported by the BootDiskBusTypeApplied condition.
+func reconcileBootDiskBusType(vm *govcd.VM, vcdMachine *infrav1beta3.VCDMachine) error {
+	busType := vcdMachine.Spec.BootDiskBusType
+	if busType == "" {
+		return nil
+	}
+	adapterType, ok := bootDiskAdapterTypes[busType]
+	if !ok {
+		return fmt.Errorf("unsupported boot disk bus type [%s]", busType)
+	}
+	bootDisk := getBootDisk(vm)
+	if bootDisk == nil {
+		return fmt.Errorf("no boot disk found at unit 0 of bus 0 of VM [%s]", vm.VM.Name)
+	}
+	if bootDisk.AdapterType == adapterType {
+		conditions.MarkTrue(vcdMachine, BootDiskBusTypeAppliedCondition)
+		return nil
 	}
 
-	buf := new(bytes.Buffer)
-	err = vmNameTemplate.Execute(buf, map[string]interface{}{
-		"machine":    machine,
-		"vcdMachine": vcdMachine,
-	})
+	vmStatus, err := vm.GetStatus()
 	if err != nil {
-		log.Error(err, "Error while generating VM Name by using VmNamingTemplate of VCDMachine")
-		return "", errors.Wrapf(err, "Error while generating VM Name by using VmNamingTemplate of VCDMachine")
+		return fmt.Errorf("failed to get status of VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	if vmStatus != "POWERED_OFF" || vcdMachine.Spec.Bootstrapped || vcdMachine.Status.BootstrapStartTime != nil {
+		conditions.MarkFalse(vcdMachine, BootDiskBusTypeAppliedCondition, BootDiskBusTypeDriftedReason,
+			clusterv1.ConditionSeverityWarning,
+			"boot disk of VM [%s] is attached to a controller of adapter type [%s] instead of [%s] (%s) since its "+
+				"first power on; replace the machine to change it", vm.VM.Name, bootDisk.AdapterType, adapterType,
+			busType)
+		return nil
+	}
+
+	bootDisk.AdapterType = adapterType
+	if _, err = vm.UpdateInternalDisks(vm.VM.VmSpecSection); err != nil {
+		return fmt.Errorf("failed to attach boot disk of VM [%s] to a [%s] controller: [%v]", vm.VM.Name, busType, err)
+	}
+	conditions.MarkTrue(vcdMachine, BootDiskBusTypeAppliedCondition)
+	return nil
+}
+
+// reconcileVMTemplateHash records the template hash of the VCDMachine in the metadata of its VM. VMs created before
+// the template hash was introduced have no hash and are left untouched.
+func reconcileVMTemplateHash(vm *govcd.VM, templateHash string) error {
+	if templateHash == "" {
+		return nil
+	}
+	metadataValue, err := vm.GetMetadataByKey(CapvcdTemplateHash, false)
+	if err == nil && metadataValue != nil && metadataValue.TypedValue != nil &&
+		metadataValue.TypedValue.Value == templateHash {
+		return nil
 	}
+	if err = vm.AddMetadataEntryWithVisibility(CapvcdTemplateHash, templateHash, types.MetadataStringValue,
+		types.MetadataReadWriteVisibility, false); err != nil {
+		return fmt.Errorf("failed to add metadata [%s: %s] to VM [%s]: [%v]", CapvcdTemplateHash, templateHash,
+			vm.VM.Name, err)
+	}
+	return nil
+}
+
+// getMachineRole returns the role of the machine in the cluster.
+func getMachineRole(machine *clusterv1.Machine) string {
+	if util.IsControlPlaneMachine(machine) {
+		return MachineRoleControlPlane
+	}
+	return MachineRoleWorker
+}
+
+// reconcileVMRole records the role of the machine in the metadata of its VM, so that VCD-side automation can tell
+// control plane VMs from worker VMs.
+func reconcileVMRole(vm *govcd.VM, role string) error {
+	metadataValue, err := vm.GetMetadataByKey(CapvcdMachineRole, false)
+	if err == nil && metadataValue != nil && metadataValue.TypedValue != nil &&
+		metadataValue.TypedValue.Value == role {
+		return nil
+	}
+	if err = vm.AddMetadataEntryWithVisibility(CapvcdMachineRole, role, types.MetadataStringValue,
+		types.MetadataReadWriteVisibility, false); err != nil {
+		return fmt.Errorf("failed to add metadata [%s: %s] to VM [%s]: [%v]", CapvcdMachineRole, role, vm.VM.Name,
+			err)
+	}
+	return nil
+}
 
-	return buf.String(), nil
+// setMachineAddresses sets the host name and the address of the VM in the status of the machine. Only the host name
+// is set while the VM waits for its DHCP lease.
+func setMachineAddresses(vcdMachine *infrav1beta3.VCDMachine, vmName string, machineAddress string) {
+	vcdMachine.Status.Addresses = []clusterv1.MachineAddress{
+		{
+			Type:    clusterv1.MachineHostName,
+			Address: vmName,
+		},
+	}
+	if machineAddress == "" {
+		return
+	}
+	vcdMachine.Status.Addresses = append(vcdMachine.Status.Addresses,
+		clusterv1.MachineAddress{
+			Type:    clusterv1.MachineInternalIP,
+			Address: machineAddress,
+		},
+		clusterv1.MachineAddress{
+			Type:    clusterv1.MachineExternalIP,
+			Address: machineAddress,
+		},
+	)
 }
 
 // getPrimaryNetwork returns the primary network based on vm.NetworkConnectionSection.PrimaryNetworkConnectionIndex
@@ -1088,9 +1980,69 @@ func getPrimaryNetwork(vm *types.Vm) *types.NetworkConnection {
 	return nil
 }
 
+// getIPAllocationMode returns the IP allocation mode of the NIC of a machine attached to the network of the cluster,
+// according to the role of the machine. An empty mode keeps the mode of the NIC, which is the case once the machine
+// is bootstrapped so that the address of a running node never changes.
+func getIPAllocationMode(machine *clusterv1.Machine, vcdMachine *infrav1beta3.VCDMachine,
+	vcdCluster *infrav1beta3.VCDCluster) string {
+
+	if vcdMachine.Spec.Bootstrapped {
+		return ""
+	}
+	if util.IsControlPlaneMachine(machine) {
+		return vcdCluster.Spec.IPAllocation.ControlPlane
+	}
+	return vcdCluster.Spec.IPAllocation.Workers
+}
+
+// getDesiredNetworks returns the networks the VM of a machine should be attached to, the primary network first.
+// Without VCDMachineSpec.Networks, the VM is attached to the network of the cluster in ipAllocationMode and to the
+// extra networks. An empty IP allocation mode leaves the mode of the existing NICs untouched.
+func getDesiredNetworks(vcdMachineSpec infrav1beta3.VCDMachineSpec, ovdcNetworkName string,
+	ipAllocationMode string) []infrav1beta3.NetworkSpec {
+
+	if len(vcdMachineSpec.Networks) > 0 {
+		return vcdMachineSpec.Networks
+	}
+	desiredNetworks := []infrav1beta3.NetworkSpec{{Name: ovdcNetworkName, IPAllocationMode: ipAllocationMode}}
+	for _, extraOvdcNetwork := range vcdMachineSpec.ExtraOvdcNetworks {
+		desiredNetworks = append(desiredNetworks, infrav1beta3.NetworkSpec{Name: extraOvdcNetwork})
+	}
+	return desiredNetworks
+}
+
+// validateNetworks checks that every network is attached once and that IP addresses are only set, and always set,
+// for NICs in MANUAL IP allocation mode. The addresses of NICs claiming them from an IPAM pool are not checked.
+func validateNetworks(networks []infrav1beta3.NetworkSpec) error {
+	networkNames := make(map[string]bool)
+	for _, network := range networks {
+		if network.Name == "" {
+			return fmt.Errorf("network name should not be empty")
+		}
+		if networkNames[network.Name] {
+			return fmt.Errorf("network [%s] is specified more than once", network.Name)
+		}
+		networkNames[network.Name] = true
+
+		if network.IPAddressPool != nil {
+			continue
+		}
+		if network.IPAllocationMode == IPAllocationModeManual && network.IPAddress == "" {
+			return fmt.Errorf("IP address of network [%s] should be set in IP allocation mode [%s]",
+				network.Name, IPAllocationModeManual)
+		}
+		if network.IPAllocationMode != IPAllocationModeManual && network.IPAddress != "" {
+			return fmt.Errorf("IP address [%s] of network [%s] can only be set in IP allocation mode [%s]",
+				network.IPAddress, network.Name, IPAllocationModeManual)
+		}
+	}
+	return nil
+}
+
 // reconcileVMNetworks ensures that desired networks are attached to VMs
 // networks[0] refers the primary network
-func (r *VCDMachineReconciler) reconcileVMNetworks(vdcManager *vcdsdk.VdcManager, vApp *govcd.VApp, vm *govcd.VM, networks []string) error {
+func (r *VCDMachineReconciler) reconcileVMNetworks(vdcManager *vcdsdk.VdcManager, vApp *govcd.VApp, vm *govcd.VM,
+	networks []infrav1beta3.NetworkSpec) error {
 	connections, err := vm.GetNetworkConnectionSection()
 	if err != nil {
 		return errors.Wrapf(err, "Failed to get attached networks to VM")
@@ -1099,9 +2051,9 @@ func (r *VCDMachineReconciler) reconcileVMNetworks(vdcManager *vcdsdk.VdcManager
 	desiredConnectionArray := make([]*types.NetworkConnection, len(networks))
 
 	for index, ovdcNetwork := range networks {
-		err = ensureNetworkIsAttachedToVApp(vdcManager, vApp, ovdcNetwork)
+		err = ensureNetworkIsAttachedToVApp(vdcManager, vApp, ovdcNetwork.Name)
 		if err != nil {
-			return errors.Wrapf(err, "Error ensuring network [%s] is attached to vApp", ovdcNetwork)
+			return errors.Wrapf(err, "Error ensuring network [%s] is attached to vApp", ovdcNetwork.Name)
 		}
 
 		desiredConnectionArray[index] = getNetworkConnection(connections, ovdcNetwork)
@@ -1146,19 +2098,35 @@ OUTER:
 	return true
 }
 
-func getNetworkConnection(connections *types.NetworkConnectionSection, ovdcNetwork string) *types.NetworkConnection {
+// getNetworkConnection returns the connection of the VM to ovdcNetwork, in the IP allocation mode of ovdcNetwork. An
+// empty IP allocation mode keeps the mode of an existing connection.
+func getNetworkConnection(connections *types.NetworkConnectionSection, ovdcNetwork infrav1beta3.NetworkSpec) *types.NetworkConnection {
 
 	for _, existingConnection := range connections.NetworkConnection {
-		if existingConnection.Network == ovdcNetwork {
+		if existingConnection.Network != ovdcNetwork.Name {
+			continue
+		}
+		if ovdcNetwork.IPAllocationMode == "" ||
+			(existingConnection.IPAddressAllocationMode == ovdcNetwork.IPAllocationMode &&
+				(ovdcNetwork.IPAllocationMode != IPAllocationModeManual || existingConnection.IPAddress == ovdcNetwork.IPAddress)) {
 			return existingConnection
 		}
+		desiredConnection := *existingConnection
+		desiredConnection.IPAddressAllocationMode = ovdcNetwork.IPAllocationMode
+		desiredConnection.IPAddress = ovdcNetwork.IPAddress
+		return &desiredConnection
 	}
 
+	ipAllocationMode := ovdcNetwork.IPAllocationMode
+	if ipAllocationMode == "" {
+		ipAllocationMode = IPAllocationModePool
+	}
 	return &types.NetworkConnection{
-		Network:                 ovdcNetwork,
+		Network:                 ovdcNetwork.Name,
 		NeedsCustomization:      false,
 		IsConnected:             true,
-		IPAddressAllocationMode: "POOL",
+		IPAddressAllocationMode: ipAllocationMode,
+		IPAddress:               ovdcNetwork.IPAddress,
 		NetworkAdapterType:      "VMXNET3",
 	}
 }
@@ -1184,7 +2152,8 @@ func ensureNetworkIsAttachedToVApp(vdcManager *vcdsdk.VdcManager, vApp *govcd.VA
 	return nil
 }
 
-func (r *VCDMachineReconciler) getBootstrapData(ctx context.Context, machine *clusterv1.Machine) (string, error) {
+func (r *VCDMachineReconciler) getBootstrapData(ctx context.Context, machine *clusterv1.Machine,
+	vcdMachine *infrav1beta3.VCDMachine) (string, error) {
 	log := ctrl.LoggerFrom(ctx)
 	if machine.Spec.Bootstrap.DataSecretName == nil {
 		return "", errors.New("error retrieving bootstrap data: linked Machine's bootstrap.dataSecretName is nil")
@@ -1202,6 +2171,9 @@ func (r *VCDMachineReconciler) getBootstrapData(ctx context.Context, machine *cl
 	if !ok {
 		return "", errors.New("error retrieving bootstrap data: secret value key is missing")
 	}
+	if err := validateBootstrapFormat(vcdMachine, string(s.Data["format"])); err != nil {
+		return "", err
+	}
 
 	log.V(2).Info(fmt.Sprintf("Auto-generated bootstrap script: [%s]", string(value)))
 
@@ -1212,6 +2184,9 @@ func (r *VCDMachineReconciler) reconcileDelete(ctx context.Context, machine *clu
 	vcdMachine *infrav1beta3.VCDMachine, vcdCluster *infrav1beta3.VCDCluster) (ctrl.Result, error) {
 	log := ctrl.LoggerFrom(ctx, "machine", machine.Name, "cluster", vcdCluster.Name)
 
+	// a VM whose creation did not start yet is not created for the machine being deleted
+	cancelMachineVMCreations(vcdMachine)
+
 	patchHelper, err := patch.NewHelper(vcdMachine, r.Client)
 	if err != nil {
 		return ctrl.Result{}, err
@@ -1219,7 +2194,7 @@ func (r *VCDMachineReconciler) reconcileDelete(ctx context.Context, machine *clu
 
 	conditions.MarkFalse(vcdMachine, ContainerProvisionedCondition,
 		clusterv1.DeletingReason, clusterv1.ConditionSeverityInfo, "")
-	if err := patchVCDMachine(ctx, patchHelper, vcdMachine); err != nil {
+	if err := patchVCDMachine(ctx, patchHelper, vcdMachine, false, nil); err != nil {
 		return ctrl.Result{}, errors.Wrapf(err, "Failed to patch VCDMachine [%s/%s]", vcdCluster.Name, vcdMachine.Name)
 	}
 
@@ -1230,6 +2205,12 @@ func (r *VCDMachineReconciler) reconcileDelete(ctx context.Context, machine *clu
 
 	vcdClient, err := createVCDClientFromSecrets(ctx, r.Client, vcdCluster)
 	if err != nil {
+		var credentialsErr *CredentialsExpiredError
+		if errors.As(err, &credentialsErr) {
+			// the VCDCluster reports the rejected credentials
+			log.Info("Waiting for the credentials of the cluster to be updated", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: CredentialsExpiredRequeueInterval}, nil
+		}
 		return ctrl.Result{}, errors.Wrapf(err, "Error creating VCD client to reconcile Cluster [%s] infrastructure", vcdCluster.Name)
 	}
 
@@ -1250,19 +2231,45 @@ func (r *VCDMachineReconciler) reconcileDelete(ctx context.Context, machine *clu
 		return ctrl.Result{}, errors.Wrapf(err, "Error updating vcdResource into vcdcluster.status to reconcile Cluster [%s] infrastructure", vcdCluster.Name)
 	}
 
+	if _, err = useMachineOvdc(vcdClient, vcdMachine); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Error getting the OVDC of the machine [%s] of cluster [%s]",
+			machine.Name, vcdCluster.Name)
+	}
+
 	capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
 	if err != nil {
 		return ctrl.Result{}, errors.Wrapf(err, "Unable to create VCD client to reconcile infrastructure for the Machine [%s]", machine.Name)
 	}
 
-	gateway, err := vcdsdk.NewGatewayManager(ctx, vcdClient, vcdCluster.Spec.OvdcNetwork,
-		vcdCluster.Spec.LoadBalancerConfigSpec.VipSubnet, vcdCluster.Spec.Ovdc)
-	if err != nil {
-		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name, fmt.Sprintf("%v", err))
-		return ctrl.Result{}, errors.Wrapf(err, "failed to create gateway manager object while reconciling machine [%s]", vcdMachine.Name)
+	if err = verifyVAppOwnershipClaim(ctx, r.Client, vcdClient, vcdCluster); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Error while deleting the infra resources of the machine [%s/%s]",
+			vcdCluster.Name, vcdMachine.Name)
 	}
 
-	if util.IsControlPlaneMachine(machine) {
+	var gateway *vcdsdk.GatewayManager
+	if isEdgeGatewayRequired(vcdCluster) {
+		gateway, err = vcdsdk.NewGatewayManager(ctx, vcdClient, getOvdcNetworkName(vcdCluster),
+			vcdCluster.Spec.LoadBalancerConfigSpec.VipSubnet, getOvdcName(vcdCluster))
+		if err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineCreationError, "", machine.Name, fmt.Sprintf("%v", err))
+			return ctrl.Result{}, errors.Wrapf(err, "failed to create gateway manager object while reconciling machine [%s]", vcdMachine.Name)
+		}
+	}
+
+	if util.IsControlPlaneMachine(machine) && isLoadBalancerManagedByCAPVCD(vcdCluster) &&
+		isControlPlaneEndpointDNAT(vcdCluster) {
+		// move the DNAT rule to another control plane machine if it forwards to this one
+		controlPlaneIPs, err := getControlPlaneMachineIPs(ctx, r.Client, machine.Namespace, machine.Spec.ClusterName)
+		if err == nil {
+			err = reconcileControlPlaneDNATRule(ctx, gateway, vcdCluster, controlPlaneIPs)
+		}
+		if err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.LoadBalancerError, "", machine.Name, fmt.Sprintf("%v", err))
+			return ctrl.Result{}, errors.Wrapf(err,
+				"Error while deleting the infra resources of the machine [%s/%s]; failed to update the DNAT rule of the control plane endpoint",
+				vcdCluster.Name, vcdMachine.Name)
+		}
+	} else if util.IsControlPlaneMachine(machine) && isLoadBalancerManagedByCAPVCD(vcdCluster) {
 		// remove the address from the lbpool
 		log.Info("Deleting the control plane IP from the load balancer pool")
 		lbPoolName := capisdk.GetLoadBalancerPoolNameUsingPrefix(
@@ -1322,6 +2329,13 @@ func (r *VCDMachineReconciler) reconcileDelete(ctx context.Context, machine *clu
 					"Error while deleting the infra resources of the machine [%s/%s]; error deleting the control plane from the load balancer pool [%s]",
 					vcdCluster.Name, vcdMachine.Name, lbPoolName)
 			}
+			if err = reconcileLoadBalancerPoolSettings(ctx, vcdClient, gateway, vcdCluster); err != nil {
+				capvcdRdeManager.AddToErrorSet(ctx, capisdk.LoadBalancerError, "", machine.Name, fmt.Sprintf("%v", err))
+
+				return ctrl.Result{}, errors.Wrapf(err,
+					"Error while deleting the infra resources of the machine [%s/%s]; failed to configure the load balancer pool [%s]",
+					vcdCluster.Name, vcdMachine.Name, lbPoolName)
+			}
 		}
 		err = capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD, capisdk.LoadBalancerError, "", "")
 		if err != nil {
@@ -1343,7 +2357,7 @@ func (r *VCDMachineReconciler) reconcileDelete(ctx context.Context, machine *clu
 	}
 
 	// get the vApp
-	vAppName := vcdCluster.Name
+	vAppName := getMachineVAppName(vcdMachine, vcdCluster)
 	vApp, err := vdcManager.Vdc.GetVAppByName(vAppName, true)
 	if err != nil {
 		if err == govcd.ErrorEntityNotFound {
@@ -1365,18 +2379,21 @@ func (r *VCDMachineReconciler) reconcileDelete(ctx context.Context, machine *clu
 		//	}
 		//	return ctrl.Result{}, errors.Errorf("Error occurred during the machine deletion; Metadata not found in vApp")
 		//}
-		metadataInfraId, err := vdcManager.GetMetadataByKey(vApp, CapvcdInfraId)
-		if err != nil {
-			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineError, "", machine.Name, fmt.Sprintf("failed to get metadata by key [%s]: %v", CapvcdInfraId, err))
+		// an unmanaged vApp is not tagged with the infra ID of the cluster
+		if !isMachineVAppUnmanaged(vcdMachine) {
+			metadataInfraId, err := vdcManager.GetMetadataByKey(vApp, CapvcdInfraId)
+			if err != nil {
+				capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineError, "", machine.Name, fmt.Sprintf("failed to get metadata by key [%s]: %v", CapvcdInfraId, err))
 
-			return ctrl.Result{}, errors.Errorf("Error occurred during fetching metadata in vApp")
-		}
-		// checking the metadata value and vcdCluster.Status.InfraId are equal or not
-		if metadataInfraId != vcdCluster.Status.InfraId {
-			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineError, "", machine.Name, fmt.Sprintf("%v", err))
+				return ctrl.Result{}, errors.Errorf("Error occurred during fetching metadata in vApp")
+			}
+			// checking the metadata value and vcdCluster.Status.InfraId are equal or not
+			if metadataInfraId != vcdCluster.Status.InfraId {
+				capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineError, "", machine.Name, fmt.Sprintf("%v", err))
 
-			return ctrl.Result{}, errors.Wrapf(err,
-				"Error occurred during the machine deletion; failed to delete vApp [%s]", vcdCluster.Name)
+				return ctrl.Result{}, errors.Wrapf(err,
+					"Error occurred during the machine deletion; failed to delete vApp [%s]", vAppName)
+			}
 		}
 		// Removed error: VCDClusterVappCreationError VCDMachineError
 		err = capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD, capisdk.VCDClusterVappCreationError, "", "")
@@ -1387,12 +2404,25 @@ func (r *VCDMachineReconciler) reconcileDelete(ctx context.Context, machine *clu
 		if err != nil {
 			log.Error(err, "failed to remove VCDMachineError from RDE", "rdeID", vcdCluster.Status.InfraId)
 		}
-		// delete the vm
-		vmName, err := getVMName(machine, vcdMachine, log)
-		if err != nil {
-			return ctrl.Result{}, err
+		// the machine being deleted is removed from the anti-affinity rule of its control plane or machine deployment
+		if err = r.reconcileAntiAffinityRule(ctx, vdcManager.Vdc, vApp, machine, vcdMachine, vcdCluster); err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineDeletionError, "", machine.Name, fmt.Sprintf("%v", err))
+
+			return ctrl.Result{}, errors.Wrapf(err, "error removing the machine [%s/%s] from its anti-affinity rule",
+				vAppName, machine.Name)
+		}
+
+		// delete the vm; the VM of a provisioned machine is found by its ID, as an adopted VM keeps its own name
+		var vm *govcd.VM
+		if vmID := getVMIDFromProviderID(vcdMachine.Status.ProviderID); vmID != "" {
+			vm, err = vApp.GetVMById(vmID, true)
+		} else {
+			var vmName string
+			if vmName, err = getVMName(machine, vcdMachine, vcdCluster, log); err != nil {
+				return ctrl.Result{}, err
+			}
+			vm, err = vApp.GetVMByName(vmName, true)
 		}
-		vm, err := vApp.GetVMByName(vmName, true)
 		if err != nil {
 			if err == govcd.ErrorEntityNotFound {
 				log.Error(err, "Error while deleting the machine; VM  not found")
@@ -1403,20 +2433,63 @@ func (r *VCDMachineReconciler) reconcileDelete(ctx context.Context, machine *clu
 			}
 		}
 		if vm != nil {
-			// check if there are any disks attached to the VM
-			if vm.VM.VmSpecSection != nil && vm.VM.VmSpecSection.DiskSection != nil {
-				for _, diskSettings := range vm.VM.VmSpecSection.DiskSection.DiskSettings {
-					if diskSettings.Disk != nil {
-						log.Info("Cannot delete VM until named disk is detached from VM (by CSI)",
-							"vm", vm.VM.Name, "disk", diskSettings.Disk.Name)
-						capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineDeletionError, "", machine.Name, fmt.Sprintf("%v", err))
+			// give the guest OS time to shut down before the VM is powered off, and before its data disks are detached
+			if !shutdownMachineVM(ctx, vm, vcdMachine) {
+				conditions.MarkFalse(vcdMachine, ContainerProvisionedCondition, VMShuttingDownReason,
+					clusterv1.ConditionSeverityInfo, "waiting for the guest OS of VM [%s] to shut down", vm.VM.Name)
+				return ctrl.Result{RequeueAfter: VMShutdownRequeueInterval}, nil
+			}
 
-						return ctrl.Result{}, fmt.Errorf(
-							"error deleting VM [%s] since named disk [%s] is attached to VM (by CSI)",
-							vm.VM.Name, diskSettings.Disk.Name)
-					}
+			// the VM of a machine replaced by an upgrade is kept with a snapshot as a rollback path; the VCDCluster
+			// controller deletes it once its retention elapsed
+			retain, err := r.shouldRetainMachineVM(ctx, machine, vcdMachine, vcdCluster)
+			if err == nil && retain {
+				err = retainMachineVM(ctx, vcdClient, vm, vcdMachine)
+			}
+			if err != nil {
+				capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineDeletionError, "", machine.Name, fmt.Sprintf("%v", err))
+
+				return ctrl.Result{}, errors.Wrapf(err, "error keeping the VM of the machine [%s/%s] with a snapshot",
+					vAppName, vm.VM.Name)
+			}
+			if retain {
+				r.recordEvent(vcdMachine, corev1.EventTypeNormal, VMSnapshottedReason, fmt.Sprintf(
+					"VM [%s] is kept powered off with a snapshot for [%s] as the machine is replaced by an upgrade",
+					vm.VM.Name, getSnapshotRetention(vcdMachine)))
+				vm = nil
+			}
+		}
+		if vm != nil {
+
+			// the data disks of the machine are deleted with it
+			if err = deleteDataDisks(ctx, vdcManager, vm, vcdMachine.Spec.DataDisks); err != nil {
+				capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineDeletionError, "", machine.Name, fmt.Sprintf("%v", err))
+
+				return ctrl.Result{}, errors.Wrapf(err, "error deleting the data disks of the machine [%s/%s]",
+					vAppName, vm.VM.Name)
+			}
+
+			// the VM is not deleted while VCD tasks run on it or its vApp, or while named disks are attached to it
+			reason, message, err := getVMDeletionBlocker(vApp, vm)
+			if err != nil {
+				capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineDeletionError, "", machine.Name, fmt.Sprintf("%v", err))
+
+				return ctrl.Result{}, errors.Wrapf(err, "error checking if the VM of the machine [%s/%s] can be deleted",
+					vAppName, vm.VM.Name)
+			}
+			if reason != "" {
+				severity := clusterv1.ConditionSeverityInfo
+				if reason == IndependentDisksAttachedReason {
+					severity = clusterv1.ConditionSeverityWarning
+					capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineDeletionError, "", machine.Name, message)
 				}
+				if markDeletionBlocked(vcdMachine, severity, reason, message) {
+					log.Info("Deletion of the VM is blocked", "vm", vm.VM.Name, "reason", reason, "details", message)
+					r.recordEvent(vcdMachine, corev1.EventTypeWarning, DeletionBlockedReason, message)
+				}
+				return ctrl.Result{RequeueAfter: DeletionBlockedRequeueInterval}, nil
 			}
+			markDeletionAllowed(vcdMachine)
 
 			// power-off the VM if it is powered on
 			vmStatus, err := vm.GetStatus()
@@ -1445,8 +2518,14 @@ func (r *VCDMachineReconciler) reconcileDelete(ctx context.Context, machine *clu
 				return ctrl.Result{}, errors.Wrapf(err, "error deleting the machine [%s/%s]", vAppName, vm.VM.Name)
 			}
 		}
+		if err = deleteEmptyMachineVApp(ctx, vcdClient, vdcManager, vApp, vcdMachine, vcdCluster); err != nil {
+			capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineDeletionError, "", machine.Name, fmt.Sprintf("%v", err))
+
+			return ctrl.Result{}, errors.Wrapf(err, "error deleting the vApp [%s] of the machine [%s]", vAppName,
+				machine.Name)
+		}
 		log.Info("Successfully deleted infra resources of the machine")
-		capvcdRdeManager.AddToEventSet(ctx, capisdk.InfraVmDeleted, "", machine.Name, "", true)
+		capvcdRdeManager.AddToEventSet(ctx, capisdk.InfraVmDeleted, "", machine.Name, "", false)
 
 		err = capvcdRdeManager.RdeManager.RemoveErrorByNameOrIdFromErrorSet(ctx, vcdsdk.ComponentCAPVCD, capisdk.VCDMachineDeletionError, "", machine.Name)
 		if err != nil {
@@ -1487,7 +2566,6 @@ func (r *VCDMachineReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Ma
 	c, err := ctrl.NewControllerManagedBy(mgr).
 		For(&infrav1beta3.VCDMachine{}).
 		WithOptions(options).
-		WithEventFilter(predicates.ResourceNotPaused(ctrl.LoggerFrom(ctx))).
 		Watches(
 			&source.Kind{Type: &clusterv1.Machine{}},
 			handler.EnqueueRequestsFromMapFunc(
@@ -1496,6 +2574,11 @@ func (r *VCDMachineReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Ma
 		Watches(
 			&source.Kind{Type: &infrav1beta3.VCDCluster{}},
 			handler.EnqueueRequestsFromMapFunc(r.VCDClusterToVCDMachines),
+			builder.WithPredicates(ignoreSubsystemRunUpdates()),
+		).
+		Watches(
+			&source.Kind{Type: &infrav1beta3.VCDMachineTemplate{}},
+			handler.EnqueueRequestsFromMapFunc(r.VCDMachineTemplateToVCDMachines),
 		).
 		Build(r)
 	if err != nil {
@@ -1542,6 +2625,32 @@ func (r *VCDMachineReconciler) VCDClusterToVCDMachines(o client.Object) []ctrl.R
 
 	return result
 }
+
+// VCDMachineTemplateToVCDMachines maps a VCDMachineTemplate to the VCDMachines cloned from it, so that changes of the
+// template which apply to provisioned machines, e.g. a larger disk size, are reconciled.
+func (r *VCDMachineReconciler) VCDMachineTemplateToVCDMachines(o client.Object) []ctrl.Request {
+	var result []ctrl.Request
+	t, ok := o.(*infrav1beta3.VCDMachineTemplate)
+	if !ok {
+		klog.Errorf("Expected a VCDMachineTemplate found [%T]", o)
+		return nil
+	}
+
+	vcdMachineList := &infrav1beta3.VCDMachineList{}
+	if err := r.Client.List(context.TODO(), vcdMachineList, client.InNamespace(t.Namespace)); err != nil {
+		return nil
+	}
+	for _, m := range vcdMachineList.Items {
+		if m.Annotations[clusterv1.TemplateClonedFromNameAnnotation] != t.Name {
+			continue
+		}
+		name := client.ObjectKey{Namespace: m.Namespace, Name: m.Name}
+		result = append(result, ctrl.Request{NamespacedName: name})
+	}
+
+	return result
+}
+
 func (r *VCDMachineReconciler) hasCloudInitExecutionFailedBefore(vcdClient *vcdsdk.Client, vm *govcd.VM) (bool, error) {
 	vdcManager, err := vcdsdk.NewVDCManager(vcdClient, vcdClient.ClusterOrgName,
 		vcdClient.ClusterOVDCName)
@@ -1572,6 +2681,32 @@ func (r *VCDMachineReconciler) hasCloudInitExecutionFailedBefore(vcdClient *vcds
 	return false, nil
 }
 
+// getKubeletExtraArgs returns the kubelet command line arguments of a kubelet configuration, sorted by name.
+func getKubeletExtraArgs(kubeletConfig *infrav1beta3.KubeletConfig) string {
+	if kubeletConfig == nil {
+		return ""
+	}
+	kubeletArgs := make(map[string]string)
+	for name, value := range kubeletConfig.ExtraArgs {
+		kubeletArgs[name] = value
+	}
+	if len(kubeletConfig.SystemReserved) > 0 {
+		reservations := make([]string, 0, len(kubeletConfig.SystemReserved))
+		for resourceName, quantity := range kubeletConfig.SystemReserved {
+			reservations = append(reservations, fmt.Sprintf("%s=%s", resourceName, quantity.String()))
+		}
+		sort.Strings(reservations)
+		kubeletArgs["system-reserved"] = strings.Join(reservations, ",")
+	}
+
+	args := make([]string, 0, len(kubeletArgs))
+	for name, value := range kubeletArgs {
+		args = append(args, fmt.Sprintf("--%s=%s", name, value))
+	}
+	sort.Strings(args)
+	return strings.Join(args, " ")
+}
+
 // MergeJinjaToCloudInitScript : merges the cloud init config with a jinja config and adds a
 // `#cloudconfig` header. Does a couple of special handling: takes jinja's runcmd and embeds
 // it into a fixed location in the cloudInitConfig. Returns the merged bytes or nil and error.
@@ -1673,3 +2808,10 @@ func MergeJinjaToCloudInitScript(cloudInitConfig CloudInitScriptInput, jinjaConf
 
 	return out, nil
 }
+
+func (r *VCDMachineReconciler) recordEvent(obj runtime.Object, eventType string, reason string, message string) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Event(obj, eventType, reason, message)
+}
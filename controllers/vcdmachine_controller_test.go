@@ -0,0 +1,178 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestGetKubeletExtraArgs(t *testing.T) {
+	testCases := []struct {
+		name          string
+		kubeletConfig *infrav1beta3.KubeletConfig
+		want          string
+	}{
+		{name: "no kubelet configuration", want: ""},
+		{name: "empty kubelet configuration", kubeletConfig: &infrav1beta3.KubeletConfig{}, want: ""},
+		{
+			name: "extra arguments are sorted by name",
+			kubeletConfig: &infrav1beta3.KubeletConfig{ExtraArgs: map[string]string{
+				"max-pods":      "200",
+				"eviction-hard": "memory.available<100Mi",
+			}},
+			want: "--eviction-hard=memory.available<100Mi --max-pods=200",
+		},
+		{
+			name: "system reservations are sorted by resource",
+			kubeletConfig: &infrav1beta3.KubeletConfig{SystemReserved: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("512Mi"),
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+			}},
+			want: "--system-reserved=cpu=500m,memory=512Mi",
+		},
+		{
+			name: "system reservations take precedence over the extra argument",
+			kubeletConfig: &infrav1beta3.KubeletConfig{
+				ExtraArgs:      map[string]string{"system-reserved": "cpu=1", "node-labels": "tier=web"},
+				SystemReserved: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+			},
+			want: "--node-labels=tier=web --system-reserved=cpu=2",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := getKubeletExtraArgs(tc.kubeletConfig); got != tc.want {
+				t.Errorf("got arguments [%s], want [%s]", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetDesiredNetworks(t *testing.T) {
+	testCases := []struct {
+		name             string
+		spec             infrav1beta3.VCDMachineSpec
+		ovdcNetworkName  string
+		ipAllocationMode string
+		want             []infrav1beta3.NetworkSpec
+	}{
+		{
+			name:             "network of the cluster",
+			ovdcNetworkName:  "cluster-network",
+			ipAllocationMode: IPAllocationModePool,
+			want:             []infrav1beta3.NetworkSpec{{Name: "cluster-network", IPAllocationMode: IPAllocationModePool}},
+		},
+		{
+			name:             "extra networks follow the network of the cluster",
+			spec:             infrav1beta3.VCDMachineSpec{ExtraOvdcNetworks: []string{"storage", "backup"}},
+			ovdcNetworkName:  "cluster-network",
+			ipAllocationMode: IPAllocationModeDHCP,
+			want: []infrav1beta3.NetworkSpec{
+				{Name: "cluster-network", IPAllocationMode: IPAllocationModeDHCP},
+				{Name: "storage"},
+				{Name: "backup"},
+			},
+		},
+		{
+			name: "networks of the machine replace the network of the cluster and the extra networks",
+			spec: infrav1beta3.VCDMachineSpec{
+				ExtraOvdcNetworks: []string{"storage"},
+				Networks: []infrav1beta3.NetworkSpec{
+					{Name: "primary", IPAllocationMode: IPAllocationModeManual, IPAddress: "10.0.0.10"},
+				},
+			},
+			ovdcNetworkName:  "cluster-network",
+			ipAllocationMode: IPAllocationModePool,
+			want: []infrav1beta3.NetworkSpec{
+				{Name: "primary", IPAllocationMode: IPAllocationModeManual, IPAddress: "10.0.0.10"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := getDesiredNetworks(tc.spec, tc.ovdcNetworkName, tc.ipAllocationMode)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got networks %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateNetworks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		networks []infrav1beta3.NetworkSpec
+		wantErr  bool
+	}{
+		{name: "no networks"},
+		{name: "valid networks", networks: []infrav1beta3.NetworkSpec{
+			{Name: "primary", IPAllocationMode: IPAllocationModeManual, IPAddress: "10.0.0.10"},
+			{Name: "storage", IPAllocationMode: IPAllocationModePool},
+			{Name: "backup"},
+		}},
+		{name: "network without name", networks: []infrav1beta3.NetworkSpec{{}}, wantErr: true},
+		{name: "network attached twice", networks: []infrav1beta3.NetworkSpec{
+			{Name: "primary"}, {Name: "primary"},
+		}, wantErr: true},
+		{name: "manual mode without address", networks: []infrav1beta3.NetworkSpec{
+			{Name: "primary", IPAllocationMode: IPAllocationModeManual},
+		}, wantErr: true},
+		{name: "address in pool mode", networks: []infrav1beta3.NetworkSpec{
+			{Name: "primary", IPAllocationMode: IPAllocationModePool, IPAddress: "10.0.0.10"},
+		}, wantErr: true},
+		{name: "addresses claimed from an IPAM pool are not checked", networks: []infrav1beta3.NetworkSpec{
+			{Name: "primary", IPAllocationMode: IPAllocationModeManual,
+				IPAddressPool: &corev1.TypedLocalObjectReference{Kind: "InClusterIPPool", Name: "pool"}},
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateNetworks(tc.networks)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("got error [%v], want error [%t]", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetBootDisk(t *testing.T) {
+	newVM := func(diskSettings ...*types.DiskSettings) *govcd.VM {
+		return &govcd.VM{VM: &types.Vm{VmSpecSection: &types.VmSpecSection{
+			DiskSection: &types.DiskSection{DiskSettings: diskSettings},
+		}}}
+	}
+	bootDisk := &types.DiskSettings{DiskId: "2000", BusNumber: 0, UnitNumber: 0, AdapterType: "5"}
+	dataDisk := &types.DiskSettings{DiskId: "2001", BusNumber: 0, UnitNumber: 1, AdapterType: "5"}
+	otherBusDisk := &types.DiskSettings{DiskId: "16000", BusNumber: 1, UnitNumber: 0, AdapterType: "6"}
+	independentDisk := &types.DiskSettings{DiskId: "2002", BusNumber: 0, UnitNumber: 0, AdapterType: "5",
+		Disk: &types.Reference{Name: "pv-disk"}}
+
+	testCases := []struct {
+		name string
+		vm   *govcd.VM
+		want *types.DiskSettings
+	}{
+		{name: "no disk section", vm: &govcd.VM{VM: &types.Vm{}}},
+		{name: "boot disk listed first", vm: newVM(bootDisk, dataDisk), want: bootDisk},
+		{name: "boot disk listed after other disks", vm: newVM(otherBusDisk, dataDisk, bootDisk), want: bootDisk},
+		{name: "independent disks are not boot disks", vm: newVM(independentDisk, dataDisk)},
+		{name: "no disk at unit 0 of bus 0", vm: newVM(dataDisk, otherBusDisk)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := getBootDisk(tc.vm); got != tc.want {
+				t.Errorf("got boot disk %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,830 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/cluster-api/util/predicates"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// MachinePoolRequeueInterval is the interval at which a VCDMachinePool whose VMs do not match its MachinePool yet
+	// is reconciled again.
+	MachinePoolRequeueInterval = 30 * time.Second
+	// machinePoolVMNameSuffixLength is the length of the random suffix of the names of the VMs created together.
+	machinePoolVMNameSuffixLength = 5
+)
+
+// VCDMachinePoolReconciler reconciles a VCDMachinePool object
+type VCDMachinePoolReconciler struct {
+	client.Client
+	Recorder record.EventRecorder
+	// Shards splits the clusters between the active replicas of the manager; all the clusters are reconciled if nil.
+	Shards *ClusterShardManager
+}
+
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vcdmachinepools,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vcdmachinepools/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vcdmachinepools/finalizers,verbs=update
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machinepools;machinepools/status,verbs=get;list;watch
+
+func (r *VCDMachinePoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, rerr error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	vcdMachinePool := &infrav1beta3.VCDMachinePool{}
+	if err := r.Client.Get(ctx, req.NamespacedName, vcdMachinePool); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	machinePool, err := getOwnerMachinePool(ctx, r.Client, vcdMachinePool.ObjectMeta)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if machinePool == nil {
+		log.Info("Waiting for MachinePool Controller to set OwnerRef on VCDMachinePool")
+		return ctrl.Result{}, nil
+	}
+
+	log = log.WithValues("machinePool", machinePool.Name)
+
+	cluster, err := util.GetClusterFromMetadata(ctx, r.Client, machinePool.ObjectMeta)
+	if err != nil {
+		log.Info("VCDMachinePool owner MachinePool is missing cluster label or cluster does not exist")
+		return ctrl.Result{}, err
+	}
+	if cluster == nil {
+		log.Info("Please associate this machine pool with a cluster using the label", "label",
+			clusterv1.ClusterNameLabel)
+		return ctrl.Result{}, nil
+	}
+
+	log = log.WithValues("cluster", cluster.Name)
+	ctx = ctrl.LoggerInto(ctx, log)
+	claimed, err := r.Shards.ClaimCluster(ctx, cluster.Namespace, cluster.Name, cluster)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Error claiming Cluster [%s]", cluster.Name)
+	}
+	if !claimed {
+		log.V(4).Info("Skipping machine pool of a cluster owned by another replica")
+		return ctrl.Result{RequeueAfter: r.Shards.GetRequeueAfter()}, nil
+	}
+
+	vcdCluster := &infrav1beta3.VCDCluster{}
+	vcdClusterName := client.ObjectKey{
+		Namespace: vcdMachinePool.Namespace,
+		Name:      cluster.Spec.InfrastructureRef.Name,
+	}
+	if err := r.Client.Get(ctx, vcdClusterName, vcdCluster); err != nil {
+		log.Info("VCDCluster is not available yet")
+		return ctrl.Result{}, nil
+	}
+
+	patchHelper, err := patch.NewHelper(vcdMachinePool, r)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	// Always attempt to Patch the VCDMachinePool object and status after each reconciliation.
+	defer func() {
+		if err := patchVCDMachinePool(ctx, patchHelper, vcdMachinePool); err != nil {
+			log.Error(err, "Failed to patch VCDMachinePool")
+			if rerr == nil {
+				rerr = err
+			}
+		}
+	}()
+
+	if annotations.IsPaused(cluster, vcdMachinePool) {
+		log.Info("Reconciliation is paused for this object")
+		return ctrl.Result{}, nil
+	}
+
+	// Add finalizer first if not exist to avoid the race condition between init and delete
+	if !controllerutil.ContainsFinalizer(vcdMachinePool, infrav1beta3.MachinePoolFinalizer) {
+		controllerutil.AddFinalizer(vcdMachinePool, infrav1beta3.MachinePoolFinalizer)
+		return ctrl.Result{}, nil
+	}
+
+	if !vcdMachinePool.ObjectMeta.DeletionTimestamp.IsZero() {
+		if isClusterBeingDeleted(cluster, vcdCluster) && isDeletionProtected(cluster, vcdCluster) {
+			log.Info("Deletion of the machine pool is blocked by the deletion protection annotation of the cluster",
+				"annotation", DeletionProtectedAnnotation)
+			r.recordEvent(vcdMachinePool, corev1.EventTypeWarning, DeletionBlockedReason, fmt.Sprintf(
+				"cluster is protected from deletion; remove the annotation [%s] of the cluster to delete the VMs",
+				DeletionProtectedAnnotation))
+			return ctrl.Result{RequeueAfter: DeletionProtectedRequeueInterval}, nil
+		}
+		return r.reconcileDelete(ctx, vcdMachinePool, vcdCluster)
+	}
+
+	if !cluster.Status.InfrastructureReady {
+		log.Info("Waiting for VCDCluster Controller to create cluster infrastructure")
+		conditions.MarkFalse(vcdMachinePool, InstancesReadyCondition, WaitingForClusterInfrastructureReason,
+			clusterv1.ConditionSeverityInfo, "")
+		return ctrl.Result{}, nil
+	}
+
+	return r.reconcileNormal(ctx, cluster, machinePool, vcdMachinePool, vcdCluster)
+}
+
+// patchVCDMachinePool patches the VCDMachinePool after summarizing its conditions into the Ready condition.
+func patchVCDMachinePool(ctx context.Context, patchHelper *patch.Helper,
+	vcdMachinePool *infrav1beta3.VCDMachinePool) error {
+	conditions.SetSummary(vcdMachinePool,
+		conditions.WithConditions(
+			InstancesReadyCondition,
+		),
+		conditions.WithStepCounterIf(vcdMachinePool.ObjectMeta.DeletionTimestamp.IsZero()),
+	)
+
+	return patchHelper.Patch(
+		ctx,
+		vcdMachinePool,
+		patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{
+			clusterv1.ReadyCondition,
+			InstancesReadyCondition,
+		}},
+	)
+}
+
+// getOwnerMachinePool returns the MachinePool owning the object, or nil if it has no MachinePool owner yet.
+func getOwnerMachinePool(ctx context.Context, cli client.Client, obj metav1.ObjectMeta) (*expv1.MachinePool, error) {
+	for _, ref := range obj.OwnerReferences {
+		if ref.Kind != "MachinePool" {
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			return nil, err
+		}
+		if gv.Group != expv1.GroupVersion.Group {
+			continue
+		}
+		machinePool := &expv1.MachinePool{}
+		if err = cli.Get(ctx, client.ObjectKey{Namespace: obj.Namespace, Name: ref.Name}, machinePool); err != nil {
+			return nil, err
+		}
+		return machinePool, nil
+	}
+	return nil, nil
+}
+
+// getMachinePoolTemplateHash returns the hash of the template of the pool and of the bootstrap data secret of its
+// MachinePool; the VMs created from another hash are replaced.
+func getMachinePoolTemplateHash(template infrav1beta3.VCDMachinePoolTemplate, dataSecretName string) (string, error) {
+	templateBytes, err := json.Marshal(struct {
+		Template       infrav1beta3.VCDMachinePoolTemplate `json:"template"`
+		DataSecretName string                              `json:"dataSecretName"`
+	}{template, dataSecretName})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal VCDMachinePool template: [%v]", err)
+	}
+	hash := sha256.Sum256(templateBytes)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// getMachinePoolVMNames returns the names of the VMs created together with the prefix, as named by VCD.
+func getMachinePoolVMNames(prefix string, count int) []string {
+	if count == 1 {
+		return []string{prefix}
+	}
+	names := make([]string, count)
+	for i := range names {
+		names[i] = prefix + strconv.Itoa(i)
+	}
+	return names
+}
+
+// sortInstancesForDeletion orders the instances of the pool to delete the outdated ones first, then the ones which
+// are not ready, then the newest ones.
+func sortInstancesForDeletion(instances []infrav1beta3.VCDMachinePoolInstance, templateHash string) {
+	rank := func(instance infrav1beta3.VCDMachinePoolInstance) int {
+		switch {
+		case instance.TemplateHash != templateHash:
+			return 0
+		case !instance.Ready:
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(instances, func(i, j int) bool {
+		return rank(instances[i]) < rank(instances[j])
+	})
+}
+
+// countReadyInstances returns the number of instances of the pool whose node has joined the cluster.
+func countReadyInstances(instances []infrav1beta3.VCDMachinePoolInstance) int {
+	ready := 0
+	for _, instance := range instances {
+		if instance.Ready {
+			ready++
+		}
+	}
+	return ready
+}
+
+func (r *VCDMachinePoolReconciler) reconcileNormal(ctx context.Context, cluster *clusterv1.Cluster,
+	machinePool *expv1.MachinePool, vcdMachinePool *infrav1beta3.VCDMachinePool,
+	vcdCluster *infrav1beta3.VCDCluster) (ctrl.Result, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	dataSecretName := machinePool.Spec.Template.Spec.Bootstrap.DataSecretName
+	if dataSecretName == nil {
+		log.Info("Waiting for the bootstrap provider to generate the bootstrap data of the machine pool")
+		conditions.MarkFalse(vcdMachinePool, InstancesReadyCondition, WaitingForBootstrapDataReason,
+			clusterv1.ConditionSeverityInfo, "")
+		return ctrl.Result{}, nil
+	}
+	desiredReplicas := 1
+	if machinePool.Spec.Replicas != nil {
+		desiredReplicas = int(*machinePool.Spec.Replicas)
+	}
+	templateHash, err := getMachinePoolTemplateHash(vcdMachinePool.Spec.Template, *dataSecretName)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Error computing the template hash of machine pool [%s]",
+			vcdMachinePool.Name)
+	}
+
+	vcdClient, err := createVCDClientFromSecrets(ctx, r.Client, vcdCluster)
+	if err != nil {
+		var credentialsErr *CredentialsExpiredError
+		if errors.As(err, &credentialsErr) {
+			// the VCDCluster reports the rejected credentials
+			log.Info("Waiting for the credentials of the cluster to be updated", "reason", err.Error())
+			return ctrl.Result{RequeueAfter: CredentialsExpiredRequeueInterval}, nil
+		}
+		return ctrl.Result{}, errors.Wrapf(err, "Error creating VCD client to reconcile machine pool [%s]",
+			vcdMachinePool.Name)
+	}
+	// close all idle connections when reconciliation is done
+	defer func() {
+		if vcdClient != nil && vcdClient.VCDClient != nil {
+			vcdClient.VCDClient.Client.Http.CloseIdleConnections()
+		}
+	}()
+
+	// don't attempt to create VMs in an OVDC disabled by the provider; resume once it is enabled again
+	if isOvdcDisabled(vcdClient) {
+		log.Info("Waiting for the OVDC of the cluster to be enabled", "ovdc", vcdClient.ClusterOVDCName)
+		conditions.MarkFalse(vcdMachinePool, InstancesReadyCondition, OvdcDisabledByProviderReason,
+			clusterv1.ConditionSeverityWarning, "OVDC [%s] is disabled by the provider", vcdClient.ClusterOVDCName)
+		return ctrl.Result{RequeueAfter: OvdcDisabledRequeueInterval}, nil
+	}
+	if err = verifyVAppOwnershipClaim(ctx, r.Client, vcdClient, vcdCluster); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Unable to provision the machine pool [%s] of cluster [%s]",
+			vcdMachinePool.Name, vcdCluster.Name)
+	}
+
+	vdcManager, err := vcdsdk.NewVDCManager(vcdClient, vcdClient.ClusterOrgName, vcdClient.ClusterOVDCName)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Error creating vdc manager to reconcile machine pool [%s]",
+			vcdMachinePool.Name)
+	}
+	// the vApp of the cluster is created along with its first control plane machine
+	vAppName := CreateFullVAppName(vcdCluster)
+	vApp, err := vdcManager.Vdc.GetVAppByName(vAppName, true)
+	if err == govcd.ErrorEntityNotFound {
+		log.Info("Waiting for the vApp of the cluster to be created", "vAppName", vAppName)
+		conditions.MarkFalse(vcdMachinePool, InstancesReadyCondition, WaitingForClusterInfrastructureReason,
+			clusterv1.ConditionSeverityInfo, "vApp [%s] does not exist yet", vAppName)
+		return ctrl.Result{RequeueAfter: MachinePoolRequeueInterval}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Error getting vApp [%s] of machine pool [%s]", vAppName,
+			vcdMachinePool.Name)
+	}
+
+	// the instances whose VM was deleted out of band are forgotten and replaced
+	instances := make([]infrav1beta3.VCDMachinePoolInstance, 0, len(vcdMachinePool.Status.Instances))
+	for _, instance := range vcdMachinePool.Status.Instances {
+		vm, err := vApp.GetVMByName(instance.Name, true)
+		if err == govcd.ErrorEntityNotFound {
+			log.Info("VM of the machine pool no longer exists", "vm", instance.Name)
+			continue
+		}
+		if err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "Error getting VM [%s] of machine pool [%s]", instance.Name,
+				vcdMachinePool.Name)
+		}
+		instance.ProviderID = fmt.Sprintf("%s://%s", infrav1beta3.VCDProviderID, vm.VM.ID)
+		instances = append(instances, instance)
+	}
+	vcdMachinePool.Status.Instances = instances
+	defer updateMachinePoolStatus(vcdMachinePool, desiredReplicas, templateHash)
+
+	// a VM which failed to be powered on is retried before the pool is scaled or rolled out, so that the outdated VMs
+	// are only deleted once their replacements are up
+	if err = r.reconcileInstancesBootstrap(ctx, cluster, machinePool, vcdMachinePool, vcdCluster, vdcManager, vApp,
+		templateHash); err != nil {
+		conditions.MarkFalse(vcdMachinePool, InstancesReadyCondition, InstancesProvisioningFailedReason,
+			clusterv1.ConditionSeverityWarning, err.Error())
+		return ctrl.Result{}, err
+	}
+
+	// scale down, deleting the outdated VMs first. A ready VM is only deleted if as many VMs as replicas remain
+	// ready, so that an outdated VM is kept until its replacement has joined the cluster.
+	if excess := len(vcdMachinePool.Status.Instances) - desiredReplicas; excess > 0 {
+		sortInstancesForDeletion(vcdMachinePool.Status.Instances, templateHash)
+		readyInstances := countReadyInstances(vcdMachinePool.Status.Instances)
+		for excess > 0 {
+			instance := vcdMachinePool.Status.Instances[0]
+			if instance.Ready && readyInstances <= desiredReplicas {
+				log.Info("Waiting for the new VMs of the machine pool to join the cluster before deleting VM",
+					"vm", instance.Name)
+				break
+			}
+			reason := ScalingDownReason
+			if instance.TemplateHash != templateHash {
+				reason = RollingUpdateReason
+			}
+			conditions.MarkFalse(vcdMachinePool, InstancesReadyCondition, reason, clusterv1.ConditionSeverityInfo,
+				"deleting VM [%s]", instance.Name)
+			if err = deleteMachinePoolVM(vApp, instance.Name); err != nil {
+				conditions.MarkFalse(vcdMachinePool, InstancesReadyCondition, InstancesProvisioningFailedReason,
+					clusterv1.ConditionSeverityWarning, err.Error())
+				return ctrl.Result{}, errors.Wrapf(err, "Error scaling down machine pool [%s]", vcdMachinePool.Name)
+			}
+			log.Info("Deleted VM of the machine pool", "vm", instance.Name, "reason", reason)
+			if instance.Ready {
+				readyInstances--
+			}
+			vcdMachinePool.Status.Instances = vcdMachinePool.Status.Instances[1:]
+			excess--
+		}
+	}
+
+	// scale up, with a single VM above the replicas while the outdated VMs are replaced
+	upToDate, outdated := 0, 0
+	for _, instance := range vcdMachinePool.Status.Instances {
+		if instance.TemplateHash == templateHash {
+			upToDate++
+		} else {
+			outdated++
+		}
+	}
+	missing := desiredReplicas - upToDate
+	if outdated > 0 && missing > desiredReplicas+1-len(vcdMachinePool.Status.Instances) {
+		missing = desiredReplicas + 1 - len(vcdMachinePool.Status.Instances)
+	}
+	if missing > 0 {
+		// the scale up and rollout of the pool wait for the end of the maintenance of the OVDC
+		if underMaintenance, description := isOvdcUnderMaintenance(vcdCluster); underMaintenance {
+			log.Info("Waiting for the end of the maintenance of the OVDC of the cluster",
+				"ovdc", vcdClient.ClusterOVDCName, "maintenance", description)
+			conditions.MarkFalse(vcdMachinePool, InstancesReadyCondition, OvdcMaintenanceReason,
+				clusterv1.ConditionSeverityInfo, "%s", getOvdcMaintenanceMessage(vcdCluster, description))
+			return ctrl.Result{RequeueAfter: OvdcMaintenanceRequeueInterval}, nil
+		}
+		reason := ScalingUpReason
+		if outdated > 0 {
+			reason = RollingUpdateReason
+		}
+		conditions.MarkFalse(vcdMachinePool, InstancesReadyCondition, reason, clusterv1.ConditionSeverityInfo,
+			"creating [%d] VMs", missing)
+		res, err := r.createMachinePoolVMs(ctx, vcdClient, vdcManager, vApp, vcdMachinePool, vcdCluster, missing,
+			templateHash)
+		if err != nil {
+			conditions.MarkFalse(vcdMachinePool, InstancesReadyCondition, InstancesProvisioningFailedReason,
+				clusterv1.ConditionSeverityWarning, err.Error())
+			return ctrl.Result{}, err
+		}
+		if !res.IsZero() {
+			return res, nil
+		}
+		if err = r.reconcileInstancesBootstrap(ctx, cluster, machinePool, vcdMachinePool, vcdCluster, vdcManager,
+			vApp, templateHash); err != nil {
+			conditions.MarkFalse(vcdMachinePool, InstancesReadyCondition, InstancesProvisioningFailedReason,
+				clusterv1.ConditionSeverityWarning, err.Error())
+			return ctrl.Result{}, err
+		}
+	}
+
+	if outdated > 0 || len(vcdMachinePool.Status.Instances) != desiredReplicas ||
+		countReadyInstances(vcdMachinePool.Status.Instances) != desiredReplicas {
+		return ctrl.Result{RequeueAfter: MachinePoolRequeueInterval}, nil
+	}
+	conditions.MarkTrue(vcdMachinePool, InstancesReadyCondition)
+	return ctrl.Result{}, nil
+}
+
+// updateMachinePoolStatus reports the provider IDs of the ready VMs of the pool to its MachinePool.
+func updateMachinePoolStatus(vcdMachinePool *infrav1beta3.VCDMachinePool, desiredReplicas int, templateHash string) {
+	providerIDs := make([]string, 0, len(vcdMachinePool.Status.Instances))
+	settled := len(vcdMachinePool.Status.Instances) == desiredReplicas
+	for _, instance := range vcdMachinePool.Status.Instances {
+		if !instance.Ready || instance.ProviderID == "" {
+			settled = false
+			continue
+		}
+		if instance.TemplateHash != templateHash {
+			settled = false
+		}
+		providerIDs = append(providerIDs, instance.ProviderID)
+	}
+	sort.Strings(providerIDs)
+	vcdMachinePool.Spec.ProviderIDList = providerIDs
+	vcdMachinePool.Status.Replicas = int32(len(providerIDs))
+	vcdMachinePool.Status.Ready = settled
+}
+
+// createMachinePoolVMs creates the VMs of the pool in a single recomposition of the vApp, recording them as instances
+// of the pool before they are created.
+func (r *VCDMachinePoolReconciler) createMachinePoolVMs(ctx context.Context, vcdClient *vcdsdk.Client,
+	vdcManager *vcdsdk.VdcManager, vApp *govcd.VApp, vcdMachinePool *infrav1beta3.VCDMachinePool,
+	vcdCluster *infrav1beta3.VCDCluster, count int, templateHash string) (ctrl.Result, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+	template := vcdMachinePool.Spec.Template
+
+	// VM creations are spaced while the task queue of the org is saturated
+	if retryAfter := reserveTaskSlot(vcdCluster.Spec.Site, vcdClient.ClusterOrgName); retryAfter > 0 {
+		log.Info("Waiting for the task queue of the org to free up before creating the VMs",
+			"org", vcdClient.ClusterOrgName, "retryAfter", retryAfter)
+		return ctrl.Result{RequeueAfter: retryAfter}, nil
+	}
+	ovdcNetworkName := getOvdcNetworkName(vcdCluster)
+	ipPoolKey := getIPPoolKey(vcdCluster.Spec.Site, vcdClient.ClusterOrgName, ovdcNetworkName)
+	if isIPPoolExhausted(ipPoolKey) {
+		conditions.MarkFalse(vcdMachinePool, InstancesReadyCondition, IPPoolExhaustedReason,
+			clusterv1.ConditionSeverityWarning, "IP pool of OVDC network [%s] is exhausted", ovdcNetworkName)
+		return ctrl.Result{RequeueAfter: IPPoolExhaustedRequeueInterval}, nil
+	}
+
+	// policies omitted in the pool are inherited from the VCDCluster
+	policies := getMachinePolicies(infrav1beta3.VCDMachineSpec{
+		SizingPolicy:    template.SizingPolicy,
+		PlacementPolicy: template.PlacementPolicy,
+		StorageProfile:  template.StorageProfile,
+	}, vcdCluster)
+	// a VM group is honoured through the placement policy referencing it
+	placementPolicy, err := resolveVmGroupPlacementPolicy(vdcManager, template.VmGroup, policies.PlacementPolicy)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Error placing the VMs of machine pool [%s] in VM group [%s]",
+			vcdMachinePool.Name, template.VmGroup)
+	}
+
+	if pending, phase := isTemplateImportPending(vcdCluster, template.Catalog, template.Template); pending {
+		log.Info("Waiting for the template of the machine pool to be imported from its source",
+			"catalog", template.Catalog, "template", template.Template, "phase", phase)
+		conditions.MarkFalse(vcdMachinePool, InstancesReadyCondition, TemplateNotReadyReason,
+			clusterv1.ConditionSeverityInfo, "template [%s] of catalog [%s] is being imported from its source: "+
+				"phase [%s]", template.Template, template.Catalog, phase)
+		return ctrl.Result{RequeueAfter: TemplateNotReadyRequeueInterval}, nil
+	}
+	templateStatus, err := getTemplateStatus(vcdClient, "", template.Catalog, template.Template)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Error getting the status of template [%s/%s] of machine pool [%s]",
+			template.Catalog, template.Template, vcdMachinePool.Name)
+	}
+	if isTemplateImporting(templateStatus) {
+		log.Info("Waiting for the template of the machine pool to be imported", "catalog", template.Catalog,
+			"template", template.Template, "status", templateStatus)
+		conditions.MarkFalse(vcdMachinePool, InstancesReadyCondition, TemplateNotReadyReason,
+			clusterv1.ConditionSeverityInfo, "template [%s] of catalog [%s] is not ready: status [%s]",
+			template.Template, template.Catalog, templateStatus)
+		return ctrl.Result{RequeueAfter: TemplateNotReadyRequeueInterval}, nil
+	}
+
+	// VCD appends the index of the VM to the prefix when several VMs are created together
+	prefix := fmt.Sprintf("%s-%s", vcdMachinePool.Name, util.RandomString(machinePoolVMNameSuffixLength))
+	if count > 1 {
+		prefix += "-"
+	}
+	vmNames := getMachinePoolVMNames(prefix, count)
+	for _, vmName := range vmNames {
+		vcdMachinePool.Status.Instances = append(vcdMachinePool.Status.Instances,
+			infrav1beta3.VCDMachinePoolInstance{Name: vmName, TemplateHash: templateHash})
+	}
+
+	log.Info("Adding VMs to the machine pool", "count", count, "prefix", prefix)
+	completeOvdcTask := startOvdcTask(vcdCluster)
+	cloneStartTime := time.Now()
+	err = vdcManager.AddNewTkgVM(prefix, vApp.VApp.Name, count, template.Catalog, template.Template,
+		placementPolicy, policies.SizingPolicy, policies.StorageProfile, false)
+	completeOvdcTask()
+	vmCloneDuration.WithLabelValues(getOperationResult(err)).Observe(time.Since(cloneStartTime).Seconds())
+	if isTaskQueueSaturatedError(err) {
+		retryAfter := recordTaskQueueSaturation(vcdCluster.Spec.Site, vcdClient.ClusterOrgName)
+		log.Info("VCD rejected the creation of the VMs as the task queue of the org is saturated",
+			"org", vcdClient.ClusterOrgName, "retryAfter", retryAfter, "error", err.Error())
+		return ctrl.Result{RequeueAfter: retryAfter}, nil
+	}
+	if isIPPoolExhaustedError(err) {
+		recordIPPoolExhausted(ipPoolKey)
+		log.Info("VCD failed to create the VMs as the IP pool of the OVDC network is exhausted",
+			"ovdcNetwork", ovdcNetworkName, "error", err.Error())
+		conditions.MarkFalse(vcdMachinePool, InstancesReadyCondition, IPPoolExhaustedReason,
+			clusterv1.ConditionSeverityWarning, "IP pool of OVDC network [%s] is exhausted", ovdcNetworkName)
+		return ctrl.Result{RequeueAfter: IPPoolExhaustedRequeueInterval}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Error creating [%d] VMs of machine pool [%s] in vApp [%s]", count,
+			vcdMachinePool.Name, vApp.VApp.Name)
+	}
+	recordTaskAccepted(vcdCluster.Spec.Site, vcdClient.ClusterOrgName)
+	if err = vApp.Refresh(); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Error refreshing vApp [%s] after creating the VMs of machine pool [%s]",
+			vApp.VApp.Name, vcdMachinePool.Name)
+	}
+
+	r.recordEvent(vcdMachinePool, corev1.EventTypeNormal, VMClonedReason,
+		fmt.Sprintf("VMs [%s] cloned from template [%s/%s] in [%s]", strings.Join(vmNames, ","), template.Catalog,
+			template.Template, time.Since(cloneStartTime).Round(time.Second)))
+	return ctrl.Result{}, nil
+}
+
+// reconcileInstancesBootstrap powers on the VMs of the pool which are not ready yet, with a cloud-init script merging
+// the bootstrap data of the MachinePool, and marks them ready once their node has joined the cluster.
+func (r *VCDMachinePoolReconciler) reconcileInstancesBootstrap(ctx context.Context, cluster *clusterv1.Cluster,
+	machinePool *expv1.MachinePool, vcdMachinePool *infrav1beta3.VCDMachinePool, vcdCluster *infrav1beta3.VCDCluster,
+	vdcManager *vcdsdk.VdcManager, vApp *govcd.VApp, templateHash string) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	var bootstrapData string
+	for idx := range vcdMachinePool.Status.Instances {
+		instance := &vcdMachinePool.Status.Instances[idx]
+		if instance.Ready {
+			continue
+		}
+		vm, err := vApp.GetVMByName(instance.Name, true)
+		if err != nil {
+			return errors.Wrapf(err, "Error getting VM [%s] of machine pool [%s]", instance.Name,
+				vcdMachinePool.Name)
+		}
+		instance.ProviderID = fmt.Sprintf("%s://%s", infrav1beta3.VCDProviderID, vm.VM.ID)
+		if err = reconcileVMTemplateHash(vm, instance.TemplateHash); err != nil {
+			return errors.Wrapf(err, "Error recording template hash of VM [%s]", instance.Name)
+		}
+		if err = reconcileVMRole(vm, MachineRoleWorker); err != nil {
+			return errors.Wrapf(err, "Error recording role of VM [%s]", instance.Name)
+		}
+
+		vmStatus, err := vm.GetStatus()
+		if err != nil {
+			return errors.Wrapf(err, "Error getting status of VM [%s]", instance.Name)
+		}
+		if vmStatus != "POWERED_ON" {
+			// the VMs of an outdated instance are bootstrapped with the current data as well
+			if bootstrapData == "" {
+				if bootstrapData, err = r.getBootstrapData(ctx, machinePool); err != nil {
+					return err
+				}
+			}
+			cloudInit, err := r.getCloudInitScript(ctx, cluster, vcdCluster, instance.Name, bootstrapData)
+			if err != nil {
+				return err
+			}
+			for key, val := range getCloudInitGuestInfo(cloudInit) {
+				if err = vdcManager.SetVmExtraConfigKeyValue(vm, key, val, true); err != nil {
+					return errors.Wrapf(err, "Error setting vm extra config key [%s] of VM [%s]", key,
+						instance.Name)
+				}
+			}
+			task, err := vm.PowerOn()
+			if err != nil {
+				return errors.Wrapf(err, "Error powering on VM [%s]", instance.Name)
+			}
+			if err = task.WaitTaskCompletion(); err != nil {
+				return errors.Wrapf(err, "Error waiting for the power-on of VM [%s]", instance.Name)
+			}
+			log.Info("Powered on VM of the machine pool", "vm", instance.Name)
+		}
+		// the instance is ready once its node has joined the cluster, as reported by the cloud-init script
+		joinStatus, err := vdcManager.GetExtraConfigValue(vm, KubeadmNodeJoin)
+		if err != nil {
+			return errors.Wrapf(err, "Error getting extra config value for key [%s] of VM [%s]", KubeadmNodeJoin,
+				instance.Name)
+		}
+		if joinStatus == "successful" {
+			log.Info("VM of the machine pool joined the cluster", "vm", instance.Name)
+			instance.Ready = true
+		}
+	}
+	return nil
+}
+
+func (r *VCDMachinePoolReconciler) getBootstrapData(ctx context.Context, machinePool *expv1.MachinePool) (string,
+	error) {
+	s := &corev1.Secret{}
+	key := client.ObjectKey{
+		Namespace: machinePool.Namespace,
+		Name:      *machinePool.Spec.Template.Spec.Bootstrap.DataSecretName,
+	}
+	if err := r.Client.Get(ctx, key, s); err != nil {
+		return "", errors.Wrapf(err, "failed to retrieve bootstrap data secret for MachinePool %s/%s",
+			machinePool.Namespace, machinePool.Name)
+	}
+	value, ok := s.Data["value"]
+	if !ok {
+		return "", errors.New("error retrieving bootstrap data: secret value key is missing")
+	}
+	if format := string(s.Data["format"]); format != "" && format != string(bootstrapv1.CloudConfig) {
+		return "", fmt.Errorf("bootstrap data of MachinePool [%s] has format [%s] while a VCDMachinePool only "+
+			"supports [%s]", machinePool.Name, format, bootstrapv1.CloudConfig)
+	}
+	return string(value), nil
+}
+
+// getCloudInitScript merges the bootstrap data into the cloud-init script of a worker VM of the pool.
+func (r *VCDMachinePoolReconciler) getCloudInitScript(ctx context.Context, cluster *clusterv1.Cluster,
+	vcdCluster *infrav1beta3.VCDCluster, vmName string, bootstrapData string) ([]byte, error) {
+
+	cloudInitInput := CloudInitScriptInput{
+		HTTPProxy:        vcdCluster.Spec.ProxyConfigSpec.HTTPProxy,
+		HTTPSProxy:       vcdCluster.Spec.ProxyConfigSpec.HTTPSProxy,
+		NoProxy:          getNoProxy(cluster, vcdCluster),
+		MachineName:      vmName,
+		VcdHostFormatted: strings.ReplaceAll(vcdCluster.Spec.Site, "/", "\\/"),
+		TKGVersion:       getTKGVersion(cluster),
+		ClusterID:        vcdCluster.Status.InfraId,
+	}
+	if vcdCluster.Spec.VerifyControlPlaneEndpoint {
+		cloudInitInput.ControlPlaneEndpoint = fmt.Sprintf("%s:%d", vcdCluster.Spec.ControlPlaneEndpoint.Host,
+			vcdCluster.Spec.ControlPlaneEndpoint.Port)
+	}
+	trustBundle, err := getTrustBundle(ctx, r.Client, vcdCluster)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error getting the trust bundle of cluster [%s] for VM [%s]",
+			vcdCluster.Name, vmName)
+	}
+	if trustBundle != nil {
+		cloudInitInput.TrustBundle = base64.StdEncoding.EncodeToString(trustBundle)
+	}
+	cloudInit, err := MergeJinjaToCloudInitScript(cloudInitInput, bootstrapData)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error merging bootstrap data with the cloudInit script for VM [%s]", vmName)
+	}
+	return cloudInit, nil
+}
+
+// deleteMachinePoolVM powers off and deletes a VM of the pool. A VM which no longer exists is ignored.
+func deleteMachinePoolVM(vApp *govcd.VApp, vmName string) error {
+	vm, err := vApp.GetVMByName(vmName, true)
+	if err == govcd.ErrorEntityNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get VM [%s]: [%v]", vmName, err)
+	}
+	// the named disks attached by CSI are detached when the node is drained
+	if vm.VM.VmSpecSection != nil && vm.VM.VmSpecSection.DiskSection != nil {
+		for _, diskSettings := range vm.VM.VmSpecSection.DiskSection.DiskSettings {
+			if diskSettings.Disk != nil {
+				return fmt.Errorf("cannot delete VM [%s] since named disk [%s] is attached to VM (by CSI)", vmName,
+					diskSettings.Disk.Name)
+			}
+		}
+	}
+	task, err := vm.PowerOff()
+	if err != nil {
+		klog.Warningf("Error while powering off VM [%s]: [%v]", vmName, err)
+	} else if err = task.WaitTaskCompletion(); err != nil {
+		return fmt.Errorf("failed to power off VM [%s]: [%v]", vmName, err)
+	}
+	if err = vm.Delete(); err != nil {
+		return fmt.Errorf("failed to delete VM [%s]: [%v]", vmName, err)
+	}
+	return nil
+}
+
+func (r *VCDMachinePoolReconciler) reconcileDelete(ctx context.Context, vcdMachinePool *infrav1beta3.VCDMachinePool,
+	vcdCluster *infrav1beta3.VCDCluster) (ctrl.Result, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	if len(vcdMachinePool.Status.Instances) == 0 {
+		controllerutil.RemoveFinalizer(vcdMachinePool, infrav1beta3.MachinePoolFinalizer)
+		return ctrl.Result{}, nil
+	}
+	vcdClient, err := createVCDClientFromSecrets(ctx, r.Client, vcdCluster)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Error creating VCD client to delete machine pool [%s]",
+			vcdMachinePool.Name)
+	}
+	defer func() {
+		if vcdClient != nil && vcdClient.VCDClient != nil {
+			vcdClient.VCDClient.Client.Http.CloseIdleConnections()
+		}
+	}()
+	if err = verifyVAppOwnershipClaim(ctx, r.Client, vcdClient, vcdCluster); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "Unable to delete the machine pool [%s] of cluster [%s]",
+			vcdMachinePool.Name, vcdCluster.Name)
+	}
+	if vcdClient.VDC == nil {
+		return ctrl.Result{}, fmt.Errorf("no OVDC found in the VCD client to delete machine pool [%s]",
+			vcdMachinePool.Name)
+	}
+	vAppName := CreateFullVAppName(vcdCluster)
+	vApp, err := vcdClient.VDC.GetVAppByName(vAppName, true)
+	if err != nil && err != govcd.ErrorEntityNotFound {
+		return ctrl.Result{}, errors.Wrapf(err, "Error getting vApp [%s] of machine pool [%s]", vAppName,
+			vcdMachinePool.Name)
+	}
+	if err == nil {
+		for len(vcdMachinePool.Status.Instances) > 0 {
+			instance := vcdMachinePool.Status.Instances[0]
+			if err = deleteMachinePoolVM(vApp, instance.Name); err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "Error deleting machine pool [%s]", vcdMachinePool.Name)
+			}
+			log.Info("Deleted VM of the machine pool", "vm", instance.Name)
+			vcdMachinePool.Status.Instances = vcdMachinePool.Status.Instances[1:]
+		}
+	}
+	vcdMachinePool.Status.Instances = nil
+	controllerutil.RemoveFinalizer(vcdMachinePool, infrav1beta3.MachinePoolFinalizer)
+	return ctrl.Result{}, nil
+}
+
+func (r *VCDMachinePoolReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager,
+	options controller.Options) error {
+	clusterToVCDMachinePools, err := util.ClusterToObjectsMapper(mgr.GetClient(),
+		&infrav1beta3.VCDMachinePoolList{}, mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+
+	c, err := ctrl.NewControllerManagedBy(mgr).
+		For(&infrav1beta3.VCDMachinePool{}).
+		WithOptions(options).
+		Watches(
+			&source.Kind{Type: &expv1.MachinePool{}},
+			handler.EnqueueRequestsFromMapFunc(r.MachinePoolToVCDMachinePool),
+		).
+		Build(r)
+	if err != nil {
+		return err
+	}
+	return c.Watch(
+		&source.Kind{Type: &clusterv1.Cluster{}},
+		handler.EnqueueRequestsFromMapFunc(clusterToVCDMachinePools),
+		predicates.ClusterUnpausedAndInfrastructureReady(ctrl.LoggerFrom(ctx)),
+	)
+}
+
+// MachinePoolToVCDMachinePool maps a MachinePool to its VCDMachinePool, so that changes of its replicas and bootstrap
+// data are reconciled.
+func (r *VCDMachinePoolReconciler) MachinePoolToVCDMachinePool(o client.Object) []ctrl.Request {
+	m, ok := o.(*expv1.MachinePool)
+	if !ok {
+		klog.Errorf("Expected a MachinePool found [%T]", o)
+		return nil
+	}
+	infraRef := m.Spec.Template.Spec.InfrastructureRef
+	if infraRef.Kind != "VCDMachinePool" || infraRef.Name == "" {
+		return nil
+	}
+	gv, err := schema.ParseGroupVersion(infraRef.APIVersion)
+	if err != nil || gv.Group != infrav1beta3.GroupVersion.Group {
+		return nil
+	}
+	return []ctrl.Request{{NamespacedName: client.ObjectKey{Namespace: m.Namespace, Name: infraRef.Name}}}
+}
+
+func (r *VCDMachinePoolReconciler) recordEvent(obj runtime.Object, eventType string, reason string, message string) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Event(obj, eventType, reason, message)
+}
@@ -0,0 +1,108 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+)
+
+func TestGetMachinePoolVMNames(t *testing.T) {
+	testCases := []struct {
+		name   string
+		prefix string
+		count  int
+		want   []string
+	}{
+		{name: "single VM is named with the prefix", prefix: "pool-abcde", count: 1, want: []string{"pool-abcde"}},
+		{name: "VMs are suffixed with their index", prefix: "pool-abcde", count: 3,
+			want: []string{"pool-abcde0", "pool-abcde1", "pool-abcde2"}},
+		{name: "no VM", prefix: "pool-abcde", count: 0, want: []string{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := getMachinePoolVMNames(tc.prefix, tc.count)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got names %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSortInstancesForDeletion(t *testing.T) {
+	testCases := []struct {
+		name         string
+		instances    []infrav1beta3.VCDMachinePoolInstance
+		templateHash string
+		want         []string
+	}{
+		{
+			name: "outdated instances first, then the ones not ready, then the others in order",
+			instances: []infrav1beta3.VCDMachinePoolInstance{
+				{Name: "ready-1", TemplateHash: "new", Ready: true},
+				{Name: "not-ready", TemplateHash: "new"},
+				{Name: "outdated-ready", TemplateHash: "old", Ready: true},
+				{Name: "ready-2", TemplateHash: "new", Ready: true},
+				{Name: "outdated", TemplateHash: "old"},
+			},
+			templateHash: "new",
+			want:         []string{"outdated-ready", "outdated", "not-ready", "ready-1", "ready-2"},
+		},
+		{
+			name: "instances without a hash are outdated",
+			instances: []infrav1beta3.VCDMachinePoolInstance{
+				{Name: "current", TemplateHash: "new", Ready: true},
+				{Name: "legacy", Ready: true},
+			},
+			templateHash: "new",
+			want:         []string{"legacy", "current"},
+		},
+		{
+			name: "order of equivalent instances is kept",
+			instances: []infrav1beta3.VCDMachinePoolInstance{
+				{Name: "a", TemplateHash: "new", Ready: true},
+				{Name: "b", TemplateHash: "new", Ready: true},
+				{Name: "c", TemplateHash: "new", Ready: true},
+			},
+			templateHash: "new",
+			want:         []string{"a", "b", "c"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sortInstancesForDeletion(tc.instances, tc.templateHash)
+			got := make([]string, len(tc.instances))
+			for i, instance := range tc.instances {
+				got[i] = instance.Name
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got order %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCountReadyInstances(t *testing.T) {
+	testCases := []struct {
+		name      string
+		instances []infrav1beta3.VCDMachinePoolInstance
+		want      int
+	}{
+		{name: "no instance", want: 0},
+		{name: "ready and not ready instances", instances: []infrav1beta3.VCDMachinePoolInstance{
+			{Name: "a", Ready: true}, {Name: "b"}, {Name: "c", Ready: true},
+		}, want: 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := countReadyInstances(tc.instances); got != tc.want {
+				t.Errorf("got [%d] ready instances, want [%d]", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+// vgpuComputePolicy is a VDC compute policy with its vGPU profiles, which are not part of types.VdcComputePolicyV2.
+type vgpuComputePolicy struct {
+	ID           string                  `json:"id,omitempty"`
+	Name         string                  `json:"name"`
+	PolicyType   string                  `json:"policyType"`
+	IsVgpuPolicy bool                    `json:"isVgpuPolicy,omitempty"`
+	VgpuProfiles []vgpuComputePolicyItem `json:"vgpuProfiles,omitempty"`
+}
+
+// vgpuComputePolicyItem is a vGPU profile of a vGPU policy and the number of vGPUs of the profile.
+type vgpuComputePolicyItem struct {
+	Profile types.OpenApiReference `json:"profile"`
+	Count   int32                  `json:"count"`
+}
+
+// getGPUCount returns the number of vGPUs of the GPUSpec, which defaults to 1.
+func getGPUCount(gpu *infrav1beta3.GPUSpec) int32 {
+	if gpu.Count < 1 {
+		return 1
+	}
+	return gpu.Count
+}
+
+// isNvidiaGPUEnabled checks if the VMs of the VCDMachineSpec have NVIDIA GPUs.
+func isNvidiaGPUEnabled(vcdMachineSpec infrav1beta3.VCDMachineSpec) bool {
+	return vcdMachineSpec.EnableNvidiaGPU || vcdMachineSpec.GPU != nil
+}
+
+// providesVgpuProfile checks if the vGPU policy attaches exactly count vGPUs of the profile to its VMs.
+func providesVgpuProfile(policy *vgpuComputePolicy, profile string, count int32) bool {
+	if len(policy.VgpuProfiles) != 1 {
+		return false
+	}
+	return policy.VgpuProfiles[0].Profile.Name == profile && policy.VgpuProfiles[0].Count == count
+}
+
+// getAssignedVgpuPolicies returns the vGPU policies assigned to the OVDC.
+func getAssignedVgpuPolicies(vdcManager *vcdsdk.VdcManager) ([]*vgpuComputePolicy, error) {
+	client := &vdcManager.Client.VCDClient.Client
+	urlRef, err := client.OpenApiBuildEndpoint(fmt.Sprintf(
+		types.OpenApiPathVersion2_0_0+types.OpenApiEndpointVdcAssignedComputePolicies, vdcManager.Vdc.Vdc.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the compute policies endpoint of OVDC [%s]: [%v]",
+			vdcManager.VdcName, err)
+	}
+	policies := []*vgpuComputePolicy{{}}
+	if err = client.OpenApiGetAllItems(client.APIVersion, urlRef, nil, &policies, nil); err != nil {
+		return nil, fmt.Errorf("failed to get compute policies assigned to OVDC [%s]: [%v]", vdcManager.VdcName, err)
+	}
+	vgpuPolicies := make([]*vgpuComputePolicy, 0)
+	for _, policy := range policies {
+		if policy != nil && policy.IsVgpuPolicy && policy.PolicyType == VdcVmPolicyType {
+			vgpuPolicies = append(vgpuPolicies, policy)
+		}
+	}
+	return vgpuPolicies, nil
+}
+
+// resolveVgpuPlacementPolicy returns the name of the vGPU policy of the OVDC providing the vGPU profile and count of
+// the VCDMachineSpec, which is applied to the VM in place of its placement policy, or placementPolicy if the spec has no
+// vGPU profile. The default placement policy of the cluster is not applied to VMs with a vGPU profile. If the policy of
+// the GPUSpec is set, it is validated to provide the profile and count instead.
+func resolveVgpuPlacementPolicy(vdcManager *vcdsdk.VdcManager, vcdMachineSpec infrav1beta3.VCDMachineSpec,
+	placementPolicy string) (string, error) {
+
+	gpu := vcdMachineSpec.GPU
+	if gpu == nil {
+		return placementPolicy, nil
+	}
+	if vcdMachineSpec.PlacementPolicy != "" || vcdMachineSpec.VmGroup != "" {
+		return "", fmt.Errorf("the vGPU policy of profile [%s] places the VM and cannot be combined with placement policy [%s] or VM group [%s]",
+			gpu.Profile, vcdMachineSpec.PlacementPolicy, vcdMachineSpec.VmGroup)
+	}
+	if vdcManager.Vdc == nil || vdcManager.Vdc.Vdc == nil {
+		return "", fmt.Errorf("no Vdc found with name [%s] to look up the vGPU policy of profile [%s]",
+			vdcManager.VdcName, gpu.Profile)
+	}
+
+	count := getGPUCount(gpu)
+	vgpuPolicies, err := getAssignedVgpuPolicies(vdcManager)
+	if err != nil {
+		return "", err
+	}
+
+	matchingPolicies := make([]string, 0)
+	for _, policy := range vgpuPolicies {
+		if providesVgpuProfile(policy, gpu.Profile, count) {
+			matchingPolicies = append(matchingPolicies, policy.Name)
+		}
+	}
+	sort.Strings(matchingPolicies)
+
+	if gpu.Policy != "" {
+		for _, policyName := range matchingPolicies {
+			if policyName == gpu.Policy {
+				return gpu.Policy, nil
+			}
+		}
+		return "", fmt.Errorf("vGPU policy [%s] does not provide [%d] vGPUs of profile [%s] in OVDC [%s]; policies providing them: [%v]",
+			gpu.Policy, count, gpu.Profile, vdcManager.VdcName, matchingPolicies)
+	}
+
+	switch len(matchingPolicies) {
+	case 0:
+		return "", fmt.Errorf("no vGPU policy assigned to OVDC [%s] provides [%d] vGPUs of profile [%s]; "+
+			"the provider needs to publish a vGPU policy for the profile to the OVDC", vdcManager.VdcName, count,
+			gpu.Profile)
+	case 1:
+		return matchingPolicies[0], nil
+	default:
+		return "", fmt.Errorf("multiple vGPU policies [%v] assigned to OVDC [%s] provide [%d] vGPUs of profile [%s]; "+
+			"set the vGPU policy explicitly", matchingPolicies, vdcManager.VdcName, count, gpu.Profile)
+	}
+}
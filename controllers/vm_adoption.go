@@ -0,0 +1,105 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// vmProviderIDPrefix is the prefix of the provider IDs of the VMs of the machines.
+const vmProviderIDPrefix = infrav1beta3.VCDProviderID + "://urn:vcloud:vm:"
+
+// isVMAdoptionPending checks if the VCDMachine was created with the provider ID of an existing VM whose adoption was
+// not completed yet. The status of a machine moved by clusterctl move is not moved either, and its VM is adopted again.
+func isVMAdoptionPending(vcdMachine *infrav1beta3.VCDMachine) bool {
+	return vcdMachine.Spec.ProviderID != nil && vcdMachine.Status.ProviderID == nil
+}
+
+// reconcileVMAdoption takes ownership of the existing VM referenced by the provider ID of the VCDMachine instead of
+// creating a VM, e.g. to bring a cluster built by hand under the management of CAPVCD. The VM must be in the vApp of
+// the machine and not be adopted by another VCDMachine. The VM is not customized nor bootstrapped again; an adopted
+// control plane VM is added to the load balancer pool of the control plane endpoint managed by CAPVCD.
+func (r *VCDMachineReconciler) reconcileVMAdoption(ctx context.Context, vcdClient *vcdsdk.Client,
+	machine *clusterv1.Machine, vcdMachine *infrav1beta3.VCDMachine, vcdCluster *infrav1beta3.VCDCluster) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	providerID := *vcdMachine.Spec.ProviderID
+	if !strings.HasPrefix(providerID, vmProviderIDPrefix) {
+		return fmt.Errorf("provider ID [%s] of machine [%s] is not of the form [%s<uuid>]", providerID,
+			machine.Name, vmProviderIDPrefix)
+	}
+	vmID := getVMIDFromProviderID(vcdMachine.Spec.ProviderID)
+	if err := verifyVAppOwnershipClaim(ctx, r.Client, vcdClient, vcdCluster); err != nil {
+		return err
+	}
+
+	vcdMachineList := &infrav1beta3.VCDMachineList{}
+	if err := r.Client.List(ctx, vcdMachineList, client.InNamespace(vcdMachine.Namespace)); err != nil {
+		return fmt.Errorf("failed to list the VCDMachines to check the adoption of VM [%s]: [%v]", vmID, err)
+	}
+	for _, other := range vcdMachineList.Items {
+		if other.Name != vcdMachine.Name && other.Spec.ProviderID != nil && *other.Spec.ProviderID == providerID {
+			return fmt.Errorf("VM [%s] is already the VM of machine [%s]", vmID, other.Name)
+		}
+	}
+
+	vAppName := getMachineVAppName(vcdMachine, vcdCluster)
+	vApp, err := vcdClient.VDC.GetVAppByName(vAppName, true)
+	if err != nil {
+		return fmt.Errorf("failed to get vApp [%s] of the VM [%s] to adopt: [%v]", vAppName, vmID, err)
+	}
+	vm, err := vApp.GetVMById(vmID, true)
+	if err == govcd.ErrorEntityNotFound {
+		return fmt.Errorf("VM [%s] to adopt is not in vApp [%s] of machine [%s]", vmID, vAppName, machine.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get VM [%s] to adopt in vApp [%s]: [%v]", vmID, vAppName, err)
+	}
+	if vm.VM.NetworkConnectionSection == nil {
+		return fmt.Errorf("VM [%s] to adopt has no network connection", vm.VM.Name)
+	}
+	primaryNetwork := getPrimaryNetwork(vm.VM)
+	if primaryNetwork == nil || primaryNetwork.IPAddress == "" {
+		return fmt.Errorf("VM [%s] to adopt has no IP address on its primary network", vm.VM.Name)
+	}
+	machineAddress := primaryNetwork.IPAddress
+
+	setMachineAddresses(vcdMachine, vm.VM.Name, machineAddress)
+
+	if util.IsControlPlaneMachine(machine) && isLoadBalancerManagedByCAPVCD(vcdCluster) {
+		gateway, err := vcdsdk.NewGatewayManager(ctx, vcdClient, getOvdcNetworkName(vcdCluster),
+			vcdCluster.Spec.LoadBalancerConfigSpec.VipSubnet, getOvdcName(vcdCluster))
+		if err != nil {
+			return fmt.Errorf("failed to create gateway manager to adopt VM [%s]: [%v]", vm.VM.Name, err)
+		}
+		if err = r.reconcileLBPool(ctx, machine, machineAddress, vcdCluster, vcdClient, gateway); err != nil {
+			return err
+		}
+	}
+
+	if !vcdMachine.Spec.Bootstrapped {
+		log.Info("Adopted the existing VM of the machine", "vm", vm.VM.Name, "vAppName", vAppName)
+		r.recordEvent(vcdMachine, corev1.EventTypeNormal, VMAdoptedReason,
+			fmt.Sprintf("existing VM [%s] of vApp [%s] adopted", vm.VM.Name, vAppName))
+	}
+	vcdMachine.Spec.Bootstrapped = true
+	vcdMachine.Status.ProviderID = vcdMachine.Spec.ProviderID
+	conditions.MarkTrue(vcdMachine, BootstrapExecSucceededCondition)
+	return nil
+}
@@ -0,0 +1,94 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+const (
+	// VdcVmPolicyType is the type of the VDC compute policies which can be applied to VMs (as opposed to
+	// VdcKubernetesPolicy used by TKGs).
+	VdcVmPolicyType = "VdcVmPolicy"
+)
+
+// getVmGroupsOfPolicy returns the references to all the VM groups and logical VM groups of a VM placement policy.
+func getVmGroupsOfPolicy(policy *types.VdcComputePolicyV2) types.OpenApiReferences {
+	vmGroups := append(types.OpenApiReferences{}, policy.LogicalVMGroupReferences...)
+	for _, namedVMGroups := range policy.NamedVMGroups {
+		vmGroups = append(vmGroups, namedVMGroups...)
+	}
+	for _, pvdcNamedVmGroups := range policy.PvdcNamedVmGroupsMap {
+		for _, namedVMGroups := range pvdcNamedVmGroups.NamedVmGroups {
+			vmGroups = append(vmGroups, namedVMGroups...)
+		}
+	}
+	for _, pvdcLogicalVmGroups := range policy.PvdcLogicalVmGroupsMap {
+		vmGroups = append(vmGroups, pvdcLogicalVmGroups.LogicalVmGroups...)
+	}
+	return vmGroups
+}
+
+// resolveVmGroupPlacementPolicy returns the name of the VM placement policy of the OVDC which places VMs in the VM group
+// or logical VM group vmGroup (referenced by name or ID). If placementPolicy is set, it is validated to reference the
+// group instead.
+func resolveVmGroupPlacementPolicy(vdcManager *vcdsdk.VdcManager, vmGroup string, placementPolicy string) (string, error) {
+	if vmGroup == "" {
+		return placementPolicy, nil
+	}
+	if vdcManager.Vdc == nil || vdcManager.Vdc.Vdc == nil {
+		return "", fmt.Errorf("no Vdc found with name [%s] to look up the placement policy of VM group [%s]",
+			vdcManager.VdcName, vmGroup)
+	}
+
+	ovdcID := vdcManager.Vdc.Vdc.ID
+	policies, err := vdcManager.Client.VCDClient.GetAllAssignedVdcComputePoliciesV2(ovdcID, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get compute policies assigned to OVDC [%s]: [%v]", vdcManager.VdcName, err)
+	}
+
+	matchingPolicies := make([]string, 0)
+	for _, policy := range policies {
+		if policy == nil || policy.VdcComputePolicyV2 == nil {
+			continue
+		}
+		if policy.VdcComputePolicyV2.IsSizingOnly || policy.VdcComputePolicyV2.PolicyType != VdcVmPolicyType {
+			continue
+		}
+		for _, vmGroupRef := range getVmGroupsOfPolicy(policy.VdcComputePolicyV2) {
+			if vmGroupRef.Name == vmGroup || vmGroupRef.ID == vmGroup {
+				matchingPolicies = append(matchingPolicies, policy.VdcComputePolicyV2.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(matchingPolicies)
+
+	if placementPolicy != "" {
+		for _, policyName := range matchingPolicies {
+			if policyName == placementPolicy {
+				return placementPolicy, nil
+			}
+		}
+		return "", fmt.Errorf("placement policy [%s] does not place VMs in VM group [%s] in OVDC [%s]; policies placing VMs in the group: [%v]",
+			placementPolicy, vmGroup, vdcManager.VdcName, matchingPolicies)
+	}
+
+	switch len(matchingPolicies) {
+	case 0:
+		return "", fmt.Errorf("no VM placement policy assigned to OVDC [%s] places VMs in VM group [%s]; "+
+			"the provider needs to publish a placement policy for the group to the OVDC", vdcManager.VdcName, vmGroup)
+	case 1:
+		return matchingPolicies[0], nil
+	default:
+		return "", fmt.Errorf("multiple VM placement policies [%v] assigned to OVDC [%s] place VMs in VM group [%s]; "+
+			"set the placement policy explicitly", matchingPolicies, vdcManager.VdcName, vmGroup)
+	}
+}
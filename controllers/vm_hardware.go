@@ -0,0 +1,251 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// getVMHardware returns the CPUs and memory of the VM.
+func getVMHardware(vm *govcd.VM) *infrav1beta3.VMHardware {
+	hardware := &infrav1beta3.VMHardware{}
+	vmSpecSection := vm.VM.VmSpecSection
+	if vmSpecSection.NumCpus != nil {
+		hardware.NumCPUs = int32(*vmSpecSection.NumCpus)
+	}
+	if vmSpecSection.NumCoresPerSocket != nil {
+		hardware.CoresPerSocket = int32(*vmSpecSection.NumCoresPerSocket)
+	}
+	if vmSpecSection.MemoryResourceMb != nil {
+		hardware.MemoryMiB = vmSpecSection.MemoryResourceMb.Configured
+	}
+	return hardware
+}
+
+// isVMHardwareUpToDate checks if the CPUs and memory of the VM are the ones set in the VCDMachineSpec.
+func isVMHardwareUpToDate(hardware *infrav1beta3.VMHardware, spec infrav1beta3.VCDMachineSpec) bool {
+	return (spec.NumCPUs == nil || hardware.NumCPUs == *spec.NumCPUs) &&
+		(spec.CoresPerSocket == nil || hardware.CoresPerSocket == *spec.CoresPerSocket) &&
+		(spec.MemoryMiB == nil || hardware.MemoryMiB == *spec.MemoryMiB)
+}
+
+// updateVMHardware sets the CPUs and memory of the VM to the ones set in the VCDMachineSpec.
+func updateVMHardware(vm *govcd.VM, spec infrav1beta3.VCDMachineSpec) error {
+	// the disks are left out as the update treats them as changes
+	vmSpecSection := *vm.VM.VmSpecSection
+	vmSpecSection.DiskSection = nil
+	if spec.NumCPUs != nil {
+		numCPUs := int(*spec.NumCPUs)
+		vmSpecSection.NumCpus = &numCPUs
+	}
+	if spec.CoresPerSocket != nil {
+		coresPerSocket := int(*spec.CoresPerSocket)
+		vmSpecSection.NumCoresPerSocket = &coresPerSocket
+	}
+	if spec.MemoryMiB != nil && vmSpecSection.MemoryResourceMb != nil {
+		memory := *vmSpecSection.MemoryResourceMb
+		memory.Configured = *spec.MemoryMiB
+		vmSpecSection.MemoryResourceMb = &memory
+	}
+	if _, err := vm.UpdateVmSpecSection(&vmSpecSection, vm.VM.Description); err != nil {
+		return fmt.Errorf("failed to set the CPUs and memory of VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	return nil
+}
+
+// getVMCapabilities returns the CPU and memory hot-add capabilities of the VM.
+func getVMCapabilities(vcdClient *vcdsdk.Client, vm *govcd.VM) (*types.VmCapabilities, error) {
+	capabilities := &types.VmCapabilities{}
+	if _, err := vcdClient.VCDClient.Client.ExecuteRequest(vm.VM.HREF+"/vmCapabilities", http.MethodGet,
+		types.MimeVmCapabilities, "error getting VM capabilities: %s", nil, capabilities); err != nil {
+		return nil, fmt.Errorf("failed to get the capabilities of VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	return capabilities, nil
+}
+
+// getHotAddBlocker returns why the CPUs and memory of the VCDMachineSpec cannot be hot-added to the running VM, or an
+// empty string if they can. Hot-add only grows the CPUs and memory, and keeps the cores per socket.
+func getHotAddBlocker(hardware *infrav1beta3.VMHardware, capabilities *types.VmCapabilities,
+	spec infrav1beta3.VCDMachineSpec) string {
+
+	if spec.CoresPerSocket != nil && *spec.CoresPerSocket != hardware.CoresPerSocket {
+		return fmt.Sprintf("the cores per socket cannot change from [%d] to [%d] while the VM runs",
+			hardware.CoresPerSocket, *spec.CoresPerSocket)
+	}
+	if spec.NumCPUs != nil && *spec.NumCPUs != hardware.NumCPUs {
+		if *spec.NumCPUs < hardware.NumCPUs {
+			return fmt.Sprintf("the CPUs cannot be reduced from [%d] to [%d] while the VM runs", hardware.NumCPUs,
+				*spec.NumCPUs)
+		}
+		if !capabilities.CPUHotAddEnabled {
+			return "CPU hot-add is not enabled on the VM"
+		}
+	}
+	if spec.MemoryMiB != nil && *spec.MemoryMiB != hardware.MemoryMiB {
+		if *spec.MemoryMiB < hardware.MemoryMiB {
+			return fmt.Sprintf("the memory cannot be reduced from [%dMiB] to [%dMiB] while the VM runs",
+				hardware.MemoryMiB, *spec.MemoryMiB)
+		}
+		if !capabilities.MemoryHotAddEnabled {
+			return "memory hot-add is not enabled on the VM"
+		}
+	}
+	return ""
+}
+
+// reconcileVMHardware sets the CPUs and memory of the VM to the ones of the VCDMachine, for machines sized without a
+// sizing policy. The hardware is only changed while the VM is powered off, i.e. before its first power on. CPU and
+// memory hot-add are enabled on the VMs of machines scaled in place, when their guest OS supports it.
+func reconcileVMHardware(ctx context.Context, vcdClient *vcdsdk.Client, vm *govcd.VM,
+	vcdMachine *infrav1beta3.VCDMachine, sizingPolicy string) error {
+
+	spec := vcdMachine.Spec
+	if sizingPolicy != "" || !spec.HasExplicitHardware() {
+		return nil
+	}
+	if vm.VM.VmSpecSection == nil {
+		return fmt.Errorf("no hardware found on VM [%s]", vm.VM.Name)
+	}
+	vmStatus, err := vm.GetStatus()
+	if err != nil {
+		return fmt.Errorf("failed to get status of VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	if vmStatus != "POWERED_OFF" {
+		return nil
+	}
+	if !isVMHardwareUpToDate(getVMHardware(vm), spec) {
+		if err = updateVMHardware(vm, spec); err != nil {
+			return err
+		}
+	}
+	if !spec.IsScaledInPlace() {
+		return nil
+	}
+
+	vcdMachine.Status.Hardware = getVMHardware(vm)
+	capabilities, err := getVMCapabilities(vcdClient, vm)
+	if err != nil {
+		return err
+	}
+	if capabilities.CPUHotAddEnabled && capabilities.MemoryHotAddEnabled {
+		return nil
+	}
+	// the machine is still created without hot-add when its guest OS does not support it; it is then scaled by
+	// replacing it
+	if _, err = vm.UpdateVmCpuAndMemoryHotAdd(true, true); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to enable CPU and memory hot-add on the VM", "vm", vm.VM.Name)
+	}
+	return nil
+}
+
+// reconcileVMHardwareInPlace hot-adds the CPUs and memory of the VCDMachine, or of the VCDMachineTemplate it was
+// cloned from, to the VM of a provisioned machine with the InPlace vertical scaling policy. The CPUs and memory of the
+// template are copied to the VCDMachine. Changes which cannot be hot-added, i.e. reductions, a change of the cores per
+// socket or a VM without hot-add, are reported in the VMHardwareScaled condition: such machines must be replaced to be
+// scaled. The hardware of the VM is recorded in the status of the VCDMachine so that VCD is only queried when it
+// changes.
+func (r *VCDMachineReconciler) reconcileVMHardwareInPlace(ctx context.Context, vcdClient *vcdsdk.Client,
+	machine *clusterv1.Machine, vcdMachine *infrav1beta3.VCDMachine, vcdCluster *infrav1beta3.VCDCluster) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	if !vcdMachine.Spec.IsScaledInPlace() {
+		return nil
+	}
+	vcdMachineTemplate, err := getClonedFromVCDMachineTemplate(ctx, r.Client, vcdMachine)
+	if err != nil {
+		return err
+	}
+	if vcdMachineTemplate != nil && vcdMachineTemplate.Spec.Template.Spec.IsScaledInPlace() {
+		templateSpec := vcdMachineTemplate.Spec.Template.Spec
+		if !pointer.Int32Equal(vcdMachine.Spec.NumCPUs, templateSpec.NumCPUs) ||
+			!pointer.Int32Equal(vcdMachine.Spec.CoresPerSocket, templateSpec.CoresPerSocket) ||
+			!pointer.Int64Equal(vcdMachine.Spec.MemoryMiB, templateSpec.MemoryMiB) {
+			log.Info("Scaling the machine to the CPUs and memory of its template", "template", vcdMachineTemplate.Name)
+			vcdMachine.Spec.NumCPUs = templateSpec.NumCPUs
+			vcdMachine.Spec.CoresPerSocket = templateSpec.CoresPerSocket
+			vcdMachine.Spec.MemoryMiB = templateSpec.MemoryMiB
+		}
+	}
+	if vcdMachine.Status.Hardware != nil && isVMHardwareUpToDate(vcdMachine.Status.Hardware, vcdMachine.Spec) {
+		conditions.MarkTrue(vcdMachine, VMHardwareScaledCondition)
+		return nil
+	}
+
+	if err = verifyVAppOwnershipClaim(ctx, r.Client, vcdClient, vcdCluster); err != nil {
+		return err
+	}
+	vAppName := getMachineVAppName(vcdMachine, vcdCluster)
+	vApp, err := vcdClient.VDC.GetVAppByName(vAppName, true)
+	if err != nil {
+		return fmt.Errorf("failed to get vApp [%s]: [%v]", vAppName, err)
+	}
+	vmID := getVMIDFromProviderID(vcdMachine.Status.ProviderID)
+	vm, err := vApp.GetVMById(vmID, true)
+	if err != nil {
+		return fmt.Errorf("failed to get VM [%s] of machine [%s]: [%v]", vmID, machine.Name, err)
+	}
+	if vm.VM.VmSpecSection == nil {
+		return fmt.Errorf("no hardware found on VM [%s]", vm.VM.Name)
+	}
+	hardware := getVMHardware(vm)
+	vcdMachine.Status.Hardware = hardware
+	if isVMHardwareUpToDate(hardware, vcdMachine.Spec) {
+		conditions.MarkTrue(vcdMachine, VMHardwareScaledCondition)
+		return nil
+	}
+
+	vmStatus, err := vm.GetStatus()
+	if err != nil {
+		return fmt.Errorf("failed to get status of VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	if vmStatus == "POWERED_ON" {
+		capabilities, err := getVMCapabilities(vcdClient, vm)
+		if err != nil {
+			return err
+		}
+		if blocker := getHotAddBlocker(hardware, capabilities, vcdMachine.Spec); blocker != "" {
+			message := fmt.Sprintf("the CPUs and memory of VM [%s] cannot be changed in place: %s; replace the "+
+				"machine to scale it", vm.VM.Name, blocker)
+			if !conditions.IsFalse(vcdMachine, VMHardwareScaledCondition) ||
+				conditions.GetMessage(vcdMachine, VMHardwareScaledCondition) != message {
+				log.Info("The CPUs and memory of the VM cannot be changed in place", "vm", vm.VM.Name,
+					"reason", blocker)
+				r.recordEvent(vcdMachine, corev1.EventTypeWarning, HotAddUnsupportedReason, message)
+			}
+			conditions.MarkFalse(vcdMachine, VMHardwareScaledCondition, HotAddUnsupportedReason,
+				clusterv1.ConditionSeverityWarning, message)
+			return nil
+		}
+	}
+
+	log.Info("Scaling the VM of the machine in place", "vm", vm.VM.Name, "status", vmStatus,
+		"fromCpus", hardware.NumCPUs, "fromMemoryMiB", hardware.MemoryMiB)
+	if err = updateVMHardware(vm, vcdMachine.Spec); err != nil {
+		capvcdRdeManager := capisdk.NewCapvcdRdeManager(vcdClient, vcdCluster.Status.InfraId)
+		capvcdRdeManager.AddToErrorSet(ctx, capisdk.VCDMachineError, "", machine.Name, fmt.Sprintf("%v", err))
+		return err
+	}
+	vcdMachine.Status.Hardware = getVMHardware(vm)
+	r.recordEvent(vcdMachine, corev1.EventTypeNormal, VMHardwareScaledReason, fmt.Sprintf(
+		"scaled VM [%s] in place to [%d] CPUs and [%dMiB] of memory", vm.VM.Name, vcdMachine.Status.Hardware.NumCPUs,
+		vcdMachine.Status.Hardware.MemoryMiB))
+	conditions.MarkTrue(vcdMachine, VMHardwareScaledCondition)
+	return nil
+}
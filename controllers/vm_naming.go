@@ -0,0 +1,194 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// maxGeneratedNameLength is the length of the names generated from the naming templates of a VCDCluster, which is the
+// maximum length of the host name of a VM.
+const maxGeneratedNameLength = 63
+
+// executeNamingTemplate generates a name from the Go template with the data.
+func executeNamingTemplate(templateName string, templateText string, data map[string]interface{}) (string, error) {
+	nameTemplate, err := template.New(templateName).
+		Funcs(sprig.TxtFuncMap()).
+		Parse(templateText)
+	if err != nil {
+		return "", fmt.Errorf("error while parsing the %s template: [%v]", templateName, err)
+	}
+	buf := new(bytes.Buffer)
+	if err = nameTemplate.Execute(buf, data); err != nil {
+		return "", fmt.Errorf("error while generating a name by using the %s template: [%v]", templateName, err)
+	}
+	return buf.String(), nil
+}
+
+// normalizeGeneratedName lowercases a name generated from a naming template of a VCDCluster and truncates it to
+// maxGeneratedNameLength. A truncated name is suffixed with a hash of the full name, so that the names which only
+// differ in their end, e.g. by the suffix of the machine, remain unique.
+func normalizeGeneratedName(name string) (string, error) {
+	name = strings.Trim(strings.ToLower(strings.TrimSpace(name)), "-")
+	if name == "" {
+		return "", fmt.Errorf("naming template generated an empty name")
+	}
+	if len(name) <= maxGeneratedNameLength {
+		return name, nil
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(name))
+	suffix := fmt.Sprintf("-%08x", hash.Sum32())
+	return strings.TrimRight(name[:maxGeneratedNameLength-len(suffix)], "-") + suffix, nil
+}
+
+// getVAppNameFromTemplate generates the name of the vApp of the cluster from its VAppNamingTemplate.
+func getVAppNameFromTemplate(vcdCluster *infrav1beta3.VCDCluster) (string, error) {
+	vAppName, err := executeNamingTemplate("vAppNamingTemplate", vcdCluster.Spec.VAppNamingTemplate,
+		map[string]interface{}{
+			"clusterName": vcdCluster.Name,
+			"vcdCluster":  vcdCluster,
+		})
+	if err != nil {
+		return "", err
+	}
+	return normalizeGeneratedName(vAppName)
+}
+
+// reconcileVAppName records the name of the vApp of the cluster generated from its VAppNamingTemplate, so that the
+// vApp keeps its name when the template changes.
+func reconcileVAppName(vcdCluster *infrav1beta3.VCDCluster) error {
+	if vcdCluster.Spec.VAppName != "" || vcdCluster.Spec.VAppNamingTemplate == "" ||
+		vcdCluster.Status.VAppName != "" {
+		return nil
+	}
+	vAppName, err := getVAppNameFromTemplate(vcdCluster)
+	if err != nil {
+		return err
+	}
+	vcdCluster.Status.VAppName = vAppName
+	return nil
+}
+
+// getVMName returns the name of the VM of the machine: the name recorded in the status of the VCDMachine, or else the
+// name generated from the VmNamingTemplate of the VCDMachine or of the VCDCluster, or else the name of the machine.
+// The names generated from the template of a VCDMachine are used as is, as they were before the template of the
+// VCDCluster existed.
+func getVMName(machine *clusterv1.Machine, vcdMachine *infrav1beta3.VCDMachine, vcdCluster *infrav1beta3.VCDCluster,
+	log logr.Logger) (string, error) {
+
+	if vcdMachine.Status.VMName != "" {
+		return vcdMachine.Status.VMName, nil
+	}
+	vmNamingTemplate := vcdMachine.Spec.VmNamingTemplate
+	if vmNamingTemplate == "" {
+		vmNamingTemplate = vcdCluster.Spec.VmNamingTemplate
+	}
+	if vmNamingTemplate == "" {
+		return machine.Name, nil
+	}
+
+	vmName, err := executeNamingTemplate("vmNamingTemplate", vmNamingTemplate, map[string]interface{}{
+		"clusterName":       machine.Spec.ClusterName,
+		"machineDeployment": machine.Labels[clusterv1.MachineDeploymentNameLabel],
+		"machine":           machine,
+		"vcdMachine":        vcdMachine,
+		"vcdCluster":        vcdCluster,
+	})
+	if err != nil {
+		log.Error(err, "Error while generating VM Name by using VmNamingTemplate")
+		return "", errors.Wrapf(err, "Error while generating VM Name of machine [%s]", machine.Name)
+	}
+	if vcdMachine.Spec.VmNamingTemplate != "" {
+		return vmName, nil
+	}
+	return normalizeGeneratedName(vmName)
+}
+
+// findVMNameByMachineName returns the name of the VM of the vApp whose CapvcdMachineName metadata is the name of the
+// machine, or an empty string if there is none, e.g. to find the VM of a machine whose name was generated from a
+// naming template which has changed since.
+func findVMNameByMachineName(vcdClient *vcdsdk.Client, vApp *govcd.VApp, machineName string) (string, error) {
+	queryType := types.QtVm
+	if vcdClient.VCDClient.Client.IsSysAdmin {
+		queryType = types.QtAdminVm
+	}
+	results, err := vcdClient.VCDClient.Client.QueryWithNotEncodedParams(nil, map[string]string{
+		"type": queryType,
+		"filter": fmt.Sprintf("metadata:%s==STRING:%s;containerName==%s;isVAppTemplate==false", CapvcdMachineName,
+			url.QueryEscape(machineName), url.QueryEscape(vApp.VApp.Name)),
+		"filterEncoded": "true",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to query the VMs of vApp [%s] with metadata [%s: %s]: [%v]", vApp.VApp.Name,
+			CapvcdMachineName, machineName, err)
+	}
+	vmRecords := results.Results.VMRecord
+	if vcdClient.VCDClient.Client.IsSysAdmin {
+		vmRecords = results.Results.AdminVMRecord
+	}
+	for _, vmRecord := range vmRecords {
+		if vmRecord.ContainerName == vApp.VApp.Name {
+			return vmRecord.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// reconcileVMName records the name of the VM of the machine in the status of the VCDMachine, so that the VM keeps
+// being found by its name when the naming templates change. The VM of a machine which has no recorded name is looked
+// up by its name first, and by the CapvcdMachineName metadata of the VMs of the vApp otherwise.
+func reconcileVMName(vcdClient *vcdsdk.Client, vApp *govcd.VApp, machine *clusterv1.Machine,
+	vcdMachine *infrav1beta3.VCDMachine, vmName string) (string, error) {
+
+	if vcdMachine.Status.VMName != "" {
+		return vcdMachine.Status.VMName, nil
+	}
+	_, err := vApp.GetVMByName(vmName, false)
+	if err == govcd.ErrorEntityNotFound {
+		var foundVMName string
+		if foundVMName, err = findVMNameByMachineName(vcdClient, vApp, machine.Name); err != nil {
+			return "", err
+		}
+		if foundVMName != "" {
+			vmName = foundVMName
+		}
+	} else if err != nil {
+		return "", fmt.Errorf("failed to look for VM [%s] in vApp [%s]: [%v]", vmName, vApp.VApp.Name, err)
+	}
+	vcdMachine.Status.VMName = vmName
+	return vmName, nil
+}
+
+// reconcileVMMachineName records the name of the machine of the VM in its CapvcdMachineName metadata, by which the VM
+// is found when it no longer has the name recorded for the machine.
+func reconcileVMMachineName(vm *govcd.VM, machineName string) error {
+	metadataValue, err := vm.GetMetadataByKey(CapvcdMachineName, false)
+	if err == nil && metadataValue != nil && metadataValue.TypedValue != nil &&
+		metadataValue.TypedValue.Value == machineName {
+		return nil
+	}
+	if err = vm.AddMetadataEntryWithVisibility(CapvcdMachineName, machineName, types.MetadataStringValue,
+		types.MetadataReadWriteVisibility, false); err != nil {
+		return fmt.Errorf("failed to add metadata [%s: %s] to VM [%s]: [%v]", CapvcdMachineName, machineName,
+			vm.VM.Name, err)
+	}
+	return nil
+}
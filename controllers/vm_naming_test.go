@@ -0,0 +1,82 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeGeneratedName(t *testing.T) {
+	longName := strings.Repeat("worker-", 10) + "md-0-abcde"
+	hashSuffix := regexp.MustCompile(`-[0-9a-f]{8}$`)
+
+	testCases := []struct {
+		name       string
+		input      string
+		want       string
+		wantHashed bool
+		wantErr    bool
+	}{
+		{name: "short name is kept", input: "cluster-md-0-abcde", want: "cluster-md-0-abcde"},
+		{name: "name is lowercased", input: "Cluster-MD-0", want: "cluster-md-0"},
+		{name: "spaces and dashes are trimmed", input: "  -cluster-md-0-  ", want: "cluster-md-0"},
+		{name: "name of the maximum length is kept", input: strings.Repeat("a", maxGeneratedNameLength),
+			want: strings.Repeat("a", maxGeneratedNameLength)},
+		{name: "long name is truncated and hashed", input: longName, wantHashed: true},
+		{name: "empty name is rejected", input: "", wantErr: true},
+		{name: "name of dashes only is rejected", input: " --- ", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := normalizeGeneratedName(tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got name [%s]", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: [%v]", err)
+			}
+			if len(got) > maxGeneratedNameLength {
+				t.Errorf("name [%s] is longer than [%d] characters", got, maxGeneratedNameLength)
+			}
+			if tc.wantHashed {
+				if !hashSuffix.MatchString(got) {
+					t.Errorf("name [%s] is not suffixed with a hash", got)
+				}
+				return
+			}
+			if got != tc.want {
+				t.Errorf("got name [%s], want [%s]", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeGeneratedNameKeepsTruncatedNamesUnique(t *testing.T) {
+	prefix := strings.Repeat("worker-", 10)
+	first, err := normalizeGeneratedName(prefix + "md-0-abcde")
+	if err != nil {
+		t.Fatalf("unexpected error: [%v]", err)
+	}
+	second, err := normalizeGeneratedName(prefix + "md-0-fghij")
+	if err != nil {
+		t.Fatalf("unexpected error: [%v]", err)
+	}
+	if first == second {
+		t.Errorf("names differing in their end are both truncated to [%s]", first)
+	}
+	again, err := normalizeGeneratedName(prefix + "md-0-abcde")
+	if err != nil {
+		t.Fatalf("unexpected error: [%v]", err)
+	}
+	if again != first {
+		t.Errorf("the same name is truncated to [%s] and [%s]", first, again)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// DefaultGracefulShutdownTimeout is how long the guest OS of a VM is given to shut down when its machine is deleted,
+	// unless the VCDMachine sets its own timeout.
+	DefaultGracefulShutdownTimeout = 5 * time.Minute
+
+	// VMShutdownRequeueInterval is the interval at which the shutdown of the guest OS of a VM is checked.
+	VMShutdownRequeueInterval = 15 * time.Second
+
+	// PreTerminateHookRequeueInterval is the interval at which the pre-terminate hooks of a Machine being deleted are
+	// checked again.
+	PreTerminateHookRequeueInterval = 30 * time.Second
+)
+
+// hasPreTerminateHooks checks if the Machine has pre-terminate hooks, which hold the deletion of its VM until they are
+// removed by their owners.
+func hasPreTerminateHooks(machine *clusterv1.Machine) bool {
+	return annotations.HasWithPrefix(clusterv1.PreTerminateDeleteHookAnnotationPrefix, machine.Annotations)
+}
+
+// getGracefulShutdownTimeout returns how long the guest OS of the VM of the VCDMachine is given to shut down.
+func getGracefulShutdownTimeout(vcdMachine *infrav1beta3.VCDMachine) time.Duration {
+	if vcdMachine.Spec.GracefulShutdownTimeout == nil {
+		return DefaultGracefulShutdownTimeout
+	}
+	return vcdMachine.Spec.GracefulShutdownTimeout.Duration
+}
+
+// shutdownMachineVM shuts down the guest OS of the VM of the VCDMachine being deleted, and reports whether the VM can
+// be powered off: once the guest OS is shut down, once the timeout elapsed, or right away if the shutdown cannot be
+// requested. The shutdown is not waited for; the caller requeues until the VM can be powered off.
+func shutdownMachineVM(ctx context.Context, vm *govcd.VM, vcdMachine *infrav1beta3.VCDMachine) bool {
+	log := ctrl.LoggerFrom(ctx, "vm", vm.VM.Name)
+
+	timeout := getGracefulShutdownTimeout(vcdMachine)
+	if timeout <= 0 {
+		return true
+	}
+	vmStatus, err := vm.GetStatus()
+	if err != nil {
+		log.Error(err, "failed to get the status of the VM; powering it off")
+		return true
+	}
+	if vmStatus != "POWERED_ON" {
+		return true
+	}
+
+	shutdownStartTime := vcdMachine.Status.ShutdownStartTime
+	if shutdownStartTime == nil {
+		if _, err = vm.Shutdown(); err != nil {
+			log.Error(err, "failed to shut down the guest OS of the VM; powering it off")
+			return true
+		}
+		log.Info("Shutting down the guest OS of the VM", "timeout", timeout.String())
+		now := metav1.Now()
+		vcdMachine.Status.ShutdownStartTime = &now
+		return false
+	}
+	if time.Since(shutdownStartTime.Time) < timeout {
+		return false
+	}
+	log.Info("Guest OS of the VM did not shut down in time; powering it off", "timeout", timeout.String())
+	return true
+}
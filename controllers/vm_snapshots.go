@@ -0,0 +1,200 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	kcpv1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// CapvcdSnapshotRetainedUntil is the metadata key of the VMs kept with a snapshot after their machine was replaced
+	// by an upgrade. Its value is the time, in RFC 3339, after which the VM is deleted.
+	CapvcdSnapshotRetainedUntil = "CapvcdSnapshotRetainedUntil"
+
+	// DefaultSnapshotRetention is how long the VM of a machine replaced by an upgrade is kept, unless the VCDMachine
+	// sets its own retention.
+	DefaultSnapshotRetention = 24 * time.Hour
+
+	// MimeCreateSnapshotParams is the content type of the request creating the snapshot of a VM.
+	MimeCreateSnapshotParams = "application/vnd.vmware.vcloud.createSnapshotParams+xml"
+)
+
+// createSnapshotParams is the payload of the request creating the snapshot of a VM.
+type createSnapshotParams struct {
+	XMLName     xml.Name `xml:"CreateSnapshotParams"`
+	Xmlns       string   `xml:"xmlns,attr"`
+	Name        string   `xml:"name,attr,omitempty"`
+	Memory      bool     `xml:"memory,attr"`
+	Quiesce     bool     `xml:"quiesce,attr"`
+	Description string   `xml:"Description,omitempty"`
+}
+
+// getSnapshotRetention returns how long the VM of the VCDMachine is kept once its machine is replaced by an upgrade.
+func getSnapshotRetention(vcdMachine *infrav1beta3.VCDMachine) time.Duration {
+	if vcdMachine.Spec.SnapshotRetention == nil {
+		return DefaultSnapshotRetention
+	}
+	return vcdMachine.Spec.SnapshotRetention.Duration
+}
+
+// isMachineReplacedByUpgrade checks if the Machine being deleted is replaced by an upgrade of the Kubernetes version of
+// its KubeadmControlPlane or MachineDeployment, as opposed to a scale down or the deletion of its owner.
+func isMachineReplacedByUpgrade(ctx context.Context, cli client.Client, machine *clusterv1.Machine) (bool, error) {
+	if machine.Spec.Version == nil {
+		return false, nil
+	}
+	if kcpName, ok := machine.Labels[clusterv1.MachineControlPlaneNameLabel]; ok {
+		kcp := &kcpv1.KubeadmControlPlane{}
+		if err := cli.Get(ctx, client.ObjectKey{Namespace: machine.Namespace, Name: kcpName}, kcp); err != nil {
+			return false, fmt.Errorf("failed to get KubeadmControlPlane [%s] of machine [%s]: [%v]", kcpName,
+				machine.Name, err)
+		}
+		return kcp.DeletionTimestamp.IsZero() && kcp.Spec.Version != *machine.Spec.Version, nil
+	}
+	if mdName, ok := machine.Labels[clusterv1.MachineDeploymentNameLabel]; ok {
+		md := &clusterv1.MachineDeployment{}
+		if err := cli.Get(ctx, client.ObjectKey{Namespace: machine.Namespace, Name: mdName}, md); err != nil {
+			return false, fmt.Errorf("failed to get MachineDeployment [%s] of machine [%s]: [%v]", mdName,
+				machine.Name, err)
+		}
+		return md.DeletionTimestamp.IsZero() && md.Spec.Template.Spec.Version != nil &&
+			*md.Spec.Template.Spec.Version != *machine.Spec.Version, nil
+	}
+	return false, nil
+}
+
+// shouldRetainMachineVM checks if the VM of the VCDMachine being deleted is kept with a snapshot rather than deleted.
+func (r *VCDMachineReconciler) shouldRetainMachineVM(ctx context.Context, machine *clusterv1.Machine,
+	vcdMachine *infrav1beta3.VCDMachine, vcdCluster *infrav1beta3.VCDCluster) (bool, error) {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	if !vcdMachine.Spec.SnapshotBeforeUpgrade {
+		return false, nil
+	}
+	replaced, err := isMachineReplacedByUpgrade(ctx, r.Client, machine)
+	if err != nil || !replaced {
+		return false, err
+	}
+	if len(vcdMachine.Spec.DataDisks) > 0 {
+		log.Info("Deleting the VM of the machine replaced by an upgrade without a snapshot; VMs with data disks " +
+			"cannot be snapshotted")
+		return false, nil
+	}
+	if getMachineVAppName(vcdMachine, vcdCluster) != CreateFullVAppName(vcdCluster) {
+		log.Info("Deleting the VM of the machine replaced by an upgrade without a snapshot; only the VMs in the " +
+			"vApp of the cluster are kept")
+		return false, nil
+	}
+	return true, nil
+}
+
+// retainMachineVM powers off the VM of the VCDMachine replaced by an upgrade, snapshots it and records in its metadata
+// until when it is kept.
+func retainMachineVM(ctx context.Context, vcdClient *vcdsdk.Client, vm *govcd.VM,
+	vcdMachine *infrav1beta3.VCDMachine) error {
+
+	vmStatus, err := vm.GetStatus()
+	if err != nil {
+		return fmt.Errorf("failed to get the status of VM [%s]: [%v]", vm.VM.Name, err)
+	}
+	if vmStatus == "POWERED_ON" {
+		task, err := vm.PowerOff()
+		if err == nil {
+			err = task.WaitTaskCompletion()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to power off VM [%s]: [%v]", vm.VM.Name, err)
+		}
+	}
+
+	params := &createSnapshotParams{
+		Xmlns: types.XMLNamespaceVCloud,
+		Name:  fmt.Sprintf("%s-before-upgrade", vm.VM.Name),
+		Description: fmt.Sprintf("Snapshot of the VM of machine [%s] taken by CAPVCD before its upgrade",
+			vcdMachine.Name),
+	}
+	task, err := vcdClient.VCDClient.Client.ExecuteTaskRequest(vm.VM.HREF+"/action/createSnapshot",
+		http.MethodPost, MimeCreateSnapshotParams, "error creating snapshot: %s", params)
+	if err == nil {
+		err = task.WaitTaskCompletion()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to snapshot VM [%s]: [%v]", vm.VM.Name, err)
+	}
+
+	retainedUntil := time.Now().Add(getSnapshotRetention(vcdMachine)).UTC().Format(time.RFC3339)
+	if err = vm.AddMetadataEntryWithVisibility(CapvcdSnapshotRetainedUntil, retainedUntil, types.MetadataStringValue,
+		types.MetadataReadWriteVisibility, false); err != nil {
+		return fmt.Errorf("failed to add metadata [%s: %s] to VM [%s]: [%v]", CapvcdSnapshotRetainedUntil,
+			retainedUntil, vm.VM.Name, err)
+	}
+	ctrl.LoggerFrom(ctx).Info("Kept the VM of the machine replaced by an upgrade with a snapshot", "vm", vm.VM.Name,
+		"retainedUntil", retainedUntil)
+	return nil
+}
+
+// deleteRetainedVMs deletes the VMs kept with a snapshot in the vApp of the cluster whose retention elapsed, or all of
+// them if all is set, e.g. when the cluster is deleted. Only the powered off VMs of the vApp are checked.
+func deleteRetainedVMs(ctx context.Context, vcdClient *vcdsdk.Client, vcdCluster *infrav1beta3.VCDCluster,
+	all bool) error {
+
+	log := ctrl.LoggerFrom(ctx)
+
+	vdcManager, err := vcdsdk.NewVDCManager(vcdClient, vcdClient.ClusterOrgName, getOvdcName(vcdCluster))
+	if err != nil {
+		return fmt.Errorf("failed to create vdc manager: [%v]", err)
+	}
+	vAppName := CreateFullVAppName(vcdCluster)
+	vApp, err := vdcManager.Vdc.GetVAppByName(vAppName, true)
+	if err == govcd.ErrorEntityNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get vApp [%s]: [%v]", vAppName, err)
+	}
+	if vApp.VApp.Children == nil {
+		return nil
+	}
+
+	for _, child := range vApp.VApp.Children.VM {
+		if types.VAppStatuses[child.Status] != "POWERED_OFF" {
+			continue
+		}
+		vm := govcd.NewVM(&vcdClient.VCDClient.Client)
+		vm.VM = child
+		metadataValue, err := vm.GetMetadataByKey(CapvcdSnapshotRetainedUntil, false)
+		if err != nil || metadataValue == nil || metadataValue.TypedValue == nil {
+			continue
+		}
+		retainedUntil, err := time.Parse(time.RFC3339, metadataValue.TypedValue.Value)
+		if err != nil {
+			log.Error(err, "invalid retention of the VM kept with a snapshot", "vm", child.Name)
+			continue
+		}
+		if !all && time.Now().Before(retainedUntil) {
+			continue
+		}
+		log.Info("Deleting the VM kept with a snapshot", "vm", child.Name, "retainedUntil", retainedUntil)
+		if err = vm.Delete(); err != nil {
+			return fmt.Errorf("failed to delete VM [%s] kept with a snapshot: [%v]", child.Name, err)
+		}
+	}
+	return nil
+}
@@ -11,11 +11,13 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/vmware/cluster-api-provider-cloud-director/release"
 
 	"go.uber.org/zap/zapcore"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -23,6 +25,9 @@ import (
 	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	bootstrapv1beta1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
 	addonsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
+	ipamv1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 
 	infrav1alpha4 "github.com/vmware/cluster-api-provider-cloud-director/api/v1alpha4"
@@ -30,6 +35,7 @@ import (
 	infrav1beta2 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta2"
 	infrav1beta3 "github.com/vmware/cluster-api-provider-cloud-director/api/v1beta3"
 	"github.com/vmware/cluster-api-provider-cloud-director/controllers"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/capisdk"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -62,6 +68,10 @@ func init() {
 	utilruntime.Must(bootstrapv1beta1.AddToScheme(myscheme))
 	// We need the addonsv1 scheme in order to list the ClusterResourceSetBindings addon.
 	utilruntime.Must(addonsv1.AddToScheme(myscheme))
+	// We need the ipamv1 scheme in order to claim IP addresses from IPAM providers.
+	utilruntime.Must(ipamv1.AddToScheme(myscheme))
+	// We need the expv1 scheme in order to reconcile the VCDMachinePools of the MachinePools.
+	utilruntime.Must(expv1.AddToScheme(myscheme))
 }
 
 func main() {
@@ -70,6 +80,22 @@ func main() {
 	var probeAddr string
 	var syncPeriod time.Duration
 	var concurrency int
+	var enableRDEDesiredStateSync bool
+	var rdeDesiredStateSyncPeriod time.Duration
+	var enableClusterSharding bool
+	var clusterShardLeaseDuration time.Duration
+	var vcdProxyConfig string
+	var auditInterval time.Duration
+	var enableMachinePools bool
+	var rdeTypeVersion string
+	var rdeMinWriteInterval time.Duration
+	var rdeEventHistorySize int
+	var disableRDEKubeConfig bool
+	var rdeKubeConfigKeySecret string
+	var vAppCompositionBatchWindow time.Duration
+	var vAppCompositionMaxBatchSize int
+	var templateImportMaxSize string
+	var templateSourceAllowedHosts string
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -80,6 +106,54 @@ func main() {
 		"The minimum interval at which watched resources are reconciled (e.g. 15m)")
 	flag.IntVar(&concurrency, "concurrency", 10,
 		"The number of VCD machines to process simultaneously")
+	flag.BoolVar(&enableRDEDesiredStateSync, "enable-rde-desired-state-sync", false,
+		"Apply changes made to the CAPI yaml of the cluster RDE in VCD (scaling, upgrades) to the CAPI objects")
+	flag.DurationVar(&rdeDesiredStateSyncPeriod, "rde-desired-state-sync-period", controllers.DefaultRDEDesiredStateSyncPeriod,
+		"The interval at which the RDE of each cluster is checked for desired state changes")
+	flag.BoolVar(&enableClusterSharding, "enable-cluster-sharding", false,
+		"Split the clusters between all the replicas of the controller manager instead of electing a leader. "+
+			"Each replica must have a unique POD_NAME.")
+	flag.DurationVar(&clusterShardLeaseDuration, "cluster-shard-lease-duration", controllers.DefaultClusterShardLeaseDuration,
+		"The duration after which the clusters of a replica which stopped renewing its lease are taken over by the other replicas")
+	flag.StringVar(&vcdProxyConfig, "vcd-proxy-config", "",
+		"The path of the file listing the outbound proxies through which the VCD sites are reached, "+
+			"usually mounted from a Secret")
+	flag.DurationVar(&auditInterval, "audit-interval", controllers.DefaultAuditInterval,
+		"The interval at which the infrastructure of each cluster is audited and the result reported in its "+
+			"InfrastructureAudited condition and in an Event; 0 disables the audit")
+	flag.BoolVar(&enableMachinePools, "enable-machine-pools", false,
+		"Reconcile the VCDMachinePools of the experimental Cluster API MachinePools. "+
+			"The MachinePool feature gate of Cluster API must be enabled as well.")
+	flag.StringVar(&rdeTypeVersion, "rde-type-version", capisdk.CapvcdRDETypeVersion,
+		"The version of the capvcdCluster entity type the RDEs of the clusters are created with and upgraded to. "+
+			"Set it to 1.1.0 or 1.2.0 for VCD sites where the latest version of the entity type is not registered")
+	flag.DurationVar(&rdeMinWriteInterval, "rde-min-write-interval", capisdk.DefaultRDEMinWriteInterval,
+		"The minimum interval between two writes of the status changes and events batched for the RDE of a cluster; "+
+			"0 writes them at every reconciliation")
+	flag.IntVar(&rdeEventHistorySize, "rde-event-history-size", capisdk.DefaultRollingWindowSize,
+		"The number of the latest events and errors kept in the eventSet and errorSet of the RDE of each cluster")
+	flag.BoolVar(&disableRDEKubeConfig, "disable-rde-kubeconfig", false,
+		"Do not publish the kubeconfig of the clusters in the private section of their RDEs, and remove the "+
+			"kubeconfigs already published")
+	flag.StringVar(&rdeKubeConfigKeySecret, "rde-kubeconfig-key-secret", "",
+		"The namespace/name of the Secret holding under 'key' the 32-byte AES-256-GCM key the kubeconfigs published "+
+			"in the RDEs are encrypted with, and optionally its ID under 'keyId'; they are published in clear if unset")
+
+	flag.DurationVar(&vAppCompositionBatchWindow, "vapp-composition-batch-window",
+		controllers.DefaultVAppCompositionBatchWindow,
+		"The time during which the VMs requested in a vApp are collected to be created together by a single "+
+			"recomposition of the vApp; 0 creates them as soon as they are requested, the ones requested while a "+
+			"recomposition of the vApp is running being created together by the next one")
+	flag.IntVar(&vAppCompositionMaxBatchSize, "vapp-composition-max-batch-size",
+		controllers.DefaultVAppCompositionMaxBatchSize,
+		"The maximum number of VMs created together by a single recomposition of a vApp; 1 creates them one at a time")
+	flag.StringVar(&templateImportMaxSize, "template-import-max-size",
+		resource.NewQuantity(controllers.DefaultTemplateImportMaxSize, resource.BinarySI).String(),
+		"The maximum size of the OVAs downloaded to import the templates of the TemplateSources, e.g. 20Gi")
+	flag.StringVar(&templateSourceAllowedHosts, "template-source-allowed-hosts", "",
+		"Comma-separated list of the hosts the OVAs of the TemplateSources may be downloaded from, a host starting "+
+			"with a dot allowing its subdomains; any host is allowed when empty, except the loopback and link-local "+
+			"addresses")
 
 	opts := zap.Options{
 		Development: true,
@@ -94,6 +168,63 @@ func main() {
 	}
 	setupLog.Info("CAPVCD version", "version", release.Version)
 
+	if enableClusterSharding && enableLeaderElection {
+		setupLog.Error(fmt.Errorf("--enable-cluster-sharding and --leader-elect are mutually exclusive"), "")
+		os.Exit(1)
+	}
+
+	if err := capisdk.SetCapvcdRDETypeVersion(rdeTypeVersion); err != nil {
+		setupLog.Error(err, "invalid --rde-type-version")
+		os.Exit(1)
+	}
+	if rdeMinWriteInterval < 0 {
+		setupLog.Error(fmt.Errorf("--rde-min-write-interval must not be negative"), "")
+		os.Exit(1)
+	}
+	capisdk.RDEMinWriteInterval = rdeMinWriteInterval
+	if rdeEventHistorySize <= 0 {
+		setupLog.Error(fmt.Errorf("--rde-event-history-size must be positive"), "")
+		os.Exit(1)
+	}
+	capisdk.RDEEventHistorySize = rdeEventHistorySize
+	if vAppCompositionBatchWindow < 0 {
+		setupLog.Error(fmt.Errorf("--vapp-composition-batch-window must not be negative"), "")
+		os.Exit(1)
+	}
+	controllers.VAppCompositionBatchWindow = vAppCompositionBatchWindow
+	if vAppCompositionMaxBatchSize <= 0 {
+		setupLog.Error(fmt.Errorf("--vapp-composition-max-batch-size must be positive"), "")
+		os.Exit(1)
+	}
+	controllers.VAppCompositionMaxBatchSize = vAppCompositionMaxBatchSize
+	maxSize, err := resource.ParseQuantity(templateImportMaxSize)
+	if err != nil || maxSize.Value() <= 0 {
+		setupLog.Error(fmt.Errorf("--template-import-max-size must be a positive quantity"), "")
+		os.Exit(1)
+	}
+	controllers.TemplateImportMaxSize = maxSize.Value()
+	for _, host := range strings.Split(templateSourceAllowedHosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			controllers.TemplateSourceAllowedHosts = append(controllers.TemplateSourceAllowedHosts, host)
+		}
+	}
+	var rdeKubeConfigKeySecretKey *client.ObjectKey
+	if rdeKubeConfigKeySecret != "" {
+		namespace, name, found := strings.Cut(rdeKubeConfigKeySecret, "/")
+		if !found || namespace == "" || name == "" {
+			setupLog.Error(fmt.Errorf("--rde-kubeconfig-key-secret must be namespace/name"), "")
+			os.Exit(1)
+		}
+		rdeKubeConfigKeySecretKey = &client.ObjectKey{Namespace: namespace, Name: name}
+	}
+
+	if vcdProxyConfig != "" {
+		if err := controllers.LoadVCDSiteProxies(vcdProxyConfig); err != nil {
+			setupLog.Error(err, "unable to load the VCD proxy configuration")
+			os.Exit(1)
+		}
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 myscheme,
 		MetricsBindAddress:     metricsAddr,
@@ -110,8 +241,36 @@ func main() {
 
 	ctx := context.Background()
 
+	var clusterShards *controllers.ClusterShardManager
+	if enableClusterSharding {
+		identity := os.Getenv("POD_NAME")
+		if identity == "" {
+			if identity, err = os.Hostname(); err != nil {
+				setupLog.Error(err, "unable to determine the identity of the replica")
+				os.Exit(1)
+			}
+		}
+		clusterShards = &controllers.ClusterShardManager{
+			Client:        mgr.GetClient(),
+			APIReader:     mgr.GetAPIReader(),
+			Namespace:     os.Getenv(controllers.EnvPodNamespace),
+			Identity:      identity,
+			LeaseDuration: clusterShardLeaseDuration,
+		}
+		if clusterShards.Namespace == "" {
+			clusterShards.Namespace = controllers.DefaultManagerNamespace
+		}
+		if err = mgr.Add(clusterShards); err != nil {
+			setupLog.Error(err, "unable to set up cluster sharding")
+			os.Exit(1)
+		}
+	}
+
+	controllers.SetOvdcConcurrencyLimit(concurrency)
 	if err = (&controllers.VCDMachineReconciler{
-		Client: mgr.GetClient(),
+		Client:   mgr.GetClient(),
+		Recorder: mgr.GetEventRecorderFor("vcdmachine-controller"),
+		Shards:   clusterShards,
 	}).SetupWithManager(ctx, mgr, controller.Options{
 		MaxConcurrentReconciles: concurrency,
 	}); err != nil {
@@ -120,14 +279,44 @@ func main() {
 	}
 
 	if err = (&controllers.VCDClusterReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr, controller.Options{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Recorder:      mgr.GetEventRecorderFor("vcdcluster-controller"),
+		Shards:        clusterShards,
+		AuditInterval: auditInterval,
+
+		DisableRDEKubeConfig:   disableRDEKubeConfig,
+		RDEKubeConfigKeySecret: rdeKubeConfigKeySecretKey,
+	}).SetupWithManager(ctx, mgr, controller.Options{
 		MaxConcurrentReconciles: concurrency,
 	}); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "VCDCluster")
 		os.Exit(1)
 	}
+	if enableMachinePools {
+		if err = (&controllers.VCDMachinePoolReconciler{
+			Client:   mgr.GetClient(),
+			Recorder: mgr.GetEventRecorderFor("vcdmachinepool-controller"),
+			Shards:   clusterShards,
+		}).SetupWithManager(ctx, mgr, controller.Options{
+			MaxConcurrentReconciles: concurrency,
+		}); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "VCDMachinePool")
+			os.Exit(1)
+		}
+	}
+	if enableRDEDesiredStateSync {
+		if err = (&controllers.RDEDesiredStateReconciler{
+			Client:     mgr.GetClient(),
+			SyncPeriod: rdeDesiredStateSyncPeriod,
+			Shards:     clusterShards,
+		}).SetupWithManager(ctx, mgr, controller.Options{
+			MaxConcurrentReconciles: concurrency,
+		}); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "RDEDesiredState")
+			os.Exit(1)
+		}
+	}
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
 		if err = (&infrav1beta3.VCDCluster{}).SetupWebhookWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "VCDCluster")
@@ -141,6 +330,10 @@ func main() {
 			setupLog.Error(err, "unable to create webhook", "webhook", "VCDMachineTemplate")
 			os.Exit(1)
 		}
+		if err = (&infrav1beta3.VCDClusterTemplate{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "VCDClusterTemplate")
+			os.Exit(1)
+		}
 	}
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -152,7 +345,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	// TODO: check if entity type [capvcdCluster:1.1.0] is already registered
+	// TODO: check if entity type [capvcdCluster:1.3.0] is already registered
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
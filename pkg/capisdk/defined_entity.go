@@ -11,7 +11,8 @@ import (
 	"github.com/vmware/cluster-api-provider-cloud-director/pkg/util"
 	"github.com/vmware/cluster-api-provider-cloud-director/pkg/vcdtypes/rde_type_1_0_0"
 	"github.com/vmware/cluster-api-provider-cloud-director/pkg/vcdtypes/rde_type_1_1_0"
-	rdeType "github.com/vmware/cluster-api-provider-cloud-director/pkg/vcdtypes/rde_type_1_1_0"
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/vcdtypes/rde_type_1_2_0"
+	rdeType "github.com/vmware/cluster-api-provider-cloud-director/pkg/vcdtypes/rde_type_1_3_0"
 	"github.com/vmware/cluster-api-provider-cloud-director/release"
 	"k8s.io/klog"
 	"net/http"
@@ -32,9 +33,8 @@ const (
 	CAPVCDEntityTypePrefix              = "urn:vcloud:type:vmware:capvcdCluster"
 	CAPVCDEntityTypeDefaultMajorVersion = "1"
 
-	CAPVCDClusterKind             = "CAPVCDCluster"
-	CAPVCDClusterEntityApiVersion = "capvcd.vmware.com/v1.1"
-	DefaultRollingWindowSize      = 20
+	CAPVCDClusterKind        = "CAPVCDCluster"
+	DefaultRollingWindowSize = 20
 
 	// VCDCluster Events
 	RdeUpgraded           = "RdeUpgraded"
@@ -43,6 +43,21 @@ const (
 	ControlplaneReady     = "ControlplaneReady"
 	LoadbalancerDeleted   = "LoadbalancerDeleted"
 	VappDeleted           = "vAppDeleted"
+	// RdeDesiredStateApplied is set when changes made to the RDE spec from VCD are applied to the CAPI objects
+	RdeDesiredStateApplied = "RdeDesiredStateApplied"
+	// RdeAddonsApplied is set when the addons of the RDE spec are applied to the management cluster
+	RdeAddonsApplied = "RdeAddonsApplied"
+	// ClusterScaled is set when the desired replicas of a node pool change
+	ClusterScaled = "ClusterScaled"
+	// KubernetesUpgradeStarted is set when the Kubernetes version of the control plane changes
+	KubernetesUpgradeStarted = "KubernetesUpgradeStarted"
+	// KubernetesUpgradeCompleted is set when all the machines of the cluster run the new Kubernetes version
+	KubernetesUpgradeCompleted = "KubernetesUpgradeCompleted"
+	// ControlPlaneEndpointChanged is set when the control plane endpoint of the cluster changes
+	ControlPlaneEndpointChanged = "ControlPlaneEndpointChanged"
+	// KubeConfigRotated is set when the kubeconfig of the cluster published in the RDE changes, e.g. when its
+	// certificates are renewed
+	KubeConfigRotated = "KubeConfigRotated"
 
 	// VCDCluster Errors
 	// Set RdeError for any errors that occurs during Rde update/validation errors
@@ -57,6 +72,10 @@ const (
 	VCDClusterVappDeleteError = "VCDClusterVAppDeleteError"
 	// Set VCDClusterError for metadata errors; newVdcManager errors; newGWManager errors
 	VCDClusterError = "VCDClusterError"
+	// Set RdeDesiredStateError when changes made to the RDE spec from VCD are rejected
+	RdeDesiredStateError = "RdeDesiredStateError"
+	// Set RdeAddonsError when the addons of the RDE spec are rejected or cannot be applied
+	RdeAddonsError = "RdeAddonsError"
 
 	// VCDMachine Events
 	InfraVmPoweredOn         = "VcdMachineInfraVMPoweredOn"
@@ -95,6 +114,51 @@ var (
 	}
 )
 
+var (
+	// CapvcdRDETypeVersion is the version of the capvcdCluster entity type the RDEs of the clusters are created with and
+	// upgraded to. It is the latest version unless SetCapvcdRDETypeVersion selected an older one.
+	CapvcdRDETypeVersion = rdeType.CapvcdRDETypeVersion
+
+	// capvcdClusterEntityApiVersions are the API versions of the CAPVCD entities by version of the entity type, for
+	// the versions CAPVCD can write.
+	capvcdClusterEntityApiVersions = map[string]string{
+		rde_type_1_1_0.CapvcdRDETypeVersion: "capvcd.vmware.com/v1.1",
+		rde_type_1_2_0.CapvcdRDETypeVersion: "capvcd.vmware.com/v1.2",
+		rdeType.CapvcdRDETypeVersion:        "capvcd.vmware.com/v1.3",
+	}
+
+	// olderCapvcdClusterEntities return an empty CAPVCD entity of the versions of the entity type older than the latest
+	// one. The sections of the RDEs of these versions are marshaled through it before they are written, so that they
+	// only carry the fields of the schema of their version.
+	olderCapvcdClusterEntities = map[string]func() interface{}{
+		rde_type_1_1_0.CapvcdRDETypeVersion: func() interface{} { return &rde_type_1_1_0.CAPVCDEntity{} },
+		rde_type_1_2_0.CapvcdRDETypeVersion: func() interface{} { return &rde_type_1_2_0.CAPVCDEntity{} },
+	}
+)
+
+// SetCapvcdRDETypeVersion sets the version of the capvcdCluster entity type the RDEs are created with and upgraded to.
+// Writing version 1.1.0 or 1.2.0 keeps CAPVCD working on VCD sites where the entity type of the latest version is not
+// registered; the RDEs already upgraded to a later version are not downgraded.
+func SetCapvcdRDETypeVersion(version string) error {
+	if _, ok := capvcdClusterEntityApiVersions[version]; !ok {
+		return fmt.Errorf("unsupported capvcdCluster entity type version [%s]; supported versions are [%s], [%s] and [%s]",
+			version, rde_type_1_1_0.CapvcdRDETypeVersion, rde_type_1_2_0.CapvcdRDETypeVersion,
+			rdeType.CapvcdRDETypeVersion)
+	}
+	CapvcdRDETypeVersion = version
+	return nil
+}
+
+// GetCAPVCDEntityTypeID returns the ID of the capvcdCluster entity type the RDEs are created with.
+func GetCAPVCDEntityTypeID() string {
+	return fmt.Sprintf("%s:%s", CAPVCDEntityTypePrefix, CapvcdRDETypeVersion)
+}
+
+// GetCAPVCDClusterEntityApiVersion returns the API version of the CAPVCD entities the RDEs are created with.
+func GetCAPVCDClusterEntityApiVersion() string {
+	return capvcdClusterEntityApiVersions[CapvcdRDETypeVersion]
+}
+
 type CapvcdRdeManager struct {
 	Client     *vcdsdk.Client
 	RdeManager *vcdsdk.RDEManager
@@ -124,6 +188,49 @@ func convertToMap(obj interface{}) (map[string]interface{}, error) {
 	return parsedMap, nil
 }
 
+// getRDETypeVersion returns the version of the entity type with the given ID.
+func getRDETypeVersion(entityTypeID string) string {
+	entiyTypeSplitArr := strings.Split(entityTypeID, ":")
+	// last item of the array will be the version string
+	return entiyTypeSplitArr[len(entiyTypeSplitArr)-1]
+}
+
+// formatForRDETypeVersion marshals a section of the CAPVCD entity, at the given path of the entity, through the CAPVCD
+// entity of the given version of the entity type. The fields the schema of an older version does not have are
+// dropped; the sections of RDEs of the latest version are returned as they are.
+func formatForRDETypeVersion(rdeTypeVersion string, sectionPath []string,
+	section map[string]interface{}) (map[string]interface{}, error) {
+	newEntity, ok := olderCapvcdClusterEntities[rdeTypeVersion]
+	if !ok {
+		return section, nil
+	}
+	var wrappedSection interface{} = section
+	for i := len(sectionPath) - 1; i >= 0; i-- {
+		wrappedSection = map[string]interface{}{sectionPath[i]: wrappedSection}
+	}
+	byteArr, err := json.Marshal(wrappedSection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read section [%s] of CAPVCD entity to json: [%v]",
+			strings.Join(sectionPath, "."), err)
+	}
+	entity := newEntity()
+	if err = json.Unmarshal(byteArr, entity); err != nil {
+		return nil, fmt.Errorf("failed to convert section [%s] of CAPVCD entity to version [%s]: [%v]",
+			strings.Join(sectionPath, "."), rdeTypeVersion, err)
+	}
+	formattedSection, err := convertToMap(entity)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range sectionPath {
+		if formattedSection, ok = formattedSection[key].(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("section [%s] of CAPVCD entity of version [%s] is not an object",
+				strings.Join(sectionPath, "."), rdeTypeVersion)
+		}
+	}
+	return formattedSection, nil
+}
+
 func CheckIfClusterRdeNeedsUpgrade(srcRdeTypeVersion string, tgtRdeTypeVersion string) bool {
 	entityTypeSemVer, err := semver.New(srcRdeTypeVersion)
 	if err != nil {
@@ -138,7 +245,10 @@ func CheckIfClusterRdeNeedsUpgrade(srcRdeTypeVersion string, tgtRdeTypeVersion s
 	return entityTypeSemVer.LT(*tgtRdeTypeVersionSemVer)
 }
 
-func patchObject(inputObj interface{}, patchMap map[string]interface{}) (map[string]interface{}, error) {
+// patchObject sets the fields of inputObj named by the keys of patchMap to their values, and returns inputObj as a map
+// along with whether any field changed.
+func patchObject(inputObj interface{}, patchMap map[string]interface{}) (map[string]interface{}, bool, error) {
+	changed := false
 	for k, v := range patchMap {
 		fields := strings.Split(k, ".")
 		updatedVal := reflect.ValueOf(v)
@@ -157,13 +267,16 @@ func patchObject(inputObj interface{}, patchMap map[string]interface{}) (map[str
 				}
 			}
 		}
+		if !reflect.DeepEqual(objVal.Interface(), v) {
+			changed = true
+		}
 		objVal.Set(updatedVal)
 	}
 	updatedMap, err := convertToMap(inputObj)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert object [%v] to map[string]interface{}: [%v]", inputObj, err)
+		return nil, false, fmt.Errorf("failed to convert object [%v] to map[string]interface{}: [%v]", inputObj, err)
 	}
-	return updatedMap, nil
+	return updatedMap, changed, nil
 }
 
 // SetIsManagementClusterInRDE : sets the isManagementCluster flag in RDE for the management cluster
@@ -188,8 +301,22 @@ func (capvcdRdeManager *CapvcdRdeManager) SetIsManagementClusterInRDE(ctx contex
 // specPatch["CapiYaml"] = updated-yaml
 // metadataPatch["Name"] = updated-name
 // capvcdStatusPatch["Version"] = updated-version
+// The RDE is written right away; QueueRDEPatch batches the patches with the other changes of the RDE instead.
 func (capvcdRdeManager *CapvcdRdeManager) PatchRDE(ctx context.Context, specPatch, metadataPatch,
-	capvcdStatusPatch map[string]interface{}, rdeID string, externalID string, updateExternalID bool) (rde *swagger.DefinedEntity, err error) {
+	capvcdStatusPatch map[string]interface{}, rdeID string, externalID string, updateExternalID bool) (*swagger.DefinedEntity, error) {
+	return capvcdRdeManager.writeRDE(ctx, rdeID, &rdeChanges{
+		specPatch:         specPatch,
+		metadataPatch:     metadataPatch,
+		capvcdStatusPatch: capvcdStatusPatch,
+		externalID:        externalID,
+		updateExternalID:  updateExternalID,
+	})
+}
+
+// writeRDE applies the changes to the RDE and updates it using its etag, retrying with the latest RDE when it was
+// modified concurrently. The RDE is not updated if the changes leave it as is.
+func (capvcdRdeManager *CapvcdRdeManager) writeRDE(ctx context.Context, rdeID string,
+	changes *rdeChanges) (rde *swagger.DefinedEntity, err error) {
 	defer func() {
 		// recover from panic if panic occurs because of
 		// 1. calling Set() on a zero value
@@ -211,7 +338,7 @@ func (capvcdRdeManager *CapvcdRdeManager) PatchRDE(ctx context.Context, specPatc
 		if err != nil {
 			return nil, fmt.Errorf("failed to call get defined entity RDE with ID [%s]: [%s]", rdeID, err)
 		}
-		if len(specPatch) == 0 && len(metadataPatch) == 0 && len(capvcdStatusPatch) == 0 {
+		if changes.isEmpty() {
 			// no updates to the entity
 			return &rde, nil
 		}
@@ -225,20 +352,49 @@ func (capvcdRdeManager *CapvcdRdeManager) PatchRDE(ctx context.Context, specPatc
 			return nil, fmt.Errorf("failed to convert map to CAPVCD entity [%v]", err)
 		}
 
+		// the events are appended to the eventSet of the latest RDE, capped to the rolling window
+		capvcdStatusPatch := changes.capvcdStatusPatch
+		if len(changes.events) != 0 {
+			capvcdStatusPatch = mergePatch(nil, changes.capvcdStatusPatch)
+			eventSet := append(append([]vcdsdk.BackendEvent{}, capvcdEntity.Status.CAPVCDStatus.EventSet...),
+				changes.events...)
+			if len(eventSet) > RDEEventHistorySize {
+				eventSet = eventSet[len(eventSet)-RDEEventHistorySize:]
+			}
+			capvcdStatusPatch["EventSet"] = eventSet
+		}
+
+		// the sections are patched with the latest CAPVCD entity, and written in the format of the version of the RDE
+		rdeTypeVersion := getRDETypeVersion(rde.EntityType)
+		changed := changes.updateExternalID && rde.ExternalId != changes.externalID
 		// patch entity.spec portion of the CAPVCD RDE
-		if len(specPatch) != 0 {
-			rde.Entity["spec"], err = patchObject(&capvcdEntity.Spec, specPatch)
+		if len(changes.specPatch) != 0 {
+			var specChanged bool
+			var specMap map[string]interface{}
+			specMap, specChanged, err = patchObject(&capvcdEntity.Spec, changes.specPatch)
 			if err != nil {
 				return nil, fmt.Errorf("failed to patch spec of CAPVCD entity: [%v]", err)
 			}
+			rde.Entity["spec"], err = formatForRDETypeVersion(rdeTypeVersion, []string{"spec"}, specMap)
+			if err != nil {
+				return nil, fmt.Errorf("failed to patch spec of CAPVCD entity: [%v]", err)
+			}
+			changed = changed || specChanged
 		}
 
 		// patch entity.metadata portion of the CAPVCD RDE
-		if len(metadataPatch) != 0 {
-			rde.Entity["metadata"], err = patchObject(&capvcdEntity.Metadata, metadataPatch)
+		if len(changes.metadataPatch) != 0 {
+			var metadataChanged bool
+			var metadataMap map[string]interface{}
+			metadataMap, metadataChanged, err = patchObject(&capvcdEntity.Metadata, changes.metadataPatch)
+			if err != nil {
+				return nil, fmt.Errorf("failed to patch metadata of CAPVCD entity: [%v]", err)
+			}
+			rde.Entity["metadata"], err = formatForRDETypeVersion(rdeTypeVersion, []string{"metadata"}, metadataMap)
 			if err != nil {
 				return nil, fmt.Errorf("failed to patch metadata of CAPVCD entity: [%v]", err)
 			}
+			changed = changed || metadataChanged
 		}
 
 		// patch entity.status.capvcd portion of the CAPVCD RDE
@@ -252,18 +408,35 @@ func (capvcdRdeManager *CapvcdRdeManager) PatchRDE(ctx context.Context, specPatc
 			if !ok {
 				return nil, fmt.Errorf("error parsing status section of CAPVCD entity to map[string]interface{}")
 			}
-			statusMap["capvcd"], err = patchObject(&capvcdEntity.Status.CAPVCDStatus, capvcdStatusPatch)
+			var statusChanged bool
+			var capvcdStatusMap map[string]interface{}
+			capvcdStatusMap, statusChanged, err = patchObject(&capvcdEntity.Status.CAPVCDStatus, capvcdStatusPatch)
+			if err != nil {
+				return nil, fmt.Errorf("failed to patch capvcd status in the CAPVCD entity: [%v]", err)
+			}
+			statusMap["capvcd"], err = formatForRDETypeVersion(rdeTypeVersion, []string{"status", "capvcd"},
+				capvcdStatusMap)
 			if err != nil {
 				return nil, fmt.Errorf("failed to patch capvcd status in the CAPVCD entity: [%v]", err)
 			}
+			changed = changed || statusChanged
+		}
+		if !changed {
+			klog.V(4).Infof("skipping the update of defined entity with ID [%s] as it is up to date", rdeID)
+			return &rde, nil
 		}
-		if updateExternalID {
-			klog.V(4).Infof("setting externalID as [%s] in RDE [%s]", externalID, rdeID)
-			rde.ExternalId = externalID
+		if changes.updateExternalID {
+			klog.V(4).Infof("setting externalID as [%s] in RDE [%s]", changes.externalID, rdeID)
+			rde.ExternalId = changes.externalID
 		}
 
 		// update the defined entity
 		rde, resp, err = client.APIClient.DefinedEntityApi.UpdateDefinedEntity(ctx, rde, etag, rdeID, org.Org.ID, nil)
+		if resp != nil && resp.StatusCode == http.StatusPreconditionFailed {
+			recordRDEWriteConflict()
+			// give the concurrent writer time to finish before reading the RDE again
+			time.Sleep(time.Duration(retries+1) * rdeWriteConflictBackoff)
+		}
 		if err != nil {
 			klog.V(5).Infof("failed to update defined entity with ID [%s] using etag [%s]: [%v]. Remaining retry attempts: [%d]", rdeID, etag, err, MaxUpdateRetries-retries-1)
 			continue
@@ -273,6 +446,8 @@ func (capvcdRdeManager *CapvcdRdeManager) PatchRDE(ctx context.Context, specPatc
 			continue
 		}
 		klog.V(4).Infof("successfully updated defined entity with ID [%s]", rdeID)
+		recordRDEUpdate(rdeID)
+		recordRDEWrite(rdeID)
 		return &rde, nil
 	}
 	return nil, fmt.Errorf("failed to update defined entity with ID [%s]", rdeID)
@@ -330,9 +505,7 @@ func (capvcdRdeManager *CapvcdRdeManager) GetRDEVersion(ctx context.Context, rde
 		}
 	}
 
-	entiyTypeSplitArr := strings.Split(definedEntity.EntityType, ":")
-	// last item of the array will be the version string
-	return &definedEntity, entiyTypeSplitArr[len(entiyTypeSplitArr)-1], nil
+	return &definedEntity, getRDETypeVersion(definedEntity.EntityType), nil
 }
 
 // IsVCDKECluster only returns true/false, not return error.
@@ -434,7 +607,7 @@ func (capvcdRdeManager *CapvcdRdeManager) IsCapvcdEntityTypeRegistered(version s
 	return true
 }
 
-// convertFrom110Format provides an automatic conversion from RDE Version 1.1.0 to the latest RDE Version in use
+// convertFrom110Format provides an automatic conversion from RDE Version 1.1.0 or 1.2.0 to the RDE Version in use
 // Get the srcCapvcdEntity and sourceRDEVersion according to RDE ID.
 // Provide an automatic conversion of the content in srcCapvcdEntity.entity.status.capvcd content to the latest RDE version format (rdeType.CAPVCDStatus)
 // Add the placeholder for any special conversion logic inside rdeType.CAPVCDStatus (for developers)
@@ -442,22 +615,22 @@ func (capvcdRdeManager *CapvcdRdeManager) IsCapvcdEntityTypeRegistered(version s
 // Call an API call (PUT) to update CAPVCD entity and persist data into VCD
 // Return dstCapvcdEntity as output. CAPVCD update capiYaml in the parent method reconcileRDE()
 func (capvcdRdeManager *CapvcdRdeManager) convertFrom110Format(ctx context.Context, srcRde *swagger.DefinedEntity, srcRdeTypeVersion string) (*swagger.DefinedEntity, error) {
-	if srcRdeTypeVersion == rdeType.CapvcdRDETypeVersion {
-		klog.V(4).Infof("RDE [%s] is already upgraded to version [%s]", srcRde.Id, rdeType.CapvcdRDETypeVersion)
+	if srcRdeTypeVersion == CapvcdRDETypeVersion {
+		klog.V(4).Infof("RDE [%s] is already upgraded to version [%s]", srcRde.Id, CapvcdRDETypeVersion)
 		return srcRde, nil
 	}
 	if capvcdRdeManager.Client == nil {
 		return nil, fmt.Errorf("obtained nil VCD Client while upgrading RDE [%s(%s)] from Version [%s] to Version [%s]",
-			srcRde.Name, srcRde.Id, rde_type_1_1_0.CapvcdRDETypeVersion, rdeType.CapvcdRDETypeVersion)
+			srcRde.Name, srcRde.Id, srcRdeTypeVersion, CapvcdRDETypeVersion)
 	}
 	org, err := capvcdRdeManager.Client.VCDClient.GetOrgByName(capvcdRdeManager.Client.ClusterOrgName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get the org [%s] while upgrading RDE [%s(%s)] from Version [%s] to Version [%s]: [%v]", capvcdRdeManager.Client.ClusterOrgName,
-			srcRde.Name, srcRde.Id, rde_type_1_1_0.CapvcdRDETypeVersion, rdeType.CapvcdRDETypeVersion, err)
+			srcRde.Name, srcRde.Id, srcRdeTypeVersion, CapvcdRDETypeVersion, err)
 	}
 	if org == nil || org.Org == nil {
 		return nil, fmt.Errorf("obtained nil org [%s] while upgrading RDE [%s(%s)] from Version [%s] to Version [%s]", capvcdRdeManager.Client.ClusterOrgName,
-			srcRde.Name, srcRde.Id, rde_type_1_1_0.CapvcdRDETypeVersion, rdeType.CapvcdRDETypeVersion)
+			srcRde.Name, srcRde.Id, srcRdeTypeVersion, CapvcdRDETypeVersion)
 	}
 	for retries := 0; retries < MaxUpdateRetries; retries++ {
 		srcCapvcdEntity, resp, etag, err := capvcdRdeManager.Client.APIClient.DefinedEntityApi.GetDefinedEntity(ctx, srcRde.Id, org.Org.ID)
@@ -466,12 +639,12 @@ func (capvcdRdeManager *CapvcdRdeManager) convertFrom110Format(ctx context.Conte
 			if gsErr, ok := err.(swagger.GenericSwaggerError); ok {
 				responseMessageBytes = gsErr.Body()
 				klog.Errorf("error occurred when upgrading defined entity [%s] to version [%s]: [%s]",
-					srcRde.Id, rdeType.CapvcdRDETypeVersion, string(responseMessageBytes))
+					srcRde.Id, CapvcdRDETypeVersion, string(responseMessageBytes))
 			}
-			return nil, fmt.Errorf("failed to get the defined entity to convert RDE to version [%s]", rdeType.CapvcdRDETypeVersion)
+			return nil, fmt.Errorf("failed to get the defined entity to convert RDE to version [%s]", CapvcdRDETypeVersion)
 		} else if resp == nil {
 			return nil, fmt.Errorf("unexpected response when fetching the defined entity [%s] to version [%s]; obtained nil response",
-				srcRde.Id, rdeType.CapvcdRDETypeVersion)
+				srcRde.Id, CapvcdRDETypeVersion)
 		} else {
 			if resp.StatusCode != http.StatusOK {
 				return nil, fmt.Errorf("obtained unexpected response status code [%d] when fetching the defined entity [%s]. expected [%d]",
@@ -497,6 +670,12 @@ func (capvcdRdeManager *CapvcdRdeManager) convertFrom110Format(ctx context.Conte
 						if err != nil {
 							return nil, fmt.Errorf("failed to convert upgraded RDE [%s(%s)] CAPVCD Status from [%T] to map[string]interface{}", srcCapvcdEntity.Name, srcCapvcdEntity.Id, CAPVCDStatus)
 						}
+						dstCAPVCDStatusMap, err = formatForRDETypeVersion(CapvcdRDETypeVersion, []string{"status", "capvcd"},
+							dstCAPVCDStatusMap)
+						if err != nil {
+							return nil, fmt.Errorf("failed to convert upgraded RDE [%s(%s)] CAPVCD Status to version [%s]: [%v]",
+								srcCapvcdEntity.Name, srcCapvcdEntity.Id, CapvcdRDETypeVersion, err)
+						}
 						newStatusMap["capvcd"] = dstCAPVCDStatusMap
 					}
 				}
@@ -507,7 +686,9 @@ func (capvcdRdeManager *CapvcdRdeManager) convertFrom110Format(ctx context.Conte
 		}
 
 		// ******************  upgrade RDE EntityType Version ******************
-		srcCapvcdEntity.EntityType = CAPVCDEntityTypePrefix + ":" + rdeType.CapvcdRDETypeVersion
+		// spec, metadata and the other sections of the status, including status.capvcd.private, are kept as they are
+		srcCapvcdEntity.EntityType = GetCAPVCDEntityTypeID()
+		srcCapvcdEntity.Entity["apiVersion"] = GetCAPVCDClusterEntityApiVersion()
 
 		updatedRde, resp, err := capvcdRdeManager.Client.APIClient.DefinedEntityApi.UpdateDefinedEntity(
 			ctx, srcCapvcdEntity, etag, srcRde.Id, org.Org.ID, nil)
@@ -516,29 +697,31 @@ func (capvcdRdeManager *CapvcdRdeManager) convertFrom110Format(ctx context.Conte
 			if gsErr, ok := err.(swagger.GenericSwaggerError); ok {
 				responseMessageBytes = gsErr.Body()
 				klog.V(5).Infof("error occurred when upgrading defined entity [%s(%s)] from version [%s] to version [%s]: [%s]",
-					srcRde.Name, srcRde.Id, srcRdeTypeVersion, rdeType.CapvcdRDETypeVersion, string(responseMessageBytes))
+					srcRde.Name, srcRde.Id, srcRdeTypeVersion, CapvcdRDETypeVersion, string(responseMessageBytes))
 			}
 			return nil, fmt.Errorf("error when upgrading defined entity [%s(%s)] from EntityType Version [%s] to EntityType Version [%s]: [%v]",
-				srcRde.Name, srcRde.Id, srcRdeTypeVersion, rdeType.CapvcdRDETypeVersion, err)
+				srcRde.Name, srcRde.Id, srcRdeTypeVersion, CapvcdRDETypeVersion, err)
 		} else if resp == nil {
 			return nil, fmt.Errorf("unexpected response when upgrading defined entity [%s(%s)] from EntityType Version [%s] to EntityType Version [%s]; obtained nil response",
-				srcRde.Name, srcRde.Id, srcRdeTypeVersion, rdeType.CapvcdRDETypeVersion)
+				srcRde.Name, srcRde.Id, srcRdeTypeVersion, CapvcdRDETypeVersion)
 		} else {
 			if resp.StatusCode == http.StatusPreconditionFailed {
+				recordRDEWriteConflict()
 				klog.V(5).Infof("wrong etag [%s] while upgrading the defined entity [%s(%s)] from EntityType Version [%s] to EntityType Version [%s]. Retries remaining: [%d]",
-					etag, srcRde.Name, srcRde.Id, srcRdeTypeVersion, rdeType.CapvcdRDETypeVersion, MaxUpdateRetries-retries-1)
+					etag, srcRde.Name, srcRde.Id, srcRdeTypeVersion, CapvcdRDETypeVersion, MaxUpdateRetries-retries-1)
 				continue
 			} else if resp.StatusCode != http.StatusOK {
 				klog.Errorf("unexpected response status code when upgrading defined entity [%s(%s)] from EntityType Version [%s] to EntityType Version [%s]. Expected response [%d] obtained [%d]",
-					srcRde.Name, srcRde.Id, srcRdeTypeVersion, rdeType.CapvcdRDETypeVersion, http.StatusOK, resp.StatusCode)
+					srcRde.Name, srcRde.Id, srcRdeTypeVersion, CapvcdRDETypeVersion, http.StatusOK, resp.StatusCode)
 				continue
 			}
 		}
-		klog.V(4).Infof("successfully upgraded RDE [%s(%s)] from EntityType Version [%s] to EntityType Version [%s]", srcRde.Name, srcRde.Id, srcRdeTypeVersion, rdeType.CapvcdRDETypeVersion)
+		klog.V(4).Infof("successfully upgraded RDE [%s(%s)] from EntityType Version [%s] to EntityType Version [%s]", srcRde.Name, srcRde.Id, srcRdeTypeVersion, CapvcdRDETypeVersion)
+		recordRDEUpdate(srcRde.Id)
 		return &updatedRde, nil
 	}
 	return nil, fmt.Errorf("failed to upgrade RDE [%s(%s)] from EntityType Version [%s] to EntityType Version [%s] after [%d] retries",
-		srcRde.Name, srcRde.Id, srcRdeTypeVersion, rdeType.CapvcdRDETypeVersion, MaxUpdateRetries)
+		srcRde.Name, srcRde.Id, srcRdeTypeVersion, CapvcdRDETypeVersion, MaxUpdateRetries)
 }
 
 // convertFrom100Format provides an automatic conversion from RDE Version 1.0.0 to the latest RDE Version in use
@@ -550,8 +733,8 @@ func (capvcdRdeManager *CapvcdRdeManager) convertFrom110Format(ctx context.Conte
 // Call an API call (PUT) to update CAPVCD entity and persist data into VCD
 // Return dstCapvcdEntity as output. CAPVCD update capiYaml in the parent method reconcileRDE()
 func (capvcdRdeManager *CapvcdRdeManager) convertFrom100Format(ctx context.Context, srcRde *swagger.DefinedEntity, srcRdeTypeVersion string) (*swagger.DefinedEntity, error) {
-	if srcRdeTypeVersion == rdeType.CapvcdRDETypeVersion {
-		klog.V(4).Infof("RDE [%s] is already upgraded to version [%s]", srcRde.Id, rdeType.CapvcdRDETypeVersion)
+	if srcRdeTypeVersion == CapvcdRDETypeVersion {
+		klog.V(4).Infof("RDE [%s] is already upgraded to version [%s]", srcRde.Id, CapvcdRDETypeVersion)
 		return srcRde, nil
 	}
 	client := capvcdRdeManager.Client
@@ -569,12 +752,12 @@ func (capvcdRdeManager *CapvcdRdeManager) convertFrom100Format(ctx context.Conte
 			if gsErr, ok := err.(swagger.GenericSwaggerError); ok {
 				responseMessageBytes = gsErr.Body()
 				klog.Errorf("error occurred when upgrading defined entity [%s] to version [%s]: [%s]",
-					srcRde.Id, rdeType.CapvcdRDETypeVersion, string(responseMessageBytes))
+					srcRde.Id, CapvcdRDETypeVersion, string(responseMessageBytes))
 			}
-			return nil, fmt.Errorf("failed to get the defined entity to convert RDE to version [%s]", rdeType.CapvcdRDETypeVersion)
+			return nil, fmt.Errorf("failed to get the defined entity to convert RDE to version [%s]", CapvcdRDETypeVersion)
 		} else if resp == nil {
 			return nil, fmt.Errorf("unexpected response when fetching the defined entity [%s] to version [%s]; obtained nil response",
-				srcRde.Id, rdeType.CapvcdRDETypeVersion)
+				srcRde.Id, CapvcdRDETypeVersion)
 		} else {
 			if resp.StatusCode != http.StatusOK {
 				return nil, fmt.Errorf("obtained unexpected response status code [%d] when fetching the defined entity [%s]. expected [%d]",
@@ -587,7 +770,7 @@ func (capvcdRdeManager *CapvcdRdeManager) convertFrom100Format(ctx context.Conte
 			Spec: rdeType.CAPVCDSpec{
 				CapiYaml: "", // will be eventually updated by CAPVCD
 			},
-			ApiVersion: CAPVCDClusterEntityApiVersion,
+			ApiVersion: GetCAPVCDClusterEntityApiVersion(),
 		}
 		dstCapvcdEntityMap, err := util.ConvertCAPVCDEntityToMap(&dstEmptyCapvcdEntity)
 		if err != nil {
@@ -614,7 +797,7 @@ func (capvcdRdeManager *CapvcdRdeManager) convertFrom100Format(ctx context.Conte
 		dstCapvcdEntityMap["status"] = newStatusMap
 
 		dstCapvcdRde := swagger.DefinedEntity{
-			EntityType: CAPVCDEntityTypePrefix + ":" + rdeType.CapvcdRDETypeVersion,
+			EntityType: GetCAPVCDEntityTypeID(),
 			Entity:     dstCapvcdEntityMap,
 			Name:       srcRde.Name,
 			ExternalId: srcRde.ExternalId,
@@ -627,30 +810,32 @@ func (capvcdRdeManager *CapvcdRdeManager) convertFrom100Format(ctx context.Conte
 			if gsErr, ok := err.(swagger.GenericSwaggerError); ok {
 				responseMessageBytes = gsErr.Body()
 				klog.V(5).Infof("error occurred when upgrading defined entity [%s] to version [%s]: [%s]",
-					srcRde.Id, rdeType.CapvcdRDETypeVersion, string(responseMessageBytes))
+					srcRde.Id, CapvcdRDETypeVersion, string(responseMessageBytes))
 			}
 			return nil, fmt.Errorf("error when upgrading defined entity [%s] to version [%s]: [%v]",
-				srcRde.Id, rdeType.CapvcdRDETypeVersion, err)
+				srcRde.Id, CapvcdRDETypeVersion, err)
 		} else if resp == nil {
 			return nil, fmt.Errorf("unexpected response when upgrading defined entity [%s] to version [%s]; obtained nil response",
-				srcRde.Id, rdeType.CapvcdRDETypeVersion)
+				srcRde.Id, CapvcdRDETypeVersion)
 		} else {
 			if resp.StatusCode == http.StatusPreconditionFailed {
+				recordRDEWriteConflict()
 				klog.V(5).Infof("wrong etag [%s] while upgrading the defined entity [%s] to version [%s]. Retries remaining: [%d]",
-					etag, srcRde.Id, rdeType.CapvcdRDETypeVersion, MaxUpdateRetries-retries-1)
+					etag, srcRde.Id, CapvcdRDETypeVersion, MaxUpdateRetries-retries-1)
 				continue
 			} else if resp.StatusCode != http.StatusOK {
 				klog.Errorf("unexpected response status code when upgrading defined entity [%s] to version [%s]. Expected response [%d] obtained [%d]",
-					srcRde.Id, rdeType.CapvcdRDETypeVersion, http.StatusOK, resp.StatusCode)
+					srcRde.Id, CapvcdRDETypeVersion, http.StatusOK, resp.StatusCode)
 				continue
 			}
 		}
-		klog.V(4).Infof("successfully upgraded RDE [%s] to version [%s]", srcRde.Id, rdeType.CapvcdRDETypeVersion)
+		klog.V(4).Infof("successfully upgraded RDE [%s] to version [%s]", srcRde.Id, CapvcdRDETypeVersion)
+		recordRDEUpdate(srcRde.Id)
 		return &updatedRde, nil
 	}
 
 	return nil, fmt.Errorf("failed to upgrade RDE [%s] to version [%s] after [%d] retries",
-		srcRde.Id, rdeType.CapvcdRDETypeVersion, MaxUpdateRetries)
+		srcRde.Id, CapvcdRDETypeVersion, MaxUpdateRetries)
 }
 
 // ConvertToLatestRDEVersionFormat updates the RDE version. The upgraded RDE will only contain minimal information related to the cluster after upgrade.
@@ -661,8 +846,8 @@ func (capvcdRdeManager *CapvcdRdeManager) convertFrom100Format(ctx context.Conte
 //
 //	The function attempts upgrade multiple times as defined by MaxUpdateRetries to avoid failures due to incorrect ETag.
 func (capvcdRdeManager *CapvcdRdeManager) ConvertToLatestRDEVersionFormat(ctx context.Context, rdeID string) (*swagger.DefinedEntity, error) {
-	if !capvcdRdeManager.IsCapvcdEntityTypeRegistered(rdeType.CapvcdRDETypeVersion) {
-		return nil, fmt.Errorf("CAPVCD entity type with version [%s] not registered", rdeType.CapvcdRDETypeVersion)
+	if !capvcdRdeManager.IsCapvcdEntityTypeRegistered(CapvcdRDETypeVersion) {
+		return nil, fmt.Errorf("CAPVCD entity type with version [%s] not registered", CapvcdRDETypeVersion)
 	}
 	srcRde, srcRdeTypeVersion, err := capvcdRdeManager.GetRDEVersion(ctx, rdeID)
 	if err != nil {
@@ -675,19 +860,19 @@ func (capvcdRdeManager *CapvcdRdeManager) ConvertToLatestRDEVersionFormat(ctx co
 	}
 	var dstRde *swagger.DefinedEntity
 	if strconv.Itoa(int(entityTypeSemVer.Major)) != CAPVCDEntityTypeDefaultMajorVersion {
-		return nil, fmt.Errorf("failed to upgrade RDE [%s(%s)] to version [%s]; invalid source RDE version [%s]", srcRde.Name, srcRde.Id, rdeType.CapvcdRDETypeVersion, srcRdeTypeVersion)
+		return nil, fmt.Errorf("failed to upgrade RDE [%s(%s)] to version [%s]; invalid source RDE version [%s]", srcRde.Name, srcRde.Id, CapvcdRDETypeVersion, srcRdeTypeVersion)
 	}
 	switch srcRdeTypeVersion {
 	case rde_type_1_0_0.CapvcdRDETypeVersion:
 		dstRde, err = capvcdRdeManager.convertFrom100Format(ctx, srcRde, srcRdeTypeVersion)
-	case rde_type_1_1_0.CapvcdRDETypeVersion:
+	case rde_type_1_1_0.CapvcdRDETypeVersion, rde_type_1_2_0.CapvcdRDETypeVersion:
 		dstRde, err = capvcdRdeManager.convertFrom110Format(ctx, srcRde, srcRdeTypeVersion)
 	default:
-		klog.V(3).Infof("CAPVCD does not support RDE [%s(%s)] upgrade from source version [%s] to version [%s]", srcRde.Name, srcRde.Id, srcRdeTypeVersion, rdeType.CapvcdRDETypeVersion)
+		klog.V(3).Infof("CAPVCD does not support RDE [%s(%s)] upgrade from source version [%s] to version [%s]", srcRde.Name, srcRde.Id, srcRdeTypeVersion, CapvcdRDETypeVersion)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert RDE [%s] from source version [%s] to destination version [%s]",
-			srcRde.Id, srcRde.EntityType, rdeType.CapvcdRDETypeVersion)
+			srcRde.Id, srcRde.EntityType, CapvcdRDETypeVersion)
 	}
 
 	return dstRde, nil
@@ -754,7 +939,7 @@ func (capvcdRdeManager *CapvcdRdeManager) AddToErrorSet(ctx context.Context,
 	if detailedErrorMsg != "" {
 		backendErr.AdditionalDetails = map[string]interface{}{"error": detailedErrorMsg}
 	}
-	err := capvcdRdeManager.RdeManager.AddToErrorSet(ctx, vcdsdk.ComponentCAPVCD, backendErr, DefaultRollingWindowSize)
+	err := capvcdRdeManager.RdeManager.AddToErrorSet(ctx, vcdsdk.ComponentCAPVCD, backendErr, RDEEventHistorySize)
 	if err != nil {
 		klog.Errorf(
 			"failed to update RDE with Error; errorName: [%s], vcdResource: [%s], vcdResourceName: [%s]; RDE update error: [%v]",
@@ -768,20 +953,44 @@ func (capvcdRdeManager *CapvcdRdeManager) AddToEventSet(ctx context.Context,
 	// Note: Adding event to the RDE should not stop CAPVCD reconciliation
 	if skipRDEEventUpdates {
 		klog.V(4).Infof("skipping updates to event set as value for skipRDEEventUpdates is [%t] for RDE [%s]", skipRDEEventUpdates, capvcdRdeManager.RdeManager.ClusterID)
+		return
 	}
-	backendEvent := vcdsdk.BackendEvent{
-		Name:            eventName,
-		OccurredAt:      time.Now(),
-		VcdResourceId:   vcdResourceId,
-		VcdResourceName: vcdResourceName,
-	}
+	var details map[string]interface{}
 	if detailedEventMsg != "" {
-		backendEvent.AdditionalDetails = map[string]interface{}{"event": detailedEventMsg}
+		details = map[string]interface{}{"event": detailedEventMsg}
 	}
-	err := capvcdRdeManager.RdeManager.AddToEventSet(ctx, vcdsdk.ComponentCAPVCD, backendEvent, DefaultRollingWindowSize)
-	if err != nil {
+	rdeID := capvcdRdeManager.RdeManager.ClusterID
+	if !capvcdRdeManager.QueueRDEEvent(eventName, vcdResourceId, vcdResourceName, details) {
+		return
+	}
+	// the event is written once the minimum write interval elapsed
+	if _, err := capvcdRdeManager.FlushRDE(ctx, rdeID, false); err != nil {
 		klog.Errorf(
 			"failed to update RDE with Event; eventName: [%s], vcdResource: [%s], vcdResourceName: [%s]; RDE update error: [%v]",
 			eventName, vcdResourceId, vcdResourceName, err)
 	}
 }
+
+// QueueRDEEvent batches the event with the changes of the RDE not written yet, to be appended to the eventSet of the
+// RDE by the next FlushRDE. The details are reported in the additionalDetails of the event. It returns false if the
+// cluster has no RDE.
+func (capvcdRdeManager *CapvcdRdeManager) QueueRDEEvent(eventName, vcdResourceId, vcdResourceName string,
+	details map[string]interface{}) bool {
+
+	rdeID := capvcdRdeManager.RdeManager.ClusterID
+	if !isRDEWritable(rdeID) {
+		klog.V(3).Infof("ClusterID [%s] is empty or generated, hence cannot add event [%s] to RDE", rdeID, eventName)
+		return false
+	}
+	queueRDEChanges(rdeID, &rdeChanges{
+		events: []vcdsdk.BackendEvent{{
+			Name:              eventName,
+			OccurredAt:        time.Now(),
+			VcdResourceId:     vcdResourceId,
+			VcdResourceName:   vcdResourceName,
+			AdditionalDetails: details,
+		}},
+	})
+	capvcdRdeManager.scheduleRDEFlush(rdeID)
+	return true
+}
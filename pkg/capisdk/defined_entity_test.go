@@ -0,0 +1,74 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package capisdk
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vmware/cluster-api-provider-cloud-director/pkg/vcdtypes/rde_type_1_1_0"
+	rdeType "github.com/vmware/cluster-api-provider-cloud-director/pkg/vcdtypes/rde_type_1_3_0"
+)
+
+func TestGetRDETypeVersion(t *testing.T) {
+	if version := getRDETypeVersion("urn:vcloud:type:vmware:capvcdCluster:1.1.0"); version != "1.1.0" {
+		t.Errorf("got version [%s], want [1.1.0]", version)
+	}
+}
+
+func TestFormatForRDETypeVersion(t *testing.T) {
+	capvcdStatusMap, err := convertToMap(&rdeType.CAPVCDStatus{
+		Phase:               "Provisioned",
+		ManagementClusterID: "mgmt-1",
+		NetworkFlows:        &rdeType.NetworkFlows{OvdcNetwork: "ovdc-net"},
+		NodePool:            []rdeType.NodePool{{Name: "md-0", DesiredReplicas: 2, ObservedGeneration: 3}},
+		Private:             rdeType.PrivateSection{KubeConfig: "kubeconfig", KubeConfigEncryptionKeyID: "key-1"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: [%v]", err)
+	}
+	specMap, err := convertToMap(&rdeType.CAPVCDSpec{
+		CapiYaml:  "yaml",
+		NodePools: []rdeType.NodePoolReplicas{{Name: "md-0", DesiredReplicas: 3}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: [%v]", err)
+	}
+
+	formattedStatusMap, err := formatForRDETypeVersion(rde_type_1_1_0.CapvcdRDETypeVersion, []string{"status", "capvcd"},
+		capvcdStatusMap)
+	if err != nil {
+		t.Fatalf("unexpected error: [%v]", err)
+	}
+	wantStatusMap, err := convertToMap(&rde_type_1_1_0.CAPVCDStatus{
+		Phase:    "Provisioned",
+		NodePool: []rde_type_1_1_0.NodePool{{Name: "md-0", DesiredReplicas: 2}},
+		Private:  rde_type_1_1_0.PrivateSection{KubeConfig: "kubeconfig"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: [%v]", err)
+	}
+	if !reflect.DeepEqual(formattedStatusMap, wantStatusMap) {
+		t.Errorf("got status [%v], want [%v]", formattedStatusMap, wantStatusMap)
+	}
+
+	formattedSpecMap, err := formatForRDETypeVersion(rde_type_1_1_0.CapvcdRDETypeVersion, []string{"spec"}, specMap)
+	if err != nil {
+		t.Fatalf("unexpected error: [%v]", err)
+	}
+	if wantSpecMap := map[string]interface{}{"capiYaml": "yaml"}; !reflect.DeepEqual(formattedSpecMap, wantSpecMap) {
+		t.Errorf("got spec [%v], want [%v]", formattedSpecMap, wantSpecMap)
+	}
+
+	latestStatusMap, err := formatForRDETypeVersion(rdeType.CapvcdRDETypeVersion, []string{"status", "capvcd"},
+		capvcdStatusMap)
+	if err != nil {
+		t.Fatalf("unexpected error: [%v]", err)
+	}
+	if !reflect.DeepEqual(latestStatusMap, capvcdStatusMap) {
+		t.Errorf("status of the latest version was changed to [%v]", latestStatusMap)
+	}
+}
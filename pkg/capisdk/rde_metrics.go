@@ -0,0 +1,57 @@
+package capisdk
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// rdeWriteConflicts counts the RDE updates rejected by VCD because the RDE was modified concurrently (stale etag).
+	rdeWriteConflicts = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "capvcd_rde_write_conflicts_total",
+		Help: "Number of RDE updates rejected by VCD because the RDE was concurrently modified.",
+	})
+
+	// rdeUpdates counts the successful RDE updates.
+	rdeUpdates = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "capvcd_rde_updates_total",
+		Help: "Number of successful RDE updates.",
+	})
+
+	// rdeLastUpdateTimes holds the time of the last successful update of each RDE, keyed by RDE ID.
+	rdeLastUpdateTimes sync.Map
+)
+
+func init() {
+	metrics.Registry.MustRegister(rdeWriteConflicts, rdeUpdates)
+}
+
+// recordRDEUpdate records a successful update of the RDE rdeID.
+func recordRDEUpdate(rdeID string) {
+	rdeUpdates.Inc()
+	rdeLastUpdateTimes.Store(rdeID, time.Now())
+}
+
+// recordRDEWriteConflict records an update of an RDE rejected because of a stale etag.
+func recordRDEWriteConflict() {
+	rdeWriteConflicts.Inc()
+}
+
+// GetRDELastUpdateTime returns the time at which the RDE rdeID was last successfully updated by this process.
+func GetRDELastUpdateTime(rdeID string) (time.Time, bool) {
+	lastUpdateTime, ok := rdeLastUpdateTimes.Load(rdeID)
+	if !ok {
+		return time.Time{}, false
+	}
+	return lastUpdateTime.(time.Time), true
+}
+
+// ForgetRDEUpdates drops the update time recorded for the RDE rdeID, and its changes not written yet, once the RDE is
+// deleted.
+func ForgetRDEUpdates(rdeID string) {
+	rdeLastUpdateTimes.Delete(rdeID)
+	forgetRDEWrites(rdeID)
+}
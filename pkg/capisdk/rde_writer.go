@@ -0,0 +1,265 @@
+package capisdk
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+	swagger "github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdswaggerclient_36_0"
+	"k8s.io/klog"
+)
+
+const (
+	// DefaultRDEMinWriteInterval is the default minimum interval between two writes of the batched changes of an RDE.
+	DefaultRDEMinWriteInterval = 30 * time.Second
+
+	// rdeWriteConflictBackoff is the delay before retrying an RDE update rejected because of a stale etag, multiplied
+	// by the number of the attempt.
+	rdeWriteConflictBackoff = 200 * time.Millisecond
+
+	// rdeFlushTimeout is the timeout of a write of the batched changes of an RDE by its flusher.
+	rdeFlushTimeout = time.Minute
+)
+
+var (
+	// RDEMinWriteInterval is the minimum interval between two writes of the batched changes of an RDE. The changes
+	// queued before it elapses are written together by the next flush.
+	RDEMinWriteInterval = DefaultRDEMinWriteInterval
+
+	// RDEEventHistorySize is the number of the latest events and errors kept in the eventSet and errorSet of the
+	// capvcd status of the RDEs, from which the history of the clusters is shown without access to the management
+	// cluster.
+	RDEEventHistorySize = DefaultRollingWindowSize
+
+	// rdeWriteStates holds the batched changes and the time of the last write of each RDE, keyed by RDE ID.
+	rdeWriteStates     = make(map[string]*rdeWriteState)
+	rdeWriteStatesLock sync.Mutex
+)
+
+// rdeChanges are the changes of an RDE written by a single update: the patches of the spec, metadata and capvcd
+// status of the RDE as taken by PatchRDE, and the events appended to the eventSet of the capvcd status.
+type rdeChanges struct {
+	specPatch         map[string]interface{}
+	metadataPatch     map[string]interface{}
+	capvcdStatusPatch map[string]interface{}
+	events            []vcdsdk.BackendEvent
+	externalID        string
+	updateExternalID  bool
+}
+
+// rdeWriteState holds the changes of an RDE not written yet and the flusher writing them once the minimum write
+// interval elapsed, with the RDE manager of the latest reconciliation which queued changes.
+type rdeWriteState struct {
+	pending       *rdeChanges
+	lastWriteTime time.Time
+	flushManager  *CapvcdRdeManager
+	flushTimer    *time.Timer
+}
+
+func (changes *rdeChanges) isEmpty() bool {
+	return len(changes.specPatch) == 0 && len(changes.metadataPatch) == 0 && len(changes.capvcdStatusPatch) == 0 &&
+		len(changes.events) == 0 && !changes.updateExternalID
+}
+
+// merge adds the later changes to the changes; the later values of the patched fields win.
+func (changes *rdeChanges) merge(later *rdeChanges) {
+	changes.specPatch = mergePatch(changes.specPatch, later.specPatch)
+	changes.metadataPatch = mergePatch(changes.metadataPatch, later.metadataPatch)
+	changes.capvcdStatusPatch = mergePatch(changes.capvcdStatusPatch, later.capvcdStatusPatch)
+	changes.events = append(changes.events, later.events...)
+	if later.updateExternalID {
+		changes.externalID = later.externalID
+		changes.updateExternalID = true
+	}
+}
+
+func mergePatch(patch, laterPatch map[string]interface{}) map[string]interface{} {
+	if len(laterPatch) == 0 {
+		return patch
+	}
+	if patch == nil {
+		patch = make(map[string]interface{})
+	}
+	for k, v := range laterPatch {
+		patch[k] = v
+	}
+	return patch
+}
+
+// isRDEWritable checks if the RDE ID identifies an RDE, as opposed to a self-generated infra ID.
+func isRDEWritable(rdeID string) bool {
+	return rdeID != "" && !strings.HasPrefix(rdeID, vcdsdk.NoRdePrefix)
+}
+
+// queueRDEChanges batches the changes with the changes of the RDE not written yet.
+func queueRDEChanges(rdeID string, changes *rdeChanges) {
+	rdeWriteStatesLock.Lock()
+	defer rdeWriteStatesLock.Unlock()
+
+	state, ok := rdeWriteStates[rdeID]
+	if !ok {
+		state = &rdeWriteState{}
+		rdeWriteStates[rdeID] = state
+	}
+	if state.pending == nil {
+		state.pending = &rdeChanges{}
+	}
+	state.pending.merge(changes)
+}
+
+// takeRDEChanges returns the changes of the RDE not written yet, and removes them from the queue, unless the minimum
+// write interval has not elapsed since the last write of the RDE and force is not set.
+func takeRDEChanges(rdeID string, force bool) *rdeChanges {
+	rdeWriteStatesLock.Lock()
+	defer rdeWriteStatesLock.Unlock()
+
+	state, ok := rdeWriteStates[rdeID]
+	if !ok || state.pending == nil {
+		return nil
+	}
+	if !force && time.Since(state.lastWriteTime) < RDEMinWriteInterval {
+		return nil
+	}
+	changes := state.pending
+	state.pending = nil
+	return changes
+}
+
+// requeueRDEChanges puts back the changes which failed to be written before the changes queued since. The failed write
+// counts as a write, so that the flusher retries once the minimum write interval elapsed.
+func requeueRDEChanges(rdeID string, changes *rdeChanges) {
+	rdeWriteStatesLock.Lock()
+	defer rdeWriteStatesLock.Unlock()
+
+	state, ok := rdeWriteStates[rdeID]
+	if !ok {
+		state = &rdeWriteState{}
+		rdeWriteStates[rdeID] = state
+	}
+	if state.pending != nil {
+		changes.merge(state.pending)
+	}
+	// the events are capped to the rolling window anyway when written
+	if len(changes.events) > RDEEventHistorySize {
+		changes.events = changes.events[len(changes.events)-RDEEventHistorySize:]
+	}
+	state.pending = changes
+	state.lastWriteTime = time.Now()
+}
+
+// recordRDEWrite records the time of the last write of the RDE, from which the minimum write interval is counted.
+func recordRDEWrite(rdeID string) {
+	rdeWriteStatesLock.Lock()
+	defer rdeWriteStatesLock.Unlock()
+
+	state, ok := rdeWriteStates[rdeID]
+	if !ok {
+		state = &rdeWriteState{}
+		rdeWriteStates[rdeID] = state
+	}
+	state.lastWriteTime = time.Now()
+}
+
+// getPendingRDEWriteDelay returns how long the changes of the RDE not written yet are held back by the minimum write
+// interval, if there are any.
+func getPendingRDEWriteDelay(state *rdeWriteState) (time.Duration, bool) {
+	if state.pending == nil {
+		return 0, false
+	}
+	delay := RDEMinWriteInterval - time.Since(state.lastWriteTime)
+	if delay < time.Second {
+		delay = time.Second
+	}
+	return delay, true
+}
+
+// scheduleRDEFlush starts the flusher of the RDE if changes of the RDE are held back by the minimum write interval, so
+// that they are written even if no reconciliation flushes the RDE afterwards. The flusher writes them with the RDE
+// manager of the latest call.
+func (capvcdRdeManager *CapvcdRdeManager) scheduleRDEFlush(rdeID string) {
+	rdeWriteStatesLock.Lock()
+	defer rdeWriteStatesLock.Unlock()
+
+	state, ok := rdeWriteStates[rdeID]
+	if !ok {
+		return
+	}
+	state.flushManager = capvcdRdeManager
+	delay, pending := getPendingRDEWriteDelay(state)
+	if !pending || state.flushTimer != nil {
+		return
+	}
+	state.flushTimer = time.AfterFunc(delay, func() {
+		flushScheduledRDE(rdeID)
+	})
+}
+
+// flushScheduledRDE writes the changes of the RDE once its flusher is due.
+func flushScheduledRDE(rdeID string) {
+	rdeWriteStatesLock.Lock()
+	state, ok := rdeWriteStates[rdeID]
+	if !ok {
+		rdeWriteStatesLock.Unlock()
+		return
+	}
+	state.flushTimer = nil
+	capvcdRdeManager := state.flushManager
+	rdeWriteStatesLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), rdeFlushTimeout)
+	defer cancel()
+	if _, err := capvcdRdeManager.FlushRDE(ctx, rdeID, false); err != nil {
+		klog.Errorf("failed to write the batched changes of RDE [%s]: [%v]", rdeID, err)
+	}
+}
+
+// forgetRDEWrites drops the changes not written yet and the time of the last write of the RDE, once it is deleted.
+func forgetRDEWrites(rdeID string) {
+	rdeWriteStatesLock.Lock()
+	defer rdeWriteStatesLock.Unlock()
+
+	if state, ok := rdeWriteStates[rdeID]; ok && state.flushTimer != nil {
+		state.flushTimer.Stop()
+	}
+	delete(rdeWriteStates, rdeID)
+}
+
+// QueueRDEPatch batches the patches of the spec, metadata and capvcd status of the RDE, as taken by PatchRDE, with the
+// changes of the RDE not written yet. The patches are written by the next FlushRDE.
+func (capvcdRdeManager *CapvcdRdeManager) QueueRDEPatch(specPatch, metadataPatch,
+	capvcdStatusPatch map[string]interface{}, rdeID string, externalID string, updateExternalID bool) {
+
+	if !isRDEWritable(rdeID) {
+		return
+	}
+	queueRDEChanges(rdeID, &rdeChanges{
+		specPatch:         specPatch,
+		metadataPatch:     metadataPatch,
+		capvcdStatusPatch: capvcdStatusPatch,
+		externalID:        externalID,
+		updateExternalID:  updateExternalID,
+	})
+	capvcdRdeManager.scheduleRDEFlush(rdeID)
+}
+
+// FlushRDE writes the changes of the RDE not written yet in a single update, if the minimum write interval elapsed
+// since the last write of the RDE or force is set. The RDE is not updated if the changes leave it as is. It returns
+// the updated RDE, or nil if nothing was written. The changes which cannot be written are kept for the next flush, and
+// the changes held back are written by the flusher of the RDE.
+func (capvcdRdeManager *CapvcdRdeManager) FlushRDE(ctx context.Context, rdeID string,
+	force bool) (*swagger.DefinedEntity, error) {
+
+	defer capvcdRdeManager.scheduleRDEFlush(rdeID)
+	changes := takeRDEChanges(rdeID, force)
+	if changes == nil || changes.isEmpty() {
+		return nil, nil
+	}
+	rde, err := capvcdRdeManager.writeRDE(ctx, rdeID, changes)
+	if err != nil {
+		requeueRDEChanges(rdeID, changes)
+		return nil, err
+	}
+	return rde, nil
+}
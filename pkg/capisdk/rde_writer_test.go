@@ -0,0 +1,141 @@
+/*
+   Copyright 2023 VMware, Inc.
+   SPDX-License-Identifier: Apache-2.0
+*/
+
+package capisdk
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vmware/cloud-provider-for-cloud-director/pkg/vcdsdk"
+)
+
+const testRDEID = "urn:vcloud:entity:vmware:capvcdCluster:1234"
+
+// setTestRDEMinWriteInterval sets the minimum write interval, and drops the writes of the test RDE at the end of the
+// test.
+func setTestRDEMinWriteInterval(t *testing.T, interval time.Duration) {
+	previousInterval := RDEMinWriteInterval
+	RDEMinWriteInterval = interval
+	t.Cleanup(func() {
+		forgetRDEWrites(testRDEID)
+		RDEMinWriteInterval = previousInterval
+	})
+}
+
+func getTestEventNames(changes *rdeChanges) []string {
+	var names []string
+	for _, event := range changes.events {
+		names = append(names, event.Name)
+	}
+	return names
+}
+
+func TestMergeRDEChanges(t *testing.T) {
+	changes := &rdeChanges{
+		capvcdStatusPatch: map[string]interface{}{"phase": "Provisioning", "kubernetes": "v1.25.7"},
+		events:            []vcdsdk.BackendEvent{{Name: "first"}},
+	}
+	changes.merge(&rdeChanges{
+		specPatch:         map[string]interface{}{"capiYaml": "yaml"},
+		capvcdStatusPatch: map[string]interface{}{"phase": "Provisioned"},
+		events:            []vcdsdk.BackendEvent{{Name: "second"}},
+		externalID:        "vapp-1",
+		updateExternalID:  true,
+	})
+	changes.merge(&rdeChanges{})
+
+	wantStatusPatch := map[string]interface{}{"phase": "Provisioned", "kubernetes": "v1.25.7"}
+	if !reflect.DeepEqual(changes.capvcdStatusPatch, wantStatusPatch) {
+		t.Errorf("got status patch [%v], want [%v]", changes.capvcdStatusPatch, wantStatusPatch)
+	}
+	if changes.specPatch["capiYaml"] != "yaml" {
+		t.Errorf("got spec patch [%v], want the later spec patch", changes.specPatch)
+	}
+	if names := getTestEventNames(changes); !reflect.DeepEqual(names, []string{"first", "second"}) {
+		t.Errorf("got events %v, want [first second]", names)
+	}
+	if !changes.updateExternalID || changes.externalID != "vapp-1" {
+		t.Errorf("external ID of the later changes was not kept")
+	}
+}
+
+func TestRequeueRDEChanges(t *testing.T) {
+	setTestRDEMinWriteInterval(t, time.Hour)
+	previousEventHistorySize := RDEEventHistorySize
+	RDEEventHistorySize = 3
+	t.Cleanup(func() {
+		RDEEventHistorySize = previousEventHistorySize
+	})
+
+	queueRDEChanges(testRDEID, &rdeChanges{
+		capvcdStatusPatch: map[string]interface{}{"phase": "Provisioning"},
+		events:            []vcdsdk.BackendEvent{{Name: "first"}, {Name: "second"}},
+	})
+	failed := takeRDEChanges(testRDEID, false)
+	if failed == nil {
+		t.Fatalf("changes of an RDE never written were held back")
+	}
+	queueRDEChanges(testRDEID, &rdeChanges{
+		capvcdStatusPatch: map[string]interface{}{"phase": "Provisioned"},
+		events:            []vcdsdk.BackendEvent{{Name: "third"}, {Name: "fourth"}},
+	})
+	requeueRDEChanges(testRDEID, failed)
+
+	// the failed write counts as a write
+	if changes := takeRDEChanges(testRDEID, false); changes != nil {
+		t.Fatalf("requeued changes were taken within the minimum write interval")
+	}
+	changes := takeRDEChanges(testRDEID, true)
+	if changes == nil {
+		t.Fatalf("requeued changes were not taken by a forced flush")
+	}
+	if phase := changes.capvcdStatusPatch["phase"]; phase != "Provisioned" {
+		t.Errorf("got phase [%v], want the phase queued after the failed write", phase)
+	}
+	if names := getTestEventNames(changes); !reflect.DeepEqual(names, []string{"second", "third", "fourth"}) {
+		t.Errorf("got events %v, want the latest events [second third fourth]", names)
+	}
+	if changes := takeRDEChanges(testRDEID, true); changes != nil {
+		t.Errorf("changes were taken twice")
+	}
+}
+
+func TestScheduleRDEFlush(t *testing.T) {
+	setTestRDEMinWriteInterval(t, time.Hour)
+	capvcdRdeManager := &CapvcdRdeManager{}
+
+	getFlushTimer := func() *time.Timer {
+		rdeWriteStatesLock.Lock()
+		defer rdeWriteStatesLock.Unlock()
+		if state, ok := rdeWriteStates[testRDEID]; ok {
+			return state.flushTimer
+		}
+		return nil
+	}
+
+	recordRDEWrite(testRDEID)
+	capvcdRdeManager.scheduleRDEFlush(testRDEID)
+	if getFlushTimer() != nil {
+		t.Errorf("flusher was started without changes to write")
+	}
+
+	queueRDEChanges(testRDEID, &rdeChanges{events: []vcdsdk.BackendEvent{{Name: "first"}}})
+	capvcdRdeManager.scheduleRDEFlush(testRDEID)
+	flushTimer := getFlushTimer()
+	if flushTimer == nil {
+		t.Fatalf("flusher was not started for the changes held back")
+	}
+	capvcdRdeManager.scheduleRDEFlush(testRDEID)
+	if getFlushTimer() != flushTimer {
+		t.Errorf("second flusher was started for the same RDE")
+	}
+
+	forgetRDEWrites(testRDEID)
+	if flushTimer.Stop() {
+		t.Errorf("flusher of the forgotten RDE was not stopped")
+	}
+}